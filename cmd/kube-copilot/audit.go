@@ -29,14 +29,13 @@ var (
 )
 
 func init() {
-	auditCmd.PersistentFlags().StringVarP(&auditName, "name", "", "", "Pod name")
-	auditCmd.PersistentFlags().StringVarP(&auditNamespace, "namespace", "n", "default", "Pod namespace")
-	auditCmd.MarkFlagRequired("name")
+	auditCmd.Flags().StringVarP(&auditName, "name", "", "", "Pod name")
+	auditCmd.Flags().StringVarP(&auditNamespace, "namespace", "n", "default", "Pod namespace")
 }
 
 var auditCmd = &cobra.Command{
-	Use:   "audit",
-	Short: "Audit security issues for a Pod",
+	Use:   "audit [pod]",
+	Short: "Audit security issues for a Pod, or view audit records with list/show/export",
 	Run: func(cmd *cobra.Command, args []string) {
 		// 获取日志记录器
 		logger := utils.GetLogger()
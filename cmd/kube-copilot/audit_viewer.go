@@ -0,0 +1,401 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/client"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// auditQueryOptions 审计查询参数
+type auditQueryOptions struct {
+	server  string
+	token   string
+	apiKey  string
+	user    string
+	model   string
+	cluster string
+	status  string
+	prompt  string
+	since   string
+	until   string
+	sort    string
+	asc     bool
+	limit   int
+	cursor  string
+}
+
+var (
+	auditQuery auditQueryOptions
+
+	exportFormat    string
+	exportOutput    string
+	exportMax       int
+	exportToolCalls bool
+)
+
+func init() {
+	auditCmd.AddCommand(auditListCmd, auditShowCmd, auditExportCmd)
+
+	flags := auditCmd.PersistentFlags()
+	flags.StringVar(&auditQuery.server, "server", os.Getenv("OPSAGENT_SERVER"), "OpsAgent server address, e.g. http://localhost:8080 (queries the audit database directly when empty)")
+	flags.StringVar(&auditQuery.token, "token", os.Getenv("OPSAGENT_TOKEN"), "JWT token for the audit API")
+	flags.StringVar(&auditQuery.apiKey, "api-key", os.Getenv("OPSAGENT_API_KEY"), "Managed API key with the read-audit scope")
+
+	for _, cmd := range []*cobra.Command{auditListCmd, auditExportCmd} {
+		addAuditFilterFlags(cmd.Flags())
+	}
+
+	auditExportCmd.Flags().StringVar(&exportFormat, "format", "json", "Export format: json or csv")
+	auditExportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Output file (default stdout)")
+	auditExportCmd.Flags().IntVar(&exportMax, "max", 1000, "Maximum number of interactions to export")
+	auditExportCmd.Flags().BoolVar(&exportToolCalls, "tool-calls", false, "Include tool calls of each interaction (json only)")
+}
+
+func addAuditFilterFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&auditQuery.user, "user", "", "Filter by username")
+	flags.StringVar(&auditQuery.model, "model", "", "Filter by model")
+	flags.StringVar(&auditQuery.cluster, "cluster", "", "Filter by cluster")
+	flags.StringVar(&auditQuery.status, "status", "", "Filter by status (success or error)")
+	flags.StringVar(&auditQuery.prompt, "prompt-version", "", "Filter by system prompt version, e.g. remote:v3")
+	flags.StringVar(&auditQuery.since, "since", "", "Start time, RFC3339 or a duration such as 24h (default 7 days ago)")
+	flags.StringVar(&auditQuery.until, "until", "", "End time, RFC3339 or a duration such as 1h (default now)")
+	flags.StringVar(&auditQuery.sort, "sort", "created_at", "Sort by created_at or duration_ms")
+	flags.BoolVar(&auditQuery.asc, "asc", false, "Sort ascending")
+	flags.IntVar(&auditQuery.limit, "limit", 20, "Page size")
+	flags.StringVar(&auditQuery.cursor, "cursor", "", "Cursor returned by the previous page")
+}
+
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List audited interactions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reader, closeReader, err := auditReader()
+		if err != nil {
+			return err
+		}
+		defer closeReader()
+
+		q, err := auditQuery.build()
+		if err != nil {
+			return err
+		}
+		interactions, next, err := reader.ListInteractions(context.Background(), q)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tUSER\tMODEL\tCLUSTER\tSTATUS\tDURATION\tQUESTION")
+		for _, i := range interactions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				i.ID, i.CreatedAt.Local().Format("2006-01-02 15:04:05"), i.Username, i.Model, i.Cluster,
+				i.Status, time.Duration(i.DurationMs)*time.Millisecond, oneLine(i.Question, 60))
+		}
+		w.Flush()
+
+		if next != "" {
+			color.Cyan("\nMore results: --cursor %s", next)
+		}
+		return nil
+	},
+}
+
+var auditShowCmd = &cobra.Command{
+	Use:   "show <interaction-id>",
+	Short: "Show an audited interaction and its tool calls",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reader, closeReader, err := auditReader()
+		if err != nil {
+			return err
+		}
+		defer closeReader()
+
+		i, err := reader.GetInteraction(context.Background(), args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Interaction:"), i.ID)
+		fmt.Printf("Time:     %s\n", i.CreatedAt.Local().Format(time.RFC3339))
+		fmt.Printf("User:     %s\n", i.Username)
+		fmt.Printf("Model:    %s\n", i.Model)
+		fmt.Printf("Cluster:  %s\n", i.Cluster)
+		fmt.Printf("Duration: %s\n", time.Duration(i.DurationMs)*time.Millisecond)
+		if i.PromptVersion != "" {
+			fmt.Printf("Prompt:   %s %s (%s)\n", i.PromptName, i.PromptVersion, shortHash(i.PromptHash))
+		}
+		if i.Status == audit.StatusSuccess {
+			fmt.Printf("Status:   %s\n", color.GreenString(i.Status))
+		} else {
+			fmt.Printf("Status:   %s (%s)\n", color.RedString(i.Status), i.Error)
+		}
+
+		color.New(color.Bold).Println("\nQuestion:")
+		fmt.Println(i.Question)
+
+		for _, call := range i.RAGCalls {
+			color.New(color.Bold).Printf("\nRetrieval #%d: %s (%dms, %d tokens)\n", call.Seq, call.Kind, call.LatencyMs, call.TotalTokens)
+			color.Cyan(call.Query)
+			if call.Error != "" {
+				color.Red(call.Error)
+			}
+			for _, text := range call.Context {
+				fmt.Println("  - " + oneLine(text, 120))
+			}
+		}
+
+		for _, call := range i.ToolCalls {
+			color.New(color.Bold).Printf("\nTool call #%d: %s\n", call.Seq, call.Name)
+			color.Cyan(call.Input)
+			fmt.Println(call.Observation)
+		}
+
+		color.New(color.Bold).Println("\nAnswer:")
+		if err := utils.RenderMarkdown(i.Answer); err != nil {
+			fmt.Println(i.Answer)
+		}
+		return nil
+	},
+}
+
+var auditExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export audited interactions as JSON or CSV",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportFormat != "json" && exportFormat != "csv" {
+			return fmt.Errorf("unsupported format %q, use json or csv", exportFormat)
+		}
+
+		reader, closeReader, err := auditReader()
+		if err != nil {
+			return err
+		}
+		defer closeReader()
+
+		q, err := auditQuery.build()
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		var interactions []audit.Interaction
+		for len(interactions) < exportMax {
+			page, next, err := reader.ListInteractions(ctx, q)
+			if err != nil {
+				return err
+			}
+			interactions = append(interactions, page...)
+			if next == "" {
+				break
+			}
+			q.Cursor = next
+		}
+		if len(interactions) > exportMax {
+			interactions = interactions[:exportMax]
+		}
+
+		if exportToolCalls {
+			for idx := range interactions {
+				detail, err := reader.GetInteraction(ctx, interactions[idx].ID)
+				if err != nil {
+					return err
+				}
+				interactions[idx].ToolCalls = detail.ToolCalls
+			}
+		}
+
+		var out io.Writer = os.Stdout
+		if exportOutput != "" {
+			f, err := os.Create(exportOutput)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+
+		if exportFormat == "csv" {
+			err = writeInteractionsCSV(out, interactions)
+		} else {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(interactions)
+		}
+		if err != nil {
+			return err
+		}
+
+		if exportOutput != "" {
+			color.Green("Exported %d interactions to %s", len(interactions), exportOutput)
+		}
+		return nil
+	},
+}
+
+// auditReader 根据参数返回审计查询来源：指定 --server 时通过 API 查询，否则直接查询数据库
+func auditReader() (audit.Reader, func(), error) {
+	if auditQuery.server != "" {
+		httpClient, err := utils.NewHTTPClient("audit")
+		if err != nil {
+			return nil, nil, err
+		}
+		return &apiAuditReader{client: &client.Client{
+			BaseURL: auditQuery.server,
+			Token:   auditQuery.token,
+			APIKey:  auditQuery.apiKey,
+			HTTP:    httpClient,
+		}}, func() {}, nil
+	}
+
+	config := utils.GetConfig()
+	store, err := audit.Open(config.GetString("audit.driver"), config.GetString("audit.dsn"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v (use --server to query the audit API instead)", err)
+	}
+	return store, func() { store.Close() }, nil
+}
+
+// apiAuditReader 通过 Go SDK 查询审计 API，实现 audit.Reader
+type apiAuditReader struct {
+	client *client.Client
+}
+
+func (r *apiAuditReader) ListInteractions(ctx context.Context, q audit.Query) ([]audit.Interaction, string, error) {
+	items, next, err := r.client.ListInteractions(ctx, client.AuditQuery{
+		Filters: q.Filters,
+		Since:   q.Since,
+		Until:   q.Until,
+		SortBy:  q.SortBy,
+		Desc:    q.Desc,
+		Limit:   q.Limit,
+		Cursor:  q.Cursor,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	var interactions []audit.Interaction
+	if err := convertJSON(items, &interactions); err != nil {
+		return nil, "", err
+	}
+	return interactions, next, nil
+}
+
+func (r *apiAuditReader) GetInteraction(ctx context.Context, id string) (*audit.Interaction, error) {
+	item, err := r.client.GetInteraction(ctx, id)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	var interaction audit.Interaction
+	if err := convertJSON(item, &interaction); err != nil {
+		return nil, err
+	}
+	return &interaction, nil
+}
+
+// convertJSON 将 SDK 的审计类型转换为 audit 包的类型，两者的 JSON 格式相同
+func convertJSON(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// build 构建审计查询条件
+func (o auditQueryOptions) build() (audit.Query, error) {
+	q := audit.Query{
+		Filters: map[string]string{},
+		SortBy:  o.sort,
+		Desc:    !o.asc,
+		Limit:   o.limit,
+		Cursor:  o.cursor,
+	}
+	for key, value := range map[string]string{"username": o.user, "model": o.model, "cluster": o.cluster, "status": o.status, "prompt_version": o.prompt} {
+		if value != "" {
+			q.Filters[key] = value
+		}
+	}
+
+	var err error
+	if q.Since, err = parseAuditTime(o.since); err != nil {
+		return q, fmt.Errorf("invalid --since: %v", err)
+	}
+	if q.Until, err = parseAuditTime(o.until); err != nil {
+		return q, fmt.Errorf("invalid --until: %v", err)
+	}
+	return q, nil
+}
+
+// parseAuditTime 解析 RFC3339 时间或相对当前时间的时长
+func parseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func writeInteractionsCSV(out io.Writer, interactions []audit.Interaction) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"id", "created_at", "username", "model", "cluster", "status", "duration_ms", "prompt_name", "prompt_version", "prompt_hash", "question", "answer", "error"}); err != nil {
+		return err
+	}
+	for _, i := range interactions {
+		record := []string{
+			i.ID, i.CreatedAt.Format(time.RFC3339), i.Username, i.Model, i.Cluster, i.Status,
+			strconv.FormatInt(i.DurationMs, 10), i.PromptName, i.PromptVersion, i.PromptHash, i.Question, i.Answer, i.Error,
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func oneLine(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/myysophia/OpsAgent/pkg/users"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// serverFlagKeys 服务器命令行参数与配置项的对应关系
+var serverFlagKeys = map[string]string{
+	"port":    "server.port",
+	"jwt-key": "jwt.key",
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd, configHashPasswordCmd)
+	// 与 server 命令相同的参数，用于检查命令行参数与环境变量、配置文件的冲突
+	configValidateCmd.Flags().Int("port", 8080, "Port to run the server on")
+	configValidateCmd.Flags().String("jwt-key", "", "Key for signing JWT tokens")
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage kube-copilot configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the configuration file, environment variables and flags",
+	Run: func(cmd *cobra.Command, args []string) {
+		config := utils.GetConfig()
+		issues := utils.ValidateConfig(config, flagOverrides(cmd.Flags()))
+
+		if file := config.ConfigFileUsed(); file != "" {
+			fmt.Printf("配置文件: %s\n", file)
+		}
+		for _, issue := range issues {
+			if issue.Level == utils.ConfigIssueError {
+				color.Red(issue.String())
+			} else {
+				color.Yellow(issue.String())
+			}
+		}
+
+		if utils.HasConfigErrors(issues) {
+			os.Exit(1)
+		}
+		color.Green("配置校验通过")
+	},
+}
+
+var configHashPasswordCmd = &cobra.Command{
+	Use:   "hash-password <password>",
+	Short: "Generate the bcrypt password_hash for an auth.users entry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		hash, err := users.HashPassword(args[0])
+		if err != nil {
+			color.Red("生成密码哈希失败: %v", err)
+			os.Exit(1)
+		}
+		fmt.Println(hash)
+	},
+}
+
+// flagOverrides 返回显式设置的命令行参数，键为对应的配置项
+func flagOverrides(flags *pflag.FlagSet) map[string]string {
+	overrides := map[string]string{}
+	for name, key := range serverFlagKeys {
+		if flag := flags.Lookup(name); flag != nil && flag.Changed {
+			overrides[key] = flag.Value.String()
+		}
+	}
+	return overrides
+}
@@ -18,6 +18,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/prompts"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"github.com/myysophia/OpsAgent/pkg/workflows"
 	"github.com/sashabaranov/go-openai"
@@ -28,8 +29,9 @@ import (
 const diagnoseSystemPrompt = `You are a seasoned expert in Kubernetes and cloud-native networking. Utilize a Chain of Thought (CoT) process to diagnose and resolve issues. Your explanations should be in simple terms for non-technical users to understand.
 
 Available Tools:
-- kubectl: Useful for executing kubectl commands. Input: a kubectl command. Output: the result of the command.
-- python: This is a Python interpreter. Use it for executing Python code with the Kubernetes Python SDK client. Ensure the results are output using "print(...)". The input is a Python script, and the output will be the stdout and stderr of this script.
+{{.Tools}}
+
+Target cluster: {{.Cluster}}. Today is {{.Date}}.
 
 Here is your process:
 
@@ -65,7 +67,7 @@ Use this JSON format for responses:
 	"question": "<input question>",
 	"thought": "<your thought process>",
 	"action": {
-		"name": "<action to take, choose from tools [kubectl, python, trivy]. Do not set final_answer when an action is required>",
+		"name": "<action to take, choose from the available tools. Do not set final_answer when an action is required>",
 		"input": "<input for the action. ensure all contexts are added as input if required, e.g. raw YAML or image name.>"
 	},
 	"observation": "<result of the action, set by external tools>",
@@ -107,7 +109,7 @@ var diagnoseCmd = &cobra.Command{
 		messages := []openai.ChatCompletionMessage{
 			{
 				Role:    openai.ChatMessageRoleSystem,
-				Content: diagnoseSystemPrompt,
+				Content: prompts.MustRender(diagnoseSystemPrompt, prompts.NewVars(prompts.Options{})),
 			},
 			{
 				Role:    openai.ChatMessageRoleUser,
@@ -0,0 +1,430 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/credentials"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/rest"
+)
+
+// 检查结果状态
+const (
+	doctorOK   = "ok"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck 单项检查结果
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// doctorReport 自检报告，敏感配置已脱敏，可直接附在工单中
+type doctorReport struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Version     string                 `json:"version"`
+	Platform    string                 `json:"platform"`
+	ConfigFile  string                 `json:"config_file"`
+	Checks      []doctorCheck          `json:"checks"`
+	Config      map[string]interface{} `json:"config"`
+	Env         map[string]string      `json:"env"`
+}
+
+// doctorBinaries 依赖的命令行工具，required 为 false 时缺失只给出警告
+var doctorBinaries = []struct {
+	name     string
+	required bool
+}{
+	{"bash", true},
+	{"kubectl", true},
+	{"python3", false},
+	{"jq", false},
+	{"trivy", false},
+}
+
+// doctorEnvVars 报告中记录的环境变量
+var doctorEnvVars = []string{
+	"KUBECONFIG", "OPENAI_API_KEY", "OPENAI_API_BASE", "GOOGLE_API_KEY", "GOOGLE_CSE_ID",
+	"HTTPS_PROXY", "HTTP_PROXY", "NO_PROXY",
+}
+
+// dsnPasswordRe 匹配 key=value 形式连接串中的密码
+var dsnPasswordRe = regexp.MustCompile(`(?i)(password=)\S+`)
+
+var (
+	doctorReportFile string
+	doctorTimeout    time.Duration
+)
+
+func init() {
+	doctorCmd.Flags().StringVarP(&doctorReportFile, "report", "r", "kube-copilot-doctor.json", "Write a redacted report to this file (empty to skip)")
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 15*time.Second, "Timeout for network checks")
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the environment for common deployment problems",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		defer cancel()
+
+		var checks []doctorCheck
+		checks = append(checks, checkBinaries()...)
+		checks = append(checks, checkConfig())
+		checks = append(checks, checkKubeconfig())
+		checks = append(checks, checkKubeCredentials(ctx)...)
+		checks = append(checks, checkLLM(ctx)...)
+		checks = append(checks, checkAuditSchema(ctx))
+		checks = append(checks, checkRAGCredentials()...)
+
+		failed := false
+		for _, check := range checks {
+			line := fmt.Sprintf("[%s] %-22s %s", strings.ToUpper(check.Status), check.Name, check.Message)
+			switch check.Status {
+			case doctorOK:
+				color.Green(line)
+			case doctorWarn:
+				color.Yellow(line)
+			default:
+				color.Red(line)
+				failed = true
+			}
+		}
+
+		if doctorReportFile != "" {
+			if err := writeDoctorReport(doctorReportFile, checks); err != nil {
+				color.Red("写入报告失败: %v", err)
+			} else {
+				fmt.Printf("\n报告已写入 %s（敏感信息已脱敏）\n", doctorReportFile)
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// checkBinaries 检查依赖的命令行工具是否在 PATH 中
+func checkBinaries() []doctorCheck {
+	checks := make([]doctorCheck, 0, len(doctorBinaries))
+	for _, bin := range doctorBinaries {
+		check := doctorCheck{Name: "binary/" + bin.name}
+		if path, err := exec.LookPath(bin.name); err == nil {
+			check.Status, check.Message = doctorOK, path
+		} else if bin.required {
+			check.Status, check.Message = doctorFail, "not found in PATH"
+		} else {
+			check.Status, check.Message = doctorWarn, "not found in PATH, the related tool will be unavailable"
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkConfig 复用 config validate 的校验
+func checkConfig() doctorCheck {
+	check := doctorCheck{Name: "config"}
+	issues := utils.ValidateConfig(utils.GetConfig(), nil)
+
+	var errors, warnings int
+	for _, issue := range issues {
+		if issue.Level == utils.ConfigIssueError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	switch {
+	case errors > 0:
+		check.Status = doctorFail
+	case warnings > 0:
+		check.Status = doctorWarn
+	default:
+		check.Status = doctorOK
+	}
+	check.Message = fmt.Sprintf("%d errors, %d warnings (run `kube-copilot config validate` for details)", errors, warnings)
+	return check
+}
+
+// checkKubeconfig 检查 kubeconfig 中的 context，集群内运行时使用 ServiceAccount
+func checkKubeconfig() doctorCheck {
+	check := doctorCheck{Name: "kubeconfig"}
+	contexts, current, err := kubernetes.ListContexts()
+	if err == nil && len(contexts) > 0 {
+		check.Status = doctorOK
+		check.Message = fmt.Sprintf("%d contexts, current: %s", len(contexts), current)
+		if current == "" {
+			check.Status = doctorWarn
+			check.Message = fmt.Sprintf("%d contexts, no current context set", len(contexts))
+		}
+		return check
+	}
+
+	if _, inClusterErr := rest.InClusterConfig(); inClusterErr == nil {
+		check.Status, check.Message = doctorOK, "running in cluster with service account"
+		return check
+	}
+
+	check.Status = doctorFail
+	if err != nil {
+		check.Message = err.Error()
+	} else {
+		check.Message = "no contexts found"
+	}
+	return check
+}
+
+// checkKubeCredentials 检查各 context 凭据的过期时间，即将过期（clusters.credentials.warn_before 内）时给出警告
+func checkKubeCredentials(ctx context.Context) []doctorCheck {
+	contexts, _, err := kubernetes.ListContexts()
+	if err != nil || len(contexts) == 0 {
+		return nil
+	}
+	warnBefore := utils.GetConfig().GetDuration("clusters.credentials.warn_before")
+	if warnBefore <= 0 {
+		warnBefore = 72 * time.Hour
+	}
+
+	var checks []doctorCheck
+	for _, name := range contexts {
+		status := credentials.Inspect(ctx, name, "")
+		check := doctorCheck{Name: "kubeconfig/" + name, Status: doctorOK, Message: status.Kind}
+		in, known := status.ExpiresIn(time.Now())
+		switch {
+		case status.Error != "":
+			check.Status, check.Message = doctorFail, status.Error
+		case known && in <= 0:
+			check.Status, check.Message = doctorFail, fmt.Sprintf("%s expired at %s", status.Kind, status.ExpiresAt.Format(time.RFC3339))
+		case known && in < warnBefore:
+			check.Status, check.Message = doctorWarn, fmt.Sprintf("%s expires in %s", status.Kind, in.Round(time.Minute))
+		case known:
+			check.Message = fmt.Sprintf("%s valid until %s", status.Kind, status.ExpiresAt.Format(time.RFC3339))
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkLLM 检查 LLM 密钥和端点连通性
+func checkLLM(ctx context.Context) []doctorCheck {
+	keyCheck := doctorCheck{Name: "llm/api_key", Status: doctorOK, Message: "configured"}
+	if utils.GetConfig().GetString("llm.api_key") == "" && os.Getenv("OPENAI_API_KEY") == "" {
+		keyCheck.Status = doctorWarn
+		keyCheck.Message = "neither llm.api_key nor OPENAI_API_KEY is set, clients must send X-API-Key"
+	}
+	checks := []doctorCheck{keyCheck}
+
+	for _, result := range llms.CheckEndpoints(ctx) {
+		check := doctorCheck{Name: "llm/endpoint", Status: doctorOK}
+		if result.Error != "" {
+			check.Status = doctorFail
+			check.Message = fmt.Sprintf("%s: %s", result.Endpoint, result.Error)
+		} else {
+			check.Message = fmt.Sprintf("%s: dns %s, connect %s", result.Endpoint, result.DNS.Round(time.Millisecond), result.Connect.Round(time.Millisecond))
+			if result.Ping > 0 {
+				check.Message += fmt.Sprintf(", ping %s", result.Ping.Round(time.Millisecond))
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkAuditSchema 检查审计数据库连接及表结构版本
+func checkAuditSchema(ctx context.Context) doctorCheck {
+	check := doctorCheck{Name: "audit/schema"}
+	config := utils.GetConfig()
+	if !config.GetBool("audit.enabled") {
+		check.Status, check.Message = doctorOK, "audit disabled"
+		return check
+	}
+
+	version, err := audit.CheckSchema(ctx, config.GetString("audit.driver"), config.GetString("audit.dsn"))
+	switch {
+	case err != nil:
+		check.Status, check.Message = doctorFail, err.Error()
+	case version < audit.SchemaVersion:
+		check.Status = doctorWarn
+		check.Message = fmt.Sprintf("schema version %d, expected %d (will be migrated when the server starts)", version, audit.SchemaVersion)
+		if pending, err := audit.PendingMigrations(config.GetString("audit.driver"), version); err == nil {
+			names := make([]string, len(pending))
+			for i, m := range pending {
+				names[i] = fmt.Sprintf("%04d_%s", m.Version, m.Name)
+			}
+			check.Message += ", pending: " + strings.Join(names, ", ")
+		}
+	case version > audit.SchemaVersion:
+		check.Status = doctorWarn
+		check.Message = fmt.Sprintf("schema version %d is newer than this binary (%d)", version, audit.SchemaVersion)
+	default:
+		check.Status, check.Message = doctorOK, fmt.Sprintf("schema version %d", version)
+	}
+	return check
+}
+
+// checkRAGCredentials 检查检索相关的凭据：会话历史的向量化以及 search 工具
+func checkRAGCredentials() []doctorCheck {
+	config := utils.GetConfig()
+	embedding := doctorCheck{Name: "rag/embedding", Status: doctorOK}
+	if model := config.GetString("llm.embedding_model"); model == "" {
+		embedding.Status, embedding.Message = doctorWarn, "llm.embedding_model is not set, conversation history retrieval is disabled"
+	} else {
+		embedding.Message = "model " + model
+	}
+
+	search := doctorCheck{Name: "rag/search", Status: doctorOK, Message: "GOOGLE_API_KEY and GOOGLE_CSE_ID are set"}
+	var missing []string
+	for _, env := range []string{"GOOGLE_API_KEY", "GOOGLE_CSE_ID"} {
+		if os.Getenv(env) == "" {
+			missing = append(missing, env)
+		}
+	}
+	if len(missing) > 0 {
+		search.Status = doctorWarn
+		search.Message = strings.Join(missing, ", ") + " not set, the search tool will fail"
+	}
+	return []doctorCheck{embedding, search}
+}
+
+// writeDoctorReport 写入脱敏后的自检报告
+func writeDoctorReport(path string, checks []doctorCheck) error {
+	config := utils.GetConfig()
+	report := doctorReport{
+		GeneratedAt: time.Now(),
+		Version:     VERSION,
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		ConfigFile:  config.ConfigFileUsed(),
+		Checks:      checks,
+		Config:      redactSettings("", config.AllSettings()),
+		Env:         map[string]string{},
+	}
+	for _, env := range doctorEnvVars {
+		if value, ok := os.LookupEnv(env); ok {
+			report.Env[env] = redactValue(env, value)
+		}
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// redactSettings 递归脱敏配置中的密钥、令牌和连接串
+func redactSettings(prefix string, settings map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	redacted := make(map[string]interface{}, len(settings))
+	for _, key := range keys {
+		redacted[key] = redactAny(prefix+key, settings[key])
+	}
+	return redacted
+}
+
+// redactAny 脱敏任意配置取值，递归处理嵌套的对象和列表，例如 auth.users[].password_hash、notify.channels[].url
+func redactAny(key string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return redactSettings(key+".", value)
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, item := range value {
+			redacted[i] = redactAny(key, item)
+		}
+		return redacted
+	case []string:
+		redacted := make([]string, len(value))
+		for i, item := range value {
+			redacted[i] = redactValue(key, item)
+		}
+		return redacted
+	case string:
+		return redactValue(key, value)
+	}
+	return value
+}
+
+// isSensitiveName 判断配置项或查询参数名是否表示密钥
+func isSensitiveName(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range []string{"key", "secret", "token", "password", "signature", "credential"} {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return name == "sig" || name == "auth"
+}
+
+// redactValue 脱敏单个取值：密钥类只保留是否设置，连接串中的密码和 URL 查询参数中的密钥被隐藏
+func redactValue(key, value string) string {
+	if value == "" {
+		return ""
+	}
+	if isSensitiveName(key[strings.LastIndex(key, ".")+1:]) {
+		return "***"
+	}
+	if dsnPasswordRe.MatchString(value) {
+		return dsnPasswordRe.ReplaceAllString(value, "${1}***")
+	}
+	if u, err := url.Parse(value); err == nil && u.Host != "" {
+		u.RawQuery = redactQuery(u.RawQuery)
+		return u.Redacted()
+	}
+	return value
+}
+
+// redactQuery 隐藏查询参数中的密钥，例如 ?access_token=、?key=，保持参数顺序
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		name, _, ok := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if ok && isSensitiveName(name) {
+			params[i] = param[:strings.Index(param, "=")+1] + "***"
+		}
+	}
+	return strings.Join(params, "&")
+}
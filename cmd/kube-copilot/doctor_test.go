@@ -0,0 +1,73 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+	}{
+		{"llm.api_key", "sk-123", "***"},
+		{"OPENAI_API_KEY", "sk-123", "***"},
+		{"auth.users.password_hash", "$2a$10$abc", "***"},
+		{"llm.api_key", "", ""},
+		{"audit.dsn", "host=db user=opsagent password=s3cret dbname=audit", "host=db user=opsagent password=*** dbname=audit"},
+		{"audit.dsn", "postgres://opsagent:s3cret@db:5432/audit", "postgres://opsagent:xxxxx@db:5432/audit"},
+		{"notify.channels.url", "https://oapi.dingtalk.com/robot/send?access_token=abc123", "https://oapi.dingtalk.com/robot/send?access_token=***"},
+		{"notify.channels.url", "https://chat.googleapis.com/v1/spaces/X/messages?key=AIza&token=t0k&threadKey=ops", "https://chat.googleapis.com/v1/spaces/X/messages?key=***&token=***&threadKey=***"},
+		{"notify.channels.url", "https://blob.example.com/x?sv=2024&sig=abc&se=2026", "https://blob.example.com/x?sv=2024&sig=***&se=2026"},
+		{"llm.providers.openai.base_url", "https://api.openai.com/v1", "https://api.openai.com/v1"},
+		{"server.mode", "release", "release"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			if got := redactValue(tt.key, tt.value); got != tt.want {
+				t.Errorf("redactValue(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactSettings(t *testing.T) {
+	settings := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"admins": []interface{}{"alice"},
+			"users": []interface{}{
+				map[string]interface{}{"username": "bob", "role": "operator", "password_hash": "$2a$10$abc"},
+			},
+		},
+		"notify": map[string]interface{}{
+			"channels": []interface{}{
+				map[string]interface{}{"name": "ops", "url": "https://hooks.example.com/send?access_token=abc"},
+			},
+		},
+		"tools": map[string]interface{}{
+			"endpoints": []string{"https://iotdb.example.com/?token=abc"},
+			"quotas":    map[string]interface{}{"trivy": 20},
+		},
+	}
+	want := map[string]interface{}{
+		"auth": map[string]interface{}{
+			"admins": []interface{}{"alice"},
+			"users": []interface{}{
+				map[string]interface{}{"username": "bob", "role": "operator", "password_hash": "***"},
+			},
+		},
+		"notify": map[string]interface{}{
+			"channels": []interface{}{
+				map[string]interface{}{"name": "ops", "url": "https://hooks.example.com/send?access_token=***"},
+			},
+		},
+		"tools": map[string]interface{}{
+			"endpoints": []string{"https://iotdb.example.com/?token=***"},
+			"quotas":    map[string]interface{}{"trivy": 20},
+		},
+	}
+	if got := redactSettings("", settings); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactSettings() = %#v, want %#v", got, want)
+	}
+}
@@ -20,8 +20,6 @@ import (
 
 	//"github.com/fatih/color"
 	"github.com/myysophia/OpsAgent/pkg/assistants"
-	"github.com/myysophia/OpsAgent/pkg/tools"
-	kubetools "github.com/myysophia/OpsAgent/pkg/tools"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"github.com/myysophia/OpsAgent/pkg/workflows"
 	"github.com/sashabaranov/go-openai"
@@ -172,8 +170,6 @@ var model string
 //var logger *logrus.Logger
 
 func init() {
-	tools.CopilotTools["trivy"] = kubetools.Trivy
-
 	executeCmd.PersistentFlags().StringVarP(&instructions, "instructions", "", "", "instructions to execute")
 	executeCmd.MarkFlagRequired("instructions")
 
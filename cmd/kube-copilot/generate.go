@@ -15,6 +15,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"os"
 	"strings"
 
@@ -73,6 +74,32 @@ var generateCmd = &cobra.Command{
 		utils.Info("生成的清单:")
 		color.New(color.FgGreen).Printf("%s\n\n", yaml)
 
+		// 应用前先执行服务端 dry-run 和 diff，被 API Server 拒绝时不再询问是否应用
+		diff, err := workflows.PreviewApply(context.Background(), yaml)
+		if err != nil {
+			logger.Error("清单 dry-run 失败", zap.Error(err))
+			color.Red(err.Error())
+			return
+		}
+		for _, obj := range diff.Objects {
+			switch obj.Action {
+			case kubernetes.DiffActionInvalid:
+				color.Red("%s: %s", obj.Object, obj.Error)
+			case kubernetes.DiffActionUnchanged:
+				color.New(color.FgHiBlack).Printf("%s 无变化\n", obj.Object)
+			default:
+				color.New(color.FgYellow).Printf("%s %s\n%s\n", obj.Action, obj.Object, obj.Diff)
+			}
+		}
+		if !diff.Valid {
+			color.Red("清单未通过服务端 dry-run 校验，请修改后重试")
+			return
+		}
+		if !diff.Changed() {
+			color.New(color.FgGreen).Printf("集群中的对象与清单一致，无需应用\n")
+			return
+		}
+
 		// apply the yaml to kubernetes cluster
 		color.New(color.FgRed).Printf("是否要将生成的清单应用到集群中？(y/n)")
 		scanner := bufio.NewScanner(os.Stdin)
@@ -32,6 +32,9 @@ func init() {
 	rootCmd.PersistentFlags().IntVarP(&maxIterations, "max-iterations", "x", 10, "Max iterations for the agent running")
 
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
 
 func main() {
@@ -70,6 +73,30 @@ func main() {
 		logConfig.LogDir = config.GetString("log.output")
 	}
 
+	// 设置日志轮转、压缩和磁盘占用上限
+	if config.IsSet("log.max_size_mb") {
+		logConfig.MaxSize = config.GetInt("log.max_size_mb")
+	}
+	if config.IsSet("log.max_backups") {
+		logConfig.MaxBackups = config.GetInt("log.max_backups")
+	}
+	if config.IsSet("log.max_age_days") {
+		logConfig.MaxAge = config.GetInt("log.max_age_days")
+	}
+	if config.IsSet("log.max_total_size_mb") {
+		logConfig.MaxTotalSize = config.GetInt("log.max_total_size_mb")
+	}
+	if config.IsSet("log.compress") {
+		logConfig.Compress = config.GetBool("log.compress")
+	}
+
+	// 设置日志外发
+	shipper, err := utils.LogShipperConfigFrom(config)
+	if err != nil {
+		panic(err)
+	}
+	logConfig.Shipper = shipper
+
 	// 初始化日志
 	if _, err := utils.InitLogger(logConfig); err != nil {
 		panic(err)
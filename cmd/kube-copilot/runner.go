@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/myysophia/OpsAgent/pkg/receipts"
+	"github.com/myysophia/OpsAgent/pkg/runners"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	runnerListen string
+	runnerTLS    runners.TLSConfig
+)
+
+// runnerCmd 在集群内运行 runner，替中心服务执行访问本集群的工具调用
+var runnerCmd = &cobra.Command{
+	Use:   "runner",
+	Short: "Run an in-cluster agent that executes cluster tools for the API server over mTLS gRPC",
+	Run: func(cmd *cobra.Command, args []string) {
+		initLogger()
+		defer logger.Sync()
+
+		config := utils.GetConfig()
+		if !cmd.Flags().Changed("listen") && config.GetString("runner.listen") != "" {
+			runnerListen = config.GetString("runner.listen")
+		}
+		var tlsConfig runners.TLSConfig
+		if err := config.UnmarshalKey("runner.tls", &tlsConfig); err != nil {
+			logger.Fatal("解析 runner.tls 失败", zap.Error(err))
+		}
+		if runnerTLS.CertFile != "" {
+			tlsConfig.CertFile = runnerTLS.CertFile
+		}
+		if runnerTLS.KeyFile != "" {
+			tlsConfig.KeyFile = runnerTLS.KeyFile
+		}
+		if runnerTLS.CAFile != "" {
+			tlsConfig.CAFile = runnerTLS.CAFile
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		server := runners.NewServer(config.GetStringSlice("runner.tools"))
+		if keyFile := config.GetString("runner.receipts.key_file"); keyFile != "" {
+			signer, err := receipts.LoadSigner(keyFile, config.GetString("runner.receipts.identity"))
+			if err != nil {
+				logger.Fatal("加载回执签名私钥失败", zap.Error(err))
+			}
+			server.SetSigner(signer)
+			logger.Info("已启用工具执行回执", zap.String("identity", signer.Identity()), zap.String("key_id", receipts.KeyID(signer.PublicKey())))
+		}
+		if err := runners.Serve(ctx, runnerListen, server, tlsConfig); err != nil {
+			logger.Fatal("runner 启动失败", zap.Error(err))
+		}
+		logger.Info("runner 已退出")
+	},
+}
+
+func init() {
+	runnerCmd.Flags().StringVar(&runnerListen, "listen", ":9443", "Address to listen on (overrides runner.listen)")
+	runnerCmd.Flags().StringVar(&runnerTLS.CertFile, "cert", "", "Runner certificate (overrides runner.tls.cert_file)")
+	runnerCmd.Flags().StringVar(&runnerTLS.KeyFile, "key", "", "Runner private key (overrides runner.tls.key_file)")
+	runnerCmd.Flags().StringVar(&runnerTLS.CAFile, "ca", "", "CA that signs the API server client certificate (overrides runner.tls.ca_file)")
+	rootCmd.AddCommand(runnerCmd)
+}
@@ -1,13 +1,32 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"k8s.io/client-go/rest"
 
 	"github.com/myysophia/OpsAgent/pkg/api"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/chaos"
+	"github.com/myysophia/OpsAgent/pkg/configrepo"
+	"github.com/myysophia/OpsAgent/pkg/credentials"
+	"github.com/myysophia/OpsAgent/pkg/devmode"
+	"github.com/myysophia/OpsAgent/pkg/kubeaudit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/reports"
+	"github.com/myysophia/OpsAgent/pkg/runners"
+	"github.com/myysophia/OpsAgent/pkg/snapshots"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 )
 
@@ -17,6 +36,7 @@ var (
 	jwtKey      string
 	logger      *zap.Logger
 	showThought bool
+	devMode     bool
 
 	// Execute flags (从 execute.go 同步)
 	maxTokens     = 8192
@@ -25,6 +45,9 @@ var (
 	maxIterations = 10
 )
 
+// defaultShutdownTimeout 退出时等待处理中的请求和审计写入的默认时长
+const defaultShutdownTimeout = 30 * time.Second
+
 const (
 	VERSION          = "v1.0.2"
 	DEFAULT_USERNAME = "admin"
@@ -73,41 +96,209 @@ var serverCmd = &cobra.Command{
 		initLogger()
 		defer logger.Sync()
 
+		// 开发模式：工具和 LLM 返回模拟数据，需要在校验配置和初始化集群登记之前启用
+		if devMode {
+			if err := devmode.Enable(); err != nil {
+				logger.Fatal("启用开发模式失败", zap.Error(err))
+			}
+			logger.Warn("已启用开发模式，kubectl、trivy 和 LLM 返回模拟数据，模拟集群：dev-cn、dev-eu")
+		}
+
+		// 校验配置，命令行参数优先于环境变量和配置文件
+		config := utils.GetConfig()
+		if devMode && !cmd.Flags().Changed("jwt-key") && utils.IsDefaultJWTKey(config.GetString("jwt.key")) {
+			config.Set("jwt.key", "opsagent-dev")
+			logger.Warn("开发模式未配置 jwt-key，使用固定的开发密钥")
+		}
+		issues := utils.ValidateConfig(config, flagOverrides(cmd.Flags()))
+		for _, issue := range issues {
+			if issue.Level == utils.ConfigIssueError {
+				logger.Error("配置错误", zap.String("key", issue.Key), zap.String("message", issue.Message))
+			} else {
+				logger.Warn("配置警告", zap.String("key", issue.Key), zap.String("message", issue.Message))
+			}
+		}
+		if utils.HasConfigErrors(issues) {
+			logger.Fatal("配置校验失败，请运行 config validate 查看详情")
+		}
+
+		// 故障注入只在非生产环境可用，需要在初始化审计存储和处理请求之前启用
+		if err := chaos.Init(); err != nil {
+			logger.Fatal("启用故障注入失败", zap.Error(err))
+		}
+		if !cmd.Flags().Changed("port") && config.IsSet("server.port") {
+			port = config.GetInt("server.port")
+		}
+		if !cmd.Flags().Changed("jwt-key") {
+			jwtKey = config.GetString("jwt.key")
+		}
+
 		logger.Info("启动服务器",
 			zap.Int("port", port),
 			zap.Bool("show-thought", showThought),
 		)
 
-		// 验证必要参数
-		if jwtKey == "" {
-			logger.Fatal("缺少必要参数: jwt-key")
-		}
-
 		// 设置全局变量
 		utils.SetGlobalVar("jwtKey", []byte(jwtKey))
 		utils.SetGlobalVar("showThought", showThought)
 		utils.SetGlobalVar("logger", logger)
 
+		// 收到 SIGINT/SIGTERM 时取消 ctx：停止后台任务，等待处理中的请求完成后退出
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		// 初始化链路追踪，需在记录审计和处理请求之前完成
+		if err := utils.InitTracing(ctx); err != nil {
+			logger.Fatal("初始化链路追踪失败",
+				zap.Error(err),
+			)
+		}
+
+		// 初始化审计存储
+		if err := audit.Init(); err != nil {
+			logger.Fatal("初始化审计存储失败",
+				zap.Error(err),
+			)
+		}
+
+		// 拉取对象存储中的集群审计日志，用于回答"谁修改了这个资源"
+		if kubeaudit.Enabled() {
+			kubeaudit.Start(ctx)
+		}
+
+		// 访问配置了 runner 的集群的工具调用转发到集群内的 runner 执行
+		if !devMode {
+			if err := runners.Start(); err != nil {
+				logger.Fatal("初始化集群 runner 失败",
+					zap.Error(err),
+				)
+			}
+		}
+
+		// 定期保存集群资源元数据快照，用于回答历史时刻存在哪些资源
+		if snapshots.Enabled() && !devMode {
+			snapshots.Start(ctx)
+		}
+
+		// 从 Git 配置仓库加载集群登记和服务登记，在预热提示之前完成首次同步
+		if configrepo.Enabled() && !devMode {
+			configrepo.Start(ctx)
+		}
+
+		// 预热系统提示缓存，部署后的首个请求不必等待远程下载；之后定期在过期前刷新
+		if !devMode {
+			utils.StartPromptPrewarm(ctx)
+		}
+
+		// 后台预热 LLM 端点，不阻塞服务启动；开发模式不请求 LLM 和集群
+		if !devMode {
+			go llms.WarmUp(ctx)
+		}
+
+		// 记录各集群的发布历史，诊断时关联错误与最近的变更
+		if utils.GetConfig().GetBool("changes.enabled") && !devMode {
+			startRolloutWatchers(ctx)
+		}
+
+		// 检查集群凭据有效期，临近过期时执行刷新钩子并告警
+		if credentials.Enabled() && !devMode {
+			credentials.Start(ctx)
+		}
+
+		// 定时生成配额合规等报告并发送到通知渠道
+		reports.Start(ctx)
+
 		// 使用pkg/api/router.go中的Router函数
 		r := api.Router()
 
 		addr := fmt.Sprintf(":%d", port)
+		server := &http.Server{Addr: addr, Handler: r}
 		logger.Info("服务器开始监听",
 			zap.String("address", addr),
 		)
 
-		if err := r.Run(addr); err != nil {
-			logger.Fatal("服务器启动失败",
-				zap.Error(err),
-			)
+		serveErr := make(chan error, 1)
+		go func() { serveErr <- server.ListenAndServe() }()
+		select {
+		case err := <-serveErr:
+			if !errors.Is(err, http.ErrServerClosed) {
+				logger.Fatal("服务器启动失败",
+					zap.Error(err),
+				)
+			}
+		case <-ctx.Done():
+			stop()
+			shutdown(server)
 		}
 	},
 }
 
+// shutdown 优雅退出：停止接收新连接并等待处理中的请求完成，然后写入剩余的审计记录、
+// 导出剩余的 span 并关闭 LLM 客户端的连接，总等待时间由 server.shutdown_timeout 控制（默认 30s）
+func shutdown(server *http.Server) {
+	timeout := utils.GetConfig().GetDuration("server.shutdown_timeout")
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	logger.Info("收到退出信号，等待处理中的请求完成", zap.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Warn("等待处理中的请求超时，强制关闭连接", zap.Error(err))
+		server.Close()
+	}
+	if err := audit.Shutdown(ctx); err != nil {
+		logger.Warn("写入剩余审计记录失败", zap.Error(err))
+	}
+	if err := utils.ShutdownTracing(ctx); err != nil {
+		logger.Warn("导出剩余链路追踪数据失败", zap.Error(err))
+	}
+	llms.CloseIdleConnections()
+	runners.Close()
+	logger.Info("服务器已退出")
+}
+
+// startRolloutWatchers 为 changes.clusters 中的每个集群启动发布历史记录
+// 未配置时记录当前 context，集群内运行时使用 ServiceAccount
+func startRolloutWatchers(ctx context.Context) {
+	clusters := utils.GetConfig().GetStringSlice("changes.clusters")
+	if len(clusters) == 0 {
+		if _, current, err := kubernetes.ListContexts(); err == nil && current != "" {
+			clusters = []string{current}
+		}
+	}
+
+	watch := func(cluster string, config *rest.Config) {
+		logger.Info("开始记录集群发布历史", zap.String("cluster", cluster))
+		if err := kubernetes.WatchRollouts(ctx, cluster, config, kubernetes.Rollouts()); err != nil {
+			logger.Error("记录集群发布历史失败", zap.String("cluster", cluster), zap.Error(err))
+		}
+	}
+
+	if len(clusters) == 0 {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			logger.Warn("没有可用的集群，不记录发布历史", zap.Error(err))
+			return
+		}
+		go watch(kubernetes.InClusterName, config)
+		return
+	}
+	for _, cluster := range clusters {
+		config, err := kubernetes.ConfigForContext(cluster)
+		if err != nil {
+			logger.Error("读取集群配置失败", zap.String("cluster", cluster), zap.Error(err))
+			continue
+		}
+		go watch(cluster, config)
+	}
+}
+
 func init() {
 	serverCmd.Flags().IntVarP(&port, "port", "p", 8080, "Port to run the server on")
-	serverCmd.Flags().StringVar(&jwtKey, "jwt-key", "", "Key for signing JWT tokens")
+	serverCmd.Flags().StringVar(&jwtKey, "jwt-key", "", "Key for signing JWT tokens (overrides jwt.key and OPSAGENT_JWT_KEY)")
 	serverCmd.Flags().BoolVar(&showThought, "show-thought", false, "Whether to show LLM's thought process in API responses")
-	serverCmd.MarkFlagRequired("jwt-key")
+	serverCmd.Flags().BoolVar(&devMode, "dev", false, "Development mode: kubectl, trivy and the LLM return deterministic fixture data, no cluster or LLM access needed")
 	rootCmd.AddCommand(serverCmd)
 }
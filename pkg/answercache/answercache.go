@@ -0,0 +1,204 @@
+package answercache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTTL        = 5 * time.Minute
+	defaultMaxEntries = 1000
+	keyPrefix         = "opsagent:answer:"
+)
+
+// Entry 缓存的回答
+type Entry struct {
+	Answer        string    `json:"answer"`
+	InteractionID string    `json:"interaction_id"` // 生成该回答的交互，便于追溯
+	Model         string    `json:"model"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Scope 影响回答内容的请求参数，与问题一起组成缓存键
+type Scope struct {
+	Tenant   string
+	Cluster  string
+	Role     string
+	Language string
+	Provider string
+	Model    string
+}
+
+// Cache 只读问题的回答缓存：进程内 LRU，配置了 Redis 时多个实例共享
+type Cache struct {
+	ttl        time.Duration
+	maxEntries int
+	redis      *redisClient
+
+	mu      sync.Mutex
+	order   *list.List // 最近使用的在前
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key       string
+	entry     Entry
+	expiresAt time.Time
+}
+
+var (
+	defaultCache *Cache
+	defaultOnce  sync.Once
+)
+
+// Enabled 是否启用回答缓存，配置项 answer_cache.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("answer_cache.enabled")
+}
+
+// Default 获取根据配置创建的全局回答缓存
+// 配置项：
+//   - answer_cache.ttl: 回答的有效期，默认 5m
+//   - answer_cache.max_entries: 进程内最多缓存的回答数，默认 1000
+//   - answer_cache.redis.addr / password / db: 配置 addr 时回答同时写入 Redis
+func Default() *Cache {
+	defaultOnce.Do(func() {
+		config := utils.GetConfig()
+		var redis *redisClient
+		if addr := config.GetString("answer_cache.redis.addr"); addr != "" {
+			redis = newRedisClient(addr, config.GetString("answer_cache.redis.password"), config.GetInt("answer_cache.redis.db"))
+		}
+		defaultCache = New(config.GetDuration("answer_cache.ttl"), config.GetInt("answer_cache.max_entries"), redis)
+	})
+	return defaultCache
+}
+
+// New 创建回答缓存，参数不大于 0 时使用默认值，redis 为 nil 时只使用进程内缓存
+func New(ttl time.Duration, maxEntries int, redis *redisClient) *Cache {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = defaultMaxEntries
+	}
+	return &Cache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		redis:      redis,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+// Key 缓存键：规范化后的问题（忽略大小写、空白和结尾标点）和影响回答的请求参数
+func Key(question string, scope Scope) string {
+	h := sha256.Sum256([]byte(strings.Join([]string{Normalize(question), scope.Tenant, scope.Cluster, scope.Role, scope.Language, scope.Provider, scope.Model}, "\x00")))
+	return keyPrefix + hex.EncodeToString(h[:16])
+}
+
+// Normalize 规范化问题："nginx 的镜像版本？" 与 "Nginx的 镜像版本" 视为同一个问题
+func Normalize(question string) string {
+	question = strings.ToLower(strings.TrimSpace(question))
+	question = strings.TrimRightFunc(question, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+	var b strings.Builder
+	for _, r := range question {
+		if !unicode.IsSpace(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Get 查找未过期的回答，进程内未命中时查询 Redis 并回填
+func (c *Cache) Get(ctx context.Context, key string) (Entry, bool) {
+	now := time.Now()
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*memoryEntry)
+		if now.Before(e.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return e.entry, true
+		}
+		c.removeLocked(elem)
+	}
+	c.mu.Unlock()
+
+	if c.redis == nil {
+		return Entry{}, false
+	}
+	data, err := c.redis.Get(ctx, key)
+	if err != nil {
+		utils.Warn("读取 Redis 回答缓存失败", zap.Error(err))
+		return Entry{}, false
+	}
+	if data == nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, false
+	}
+	if expiresAt := entry.CreatedAt.Add(c.ttl); now.Before(expiresAt) {
+		c.put(key, entry, expiresAt)
+		return entry, true
+	}
+	return Entry{}, false
+}
+
+// Set 缓存回答，Redis 写入失败只记录日志
+func (c *Cache) Set(ctx context.Context, key string, entry Entry) {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	c.put(key, entry, entry.CreatedAt.Add(c.ttl))
+
+	if c.redis == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = c.redis.Set(ctx, key, data, c.ttl)
+	}
+	if err != nil {
+		utils.Warn("写入 Redis 回答缓存失败", zap.Error(err))
+	}
+}
+
+func (c *Cache) put(key string, entry Entry, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = &memoryEntry{key: key, entry: entry, expiresAt: expiresAt}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, entry: entry, expiresAt: expiresAt})
+	for c.order.Len() > c.maxEntries {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+func (c *Cache) removeLocked(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*memoryEntry).key)
+}
+
+// Len 进程内缓存的回答数（包括尚未清理的过期回答）
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,121 @@
+package answercache
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyNormalizesQuestion(t *testing.T) {
+	scope := Scope{Cluster: "prod", Role: "viewer"}
+	if Key("nginx 的镜像版本？", scope) != Key("  Nginx的 镜像版本", scope) {
+		t.Error("questions differing only in case, spaces and trailing punctuation should share a key")
+	}
+	if Key("nginx 的镜像版本", scope) == Key("nginx 的镜像版本", Scope{Cluster: "staging", Role: "viewer"}) {
+		t.Error("different clusters should not share a key")
+	}
+	if Key("nginx 的镜像版本", scope) == Key("nginx 的镜像版本", Scope{Cluster: "prod", Role: "viewer", Model: "gpt-4o-mini"}) {
+		t.Error("different models should not share a key")
+	}
+	if Key("nginx 的镜像版本", Scope{Provider: "openai", Model: "m"}) == Key("nginx 的镜像版本", Scope{Provider: "anthropic", Model: "m"}) {
+		t.Error("different providers should not share a key")
+	}
+	if Key("nginx 的镜像版本", scope) == Key("redis 的镜像版本", scope) {
+		t.Error("different questions should not share a key")
+	}
+}
+
+func TestCacheTTLAndEviction(t *testing.T) {
+	ctx := context.Background()
+	c := New(time.Minute, 2, nil)
+	c.Set(ctx, "a", Entry{Answer: "A"})
+	c.Set(ctx, "b", Entry{Answer: "B"})
+	if _, ok := c.Get(ctx, "a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	// a 最近被读取，写入 c 时淘汰 b
+	c.Set(ctx, "c", Entry{Answer: "C"})
+	if _, ok := c.Get(ctx, "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	if e, ok := c.Get(ctx, "a"); !ok || e.Answer != "A" {
+		t.Errorf("Get(a) = %+v, %v", e, ok)
+	}
+
+	c.Set(ctx, "old", Entry{Answer: "old", CreatedAt: time.Now().Add(-2 * time.Minute)})
+	if _, ok := c.Get(ctx, "old"); ok {
+		t.Error("expected expired entry to be ignored")
+	}
+}
+
+// fakeRedis 支持 GET/SET 的最小 RESP 服务
+func fakeRedis(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	store := map[string]string{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						r.ReadString('\n')
+						arg, _ := r.ReadString('\n')
+						args[i] = strings.TrimSuffix(arg, "\r\n")
+					}
+					switch args[0] {
+					case "SET":
+						store[args[1]] = args[2]
+						conn.Write([]byte("+OK\r\n"))
+					case "GET":
+						if v, ok := store[args[1]]; ok {
+							conn.Write([]byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"))
+						} else {
+							conn.Write([]byte("$-1\r\n"))
+						}
+					default:
+						conn.Write([]byte("-ERR unknown command\r\n"))
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestCacheSharedThroughRedis(t *testing.T) {
+	ctx := context.Background()
+	addr := fakeRedis(t)
+	writer := New(time.Minute, 10, newRedisClient(addr, "", 0))
+	reader := New(time.Minute, 10, newRedisClient(addr, "", 0))
+
+	writer.Set(ctx, "k", Entry{Answer: "镜像版本 1.25", InteractionID: "i-1"})
+	e, ok := reader.Get(ctx, "k")
+	if !ok || e.Answer != "镜像版本 1.25" || e.InteractionID != "i-1" {
+		t.Fatalf("Get from redis = %+v, %v", e, ok)
+	}
+	if reader.Len() != 1 {
+		t.Errorf("expected redis hit to be stored in memory, len = %d", reader.Len())
+	}
+	if _, ok := reader.Get(ctx, "missing"); ok {
+		t.Error("expected miss for unknown key")
+	}
+}
@@ -0,0 +1,138 @@
+package answercache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redisTimeout = 2 * time.Second
+
+// redisClient 只实现回答缓存需要的 AUTH/SELECT/GET/SET 命令，连接失败时下次调用重新建立
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisClient(addr, password string, db int) *redisClient {
+	return &redisClient{addr: addr, password: password, db: db}
+}
+
+// Get 返回 key 的值，不存在时返回 nil
+func (r *redisClient) Get(ctx context.Context, key string) ([]byte, error) {
+	return r.do(ctx, "GET", key)
+}
+
+// Set 写入 key，ttl 后过期
+func (r *redisClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.do(ctx, "SET", key, string(value), "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	return err
+}
+
+func (r *redisClient) do(ctx context.Context, args ...string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		if err := r.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := r.roundTrip(ctx, args)
+	if err != nil {
+		// 连接状态未知，丢弃后下次重连
+		r.conn.Close()
+		r.conn, r.reader = nil, nil
+	}
+	return reply, err
+}
+
+func (r *redisClient) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: redisTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return fmt.Errorf("连接 Redis %s 失败: %w", r.addr, err)
+	}
+	r.conn, r.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if r.password != "" {
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		if _, err := r.roundTrip(ctx, args); err != nil {
+			conn.Close()
+			r.conn, r.reader = nil, nil
+			return fmt.Errorf("初始化 Redis 连接失败 (%s): %w", args[0], err)
+		}
+	}
+	return nil
+}
+
+func (r *redisClient) roundTrip(ctx context.Context, args []string) ([]byte, error) {
+	deadline := time.Now().Add(redisTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := r.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(r.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return readReply(r.reader)
+}
+
+// readReply 读取一个简单字符串、错误、整数或批量字符串回复
+func readReply(reader *bufio.Reader) ([]byte, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("Redis 回复格式错误")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, fmt.Errorf("Redis 错误: %s", line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("Redis 回复格式错误: %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	default:
+		return nil, fmt.Errorf("不支持的 Redis 回复类型: %q", line)
+	}
+}
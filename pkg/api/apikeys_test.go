@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestAPIKeyRoutes(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("apikeys.enabled", true)
+	config.Set("apikeys.file", filepath.Join(t.TempDir(), "apikeys.json"))
+	defer config.Set("apikeys.enabled", false)
+	utils.SetGlobalVar("jwtKey", []byte("test-key"))
+	defer utils.RemoveGlobalVar("jwtKey")
+
+	token := func(username, role string) string {
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &middleware.Claims{Username: username, Role: role}).SignedString([]byte("test-key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+	admin, viewer := token("root", "admin"), token("bob", "viewer")
+	r := Router()
+	do := func(method, path, auth, apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", auth)
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	create := func(scopes string) (string, string) {
+		w := do(http.MethodPost, "/api/apikeys", admin, "", `{"name":"ci","tenant":"ops","scopes":`+scopes+`}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("create as admin: status %d, body %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Key  string `json:"key"`
+			Info struct {
+				ID string `json:"id"`
+			} `json:"info"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Key, resp.Info.ID
+	}
+
+	// 非管理员不能创建、轮换、删除或列出 API Key
+	if w := do(http.MethodPost, "/api/apikeys", viewer, "", `{"name":"mine","tenant":"ops","scopes":["*"]}`); w.Code != http.StatusForbidden {
+		t.Errorf("create as viewer: status %d", w.Code)
+	}
+	auditKey, auditID := create(`["read-audit"]`)
+	executeKey, _ := create(`["execute"]`)
+	if w := do(http.MethodPost, "/api/apikeys/"+auditID+"/rotate", viewer, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("rotate as viewer: status %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/apikeys/"+auditID, viewer, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("revoke as viewer: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/apikeys", viewer, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("list as viewer: status %d", w.Code)
+	}
+
+	// 权限范围：审计存储未启用时通过校验的请求返回 503
+	if w := do(http.MethodGet, "/api/audit/interactions", viewer, auditKey, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("read-audit key on audit: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/audit/interactions", viewer, executeKey, ""); w.Code != http.StatusForbidden {
+		t.Errorf("execute key on audit: status %d", w.Code)
+	}
+
+	// 轮换后旧密钥失效，删除后新密钥失效
+	w := do(http.MethodPost, "/api/apikeys/"+auditID+"/rotate", admin, "", "")
+	var rotated struct {
+		Key string `json:"key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	if w.Code != http.StatusOK || rotated.Key == "" || rotated.Key == auditKey {
+		t.Fatalf("rotate as admin: status %d, body %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/audit/interactions", viewer, auditKey, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("old key after rotation: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/audit/interactions", viewer, rotated.Key, ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("rotated key: status %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/apikeys/"+auditID, admin, "", ""); w.Code != http.StatusOK {
+		t.Errorf("revoke as admin: status %d", w.Code)
+	}
+	if w := do(http.MethodGet, "/api/audit/interactions", viewer, rotated.Key, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked key: status %d", w.Code)
+	}
+}
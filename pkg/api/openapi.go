@@ -0,0 +1,565 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/client"
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/configrepo"
+	"github.com/myysophia/OpsAgent/pkg/credentials"
+	"github.com/myysophia/OpsAgent/pkg/evaluation"
+	"github.com/myysophia/OpsAgent/pkg/handlers"
+	"github.com/myysophia/OpsAgent/pkg/handoff"
+	"github.com/myysophia/OpsAgent/pkg/inventory"
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/myysophia/OpsAgent/pkg/workflows"
+)
+
+const (
+	openAPIBasePath        = "/api/v2"
+	defaultSwaggerUIAssets = "https://unpkg.com/swagger-ui-dist@5"
+)
+
+// fields 响应 data 的字段，值为字段类型的零值
+type fields map[string]interface{}
+
+// param 查询参数或路径参数
+type param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// operation 接口说明，请求体和响应 data 的 schema 由 Go 类型生成
+type operation struct {
+	Summary     string
+	Tag         string
+	Description string
+	Query       []param
+	Request     interface{} // 请求体类型的零值，nil 表示没有请求体
+	Response    interface{} // 响应 data 类型的零值或 fields，nil 表示任意对象
+	Status      int         // 成功状态码，默认 200
+	Public      bool        // 不需要认证
+}
+
+var auditQuery = []param{
+	{Name: "sort", Description: "排序字段，默认 created_at"},
+	{Name: "order", Description: "asc 或 desc，默认 desc"},
+	{Name: "limit", Description: "每页条数"},
+	{Name: "cursor", Description: "上一页返回的 next_cursor"},
+	{Name: "since", Description: "开始时间（RFC3339）"},
+	{Name: "until", Description: "结束时间（RFC3339）"},
+	{Name: "username", Description: "按用户过滤，其他审计字段同样可以作为过滤参数"},
+	{Name: "cluster", Description: "按集群过滤"},
+	{Name: "status", Description: "按状态过滤"},
+}
+
+// operations /api/v2 下的接口说明，键为 "方法 路径"，路径与路由注册时相同
+// 新增路由时需要在这里补充说明，TestOpenAPICoversRoutes 会检查遗漏
+var operations = map[string]operation{
+	"POST /login": {Summary: "用户名密码登录，返回 JWT 令牌", Tag: "auth", Public: true,
+		Request: handlers.LoginRequest{}, Response: client.LoginResponse{}},
+	"GET /version": {Summary: "服务版本", Tag: "system", Public: true,
+		Response: fields{"version": ""}},
+	"GET /approvals/callback": {Summary: "钉钉/企业微信审批卡片按钮回调", Tag: "approvals", Public: true,
+		Query: []param{{Name: "state", Description: "签名的审批动作", Required: true}, {Name: "code", Description: "IM 免登授权码"}}},
+	"POST /kube-audit/:cluster": {Summary: "apiserver webhook 审计后端，使用 kube_audit.webhook_token 认证", Tag: "kube-audit", Public: true},
+	"POST /config-repo/webhook": {Summary: "配置仓库 push webhook，使用 config_repo.webhook_secret 校验签名后在后台拉取", Tag: "admin",
+		Status: http.StatusAccepted, Response: fields{"message": ""}, Public: true},
+
+	"POST /execute": {Summary: "提问并执行诊断，返回最终回答", Tag: "execute",
+		Query:   []param{{Name: "show-thought", Description: "为 true 时返回工具调用历史"}},
+		Request: handlers.ExecuteRequest{}, Response: client.ExecuteResponse{}},
+	"POST /execute/stream": {Summary: "提问并执行诊断，以 SSE 推送执行过程中的事件", Tag: "execute",
+		Description: "响应为 text/event-stream：event 为事件类型（iteration_started、tool_selected、tool_completed、parse_failed、final_answer），" +
+			"data 为 assistants.Event；结束时推送 result 事件，data 为 {status_code, response}，response 与 POST /execute 的响应相同。" +
+			"没有执行诊断就返回的请求（参数错误、命中回答缓存等）按普通 JSON 响应返回，SSE 事件不使用统一响应格式",
+		Query:   []param{{Name: "show-thought", Description: "为 true 时 result 中返回工具调用历史"}},
+		Request: handlers.ExecuteRequest{}},
+	"GET /ws/chat": {Summary: "多轮对话（WebSocket）", Tag: "execute", Status: http.StatusSwitchingProtocols,
+		Description: "升级为 WebSocket 后客户端发送 ChatMessage，服务端推送 ChatEvent（session、progress、answer、error），WebSocket 消息不使用统一响应格式",
+		Query: []param{
+			{Name: "session_id", Description: "恢复已有会话，为空时创建新会话"},
+			{Name: "provider", Description: "LLM 服务商，仅在创建会话时生效"},
+			{Name: "model", Description: "使用的模型，默认 gpt-4"},
+			{Name: "cluster", Description: "目标集群，仅在创建会话时生效"},
+			{Name: "baseUrl", Description: "LLM 服务地址，使用服务端密钥时只接受配置的服务商地址和 llm.allowed_base_urls"},
+			{Name: "showThought", Description: "为 true 时返回工具调用历史"},
+		}},
+	"POST /diagnose": {Summary: "诊断 Pod 问题", Tag: "diagnose",
+		Query:   []param{{Name: "model"}, {Name: "cluster"}},
+		Request: handlers.DiagnoseRequest{}},
+	"POST /analyze": {Summary: "分析 Kubernetes 资源", Tag: "analyze",
+		Query:   []param{{Name: "model"}, {Name: "cluster"}},
+		Request: handlers.AnalyzeRequest{}},
+
+	"POST /generate": {Summary: "按说明生成 Kubernetes YAML 清单并执行服务端 dry-run，返回清单和校验结果；apply=true 时通过校验的清单提交审批，返回 202 和 apply，被 API Server 拒绝时返回 422 和 errors、diff；生成的清单写入审计记录，响应包含 interaction_id", Tag: "generate",
+		Query:    []param{{Name: "apply", Description: "true 时提交应用请求等待审批，也可以在请求体中指定"}},
+		Request:  handlers.GenerateRequest{},
+		Response: fields{"yaml": "", "diff": kubernetes.ManifestDiff{}, "apply": workflows.ApplyRequest{}, "status": ""}},
+	"POST /generate/diff": {Summary: "对清单执行服务端 dry-run，返回校验错误和相对集群中现有对象的 diff，不修改集群", Tag: "generate",
+		Request: handlers.ApplyManifestRequest{}, Response: fields{"diff": kubernetes.ManifestDiff{}, "status": ""}},
+	"POST /generate/apply": {Summary: "提交生成的清单，服务端 dry-run 和 diff 通过后等待审批；被 API Server 拒绝时返回 422 和 errors、diff", Tag: "generate", Status: http.StatusAccepted,
+		Request: handlers.ApplyManifestRequest{}, Response: fields{"apply": workflows.ApplyRequest{}, "status": ""}},
+	"GET /generate/apply/:id": {Summary: "查询清单应用请求", Tag: "generate",
+		Response: fields{"apply": workflows.ApplyRequest{}, "status": ""}},
+	"POST /generate/apply/:id/approve": {Summary: "批准并以服务端凭据应用清单，仅管理员，默认审批人不能是申请人", Tag: "generate",
+		Request: handlers.ReviewApplyRequest{}, Response: fields{"apply": workflows.ApplyRequest{}, "status": ""}},
+	"POST /generate/apply/:id/reject": {Summary: "拒绝清单应用请求", Tag: "generate",
+		Request: handlers.ReviewApplyRequest{}, Response: fields{"apply": workflows.ApplyRequest{}, "status": ""}},
+	"GET /generate/apply/:id/drift": {Summary: "检查已应用清单的配置漂移", Tag: "generate",
+		Response: fields{"drift": workflows.DriftReport{}}},
+	"GET /generate/drift": {Summary: "检查全部已应用清单的配置漂移，单个清单检测失败时在其报告的 error 中说明", Tag: "generate",
+		Response: fields{"reports": []workflows.DriftReport{}, "total": 0, "drifted": 0}},
+
+	"GET /approvals": {Summary: "查询变更命令审批", Tag: "approvals",
+		Query:    []param{{Name: "status"}, {Name: "username", Description: "管理员可查询其他用户的审批"}},
+		Response: fields{"approvals": []audit.Approval{}, "total": 0}},
+	"GET /approvals/:id": {Summary: "查询单个审批", Tag: "approvals",
+		Response: fields{"approval": audit.Approval{}, "status": ""}},
+	"POST /approvals/:id/approve": {Summary: "批准变更命令，执行后继续暂停的对话", Tag: "approvals"},
+	"POST /approvals/:id/reject":  {Summary: "拒绝变更命令", Tag: "approvals"},
+
+	"GET /clusters": {Summary: "查询登记的集群", Tag: "clusters",
+		Response: fields{"clusters": []clusters.Cluster{}, "total": 0}},
+	"GET /clusters/:name": {Summary: "查询单个集群", Tag: "clusters",
+		Response: fields{"cluster": clusters.Cluster{}}},
+	"POST /clusters": {Summary: "登记集群", Tag: "clusters", Status: http.StatusCreated,
+		Request: handlers.ClusterRequest{}, Response: fields{"cluster": clusters.Cluster{}}},
+	"PUT /clusters/:name": {Summary: "修改接口登记的集群", Tag: "clusters",
+		Request: handlers.ClusterRequest{}, Response: fields{"cluster": clusters.Cluster{}}},
+	"DELETE /clusters/:name": {Summary: "删除接口登记的集群", Tag: "clusters",
+		Response: fields{"message": ""}},
+	"GET /search": {Summary: "在全部集群的资源清单中按名称搜索资源", Tag: "clusters",
+		Description: "资源清单在内存中缓存 inventory.ttl（默认 5 分钟），过期后在搜索时重新列出；刷新失败的集群使用上一次的清单并在 clusters 中返回错误",
+		Query: []param{
+			{Name: "name", Description: "名称中包含的文本，不区分大小写"},
+			{Name: "kind", Description: "资源类型，例如 deployments、deploy、service"},
+			{Name: "namespace"},
+			{Name: "cluster", Description: "可重复或以逗号分隔，为空时搜索全部集群"},
+			{Name: "limit", Description: "最多返回的匹配数，默认 100，最大 1000"},
+		},
+		Response: inventory.Result{}},
+	"GET /snapshot": {Summary: "查询集群的结构化快照", Tag: "clusters",
+		Description: "包含资源元数据、工作负载的副本数、镜像和资源配置、ConfigMap 内容的哈希；指定 at 时返回该时刻之前最近的历史快照（需要启用 snapshots.enabled 和 audit.enabled），否则获取实时快照",
+		Query: []param{
+			{Name: "cluster", Description: "kubeconfig context，为空时使用当前 context"},
+			{Name: "namespace", Description: "可重复或以逗号分隔，为空时包含全部命名空间和集群级资源"},
+			{Name: "at", Description: "RFC3339 时间、2006-01-02 日期或 24h、7d 表示多久之前"},
+		},
+		Response: kubernetes.Snapshot{}},
+	"GET /snapshot/diff": {Summary: "比较集群两个时刻的快照", Tag: "clusters",
+		Description: "返回新增、删除的资源和变化的字段（镜像、副本数、资源配置、ConfigMap 内容、标签等）；from 取该时刻之前最近的历史快照，未指定 to 时与实时快照比较",
+		Query: []param{
+			{Name: "cluster", Description: "kubeconfig context，为空时使用当前 context"},
+			{Name: "namespace", Description: "可重复或以逗号分隔，为空时比较全部命名空间和集群级资源"},
+			{Name: "from", Description: "RFC3339 时间、2006-01-02 日期或 24h、7d 表示多久之前，默认 24h"},
+			{Name: "to", Description: "格式同 from，为空时与实时快照比较"},
+		},
+		Response: kubernetes.SnapshotDiff{}},
+
+	"POST /handoffs": {Summary: "转交值班人员", Tag: "handoffs", Status: http.StatusCreated,
+		Request: handlers.HandoffRequest{}, Response: fields{"handoff": handoff.Bundle{}, "status": ""}},
+	"GET /handoffs/:id": {Summary: "查询转交记录", Tag: "handoffs",
+		Response: fields{"handoff": handoff.Bundle{}, "status": ""}},
+
+	"GET /perf/stats": {Summary: "性能统计", Tag: "system",
+		Query: []param{{Name: "resources_top"}, {Name: "resources_by", Description: "cpu 或 memory"}}},
+	"POST /perf/reset": {Summary: "重置性能统计", Tag: "system"},
+
+	"GET /apikeys": {Summary: "查询 API Key", Tag: "apikeys", Description: "API Key 管理接口仅管理员可用"},
+	"POST /apikeys": {Summary: "创建 API Key，明文只在创建时返回一次", Tag: "apikeys",
+		Request: handlers.CreateAPIKeyRequest{}},
+	"POST /apikeys/:id/rotate": {Summary: "轮换 API Key", Tag: "apikeys"},
+	"DELETE /apikeys/:id":      {Summary: "吊销 API Key", Tag: "apikeys"},
+
+	"GET /audit/interactions": {Summary: "分页查询审计记录", Tag: "audit", Query: auditQuery,
+		Response: fields{"interactions": []audit.Interaction{}, "next_cursor": "", "status": ""}},
+	"GET /audit/interactions/:id": {Summary: "查询单个交互及其事件时间线", Tag: "audit",
+		Response: fields{"interaction": audit.Interaction{}, "timeline": []audit.TimelineEntry{}, "status": ""}},
+	"GET /audit/interactions/:id/receipts": {Summary: "查询交互的工具执行回执并校验签名", Tag: "audit",
+		Response: fields{"receipts": []handlers.AuditReceipt{}, "verified": false, "status": ""}},
+
+	"GET /tools/quotas": {Summary: "工具配额使用情况", Tag: "tools",
+		Query: []param{{Name: "username", Description: "管理员可查询其他用户"}}},
+	"POST /tools/quotas/override": {Summary: "覆盖用户当天的工具配额", Tag: "tools",
+		Request: handlers.OverrideQuotaRequest{}},
+
+	"GET /prompts":             {Summary: "远程提示缓存状态", Tag: "prompts"},
+	"POST /prompts/invalidate": {Summary: "使远程提示缓存失效", Tag: "prompts", Request: handlers.InvalidatePromptsRequest{}},
+
+	"GET /admin/stats": {Summary: "服务内部状态", Tag: "admin"},
+	"GET /admin/aliases": {Summary: "查询服务别名", Tag: "admin",
+		Response: fields{"aliases": []knowledge.Alias{}, "status": ""}},
+	"POST /admin/aliases": {Summary: "添加服务别名", Tag: "admin",
+		Request: handlers.AddServiceAliasRequest{}},
+	"PUT /admin/aliases/:alias": {Summary: "修改服务别名", Tag: "admin",
+		Request: handlers.UpdateServiceAliasRequest{}},
+	"DELETE /admin/aliases/:alias": {Summary: "删除服务别名", Tag: "admin"},
+	"GET /admin/aliases/suggestions": {Summary: "从审计记录挖掘服务别名建议", Tag: "admin",
+		Query: []param{{Name: "days"}, {Name: "min_occurrences"}}},
+	"GET /admin/credentials": {Summary: "查询各 context 的凭据类型和过期时间", Tag: "admin",
+		Query:    []param{{Name: "refresh", Description: "为 true 时立即重新检查"}},
+		Response: fields{"credentials": []credentials.Status{}, "total": 0, "status": ""}},
+	"POST /admin/credentials/:context/rotate": {Summary: "立即执行 context 配置的凭据刷新钩子", Tag: "admin",
+		Response: fields{"credential": credentials.Status{}, "status": ""}},
+	"POST /admin/evaluations": {Summary: "在基准模型和候选模型上并行执行同一问题，返回工具选择、迭代次数和回答的对比", Tag: "admin",
+		Request: handlers.EvaluationRequest{},
+		Response: fields{"evaluation_id": "", "question": "", "baseline": evaluation.Run{}, "candidate": evaluation.Run{},
+			"comparison": evaluation.Comparison{}, "stored": false, "status": ""}},
+	"GET /admin/evaluations": {Summary: "按时间倒序列出评测记录", Tag: "admin",
+		Query: []param{
+			{Name: "model", Description: "按基准或候选模型过滤，指定了服务商时为 provider/model"},
+			{Name: "limit", Description: "返回条数，默认 50，最大 500"},
+		},
+		Response: fields{"evaluations": []audit.Evaluation{}, "total": 0, "status": ""}},
+	"GET /admin/evaluations/:id": {Summary: "获取评测记录，两次执行的工具输出可按 interaction_id 查询审计记录", Tag: "admin",
+		Response: fields{"evaluation": audit.Evaluation{}, "status": ""}},
+	"GET /admin/config-repo": {Summary: "查询配置仓库当前生效的提交和最近一次同步结果", Tag: "admin",
+		Response: fields{"repo": configrepo.Status{}, "status": ""}},
+	"POST /admin/config-repo/sync": {Summary: "立即拉取配置仓库并应用集群登记、服务登记和提示的变更", Tag: "admin",
+		Response: fields{"repo": configrepo.Status{}, "status": ""}},
+	"POST /admin/audit/backfill": {Summary: "补齐历史交互的工具调用耗时、观察结果 token 数和 token 用量，只处理尚未补齐的交互", Tag: "admin",
+		Query:    []param{{Name: "batch_size", Description: "每批处理的交互数，默认 audit.backfill.batch_size 或 200"}},
+		Response: fields{"result": audit.BackfillResult{}, "status": ""}},
+}
+
+// OpenAPISpec 根据已注册的 /api/v2 路由生成 OpenAPI 3 文档
+// 路由以 gin 的注册结果为准，operations 中缺少说明的路由同样会列出，只是没有请求体和响应结构
+func OpenAPISpec(routes gin.RoutesInfo) map[string]interface{} {
+	gen := &schemaGenerator{components: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, openAPIBasePath+"/") {
+			continue
+		}
+		path := strings.TrimPrefix(route.Path, openAPIBasePath)
+		op, ok := operations[route.Method+" "+path]
+		if !ok {
+			op = operation{Summary: "未补充说明的接口", Tag: "undocumented"}
+		}
+
+		openPath, pathParams := openAPIPath(path)
+		if paths[openPath] == nil {
+			paths[openPath] = map[string]interface{}{}
+		}
+		paths[openPath][strings.ToLower(route.Method)] = gen.operation(route, op, pathParams)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "OpsAgent API",
+			"version":     handlers.VERSION,
+			"description": "响应统一为 {code, message, data, request_id}，code 为 0 表示成功，否则为 HTTP 状态码",
+		},
+		"servers": []map[string]string{{"url": openAPIBasePath}},
+		"paths":   paths,
+		"security": []map[string][]string{
+			{"bearerAuth": {}},
+			{"apiKey": {}},
+		},
+		"components": map[string]interface{}{
+			"schemas": gen.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+	}
+}
+
+// openAPIPath 将 gin 的 :name 参数转换为 {name}
+func openAPIPath(path string) (string, []string) {
+	segments := strings.Split(path, "/")
+	var params []string
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			params = append(params, segment[1:])
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// OpenAPIHandler 返回 OpenAPI 文档，文档在第一次请求时根据路由生成
+func OpenAPIHandler(r *gin.Engine) gin.HandlerFunc {
+	var (
+		once sync.Once
+		spec []byte
+		err  error
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			spec, err = json.Marshal(OpenAPISpec(r.Routes()))
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+	}
+}
+
+var swaggerUITemplate = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+  <meta charset="utf-8">
+  <title>OpsAgent API</title>
+  <link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`))
+
+// SwaggerUI 返回加载 OpenAPI 文档的 Swagger UI 页面
+// 页面资源默认从公共 CDN 加载，内网环境通过配置项 openapi.swagger_ui_assets 指向内部镜像
+func SwaggerUI(c *gin.Context) {
+	assets := utils.GetConfig().GetString("openapi.swagger_ui_assets")
+	if assets == "" {
+		assets = defaultSwaggerUIAssets
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	swaggerUITemplate.Execute(c.Writer, map[string]string{
+		"Assets":  strings.TrimRight(assets, "/"),
+		"SpecURL": "/api/openapi.json",
+	})
+}
+
+// schemaGenerator 由 Go 类型生成 JSON Schema，命名结构体放入 components 并通过 $ref 引用
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+func (g *schemaGenerator) operation(route gin.RouteInfo, op operation, pathParams []string) map[string]interface{} {
+	result := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(route),
+		"tags":        []string{op.Tag},
+	}
+	if op.Description != "" {
+		result["description"] = op.Description
+	}
+	if op.Public {
+		result["security"] = []interface{}{}
+	}
+
+	var parameters []map[string]interface{}
+	for _, name := range pathParams {
+		parameters = append(parameters, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+		})
+	}
+	for _, p := range op.Query {
+		parameter := map[string]interface{}{
+			"name": p.Name, "in": "query", "required": p.Required, "schema": map[string]string{"type": "string"},
+		}
+		if p.Description != "" {
+			parameter["description"] = p.Description
+		}
+		parameters = append(parameters, parameter)
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Request))},
+			},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := map[string]interface{}{}
+	if status == http.StatusSwitchingProtocols {
+		responses["101"] = map[string]string{"description": "升级为 WebSocket"}
+	} else {
+		responses[fmt.Sprint(status)] = map[string]interface{}{
+			"description": "成功",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.envelope(g.responseSchema(op.Response))},
+			},
+		}
+	}
+	responses["default"] = map[string]interface{}{
+		"description": "失败，message 为错误说明，data.error_code 为机器可读的错误码（例如 llm_throttled、command_denied）",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": g.envelope(map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"error_code": map[string]string{"type": "string"}},
+			})},
+		},
+	}
+	result["responses"] = responses
+	return result
+}
+
+// operationID 使用处理器函数名，例如 ListAuditInteractions
+func operationID(route gin.RouteInfo) string {
+	name := route.Handler
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, "-fm")
+}
+
+func (g *schemaGenerator) envelope(data interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"code", "message", "data", "request_id"},
+		"properties": map[string]interface{}{
+			"code":       map[string]string{"type": "integer"},
+			"message":    map[string]string{"type": "string"},
+			"data":       data,
+			"request_id": map[string]string{"type": "string"},
+		},
+	}
+}
+
+func (g *schemaGenerator) responseSchema(response interface{}) interface{} {
+	switch r := response.(type) {
+	case nil:
+		return map[string]interface{}{"type": "object"}
+	case fields:
+		properties := map[string]interface{}{}
+		for name, value := range r {
+			properties[name] = g.schema(reflect.TypeOf(value))
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	default:
+		return g.schema(reflect.TypeOf(response))
+	}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schema 生成类型的 JSON Schema
+func (g *schemaGenerator) schema(t reflect.Type) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]string{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]string{"type": "string"}
+	case reflect.Bool:
+		return map[string]string{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if t.PkgPath() == "time" && t.Name() == "Duration" {
+			return map[string]string{"type": "integer", "description": "纳秒"}
+		}
+		return map[string]string{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]string{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]string{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := g.components[name]; !ok {
+			// 先占位，避免自引用的类型无限递归
+			g.components[name] = map[string]interface{}{}
+			g.components[name] = g.structSchema(t)
+		}
+		return map[string]string{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// componentName 使用包名加类型名，避免 handlers.ExecuteRequest 与 client.ExecuteRequest 冲突
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + t.Name()
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	g.collectFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields 按 encoding/json 的规则收集字段，匿名嵌入的结构体字段展开到外层
+func (g *schemaGenerator) collectFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.collectFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	r := Router()
+	for _, route := range r.Routes() {
+		if !strings.HasPrefix(route.Path, openAPIBasePath+"/") {
+			continue
+		}
+		key := route.Method + " " + strings.TrimPrefix(route.Path, openAPIBasePath)
+		if _, ok := operations[key]; !ok {
+			t.Errorf("route %s has no OpenAPI description", key)
+		}
+	}
+	for key := range operations {
+		method, path, _ := strings.Cut(key, " ")
+		found := false
+		for _, route := range r.Routes() {
+			if route.Method == method && route.Path == openAPIBasePath+path {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("OpenAPI description %s has no registered route", key)
+		}
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := Router()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string               `json:"required"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("invalid spec: %v", err)
+	}
+	if spec.OpenAPI != "3.0.3" {
+		t.Errorf("openapi = %q", spec.OpenAPI)
+	}
+
+	execute := spec.Paths["/execute"]["post"]
+	if execute["operationId"] != "Execute" || execute["requestBody"] == nil {
+		t.Errorf("unexpected /execute operation: %v", execute)
+	}
+	if _, ok := spec.Paths["/audit/interactions/{id}"]["get"]; !ok {
+		t.Errorf("path parameters not converted: %v", keys(spec.Paths))
+	}
+
+	request := spec.Components.Schemas["handlers.ExecuteRequest"]
+	if strings.Join(request.Required, ",") != "args,instructions" {
+		t.Errorf("required = %v", request.Required)
+	}
+	if _, ok := request.Properties["noCache"]; !ok {
+		t.Errorf("properties = %v", request.Properties)
+	}
+}
+
+func keys(m map[string]map[string]map[string]interface{}) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
@@ -8,8 +8,10 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/apikeys"
 	"github.com/myysophia/OpsAgent/pkg/handlers"
 	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/users"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"go.uber.org/zap"
 )
@@ -28,13 +30,16 @@ func Router() *gin.Engine {
 	// 使用自定义中间件
 	r.Use(gin.Recovery())
 	r.Use(middleware.Logger())
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
+	r.Use(middleware.Metrics())
 
 	// 配置CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-OpenAI-Key", "X-API-Key", "X-Requested-With", "api-key"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-OpenAI-Key", "X-API-Key", "X-Requested-With", "api-key", "traceparent", "tracestate"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", "X-Request-ID", "Deprecation", "Sunset", "Link"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 		AllowWildcard:    true,
@@ -79,32 +84,139 @@ func Router() *gin.Engine {
 		c.Status(http.StatusNoContent)
 	})
 
-	r.POST("/login", handlers.Login)
+	r.POST("/login", middleware.Deprecated("/login", "/api/v2/login"), handlers.Login)
 
-	// 注册API路由
+	// Prometheus 指标
+	r.GET("/metrics", handlers.Metrics)
+
+	// 旧版接口：响应格式保持不变，响应头标记为已弃用，请迁移到 /api/v2
 	api := r.Group("/api")
-	{
-		// 版本信息
-		api.GET("/version", handlers.Version)
+	api.Use(middleware.Deprecated("/api", "/api/v2"))
+	registerAPIRoutes(api)
 
-		// 需要认证的路由
-		auth := api.Group("")
-		auth.Use(middleware.JWTAuth())
-		{
-			// 执行命令
-			auth.POST("/execute", handlers.Execute)
+	// v2 接口：与旧版接口相同，响应统一为 {code, message, data, request_id}
+	v2 := r.Group("/api/v2")
+	v2.Use(middleware.ResponseEnvelope())
+	v2.POST("/login", handlers.Login)
+	registerAPIRoutes(v2)
 
-			// 诊断
-			auth.POST("/diagnose", handlers.Diagnose)
+	// OpenAPI 文档和 Swagger UI，文档根据 /api/v2 路由生成
+	r.GET("/api/openapi.json", OpenAPIHandler(r))
+	r.GET("/api/docs", SwaggerUI)
 
-			// 分析
-			auth.POST("/analyze", handlers.Analyze)
+	return r
+}
 
-			// 性能统计
-			auth.GET("/perf/stats", handlers.PerfStats)
-			auth.POST("/perf/reset", handlers.ResetPerfStats)
-		}
+// registerAPIRoutes 注册 API 路由，旧版 /api 和 /api/v2 共用同一组处理器
+func registerAPIRoutes(group *gin.RouterGroup) {
+	// 版本信息
+	group.GET("/version", handlers.Version)
+
+	// 钉钉/企业微信审批卡片按钮回调，由链接签名和 IM 身份校验保护
+	group.GET("/approvals/callback", handlers.ApprovalCallback)
+	// apiserver webhook 审计后端，使用 kube_audit.webhook_token 认证
+	group.POST("/kube-audit/:cluster", handlers.KubeAuditWebhook)
+	// 配置仓库 push webhook，使用 config_repo.webhook_secret 校验签名
+	group.POST("/config-repo/webhook", handlers.ConfigRepoWebhook)
+
+	// 需要认证的路由
+	auth := group.Group("")
+	auth.Use(middleware.JWTAuth())
+	{
+		// 执行命令
+		auth.POST("/execute", middleware.APIKeyScope(apikeys.ScopeExecute), handlers.Execute)
+		auth.POST("/execute/stream", middleware.APIKeyScope(apikeys.ScopeExecute), handlers.ExecuteStream)
+
+		// 多轮对话（WebSocket），对话历史保存在服务端会话中
+		auth.GET("/ws/chat", middleware.APIKeyScope(apikeys.ScopeExecute), handlers.ChatWS)
+
+		// 诊断
+		auth.POST("/diagnose", middleware.APIKeyScope(apikeys.ScopeDiagnose), handlers.Diagnose)
+
+		// 分析
+		auth.POST("/analyze", handlers.Analyze)
+
+		// 生成清单的应用流水线
+		auth.POST("/generate", middleware.RequireRole(users.RoleOperator), handlers.Generate)
+		auth.POST("/generate/diff", middleware.RequireRole(users.RoleOperator), handlers.DiffManifest)
+		auth.POST("/generate/apply", middleware.RequireRole(users.RoleOperator), handlers.SubmitApply)
+		auth.GET("/generate/apply/:id", handlers.GetApply)
+		auth.POST("/generate/apply/:id/approve", middleware.AdminOnly(), handlers.ApproveApply)
+		auth.POST("/generate/apply/:id/reject", middleware.RequireRole(users.RoleOperator), handlers.RejectApply)
+		auth.GET("/generate/apply/:id/drift", handlers.GetApplyDrift)
+		auth.GET("/generate/drift", handlers.ListDrift)
+
+		// 变更命令审批：审批后执行命令并继续暂停的对话
+		auth.GET("/approvals", handlers.ListApprovals)
+		auth.GET("/approvals/:id", handlers.GetApproval)
+		auth.POST("/approvals/:id/approve", middleware.AdminOnly(), handlers.ApproveApproval)
+		auth.POST("/approvals/:id/reject", middleware.AdminOnly(), handlers.RejectApproval)
+
+		// 集群登记：名称、context、默认命名空间、别名和 kubeconfig 文件
+		auth.GET("/clusters", handlers.ListClusters)
+		auth.GET("/clusters/:name", handlers.GetCluster)
+		auth.POST("/clusters", middleware.AdminOnly(), handlers.CreateCluster)
+		auth.PUT("/clusters/:name", middleware.AdminOnly(), handlers.UpdateCluster)
+		auth.DELETE("/clusters/:name", middleware.AdminOnly(), handlers.DeleteCluster)
+
+		// 跨集群搜索：在各集群的资源清单缓存中按名称查找资源
+		auth.GET("/search", handlers.Search)
+
+		// 集群快照：命名空间内工作负载、镜像、资源配置和 ConfigMap 哈希的结构化快照及两个时刻之间的变化
+		auth.GET("/snapshot", handlers.GetSnapshot)
+		auth.GET("/snapshot/diff", handlers.DiffSnapshot)
+
+		// 转交值班人员：打包交互上下文并发送到值班渠道
+		auth.POST("/handoffs", handlers.CreateHandoff)
+		auth.GET("/handoffs/:id", handlers.GetHandoff)
+
+		// 性能统计
+		auth.GET("/perf/stats", handlers.PerfStats)
+		auth.POST("/perf/reset", middleware.AdminOnly(), handlers.ResetPerfStats)
+
+		// API Key 管理：密钥可以绑定任意租户和权限范围，仅管理员可以管理
+		auth.GET("/apikeys", middleware.AdminOnly(), handlers.ListAPIKeys)
+		auth.POST("/apikeys", middleware.AdminOnly(), handlers.CreateAPIKey)
+		auth.POST("/apikeys/:id/rotate", middleware.AdminOnly(), handlers.RotateAPIKey)
+		auth.DELETE("/apikeys/:id", middleware.AdminOnly(), handlers.RevokeAPIKey)
+
+		// 审计查询
+		auth.GET("/audit/interactions", middleware.APIKeyScope(apikeys.ScopeReadAudit), handlers.ListAuditInteractions)
+		auth.GET("/audit/interactions/:id", middleware.APIKeyScope(apikeys.ScopeReadAudit), handlers.GetAuditInteraction)
+		auth.GET("/audit/interactions/:id/receipts", middleware.APIKeyScope(apikeys.ScopeReadAudit), handlers.GetAuditReceipts)
+
+		// 工具配额
+		auth.GET("/tools/quotas", handlers.GetToolQuotas)
+		auth.POST("/tools/quotas/override", middleware.AdminOnly(), handlers.OverrideToolQuota)
+
+		// 远程提示缓存
+		auth.GET("/prompts", handlers.ListPrompts)
+		auth.POST("/prompts/invalidate", middleware.AdminOnly(), handlers.InvalidatePrompts)
+
+		// 服务内部状态
+		auth.GET("/admin/stats", middleware.AdminOnly(), handlers.AdminStats)
+
+		// 服务别名：从审计记录挖掘别名建议，确认后添加到服务登记
+		auth.GET("/admin/aliases", middleware.AdminOnly(), handlers.ListServiceAliases)
+		auth.POST("/admin/aliases", middleware.AdminOnly(), handlers.AddServiceAlias)
+		auth.PUT("/admin/aliases/:alias", middleware.AdminOnly(), handlers.UpdateServiceAlias)
+		auth.DELETE("/admin/aliases/:alias", middleware.AdminOnly(), handlers.DeleteServiceAlias)
+		auth.GET("/admin/aliases/suggestions", middleware.AdminOnly(), handlers.SuggestServiceAliases)
+
+		// 集群凭据有效期和刷新
+		auth.GET("/admin/credentials", middleware.AdminOnly(), handlers.ListCredentials)
+		auth.POST("/admin/credentials/:context/rotate", middleware.AdminOnly(), handlers.RotateCredentials)
+
+		// 模型评测：同一问题在两个模型上执行并对比，用于模型迁移决策
+		auth.POST("/admin/evaluations", middleware.AdminOnly(), handlers.CreateEvaluation)
+		auth.GET("/admin/evaluations", middleware.AdminOnly(), handlers.ListEvaluations)
+		auth.GET("/admin/evaluations/:id", middleware.AdminOnly(), handlers.GetEvaluation)
+
+		// Git 配置仓库同步状态
+		auth.GET("/admin/config-repo", middleware.AdminOnly(), handlers.GetConfigRepo)
+		auth.POST("/admin/config-repo/sync", middleware.AdminOnly(), handlers.SyncConfigRepo)
+
+		// 补齐历史审计记录的用量
+		auth.POST("/admin/audit/backfill", middleware.AdminOnly(), handlers.BackfillAuditUsage)
 	}
-
-	return r
 }
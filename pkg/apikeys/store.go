@@ -0,0 +1,315 @@
+package apikeys
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// Scope API Key 的权限范围
+type Scope string
+
+const (
+	// ScopeExecute 允许调用 /api/execute
+	ScopeExecute Scope = "execute"
+	// ScopeDiagnose 允许调用 /api/diagnose
+	ScopeDiagnose Scope = "diagnose"
+	// ScopeReadAudit 允许读取审计记录
+	ScopeReadAudit Scope = "read-audit"
+	// ScopeAll 允许访问所有接口
+	ScopeAll Scope = "*"
+)
+
+// keyPrefix 托管 API Key 的统一前缀，便于识别和日志脱敏
+const keyPrefix = "oak"
+
+// lastUsedSaveInterval 最后使用时间写入文件的最小间隔，避免每个请求都写存储文件
+const lastUsedSaveInterval = time.Minute
+
+// ValidScopes 所有合法的权限范围
+var ValidScopes = []Scope{ScopeExecute, ScopeDiagnose, ScopeReadAudit, ScopeAll}
+
+// APIKey 托管的 API Key 元数据，密钥本身只保存哈希，哈希不会出现在 API 响应中
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Tenant     string     `json:"tenant,omitempty"`
+	Hash       string     `json:"-"`
+	Scopes     []Scope    `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // 精确到 lastUsedSaveInterval
+}
+
+// HasScope 判断 API Key 是否拥有指定权限
+func (k *APIKey) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// storedKey 存储文件中的 API Key，与 API 响应不同，包含密钥哈希
+type storedKey struct {
+	*APIKey
+	Hash string `json:"hash"`
+}
+
+// Store API Key 存储，持久化为 JSON 文件
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	keys map[string]*APIKey
+}
+
+var (
+	globalStore *Store
+	storeOnce   sync.Once
+)
+
+// Enabled 是否启用托管 API Key
+// 未启用时沿用旧模式：X-API-Key 直接作为 LLM 密钥转发
+func Enabled() bool {
+	return utils.GetConfig().GetBool("apikeys.enabled")
+}
+
+// GetStore 获取全局 API Key 存储
+func GetStore() *Store {
+	storeOnce.Do(func() {
+		path := utils.GetConfig().GetString("apikeys.file")
+		if path == "" {
+			path = filepath.Join("data", "apikeys.json")
+		}
+
+		store, err := NewStore(path)
+		if err != nil {
+			utils.Error(fmt.Sprintf("加载 API Key 存储失败: %v", err))
+			store = &Store{path: path, keys: map[string]*APIKey{}}
+		}
+		globalStore = store
+	})
+	return globalStore
+}
+
+// SetStore 使 GetStore 返回指定的存储而不是 apikeys.file 对应的文件，例如测试中的临时文件；
+// nil 恢复默认行为，下次调用 GetStore 时重新加载
+func SetStore(store *Store) {
+	if store == nil {
+		storeOnce = sync.Once{}
+		globalStore = nil
+		return
+	}
+	storeOnce.Do(func() {})
+	globalStore = store
+}
+
+// NewStore 从文件创建 API Key 存储，文件不存在时创建空存储
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path, keys: map[string]*APIKey{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	var keys []storedKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("解析 API Key 文件失败: %v", err)
+	}
+	for _, k := range keys {
+		if k.APIKey == nil {
+			continue
+		}
+		k.APIKey.Hash = k.Hash
+		s.keys[k.ID] = k.APIKey
+	}
+	return s, nil
+}
+
+// Create 创建新的 API Key，返回明文密钥（仅此一次可见）
+// tenant 为空时，使用该密钥的请求按 JWT 用户名匹配租户策略
+func (s *Store) Create(name, tenant string, scopes []Scope) (string, *APIKey, error) {
+	if err := validateScopes(scopes); err != nil {
+		return "", nil, err
+	}
+
+	id, err := randomHex(6)
+	if err != nil {
+		return "", nil, err
+	}
+	raw, hash, err := newSecret(id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Tenant:    tenant,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[id] = key
+	if err := s.save(); err != nil {
+		delete(s.keys, id)
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Rotate 轮换 API Key 的密钥，旧密钥立即失效
+func (s *Store) Rotate(id string) (string, *APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return "", nil, fmt.Errorf("API Key %s 不存在", id)
+	}
+
+	raw, hash, err := newSecret(id)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	key.Hash = hash
+	key.RotatedAt = &now
+	if err := s.save(); err != nil {
+		return "", nil, err
+	}
+	return raw, key, nil
+}
+
+// Revoke 删除 API Key
+func (s *Store) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[id]; !ok {
+		return fmt.Errorf("API Key %s 不存在", id)
+	}
+	delete(s.keys, id)
+	return s.save()
+}
+
+// List 按创建时间返回所有 API Key
+func (s *Store) List() []APIKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, *k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+// Authenticate 校验明文密钥并更新最后使用时间
+// 最后使用时间距上次写入超过 lastUsedSaveInterval 时才写入文件
+func (s *Store) Authenticate(raw string) (*APIKey, bool) {
+	parts := strings.SplitN(raw, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[parts[1]]
+	if !ok || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(raw))) != 1 {
+		return nil, false
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedSaveInterval {
+		key.LastUsedAt = &now
+		// 最后使用时间仅用于展示，写入失败不影响认证
+		_ = s.save()
+	}
+
+	copied := *key
+	return &copied, true
+}
+
+// save 将存储写入文件，调用方需持有写锁
+func (s *Store) save() error {
+	keys := make([]storedKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, storedKey{APIKey: k, Hash: k.Hash})
+	}
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// validateScopes 校验权限范围是否合法
+func validateScopes(scopes []Scope) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("至少需要一个权限范围")
+	}
+	for _, scope := range scopes {
+		valid := false
+		for _, v := range ValidScopes {
+			if scope == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("未知的权限范围: %s", scope)
+		}
+	}
+	return nil
+}
+
+// newSecret 生成明文密钥及其哈希
+func newSecret(id string) (string, string, error) {
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", "", err
+	}
+	raw := fmt.Sprintf("%s_%s_%s", keyPrefix, id, secret)
+	return raw, hashSecret(raw), nil
+}
+
+func hashSecret(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
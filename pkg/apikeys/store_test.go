@@ -0,0 +1,79 @@
+package apikeys
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := store.Create("ci", "", nil); err == nil {
+		t.Error("expected error without scopes")
+	}
+	if _, _, err := store.Create("ci", "", []Scope{"admin"}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+
+	raw, key, err := store.Create("ci", "ops", []Scope{ScopeReadAudit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := store.Authenticate(raw)
+	if !ok || got.ID != key.ID || got.Tenant != "ops" || got.LastUsedAt == nil {
+		t.Fatalf("Authenticate() = %+v, %v", got, ok)
+	}
+	if !got.HasScope(ScopeReadAudit) || got.HasScope(ScopeExecute) {
+		t.Errorf("unexpected scopes: %v", got.Scopes)
+	}
+	if _, ok := store.Authenticate(raw + "x"); ok {
+		t.Error("expected a modified key to be rejected")
+	}
+
+	// 最后使用时间在间隔内不重复写入文件
+	info, _ := os.Stat(path)
+	os.Chtimes(path, info.ModTime().Add(-time.Hour), info.ModTime().Add(-time.Hour))
+	store.Authenticate(raw)
+	if after, _ := os.Stat(path); !after.ModTime().Equal(info.ModTime().Add(-time.Hour)) {
+		t.Error("store written again within the last-used interval")
+	}
+
+	rotated, _, err := store.Rotate(key.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Authenticate(raw); ok {
+		t.Error("expected the old key to be rejected after rotation")
+	}
+	if _, ok := store.Authenticate(rotated); !ok {
+		t.Error("expected the rotated key to be accepted")
+	}
+
+	// 重新加载后密钥仍然有效
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Authenticate(rotated); !ok || len(reloaded.List()) != 1 {
+		t.Error("expected the rotated key after reload")
+	}
+
+	if err := store.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Authenticate(rotated); ok {
+		t.Error("expected the revoked key to be rejected")
+	}
+	if err := store.Revoke(key.ID); err == nil {
+		t.Error("expected error revoking a missing key")
+	}
+	if _, _, err := store.Rotate(key.ID); err == nil {
+		t.Error("expected error rotating a missing key")
+	}
+}
@@ -0,0 +1,223 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 审批状态
+const (
+	StatusPending  = "pending"  // 等待审批，对话暂停
+	StatusApproved = "approved" // 审批通过，正在执行命令并继续对话
+	StatusRejected = "rejected" // 审批拒绝，命令未执行
+	StatusExecuted = "executed" // 命令已执行，对话已继续完成
+	StatusFailed   = "failed"   // 审批后继续对话失败
+	StatusExpired  = "expired"  // 超过有效期未审批
+)
+
+const (
+	defaultTTL       = 24 * time.Hour
+	defaultListLimit = 100
+)
+
+// ErrNotFound 审批不存在
+var ErrNotFound = audit.ErrApprovalNotFound
+
+// store 审批存储，启用审计时保存在审计数据库，否则只保存在内存中
+type store interface {
+	SaveApproval(ctx context.Context, a *audit.Approval) error
+	TransitionApproval(ctx context.Context, a *audit.Approval, from string) error
+	GetApproval(ctx context.Context, id string) (*audit.Approval, error)
+	ListApprovals(ctx context.Context, status, username string, limit int) ([]*audit.Approval, error)
+}
+
+// Manager 管理变更命令的审批生命周期：创建 → 审批/拒绝/过期 → 执行完成
+type Manager struct {
+	memory *memoryStore
+	now    func() time.Time
+}
+
+var (
+	manager     *Manager
+	managerOnce sync.Once
+)
+
+// Default 获取全局审批管理器
+func Default() *Manager {
+	managerOnce.Do(func() {
+		manager = &Manager{memory: newMemoryStore(), now: time.Now}
+	})
+	return manager
+}
+
+func (m *Manager) store() store {
+	if s := audit.GetStore(); s != nil {
+		return s
+	}
+	return m.memory
+}
+
+// ttl 审批有效期，配置项 approvals.ttl，默认 24h
+func ttl() time.Duration {
+	if d := utils.GetConfig().GetDuration("approvals.ttl"); d > 0 {
+		return d
+	}
+	return defaultTTL
+}
+
+// Create 创建待审批记录
+func (m *Manager) Create(ctx context.Context, a *audit.Approval) error {
+	now := m.now()
+	a.ID = audit.NewInteractionID()
+	a.Status = StatusPending
+	a.CreatedAt, a.UpdatedAt = now, now
+	if err := m.store().SaveApproval(ctx, a); err != nil {
+		return fmt.Errorf("保存审批记录失败: %v", err)
+	}
+	utils.LoggerFromContext(ctx).Info("创建变更命令审批",
+		zap.String("approval_id", a.ID),
+		zap.String("tool", a.Tool),
+		zap.String("verb", a.Verb),
+		zap.String("input", a.Input),
+		zap.String("requested_by", a.Username),
+	)
+	return nil
+}
+
+// Get 获取审批记录
+func (m *Manager) Get(ctx context.Context, id string) (*audit.Approval, error) {
+	return m.store().GetApproval(ctx, id)
+}
+
+// List 列出审批记录，status、username 为空时不过滤
+func (m *Manager) List(ctx context.Context, status, username string) ([]*audit.Approval, error) {
+	return m.store().ListApprovals(ctx, status, username, defaultListLimit)
+}
+
+// Review 审批或拒绝待审批的命令
+// 只有 pending 状态可以审批；超过有效期的记录标记为 expired；
+// 配置 approvals.require_distinct_reviewer 时审批人不能是申请人
+func (m *Manager) Review(ctx context.Context, id, reviewer string, approve bool) (*audit.Approval, error) {
+	a, err := m.store().GetApproval(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != StatusPending {
+		return nil, fmt.Errorf("审批 %s 当前状态为 %s，无法审批", id, a.Status)
+	}
+	if utils.GetConfig().GetBool("approvals.require_distinct_reviewer") && reviewer == a.Username {
+		return nil, fmt.Errorf("审批人不能与申请人相同")
+	}
+
+	now := m.now()
+	a.ReviewedBy, a.ReviewedAt, a.UpdatedAt = reviewer, &now, now
+	switch {
+	case now.Sub(a.CreatedAt) > ttl():
+		a.Status = StatusExpired
+	case approve:
+		a.Status = StatusApproved
+	default:
+		a.Status = StatusRejected
+	}
+	if err := m.store().TransitionApproval(ctx, a, StatusPending); err != nil {
+		if errors.Is(err, audit.ErrApprovalConflict) {
+			return nil, fmt.Errorf("审批 %s 已被其他人处理", id)
+		}
+		return nil, fmt.Errorf("更新审批记录失败: %v", err)
+	}
+	if a.Status == StatusExpired {
+		return nil, fmt.Errorf("审批 %s 已超过有效期，请重新提问", id)
+	}
+
+	utils.LoggerFromContext(ctx).Info("变更命令审批完成",
+		zap.String("approval_id", a.ID),
+		zap.String("status", a.Status),
+		zap.String("input", a.Input),
+		zap.String("requested_by", a.Username),
+		zap.String("reviewer", reviewer),
+	)
+	return a, nil
+}
+
+// Complete 记录审批后继续对话的结果
+func (m *Manager) Complete(ctx context.Context, a *audit.Approval, status, output, answer string, err error) {
+	a.Status, a.Output, a.Answer, a.UpdatedAt = status, output, answer, m.now()
+	if err != nil {
+		a.Error = err.Error()
+	}
+	if saveErr := m.store().SaveApproval(ctx, a); saveErr != nil {
+		utils.LoggerFromContext(ctx).Error("保存审批结果失败",
+			zap.String("approval_id", a.ID),
+			zap.Error(saveErr),
+		)
+	}
+}
+
+// memoryStore 未启用审计数据库时的审批存储，服务重启后丢失
+type memoryStore struct {
+	mu        sync.Mutex
+	approvals map[string]*audit.Approval
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{approvals: map[string]*audit.Approval{}}
+}
+
+func (s *memoryStore) SaveApproval(ctx context.Context, a *audit.Approval) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *a
+	s.approvals[a.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) TransitionApproval(ctx context.Context, a *audit.Approval, from string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.approvals[a.ID]
+	if !ok {
+		return audit.ErrApprovalNotFound
+	}
+	if current.Status != from {
+		return audit.ErrApprovalConflict
+	}
+	copied := *a
+	s.approvals[a.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) GetApproval(ctx context.Context, id string) (*audit.Approval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.approvals[id]
+	if !ok {
+		return nil, audit.ErrApprovalNotFound
+	}
+	copied := *a
+	return &copied, nil
+}
+
+func (s *memoryStore) ListApprovals(ctx context.Context, status, username string, limit int) ([]*audit.Approval, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var approvals []*audit.Approval
+	for _, a := range s.approvals {
+		if (status == "" || a.Status == status) && (username == "" || a.Username == username) {
+			copied := *a
+			approvals = append(approvals, &copied)
+		}
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].CreatedAt.After(approvals[j].CreatedAt) })
+	if len(approvals) > limit {
+		approvals = approvals[:limit]
+	}
+	return approvals, nil
+}
@@ -0,0 +1,55 @@
+package approvals
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestReviewLifecycle(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	m := &Manager{memory: newMemoryStore(), now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	utils.GetConfig().Set("approvals.require_distinct_reviewer", true)
+	defer utils.GetConfig().Set("approvals.require_distinct_reviewer", nil)
+
+	a := &audit.Approval{Username: "alice", Tool: "kubectl", Input: "kubectl scale deployment/payment-api --replicas=3", Verb: "scale"}
+	if err := m.Create(ctx, a); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := m.Review(ctx, a.ID, "alice", true); err == nil {
+		t.Fatal("expected requester to be rejected as reviewer")
+	}
+
+	reviewed, err := m.Review(ctx, a.ID, "bob", true)
+	if err != nil || reviewed.Status != StatusApproved || reviewed.ReviewedBy != "bob" {
+		t.Fatalf("Review() = %+v, %v, want approved by bob", reviewed, err)
+	}
+	if _, err := m.Review(ctx, a.ID, "carol", false); err == nil {
+		t.Fatal("expected second review to fail")
+	}
+
+	m.Complete(ctx, reviewed, StatusExecuted, "deployment.apps/payment-api scaled", "done", nil)
+	if got, _ := m.Get(ctx, a.ID); got.Status != StatusExecuted || got.Output == "" {
+		t.Errorf("Get() after Complete = %+v", got)
+	}
+
+	expired := &audit.Approval{Username: "alice", Tool: "kubectl", Input: "kubectl delete pod payment-api-7d9f", Verb: "delete"}
+	if err := m.Create(ctx, expired); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	now = now.Add(defaultTTL + time.Minute)
+	if _, err := m.Review(ctx, expired.ID, "bob", true); err == nil {
+		t.Fatal("expected expired approval to fail review")
+	}
+	if got, _ := m.Get(ctx, expired.ID); got.Status != StatusExpired {
+		t.Errorf("status = %s, want %s", got.Status, StatusExpired)
+	}
+	if list, _ := m.List(ctx, StatusExpired, "alice"); len(list) != 1 {
+		t.Errorf("List(expired) returned %d approvals, want 1", len(list))
+	}
+}
@@ -0,0 +1,309 @@
+package approvals
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// 审批卡片支持的 IM 平台
+const (
+	PlatformDingTalk = "dingtalk"
+	PlatformWeCom    = "wecom"
+)
+
+// 卡片按钮对应的操作
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+)
+
+// CallbackPath 审批卡片按钮的回调路径，不经过 JWT 认证，由链接签名和 IM 身份校验保护
+const CallbackPath = "/api/v2/approvals/callback"
+
+// IM 开放平台接口地址，测试时替换
+var (
+	dingTalkAPI = "https://api.dingtalk.com"
+	weComAPI    = "https://qyapi.weixin.qq.com"
+)
+
+var (
+	imClient     *http.Client
+	imClientOnce sync.Once
+)
+
+func imHTTPClient() *http.Client {
+	imClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("approvals_im")
+		if err != nil {
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		imClient = client
+	})
+	return imClient
+}
+
+// IMEnabled 是否通过钉钉/企业微信卡片发送审批，需配置 approvals.im.platform、base_url 和 secret
+func IMEnabled() bool {
+	config := utils.GetConfig()
+	return imPlatform() != "" && config.GetString("approvals.im.base_url") != "" &&
+		config.GetString("approvals.im.secret") != ""
+}
+
+func imPlatform() string {
+	return strings.ToLower(utils.GetConfig().GetString("approvals.im.platform"))
+}
+
+// SignAction 生成卡片按钮链接的签名令牌，绑定审批 ID、操作和过期时间
+func SignAction(id, action string, expires time.Time) string {
+	payload := strings.Join([]string{id, action, strconv.FormatInt(expires.Unix(), 10)}, ".")
+	return payload + "." + actionSignature(payload)
+}
+
+// VerifyAction 校验按钮链接的签名令牌，返回审批 ID 以及是否为批准操作
+func VerifyAction(token string) (string, bool, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return "", false, errors.New("审批链接格式无效")
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(actionSignature(payload))) {
+		return "", false, errors.New("审批链接签名无效")
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return "", false, errors.New("审批链接已过期")
+	}
+	switch parts[1] {
+	case ActionApprove:
+		return parts[0], true, nil
+	case ActionReject:
+		return parts[0], false, nil
+	default:
+		return "", false, fmt.Errorf("不支持的审批操作 %q", parts[1])
+	}
+}
+
+func actionSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(utils.GetConfig().GetString("approvals.im.secret")))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// ActionURL 生成卡片按钮的链接：先跳转到 IM 平台的 OAuth 授权页（内置浏览器中免登），
+// 授权后携带授权码回调 CallbackPath，以识别点击按钮的审批人
+func ActionURL(id, action string, expires time.Time) (string, error) {
+	config := utils.GetConfig()
+	redirect := strings.TrimRight(config.GetString("approvals.im.base_url"), "/") + CallbackPath
+	state := SignAction(id, action, expires)
+	clientID := config.GetString("approvals.im.client_id")
+
+	switch imPlatform() {
+	case PlatformDingTalk:
+		query := url.Values{
+			"redirect_uri":  {redirect},
+			"response_type": {"code"},
+			"client_id":     {clientID},
+			"scope":         {"openid"},
+			"state":         {state},
+			"prompt":        {"consent"},
+		}
+		return "https://login.dingtalk.com/oauth2/auth?" + query.Encode(), nil
+	case PlatformWeCom:
+		query := url.Values{
+			"appid":         {clientID},
+			"redirect_uri":  {redirect},
+			"response_type": {"code"},
+			"scope":         {"snsapi_base"},
+			"state":         {state},
+			"agentid":       {config.GetString("approvals.im.agent_id")},
+		}
+		return "https://open.weixin.qq.com/connect/oauth2/authorize?" + query.Encode() + "#wechat_redirect", nil
+	default:
+		return "", fmt.Errorf("不支持的审批 IM 平台 %q", imPlatform())
+	}
+}
+
+// SendCard 将审批以带批准/拒绝按钮的卡片发送到 approvals.im.channel 渠道（为空时发送到所有渠道）
+func SendCard(ctx context.Context, a *audit.Approval) error {
+	expires := a.CreatedAt.Add(ttl())
+	buttons := make([]notify.Button, 0, 2)
+	for _, b := range []struct{ label, action string }{{"批准", ActionApprove}, {"拒绝", ActionReject}} {
+		link, err := ActionURL(a.ID, b.action, expires)
+		if err != nil {
+			return err
+		}
+		buttons = append(buttons, notify.Button{Label: b.label, URL: link})
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "- 申请人：%s\n", a.Username)
+	if a.KubeContext != "" {
+		fmt.Fprintf(&text, "- 集群：%s\n", a.KubeContext)
+	}
+	fmt.Fprintf(&text, "- 命令：`%s`\n", a.Input)
+	fmt.Fprintf(&text, "- 问题：%s\n", a.Question)
+	fmt.Fprintf(&text, "- 审批 ID：%s，%s 前有效", a.ID, expires.Format("2006-01-02 15:04"))
+
+	config := utils.GetConfig()
+	return notify.Default().SendCard(ctx, config.GetString("approvals.im.channel"), notify.Card{
+		Title:   fmt.Sprintf("变更命令待审批：%s", a.Verb),
+		Text:    text.String(),
+		Level:   notify.LevelWarning,
+		URL:     config.GetString("approvals.im.base_url"),
+		Buttons: buttons,
+	})
+}
+
+// ResolveApprover 用 OAuth 授权码向 IM 平台查询点击按钮的用户，
+// 按 approvals.im.users（IM 用户 ID → OpsAgent 用户名）映射为审批人，未映射的用户不能审批
+func ResolveApprover(ctx context.Context, code string) (string, error) {
+	var imUser string
+	var err error
+	switch imPlatform() {
+	case PlatformDingTalk:
+		imUser, err = dingTalkUser(ctx, code)
+	case PlatformWeCom:
+		imUser, err = weComUser(ctx, code)
+	default:
+		err = fmt.Errorf("不支持的审批 IM 平台 %q", imPlatform())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// 配置键由 viper 统一转为小写
+	users := utils.GetConfig().GetStringMapString("approvals.im.users")
+	if username := users[strings.ToLower(imUser)]; username != "" {
+		return username, nil
+	}
+	return "", fmt.Errorf("IM 用户 %s 未映射到 OpsAgent 用户", imUser)
+}
+
+// dingTalkUser 用授权码换取用户 token 后查询当前用户的 unionId
+func dingTalkUser(ctx context.Context, code string) (string, error) {
+	config := utils.GetConfig()
+	var token struct {
+		AccessToken string `json:"accessToken"`
+		Message     string `json:"message"`
+	}
+	body, _ := json.Marshal(map[string]string{
+		"clientId":     config.GetString("approvals.im.client_id"),
+		"clientSecret": config.GetString("approvals.im.client_secret"),
+		"code":         code,
+		"grantType":    "authorization_code",
+	})
+	if err := imRequest(ctx, http.MethodPost, dingTalkAPI+"/v1.0/oauth2/userAccessToken", body, nil, &token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("钉钉授权失败: %s", token.Message)
+	}
+
+	var user struct {
+		UnionID string `json:"unionId"`
+	}
+	headers := map[string]string{"x-acs-dingtalk-access-token": token.AccessToken}
+	if err := imRequest(ctx, http.MethodGet, dingTalkAPI+"/v1.0/contact/users/me", nil, headers, &user); err != nil {
+		return "", err
+	}
+	if user.UnionID == "" {
+		return "", errors.New("钉钉未返回用户 unionId")
+	}
+	return user.UnionID, nil
+}
+
+// weComToken 企业微信应用 access_token 缓存，有效期 2 小时
+var weComToken struct {
+	sync.Mutex
+	value   string
+	expires time.Time
+}
+
+// weComUser 用授权码查询企业成员的 userid
+func weComUser(ctx context.Context, code string) (string, error) {
+	token, err := weComAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		UserID  string `json:"userid"`
+	}
+	query := url.Values{"access_token": {token}, "code": {code}}
+	if err := imRequest(ctx, http.MethodGet, weComAPI+"/cgi-bin/auth/getuserinfo?"+query.Encode(), nil, nil, &user); err != nil {
+		return "", err
+	}
+	if user.ErrCode != 0 || user.UserID == "" {
+		return "", fmt.Errorf("企业微信授权失败: %d %s", user.ErrCode, user.ErrMsg)
+	}
+	return user.UserID, nil
+}
+
+func weComAccessToken(ctx context.Context) (string, error) {
+	weComToken.Lock()
+	defer weComToken.Unlock()
+	if weComToken.value != "" && time.Now().Before(weComToken.expires) {
+		return weComToken.value, nil
+	}
+
+	config := utils.GetConfig()
+	var resp struct {
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	query := url.Values{
+		"corpid":     {config.GetString("approvals.im.client_id")},
+		"corpsecret": {config.GetString("approvals.im.client_secret")},
+	}
+	if err := imRequest(ctx, http.MethodGet, weComAPI+"/cgi-bin/gettoken?"+query.Encode(), nil, nil, &resp); err != nil {
+		return "", err
+	}
+	if resp.ErrCode != 0 || resp.AccessToken == "" {
+		return "", fmt.Errorf("获取企业微信 access_token 失败: %d %s", resp.ErrCode, resp.ErrMsg)
+	}
+	// 提前一分钟过期，避免使用即将失效的 token
+	weComToken.value = resp.AccessToken
+	weComToken.expires = time.Now().Add(time.Duration(resp.ExpiresIn)*time.Second - time.Minute)
+	return weComToken.value, nil
+}
+
+func imRequest(ctx context.Context, method, rawURL string, body []byte, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := imHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 IM 开放平台失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("请求 IM 开放平台失败: HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,87 @@
+package approvals
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestActionLinks(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("approvals.im.platform", "wecom")
+	config.Set("approvals.im.base_url", "https://opsagent.example.com/")
+	config.Set("approvals.im.secret", "s3cret")
+	config.Set("approvals.im.client_id", "ww123")
+	defer func() {
+		for _, key := range []string{"platform", "base_url", "secret", "client_id"} {
+			config.Set("approvals.im."+key, nil)
+		}
+	}()
+
+	link, err := ActionURL("a1b2c3", ActionReject, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ActionURL() error = %v", err)
+	}
+	parsed, _ := url.Parse(link)
+	if parsed.Host != "open.weixin.qq.com" || parsed.Query().Get("redirect_uri") != "https://opsagent.example.com"+CallbackPath {
+		t.Errorf("ActionURL() = %s", link)
+	}
+	if id, approve, err := VerifyAction(parsed.Query().Get("state")); err != nil || id != "a1b2c3" || approve {
+		t.Errorf("VerifyAction() = %q, %v, %v, want a1b2c3 reject", id, approve, err)
+	}
+
+	token := SignAction("a1b2c3", ActionReject, time.Now().Add(time.Hour))
+	for name, bad := range map[string]string{
+		"tampered action": strings.Replace(token, ActionReject, ActionApprove, 1),
+		"expired":         SignAction("a1b2c3", ActionApprove, time.Now().Add(-time.Minute)),
+		"malformed":       "a1b2c3.approve",
+	} {
+		if _, _, err := VerifyAction(bad); err == nil {
+			t.Errorf("VerifyAction(%s) expected error", name)
+		}
+	}
+}
+
+func TestResolveApproverWeCom(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/gettoken":
+			w.Write([]byte(`{"errcode":0,"access_token":"tok","expires_in":7200}`))
+		case "/cgi-bin/auth/getuserinfo":
+			switch r.URL.Query().Get("code") {
+			case "zhangsan-code":
+				w.Write([]byte(`{"errcode":0,"userid":"ZhangSan"}`))
+			case "lisi-code":
+				w.Write([]byte(`{"errcode":0,"userid":"lisi"}`))
+			default:
+				w.Write([]byte(`{"errcode":40029,"errmsg":"invalid code"}`))
+			}
+		}
+	}))
+	defer server.Close()
+	defer func(api string) { weComAPI = api }(weComAPI)
+	weComAPI = server.URL
+
+	config := utils.GetConfig()
+	config.Set("approvals.im.platform", "wecom")
+	config.Set("approvals.im.users", map[string]string{"zhangsan": "admin"})
+	defer config.Set("approvals.im.platform", nil)
+	defer config.Set("approvals.im.users", nil)
+
+	ctx := context.Background()
+	if user, err := ResolveApprover(ctx, "zhangsan-code"); err != nil || user != "admin" {
+		t.Errorf("ResolveApprover() = %q, %v, want admin", user, err)
+	}
+	if _, err := ResolveApprover(ctx, "lisi-code"); err == nil {
+		t.Error("expected unmapped user to be rejected")
+	}
+	if _, err := ResolveApprover(ctx, "forged"); err == nil {
+		t.Error("expected invalid code to be rejected")
+	}
+}
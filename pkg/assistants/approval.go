@@ -0,0 +1,43 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+// RunApprovedTool 执行已审批通过的命令，返回按 model 裁剪后作为观察结果的文本
+// 只放行审批的这一条命令，执行中再次遇到需要审批的命令时返回错误
+func RunApprovedTool(ctx context.Context, model, name, input string) (string, error) {
+	return runTool(tools.WithApprovedCommand(ctx, name, input), model, "", name, input)
+}
+
+// RejectedObservation 审批拒绝时交给 LLM 的观察结果
+func RejectedObservation(name, input, reviewer string) string {
+	return fmt.Sprintf("The %s command %q was rejected by operator %s and was not executed. "+
+		"Do not retry it or any other state-changing command; answer with read-only findings and explain what the user can do next.", name, input, reviewer)
+}
+
+// ResumeMessages 在因等待审批而暂停的对话历史后追加该工具调用的观察结果，
+// 以此作为 AssistantWithContext 的输入即可继续 ReAct 循环
+// 暂停时历史的最后一条是提出该工具调用的 assistant 消息（ReAct JSON）
+func ResumeMessages(chatHistory []openai.ChatCompletionMessage, name, input, observation, model string) []openai.ChatCompletionMessage {
+	var toolPrompt tools.ToolPrompt
+	if n := len(chatHistory); n > 0 && chatHistory[n-1].Role == openai.ChatMessageRoleAssistant {
+		json.Unmarshal([]byte(chatHistory[n-1].Content), &toolPrompt)
+	}
+	toolPrompt.Action.Name = name
+	toolPrompt.Action.Input = input
+	toolPrompt.Observation = llms.ConstrictPrompt(observation, model, llms.ObservationTokens())
+	message, _ := json.Marshal(toolPrompt)
+
+	messages := append([]openai.ChatCompletionMessage(nil), chatHistory...)
+	return append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: string(message),
+	})
+}
@@ -0,0 +1,18 @@
+package assistants
+
+import "fmt"
+
+// ErrCodeParseError LLM 响应无法解析的错误码
+const ErrCodeParseError = "parse_error"
+
+// ParseError LLM 的响应既不是工具调用也没有最终答案，Response 为模型的原始输出
+type ParseError struct {
+	Response string
+	Reason   string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("unable to parse LLM response: %s", e.Reason)
+}
+
+func (e *ParseError) ErrorCode() string { return ErrCodeParseError }
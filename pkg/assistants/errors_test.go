@@ -0,0 +1,40 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAssistantParseError(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: `{"thought":"checking the pods"}`}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: message}},
+		})
+	}))
+	defer srv.Close()
+
+	prompts := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are an ops assistant."},
+		{Role: openai.ChatMessageRoleUser, Content: "Is payment-api healthy?"},
+	}
+	_, _, err := AssistantWithContext(context.Background(), "gpt-4o", prompts, 1024, false, false, 3, "sk-test", srv.URL+"/v1")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected ParseError, got %v", err)
+	}
+	if parseErr.Response != `{"thought":"checking the pods"}` || parseErr.ErrorCode() != ErrCodeParseError {
+		t.Errorf("unexpected error %+v", parseErr)
+	}
+	if calls != 1 {
+		t.Errorf("expected the loop to stop after 1 LLM call, got %d", calls)
+	}
+}
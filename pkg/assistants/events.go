@@ -0,0 +1,234 @@
+package assistants
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Assistant 循环中的事件类型
+const (
+	EventIterationStarted = "iteration_started" // 开始一轮 LLM 调用，Iteration 从 1 开始
+	EventToolSelected     = "tool_selected"     // LLM 选择了工具，即将执行
+	EventToolCompleted    = "tool_completed"    // 工具执行完成，Observation 为交给 LLM 的观察结果（已裁剪）
+	EventParseFailed      = "parse_failed"      // LLM 响应无法解析为 ReAct JSON，Response 为原始响应
+	EventFinalAnswer      = "final_answer"      // 得到最终答案
+)
+
+// Event Assistant 循环中的结构化事件
+// 流式响应、审计记录、指标和通知都订阅事件，不再各自从对话历史或日志中提取
+type Event struct {
+	Type          string    `json:"type"`
+	InteractionID string    `json:"interaction_id,omitempty"`
+	User          string    `json:"user,omitempty"`
+	Cluster       string    `json:"cluster,omitempty"`
+	Iteration     int       `json:"iteration,omitempty"`
+	Tool          string    `json:"tool,omitempty"`
+	Input         string    `json:"input,omitempty"`
+	Thought       string    `json:"thought,omitempty"`
+	Observation   string    `json:"observation,omitempty"`
+	DurationMs    int64     `json:"duration_ms,omitempty"`
+	Response      string    `json:"response,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Answer        string    `json:"answer,omitempty"`
+	Time          time.Time `json:"time"`
+}
+
+// Subscriber 事件订阅者，在 Assistant 所在的 goroutine 中同步调用
+// 跨集群查询时同一请求的订阅者会被并发调用；耗时的处理（如发送通知）需要自行异步执行
+type Subscriber func(Event)
+
+// subscribers 全局订阅者，接收所有请求的事件
+var subscribers struct {
+	mu   sync.RWMutex
+	list []Subscriber
+}
+
+// Subscribe 注册全局订阅者，通常在 init 中调用
+func Subscribe(sub Subscriber) {
+	subscribers.mu.Lock()
+	defer subscribers.mu.Unlock()
+	subscribers.list = append(subscribers.list, sub)
+}
+
+// eventScope 请求级的事件订阅
+type eventScope struct {
+	interactionID string
+	subscribers   []Subscriber
+}
+
+type eventsKey struct{}
+
+// WithEvents 为请求附加订阅者（如审计记录、SSE 流），事件带上 interactionID
+// 可以多次调用，已附加的订阅者保留；interactionID 为空时沿用已附加的值
+func WithEvents(ctx context.Context, interactionID string, subs ...Subscriber) context.Context {
+	scope := &eventScope{interactionID: interactionID}
+	if parent, ok := ctx.Value(eventsKey{}).(*eventScope); ok {
+		if scope.interactionID == "" {
+			scope.interactionID = parent.interactionID
+		}
+		scope.subscribers = append(scope.subscribers, parent.subscribers...)
+	}
+	scope.subscribers = append(scope.subscribers, subs...)
+	return context.WithValue(ctx, eventsKey{}, scope)
+}
+
+// iterationKey 一次 Assistant 执行的当前迭代，工具事件据此带上迭代序号
+type iterationKey struct{}
+
+// withIteration 为一次 Assistant 执行附加迭代计数，跨集群查询时每个集群分别计数
+func withIteration(ctx context.Context) context.Context {
+	return context.WithValue(ctx, iterationKey{}, new(int))
+}
+
+// startIteration 进入第 iteration 轮并发出 iteration_started 事件
+func startIteration(ctx context.Context, iteration int) {
+	if current, ok := ctx.Value(iterationKey{}).(*int); ok {
+		*current = iteration
+	}
+	emit(ctx, Event{Type: EventIterationStarted})
+}
+
+// emit 补齐事件的交互、用户、集群、迭代和时间，依次通知全局订阅者和请求级订阅者
+func emit(ctx context.Context, e Event) {
+	scope, _ := ctx.Value(eventsKey{}).(*eventScope)
+	if scope != nil {
+		e.InteractionID = scope.interactionID
+	}
+	e.User = tools.UserFromContext(ctx)
+	e.Cluster = tools.KubeContextFromContext(ctx)
+	if current, ok := ctx.Value(iterationKey{}).(*int); ok && e.Iteration == 0 {
+		e.Iteration = *current
+	}
+	e.Time = time.Now()
+
+	subscribers.mu.RLock()
+	list := subscribers.list
+	subscribers.mu.RUnlock()
+	for _, sub := range list {
+		sub(e)
+	}
+	if scope != nil {
+		for _, sub := range scope.subscribers {
+			sub(e)
+		}
+	}
+}
+
+func init() {
+	// 指标：按事件类型和工具计数
+	Subscribe(func(e Event) {
+		utils.RecordAssistantEvent(e.Type, e.Tool)
+	})
+	Subscribe(notifyEvent)
+}
+
+// defaultEventNotifyCooldown 同一类事件重复通知的默认最小间隔
+const defaultEventNotifyCooldown = 10 * time.Minute
+
+// maxEventNotifyRunes 通知中输入、响应、回答等文本的最大长度
+const maxEventNotifyRunes = 500
+
+// eventTitles 各类事件的通知标题
+var eventTitles = map[string]string{
+	EventIterationStarted: "Assistant 开始新一轮迭代",
+	EventToolSelected:     "Assistant 选择工具",
+	EventToolCompleted:    "Assistant 工具执行完成",
+	EventParseFailed:      "LLM 响应无法解析",
+	EventFinalAnswer:      "Assistant 得到最终答案",
+}
+
+// lastNotified 各类事件（类型 + 工具）最近一次通知的时间
+var lastNotified = struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}{last: map[string]time.Time{}}
+
+// notifyEvent 将 events.notify.types 中列出的事件发送到通知渠道 events.notify.channel（为空时发送到全部渠道）
+// 同一类型和工具的事件在 events.notify.cooldown（默认 10m）内只通知一次，发送在后台进行，不阻塞 Assistant 循环
+func notifyEvent(e Event) {
+	config := utils.GetConfig()
+	if !slices.Contains(config.GetStringSlice("events.notify.types"), e.Type) {
+		return
+	}
+	notifier := notify.Default()
+	if !notifier.Enabled() {
+		return
+	}
+	cooldown := defaultEventNotifyCooldown
+	if config.IsSet("events.notify.cooldown") {
+		cooldown = config.GetDuration("events.notify.cooldown")
+	}
+
+	key := e.Type + "/" + e.Tool
+	lastNotified.mu.Lock()
+	if last, ok := lastNotified.last[key]; ok && e.Time.Sub(last) < cooldown {
+		lastNotified.mu.Unlock()
+		return
+	}
+	lastNotified.last[key] = e.Time
+	lastNotified.mu.Unlock()
+
+	msg := notify.Message{Title: eventTitles[e.Type], Text: eventText(e), Level: notify.LevelInfo}
+	if e.Type == EventParseFailed {
+		msg.Level = notify.LevelWarning
+	}
+	channel := config.GetString("events.notify.channel")
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var err error
+		if channel == "" {
+			err = notifier.Send(ctx, msg)
+		} else {
+			err = notifier.SendTo(ctx, channel, msg)
+		}
+		if err != nil {
+			utils.Warn("发送 Assistant 事件通知失败",
+				zap.String("type", e.Type),
+				zap.Error(err),
+			)
+		}
+	}()
+}
+
+// eventText 通知正文，每行一个不为空的字段
+func eventText(e Event) string {
+	var lines []string
+	field := func(name, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("- %s：%s", name, truncateRunes(value, maxEventNotifyRunes)))
+		}
+	}
+	field("交互", e.InteractionID)
+	field("用户", e.User)
+	field("集群", e.Cluster)
+	if e.Iteration > 0 {
+		field("迭代", fmt.Sprint(e.Iteration))
+	}
+	field("工具", e.Tool)
+	field("输入", e.Input)
+	if e.DurationMs > 0 {
+		field("耗时", fmt.Sprintf("%dms", e.DurationMs))
+	}
+	field("错误", e.Error)
+	field("响应", e.Response)
+	field("回答", e.Answer)
+	return strings.Join(lines, "\n")
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
@@ -0,0 +1,95 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestWithEvents(t *testing.T) {
+	var got []string
+	record := func(name string) Subscriber {
+		return func(e Event) { got = append(got, name+":"+e.InteractionID) }
+	}
+	ctx := WithEvents(context.Background(), "", record("stream"))
+	ctx = WithEvents(ctx, "int-1", record("audit"))
+	emit(ctx, Event{Type: EventFinalAnswer})
+	if len(got) != 2 || got[0] != "stream:int-1" || got[1] != "audit:int-1" {
+		t.Errorf("subscribers called as %v", got)
+	}
+
+	// 未附加订阅者的请求只通知全局订阅者
+	emit(context.Background(), Event{Type: EventFinalAnswer})
+	if len(got) != 2 {
+		t.Errorf("request subscribers called without WithEvents: %v", got)
+	}
+}
+
+func TestAssistantEvents(t *testing.T) {
+	tools.Registry.Register(tools.ToolSpec{
+		Name: "echo",
+		Run:  func(ctx context.Context, input string) (string, error) { return "echo: " + input, nil },
+	})
+	defer tools.Registry.Unregister("echo")
+
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+		if calls == 1 {
+			message.ToolCalls = []openai.ToolCall{{
+				ID:       "call_1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "echo", Arguments: `{"input":"payment-api"}`},
+			}}
+		} else {
+			message.Content = `{"final_answer":"payment-api is healthy."}`
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: message}},
+		})
+	}))
+	defer srv.Close()
+
+	client, err := llms.NewOpenAIClient("sk-test", srv.URL+"/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	ctx := withIteration(WithEvents(context.Background(), "int-1", func(e Event) { events = append(events, e) }))
+	prompts := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "Is payment-api healthy?"}}
+	if _, _, err := assistantWithTools(ctx, client, "gpt-4o", prompts, 1024, false, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind      string
+		iteration int
+	}{
+		{EventIterationStarted, 1},
+		{EventToolSelected, 1},
+		{EventToolCompleted, 1},
+		{EventIterationStarted, 2},
+		{EventFinalAnswer, 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events: %+v", len(events), events)
+	}
+	for i, w := range want {
+		if events[i].Type != w.kind || events[i].Iteration != w.iteration || events[i].InteractionID != "int-1" {
+			t.Errorf("event %d = %+v, want %s in iteration %d", i, events[i], w.kind, w.iteration)
+		}
+	}
+	if completed := events[2]; completed.Tool != "echo" || completed.Input != "payment-api" || completed.Observation != "echo: payment-api" {
+		t.Errorf("tool_completed = %+v", completed)
+	}
+	if events[4].Answer != "payment-api is healthy." {
+		t.Errorf("final_answer = %+v", events[4])
+	}
+}
@@ -0,0 +1,227 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// 工具调用模式
+const (
+	ToolCallingPrompt = "prompt" // 模型在回复中输出 ReAct JSON，由 Assistant 解析后调用工具
+	ToolCallingNative = "native" // 使用 OpenAI tools/function calling 接口调用工具
+)
+
+// nativeToolsInstruction 原生工具调用模式下追加的系统提示，覆盖系统提示中要求输出 action JSON 的约定
+const nativeToolsInstruction = "工具调用方式：需要执行命令时直接通过 function calling 调用工具，不要在回复中输出 action JSON；" +
+	"获得足够信息后直接输出最终答案（可沿用 final_answer 字段）。"
+
+// summarizePrompt 达到最大迭代次数或无法继续调用工具时，要求模型基于已有信息作答
+const summarizePrompt = "Summarize all the chat history and respond to original question with final answer"
+
+// nativeToolCalling 是否启用原生工具调用（配置 llm.tool_calling: native）
+func nativeToolCalling() bool {
+	return strings.ToLower(utils.GetConfig().GetString("llm.tool_calling")) == ToolCallingNative
+}
+
+// toolDefinitions 将工具注册表转换为 function calling 的工具定义，按名称排序保证请求稳定（便于录制回放）
+// 不包含目标集群的 runner 不支持的工具
+func toolDefinitions(cluster string) []openai.Tool {
+	specs := tools.Registry.List()
+	definitions := make([]openai.Tool, 0, len(specs))
+	for _, spec := range specs {
+		if !tools.Available(cluster, spec.Name) {
+			continue
+		}
+		definitions = append(definitions, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Parameters:  spec.InputSchema(),
+			},
+		})
+	}
+	return definitions
+}
+
+// toolCallInput 解析工具调用参数中的 input，参数不是预期的 JSON 时原样作为输入
+// 声明了自定义参数 Schema 的工具（例如 jq）直接接收完整的 JSON 参数
+func toolCallInput(call openai.ToolCall) string {
+	if spec, ok := tools.Registry.Get(call.Function.Name); ok && spec.Schema != nil {
+		return call.Function.Arguments
+	}
+	var args struct {
+		Input string `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		return call.Function.Arguments
+	}
+	return args.Input
+}
+
+// finalAnswerFrom 模型沿用 final_answer 字段输出时提取其中的答案，否则返回原文
+func finalAnswerFrom(content string) string {
+	var toolPrompt tools.ToolPrompt
+	if err := json.Unmarshal([]byte(content), &toolPrompt); err == nil && toolPrompt.FinalAnswer != "" && !isTemplateValue(toolPrompt.FinalAnswer) {
+		return toolPrompt.FinalAnswer
+	}
+	return content
+}
+
+// assistantWithTools 基于原生工具调用的 Assistant 循环
+// 发送给 LLM 的是原生格式的消息（tool_calls / tool）；返回的 chatHistory 保持与提示模式相同的 ReAct JSON 格式，
+// 调用方提取工具调用历史、写入审计和会话记忆时无需区分模式
+func assistantWithTools(ctx context.Context, client llms.ToolCaller, model string, prompts []openai.ChatCompletionMessage, maxTokens int, verbose bool, maxIterations int) (string, []openai.ChatCompletionMessage, error) {
+	logger := utils.LoggerFromContext(ctx)
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("assistant_native_tools")()
+
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxIterations
+	}
+	definitions := toolDefinitions(tools.KubeContextFromContext(ctx))
+	chatHistory := prompts
+	messages := append(append([]openai.ChatCompletionMessage(nil), prompts...), openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: nativeToolsInstruction,
+	})
+
+	progress := ProgressFromContext(ctx)
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		progress.Enter(StageAnalyzing, "")
+		startIteration(ctx, iteration)
+		perfStats.StartTimer("assistant_native_chat")
+		chatModel := routeModel(ctx, model, maxTokens, messages)
+		promptTokens, err := checkTokenBudget(ctx, chatModel, messages)
+		var message openai.ChatCompletionMessage
+		started := time.Now()
+		if err == nil {
+			chatCtx, span := startChatSpan(ctx, client, chatModel, maxTokens)
+			message, err = client.ChatWithTools(chatModel, maxTokens, messages, definitions)
+			if err == nil {
+				recordTokenUsage(chatCtx, chatModel, promptTokens, completionTokens(message, chatModel), started, llms.FinishReason(client))
+			}
+			utils.EndSpan(span, err)
+		}
+		chatDuration := perfStats.StopSpanTimer(ctx, "assistant_native_chat")
+		if err != nil {
+			logger.Error("对话完成失败",
+				zap.Error(err),
+			)
+			return "", chatHistory, fmt.Errorf("chat completion error: %w", err)
+		}
+		logger.Debug("原生工具调用对话完成",
+			zap.Int("iteration", iteration),
+			zap.Int("toolCalls", len(message.ToolCalls)),
+			zap.Duration("duration", chatDuration),
+		)
+
+		if len(message.ToolCalls) == 0 {
+			chatHistory = append(chatHistory, openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: message.Content,
+			})
+			answer := finalAnswerFrom(message.Content)
+			logger.Info("获得最终答案",
+				zap.String("finalAnswer", answer),
+			)
+			emit(ctx, Event{Type: EventFinalAnswer, Answer: answer})
+			return answer, chatHistory, nil
+		}
+
+		messages = append(messages, message)
+		for _, call := range message.ToolCalls {
+			input := toolCallInput(call)
+			logger.Debug("执行工具",
+				zap.String("tool", call.Function.Name),
+				zap.String("input", input),
+			)
+
+			var toolPrompt tools.ToolPrompt
+			toolPrompt.Thought = message.Content
+			toolPrompt.Action.Name = call.Function.Name
+			toolPrompt.Action.Input = input
+			step, _ := json.Marshal(toolPrompt)
+			chatHistory = append(chatHistory, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: string(step)})
+
+			observation, err := runTool(ctx, model, message.Content, call.Function.Name, input)
+			if err != nil {
+				// 变更命令等待人工审批，返回的历史以该工具调用结束，审批后由调用方带上执行结果继续
+				return "", chatHistory, err
+			}
+			if err := ctx.Err(); err != nil {
+				// 请求已取消（如客户端断开），不再继续对话
+				logger.Warn("请求已取消，停止执行",
+					zap.Error(err),
+				)
+				return "", chatHistory, err
+			}
+			if verbose {
+				logger.Debug("工具执行结果",
+					zap.String("observation", observation),
+				)
+			}
+			messages = append(messages, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				ToolCallID: call.ID,
+				Content:    observation,
+			})
+
+			toolPrompt.Observation = observation
+			step, _ = json.Marshal(toolPrompt)
+			chatHistory = append(chatHistory, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: string(step)})
+		}
+	}
+
+	// 达到最大迭代次数后不再提供工具，基于 ReAct 格式的历史（不含 tool_calls）要求模型总结作答
+	logger.Warn("达到最大迭代次数",
+		zap.Int("maxIterations", maxIterations),
+	)
+	chatHistory = append(chatHistory, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: summarizePrompt,
+	})
+	progress.Enter(StageComposingAnswer, "")
+	perfStats.StartTimer("assistant_summarize")
+	summaryModel := routeModel(ctx, model, maxTokens, chatHistory)
+	chatCtx, span := startChatSpan(ctx, client, summaryModel, maxTokens)
+	started := time.Now()
+	message, err := client.ChatWithTools(summaryModel, maxTokens, chatHistory, nil)
+	if err == nil && llms.TokenBudgetFromContext(ctx) != nil {
+		recordTokenUsage(chatCtx, summaryModel, llms.CountMessageTokens(chatHistory, summaryModel), completionTokens(message, summaryModel),
+			started, llms.FinishReason(client))
+	}
+	utils.EndSpan(span, err)
+	perfStats.StopSpanTimer(ctx, "assistant_summarize")
+	if err != nil {
+		logger.Error("总结对话失败",
+			zap.Error(err),
+		)
+		return "", chatHistory, fmt.Errorf("chat completion error: %w", err)
+	}
+	chatHistory = append(chatHistory, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleAssistant,
+		Content: message.Content,
+	})
+	answer := finalAnswerFrom(message.Content)
+	emit(ctx, Event{Type: EventFinalAnswer, Answer: answer})
+	return answer, chatHistory, nil
+}
+
+// completionTokens 模型回复的 token 数，包括工具调用的名称和参数
+func completionTokens(message openai.ChatCompletionMessage, model string) int {
+	tokens := llms.CountTokens(message.Content, model)
+	for _, call := range message.ToolCalls {
+		tokens += llms.CountTokens(call.Function.Name, model) + llms.CountTokens(call.Function.Arguments, model)
+	}
+	return tokens
+}
@@ -0,0 +1,91 @@
+package assistants
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestToolCallInput(t *testing.T) {
+	call := openai.ToolCall{Function: openai.FunctionCall{Name: "kubectl", Arguments: `{"input":"get pods -n shop"}`}}
+	if got := toolCallInput(call); got != "get pods -n shop" {
+		t.Errorf("toolCallInput() = %q", got)
+	}
+	call.Function.Arguments = "get pods"
+	if got := toolCallInput(call); got != "get pods" {
+		t.Errorf("toolCallInput() with raw arguments = %q", got)
+	}
+	// 声明了参数 Schema 的工具接收完整参数
+	call = openai.ToolCall{Function: openai.FunctionCall{Name: "jq", Arguments: `{"expression":".a","json":"{\"a\":1}"}`}}
+	if got := toolCallInput(call); got != call.Function.Arguments {
+		t.Errorf("toolCallInput() for jq = %q", got)
+	}
+}
+
+func TestAssistantWithTools(t *testing.T) {
+	tools.Registry.Register(tools.ToolSpec{
+		Name: "echo",
+		Run:  func(ctx context.Context, input string) (string, error) { return "echo: " + input, nil },
+	})
+	defer tools.Registry.Unregister("echo")
+
+	var requests []openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, req)
+
+		message := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant}
+		if len(requests) == 1 {
+			message.ToolCalls = []openai.ToolCall{{
+				ID:       "call_1",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "echo", Arguments: `{"input":"payment-api"}`},
+			}}
+		} else {
+			message.Content = `{"final_answer":"payment-api is healthy and serving traffic."}`
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: message}},
+		})
+	}))
+	defer srv.Close()
+
+	client, err := llms.NewOpenAIClient("sk-test", srv.URL+"/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: "You are an ops assistant."},
+		{Role: openai.ChatMessageRoleUser, Content: "Is payment-api healthy?"},
+	}
+	answer, chatHistory, err := assistantWithTools(context.Background(), client, "gpt-4o", prompts, 1024, false, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "payment-api is healthy and serving traffic." {
+		t.Errorf("answer = %q", answer)
+	}
+
+	if len(requests) != 2 || len(requests[0].Tools) == 0 {
+		t.Fatalf("expected 2 requests with tool definitions, got %d", len(requests))
+	}
+	last := requests[1].Messages[len(requests[1].Messages)-1]
+	if last.Role != openai.ChatMessageRoleTool || last.ToolCallID != "call_1" || last.Content != "echo: payment-api" {
+		t.Errorf("tool result message = %+v", last)
+	}
+
+	// 返回的历史保持 ReAct JSON 格式
+	var step tools.ToolPrompt
+	if err := json.Unmarshal([]byte(chatHistory[3].Content), &step); err != nil || step.Action.Name != "echo" || step.Observation != "echo: payment-api" {
+		t.Errorf("chatHistory[3] = %q", chatHistory[3].Content)
+	}
+}
@@ -0,0 +1,147 @@
+package assistants
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// 一次交互的进度阶段
+const (
+	StageResolvingContext = "resolving_context" // 解析集群、构建提示和会话历史
+	StageQueryingCluster  = "querying_cluster"  // 执行工具查询集群或外部系统
+	StageAnalyzing        = "analyzing"         // 等待 LLM 分析工具结果、决定下一步
+	StageComposingAnswer  = "composing_answer"  // 生成、审阅或翻译最终回答
+)
+
+// StageTiming 一个阶段的耗时，同一阶段多次进入时分别记录
+type StageTiming struct {
+	Stage     string `json:"stage"`
+	Detail    string `json:"detail,omitempty"` // 工具名称等补充说明
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// ProgressEvent 进度通知：当前阶段、已在该阶段停留的时间和交互总耗时
+type ProgressEvent struct {
+	Stage          string `json:"stage"`
+	Detail         string `json:"detail,omitempty"`
+	StageElapsedMs int64  `json:"stage_elapsed_ms"`
+	ElapsedMs      int64  `json:"elapsed_ms"`
+}
+
+// Progress 记录一次交互经过的阶段，阶段切换时立即通知，停留在同一阶段时按 heartbeat 周期重复通知
+// 方法可以在 nil 上调用，未附加进度的请求不需要判断
+type Progress struct {
+	mu         sync.Mutex
+	report     func(ProgressEvent)
+	start      time.Time
+	stage      string
+	detail     string
+	stageStart time.Time
+	stages     []StageTiming
+	stop       chan struct{}
+	stopOnce   sync.Once
+}
+
+// NewProgress 创建进度记录，report 在阶段切换和心跳时被调用（可能来自其他 goroutine，不能再调用 Progress 的方法），
+// heartbeat 不大于 0 时不发送心跳。使用完毕后需要调用 Finish
+func NewProgress(report func(ProgressEvent), heartbeat time.Duration) *Progress {
+	p := &Progress{report: report, start: time.Now(), stop: make(chan struct{})}
+	if heartbeat > 0 {
+		go p.heartbeat(heartbeat)
+	}
+	return p
+}
+
+type progressKey struct{}
+
+// WithProgress 将进度记录附加到 context，Assistant 在调用工具和 LLM 时更新阶段
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFromContext 获取 context 中的进度记录，未附加时返回 nil
+func ProgressFromContext(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
+
+// Enter 进入新阶段，与当前阶段和说明都相同时忽略
+func (p *Progress) Enter(stage, detail string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stage == stage && p.detail == detail {
+		return
+	}
+	now := time.Now()
+	p.closeStageLocked(now)
+	p.stage, p.detail, p.stageStart = stage, detail, now
+	p.reportLocked(now)
+}
+
+// Stage 返回当前阶段和已停留的时间
+func (p *Progress) Stage() (string, time.Duration) {
+	if p == nil {
+		return "", 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stage, time.Since(p.stageStart)
+}
+
+// Finish 结束当前阶段并停止心跳，返回各阶段的耗时
+func (p *Progress) Finish() []StageTiming {
+	if p == nil {
+		return nil
+	}
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeStageLocked(time.Now())
+	p.stage, p.detail = "", ""
+	return append([]StageTiming(nil), p.stages...)
+}
+
+func (p *Progress) closeStageLocked(now time.Time) {
+	if p.stage == "" {
+		return
+	}
+	p.stages = append(p.stages, StageTiming{
+		Stage:     p.stage,
+		Detail:    p.detail,
+		ElapsedMs: now.Sub(p.stageStart).Milliseconds(),
+	})
+}
+
+// reportLocked 在持有锁时通知，保证 Finish 返回后不会再有进度通知
+func (p *Progress) reportLocked(now time.Time) {
+	if p.report == nil {
+		return
+	}
+	p.report(ProgressEvent{
+		Stage:          p.stage,
+		Detail:         p.detail,
+		StageElapsedMs: now.Sub(p.stageStart).Milliseconds(),
+		ElapsedMs:      now.Sub(p.start).Milliseconds(),
+	})
+}
+
+func (p *Progress) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		p.mu.Lock()
+		if p.stage != "" {
+			p.reportLocked(time.Now())
+		}
+		p.mu.Unlock()
+	}
+}
@@ -0,0 +1,52 @@
+package assistants
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	var mu sync.Mutex
+	var events []ProgressEvent
+	p := NewProgress(func(e ProgressEvent) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}, 5*time.Millisecond)
+	ctx := WithProgress(context.Background(), p)
+
+	ProgressFromContext(ctx).Enter(StageResolvingContext, "")
+	ProgressFromContext(ctx).Enter(StageQueryingCluster, "kubectl")
+	ProgressFromContext(ctx).Enter(StageQueryingCluster, "kubectl")
+	time.Sleep(20 * time.Millisecond)
+	p.Enter(StageAnalyzing, "")
+	stages := p.Finish()
+
+	mu.Lock()
+	reported := len(events)
+	heartbeats := 0
+	for _, e := range events {
+		if e.Stage == StageQueryingCluster {
+			heartbeats++
+		}
+	}
+	mu.Unlock()
+	if heartbeats < 2 {
+		t.Errorf("expected heartbeats while querying the cluster, got %d", heartbeats)
+	}
+	if len(stages) != 3 || stages[1].Detail != "kubectl" || stages[1].ElapsedMs < 20 {
+		t.Errorf("unexpected stages: %+v", stages)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != reported {
+		t.Errorf("expected no events after Finish, got %d more", len(events)-reported)
+	}
+
+	// 未附加进度的请求可以直接调用
+	ProgressFromContext(context.Background()).Enter(StageAnalyzing, "")
+}
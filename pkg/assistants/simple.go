@@ -1,14 +1,20 @@
 package assistants
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/policy"
 	"github.com/myysophia/OpsAgent/pkg/tools"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"strings"
+	"time"
 )
 
 var logger *zap.Logger
@@ -290,13 +296,30 @@ func Assistant(model string, prompts []openai.ChatCompletionMessage, maxTokens i
 
 // AssistantWithConfig is the AI assistant with custom configuration.
 func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, maxTokens int, countTokens bool, verbose bool, maxIterations int, apiKey string, baseUrl string) (result string, chatHistory []openai.ChatCompletionMessage, err error) {
+	return AssistantWithContext(context.Background(), model, prompts, maxTokens, countTokens, verbose, maxIterations, apiKey, baseUrl)
+}
+
+// AssistantWithContext is the AI assistant with custom configuration.
+// The context carries the request scope (e.g. the user) to the tool invocations.
+func AssistantWithContext(ctx context.Context, model string, prompts []openai.ChatCompletionMessage, maxTokens int, countTokens bool, verbose bool, maxIterations int, apiKey string, baseUrl string) (result string, chatHistory []openai.ChatCompletionMessage, err error) {
+	// 使用请求级 logger，日志自动带上用户、交互、集群等字段
+	logger := utils.LoggerFromContext(ctx)
+
 	// 获取性能统计工具
 	perfStats := utils.GetPerfStats()
 	// 开始整体执行计时
 	defer perfStats.TraceFunc("assistant_total")()
 
+	// 本次执行的 LLM 调用和工具调用 span 都挂在 assistant span 下，各阶段计时写入其属性
+	ctx, span := utils.StartSpan(ctx, "assistant",
+		attribute.String("gen_ai.request.model", model),
+		attribute.String("gen_ai.system", llms.ProviderFromContext(ctx)),
+		attribute.Int("assistant.max_iterations", maxIterations),
+	)
+	defer func() { utils.EndSpan(span, err) }()
+	ctx = withIteration(ctx)
+
 	logger.Info("开始执行 AssistantWithConfig",
-		zap.String("model", model),
 		zap.Int("maxTokens", maxTokens),
 		zap.Bool("countTokens", countTokens),
 		zap.Bool("verbose", verbose),
@@ -313,37 +336,61 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 	// 开始创建客户端计时
 	perfStats.StartTimer("assistant_create_client")
 
-	client, err := llms.NewOpenAIClient(apiKey, baseUrl)
+	client, err := llms.NewProvider(llms.ProviderFromContext(ctx), apiKey, baseUrl)
 
 	// 停止创建客户端计时
-	clientDuration := perfStats.StopTimer("assistant_create_client")
-	logger.Debug("创建OpenAI客户端完成",
+	clientDuration := perfStats.StopSpanTimer(ctx, "assistant_create_client")
+	logger.Debug("创建LLM客户端完成",
+		zap.String("provider", llms.ProviderFromContext(ctx)),
 		zap.Duration("duration", clientDuration),
 	)
 
 	if err != nil {
-		logger.Error("创建 OpenAI 客户端失败",
+		logger.Error("创建 LLM 客户端失败",
+			zap.String("provider", llms.ProviderFromContext(ctx)),
 			zap.Error(err),
 		)
-		return "", nil, fmt.Errorf("unable to get OpenAI client: %v", err)
+		return "", nil, fmt.Errorf("unable to get LLM client: %v", err)
 	}
-	//
-	//defer func() {
-	//	if countTokens {
-	//		count := llms.NumTokensFromMessages(chatHistory, model)
-	//		logger.Info("Token 统计",
-	//			zap.Int("total_tokens", count),
-	//		)
-	//	}
-	//}()
+
+	// 启用原生工具调用时通过 function calling 调度工具，不支持的服务商回退到基于提示的 ReAct 循环
+	if nativeToolCalling() {
+		if caller, ok := client.(llms.ToolCaller); ok {
+			return assistantWithTools(ctx, caller, model, prompts, maxTokens, verbose, maxIterations)
+		}
+		logger.Info("服务商不支持原生工具调用，使用提示模式",
+			zap.String("provider", client.Name()),
+		)
+	}
+
+	// 统计 token 时，调用方未附加预算（如命令行）则为本次执行创建，结束时输出用量
+	if countTokens {
+		budget := llms.TokenBudgetFromContext(ctx)
+		if budget == nil {
+			ctx, budget = llms.WithTokenBudget(ctx, tools.UserFromContext(ctx))
+		}
+		defer func() {
+			usage := budget.Usage()
+			logger.Info("Token 统计",
+				zap.Int("prompt_tokens", usage.PromptTokens),
+				zap.Int("completion_tokens", usage.CompletionTokens),
+				zap.Int("total_tokens", usage.TotalTokens),
+				zap.Int("llm_calls", len(usage.Iterations)),
+			)
+		}()
+	}
+
+	progress := ProgressFromContext(ctx)
+	progress.Enter(StageAnalyzing, "")
+	startIteration(ctx, 1)
 
 	// 开始第一轮对话计时
 	perfStats.StartTimer("assistant_first_chat")
 
-	resp, err := client.Chat(model, maxTokens, chatHistory)
+	resp, err := chatWithRouting(ctx, client, model, maxTokens, chatHistory)
 
 	// 停止第一轮对话计时
-	chatDuration := perfStats.StopTimer("assistant_first_chat")
+	chatDuration := perfStats.StopSpanTimer(ctx, "assistant_first_chat")
 	logger.Debug("第一轮对话完成",
 		zap.Duration("duration", chatDuration),
 		zap.String("response", resp),
@@ -352,7 +399,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 		logger.Error("对话完成失败",
 			zap.Error(err),
 		)
-		return "", chatHistory, fmt.Errorf("chat completion error: %v", err)
+		return "", chatHistory, fmt.Errorf("chat completion error: %w", err)
 	}
 
 	chatHistory = append(chatHistory, openai.ChatCompletionMessage{
@@ -366,7 +413,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 	var toolPrompt tools.ToolPrompt
 	if err = json.Unmarshal([]byte(resp), &toolPrompt); err != nil {
 		// 停止解析工具提示计时
-		parseDuration := perfStats.StopTimer("assistant_parse_tool_prompt")
+		parseDuration := perfStats.StopSpanTimer(ctx, "assistant_parse_tool_prompt")
 		logger.Debug("解析工具提示失败",
 			zap.Duration("duration", parseDuration),
 			zap.Error(err),
@@ -378,11 +425,13 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 				zap.String("response", resp),
 			)
 		}
+		emit(ctx, Event{Type: EventParseFailed, Response: resp, Error: err.Error()})
+		emit(ctx, Event{Type: EventFinalAnswer, Answer: resp})
 		return resp, chatHistory, nil
 	}
 
 	// 停止解析工具提示计时
-	parseDuration := perfStats.StopTimer("assistant_parse_tool_prompt")
+	parseDuration := perfStats.StopSpanTimer(ctx, "assistant_parse_tool_prompt")
 	logger.Debug("解析工具提示成功",
 		zap.Duration("duration", parseDuration),
 	)
@@ -404,10 +453,23 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 			)
 		}
 
+		// 可以解析为 JSON 但既没有工具调用也没有最终答案，继续循环只会空转到最大迭代次数
+		if toolPrompt.Action.Name == "" && strings.TrimSpace(toolPrompt.FinalAnswer) == "" {
+			response := chatHistory[len(chatHistory)-1].Content
+			logger.Warn("LLM 响应既没有工具调用也没有最终答案",
+				zap.Int("iteration", iterations),
+				zap.String("response", response),
+			)
+			parseErr := &ParseError{Response: response, Reason: "response has neither an action nor a final_answer"}
+			emit(ctx, Event{Type: EventParseFailed, Response: response, Error: parseErr.Reason})
+			return "", chatHistory, parseErr
+		}
+
 		if iterations > maxIterations {
 			logger.Warn("达到最大迭代次数",
 				zap.Int("maxIterations", maxIterations),
 			)
+			emit(ctx, Event{Type: EventFinalAnswer, Answer: toolPrompt.FinalAnswer})
 			return toolPrompt.FinalAnswer, chatHistory, nil
 		}
 
@@ -415,11 +477,11 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 			logger.Info("获得最终答案",
 				zap.String("finalAnswer", toolPrompt.FinalAnswer),
 			)
+			emit(ctx, Event{Type: EventFinalAnswer, Answer: toolPrompt.FinalAnswer})
 			return toolPrompt.FinalAnswer, chatHistory, nil
 		}
 
 		if toolPrompt.Action.Name != "" {
-			var observation string
 			logger.Debug("执行工具",
 				zap.String("tool", toolPrompt.Action.Name),
 				zap.String("input", toolPrompt.Action.Input),
@@ -436,49 +498,17 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 				)
 			}
 
-			// 开始工具执行计时
-			perfStats.StartTimer("assistant_tool_" + toolPrompt.Action.Name)
-
-			if toolFunc, ok := tools.CopilotTools[toolPrompt.Action.Name]; ok {
-				ret, err := toolFunc(toolPrompt.Action.Input)
-				observation = strings.TrimSpace(ret)
-
-				// 停止工具执行计时
-				toolDuration := perfStats.StopTimer("assistant_tool_" + toolPrompt.Action.Name)
-
-				if err != nil {
-					logger.Error("工具执行失败",
-						zap.String("tool", toolPrompt.Action.Name),
-						zap.Error(err),
-						zap.Duration("duration", toolDuration),
-					)
-					observation = fmt.Sprintf("Tool %s failed with error %s. Considering refine the inputs for the tool.", toolPrompt.Action.Name, ret)
-				} else {
-					logger.Debug("工具执行成功",
-						zap.String("tool", toolPrompt.Action.Name),
-						zap.String("observation", observation),
-						zap.Duration("duration", toolDuration),
-					)
-					// 检查执行结果是否为空
-					//if observation == "" {
-					//	toolPrompt.FinalAnswer = "我的模型是:" + model + "你的问题我好像没理解,你可以重新问我一次，我保证认真回答或者试试其他模型吧!"
-					//	assistantMessage, _ := json.Marshal(toolPrompt)
-					//	chatHistory = append(chatHistory, openai.ChatCompletionMessage{
-					//		Role:    openai.ChatMessageRoleAssistant,
-					//		Content: string(assistantMessage),
-					//	})
-					//	return toolPrompt.FinalAnswer, chatHistory, nil
-					//}
-				}
-			} else {
-				// 停止工具执行计时（工具不可用的情况）
-				toolDuration := perfStats.StopTimer("assistant_tool_" + toolPrompt.Action.Name)
-
-				logger.Warn("工具不可用",
-					zap.String("tool", toolPrompt.Action.Name),
-					zap.Duration("duration", toolDuration),
+			observation, err := runTool(ctx, model, toolPrompt.Thought, toolPrompt.Action.Name, toolPrompt.Action.Input)
+			if err != nil {
+				// 变更命令等待人工审批，对话在此暂停，审批后由调用方带上执行结果继续
+				return "", chatHistory, err
+			}
+			if err := ctx.Err(); err != nil {
+				// 请求已取消（如客户端断开），不再继续对话
+				logger.Warn("请求已取消，停止执行",
+					zap.Error(err),
 				)
-				observation = fmt.Sprintf("Tool %s is not available. Considering switch to other supported tools.", toolPrompt.Action.Name)
+				return "", chatHistory, err
 			}
 
 			if verbose {
@@ -490,9 +520,6 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 			// 开始消息构建计时
 			perfStats.StartTimer("assistant_construct_message")
 
-			// Constrict the prompt to the max tokens allowed by the model.
-			// This is required because the tool may have generated a long output.
-			observation = llms.ConstrictPrompt(observation, model, 1024)
 			toolPrompt.Observation = observation
 			assistantMessage, _ := json.Marshal(toolPrompt)
 			chatHistory = append(chatHistory, openai.ChatCompletionMessage{
@@ -504,18 +531,20 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 			//chatHistory = llms.ConstrictMessages(chatHistory, model, maxTokens)
 
 			// 停止消息构建计时
-			constructDuration := perfStats.StopTimer("assistant_construct_message")
+			constructDuration := perfStats.StopSpanTimer(ctx, "assistant_construct_message")
 			logger.Debug("消息构建完成",
 				zap.Duration("duration", constructDuration),
 			)
 
+			progress.Enter(StageAnalyzing, "")
+			startIteration(ctx, iterations+1)
 			// 开始中间对话计时
 			perfStats.StartTimer("assistant_intermediate_chat")
 
-			resp, err := client.Chat(model, maxTokens, chatHistory)
+			resp, err := chatWithRouting(ctx, client, model, maxTokens, chatHistory)
 
 			// 停止中间对话计时
-			intermediateChatDuration := perfStats.StopTimer("assistant_intermediate_chat")
+			intermediateChatDuration := perfStats.StopSpanTimer(ctx, "assistant_intermediate_chat")
 			logger.Debug("中间对话完成",
 				zap.Duration("duration", intermediateChatDuration),
 			)
@@ -524,7 +553,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 				logger.Error("对话完成失败",
 					zap.Error(err),
 				)
-				return "", chatHistory, fmt.Errorf("chat completion error: %v", err)
+				return "", chatHistory, fmt.Errorf("chat completion error: %w", err)
 			}
 
 			chatHistory = append(chatHistory, openai.ChatCompletionMessage{
@@ -543,7 +572,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 			// extract the tool prompt from the LLM response.
 			if err = json.Unmarshal([]byte(resp), &toolPrompt); err != nil {
 				// 停止解析中间响应计时
-				parseIntermediateDuration := perfStats.StopTimer("assistant_parse_intermediate")
+				parseIntermediateDuration := perfStats.StopSpanTimer(ctx, "assistant_parse_intermediate")
 				logger.Debug("解析中间响应失败",
 					zap.Duration("duration", parseIntermediateDuration),
 					zap.Error(err),
@@ -554,19 +583,21 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 						zap.Error(err),
 					)
 				}
+				emit(ctx, Event{Type: EventParseFailed, Response: resp, Error: err.Error()})
 
 				chatHistory = append(chatHistory, openai.ChatCompletionMessage{
 					Role:    openai.ChatMessageRoleUser,
-					Content: "Summarize all the chat history and respond to original question with final answer",
+					Content: summarizePrompt,
 				})
 
+				progress.Enter(StageComposingAnswer, "")
 				// 开始总结对话计时
 				perfStats.StartTimer("assistant_summarize")
 
-				resp, err = client.Chat(model, maxTokens, chatHistory)
+				resp, err = chatWithRouting(ctx, client, model, maxTokens, chatHistory)
 
 				// 停止总结对话计时
-				summarizeDuration := perfStats.StopTimer("assistant_summarize")
+				summarizeDuration := perfStats.StopSpanTimer(ctx, "assistant_summarize")
 				logger.Debug("总结对话完成",
 					zap.Duration("duration", summarizeDuration),
 				)
@@ -575,12 +606,13 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 					logger.Error("总结对话失败",
 						zap.Error(err),
 					)
-					return "", chatHistory, fmt.Errorf("chat completion error: %v", err)
+					return "", chatHistory, fmt.Errorf("chat completion error: %w", err)
 				}
 
 				logger.Info("完成总结",
 					zap.String("summary", resp),
 				)
+				emit(ctx, Event{Type: EventFinalAnswer, Answer: resp})
 
 				// 尝试从响应中提取final_answer并处理格式
 				// 这里处理LLM返回的JSON响应，确保只返回final_answer部分
@@ -600,7 +632,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 				return resp, chatHistory, nil
 			} else {
 				// 停止解析中间响应计时
-				parseIntermediateDuration := perfStats.StopTimer("assistant_parse_intermediate")
+				parseIntermediateDuration := perfStats.StopSpanTimer(ctx, "assistant_parse_intermediate")
 				logger.Debug("解析中间响应成功",
 					zap.Duration("duration", parseIntermediateDuration),
 				)
@@ -608,6 +640,7 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 					logger.Info("获得最终答案",
 						zap.String("finalAnswer", toolPrompt.FinalAnswer),
 					)
+					emit(ctx, Event{Type: EventFinalAnswer, Answer: toolPrompt.FinalAnswer})
 					return toolPrompt.FinalAnswer, chatHistory, nil
 				}
 			}
@@ -615,6 +648,209 @@ func AssistantWithConfig(model string, prompts []openai.ChatCompletionMessage, m
 	}
 }
 
+// chatWithRouting 根据组装后的提示长度选择模型并执行对话
+// 当提示超过阈值时自动切换到长上下文模型，并记录路由决策
+// 请求附加了 token 预算时，发送前检查预算，完成后记录本轮的提示和补全 token 数
+func chatWithRouting(ctx context.Context, client llms.Provider, model string, maxTokens int, chatHistory []openai.ChatCompletionMessage) (string, error) {
+	model = routeModel(ctx, model, maxTokens, chatHistory)
+	promptTokens, err := checkTokenBudget(ctx, model, chatHistory)
+	if err != nil {
+		return "", err
+	}
+	ctx, span := startChatSpan(ctx, client, model, maxTokens)
+	started := time.Now()
+	resp, err := client.Chat(model, maxTokens, chatHistory)
+	if err == nil {
+		recordTokenUsage(ctx, model, promptTokens, llms.CountTokens(resp, model), started, llms.FinishReason(client))
+	}
+	utils.EndSpan(span, err)
+	return resp, err
+}
+
+// startChatSpan 为一次 LLM 调用创建 span，token 用量和结束原因由 recordTokenUsage 写入
+func startChatSpan(ctx context.Context, client interface{}, model string, maxTokens int) (context.Context, trace.Span) {
+	provider := llms.ProviderFromContext(ctx)
+	if p, ok := client.(llms.Provider); ok {
+		provider = p.Name()
+	}
+	return utils.StartSpan(ctx, "chat "+model,
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", provider),
+		attribute.String("gen_ai.request.model", model),
+		attribute.Int("gen_ai.request.max_tokens", maxTokens),
+	)
+}
+
+// checkTokenBudget 计算提示的 token 数并检查请求和用户的预算，未附加预算时返回 0
+func checkTokenBudget(ctx context.Context, model string, messages []openai.ChatCompletionMessage) (int, error) {
+	budget := llms.TokenBudgetFromContext(ctx)
+	if budget == nil {
+		return 0, nil
+	}
+	promptTokens := llms.CountMessageTokens(messages, model)
+	if err := budget.Check(promptTokens); err != nil {
+		utils.LoggerFromContext(ctx).Warn("token 预算已用尽，停止调用 LLM",
+			zap.Int("promptTokens", promptTokens),
+			zap.Error(err),
+		)
+		utils.GetPerfStats().IncrCounter("llm_budget_exceeded")
+		return 0, err
+	}
+	return promptTokens, nil
+}
+
+// recordTokenUsage 记录一轮对话的 token 用量、耗时和结束原因，started 为发送请求的时间
+// 同时写入上下文中 LLM 调用 span 的属性
+func recordTokenUsage(ctx context.Context, model string, promptTokens, completionTokens int, started time.Time, finishReason string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", promptTokens),
+		attribute.Int("gen_ai.usage.output_tokens", completionTokens),
+	)
+	if finishReason != "" {
+		span.SetAttributes(attribute.StringSlice("gen_ai.response.finish_reasons", []string{finishReason}))
+	}
+	if budget := llms.TokenBudgetFromContext(ctx); budget != nil {
+		budget.RecordCall(llms.IterationUsage{
+			Model:            model,
+			PromptTokens:     promptTokens,
+			CompletionTokens: completionTokens,
+			LatencyMs:        time.Since(started).Milliseconds(),
+			FinishReason:     finishReason,
+			StartedAt:        started,
+		})
+	}
+}
+
+// routeModel 返回本轮对话实际使用的模型，路由到长上下文模型时记录日志和计数
+// 长上下文模型同样要在租户和角色的模型允许列表中，不允许时继续使用原模型
+func routeModel(ctx context.Context, model string, maxTokens int, chatHistory []openai.ChatCompletionMessage) string {
+	tenant, role := tools.TenantFromContext(ctx), tools.RoleFromContext(ctx)
+	decision := llms.RouteModel(model, maxTokens, chatHistory, func(routed string) error {
+		return policy.ValidateModelSelection(tenant, role, llms.ProviderFromContext(ctx), routed, nil)
+	})
+	if decision.Denied {
+		utils.LoggerFromContext(ctx).Warn("提示超过阈值，但长上下文模型不在租户或角色允许列表中，继续使用原模型",
+			zap.String("tenant", tenant),
+			zap.String("role", role),
+			zap.String("model", decision.OriginalModel),
+			zap.Int("promptTokens", decision.PromptTokens),
+			zap.Int("threshold", decision.Threshold),
+		)
+	}
+	if decision.Routed {
+		utils.LoggerFromContext(ctx).Info("提示超过阈值，路由到长上下文模型",
+			zap.String("model", decision.OriginalModel),
+			zap.String("routedModel", decision.Model),
+			zap.Int("promptTokens", decision.PromptTokens),
+			zap.Int("threshold", decision.Threshold),
+		)
+		utils.GetPerfStats().IncrCounter("llm_route_" + decision.Model)
+	}
+	return decision.Model
+}
+
+// runTool 调用工具并返回作为观察结果的文本，观察结果已按 model 裁剪
+// 只有命令需要人工审批时返回错误（*tools.ApprovalRequiredError），其余失败都作为观察结果交给 LLM
+// 配额超限、目标不可达、工具失败或不存在时返回提示 LLM 调整策略的说明，两种工具调用模式共用
+// 执行前后分别发出 tool_selected 和 tool_completed 事件，需要审批时没有 tool_completed
+func runTool(ctx context.Context, model, thought, name, input string) (string, error) {
+	started := time.Now()
+	emit(ctx, Event{Type: EventToolSelected, Tool: name, Input: input, Thought: thought})
+	observation, err := invokeTool(ctx, name, input)
+	if err != nil {
+		return "", err
+	}
+	// Constrict the observation to the max tokens allowed by the model.
+	// This is required because the tool may have generated a long output.
+	observation = constrictObservation(observation, model)
+	emit(ctx, Event{Type: EventToolCompleted, Tool: name, Input: input, Observation: observation,
+		DurationMs: time.Since(started).Milliseconds()})
+	return observation, nil
+}
+
+// invokeTool 调用工具，将各类失败转换为观察结果
+func invokeTool(ctx context.Context, name, input string) (string, error) {
+	logger := utils.LoggerFromContext(ctx)
+	perfStats := utils.GetPerfStats()
+
+	var observation string
+	ProgressFromContext(ctx).Enter(StageQueryingCluster, name)
+	// 开始工具执行计时
+	perfStats.StartTimer("assistant_tool_" + name)
+
+	ret, err := tools.Invoke(ctx, name, input)
+	var quotaErr *tools.QuotaExceededError
+	var unavailableErr *tools.TargetUnavailableError
+	var timeoutErr *tools.ToolTimeoutError
+	var policyErr *tools.PolicyViolationError
+	var approvalErr *tools.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+		return "", approvalErr
+	} else if errors.As(err, &quotaErr) {
+		// 超出配额时将原因作为观察结果返回给 LLM，由其基于已有信息作答
+		perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+		observation = quotaErr.Error()
+	} else if errors.As(err, &unavailableErr) {
+		// 目标不可达时要求 LLM 返回部分结果，避免在该目标上耗尽迭代次数
+		perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+		observation = unavailableErr.Error()
+	} else if errors.As(err, &policyErr) {
+		// 违反只读策略时命令未执行，将结构化的拒绝原因返回给 LLM，由其给出只读结论和建议的命令
+		perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+		observation = policyErr.Error()
+	} else if errors.As(err, &timeoutErr) {
+		// 超时时提示 LLM 缩小查询范围，部分输出通常不完整，不作为观察结果
+		toolDuration := perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+		logger.Warn("工具执行超时",
+			zap.String("tool", name),
+			zap.Duration("timeout", timeoutErr.Timeout),
+			zap.Duration("duration", toolDuration),
+		)
+		observation = timeoutErr.Error()
+	} else if !errors.Is(err, tools.ErrToolNotFound) {
+		observation = strings.TrimSpace(ret)
+
+		// 停止工具执行计时
+		toolDuration := perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+
+		if err != nil {
+			logger.Error("工具执行失败",
+				zap.String("tool", name),
+				zap.Error(err),
+				zap.Duration("duration", toolDuration),
+			)
+			observation = fmt.Sprintf("Tool %s failed with error %s. Considering refine the inputs for the tool.", name, ret)
+		} else {
+			logger.Debug("工具执行成功",
+				zap.String("tool", name),
+				zap.String("observation", observation),
+				zap.Duration("duration", toolDuration),
+			)
+		}
+	} else {
+		// 停止工具执行计时（工具不可用的情况）
+		toolDuration := perfStats.StopSpanTimer(ctx, "assistant_tool_"+name)
+
+		logger.Warn("工具不可用",
+			zap.String("tool", name),
+			zap.Duration("duration", toolDuration),
+		)
+		observation = fmt.Sprintf("Tool %s is not available. Considering switch to other supported tools.", name)
+	}
+	return observation, nil
+}
+
+// constrictObservation 将观察结果裁剪到 llm.budget.observation_tokens（默认 1024）以内
+// 裁剪会丢弃开头的行，缓存命中的说明需要在裁剪后重新加上，工具历史依赖它标记缓存结果
+func constrictObservation(observation, model string) string {
+	if !tools.IsCachedResult(observation) {
+		return llms.ConstrictPrompt(observation, model, llms.ObservationTokens())
+	}
+	return tools.CachedResultNote + llms.ConstrictPrompt(strings.TrimPrefix(observation, tools.CachedResultNote), model, llms.ObservationTokens())
+}
+
 // isTemplateValue 检查字符串是否为模板值或占位符
 // 参数：
 //   - value: 要检查的字符串
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrApplyRequestNotFound 应用请求不存在
+var ErrApplyRequestNotFound = errors.New("apply request not found")
+
+// ErrApplyRequestConflict 应用请求的状态已被其他请求修改
+var ErrApplyRequestConflict = errors.New("apply request status changed concurrently")
+
+// ApplyRequest 生成清单的应用请求，Objects 和 Diff 为 JSON，结构由 workflows 定义
+type ApplyRequest struct {
+	ID          string
+	Manifest    string
+	Hash        string
+	Objects     string
+	Diff        string
+	Status      string
+	RequestedBy string
+	ReviewedBy  string
+	Error       string
+	CreatedAt   time.Time
+	ReviewedAt  *time.Time
+}
+
+const applyRequestColumns = `id, manifest, hash, objects, diff, status, requested_by, reviewed_by, error, created_at, reviewed_at`
+
+// SaveApplyRequest 写入或更新应用请求，与审批记录一样同步写入
+func (s *Store) SaveApplyRequest(ctx context.Context, r *ApplyRequest) error {
+	_, err := s.dialect.exec(ctx, s.db, s.dialect.upsert(
+		`INSERT INTO apply_requests (`+applyRequestColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		[]string{"id"}, "status", "reviewed_by", "error", "reviewed_at"),
+		r.ID, r.Manifest, r.Hash, r.Objects, r.Diff, r.Status, r.RequestedBy, r.ReviewedBy, r.Error, r.CreatedAt, r.ReviewedAt,
+	)
+	return err
+}
+
+// TransitionApplyRequest 仅当应用请求仍处于 from 状态时更新为 r.Status，多个实例同时审批时只有一个成功
+func (s *Store) TransitionApplyRequest(ctx context.Context, r *ApplyRequest, from string) error {
+	result, err := s.dialect.exec(ctx, s.db,
+		`UPDATE apply_requests SET status = $1, reviewed_by = $2, reviewed_at = $3, error = $4 WHERE id = $5 AND status = $6`,
+		r.Status, r.ReviewedBy, r.ReviewedAt, r.Error, r.ID, from,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrApplyRequestConflict
+	}
+	return nil
+}
+
+// GetApplyRequest 获取应用请求
+func (s *Store) GetApplyRequest(ctx context.Context, id string) (*ApplyRequest, error) {
+	row := s.dialect.queryRow(ctx, s.db, `SELECT `+applyRequestColumns+` FROM apply_requests WHERE id = $1`, id)
+	r, err := scanApplyRequest(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrApplyRequestNotFound
+	}
+	return r, err
+}
+
+// ListApplyRequests 按创建时间倒序列出应用请求，status 为空时不过滤
+func (s *Store) ListApplyRequests(ctx context.Context, status string, limit int) ([]*ApplyRequest, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT `+applyRequestColumns+` FROM apply_requests
+		WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*ApplyRequest
+	for rows.Next() {
+		r, err := scanApplyRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, r)
+	}
+	return requests, rows.Err()
+}
+
+func scanApplyRequest(row scanner) (*ApplyRequest, error) {
+	var (
+		r          ApplyRequest
+		reviewedAt sql.NullTime
+	)
+	err := row.Scan(&r.ID, &r.Manifest, &r.Hash, &r.Objects, &r.Diff, &r.Status, &r.RequestedBy, &r.ReviewedBy,
+		&r.Error, &r.CreatedAt, &reviewedAt)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		r.ReviewedAt = &reviewedAt.Time
+	}
+	return &r, nil
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrApprovalNotFound 审批记录不存在
+var ErrApprovalNotFound = errors.New("approval not found")
+
+// ErrApprovalConflict 审批记录的状态已被其他请求修改
+var ErrApprovalConflict = errors.New("approval status changed concurrently")
+
+// Approval 待人工审批的变更命令及暂停的对话
+// 对话历史保存为 JSON，审批后据此恢复 Assistant 循环
+type Approval struct {
+	ID            string     `json:"id"`
+	InteractionID string     `json:"interaction_id"`
+	Username      string     `json:"username"`
+	Question      string     `json:"question"`
+	Tool          string     `json:"tool"`
+	Input         string     `json:"input"`
+	Verb          string     `json:"verb"`
+	KubeContext   string     `json:"kube_context,omitempty"`
+	Provider      string     `json:"provider,omitempty"`
+	Model         string     `json:"model"`
+	BaseURL       string     `json:"-"`
+	Messages      string     `json:"-"`
+	Status        string     `json:"status"`
+	ReviewedBy    string     `json:"reviewed_by,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	Output        string     `json:"output,omitempty"`
+	Answer        string     `json:"answer,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+const approvalColumns = `id, interaction_id, username, question, tool, input, verb, kube_context, provider, model, base_url,
+	messages, status, reviewed_by, reviewed_at, output, answer, error, created_at, updated_at`
+
+// SaveApproval 写入或更新审批记录
+// 审批记录数量少且必须可靠保存（服务重启后仍可审批），因此同步写入，不经过批量写入队列
+func (s *Store) SaveApproval(ctx context.Context, a *Approval) error {
+	_, err := s.dialect.exec(ctx, s.db, s.dialect.upsert(
+		`INSERT INTO approvals (`+approvalColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`,
+		[]string{"id"}, "status", "reviewed_by", "reviewed_at", "output", "answer", "error", "updated_at"),
+		a.ID, a.InteractionID, a.Username, a.Question, a.Tool, a.Input, a.Verb, a.KubeContext, a.Provider, a.Model, a.BaseURL,
+		a.Messages, a.Status, a.ReviewedBy, a.ReviewedAt, a.Output, a.Answer, a.Error, a.CreatedAt, a.UpdatedAt,
+	)
+	return err
+}
+
+// TransitionApproval 仅当审批记录仍处于 from 状态时更新为 a.Status，多个实例同时审批时只有一个成功
+func (s *Store) TransitionApproval(ctx context.Context, a *Approval, from string) error {
+	result, err := s.dialect.exec(ctx, s.db,
+		`UPDATE approvals SET status = $1, reviewed_by = $2, reviewed_at = $3, updated_at = $4 WHERE id = $5 AND status = $6`,
+		a.Status, a.ReviewedBy, a.ReviewedAt, a.UpdatedAt, a.ID, from,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrApprovalConflict
+	}
+	return nil
+}
+
+// GetApproval 获取审批记录
+func (s *Store) GetApproval(ctx context.Context, id string) (*Approval, error) {
+	row := s.dialect.queryRow(ctx, s.db, `SELECT `+approvalColumns+` FROM approvals WHERE id = $1`, id)
+	a, err := scanApproval(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrApprovalNotFound
+	}
+	return a, err
+}
+
+// ListApprovals 按创建时间倒序列出审批记录，status、username 为空时不过滤
+func (s *Store) ListApprovals(ctx context.Context, status, username string, limit int) ([]*Approval, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT `+approvalColumns+` FROM approvals
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR username = $2)
+		ORDER BY created_at DESC LIMIT $3`, status, username, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var approvals []*Approval
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+func scanApproval(row scanner) (*Approval, error) {
+	var (
+		a          Approval
+		reviewedAt sql.NullTime
+	)
+	err := row.Scan(&a.ID, &a.InteractionID, &a.Username, &a.Question, &a.Tool, &a.Input, &a.Verb, &a.KubeContext,
+		&a.Provider, &a.Model, &a.BaseURL, &a.Messages, &a.Status, &a.ReviewedBy, &reviewedAt, &a.Output, &a.Answer,
+		&a.Error, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		a.ReviewedAt = &reviewedAt.Time
+	}
+	return &a, nil
+}
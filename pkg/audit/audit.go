@@ -0,0 +1,636 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/chaos"
+	"github.com/myysophia/OpsAgent/pkg/redact"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	// StatusSuccess 交互成功完成
+	StatusSuccess = "success"
+	// StatusError 交互执行失败
+	StatusError = "error"
+	// StatusPendingApproval 助手提出的变更命令等待人工审批，对话暂停
+	StatusPendingApproval = "pending_approval"
+	// StatusHandedOff 回答置信度低，未交付草稿，已转交值班人员
+	StatusHandedOff = "handed_off"
+
+	defaultQueueSize = 1024
+	defaultBatchSize = 50
+)
+
+// Interaction 一次用户交互的审计记录
+type Interaction struct {
+	ID         string     `json:"id"`
+	Username   string     `json:"username"`
+	Model      string     `json:"model"`
+	Cluster    string     `json:"cluster"`
+	Question   string     `json:"question"`
+	Answer     string     `json:"answer"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	DurationMs int64      `json:"duration_ms"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	RAGCalls   []RAGCall  `json:"rag_calls,omitempty"`
+	// 启用回答审阅时的各版回答草稿及审阅意见，用于分析回答质量
+	Drafts []AnswerDraft `json:"drafts,omitempty"`
+
+	// 本次交互使用的系统提示，用于关联提示发布与回答质量的变化
+	PromptName    string `json:"prompt_name,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	PromptHash    string `json:"prompt_hash,omitempty"`
+
+	// 本次交互所有 LLM 调用的 token 用量
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	// token 用量的来源：recorded、cached、derived 或 estimated，见 UsageRecorded 等常量
+	UsageSource string `json:"usage_source,omitempty"`
+	// 每次 LLM 调用的用量和耗时，用于把延迟和成本归因到具体的迭代
+	LLMCalls []LLMCall `json:"llm_calls,omitempty"`
+	// 启用执行回执时每次工具执行的签名回执
+	Receipts []ToolReceipt `json:"receipts,omitempty"`
+
+	// 发起交互的请求所在的 span，后台写入时的 span 挂在同一 trace 下
+	spanContext trace.SpanContext
+}
+
+// ToolCall 交互中的一次工具调用
+type ToolCall struct {
+	Seq         int    `json:"seq"`
+	Name        string `json:"name"`
+	Input       string `json:"input"`
+	Observation string `json:"observation"`
+	// 工具执行耗时，由 Assistant 的 tool_completed 事件记录；升级前的记录由相邻 LLM 调用的时间和执行回执推算，无法推算时为 0
+	DurationMs int64 `json:"duration_ms"`
+	// 观察结果的 token 数，即工具输出在下一轮提示中的占用
+	ObservationTokens int `json:"observation_tokens"`
+}
+
+// RAGCall 交互中的一次检索调用，记录查询、解析出的上下文、耗时和 token 用量
+type RAGCall struct {
+	Seq          int       `json:"seq"`
+	Kind         string    `json:"kind"`
+	Query        string    `json:"query"`
+	Context      []string  `json:"context"`
+	LatencyMs    int64     `json:"latency_ms"`
+	PromptTokens int       `json:"prompt_tokens"`
+	TotalTokens  int       `json:"total_tokens"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LLMCall 交互中的一次 LLM 调用，Seq 为调用顺序（即第几轮迭代）
+type LLMCall struct {
+	Seq              int       `json:"seq"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AnswerDraft 回答审阅中的一版回答：第 1 版为助手的草稿，审阅未通过时第 2 版为修改后的回答
+type AnswerDraft struct {
+	Seq      int    `json:"seq"`
+	Answer   string `json:"answer"`
+	Reviewer string `json:"reviewer,omitempty"` // 审阅模型
+	Verdict  string `json:"verdict"`            // approved、revise（需要修改）或 revised（修改后的回答）
+	Critique string `json:"critique,omitempty"`
+	// ReviewError 审阅失败时的错误，此时直接使用草稿
+	ReviewError string    `json:"review_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Store 审计存储
+type Store struct {
+	db      *sql.DB
+	dialect *dialect
+	queue   chan *Interaction
+	wg      sync.WaitGroup
+	logger  *zap.Logger
+
+	batchSize int
+	stats     queueStats
+	stop      chan struct{}
+}
+
+var (
+	globalStore *Store
+	storeMu     sync.RWMutex
+)
+
+// Init 根据配置初始化全局审计存储，audit.enabled 为 false 时不做任何事情
+func Init() error {
+	config := utils.GetConfig()
+	if !config.GetBool("audit.enabled") {
+		return nil
+	}
+
+	store, err := Open(config.GetString("audit.driver"), config.GetString("audit.dsn"))
+	if err != nil {
+		return err
+	}
+
+	storeMu.Lock()
+	globalStore = store
+	storeMu.Unlock()
+	store.startBackfill()
+	return nil
+}
+
+// GetStore 获取全局审计存储，未启用审计时返回 nil
+func GetStore() *Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return globalStore
+}
+
+// Open 打开审计数据库并初始化或升级表结构，driver 为 postgres（默认）、sqlite 或 mysql
+func Open(driver, dsn string) (*Store, error) {
+	d, err := getDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	if dsn == "" {
+		return nil, fmt.Errorf("audit.dsn is not set")
+	}
+
+	db, err := d.open(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计数据库失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接审计数据库失败: %v", err)
+	}
+	if err := d.migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化审计表结构失败: %v", err)
+	}
+
+	s := &Store{
+		db:        db,
+		dialect:   d,
+		queue:     make(chan *Interaction, defaultQueueSize),
+		logger:    utils.GetLogger().Named("audit"),
+		batchSize: defaultBatchSize,
+		stop:      make(chan struct{}),
+	}
+	if size := utils.GetConfig().GetInt("audit.batch_size"); size > 0 {
+		s.batchSize = size
+	}
+	s.wg.Add(1)
+	go s.worker()
+	go s.monitorLag(newLagAlert())
+	return s, nil
+}
+
+// CheckSchema 只读地检查审计数据库的连接和表结构版本，不会创建或修改表
+// 返回数据库中记录的最高版本，未记录版本时返回 0
+func CheckSchema(ctx context.Context, driver, dsn string) (int, error) {
+	d, err := getDialect(driver)
+	if err != nil {
+		return 0, err
+	}
+	if dsn == "" {
+		return 0, fmt.Errorf("audit.dsn is not set")
+	}
+
+	db, err := d.open(dsn)
+	if err != nil {
+		return 0, fmt.Errorf("打开审计数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return 0, fmt.Errorf("连接审计数据库失败: %v", err)
+	}
+
+	version, err := d.schemaVersion(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("查询审计表结构版本失败: %v", err)
+	}
+	return version, nil
+}
+
+// NewInteractionID 生成交互 ID
+func NewInteractionID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Record 异步记录一次交互，未启用审计时直接忽略
+func Record(interaction *Interaction) {
+	RecordContext(context.Background(), interaction)
+}
+
+// RecordContext 异步记录一次交互，后台写入的 span 归属于 ctx 中请求的 trace
+func RecordContext(ctx context.Context, interaction *Interaction) {
+	interaction.spanContext = trace.SpanContextFromContext(ctx)
+	store := GetStore()
+	if store == nil {
+		return
+	}
+
+	store.stats.enqueue()
+	select {
+	case store.queue <- interaction:
+	default:
+		store.stats.drop()
+		store.logger.Warn("审计队列已满，丢弃记录",
+			zap.String("interaction_id", interaction.ID),
+		)
+	}
+}
+
+// Shutdown 停止接收新的审计记录，等待队列中的记录写入完成后关闭全局审计存储
+// ctx 到期时不再等待，返回 ctx 的错误，尚未写入的记录会丢失
+func Shutdown(ctx context.Context) error {
+	storeMu.Lock()
+	store := globalStore
+	globalStore = nil
+	storeMu.Unlock()
+	if store == nil {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- store.Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 等待队列中的记录写入完成并关闭数据库
+func (s *Store) Close() error {
+	close(s.stop)
+	close(s.queue)
+	s.wg.Wait()
+	return s.db.Close()
+}
+
+// worker 后台批量写入审计记录
+// 每次取出队列中已有的记录（最多 audit.batch_size 条）在一个事务中提交
+func (s *Store) worker() {
+	defer s.wg.Done()
+	for interaction := range s.queue {
+		batch := []*Interaction{interaction}
+	drain:
+		for len(batch) < s.batchSize {
+			select {
+			case next, ok := <-s.queue:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+		s.writeBatch(context.Background(), batch)
+	}
+}
+
+// writeBatch 写入一批记录，批量提交失败时逐条重试，避免单条异常记录导致整批丢失
+func (s *Store) writeBatch(ctx context.Context, batch []*Interaction) {
+	defer s.stats.done(len(batch))
+
+	start := time.Now()
+	err := s.insertBatch(ctx, batch)
+	s.stats.commit(len(batch), time.Since(start), err)
+	if err == nil {
+		return
+	}
+
+	s.logger.Warn("批量写入审计记录失败，逐条重试",
+		zap.Int("batch_size", len(batch)),
+		zap.Error(err),
+	)
+	for _, interaction := range batch {
+		if err := s.insertBatch(ctx, []*Interaction{interaction}); err != nil {
+			s.stats.fail()
+			s.logger.Error("写入审计记录失败",
+				zap.String("interaction_id", interaction.ID),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// insertBatch 在一个事务中写入多条交互及其工具调用
+func (s *Store) insertBatch(ctx context.Context, batch []*Interaction) error {
+	if err := chaos.Inject(chaos.TargetDB); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, interaction := range batch {
+		spanCtx, span := utils.StartSpan(trace.ContextWithSpanContext(ctx, interaction.spanContext), "audit.insert",
+			attribute.String("db.system", s.dialect.name),
+			attribute.String(utils.LogFieldInteraction, interaction.ID),
+			attribute.Int("audit.batch_size", len(batch)),
+		)
+		err := s.insert(spanCtx, tx, interaction)
+		utils.EndSpan(span, err)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// insert 在事务中写入交互及其工具、检索和 LLM 调用，问题、回答和工具调用在写入前脱敏
+func (s *Store) insert(ctx context.Context, tx *sql.Tx, interaction *Interaction) error {
+	// 新记录的 token 用量由 token 预算记录，为 0 表示没有调用 LLM，不需要估算
+	if interaction.UsageSource == "" {
+		interaction.UsageSource = UsageRecorded
+	}
+	fillUsage(interaction)
+	_, err := s.dialect.exec(ctx, tx,
+		`INSERT INTO interactions (id, username, model, cluster, question, answer, status, error, duration_ms, created_at,
+			prompt_name, prompt_version, prompt_hash, prompt_tokens, completion_tokens, usage_source)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		interaction.ID, interaction.Username, interaction.Model, interaction.Cluster, redact.String(interaction.Question),
+		redact.String(interaction.Answer), interaction.Status, redact.String(interaction.Error), interaction.DurationMs, interaction.CreatedAt,
+		interaction.PromptName, interaction.PromptVersion, interaction.PromptHash,
+		interaction.PromptTokens, interaction.CompletionTokens, interaction.UsageSource,
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, call := range interaction.ToolCalls {
+		_, err = s.dialect.exec(ctx, tx,
+			`INSERT INTO tool_calls (interaction_id, seq, name, input, observation, duration_ms, observation_tokens)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			interaction.ID, call.Seq, call.Name, redact.String(call.Input), redact.String(call.Observation),
+			call.DurationMs, call.ObservationTokens,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, call := range interaction.RAGCalls {
+		contextJSON, err := json.Marshal(call.Context)
+		if err != nil {
+			return err
+		}
+		_, err = s.dialect.exec(ctx, tx,
+			`INSERT INTO rag_calls (interaction_id, seq, kind, query, context, latency_ms, prompt_tokens, total_tokens, error, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			interaction.ID, call.Seq, call.Kind, redact.String(call.Query), redact.String(string(contextJSON)), call.LatencyMs,
+			call.PromptTokens, call.TotalTokens, call.Error, call.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, call := range interaction.LLMCalls {
+		_, err = s.dialect.exec(ctx, tx,
+			`INSERT INTO llm_calls (interaction_id, seq, model, prompt_tokens, completion_tokens, latency_ms, finish_reason, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			interaction.ID, call.Seq, call.Model, call.PromptTokens, call.CompletionTokens, call.LatencyMs,
+			call.FinishReason, call.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, receipt := range interaction.Receipts {
+		_, err = s.dialect.exec(ctx, tx,
+			`INSERT INTO tool_receipts (interaction_id, seq, tool, input_hash, output_hash, executed_at, runner, key_id, signature)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			interaction.ID, receipt.Seq, receipt.Tool, receipt.InputHash, receipt.OutputHash, receipt.ExecutedAt,
+			receipt.Runner, receipt.KeyID, receipt.Signature,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, draft := range interaction.Drafts {
+		_, err = s.dialect.exec(ctx, tx,
+			`INSERT INTO answer_drafts (interaction_id, seq, answer, reviewer, verdict, critique, review_error, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			interaction.ID, draft.Seq, redact.String(draft.Answer), draft.Reviewer, draft.Verdict, draft.Critique,
+			draft.ReviewError, draft.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetInteraction 获取单个交互及其工具调用
+func (s *Store) GetInteraction(ctx context.Context, id string) (*Interaction, error) {
+	row := s.dialect.queryRow(ctx, s.db,
+		`SELECT `+interactionColumns+` FROM interactions WHERE id = $1`, id)
+	interaction, err := scanInteraction(row)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT seq, name, input, observation, duration_ms, observation_tokens
+		FROM tool_calls WHERE interaction_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var call ToolCall
+		if err := rows.Scan(&call.Seq, &call.Name, &call.Input, &call.Observation, &call.DurationMs, &call.ObservationTokens); err != nil {
+			return nil, err
+		}
+		interaction.ToolCalls = append(interaction.ToolCalls, call)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ragRows, err := s.dialect.query(ctx, s.db,
+		`SELECT seq, kind, query, context, latency_ms, prompt_tokens, total_tokens, error, created_at
+		FROM rag_calls WHERE interaction_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer ragRows.Close()
+
+	for ragRows.Next() {
+		var (
+			call        RAGCall
+			contextJSON string
+		)
+		if err := ragRows.Scan(&call.Seq, &call.Kind, &call.Query, &contextJSON, &call.LatencyMs,
+			&call.PromptTokens, &call.TotalTokens, &call.Error, &call.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(contextJSON), &call.Context); err != nil {
+			return nil, err
+		}
+		interaction.RAGCalls = append(interaction.RAGCalls, call)
+	}
+	if err := ragRows.Err(); err != nil {
+		return nil, err
+	}
+
+	llmRows, err := s.dialect.query(ctx, s.db,
+		`SELECT seq, model, prompt_tokens, completion_tokens, latency_ms, finish_reason, created_at
+		FROM llm_calls WHERE interaction_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer llmRows.Close()
+
+	for llmRows.Next() {
+		var call LLMCall
+		if err := llmRows.Scan(&call.Seq, &call.Model, &call.PromptTokens, &call.CompletionTokens, &call.LatencyMs,
+			&call.FinishReason, &call.CreatedAt); err != nil {
+			return nil, err
+		}
+		interaction.LLMCalls = append(interaction.LLMCalls, call)
+	}
+	if err := llmRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if interaction.Receipts, err = s.ToolReceipts(ctx, id); err != nil {
+		return nil, err
+	}
+
+	draftRows, err := s.dialect.query(ctx, s.db,
+		`SELECT seq, answer, reviewer, verdict, critique, review_error, created_at
+		FROM answer_drafts WHERE interaction_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer draftRows.Close()
+
+	for draftRows.Next() {
+		var draft AnswerDraft
+		if err := draftRows.Scan(&draft.Seq, &draft.Answer, &draft.Reviewer, &draft.Verdict, &draft.Critique,
+			&draft.ReviewError, &draft.CreatedAt); err != nil {
+			return nil, err
+		}
+		interaction.Drafts = append(interaction.Drafts, draft)
+	}
+	return interaction, draftRows.Err()
+}
+
+// ListInteractions 按查询条件分页列出交互，返回下一页游标
+func (s *Store) ListInteractions(ctx context.Context, q Query) ([]Interaction, string, error) {
+	query, args, err := q.Build()
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := s.dialect.query(ctx, s.db, query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var interactions []Interaction
+	for rows.Next() {
+		interaction, err := scanInteraction(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		interactions = append(interactions, *interaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(interactions) == q.limit() {
+		next = q.NextCursor(&interactions[len(interactions)-1])
+	}
+	return interactions, next, nil
+}
+
+// ToolCalls 批量获取多个交互的工具调用，键为交互 ID
+func (s *Store) ToolCalls(ctx context.Context, ids []string) (map[string][]ToolCall, error) {
+	calls := make(map[string][]ToolCall, len(ids))
+	if len(ids) == 0 {
+		return calls, nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT interaction_id, seq, name, input, observation, duration_ms, observation_tokens FROM tool_calls
+		WHERE interaction_id IN (`+strings.Join(placeholders, ", ")+`) ORDER BY interaction_id, seq`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   string
+			call ToolCall
+		)
+		if err := rows.Scan(&id, &call.Seq, &call.Name, &call.Input, &call.Observation, &call.DurationMs, &call.ObservationTokens); err != nil {
+			return nil, err
+		}
+		calls[id] = append(calls[id], call)
+	}
+	return calls, rows.Err()
+}
+
+const interactionColumns = `id, username, model, cluster, question, answer, status, error, duration_ms, created_at,
+	prompt_name, prompt_version, prompt_hash, prompt_tokens, completion_tokens, usage_source`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInteraction(row scanner) (*Interaction, error) {
+	var i Interaction
+	err := row.Scan(&i.ID, &i.Username, &i.Model, &i.Cluster, &i.Question, &i.Answer,
+		&i.Status, &i.Error, &i.DurationMs, &i.CreatedAt, &i.PromptName, &i.PromptVersion, &i.PromptHash,
+		&i.PromptTokens, &i.CompletionTokens, &i.UsageSource)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
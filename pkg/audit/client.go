@@ -0,0 +1,11 @@
+package audit
+
+import "context"
+
+// Reader 审计记录只读查询接口，可直接查询数据库或通过 API 查询（见 Go SDK pkg/client）
+type Reader interface {
+	ListInteractions(ctx context.Context, q Query) ([]Interaction, string, error)
+	GetInteraction(ctx context.Context, id string) (*Interaction, error)
+}
+
+var _ Reader = (*Store)(nil)
@@ -0,0 +1,227 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 支持的 audit.driver
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+)
+
+// dialect 数据库方言
+// 审计 SQL 统一按 Postgres 的 $N 占位符编写，执行前由 rebind 转换为各驱动的占位符；
+// upsert 等语法差异较大的语句按方言分别生成，表结构见 migrations 下各驱动的迁移文件
+type dialect struct {
+	name   string // audit.driver 的取值，同时是 migrations 下的目录名
+	driver string // database/sql 驱动名
+	// versionTable 创建记录迁移版本的 schema_version 表
+	versionTable string
+	// lock 和 unlock 执行迁移前后获取和释放的数据库锁，避免多个实例同时迁移，为空时不加锁
+	lock, unlock string
+	// tableExists 查询表是否存在的语句，参数为表名
+	tableExists string
+}
+
+var dialects = map[string]*dialect{
+	DriverPostgres: {
+		name:   DriverPostgres,
+		driver: "postgres",
+		versionTable: `CREATE TABLE IF NOT EXISTS schema_version (
+	version    INT PRIMARY KEY,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+ALTER TABLE schema_version ADD COLUMN IF NOT EXISTS name VARCHAR(128) NOT NULL DEFAULT ''`,
+		lock:        `SELECT pg_advisory_lock(7164357)`,
+		unlock:      `SELECT pg_advisory_unlock(7164357)`,
+		tableExists: `SELECT to_regclass($1) IS NOT NULL`,
+	},
+	DriverSQLite: {
+		name:   DriverSQLite,
+		driver: "sqlite3",
+		// SQLite 的写事务本身是互斥的，无需额外加锁
+		versionTable: `CREATE TABLE IF NOT EXISTS schema_version (
+	version    INT PRIMARY KEY,
+	name       VARCHAR(128) NOT NULL DEFAULT '',
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+		tableExists: `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = $1`,
+	},
+	DriverMySQL: {
+		name:   DriverMySQL,
+		driver: "mysql",
+		versionTable: `CREATE TABLE IF NOT EXISTS schema_version (
+	version    INT PRIMARY KEY,
+	name       VARCHAR(128) NOT NULL DEFAULT '',
+	applied_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)
+) DEFAULT CHARSET = utf8mb4`,
+		lock:        `SELECT GET_LOCK('opsagent_audit_migrate', 300)`,
+		unlock:      `SELECT RELEASE_LOCK('opsagent_audit_migrate')`,
+		tableExists: `SELECT COUNT(*) > 0 FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = $1`,
+	},
+}
+
+// getDialect 根据 audit.driver 获取方言，为空时使用 postgres，sqlite3 视为 sqlite
+func getDialect(driver string) (*dialect, error) {
+	switch strings.ToLower(driver) {
+	case "", "postgresql", "pgx":
+		driver = DriverPostgres
+	case "sqlite3":
+		driver = DriverSQLite
+	}
+	d, ok := dialects[strings.ToLower(driver)]
+	if !ok {
+		return nil, fmt.Errorf("不支持的审计数据库驱动 %q，可选值: postgres, sqlite, mysql", driver)
+	}
+	return d, nil
+}
+
+// open 打开数据库，补充各驱动必需的连接参数
+func (d *dialect) open(dsn string) (*sql.DB, error) {
+	switch d.name {
+	case DriverSQLite:
+		// 并发写入时等待锁而不是直接返回 SQLITE_BUSY，并启用外键以支持级联删除
+		dsn = withQueryDefaults(dsn, map[string]string{
+			"_busy_timeout": "5000",
+			"_foreign_keys": "on",
+			"_journal_mode": "WAL",
+		})
+	case DriverMySQL:
+		config, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+		// DATETIME 列扫描为 time.Time，时间统一按 UTC 存储
+		config.ParseTime = true
+		config.Loc = time.UTC
+		dsn = config.FormatDSN()
+	}
+	return sql.Open(d.driver, dsn)
+}
+
+// withQueryDefaults 为 DSN 补充未设置的查询参数
+func withQueryDefaults(dsn string, defaults map[string]string) string {
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return dsn
+	}
+	for key, value := range defaults {
+		if _, ok := query[key]; !ok {
+			query.Set(key, value)
+		}
+	}
+	return path + "?" + query.Encode()
+}
+
+var placeholderRegexp = regexp.MustCompile(`\$(\d+)`)
+
+// rebind 将 $N 占位符转换为驱动的占位符
+// SQLite 使用 ?N，同一参数可以多次引用；MySQL 只支持按出现顺序绑定的 ?，重复引用的参数会被复制
+// SQLite 的时间按文本存储和比较，因此统一转换为 UTC
+func (d *dialect) rebind(query string, args []interface{}) (string, []interface{}) {
+	switch d.name {
+	case DriverSQLite:
+		for i, arg := range args {
+			switch t := arg.(type) {
+			case time.Time:
+				args[i] = t.UTC()
+			case *time.Time:
+				if t != nil {
+					args[i] = t.UTC()
+				}
+			}
+		}
+		return placeholderRegexp.ReplaceAllString(query, "?$1"), args
+	case DriverMySQL:
+		var bound []interface{}
+		query = placeholderRegexp.ReplaceAllStringFunc(query, func(placeholder string) string {
+			n, _ := strconv.Atoi(placeholder[1:])
+			if n >= 1 && n <= len(args) {
+				bound = append(bound, args[n-1])
+			}
+			return "?"
+		})
+		return query, bound
+	}
+	return query, args
+}
+
+// upsert 为 INSERT 语句追加冲突处理：update 为空时忽略冲突的行，否则用新值更新 update 中的列
+func (d *dialect) upsert(insert string, conflict []string, update ...string) string {
+	if d.name == DriverMySQL {
+		if len(update) == 0 {
+			return strings.Replace(insert, "INSERT INTO", "INSERT IGNORE INTO", 1)
+		}
+		sets := make([]string, len(update))
+		for i, column := range update {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+		}
+		return insert + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	}
+
+	clause := fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflict, ", "))
+	if len(update) > 0 {
+		sets := make([]string, len(update))
+		for i, column := range update {
+			sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+		}
+		clause = fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(sets, ", "))
+	}
+	return insert + clause
+}
+
+// execer 和 querier 由 *sql.DB 和 *sql.Tx 实现
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (d *dialect) exec(ctx context.Context, db execer, query string, args ...interface{}) (sql.Result, error) {
+	query, args = d.rebind(query, args)
+	return db.ExecContext(ctx, query, args...)
+}
+
+func (d *dialect) query(ctx context.Context, db querier, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args = d.rebind(query, args)
+	return db.QueryContext(ctx, query, args...)
+}
+
+func (d *dialect) queryRow(ctx context.Context, db querier, query string, args ...interface{}) *sql.Row {
+	query, args = d.rebind(query, args)
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// schemaVersion 返回数据库中记录的最高表结构版本，未记录时返回 0
+func (d *dialect) schemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := d.queryRow(ctx, db, d.tableExists, "schema_version").Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
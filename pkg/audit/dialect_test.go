@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM approvals WHERE ($1 = '' OR status = $1) AND ($2 = '' OR username = $2) LIMIT $3`
+	args := []interface{}{"pending", "", 10}
+
+	got, bound := dialects[DriverMySQL].rebind(query, args)
+	want := `SELECT id FROM approvals WHERE (? = '' OR status = ?) AND (? = '' OR username = ?) LIMIT ?`
+	if got != want || !reflect.DeepEqual(bound, []interface{}{"pending", "pending", "", "", 10}) {
+		t.Errorf("mysql rebind = %q %v", got, bound)
+	}
+
+	got, bound = dialects[DriverSQLite].rebind(query, args)
+	if got != `SELECT id FROM approvals WHERE (?1 = '' OR status = ?1) AND (?2 = '' OR username = ?2) LIMIT ?3` || len(bound) != 3 {
+		t.Errorf("sqlite rebind = %q %v", got, bound)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "audit.db")
+	store, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	// 使用非 UTC 时区写入，查询条件和游标仍按时间先后比较
+	zone := time.FixedZone("CST", 8*3600)
+	created := time.Now().Add(-time.Hour).In(zone).Truncate(time.Microsecond)
+	interaction := &Interaction{ID: "i1", Username: "alice", Question: "q", Status: StatusSuccess, CreatedAt: created,
+		ToolCalls: []ToolCall{{Seq: 1, Name: "kubectl", Input: "get pods"}},
+		LLMCalls:  []LLMCall{{Seq: 1, Model: "gpt-4o", PromptTokens: 10, CreatedAt: created}},
+		Receipts:  []ToolReceipt{{Seq: 1, Tool: "kubectl", InputHash: "in", OutputHash: "out", ExecutedAt: created, Runner: "opsagent", KeyID: "k1", Signature: "sig"}}}
+	if err := store.insertBatch(ctx, []*Interaction{interaction}); err != nil {
+		t.Fatalf("insertBatch() error = %v", err)
+	}
+
+	got, err := store.GetInteraction(ctx, "i1")
+	if err != nil || !got.CreatedAt.Equal(created) || len(got.ToolCalls) != 1 || len(got.LLMCalls) != 1 || len(got.Receipts) != 1 {
+		t.Fatalf("GetInteraction() = %+v, %v", got, err)
+	}
+	if r := got.Receipts[0]; r.Signature != "sig" || !r.ExecutedAt.Equal(created) {
+		t.Errorf("Receipts = %+v", got.Receipts)
+	}
+	list, _, err := store.ListInteractions(ctx, Query{Filters: map[string]string{"username": "alice"}, Desc: true})
+	if err != nil || len(list) != 1 {
+		t.Errorf("ListInteractions() = %v, %v", list, err)
+	}
+	calls, err := store.ToolCalls(ctx, []string{"i1", "missing"})
+	if err != nil || len(calls["i1"]) != 1 {
+		t.Errorf("ToolCalls() = %v, %v", calls, err)
+	}
+
+	approval := &Approval{ID: "a1", Tool: "kubectl", Input: "scale", Status: "pending", CreatedAt: created, UpdatedAt: created}
+	if err := store.SaveApproval(ctx, approval); err != nil {
+		t.Fatalf("SaveApproval() error = %v", err)
+	}
+	approval.Status = "approved"
+	if err := store.SaveApproval(ctx, approval); err != nil {
+		t.Fatalf("SaveApproval() update error = %v", err)
+	}
+	if err := store.TransitionApproval(ctx, approval, "pending"); err != ErrApprovalConflict {
+		t.Errorf("TransitionApproval() error = %v, want conflict", err)
+	}
+	approvals, err := store.ListApprovals(ctx, "approved", "", 10)
+	if err != nil || len(approvals) != 1 {
+		t.Errorf("ListApprovals() = %v, %v", approvals, err)
+	}
+
+	events := []KubeAuditEvent{
+		{Cluster: "prod", AuditID: "e1", Time: created, Verb: "patch", Resource: "deployments", Name: "gateway"},
+		{Cluster: "prod", AuditID: "e2", Time: created, Verb: "patch", Resource: "deployments", Name: "gateway_v2"},
+	}
+	if n, err := store.SaveKubeAuditEvents(ctx, events); err != nil || n != 2 {
+		t.Fatalf("SaveKubeAuditEvents() = %d, %v", n, err)
+	}
+	if n, err := store.SaveKubeAuditEvents(ctx, events); err != nil || n != 0 {
+		t.Errorf("duplicate SaveKubeAuditEvents() = %d, %v; want 0", n, err)
+	}
+	found, err := store.QueryKubeAuditEvents(ctx, KubeAuditQuery{Cluster: "prod", Name: "gateway_", Since: created.Add(-time.Minute)})
+	if err != nil || len(found) != 1 || found[0].AuditID != "e2" {
+		t.Errorf("QueryKubeAuditEvents() = %v, %v", found, err)
+	}
+
+	store.SetKubeAuditCursor(ctx, "s3", "b")
+	store.SetKubeAuditCursor(ctx, "s3", "a")
+	if key, err := store.KubeAuditCursor(ctx, "s3"); err != nil || key != "b" {
+		t.Errorf("KubeAuditCursor() = %q, %v; want b", key, err)
+	}
+
+	evaluation := &Evaluation{ID: "ev1", Question: "q", BaselineModel: "gpt-4o", CandidateModel: "qwen-max",
+		Result: []byte(`{"comparison":{}}`), CreatedAt: created}
+	if err := store.SaveEvaluation(ctx, evaluation); err != nil {
+		t.Fatalf("SaveEvaluation() error = %v", err)
+	}
+	if got, err := store.GetEvaluation(ctx, "ev1"); err != nil || string(got.Result) != `{"comparison":{}}` {
+		t.Errorf("GetEvaluation() = %+v, %v", got, err)
+	}
+	if list, err := store.ListEvaluations(ctx, "qwen-max", 10); err != nil || len(list) != 1 {
+		t.Errorf("ListEvaluations() = %v, %v", list, err)
+	}
+
+	for _, taken := range []time.Time{created.Add(-48 * time.Hour), created} {
+		if err := store.SaveClusterSnapshot(ctx, &ClusterSnapshot{Cluster: "prod", TakenAt: taken, Objects: 1, Data: []byte{1, 2}}); err != nil {
+			t.Fatalf("SaveClusterSnapshot() error = %v", err)
+		}
+	}
+	if snapshot, err := store.ClusterSnapshotAt(ctx, "prod", created.Add(-time.Hour)); err != nil || !snapshot.TakenAt.Equal(created.Add(-48*time.Hour)) || len(snapshot.Data) != 2 {
+		t.Errorf("ClusterSnapshotAt() = %+v, %v", snapshot, err)
+	}
+	if _, err := store.ClusterSnapshotAt(ctx, "prod", created.Add(-72*time.Hour)); err != ErrSnapshotNotFound {
+		t.Errorf("ClusterSnapshotAt() before the first snapshot error = %v", err)
+	}
+	if n, err := store.DeleteClusterSnapshotsBefore(ctx, created.Add(-time.Hour)); err != nil || n != 1 {
+		t.Errorf("DeleteClusterSnapshotsBefore() = %d, %v", n, err)
+	}
+	if list, err := store.ListClusterSnapshots(ctx, "", 10); err != nil || len(list) != 1 || list[0].Size != 2 {
+		t.Errorf("ListClusterSnapshots() = %+v, %v", list, err)
+	}
+
+	apply := &ApplyRequest{ID: "ap1", Manifest: "kind: ConfigMap", Hash: "h1", Objects: `["ConfigMap/default/demo"]`, Diff: "[]",
+		Status: "pending", RequestedBy: "alice", CreatedAt: created}
+	if err := store.SaveApplyRequest(ctx, apply); err != nil {
+		t.Fatalf("SaveApplyRequest() error = %v", err)
+	}
+	apply.Status, apply.ReviewedBy, apply.ReviewedAt = "applying", "bob", &created
+	if err := store.TransitionApplyRequest(ctx, apply, "pending"); err != nil {
+		t.Fatalf("TransitionApplyRequest() error = %v", err)
+	}
+	if err := store.TransitionApplyRequest(ctx, apply, "pending"); err != ErrApplyRequestConflict {
+		t.Errorf("second TransitionApplyRequest() error = %v, want conflict", err)
+	}
+	if got, err := store.GetApplyRequest(ctx, "ap1"); err != nil || got.Status != "applying" || got.ReviewedBy != "bob" || got.ReviewedAt == nil || got.Objects != apply.Objects {
+		t.Errorf("GetApplyRequest() = %+v, %v", got, err)
+	}
+	if _, err := store.GetApplyRequest(ctx, "missing"); err != ErrApplyRequestNotFound {
+		t.Errorf("GetApplyRequest() missing error = %v", err)
+	}
+	if list, err := store.ListApplyRequests(ctx, "applied", 10); err != nil || len(list) != 0 {
+		t.Errorf("ListApplyRequests(applied) = %v, %v", list, err)
+	}
+	if list, err := store.ListApplyRequests(ctx, "", 10); err != nil || len(list) != 1 {
+		t.Errorf("ListApplyRequests() = %v, %v", list, err)
+	}
+
+	if version, err := CheckSchema(ctx, "sqlite", dsn); err != nil || version != SchemaVersion {
+		t.Errorf("CheckSchema() = %d, %v", version, err)
+	}
+	reopened, err := Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	reopened.Close()
+}
+
+func TestShutdown(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "audit.db")
+	store, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	storeMu.Lock()
+	globalStore = store
+	storeMu.Unlock()
+
+	Record(&Interaction{ID: "i1", Question: "q", Status: StatusSuccess, CreatedAt: time.Now()})
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if GetStore() != nil {
+		t.Error("expected the global store to be detached")
+	}
+	// 退出后的记录直接忽略，不会写入已关闭的队列
+	Record(&Interaction{ID: "i2"})
+
+	reopened, err := Open("sqlite", dsn)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	defer reopened.Close()
+	if _, err := reopened.GetInteraction(context.Background(), "i1"); err != nil {
+		t.Errorf("expected the queued interaction to be written before shutdown: %v", err)
+	}
+}
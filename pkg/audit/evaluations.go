@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/redact"
+)
+
+// ErrEvaluationNotFound 评测记录不存在
+var ErrEvaluationNotFound = errors.New("evaluation not found")
+
+// Evaluation 同一问题在基准模型和候选模型上的执行对比
+// 两次执行分别记录为交互，Result 为对比结果的 JSON，结构由调用方定义
+type Evaluation struct {
+	ID                     string          `json:"id"`
+	Username               string          `json:"username"`
+	Question               string          `json:"question"`
+	Cluster                string          `json:"cluster,omitempty"`
+	BaselineModel          string          `json:"baseline_model"`
+	CandidateModel         string          `json:"candidate_model"`
+	BaselineInteractionID  string          `json:"baseline_interaction_id"`
+	CandidateInteractionID string          `json:"candidate_interaction_id"`
+	Result                 json.RawMessage `json:"result"`
+	CreatedAt              time.Time       `json:"created_at"`
+}
+
+const evaluationColumns = `id, username, question, cluster, baseline_model, candidate_model,
+	baseline_interaction_id, candidate_interaction_id, result, created_at`
+
+// SaveEvaluation 写入评测记录，问题和对比结果中的回答在写入前脱敏
+func (s *Store) SaveEvaluation(ctx context.Context, e *Evaluation) error {
+	_, err := s.dialect.exec(ctx, s.db,
+		`INSERT INTO evaluations (`+evaluationColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		e.ID, e.Username, redact.String(e.Question), e.Cluster, e.BaselineModel, e.CandidateModel,
+		e.BaselineInteractionID, e.CandidateInteractionID, redact.String(string(e.Result)), e.CreatedAt,
+	)
+	return err
+}
+
+// GetEvaluation 获取评测记录
+func (s *Store) GetEvaluation(ctx context.Context, id string) (*Evaluation, error) {
+	row := s.dialect.queryRow(ctx, s.db, `SELECT `+evaluationColumns+` FROM evaluations WHERE id = $1`, id)
+	e, err := scanEvaluation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEvaluationNotFound
+	}
+	return e, err
+}
+
+// ListEvaluations 按创建时间倒序列出评测记录，model 匹配基准或候选模型，为空时不过滤
+func (s *Store) ListEvaluations(ctx context.Context, model string, limit int) ([]*Evaluation, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT `+evaluationColumns+` FROM evaluations
+		WHERE ($1 = '' OR baseline_model = $1 OR candidate_model = $1)
+		ORDER BY created_at DESC LIMIT $2`, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var evaluations []*Evaluation
+	for rows.Next() {
+		e, err := scanEvaluation(rows)
+		if err != nil {
+			return nil, err
+		}
+		evaluations = append(evaluations, e)
+	}
+	return evaluations, rows.Err()
+}
+
+func scanEvaluation(row scanner) (*Evaluation, error) {
+	var (
+		e      Evaluation
+		result string
+	)
+	err := row.Scan(&e.ID, &e.Username, &e.Question, &e.Cluster, &e.BaselineModel, &e.CandidateModel,
+		&e.BaselineInteractionID, &e.CandidateInteractionID, &result, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Result = json.RawMessage(result)
+	return &e, nil
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKubeAuditLimit = 20
+	maxKubeAuditLimit     = 200
+)
+
+// KubeAuditEvent 集群 apiserver 审计日志中的一次变更请求，用于回答"谁修改了这个资源"
+type KubeAuditEvent struct {
+	Cluster      string    `json:"cluster"`
+	AuditID      string    `json:"audit_id"`
+	Time         time.Time `json:"time"`
+	Verb         string    `json:"verb"`
+	Username     string    `json:"username"`
+	UserAgent    string    `json:"user_agent,omitempty"`
+	SourceIP     string    `json:"source_ip,omitempty"`
+	Namespace    string    `json:"namespace,omitempty"`
+	Resource     string    `json:"resource"`
+	Subresource  string    `json:"subresource,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	ResponseCode int       `json:"response_code"`
+	// Detail 请求内容摘要，例如 scale 的副本数，审计策略为 Metadata 级别时为空
+	Detail string `json:"detail,omitempty"`
+}
+
+// KubeAuditQuery 审计日志查询条件，字符串条件为空时不过滤
+// Name 和 Username 按前缀匹配，传入 Deployment 名称即可查到其 scale 子资源的记录
+type KubeAuditQuery struct {
+	Cluster   string
+	Namespace string
+	Resource  string
+	Name      string
+	Verb      string
+	Username  string
+	Since     time.Time
+	Until     time.Time
+	Limit     int // 默认 20，最大 200
+}
+
+const kubeAuditColumns = `cluster, audit_id, event_time, verb, username, user_agent, source_ip, namespace, resource,
+	subresource, name, response_code, detail`
+
+// SaveKubeAuditEvents 写入一批集群审计事件，已写入的事件（相同集群和 auditID）忽略，返回新写入的数量
+func (s *Store) SaveKubeAuditEvents(ctx context.Context, events []KubeAuditEvent) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	inserted := 0
+	for _, e := range events {
+		result, err := s.dialect.exec(ctx, tx, s.dialect.upsert(
+			`INSERT INTO k8s_audit_events (`+kubeAuditColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+			[]string{"cluster", "audit_id"}),
+			e.Cluster, e.AuditID, e.Time, e.Verb, e.Username, e.UserAgent, e.SourceIP, e.Namespace, e.Resource,
+			e.Subresource, e.Name, e.ResponseCode, e.Detail,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			inserted += int(n)
+		}
+	}
+	return inserted, tx.Commit()
+}
+
+// QueryKubeAuditEvents 按时间倒序查询集群审计事件
+func (s *Store) QueryKubeAuditEvents(ctx context.Context, q KubeAuditQuery) ([]KubeAuditEvent, error) {
+	if q.Limit <= 0 {
+		q.Limit = defaultKubeAuditLimit
+	}
+	if q.Limit > maxKubeAuditLimit {
+		q.Limit = maxKubeAuditLimit
+	}
+	if q.Until.IsZero() {
+		q.Until = time.Now()
+	}
+
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT `+kubeAuditColumns+` FROM k8s_audit_events
+		WHERE event_time >= $1 AND event_time <= $2
+			AND ($3 = '' OR cluster = $3) AND ($4 = '' OR namespace = $4) AND ($5 = '' OR resource = $5)
+			AND name LIKE $6 ESCAPE '!' AND ($7 = '' OR verb = $7) AND username LIKE $8 ESCAPE '!'
+		ORDER BY event_time DESC LIMIT $9`,
+		q.Since, q.Until, q.Cluster, q.Namespace, q.Resource, escapeLike(q.Name)+"%", q.Verb, escapeLike(q.Username)+"%", q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []KubeAuditEvent
+	for rows.Next() {
+		var e KubeAuditEvent
+		if err := rows.Scan(&e.Cluster, &e.AuditID, &e.Time, &e.Verb, &e.Username, &e.UserAgent, &e.SourceIP,
+			&e.Namespace, &e.Resource, &e.Subresource, &e.Name, &e.ResponseCode, &e.Detail); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// KubeAuditCursor 返回审计日志来源已处理的最后一个对象名，未处理过时返回空字符串
+func (s *Store) KubeAuditCursor(ctx context.Context, source string) (string, error) {
+	var key string
+	err := s.dialect.queryRow(ctx, s.db, `SELECT last_key FROM k8s_audit_cursors WHERE source = $1`, source).Scan(&key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return key, err
+}
+
+// SetKubeAuditCursor 记录审计日志来源已处理的最后一个对象名，多个实例轮询时不会重复下载
+func (s *Store) SetKubeAuditCursor(ctx context.Context, source, key string) error {
+	query := `INSERT INTO k8s_audit_cursors (source, last_key, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (source) DO UPDATE SET last_key = EXCLUDED.last_key, updated_at = EXCLUDED.updated_at
+		WHERE k8s_audit_cursors.last_key < EXCLUDED.last_key`
+	if s.dialect.name == DriverMySQL {
+		// MySQL 的 ON DUPLICATE KEY UPDATE 不支持 WHERE，按顺序赋值，先根据旧的 last_key 更新 updated_at
+		query = `INSERT INTO k8s_audit_cursors (source, last_key, updated_at) VALUES ($1, $2, $3)
+		ON DUPLICATE KEY UPDATE updated_at = IF(last_key < VALUES(last_key), VALUES(updated_at), updated_at),
+			last_key = IF(last_key < VALUES(last_key), VALUES(last_key), last_key)`
+	}
+	_, err := s.dialect.exec(ctx, s.db, query, source, key, time.Now())
+	return err
+}
+
+// escapeLike 转义 LIKE 通配符，前缀匹配时用户输入中的 % 和 _ 按字面匹配
+// 使用 ! 作为转义字符，反斜杠在 MySQL 字符串中本身需要转义，各数据库的写法不一致
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
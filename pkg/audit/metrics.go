@@ -0,0 +1,225 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 写入延迟告警默认配置
+const (
+	defaultLagThreshold  = time.Minute
+	defaultLagInterval   = 30 * time.Second
+	defaultAlertCooldown = 10 * time.Minute
+)
+
+// QueueStats 审计写入队列的状态
+type QueueStats struct {
+	Depth                int       `json:"depth"`                  // 待写入（含正在写入）的记录数
+	Capacity             int       `json:"capacity"`               // 队列容量
+	OldestPendingSeconds float64   `json:"oldest_pending_seconds"` // 最早一条待写入记录的等待时间
+	Enqueued             uint64    `json:"enqueued"`
+	Dropped              uint64    `json:"dropped"` // 队列已满被丢弃的记录
+	Committed            uint64    `json:"committed"`
+	Batches              uint64    `json:"batches"`
+	FailedBatches        uint64    `json:"failed_batches"`
+	FailedRecords        uint64    `json:"failed_records"` // 逐条重试后仍失败的记录
+	LastCommitMs         float64   `json:"last_commit_ms"`
+	AvgCommitMs          float64   `json:"avg_commit_ms"`
+	LastCommitAt         time.Time `json:"last_commit_at,omitempty"`
+}
+
+// queueStats 队列统计，pending 按入队顺序记录每条待写入记录的入队时间
+type queueStats struct {
+	mu            sync.Mutex
+	pending       []time.Time
+	enqueued      uint64
+	dropped       uint64
+	committed     uint64
+	batches       uint64
+	failedBatches uint64
+	failedRecords uint64
+	lastCommit    time.Duration
+	totalCommit   time.Duration
+	lastCommitAt  time.Time
+}
+
+func (q *queueStats) enqueue() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.enqueued++
+	q.pending = append(q.pending, time.Now())
+}
+
+func (q *queueStats) drop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.enqueued--
+	q.dropped++
+	if len(q.pending) > 0 {
+		q.pending = q.pending[:len(q.pending)-1]
+	}
+}
+
+func (q *queueStats) commit(n int, latency time.Duration, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.batches++
+	q.lastCommit = latency
+	q.totalCommit += latency
+	q.lastCommitAt = time.Now()
+	if err != nil {
+		q.failedBatches++
+		return
+	}
+	q.committed += uint64(n)
+}
+
+func (q *queueStats) fail() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.failedRecords++
+}
+
+func (q *queueStats) done(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n = min(n, len(q.pending))
+	q.pending = q.pending[n:]
+}
+
+func (q *queueStats) snapshot(capacity int, now time.Time) QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := QueueStats{
+		Depth:         len(q.pending),
+		Capacity:      capacity,
+		Enqueued:      q.enqueued,
+		Dropped:       q.dropped,
+		Committed:     q.committed,
+		Batches:       q.batches,
+		FailedBatches: q.failedBatches,
+		FailedRecords: q.failedRecords,
+		LastCommitMs:  float64(q.lastCommit.Microseconds()) / 1000,
+		LastCommitAt:  q.lastCommitAt,
+	}
+	if len(q.pending) > 0 {
+		stats.OldestPendingSeconds = now.Sub(q.pending[0]).Seconds()
+	}
+	if q.batches > 0 {
+		stats.AvgCommitMs = float64(q.totalCommit.Microseconds()) / 1000 / float64(q.batches)
+	}
+	return stats
+}
+
+// QueueStats 返回写入队列的状态
+func (s *Store) QueueStats() QueueStats {
+	return s.stats.snapshot(cap(s.queue), time.Now())
+}
+
+// Stats 返回全局审计存储的队列状态，未启用审计时返回 false
+func Stats() (QueueStats, bool) {
+	store := GetStore()
+	if store == nil {
+		return QueueStats{}, false
+	}
+	return store.QueueStats(), true
+}
+
+// lagAlert 写入延迟告警配置
+type lagAlert struct {
+	threshold time.Duration // 最早待写入记录的等待时间阈值，0 表示不检查
+	depth     int           // 队列深度阈值，0 表示不检查
+	interval  time.Duration
+	cooldown  time.Duration
+}
+
+// newLagAlert 读取告警配置：audit.alert.lag_threshold、audit.alert.queue_depth、
+// audit.alert.interval、audit.alert.cooldown
+func newLagAlert() lagAlert {
+	config := utils.GetConfig()
+	alert := lagAlert{
+		threshold: defaultLagThreshold,
+		depth:     config.GetInt("audit.alert.queue_depth"),
+		interval:  defaultLagInterval,
+		cooldown:  defaultAlertCooldown,
+	}
+	if config.IsSet("audit.alert.lag_threshold") {
+		alert.threshold = config.GetDuration("audit.alert.lag_threshold")
+	}
+	if interval := config.GetDuration("audit.alert.interval"); interval > 0 {
+		alert.interval = interval
+	}
+	if cooldown := config.GetDuration("audit.alert.cooldown"); cooldown > 0 {
+		alert.cooldown = cooldown
+	}
+	return alert
+}
+
+// exceeded 判断是否超过告警阈值，返回告警说明
+func (a lagAlert) exceeded(stats QueueStats) (bool, string) {
+	if a.threshold > 0 && stats.OldestPendingSeconds >= a.threshold.Seconds() {
+		return true, fmt.Sprintf("最早一条待写入记录已等待 %.0f 秒，超过阈值 %s", stats.OldestPendingSeconds, a.threshold)
+	}
+	if a.depth > 0 && stats.Depth >= a.depth {
+		return true, fmt.Sprintf("队列中有 %d 条待写入记录，超过阈值 %d", stats.Depth, a.depth)
+	}
+	return false, ""
+}
+
+// monitorLag 定期检查写入延迟，超过阈值时通过通知渠道告警，恢复后发送恢复通知
+func (s *Store) monitorLag(alert lagAlert) {
+	notifier := notify.Default()
+	if !notifier.Enabled() || (alert.threshold <= 0 && alert.depth <= 0) {
+		return
+	}
+
+	ticker := time.NewTicker(alert.interval)
+	defer ticker.Stop()
+
+	var alerting bool
+	var lastAlert time.Time
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		stats := s.QueueStats()
+		exceeded, reason := alert.exceeded(stats)
+		var msg *notify.Message
+		switch {
+		case exceeded && time.Since(lastAlert) >= alert.cooldown:
+			msg = &notify.Message{
+				Title: "审计写入延迟",
+				Level: notify.LevelWarning,
+				Text: fmt.Sprintf("%s。队列深度 %d/%d，失败批次 %d，最近一次提交耗时 %.1fms。",
+					reason, stats.Depth, stats.Capacity, stats.FailedBatches, stats.LastCommitMs),
+			}
+			alerting, lastAlert = true, time.Now()
+		case !exceeded && alerting:
+			msg = &notify.Message{
+				Title: "审计写入延迟已恢复",
+				Level: notify.LevelInfo,
+				Text:  fmt.Sprintf("队列深度 %d/%d。", stats.Depth, stats.Capacity),
+			}
+			alerting = false
+		}
+		if msg == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := notifier.Send(ctx, *msg); err != nil {
+			s.logger.Warn("发送审计延迟告警失败", zap.Error(err))
+		}
+		cancel()
+	}
+}
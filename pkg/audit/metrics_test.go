@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQueueStats(t *testing.T) {
+	var q queueStats
+	for i := 0; i < 3; i++ {
+		q.enqueue()
+	}
+	q.drop()
+	q.commit(2, 10*time.Millisecond, nil)
+	q.done(2)
+	q.enqueue()
+	q.commit(1, 30*time.Millisecond, errors.New("boom"))
+	q.fail()
+
+	stats := q.snapshot(8, time.Now().Add(5*time.Second))
+	if stats.Depth != 1 || stats.Enqueued != 3 || stats.Dropped != 1 || stats.Committed != 2 {
+		t.Errorf("unexpected counters: %+v", stats)
+	}
+	if stats.Batches != 2 || stats.FailedBatches != 1 || stats.FailedRecords != 1 {
+		t.Errorf("unexpected batch counters: %+v", stats)
+	}
+	if stats.AvgCommitMs != 20 || stats.LastCommitMs != 30 {
+		t.Errorf("unexpected commit latency: avg %v last %v", stats.AvgCommitMs, stats.LastCommitMs)
+	}
+	if stats.OldestPendingSeconds < 5 {
+		t.Errorf("oldest pending = %v, want >= 5s", stats.OldestPendingSeconds)
+	}
+}
+
+func TestLagAlertExceeded(t *testing.T) {
+	alert := lagAlert{threshold: time.Minute, depth: 100}
+
+	if exceeded, _ := alert.exceeded(QueueStats{Depth: 10, OldestPendingSeconds: 5}); exceeded {
+		t.Error("expected no alert below thresholds")
+	}
+	if exceeded, _ := alert.exceeded(QueueStats{Depth: 1, OldestPendingSeconds: 90}); !exceeded {
+		t.Error("expected alert when the oldest entry is too old")
+	}
+	if exceeded, _ := alert.exceeded(QueueStats{Depth: 100}); !exceeded {
+		t.Error("expected alert when the queue is too deep")
+	}
+}
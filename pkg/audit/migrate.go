@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaVersion 当前审计表结构版本，等于各驱动最新迁移文件的版本号
+// 修改表结构时在 migrations/<驱动>/ 下为每种驱动添加 NNNN_说明.sql 并递增该版本，已发布的迁移文件不能修改
+// 1: interactions  2: tool_calls  3: rag_calls  4: interactions.prompt_*  5: approvals  6: answer_drafts
+// 7: interactions.prompt_tokens, completion_tokens  8: k8s_audit_events, k8s_audit_cursors  9: llm_calls
+// 10: evaluations  11: cluster_snapshots  12: tool_receipts
+// 13: tool_calls.duration_ms, observation_tokens, interactions.usage_source  14: apply_requests  15: tool_quotas
+// SQLite 和 MySQL 从版本 9 开始支持，0009_initial.sql 直接创建版本 9 的表结构
+const SchemaVersion = 15
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration 一个表结构迁移
+type Migration struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	sql     string
+}
+
+// Migrations 返回驱动的全部迁移，按版本排序
+func Migrations(driver string) ([]Migration, error) {
+	d, err := getDialect(driver)
+	if err != nil {
+		return nil, err
+	}
+	return d.loadMigrations()
+}
+
+// PendingMigrations 返回驱动中版本高于 version 的迁移，即升级时将要执行的迁移
+func PendingMigrations(driver string, version int) ([]Migration, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// loadMigrations 读取内嵌的 migrations/<驱动>/NNNN_name.sql
+func (d *dialect) loadMigrations() ([]Migration, error) {
+	dir := path.Join("migrations", d.name)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := map[int]string{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".sql")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		prefix, title, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件名无效 %s，格式应为 NNNN_说明.sql", entry.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s, %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		data, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: title, sql: string(data)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrate 依次执行版本高于数据库当前版本的迁移，每个迁移在一个事务中执行并记录到 schema_version
+// 多个实例同时启动时通过数据库锁串行执行；MySQL 的 DDL 会隐式提交，因此 MySQL 迁移需写成可重复执行的语句
+// Postgres 在记录迁移版本之前创建的数据库版本为 0，迁移 1-9 均为 IF NOT EXISTS，可以在已有表上重新执行
+func (d *dialect) migrate(ctx context.Context, db *sql.DB) error {
+	migrations, err := d.loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if d.lock != "" {
+		if _, err := conn.ExecContext(ctx, d.lock); err != nil {
+			return fmt.Errorf("获取迁移锁失败: %v", err)
+		}
+		defer conn.ExecContext(context.Background(), d.unlock)
+	}
+	for _, statement := range statements(d.versionTable) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	var current sql.NullInt64
+	if err := conn.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&current); err != nil {
+		return err
+	}
+	if int(current.Int64) > SchemaVersion {
+		return fmt.Errorf("数据库表结构版本 %d 高于程序支持的版本 %d，请升级 OpsAgent", current.Int64, SchemaVersion)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= int(current.Int64) {
+			continue
+		}
+		if err := d.apply(ctx, conn, m); err != nil {
+			return fmt.Errorf("执行迁移 %04d_%s 失败: %v", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// apply 在事务中执行迁移并记录版本
+func (d *dialect) apply(ctx context.Context, conn *sql.Conn, m Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements(m.sql) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if _, err := d.exec(ctx, tx, d.upsert(`INSERT INTO schema_version (version, name) VALUES ($1, $2)`, []string{"version"}),
+		m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// statements 将 SQL 脚本按行尾的分号拆分为单条语句，忽略只有注释的片段；MySQL 默认不允许一次执行多条语句
+func statements(script string) []string {
+	var result []string
+	for _, chunk := range strings.Split(script, ";\n") {
+		empty := true
+		for _, line := range strings.Split(chunk, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			result = append(result, strings.TrimSuffix(strings.TrimSpace(chunk), ";"))
+		}
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package audit
+
+import "testing"
+
+func TestMigrationsUpToDate(t *testing.T) {
+	for name := range dialects {
+		migrations, err := Migrations(name)
+		if err != nil || len(migrations) == 0 {
+			t.Fatalf("Migrations(%s) = %v, %v", name, migrations, err)
+		}
+		if latest := migrations[len(migrations)-1].Version; latest != SchemaVersion {
+			t.Errorf("latest %s migration = %d, want SchemaVersion %d", name, latest, SchemaVersion)
+		}
+	}
+
+	pending, err := PendingMigrations("postgres", 7)
+	if err != nil || len(pending) != SchemaVersion-7 || pending[0].Name != "k8s_audit" {
+		t.Errorf("PendingMigrations(postgres, 7) = %v, %v", pending, err)
+	}
+}
+
+func TestStatements(t *testing.T) {
+	script := "-- comment\nCREATE TABLE a (id INT);\n\n-- trailing comment\nCREATE INDEX i ON a (id);\n-- only a comment\n"
+	got := statements(script)
+	if len(got) != 2 || got[1] != "-- trailing comment\nCREATE INDEX i ON a (id)" {
+		t.Errorf("statements() = %q", got)
+	}
+}
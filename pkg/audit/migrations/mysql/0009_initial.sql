@@ -0,0 +1,126 @@
+-- 初始表结构，与 Postgres 版本 9 相同
+CREATE TABLE IF NOT EXISTS interactions (
+	id                VARCHAR(64) PRIMARY KEY,
+	username          VARCHAR(128) NOT NULL DEFAULT '',
+	model             VARCHAR(128) NOT NULL DEFAULT '',
+	cluster           VARCHAR(128) NOT NULL DEFAULT '',
+	question          TEXT NOT NULL,
+	answer            MEDIUMTEXT NOT NULL,
+	status            VARCHAR(32) NOT NULL,
+	error             TEXT NOT NULL,
+	duration_ms       BIGINT NOT NULL DEFAULT 0,
+	created_at        DATETIME(6) NOT NULL,
+	prompt_name       VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_version    VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_hash       VARCHAR(64) NOT NULL DEFAULT '',
+	prompt_tokens     INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0,
+	INDEX idx_interactions_created_at (created_at, id),
+	INDEX idx_interactions_duration (duration_ms, id),
+	INDEX idx_interactions_username (username, created_at),
+	INDEX idx_interactions_prompt (prompt_name, prompt_version, created_at)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL,
+	seq            INT NOT NULL,
+	name           VARCHAR(64) NOT NULL,
+	input          MEDIUMTEXT NOT NULL,
+	observation    MEDIUMTEXT NOT NULL,
+	INDEX idx_tool_calls_interaction (interaction_id, seq),
+	FOREIGN KEY (interaction_id) REFERENCES interactions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS rag_calls (
+	id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL,
+	seq            INT NOT NULL,
+	kind           VARCHAR(64) NOT NULL,
+	query          TEXT NOT NULL,
+	context        MEDIUMTEXT NOT NULL,
+	latency_ms     BIGINT NOT NULL DEFAULT 0,
+	prompt_tokens  INT NOT NULL DEFAULT 0,
+	total_tokens   INT NOT NULL DEFAULT 0,
+	error          TEXT NOT NULL,
+	created_at     DATETIME(6) NOT NULL,
+	INDEX idx_rag_calls_interaction (interaction_id, seq),
+	FOREIGN KEY (interaction_id) REFERENCES interactions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS approvals (
+	id             VARCHAR(64) PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL DEFAULT '',
+	username       VARCHAR(128) NOT NULL DEFAULT '',
+	question       TEXT NOT NULL,
+	tool           VARCHAR(64) NOT NULL,
+	input          TEXT NOT NULL,
+	verb           VARCHAR(64) NOT NULL DEFAULT '',
+	kube_context   VARCHAR(128) NOT NULL DEFAULT '',
+	provider       VARCHAR(64) NOT NULL DEFAULT '',
+	model          VARCHAR(128) NOT NULL DEFAULT '',
+	base_url       TEXT NOT NULL,
+	messages       MEDIUMTEXT NOT NULL,
+	status         VARCHAR(32) NOT NULL,
+	reviewed_by    VARCHAR(128) NOT NULL DEFAULT '',
+	reviewed_at    DATETIME(6) NULL,
+	output         MEDIUMTEXT NOT NULL,
+	answer         MEDIUMTEXT NOT NULL,
+	error          TEXT NOT NULL,
+	created_at     DATETIME(6) NOT NULL,
+	updated_at     DATETIME(6) NOT NULL,
+	INDEX idx_approvals_status (status, created_at)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS answer_drafts (
+	id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL,
+	seq            INT NOT NULL,
+	answer         MEDIUMTEXT NOT NULL,
+	reviewer       VARCHAR(128) NOT NULL DEFAULT '',
+	verdict        VARCHAR(32) NOT NULL,
+	critique       TEXT NOT NULL,
+	review_error   TEXT NOT NULL,
+	created_at     DATETIME(6) NOT NULL,
+	INDEX idx_answer_drafts_interaction (interaction_id, seq),
+	FOREIGN KEY (interaction_id) REFERENCES interactions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS k8s_audit_events (
+	cluster       VARCHAR(128) NOT NULL,
+	audit_id      VARCHAR(64) NOT NULL,
+	event_time    DATETIME(6) NOT NULL,
+	verb          VARCHAR(32) NOT NULL,
+	username      VARCHAR(256) NOT NULL DEFAULT '',
+	user_agent    TEXT NOT NULL,
+	source_ip     VARCHAR(64) NOT NULL DEFAULT '',
+	namespace     VARCHAR(128) NOT NULL DEFAULT '',
+	resource      VARCHAR(128) NOT NULL DEFAULT '',
+	subresource   VARCHAR(64) NOT NULL DEFAULT '',
+	name          VARCHAR(256) NOT NULL DEFAULT '',
+	response_code INT NOT NULL DEFAULT 0,
+	detail        TEXT NOT NULL,
+	PRIMARY KEY (cluster, audit_id),
+	INDEX idx_k8s_audit_events_object (cluster, resource, name, event_time),
+	INDEX idx_k8s_audit_events_time (event_time)
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS k8s_audit_cursors (
+	source     VARCHAR(256) PRIMARY KEY,
+	last_key   TEXT NOT NULL,
+	updated_at DATETIME(6) NOT NULL
+) DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS llm_calls (
+	id                BIGINT AUTO_INCREMENT PRIMARY KEY,
+	interaction_id    VARCHAR(64) NOT NULL,
+	seq               INT NOT NULL,
+	model             VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_tokens     INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0,
+	latency_ms        BIGINT NOT NULL DEFAULT 0,
+	finish_reason     VARCHAR(32) NOT NULL DEFAULT '',
+	created_at        DATETIME(6) NOT NULL,
+	INDEX idx_llm_calls_interaction (interaction_id, seq),
+	FOREIGN KEY (interaction_id) REFERENCES interactions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,15 @@
+-- 工具执行回执：执行的命令和输出的 SHA-256、执行时间和执行方，由执行方使用 Ed25519 签名
+CREATE TABLE IF NOT EXISTS tool_receipts (
+	id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL,
+	seq            INT NOT NULL,
+	tool           VARCHAR(64) NOT NULL,
+	input_hash     CHAR(64) NOT NULL,
+	output_hash    CHAR(64) NOT NULL,
+	executed_at    DATETIME(6) NOT NULL,
+	runner         VARCHAR(255) NOT NULL,
+	key_id         VARCHAR(64) NOT NULL,
+	signature      TEXT NOT NULL,
+	INDEX idx_tool_receipts_interaction (interaction_id, seq),
+	FOREIGN KEY (interaction_id) REFERENCES interactions (id) ON DELETE CASCADE
+) DEFAULT CHARSET = utf8mb4;
@@ -0,0 +1,8 @@
+-- 工具调用的耗时和观察结果 token 数，交互 token 用量的来源；历史记录由 Backfill 补齐
+-- MySQL 不支持 ADD COLUMN IF NOT EXISTS，每张表的变更写在一条 ALTER 语句中，单条语句要么全部生效要么不生效
+ALTER TABLE tool_calls
+	ADD COLUMN duration_ms BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN observation_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE interactions
+	ADD COLUMN usage_source VARCHAR(16) NOT NULL DEFAULT '',
+	ADD INDEX idx_interactions_usage_source (usage_source, created_at);
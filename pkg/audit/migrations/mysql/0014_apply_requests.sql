@@ -0,0 +1,15 @@
+-- 生成清单的应用请求：dry-run 时的清单、哈希和 diff，审批和应用结果；已应用的清单用于漂移检测
+CREATE TABLE IF NOT EXISTS apply_requests (
+	id           VARCHAR(64) PRIMARY KEY,
+	manifest     MEDIUMTEXT NOT NULL,
+	hash         CHAR(64) NOT NULL,
+	objects      TEXT NOT NULL,
+	diff         MEDIUMTEXT NOT NULL,
+	status       VARCHAR(32) NOT NULL,
+	requested_by VARCHAR(128) NOT NULL DEFAULT '',
+	reviewed_by  VARCHAR(128) NOT NULL DEFAULT '',
+	error        TEXT NOT NULL,
+	created_at   DATETIME(6) NOT NULL,
+	reviewed_at  DATETIME(6) NULL,
+	INDEX idx_apply_requests_status (status, created_at)
+) DEFAULT CHARSET = utf8mb4;
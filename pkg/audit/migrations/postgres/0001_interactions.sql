@@ -0,0 +1,16 @@
+-- 审计交互记录
+CREATE TABLE IF NOT EXISTS interactions (
+	id          VARCHAR(64) PRIMARY KEY,
+	username    VARCHAR(128) NOT NULL DEFAULT '',
+	model       VARCHAR(128) NOT NULL DEFAULT '',
+	cluster     VARCHAR(128) NOT NULL DEFAULT '',
+	question    TEXT NOT NULL,
+	answer      TEXT NOT NULL DEFAULT '',
+	status      VARCHAR(32) NOT NULL,
+	error       TEXT NOT NULL DEFAULT '',
+	duration_ms BIGINT NOT NULL DEFAULT 0,
+	created_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_interactions_created_at ON interactions (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_interactions_duration ON interactions (duration_ms, id);
+CREATE INDEX IF NOT EXISTS idx_interactions_username ON interactions (username, created_at);
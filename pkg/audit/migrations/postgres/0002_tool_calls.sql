@@ -0,0 +1,10 @@
+-- 交互中的工具调用
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id             BIGSERIAL PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	name           VARCHAR(64) NOT NULL,
+	input          TEXT NOT NULL,
+	observation    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_interaction ON tool_calls (interaction_id, seq);
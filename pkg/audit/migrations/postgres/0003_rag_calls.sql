@@ -0,0 +1,15 @@
+-- 交互中的检索调用
+CREATE TABLE IF NOT EXISTS rag_calls (
+	id             BIGSERIAL PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	kind           VARCHAR(64) NOT NULL,
+	query          TEXT NOT NULL,
+	context        TEXT NOT NULL DEFAULT '[]',
+	latency_ms     BIGINT NOT NULL DEFAULT 0,
+	prompt_tokens  INT NOT NULL DEFAULT 0,
+	total_tokens   INT NOT NULL DEFAULT 0,
+	error          TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rag_calls_interaction ON rag_calls (interaction_id, seq);
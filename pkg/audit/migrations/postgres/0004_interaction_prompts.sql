@@ -0,0 +1,5 @@
+-- 交互使用的系统提示版本
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS prompt_name VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS prompt_version VARCHAR(128) NOT NULL DEFAULT '';
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS prompt_hash VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_interactions_prompt ON interactions (prompt_name, prompt_version, created_at);
@@ -0,0 +1,24 @@
+-- 变更命令审批
+CREATE TABLE IF NOT EXISTS approvals (
+	id             VARCHAR(64) PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL DEFAULT '',
+	username       VARCHAR(128) NOT NULL DEFAULT '',
+	question       TEXT NOT NULL DEFAULT '',
+	tool           VARCHAR(64) NOT NULL,
+	input          TEXT NOT NULL,
+	verb           VARCHAR(64) NOT NULL DEFAULT '',
+	kube_context   VARCHAR(128) NOT NULL DEFAULT '',
+	provider       VARCHAR(64) NOT NULL DEFAULT '',
+	model          VARCHAR(128) NOT NULL DEFAULT '',
+	base_url       TEXT NOT NULL DEFAULT '',
+	messages       TEXT NOT NULL DEFAULT '[]',
+	status         VARCHAR(32) NOT NULL,
+	reviewed_by    VARCHAR(128) NOT NULL DEFAULT '',
+	reviewed_at    TIMESTAMPTZ,
+	output         TEXT NOT NULL DEFAULT '',
+	answer         TEXT NOT NULL DEFAULT '',
+	error          TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL,
+	updated_at     TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals (status, created_at);
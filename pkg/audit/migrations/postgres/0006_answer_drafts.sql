@@ -0,0 +1,13 @@
+-- 回答审阅的草稿
+CREATE TABLE IF NOT EXISTS answer_drafts (
+	id             BIGSERIAL PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	answer         TEXT NOT NULL,
+	reviewer       VARCHAR(128) NOT NULL DEFAULT '',
+	verdict        VARCHAR(32) NOT NULL,
+	critique       TEXT NOT NULL DEFAULT '',
+	review_error   TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_answer_drafts_interaction ON answer_drafts (interaction_id, seq);
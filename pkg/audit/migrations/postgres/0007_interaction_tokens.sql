@@ -0,0 +1,3 @@
+-- 交互的 token 用量
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS prompt_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE interactions ADD COLUMN IF NOT EXISTS completion_tokens INT NOT NULL DEFAULT 0;
@@ -0,0 +1,25 @@
+-- 集群 apiserver 审计事件及拉取游标
+CREATE TABLE IF NOT EXISTS k8s_audit_events (
+	cluster       VARCHAR(128) NOT NULL,
+	audit_id      VARCHAR(64) NOT NULL,
+	event_time    TIMESTAMPTZ NOT NULL,
+	verb          VARCHAR(32) NOT NULL,
+	username      VARCHAR(256) NOT NULL DEFAULT '',
+	user_agent    TEXT NOT NULL DEFAULT '',
+	source_ip     VARCHAR(64) NOT NULL DEFAULT '',
+	namespace     VARCHAR(128) NOT NULL DEFAULT '',
+	resource      VARCHAR(128) NOT NULL DEFAULT '',
+	subresource   VARCHAR(64) NOT NULL DEFAULT '',
+	name          VARCHAR(256) NOT NULL DEFAULT '',
+	response_code INT NOT NULL DEFAULT 0,
+	detail        TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (cluster, audit_id)
+);
+CREATE INDEX IF NOT EXISTS idx_k8s_audit_events_object ON k8s_audit_events (cluster, resource, name, event_time);
+CREATE INDEX IF NOT EXISTS idx_k8s_audit_events_time ON k8s_audit_events (event_time);
+
+CREATE TABLE IF NOT EXISTS k8s_audit_cursors (
+	source     VARCHAR(256) PRIMARY KEY,
+	last_key   TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
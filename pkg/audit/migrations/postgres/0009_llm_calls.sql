@@ -0,0 +1,13 @@
+-- 每次 LLM 调用的用量和耗时
+CREATE TABLE IF NOT EXISTS llm_calls (
+	id                BIGSERIAL PRIMARY KEY,
+	interaction_id    VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq               INT NOT NULL,
+	model             VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_tokens     INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0,
+	latency_ms        BIGINT NOT NULL DEFAULT 0,
+	finish_reason     VARCHAR(32) NOT NULL DEFAULT '',
+	created_at        TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_interaction ON llm_calls (interaction_id, seq);
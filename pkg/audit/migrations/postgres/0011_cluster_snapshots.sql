@@ -0,0 +1,10 @@
+-- 集群资源元数据快照：gzip 压缩的 JSON，只包含名称、标签、属主等元数据，不包含 spec 和 Secret 内容
+CREATE TABLE IF NOT EXISTS cluster_snapshots (
+	cluster  VARCHAR(128) NOT NULL,
+	taken_at TIMESTAMPTZ NOT NULL,
+	objects  INT NOT NULL DEFAULT 0,
+	size     INT NOT NULL DEFAULT 0,
+	data     BYTEA NOT NULL,
+	PRIMARY KEY (cluster, taken_at)
+);
+CREATE INDEX IF NOT EXISTS idx_cluster_snapshots_taken_at ON cluster_snapshots (taken_at);
@@ -0,0 +1,14 @@
+-- 工具执行回执：执行的命令和输出的 SHA-256、执行时间和执行方，由执行方使用 Ed25519 签名
+CREATE TABLE IF NOT EXISTS tool_receipts (
+	id             BIGSERIAL PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	tool           VARCHAR(64) NOT NULL,
+	input_hash     CHAR(64) NOT NULL,
+	output_hash    CHAR(64) NOT NULL,
+	executed_at    TIMESTAMPTZ NOT NULL,
+	runner         VARCHAR(255) NOT NULL,
+	key_id         VARCHAR(64) NOT NULL,
+	signature      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tool_receipts_interaction ON tool_receipts (interaction_id, seq);
@@ -0,0 +1,122 @@
+-- 初始表结构，与 Postgres 版本 9 相同
+CREATE TABLE IF NOT EXISTS interactions (
+	id                VARCHAR(64) PRIMARY KEY,
+	username          VARCHAR(128) NOT NULL DEFAULT '',
+	model             VARCHAR(128) NOT NULL DEFAULT '',
+	cluster           VARCHAR(128) NOT NULL DEFAULT '',
+	question          TEXT NOT NULL,
+	answer            TEXT NOT NULL DEFAULT '',
+	status            VARCHAR(32) NOT NULL,
+	error             TEXT NOT NULL DEFAULT '',
+	duration_ms       BIGINT NOT NULL DEFAULT 0,
+	created_at        TIMESTAMP NOT NULL,
+	prompt_name       VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_version    VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_hash       VARCHAR(64) NOT NULL DEFAULT '',
+	prompt_tokens     INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_interactions_created_at ON interactions (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_interactions_duration ON interactions (duration_ms, id);
+CREATE INDEX IF NOT EXISTS idx_interactions_username ON interactions (username, created_at);
+CREATE INDEX IF NOT EXISTS idx_interactions_prompt ON interactions (prompt_name, prompt_version, created_at);
+
+CREATE TABLE IF NOT EXISTS tool_calls (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	name           VARCHAR(64) NOT NULL,
+	input          TEXT NOT NULL,
+	observation    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_interaction ON tool_calls (interaction_id, seq);
+
+CREATE TABLE IF NOT EXISTS rag_calls (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	kind           VARCHAR(64) NOT NULL,
+	query          TEXT NOT NULL,
+	context        TEXT NOT NULL DEFAULT '[]',
+	latency_ms     BIGINT NOT NULL DEFAULT 0,
+	prompt_tokens  INT NOT NULL DEFAULT 0,
+	total_tokens   INT NOT NULL DEFAULT 0,
+	error          TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rag_calls_interaction ON rag_calls (interaction_id, seq);
+
+CREATE TABLE IF NOT EXISTS approvals (
+	id             VARCHAR(64) PRIMARY KEY,
+	interaction_id VARCHAR(64) NOT NULL DEFAULT '',
+	username       VARCHAR(128) NOT NULL DEFAULT '',
+	question       TEXT NOT NULL DEFAULT '',
+	tool           VARCHAR(64) NOT NULL,
+	input          TEXT NOT NULL,
+	verb           VARCHAR(64) NOT NULL DEFAULT '',
+	kube_context   VARCHAR(128) NOT NULL DEFAULT '',
+	provider       VARCHAR(64) NOT NULL DEFAULT '',
+	model          VARCHAR(128) NOT NULL DEFAULT '',
+	base_url       TEXT NOT NULL DEFAULT '',
+	messages       TEXT NOT NULL DEFAULT '[]',
+	status         VARCHAR(32) NOT NULL,
+	reviewed_by    VARCHAR(128) NOT NULL DEFAULT '',
+	reviewed_at    TIMESTAMP,
+	output         TEXT NOT NULL DEFAULT '',
+	answer         TEXT NOT NULL DEFAULT '',
+	error          TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMP NOT NULL,
+	updated_at     TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_approvals_status ON approvals (status, created_at);
+
+CREATE TABLE IF NOT EXISTS answer_drafts (
+	id             INTEGER PRIMARY KEY AUTOINCREMENT,
+	interaction_id VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq            INT NOT NULL,
+	answer         TEXT NOT NULL,
+	reviewer       VARCHAR(128) NOT NULL DEFAULT '',
+	verdict        VARCHAR(32) NOT NULL,
+	critique       TEXT NOT NULL DEFAULT '',
+	review_error   TEXT NOT NULL DEFAULT '',
+	created_at     TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_answer_drafts_interaction ON answer_drafts (interaction_id, seq);
+
+CREATE TABLE IF NOT EXISTS k8s_audit_events (
+	cluster       VARCHAR(128) NOT NULL,
+	audit_id      VARCHAR(64) NOT NULL,
+	event_time    TIMESTAMP NOT NULL,
+	verb          VARCHAR(32) NOT NULL,
+	username      VARCHAR(256) NOT NULL DEFAULT '',
+	user_agent    TEXT NOT NULL DEFAULT '',
+	source_ip     VARCHAR(64) NOT NULL DEFAULT '',
+	namespace     VARCHAR(128) NOT NULL DEFAULT '',
+	resource      VARCHAR(128) NOT NULL DEFAULT '',
+	subresource   VARCHAR(64) NOT NULL DEFAULT '',
+	name          VARCHAR(256) NOT NULL DEFAULT '',
+	response_code INT NOT NULL DEFAULT 0,
+	detail        TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (cluster, audit_id)
+);
+CREATE INDEX IF NOT EXISTS idx_k8s_audit_events_object ON k8s_audit_events (cluster, resource, name, event_time);
+CREATE INDEX IF NOT EXISTS idx_k8s_audit_events_time ON k8s_audit_events (event_time);
+
+CREATE TABLE IF NOT EXISTS k8s_audit_cursors (
+	source     VARCHAR(256) PRIMARY KEY,
+	last_key   TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS llm_calls (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	interaction_id    VARCHAR(64) NOT NULL REFERENCES interactions (id) ON DELETE CASCADE,
+	seq               INT NOT NULL,
+	model             VARCHAR(128) NOT NULL DEFAULT '',
+	prompt_tokens     INT NOT NULL DEFAULT 0,
+	completion_tokens INT NOT NULL DEFAULT 0,
+	latency_ms        BIGINT NOT NULL DEFAULT 0,
+	finish_reason     VARCHAR(32) NOT NULL DEFAULT '',
+	created_at        TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_llm_calls_interaction ON llm_calls (interaction_id, seq);
@@ -0,0 +1,14 @@
+-- 模型评测：同一问题在两个模型上的执行结果对比，两次执行的完整记录见 interactions
+CREATE TABLE IF NOT EXISTS evaluations (
+	id                       VARCHAR(64) PRIMARY KEY,
+	username                 VARCHAR(128) NOT NULL DEFAULT '',
+	question                 TEXT NOT NULL DEFAULT '',
+	cluster                  VARCHAR(128) NOT NULL DEFAULT '',
+	baseline_model           VARCHAR(128) NOT NULL DEFAULT '',
+	candidate_model          VARCHAR(128) NOT NULL DEFAULT '',
+	baseline_interaction_id  VARCHAR(64) NOT NULL DEFAULT '',
+	candidate_interaction_id VARCHAR(64) NOT NULL DEFAULT '',
+	result                   TEXT NOT NULL DEFAULT '{}',
+	created_at               TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_evaluations_created ON evaluations (created_at);
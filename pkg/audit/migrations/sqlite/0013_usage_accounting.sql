@@ -0,0 +1,5 @@
+-- 工具调用的耗时和观察结果 token 数，交互 token 用量的来源；历史记录由 Backfill 补齐
+ALTER TABLE tool_calls ADD COLUMN duration_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tool_calls ADD COLUMN observation_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE interactions ADD COLUMN usage_source VARCHAR(16) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_interactions_usage_source ON interactions (usage_source, created_at);
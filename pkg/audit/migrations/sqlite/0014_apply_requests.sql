@@ -0,0 +1,15 @@
+-- 生成清单的应用请求：dry-run 时的清单、哈希和 diff，审批和应用结果；已应用的清单用于漂移检测
+CREATE TABLE IF NOT EXISTS apply_requests (
+	id           VARCHAR(64) PRIMARY KEY,
+	manifest     TEXT NOT NULL,
+	hash         CHAR(64) NOT NULL,
+	objects      TEXT NOT NULL DEFAULT '[]',
+	diff         TEXT NOT NULL DEFAULT '[]',
+	status       VARCHAR(32) NOT NULL,
+	requested_by VARCHAR(128) NOT NULL DEFAULT '',
+	reviewed_by  VARCHAR(128) NOT NULL DEFAULT '',
+	error        TEXT NOT NULL DEFAULT '',
+	created_at   TIMESTAMP NOT NULL,
+	reviewed_at  TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_apply_requests_status ON apply_requests (status, created_at);
@@ -0,0 +1,9 @@
+-- 昂贵工具的每日调用次数和管理员覆盖的当天配额，多个实例共享，重启后保留；quota_limit 为空表示使用配置的配额
+CREATE TABLE IF NOT EXISTS tool_quotas (
+	day         CHAR(10) NOT NULL,
+	username    VARCHAR(128) NOT NULL,
+	tool        VARCHAR(64) NOT NULL,
+	used        INT NOT NULL DEFAULT 0,
+	quota_limit INT,
+	PRIMARY KEY (day, username, tool)
+);
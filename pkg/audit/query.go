@@ -0,0 +1,194 @@
+package audit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLimit  = 50
+	maxLimit      = 200
+	defaultWindow = 7 * 24 * time.Hour
+	maxWindow     = 90 * 24 * time.Hour
+)
+
+// filterColumns 允许过滤的字段，键为请求参数，值为数据库列名
+// 只有在白名单中的字段才会出现在 SQL 中，所有取值均通过占位符传递
+var filterColumns = map[string]string{
+	"username": "username",
+	"model":    "model",
+	"cluster":  "cluster",
+	"status":   "status",
+
+	"prompt_name":    "prompt_name",
+	"prompt_version": "prompt_version",
+	"prompt_hash":    "prompt_hash",
+}
+
+// sortColumn 允许排序的字段定义
+type sortColumn struct {
+	column string
+	parse  func(string) (interface{}, error)
+	value  func(*Interaction) string
+}
+
+// sortColumns 允许排序的字段，均建有 (column, id) 索引以支持键集分页
+var sortColumns = map[string]sortColumn{
+	"created_at": {
+		column: "created_at",
+		parse: func(v string) (interface{}, error) {
+			return time.Parse(time.RFC3339Nano, v)
+		},
+		value: func(i *Interaction) string {
+			return i.CreatedAt.UTC().Format(time.RFC3339Nano)
+		},
+	},
+	"duration_ms": {
+		column: "duration_ms",
+		parse: func(v string) (interface{}, error) {
+			return strconv.ParseInt(v, 10, 64)
+		},
+		value: func(i *Interaction) string {
+			return strconv.FormatInt(i.DurationMs, 10)
+		},
+	},
+}
+
+// Query 审计记录查询条件
+type Query struct {
+	Filters map[string]string // 字段过滤，仅支持 filterColumns 中的字段
+	Since   time.Time         // 起始时间，默认最近 7 天
+	Until   time.Time         // 结束时间，默认当前时间
+	SortBy  string            // 排序字段，默认 created_at
+	Desc    bool              // 是否降序
+	Limit   int               // 每页数量，默认 50，最大 200
+	Cursor  string            // 上一页返回的游标
+}
+
+// cursor 键集分页游标
+type cursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// limit 返回规范化后的分页大小
+func (q Query) limit() int {
+	if q.Limit <= 0 {
+		return defaultLimit
+	}
+	if q.Limit > maxLimit {
+		return maxLimit
+	}
+	return q.Limit
+}
+
+// sortColumn 返回排序字段定义
+func (q Query) sortColumn() (sortColumn, error) {
+	sortBy := q.SortBy
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	col, ok := sortColumns[sortBy]
+	if !ok {
+		return sortColumn{}, fmt.Errorf("不支持的排序字段: %s", sortBy)
+	}
+	return col, nil
+}
+
+// Build 构建参数化查询语句
+// 过滤和排序字段必须在白名单中，时间窗口受限以避免全表扫描
+func (q Query) Build() (string, []interface{}, error) {
+	col, err := q.sortColumn()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	placeholder := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// 按字段名排序，保证生成的 SQL 稳定
+	keys := make([]string, 0, len(q.Filters))
+	for k := range q.Filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		column, ok := filterColumns[k]
+		if !ok {
+			return "", nil, fmt.Errorf("不支持的过滤字段: %s", k)
+		}
+		conditions = append(conditions, fmt.Sprintf("%s = %s", column, placeholder(q.Filters[k])))
+	}
+
+	until := q.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+	since := q.Since
+	if since.IsZero() {
+		since = until.Add(-defaultWindow)
+	}
+	if until.Sub(since) > maxWindow {
+		return "", nil, fmt.Errorf("查询时间窗口不能超过 %d 天", int(maxWindow.Hours()/24))
+	}
+	conditions = append(conditions,
+		fmt.Sprintf("created_at >= %s", placeholder(since)),
+		fmt.Sprintf("created_at < %s", placeholder(until)),
+	)
+
+	order, op := "ASC", ">"
+	if q.Desc {
+		order, op = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		value, err := col.parse(c.Value)
+		if err != nil {
+			return "", nil, fmt.Errorf("无效的游标: %v", err)
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s (%s, %s)",
+			col.column, op, placeholder(value), placeholder(c.ID)))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM interactions WHERE %s ORDER BY %s %s, id %s LIMIT %s",
+		interactionColumns, strings.Join(conditions, " AND "), col.column, order, order, placeholder(q.limit()))
+	return query, args, nil
+}
+
+// NextCursor 根据当前页最后一条记录生成下一页游标
+func (q Query) NextCursor(last *Interaction) string {
+	col, err := q.sortColumn()
+	if err != nil {
+		return ""
+	}
+	data, _ := json.Marshal(cursor{Value: col.value(last), ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor 解析分页游标
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("无效的游标: %v", err)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("无效的游标: %v", err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueryBuild(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		query    Query
+		want     []string
+		wantArgs int
+		wantErr  bool
+	}{
+		{
+			name:     "default window",
+			query:    Query{Until: now, Desc: true},
+			want:     []string{"created_at >= $1", "created_at < $2", "ORDER BY created_at DESC, id DESC LIMIT $3"},
+			wantArgs: 3,
+		},
+		{
+			name:     "filters use placeholders",
+			query:    Query{Until: now, Filters: map[string]string{"username": "admin' OR '1'='1", "model": "gpt-4"}},
+			want:     []string{"model = $1", "username = $2", "ORDER BY created_at ASC"},
+			wantArgs: 5,
+		},
+		{
+			name:    "unknown filter",
+			query:   Query{Until: now, Filters: map[string]string{"question; DROP TABLE interactions": "x"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown sort field",
+			query:   Query{Until: now, SortBy: "answer"},
+			wantErr: true,
+		},
+		{
+			name:    "window too large",
+			query:   Query{Since: now.Add(-365 * 24 * time.Hour), Until: now},
+			wantErr: true,
+		},
+		{
+			name: "keyset cursor",
+			query: Query{Until: now, SortBy: "duration_ms", Desc: true,
+				Cursor: Query{SortBy: "duration_ms"}.NextCursor(&Interaction{ID: "abc", DurationMs: 42})},
+			want:     []string{"(duration_ms, id) < ($3, $4)", "ORDER BY duration_ms DESC, id DESC LIMIT $5"},
+			wantArgs: 5,
+		},
+		{
+			name:    "invalid cursor",
+			query:   Query{Until: now, Cursor: "not-a-cursor"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, args, err := tt.query.Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("Build() = %v, want to contain %v", got, want)
+				}
+			}
+			if len(args) != tt.wantArgs {
+				t.Errorf("Build() args = %v, want %d args", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestQueryLimit(t *testing.T) {
+	if got := (Query{}).limit(); got != defaultLimit {
+		t.Errorf("limit() = %v, want %v", got, defaultLimit)
+	}
+	if got := (Query{Limit: 10000}).limit(); got != maxLimit {
+		t.Errorf("limit() = %v, want %v", got, maxLimit)
+	}
+}
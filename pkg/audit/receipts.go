@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// ToolReceipt 工具执行回执：执行的命令和输出的 SHA-256、执行时间和执行方，由执行方签名
+// 用于证明审计中记录的命令确实被执行过、输出在执行之后未被修改，签名和校验见 receipts 包
+type ToolReceipt struct {
+	InteractionID string    `json:"interaction_id,omitempty"` // 所属交互，与 Seq 一起签名，存储时即 tool_receipts.interaction_id
+	Seq           int       `json:"seq"`
+	Tool          string    `json:"tool"`
+	InputHash     string    `json:"input_hash"`  // 实际执行的输入的 SHA-256（十六进制）
+	OutputHash    string    `json:"output_hash"` // 返回给助手的输出（已脱敏）的 SHA-256
+	ExecutedAt    time.Time `json:"executed_at"`
+	Runner        string    `json:"runner"` // 执行方标识：中心服务或集群内 runner
+	KeyID         string    `json:"key_id"` // 签名公钥的指纹
+	Signature     string    `json:"signature"`
+}
+
+// ToolReceipts 获取交互的执行回执
+func (s *Store) ToolReceipts(ctx context.Context, interactionID string) ([]ToolReceipt, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT seq, tool, input_hash, output_hash, executed_at, runner, key_id, signature
+		FROM tool_receipts WHERE interaction_id = $1 ORDER BY seq`, interactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receipts []ToolReceipt
+	for rows.Next() {
+		r := ToolReceipt{InteractionID: interactionID}
+		if err := rows.Scan(&r.Seq, &r.Tool, &r.InputHash, &r.OutputHash, &r.ExecutedAt, &r.Runner, &r.KeyID, &r.Signature); err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, r)
+	}
+	return receipts, rows.Err()
+}
@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSnapshotNotFound 没有符合条件的集群快照
+var ErrSnapshotNotFound = errors.New("cluster snapshot not found")
+
+// ClusterSnapshot 某一时刻集群资源的元数据快照
+// Data 为压缩后的快照内容，编码由 kubernetes.EncodeSnapshot 定义，列表查询时不读取
+type ClusterSnapshot struct {
+	Cluster string    `json:"cluster"`
+	TakenAt time.Time `json:"taken_at"`
+	Objects int       `json:"objects"`
+	Size    int       `json:"size"` // 压缩后的字节数
+	Data    []byte    `json:"-"`
+}
+
+// SaveClusterSnapshot 写入集群快照
+func (s *Store) SaveClusterSnapshot(ctx context.Context, snapshot *ClusterSnapshot) error {
+	_, err := s.dialect.exec(ctx, s.db,
+		`INSERT INTO cluster_snapshots (cluster, taken_at, objects, size, data) VALUES ($1, $2, $3, $4, $5)`,
+		snapshot.Cluster, snapshot.TakenAt, snapshot.Objects, len(snapshot.Data), snapshot.Data,
+	)
+	return err
+}
+
+// ClusterSnapshotAt 返回集群在 at 时刻或之前最近的一份快照
+func (s *Store) ClusterSnapshotAt(ctx context.Context, cluster string, at time.Time) (*ClusterSnapshot, error) {
+	var snapshot ClusterSnapshot
+	err := s.dialect.queryRow(ctx, s.db,
+		`SELECT cluster, taken_at, objects, size, data FROM cluster_snapshots
+		WHERE cluster = $1 AND taken_at <= $2 ORDER BY taken_at DESC LIMIT 1`, cluster, at,
+	).Scan(&snapshot.Cluster, &snapshot.TakenAt, &snapshot.Objects, &snapshot.Size, &snapshot.Data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// ListClusterSnapshots 按时间倒序列出快照（不含内容），cluster 为空时列出所有集群
+func (s *Store) ListClusterSnapshots(ctx context.Context, cluster string, limit int) ([]ClusterSnapshot, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT cluster, taken_at, objects, size FROM cluster_snapshots
+		WHERE ($1 = '' OR cluster = $1) ORDER BY taken_at DESC LIMIT $2`, cluster, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []ClusterSnapshot
+	for rows.Next() {
+		var snapshot ClusterSnapshot
+		if err := rows.Scan(&snapshot.Cluster, &snapshot.TakenAt, &snapshot.Objects, &snapshot.Size); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+// DeleteClusterSnapshotsBefore 删除 before 之前的快照，返回删除的数量
+func (s *Store) DeleteClusterSnapshotsBefore(ctx context.Context, before time.Time) (int, error) {
+	result, err := s.dialect.exec(ctx, s.db, `DELETE FROM cluster_snapshots WHERE taken_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
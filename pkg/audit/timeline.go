@@ -0,0 +1,21 @@
+package audit
+
+// TimelineEntry 交互时间线中的一个步骤
+type TimelineEntry struct {
+	Type     string    `json:"type"` // rag 或 tool
+	RAGCall  *RAGCall  `json:"rag_call,omitempty"`
+	ToolCall *ToolCall `json:"tool_call,omitempty"`
+}
+
+// Timeline 按执行顺序返回交互中的检索和工具调用
+// 检索发生在助手执行之前，因此排在工具调用之前
+func (i *Interaction) Timeline() []TimelineEntry {
+	timeline := make([]TimelineEntry, 0, len(i.RAGCalls)+len(i.ToolCalls))
+	for idx := range i.RAGCalls {
+		timeline = append(timeline, TimelineEntry{Type: "rag", RAGCall: &i.RAGCalls[idx]})
+	}
+	for idx := range i.ToolCalls {
+		timeline = append(timeline, TimelineEntry{Type: "tool", ToolCall: &i.ToolCalls[idx]})
+	}
+	return timeline
+}
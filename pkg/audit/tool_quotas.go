@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ToolQuota 用户某个工具某天的调用次数，Override 为管理员覆盖的当天配额，nil 表示使用配置的配额
+type ToolQuota struct {
+	Day      string // 2006-01-02
+	Username string
+	Tool     string
+	Used     int
+	Override *int
+}
+
+// GetToolQuota 获取用户工具当天的调用记录，不存在时返回 nil
+func (s *Store) GetToolQuota(ctx context.Context, day, username, tool string) (*ToolQuota, error) {
+	row := s.dialect.queryRow(ctx, s.db,
+		`SELECT day, username, tool, used, quota_limit FROM tool_quotas WHERE day = $1 AND username = $2 AND tool = $3`,
+		day, username, tool)
+	q, err := scanToolQuota(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return q, err
+}
+
+// IncrementToolQuota 调用次数小于 limit 时加一并返回 true，limit 小于 0 表示不限制
+// 检查和递增在一条语句中完成，多个实例同时调用时不会超出配额
+func (s *Store) IncrementToolQuota(ctx context.Context, day, username, tool string, limit int) (bool, error) {
+	_, err := s.dialect.exec(ctx, s.db, s.dialect.upsert(
+		`INSERT INTO tool_quotas (day, username, tool, used) VALUES ($1, $2, $3, 0)`,
+		[]string{"day", "username", "tool"}),
+		day, username, tool,
+	)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.dialect.exec(ctx, s.db,
+		`UPDATE tool_quotas SET used = used + 1 WHERE day = $1 AND username = $2 AND tool = $3 AND ($4 < 0 OR used < $4)`,
+		day, username, tool, limit,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+// SetToolQuotaOverride 覆盖用户工具当天的配额，limit 为 -1 表示不限制
+func (s *Store) SetToolQuotaOverride(ctx context.Context, day, username, tool string, limit int) error {
+	_, err := s.dialect.exec(ctx, s.db, s.dialect.upsert(
+		`INSERT INTO tool_quotas (day, username, tool, used, quota_limit) VALUES ($1, $2, $3, 0, $4)`,
+		[]string{"day", "username", "tool"}, "quota_limit"),
+		day, username, tool, limit,
+	)
+	return err
+}
+
+// ListToolQuotas 列出用户当天的全部工具调用记录
+func (s *Store) ListToolQuotas(ctx context.Context, day, username string) ([]ToolQuota, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT day, username, tool, used, quota_limit FROM tool_quotas WHERE day = $1 AND username = $2 ORDER BY tool`,
+		day, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotas []ToolQuota
+	for rows.Next() {
+		q, err := scanToolQuota(rows)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, *q)
+	}
+	return quotas, rows.Err()
+}
+
+// PruneToolQuotas 删除 before 之前的调用记录，返回删除的行数
+func (s *Store) PruneToolQuotas(ctx context.Context, before string) (int64, error) {
+	result, err := s.dialect.exec(ctx, s.db, `DELETE FROM tool_quotas WHERE day < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func scanToolQuota(row scanner) (*ToolQuota, error) {
+	var q ToolQuota
+	var override sql.NullInt64
+	if err := row.Scan(&q.Day, &q.Username, &q.Tool, &q.Used, &override); err != nil {
+		return nil, err
+	}
+	if override.Valid {
+		limit := int(override.Int64)
+		q.Override = &limit
+	}
+	return &q, nil
+}
@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteToolQuotas(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open("sqlite", filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	if q, err := store.GetToolQuota(ctx, "2025-03-01", "alice", "trivy"); err != nil || q != nil {
+		t.Fatalf("GetToolQuota(missing) = %+v, %v", q, err)
+	}
+	for i, want := range []bool{true, true, false} {
+		if ok, err := store.IncrementToolQuota(ctx, "2025-03-01", "alice", "trivy", 2); err != nil || ok != want {
+			t.Fatalf("IncrementToolQuota() call %d = %v, %v, want %v", i+1, ok, err, want)
+		}
+	}
+
+	// 覆盖配额不影响已记录的调用次数
+	if err := store.SetToolQuotaOverride(ctx, "2025-03-01", "alice", "trivy", 3); err != nil {
+		t.Fatal(err)
+	}
+	q, err := store.GetToolQuota(ctx, "2025-03-01", "alice", "trivy")
+	if err != nil || q.Used != 2 || q.Override == nil || *q.Override != 3 {
+		t.Fatalf("GetToolQuota() = %+v, %v", q, err)
+	}
+	if ok, err := store.IncrementToolQuota(ctx, "2025-03-01", "alice", "trivy", -1); err != nil || !ok {
+		t.Errorf("IncrementToolQuota(unlimited) = %v, %v", ok, err)
+	}
+
+	store.IncrementToolQuota(ctx, "2025-03-02", "alice", "python", 5)
+	if quotas, err := store.ListToolQuotas(ctx, "2025-03-01", "alice"); err != nil || len(quotas) != 1 || quotas[0].Used != 3 {
+		t.Errorf("ListToolQuotas() = %+v, %v", quotas, err)
+	}
+	if pruned, err := store.PruneToolQuotas(ctx, "2025-03-02"); err != nil || pruned != 1 {
+		t.Errorf("PruneToolQuotas() = %d, %v, want 1", pruned, err)
+	}
+	if quotas, _ := store.ListToolQuotas(ctx, "2025-03-02", "alice"); len(quotas) != 1 || quotas[0].Tool != "python" {
+		t.Errorf("ListToolQuotas() after prune = %+v", quotas)
+	}
+}
@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// 交互 token 用量的来源，见 Interaction.UsageSource
+const (
+	UsageRecorded  = "recorded"  // 执行时由 token 预算记录
+	UsageCached    = "cached"    // 命中回答缓存，没有调用 LLM
+	UsageDerived   = "derived"   // 由保存的每次 LLM 调用用量汇总
+	UsageEstimated = "estimated" // 没有 LLM 调用记录，按问题、工具调用和回答的文本估算
+)
+
+// defaultBackfillBatchSize 未配置 audit.backfill.batch_size 时每批补齐的交互数
+const defaultBackfillBatchSize = 200
+
+// BackfillResult 一次补齐的结果
+type BackfillResult struct {
+	Interactions int   `json:"interactions"` // 处理的交互数
+	Derived      int   `json:"derived"`      // token 用量由 LLM 调用记录汇总的交互数
+	Estimated    int   `json:"estimated"`    // token 用量按文本估算的交互数
+	ToolCalls    int   `json:"tool_calls"`   // 更新的工具调用数
+	Durations    int   `json:"durations"`    // 推算出耗时的工具调用数
+	ElapsedMs    int64 `json:"elapsed_ms"`
+}
+
+// fillUsage 补齐交互中缺少的用量：工具调用的耗时和观察结果 token 数、交互的耗时，
+// 以及 UsageSource 为空（历史记录）时的 token 用量；写入新记录和补齐历史记录使用相同的推算方式，已有的值不会被覆盖
+func fillUsage(i *Interaction) {
+	for idx := range i.ToolCalls {
+		call := &i.ToolCalls[idx]
+		if call.ObservationTokens == 0 && call.Observation != "" {
+			call.ObservationTokens = llms.CountTokens(call.Observation, i.Model)
+		}
+	}
+	for idx, duration := range toolDurations(i) {
+		if i.ToolCalls[idx].DurationMs == 0 {
+			i.ToolCalls[idx].DurationMs = duration
+		}
+	}
+	if i.DurationMs == 0 && len(i.LLMCalls) > 0 {
+		last := i.LLMCalls[len(i.LLMCalls)-1]
+		i.DurationMs = max(last.CreatedAt.Add(time.Duration(last.LatencyMs)*time.Millisecond).Sub(i.CreatedAt).Milliseconds(), 0)
+	}
+
+	if i.UsageSource != "" {
+		return
+	}
+	switch {
+	case i.PromptTokens > 0 || i.CompletionTokens > 0:
+		i.UsageSource = UsageRecorded
+	case len(i.LLMCalls) > 0:
+		for _, call := range i.LLMCalls {
+			i.PromptTokens += call.PromptTokens
+			i.CompletionTokens += call.CompletionTokens
+		}
+		i.UsageSource = UsageDerived
+	default:
+		i.PromptTokens, i.CompletionTokens = estimateTokens(i)
+		i.UsageSource = UsageEstimated
+	}
+}
+
+// toolDurations 推算每次工具调用的耗时，返回值与 ToolCalls 一一对应，无法推算时为 0
+// 每轮迭代先调用 LLM 再执行一个工具，第 k 次工具调用从执行回执的执行时间（没有回执时为第 k 次 LLM 调用结束）
+// 开始，到第 k+1 次 LLM 调用开始结束；LLM 调用次数不等于工具调用次数加一时（一轮多个工具、跨集群并行执行等）无法对应，不推算
+func toolDurations(i *Interaction) []int64 {
+	if len(i.ToolCalls) == 0 || len(i.LLMCalls) != len(i.ToolCalls)+1 {
+		return nil
+	}
+	llmCalls := append([]LLMCall(nil), i.LLMCalls...)
+	sort.Slice(llmCalls, func(a, b int) bool { return llmCalls[a].Seq < llmCalls[b].Seq })
+	// 重复的调用直接使用交互内缓存的结果，不会签发回执，回执数不同时无法对应
+	receipts := i.Receipts
+	if len(receipts) != len(i.ToolCalls) {
+		receipts = nil
+	}
+
+	durations := make([]int64, len(i.ToolCalls))
+	for k := range i.ToolCalls {
+		start := llmCalls[k].CreatedAt.Add(time.Duration(llmCalls[k].LatencyMs) * time.Millisecond)
+		if receipts != nil {
+			start = receipts[k].ExecutedAt
+		}
+		if end := llmCalls[k+1].CreatedAt; end.After(start) {
+			durations[k] = end.Sub(start).Milliseconds()
+		}
+	}
+	return durations
+}
+
+// estimateTokens 按文本估算没有 LLM 调用记录的交互的 token 用量
+// 每轮提示包含问题和之前全部的工具调用及观察结果，每轮输出为工具调用，最后一轮输出回答；
+// 系统提示和会话历史没有保存，估算值偏低，只用于历史报表的趋势对比
+func estimateTokens(i *Interaction) (prompt, completion int) {
+	question := llms.CountTokens(i.Question, i.Model)
+	history := question
+	for _, call := range i.ToolCalls {
+		input := llms.CountTokens(call.Input, i.Model)
+		prompt += history
+		completion += input
+		history += input + call.ObservationTokens
+	}
+	prompt += history
+	completion += llms.CountTokens(i.Answer, i.Model)
+	return prompt, completion
+}
+
+// Backfill 补齐历史交互的用量：升级到表结构版本 13 之前写入的交互没有工具调用耗时、观察结果 token 数和用量来源，
+// 按 fillUsage 的方式从保存的 LLM 调用、执行回执和对话文本推算，使历史报表与新记录口径一致
+// 只处理 usage_source 为空的交互，可以重复执行；版本 13 之前命中回答缓存的交互无法区分，按估算处理
+func (s *Store) Backfill(ctx context.Context, batchSize int) (*BackfillResult, error) {
+	if batchSize <= 0 {
+		batchSize = defaultBackfillBatchSize
+	}
+	start := time.Now()
+	result := &BackfillResult{}
+	for {
+		ids, err := s.pendingUsage(ctx, batchSize)
+		if err != nil {
+			return result, err
+		}
+		for _, id := range ids {
+			if err := s.backfillInteraction(ctx, id, result); err != nil {
+				return result, fmt.Errorf("补齐交互 %s 的用量失败: %v", id, err)
+			}
+		}
+		result.ElapsedMs = time.Since(start).Milliseconds()
+		if len(ids) < batchSize {
+			return result, nil
+		}
+	}
+}
+
+// pendingUsage 返回尚未补齐用量的交互，按创建时间从早到晚
+func (s *Store) pendingUsage(ctx context.Context, limit int) ([]string, error) {
+	rows, err := s.dialect.query(ctx, s.db,
+		`SELECT id FROM interactions WHERE usage_source = '' ORDER BY created_at, id LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// backfillInteraction 在一个事务中更新单个交互及其工具调用的用量
+func (s *Store) backfillInteraction(ctx context.Context, id string, result *BackfillResult) error {
+	interaction, err := s.GetInteraction(ctx, id)
+	if err != nil {
+		return err
+	}
+	before := append([]ToolCall(nil), interaction.ToolCalls...)
+	fillUsage(interaction)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = s.dialect.exec(ctx, tx,
+		`UPDATE interactions SET duration_ms = $1, prompt_tokens = $2, completion_tokens = $3, usage_source = $4 WHERE id = $5`,
+		interaction.DurationMs, interaction.PromptTokens, interaction.CompletionTokens, interaction.UsageSource, id)
+	if err != nil {
+		return err
+	}
+	for idx, call := range interaction.ToolCalls {
+		if call.DurationMs == before[idx].DurationMs && call.ObservationTokens == before[idx].ObservationTokens {
+			continue
+		}
+		_, err = s.dialect.exec(ctx, tx,
+			`UPDATE tool_calls SET duration_ms = $1, observation_tokens = $2 WHERE interaction_id = $3 AND seq = $4`,
+			call.DurationMs, call.ObservationTokens, id, call.Seq)
+		if err != nil {
+			return err
+		}
+		result.ToolCalls++
+		if call.DurationMs > 0 && before[idx].DurationMs == 0 {
+			result.Durations++
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	result.Interactions++
+	switch interaction.UsageSource {
+	case UsageDerived:
+		result.Derived++
+	case UsageEstimated:
+		result.Estimated++
+	}
+	return nil
+}
+
+// startBackfill 打开审计存储后在后台补齐历史交互的用量，配置项 audit.backfill.enabled（默认启用）
+// 多个实例同时执行时各自更新相同的值，结果一致；存储关闭时停止，下次启动时继续
+func (s *Store) startBackfill() {
+	config := utils.GetConfig()
+	if config.IsSet("audit.backfill.enabled") && !config.GetBool("audit.backfill.enabled") {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		result, err := s.Backfill(ctx, config.GetInt("audit.backfill.batch_size"))
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.logger.Error("补齐历史交互的用量失败", zap.Error(err))
+			return
+		}
+		if result.Interactions > 0 {
+			s.logger.Info("已补齐历史交互的用量",
+				zap.Int("interactions", result.Interactions),
+				zap.Int("derived", result.Derived),
+				zap.Int("estimated", result.Estimated),
+				zap.Int("tool_calls", result.ToolCalls),
+				zap.Int64("elapsed_ms", result.ElapsedMs),
+			)
+		}
+	}()
+}
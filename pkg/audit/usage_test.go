@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFillUsage(t *testing.T) {
+	start := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	i := &Interaction{Model: "gpt-4o", Question: "how many pods are running", Answer: "3", CreatedAt: start,
+		ToolCalls: []ToolCall{
+			{Seq: 1, Name: "kubectl", Input: "get pods", Observation: "a Running\nb Running\nc Running"},
+			{Seq: 2, Name: "jq", Input: ".items | length", Observation: "3"},
+		},
+		LLMCalls: []LLMCall{
+			{Seq: 1, PromptTokens: 100, CompletionTokens: 10, LatencyMs: 500, CreatedAt: at(0)},
+			{Seq: 2, PromptTokens: 150, CompletionTokens: 10, LatencyMs: 400, CreatedAt: at(1500)},
+			{Seq: 3, PromptTokens: 160, CompletionTokens: 20, LatencyMs: 300, CreatedAt: at(2200)},
+		},
+	}
+	fillUsage(i)
+	if i.ToolCalls[0].DurationMs != 1000 || i.ToolCalls[1].DurationMs != 300 {
+		t.Errorf("tool durations = %d, %d, want 1000, 300", i.ToolCalls[0].DurationMs, i.ToolCalls[1].DurationMs)
+	}
+	if i.ToolCalls[0].ObservationTokens == 0 || i.ToolCalls[1].ObservationTokens == 0 {
+		t.Errorf("observation tokens not filled: %+v", i.ToolCalls)
+	}
+	if i.UsageSource != UsageDerived || i.PromptTokens != 410 || i.CompletionTokens != 40 || i.DurationMs != 2500 {
+		t.Errorf("usage = %s %d/%d %dms, want derived 410/40 2500ms", i.UsageSource, i.PromptTokens, i.CompletionTokens, i.DurationMs)
+	}
+
+	// 执行回执的时间是工具开始执行的时间，优先于 LLM 调用结束的时间
+	i.ToolCalls[0].DurationMs, i.ToolCalls[1].DurationMs = 0, 0
+	i.Receipts = []ToolReceipt{{Seq: 1, ExecutedAt: at(700)}, {Seq: 2, ExecutedAt: at(2000)}}
+	fillUsage(i)
+	if i.ToolCalls[0].DurationMs != 800 || i.ToolCalls[1].DurationMs != 200 {
+		t.Errorf("tool durations with receipts = %d, %d, want 800, 200", i.ToolCalls[0].DurationMs, i.ToolCalls[1].DurationMs)
+	}
+
+	// 一轮多个工具调用时无法对应到 LLM 调用
+	i.ToolCalls = append(i.ToolCalls, ToolCall{Seq: 3, Name: "kubectl", Input: "get svc"})
+	if durations := toolDurations(i); durations != nil {
+		t.Errorf("toolDurations() = %v, want nil", durations)
+	}
+
+	// 没有 LLM 调用记录时按文本估算，工具调用越多提示越长
+	old := &Interaction{Model: "gpt-4o", Question: "how many pods are running", Answer: "3",
+		ToolCalls: []ToolCall{{Seq: 1, Name: "kubectl", Input: "get pods", Observation: "a Running\nb Running"}}}
+	fillUsage(old)
+	if old.UsageSource != UsageEstimated || old.PromptTokens <= old.ToolCalls[0].ObservationTokens || old.CompletionTokens == 0 {
+		t.Errorf("estimated usage = %s %d/%d", old.UsageSource, old.PromptTokens, old.CompletionTokens)
+	}
+
+	recorded := &Interaction{PromptTokens: 5, UsageSource: UsageRecorded}
+	fillUsage(recorded)
+	if recorded.PromptTokens != 5 || recorded.UsageSource != UsageRecorded {
+		t.Errorf("recorded usage changed: %+v", recorded)
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	ctx := context.Background()
+	store, err := Open("sqlite", filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer store.Close()
+
+	// 模拟升级前写入的记录：没有用量来源、工具调用耗时和观察结果 token 数
+	created := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Microsecond)
+	for _, statement := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO interactions (id, question, answer, status, duration_ms, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			[]interface{}{"old", "list pods", "two pods", StatusSuccess, 3000, created}},
+		{`INSERT INTO tool_calls (interaction_id, seq, name, input, observation) VALUES ($1, $2, $3, $4, $5)`,
+			[]interface{}{"old", 1, "kubectl", "get pods", "a\nb"}},
+		{`INSERT INTO interactions (id, question, answer, status, duration_ms, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			[]interface{}{"tracked", "list pods", "two pods", StatusSuccess, 3000, created.Add(time.Second)}},
+		{`INSERT INTO tool_calls (interaction_id, seq, name, input, observation) VALUES ($1, $2, $3, $4, $5)`,
+			[]interface{}{"tracked", 1, "kubectl", "get pods", "a\nb"}},
+		{`INSERT INTO llm_calls (interaction_id, seq, prompt_tokens, completion_tokens, latency_ms, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			[]interface{}{"tracked", 1, 80, 12, 400, created.Add(time.Second)}},
+		{`INSERT INTO llm_calls (interaction_id, seq, prompt_tokens, completion_tokens, latency_ms, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			[]interface{}{"tracked", 2, 120, 30, 600, created.Add(2 * time.Second)}},
+	} {
+		if _, err := store.dialect.exec(ctx, store.db, statement.query, statement.args...); err != nil {
+			t.Fatalf("exec %s: %v", statement.query, err)
+		}
+	}
+	if err := store.insertBatch(ctx, []*Interaction{{ID: "new", Question: "q", Status: StatusSuccess, CreatedAt: created}}); err != nil {
+		t.Fatalf("insertBatch() error = %v", err)
+	}
+
+	result, err := store.Backfill(ctx, 1)
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+	if result.Interactions != 2 || result.Derived != 1 || result.Estimated != 1 || result.ToolCalls != 2 || result.Durations != 1 {
+		t.Errorf("Backfill() = %+v", result)
+	}
+
+	tracked, err := store.GetInteraction(ctx, "tracked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tracked.UsageSource != UsageDerived || tracked.PromptTokens != 200 || tracked.CompletionTokens != 42 ||
+		tracked.ToolCalls[0].DurationMs != 600 || tracked.ToolCalls[0].ObservationTokens == 0 {
+		t.Errorf("tracked = %+v", tracked)
+	}
+	old, err := store.GetInteraction(ctx, "old")
+	if err != nil || old.UsageSource != UsageEstimated || old.PromptTokens == 0 || old.ToolCalls[0].DurationMs != 0 {
+		t.Errorf("old = %+v, %v", old, err)
+	}
+	if fresh, err := store.GetInteraction(ctx, "new"); err != nil || fresh.UsageSource != UsageRecorded || fresh.PromptTokens != 0 {
+		t.Errorf("new = %+v, %v", fresh, err)
+	}
+
+	// 已补齐的交互不会重复处理
+	if result, err := store.Backfill(ctx, 0); err != nil || result.Interactions != 0 {
+		t.Errorf("second Backfill() = %+v, %v", result, err)
+	}
+}
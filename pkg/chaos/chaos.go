@@ -0,0 +1,127 @@
+// Package chaos 故障注入：按配置的比例让 LLM 调用、工具执行和审计数据库写入失败，
+// 用于在预发环境验证重试、故障转移和降级路径；生产环境不能启用，见 utils.IsProductionEnvironment
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Target 故障注入点
+type Target string
+
+const (
+	TargetLLM  Target = "llm"  // 每次 LLM 请求（含重试），注入的错误按服务端 500 处理，触发退避重试和多端点故障转移
+	TargetTool Target = "tool" // 每次工具执行（含自动重试），注入的错误按目标不可达处理，触发只读调用的自动重试和重试预算
+	TargetDB   Target = "db"   // 每个审计记录写入事务，触发批量写入失败后的逐条重试
+)
+
+// Targets 全部故障注入点
+var Targets = []Target{TargetLLM, TargetTool, TargetDB}
+
+// ErrProduction 在生产环境中启用故障注入
+var ErrProduction = errors.New("生产环境不能启用故障注入，请检查 server.environment")
+
+// Fault 注入的故障
+type Fault struct {
+	Target Target
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("chaos: injected %s fault", f.Target)
+}
+
+// IsFault 错误是否由故障注入产生
+func IsFault(err error) bool {
+	var fault *Fault
+	return errors.As(err, &fault)
+}
+
+// injector 启用后的故障注入配置
+type injector struct {
+	rates map[Target]float64
+	mu    sync.Mutex
+	rand  *rand.Rand
+}
+
+var current atomic.Pointer[injector]
+
+// Init 按配置项 chaos.* 启用故障注入，未启用时不做任何事
+// server.environment 未设置或为生产环境时返回 ErrProduction
+func Init() error {
+	config := utils.GetConfig()
+	if !config.GetBool("chaos.enabled") {
+		Disable()
+		return nil
+	}
+	environment := config.GetString("server.environment")
+	if utils.IsProductionEnvironment(environment) {
+		Disable()
+		return ErrProduction
+	}
+
+	rates := make(map[Target]float64, len(Targets))
+	for _, target := range Targets {
+		rates[target] = config.GetFloat64("chaos." + string(target) + "_error_rate")
+	}
+	Enable(rates, config.GetInt64("chaos.seed"))
+	utils.Warn("已启用故障注入，部分 LLM 调用、工具执行和审计写入会失败",
+		zap.String("environment", environment),
+		zap.Float64("llm_error_rate", rates[TargetLLM]),
+		zap.Float64("tool_error_rate", rates[TargetTool]),
+		zap.Float64("db_error_rate", rates[TargetDB]),
+	)
+	return nil
+}
+
+// Enable 按各注入点的失败比例（0-1）启用故障注入，seed 为 0 时使用当前时间
+// 相同的 seed 和调用顺序产生相同的注入结果，便于复现
+func Enable(rates map[Target]float64, seed int64) {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	copied := make(map[Target]float64, len(rates))
+	for target, rate := range rates {
+		copied[target] = rate
+	}
+	current.Store(&injector{rates: copied, rand: rand.New(rand.NewSource(seed))})
+}
+
+// Disable 停止故障注入
+func Disable() {
+	current.Store(nil)
+}
+
+// Enabled 是否启用了故障注入
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+// Inject 按注入点配置的比例返回 *Fault，未启用或未命中时返回 nil
+// 调用方在真正执行操作之前调用，并把 *Fault 转换为该操作的重试、降级路径能识别的错误
+func Inject(target Target) error {
+	inj := current.Load()
+	if inj == nil {
+		return nil
+	}
+	rate := inj.rates[target]
+	if rate <= 0 {
+		return nil
+	}
+	inj.mu.Lock()
+	hit := inj.rand.Float64() < rate
+	inj.mu.Unlock()
+	if !hit {
+		return nil
+	}
+	utils.GetPerfStats().IncrCounter("chaos_injected_" + string(target))
+	utils.Debug("注入故障", zap.String("target", string(target)))
+	return &Fault{Target: target}
+}
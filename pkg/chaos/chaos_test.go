@@ -0,0 +1,74 @@
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestInject(t *testing.T) {
+	defer Disable()
+
+	if err := Inject(TargetLLM); err != nil {
+		t.Fatalf("Inject() without Enable = %v", err)
+	}
+
+	Enable(map[Target]float64{TargetLLM: 1, TargetTool: 0.5}, 42)
+	if err := Inject(TargetLLM); !IsFault(err) {
+		t.Errorf("Inject(llm) with rate 1 = %v", err)
+	}
+	if err := Inject(TargetDB); err != nil {
+		t.Errorf("Inject(db) without rate = %v", err)
+	}
+	if !IsFault(fmt.Errorf("%w: connection refused", &Fault{Target: TargetTool})) || IsFault(errors.New("connection refused")) {
+		t.Error("IsFault() does not match wrapped faults only")
+	}
+
+	// 相同的种子产生相同的注入结果
+	sequence := func() []bool {
+		Enable(map[Target]float64{TargetTool: 0.5}, 7)
+		hits := make([]bool, 100)
+		for i := range hits {
+			hits[i] = Inject(TargetTool) != nil
+		}
+		return hits
+	}
+	first, second := sequence(), sequence()
+	failed := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("injection %d differs between runs with the same seed", i)
+		}
+		if first[i] {
+			failed++
+		}
+	}
+	if failed < 25 || failed > 75 {
+		t.Errorf("%d of 100 calls failed with rate 0.5", failed)
+	}
+}
+
+func TestInitRefusesProduction(t *testing.T) {
+	config := utils.GetConfig()
+	defer func() {
+		config.Set("chaos.enabled", false)
+		config.Set("server.environment", "")
+		Disable()
+	}()
+
+	config.Set("chaos.enabled", true)
+	config.Set("chaos.llm_error_rate", 1)
+	for _, environment := range []string{"", "prod", "Production"} {
+		config.Set("server.environment", environment)
+		if err := Init(); !errors.Is(err, ErrProduction) || Enabled() {
+			t.Errorf("Init() in %q = %v, enabled %v", environment, err, Enabled())
+		}
+	}
+
+	config.Set("server.environment", "staging")
+	if err := Init(); err != nil || !IsFault(Inject(TargetLLM)) {
+		t.Errorf("Init() in staging = %v, enabled %v", err, Enabled())
+	}
+}
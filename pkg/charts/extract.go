@@ -0,0 +1,281 @@
+package charts
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 图表类型
+const (
+	TypeBar  = "bar"
+	TypeLine = "line"
+)
+
+// 坐标轴类型
+const (
+	AxisCategory = "category"
+	AxisTime     = "time"
+)
+
+// Axis 横轴定义
+type Axis struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// Series 数据序列，Data 与横轴取值一一对应，无法解析的值为 nil
+type Series struct {
+	Name string     `json:"name"`
+	Unit string     `json:"unit,omitempty"`
+	Data []*float64 `json:"data"`
+}
+
+// Chart 前端可直接渲染的图表数据
+type Chart struct {
+	Type   string   `json:"type"`
+	Title  string   `json:"title,omitempty"`
+	Source string   `json:"source,omitempty"`
+	XAxis  Axis     `json:"x_axis"`
+	Series []Series `json:"series"`
+}
+
+// Table 从工具输出中解析出的表格
+type Table struct {
+	Source  string     `json:"source,omitempty"`
+	Title   string     `json:"title,omitempty"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Input 待提取图表的工具输出
+type Input struct {
+	Tool   string
+	Input  string
+	Output string
+}
+
+const (
+	minRows = 2
+	maxRows = 200
+)
+
+var columnSplitter = regexp.MustCompile(`\s{2,}|\t`)
+
+// Extract 从工具输出中提取图表数据
+// 支持 kubectl 风格的表格输出：首列或时间列作为横轴，带单位的数值列作为数据序列
+// 时间列生成折线图，其他情况（如按 Pod、节点、集群对比）生成柱状图
+func Extract(inputs []Input) []Chart {
+	var charts []Chart
+	for _, in := range inputs {
+		if chart, ok := extractTable(in.Output); ok {
+			chart.Source = in.Tool
+			chart.Title = in.Input
+			charts = append(charts, chart)
+		}
+	}
+	return charts
+}
+
+// ExtractTables 从工具输出中提取表格数据，不要求包含数值列
+func ExtractTables(inputs []Input) []Table {
+	var tables []Table
+	for _, in := range inputs {
+		if header, body, ok := parseTable(in.Output, 1); ok {
+			tables = append(tables, Table{Source: in.Tool, Title: in.Input, Columns: header, Rows: body})
+		}
+	}
+	return tables
+}
+
+// parseTable 将 kubectl 风格的表格输出拆分为表头和数据行，要求每行列数一致
+func parseTable(output string, minBodyRows int) ([]string, [][]string, bool) {
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rows = append(rows, columnSplitter.Split(line, -1))
+	}
+	if len(rows) < minBodyRows+1 || len(rows) > maxRows+1 {
+		return nil, nil, false
+	}
+
+	header, body := rows[0], rows[1:]
+	if len(header) < 2 {
+		return nil, nil, false
+	}
+	for _, row := range body {
+		if len(row) != len(header) {
+			return nil, nil, false
+		}
+	}
+	return header, body, true
+}
+
+// extractTable 解析表格输出
+func extractTable(output string) (Chart, bool) {
+	header, body, ok := parseTable(output, minRows)
+	if !ok {
+		return Chart{}, false
+	}
+
+	// 横轴：优先使用时间列，否则使用首列
+	xIndex, xType := 0, AxisCategory
+	for i := range header {
+		if columnIs(body, i, isTimestamp) {
+			xIndex, xType = i, AxisTime
+			break
+		}
+	}
+
+	chart := Chart{Type: TypeBar, XAxis: Axis{Name: header[xIndex], Type: xType}}
+	if xType == AxisTime {
+		chart.Type = TypeLine
+	}
+	for _, row := range body {
+		chart.XAxis.Values = append(chart.XAxis.Values, row[xIndex])
+	}
+
+	for i, name := range header {
+		if i == xIndex {
+			continue
+		}
+		series, ok := parseSeries(name, body, i)
+		if ok {
+			chart.Series = append(chart.Series, series)
+		}
+	}
+	if len(chart.Series) == 0 {
+		return Chart{}, false
+	}
+	return chart, true
+}
+
+// 列的取值类型，由表头决定单位的含义，例如 AGE 列中的 5m 表示 5 分钟而不是 5 毫核
+type columnKind int
+
+const (
+	columnNumber columnKind = iota // 计数、百分比或容量，m 后缀有歧义，不解析
+	columnCPU                      // CPU，统一为毫核
+	columnMemory                   // 内存，统一为 Mi
+	columnSkip                     // 时长、时间等非数值列
+)
+
+// skippedColumns 表头包含这些词的列不作为数据序列
+var skippedColumns = []string{"AGE", "LAST SEEN", "DURATION", "SINCE", "TIME", "DATE"}
+
+// kindOf 根据表头判断列的取值类型
+func kindOf(header string) columnKind {
+	header = strings.ToUpper(header)
+	for _, word := range skippedColumns {
+		if strings.Contains(header, word) {
+			return columnSkip
+		}
+	}
+	switch {
+	case strings.Contains(header, "CPU"):
+		return columnCPU
+	case strings.Contains(header, "MEM"):
+		return columnMemory
+	}
+	return columnNumber
+}
+
+// parseSeries 将一列解析为数据序列，要求大部分取值为同一单位的数值
+func parseSeries(name string, body [][]string, col int) (Series, bool) {
+	kind := kindOf(name)
+	if kind == columnSkip {
+		return Series{}, false
+	}
+	series := Series{Name: name}
+	parsed := 0
+	for i, row := range body {
+		value, unit, ok := parseQuantity(row[col], kind)
+		if !ok {
+			series.Data = append(series.Data, nil)
+			continue
+		}
+		if i > 0 && parsed > 0 && unit != series.Unit {
+			return Series{}, false
+		}
+		series.Unit = unit
+		series.Data = append(series.Data, &value)
+		parsed++
+	}
+	// 至少一半的值可解析才视为数值列，避免把 READY(1/1) 这样的文本列当作数据
+	return series, parsed > 0 && parsed*2 >= len(body)
+}
+
+var quantityPattern = regexp.MustCompile(`^(-?\d+(?:\.\d+)?)(m|Ki|Mi|Gi|Ti|k|M|G|%)?$`)
+
+var (
+	// miPerUnit 容量单位换算为 Mi 的倍数
+	miPerUnit = map[string]float64{"Ki": 1.0 / 1024, "Mi": 1, "Gi": 1024, "Ti": 1024 * 1024}
+	// decimalUnits 计数的十进制单位
+	decimalUnits = map[string]float64{"": 1, "k": 1e3, "M": 1e6, "G": 1e9}
+)
+
+// parseQuantity 按列的类型解析带单位的数值，CPU 统一为毫核，内存和容量统一为 Mi
+func parseQuantity(s string, kind columnKind) (float64, string, bool) {
+	m := quantityPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, "", false
+	}
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	unit := m[2]
+	if unit == "%" {
+		return value, "%", true
+	}
+
+	switch kind {
+	case columnCPU:
+		switch unit {
+		case "m":
+			return value, "m", true
+		case "":
+			return value * 1000, "m", true
+		}
+	case columnMemory:
+		if unit == "" {
+			return value / 1024 / 1024, "Mi", true
+		}
+		if f, ok := miPerUnit[unit]; ok {
+			return value * f, "Mi", true
+		}
+	case columnNumber:
+		if f, ok := miPerUnit[unit]; ok {
+			return value * f, "Mi", true
+		}
+		if f, ok := decimalUnits[unit]; ok {
+			return value * f, "", true
+		}
+	}
+	return 0, "", false
+}
+
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02", "15:04:05", "15:04"}
+
+func isTimestamp(s string) bool {
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func columnIs(body [][]string, col int, pred func(string) bool) bool {
+	for _, row := range body {
+		if !pred(row[col]) {
+			return false
+		}
+	}
+	return true
+}
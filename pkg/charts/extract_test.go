@@ -0,0 +1,107 @@
+package charts
+
+import (
+	"testing"
+)
+
+func TestExtractKubectlTop(t *testing.T) {
+	output := `NAME                     CPU(cores)   MEMORY(bytes)
+nginx-6d4cf56db6-abcde   12m          64Mi
+redis-0                  250m         1Gi
+api-7c9f8d-xyz           3m           512Ki`
+
+	charts := Extract([]Input{{Tool: "kubectl", Input: "kubectl top pods", Output: output}})
+	if len(charts) != 1 {
+		t.Fatalf("expected 1 chart, got %d", len(charts))
+	}
+
+	chart := charts[0]
+	if chart.Type != TypeBar || chart.XAxis.Type != AxisCategory || len(chart.XAxis.Values) != 3 {
+		t.Fatalf("unexpected chart: %+v", chart)
+	}
+	if len(chart.Series) != 2 {
+		t.Fatalf("expected 2 series, got %+v", chart.Series)
+	}
+
+	cpu, memory := chart.Series[0], chart.Series[1]
+	if cpu.Unit != "m" || *cpu.Data[1] != 250 {
+		t.Errorf("unexpected cpu series: %+v", cpu)
+	}
+	if memory.Unit != "Mi" || *memory.Data[1] != 1024 || *memory.Data[2] != 0.5 {
+		t.Errorf("unexpected memory series: %+v", memory)
+	}
+}
+
+func TestExtractColumnsByHeader(t *testing.T) {
+	// AGE 列的 5m 是时长，不能当作 CPU 毫核
+	output := `NAME                     READY   STATUS    RESTARTS   AGE
+nginx-6d4cf56db6-abcde   1/1     Running   0          5m
+redis-0                  1/1     Running   3          12m
+api-7c9f8d-xyz           1/1     Running   1          30m`
+
+	charts := Extract([]Input{{Tool: "kubectl", Input: "kubectl get pods", Output: output}})
+	if len(charts) != 1 || len(charts[0].Series) != 1 {
+		t.Fatalf("expected 1 chart with 1 series, got %+v", charts)
+	}
+	if restarts := charts[0].Series[0]; restarts.Name != "RESTARTS" || restarts.Unit != "" || *restarts.Data[1] != 3 {
+		t.Errorf("unexpected series: %+v", restarts)
+	}
+
+	output = `NAME     CPU(cores)   CPU%   MEMORY(bytes)   MEMORY%
+node-1   250m         12%    2048Mi          26%
+node-2   1            50%    4Gi             52%`
+
+	charts = Extract([]Input{{Tool: "kubectl", Input: "kubectl top nodes", Output: output}})
+	if len(charts) != 1 || len(charts[0].Series) != 4 {
+		t.Fatalf("expected 1 chart with 4 series, got %+v", charts)
+	}
+	cpu, cpuPercent, memory := charts[0].Series[0], charts[0].Series[1], charts[0].Series[2]
+	if cpu.Unit != "m" || *cpu.Data[1] != 1000 {
+		t.Errorf("unexpected cpu series: %+v", cpu)
+	}
+	if cpuPercent.Unit != "%" || *cpuPercent.Data[0] != 12 {
+		t.Errorf("unexpected cpu%% series: %+v", cpuPercent)
+	}
+	if memory.Unit != "Mi" || *memory.Data[1] != 4096 {
+		t.Errorf("unexpected memory series: %+v", memory)
+	}
+}
+
+func TestExtractTimeSeries(t *testing.T) {
+	output := `TIME                   CLUSTER   RESTARTS
+2025-03-01T10:00:00Z   prod      1
+2025-03-01T11:00:00Z   prod      4
+2025-03-01T12:00:00Z   prod      9`
+
+	charts := Extract([]Input{{Tool: "python", Output: output}})
+	if len(charts) != 1 || charts[0].Type != TypeLine || charts[0].XAxis.Name != "TIME" {
+		t.Fatalf("expected a line chart over TIME, got %+v", charts)
+	}
+	if len(charts[0].Series) != 1 || charts[0].Series[0].Name != "RESTARTS" {
+		t.Errorf("unexpected series: %+v", charts[0].Series)
+	}
+}
+
+func TestExtractIgnoresNonTabular(t *testing.T) {
+	inputs := []Input{
+		{Tool: "kubectl", Output: "Error from server (NotFound): pods \"x\" not found"},
+		{Tool: "kubectl", Output: "NAME   STATUS\nfoo    Running\nbar    Pending"},
+	}
+	if charts := Extract(inputs); len(charts) != 0 {
+		t.Errorf("expected no charts, got %+v", charts)
+	}
+}
+
+func TestExtractTables(t *testing.T) {
+	inputs := []Input{
+		{Tool: "kubectl", Output: "Error from server (NotFound): pods \"x\" not found"},
+		{Tool: "kubectl", Input: "get pods", Output: "NAME   STATUS\nfoo    Running\nbar    Pending"},
+	}
+	tables := ExtractTables(inputs)
+	if len(tables) != 1 {
+		t.Fatalf("expected 1 table, got %+v", tables)
+	}
+	if tables[0].Title != "get pods" || len(tables[0].Columns) != 2 || len(tables[0].Rows) != 2 || tables[0].Rows[1][1] != "Pending" {
+		t.Errorf("unexpected table: %+v", tables[0])
+	}
+}
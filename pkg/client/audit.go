@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Interaction 一次用户交互的审计记录，字段与服务端审计 API 的 JSON 一致
+type Interaction struct {
+	ID         string          `json:"id"`
+	Username   string          `json:"username"`
+	Model      string          `json:"model"`
+	Cluster    string          `json:"cluster"`
+	Question   string          `json:"question"`
+	Answer     string          `json:"answer"`
+	Status     string          `json:"status"` // success、error、pending_approval 或 handed_off
+	Error      string          `json:"error,omitempty"`
+	DurationMs int64           `json:"duration_ms"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ToolCalls  []AuditToolCall `json:"tool_calls,omitempty"`
+	RAGCalls   []RAGCall       `json:"rag_calls,omitempty"`
+	Drafts     []AnswerDraft   `json:"drafts,omitempty"`
+
+	PromptName    string `json:"prompt_name,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+	PromptHash    string `json:"prompt_hash,omitempty"`
+
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	UsageSource      string        `json:"usage_source,omitempty"`
+	LLMCalls         []LLMCall     `json:"llm_calls,omitempty"`
+	Receipts         []ToolReceipt `json:"receipts,omitempty"`
+}
+
+// AuditToolCall 审计记录中的一次工具调用
+type AuditToolCall struct {
+	Seq               int    `json:"seq"`
+	Name              string `json:"name"`
+	Input             string `json:"input"`
+	Observation       string `json:"observation"`
+	DurationMs        int64  `json:"duration_ms"`
+	ObservationTokens int    `json:"observation_tokens"`
+}
+
+// RAGCall 交互中的一次检索调用
+type RAGCall struct {
+	Seq          int       `json:"seq"`
+	Kind         string    `json:"kind"`
+	Query        string    `json:"query"`
+	Context      []string  `json:"context"`
+	LatencyMs    int64     `json:"latency_ms"`
+	PromptTokens int       `json:"prompt_tokens"`
+	TotalTokens  int       `json:"total_tokens"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LLMCall 交互中的一次 LLM 调用
+type LLMCall struct {
+	Seq              int       `json:"seq"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	FinishReason     string    `json:"finish_reason,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// AnswerDraft 回答审阅中的一版回答
+type AnswerDraft struct {
+	Seq         int       `json:"seq"`
+	Answer      string    `json:"answer"`
+	Reviewer    string    `json:"reviewer,omitempty"`
+	Verdict     string    `json:"verdict"`
+	Critique    string    `json:"critique,omitempty"`
+	ReviewError string    `json:"review_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToolReceipt 工具执行的签名回执
+type ToolReceipt struct {
+	InteractionID string    `json:"interaction_id,omitempty"`
+	Seq           int       `json:"seq"`
+	Tool          string    `json:"tool"`
+	InputHash     string    `json:"input_hash"`
+	OutputHash    string    `json:"output_hash"`
+	ExecutedAt    time.Time `json:"executed_at"`
+	Runner        string    `json:"runner"`
+	KeyID         string    `json:"key_id"`
+	Signature     string    `json:"signature"`
+}
+
+// TimelineEntry 交互时间线中的一个事件，Type 为 rag 或 tool
+type TimelineEntry struct {
+	Type     string         `json:"type"`
+	RAGCall  *RAGCall       `json:"rag_call,omitempty"`
+	ToolCall *AuditToolCall `json:"tool_call,omitempty"`
+}
+
+// AuditQuery 审计记录查询条件
+type AuditQuery struct {
+	Filters map[string]string // 字段过滤，例如 username、model、cluster、status
+	Since   time.Time         // 起始时间，默认最近 7 天
+	Until   time.Time         // 结束时间，默认当前时间
+	SortBy  string            // 排序字段，默认 created_at
+	Desc    bool              // 是否降序
+	Limit   int               // 每页数量，默认 50，最大 200
+	Cursor  string            // 上一页返回的游标
+}
+
+// InteractionDetail 单个交互的审计记录及按时间排列的事件
+type InteractionDetail struct {
+	Interaction *Interaction    `json:"interaction"`
+	Timeline    []TimelineEntry `json:"timeline,omitempty"`
+}
+
+// ListInteractions 分页查询审计记录，返回下一页的游标，没有更多记录时为空
+func (c *Client) ListInteractions(ctx context.Context, q AuditQuery) ([]Interaction, string, error) {
+	params := url.Values{}
+	for key, value := range q.Filters {
+		params.Set(key, value)
+	}
+	if !q.Since.IsZero() {
+		params.Set("since", q.Since.Format(time.RFC3339))
+	}
+	if !q.Until.IsZero() {
+		params.Set("until", q.Until.Format(time.RFC3339))
+	}
+	if q.SortBy != "" {
+		params.Set("sort", q.SortBy)
+	}
+	if q.Desc {
+		params.Set("order", "desc")
+	} else {
+		params.Set("order", "asc")
+	}
+	if q.Limit > 0 {
+		params.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		params.Set("cursor", q.Cursor)
+	}
+
+	var resp struct {
+		Interactions []Interaction `json:"interactions"`
+		NextCursor   string        `json:"next_cursor"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/audit/interactions?"+params.Encode(), nil, &resp); err != nil {
+		return nil, "", err
+	}
+	return resp.Interactions, resp.NextCursor, nil
+}
+
+// GetInteraction 获取单个交互及其工具调用
+func (c *Client) GetInteraction(ctx context.Context, id string) (*Interaction, error) {
+	detail, err := c.GetInteractionDetail(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return detail.Interaction, nil
+}
+
+// GetInteractionDetail 获取单个交互及其事件时间线
+func (c *Client) GetInteractionDetail(ctx context.Context, id string) (*InteractionDetail, error) {
+	var detail InteractionDetail
+	if err := c.do(ctx, http.MethodGet, "/audit/interactions/"+url.PathEscape(id), nil, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
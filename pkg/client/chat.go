@@ -0,0 +1,190 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket 消息类型
+const (
+	ChatEventSession  = "session"  // 会话信息
+	ChatEventAnswer   = "answer"   // 回答
+	ChatEventError    = "error"    // 错误
+	ChatEventProgress = "progress" // 处理进度
+)
+
+// ChatOptions 建立对话的参数，SessionID 为空时创建新会话，Provider、Model、Cluster 仅在创建会话时生效
+type ChatOptions struct {
+	SessionID   string
+	Provider    string
+	Model       string
+	Cluster     string
+	BaseURL     string // LLM 服务地址
+	ShowThought bool   // 回答中返回工具调用历史
+}
+
+// StageTiming 一个处理阶段的耗时
+type StageTiming struct {
+	Stage     string `json:"stage"`
+	Detail    string `json:"detail,omitempty"`
+	ElapsedMs int64  `json:"elapsed_ms"`
+}
+
+// ChatEvent 服务端推送的消息
+type ChatEvent struct {
+	Type           string        `json:"type"`
+	SessionID      string        `json:"session_id,omitempty"`
+	Turns          int           `json:"turns,omitempty"`
+	Message        string        `json:"message,omitempty"`
+	InteractionID  string        `json:"interaction_id,omitempty"`
+	ToolsHistory   []ToolCall    `json:"tools_history,omitempty"`
+	Error          string        `json:"error,omitempty"`
+	ErrorCode      string        `json:"error_code,omitempty"`
+	Stage          string        `json:"stage,omitempty"`
+	Detail         string        `json:"detail,omitempty"`
+	StageElapsedMs int64         `json:"stage_elapsed_ms,omitempty"`
+	ElapsedMs      int64         `json:"elapsed_ms,omitempty"`
+	Stages         []StageTiming `json:"stages,omitempty"`
+}
+
+type chatMessage struct {
+	Type    string `json:"type"`
+	Content string `json:"content,omitempty"`
+}
+
+// ChatStream 一个 WebSocket 对话连接，Send 和 Recv 可以在不同 goroutine 中调用
+type ChatStream struct {
+	SessionID string // 服务端分配的会话 ID，可用于断线后恢复会话
+
+	conn   *websocket.Conn
+	sendMu sync.Mutex
+}
+
+// Chat 建立多轮对话连接，返回前已收到服务端的会话信息
+// WebSocket 握手不经过重试，连接失败时由调用方决定是否使用 SessionID 重新连接
+func (c *Client) Chat(ctx context.Context, opts ChatOptions) (*ChatStream, error) {
+	location, err := url.Parse(strings.TrimRight(c.BaseURL, "/") + apiPrefix + "/ws/chat")
+	if err != nil {
+		return nil, err
+	}
+	origin := *location
+	origin.Path, origin.RawQuery = "/", ""
+	switch location.Scheme {
+	case "https":
+		location.Scheme = "wss"
+	case "http":
+		location.Scheme = "ws"
+	}
+
+	params := url.Values{}
+	for key, value := range map[string]string{
+		"session_id": opts.SessionID,
+		"provider":   opts.Provider,
+		"model":      opts.Model,
+		"cluster":    opts.Cluster,
+		"baseUrl":    opts.BaseURL,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	if opts.ShowThought {
+		params.Set("showThought", "true")
+	}
+	location.RawQuery = params.Encode()
+
+	config, err := websocket.NewConfig(location.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(config.Header)
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &ChatStream{conn: conn}
+	event, err := stream.Recv()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if event.Type != ChatEventSession {
+		conn.Close()
+		return nil, errors.New("建立对话失败: " + event.Error)
+	}
+	stream.SessionID = event.SessionID
+	return stream, nil
+}
+
+// Send 发送一个问题，回答通过 Recv 接收
+func (s *ChatStream) Send(content string) error {
+	return s.send(chatMessage{Type: "message", Content: content})
+}
+
+// Reset 清空会话历史
+func (s *ChatStream) Reset() error {
+	return s.send(chatMessage{Type: "reset"})
+}
+
+// Recv 接收下一条服务端消息
+func (s *ChatStream) Recv() (ChatEvent, error) {
+	var event ChatEvent
+	err := websocket.JSON.Receive(s.conn, &event)
+	return event, err
+}
+
+// Ask 发送问题并等待回答，期间收到的进度消息交给 onProgress（可以为空）
+// 服务端返回错误消息时返回 error，ctx 取消时关闭连接
+func (s *ChatStream) Ask(ctx context.Context, content string, onProgress func(ChatEvent)) (*ChatEvent, error) {
+	if err := s.Send(content); err != nil {
+		return nil, err
+	}
+	stop := context.AfterFunc(ctx, func() { s.conn.Close() })
+	defer stop()
+
+	for {
+		event, err := s.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		switch event.Type {
+		case ChatEventAnswer:
+			return &event, nil
+		case ChatEventError:
+			return &event, errors.New(event.Error)
+		case ChatEventProgress:
+			if onProgress != nil {
+				onProgress(event)
+			}
+		}
+	}
+}
+
+// Close 结束会话并关闭连接，服务端会删除会话历史；只断开连接而保留会话时使用 Disconnect
+func (s *ChatStream) Close() error {
+	err := s.send(chatMessage{Type: "close"})
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Disconnect 断开连接，会话保留在服务端，可以使用 SessionID 重新连接
+func (s *ChatStream) Disconnect() error {
+	return s.conn.Close()
+}
+
+func (s *ChatStream) send(msg chatMessage) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	return websocket.JSON.Send(s.conn, msg)
+}
@@ -0,0 +1,215 @@
+// Package client OpsAgent REST API 的 Go SDK：登录、执行问题、WebSocket 多轮对话和审计查询，
+// 请求和响应使用类型化的结构，/api/v2 统一响应格式的解包和失败重试由 SDK 处理
+//
+//	c := &client.Client{BaseURL: "http://opsagent:8080", APIKey: os.Getenv("OPSAGENT_API_KEY")}
+//	resp, err := c.Execute(ctx, client.ExecuteRequest{Instructions: "payment-api 的镜像版本", Cluster: "prod"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	apiPrefix      = "/api/v2"
+	defaultRetries = 2
+	defaultBackoff = 500 * time.Millisecond
+)
+
+// Client OpsAgent API 客户端，零值之外只需要设置 BaseURL 和一种认证方式
+// 调用 Login 会写入 Token，此后不要在多个 goroutine 中同时调用 Login
+type Client struct {
+	BaseURL string       // 服务地址，例如 http://localhost:8080
+	Token   string       // JWT 令牌，可通过 Login 获取
+	APIKey  string       // 托管 API Key，或未启用托管 API Key 时转发给 LLM 的密钥（X-API-Key）
+	HTTP    *http.Client // 为空时使用 http.DefaultClient
+	Retries int          // 失败重试次数，0 使用默认值 2，小于 0 不重试
+	Backoff time.Duration
+}
+
+// APIError 服务返回的错误响应
+type APIError struct {
+	StatusCode int
+	Message    string
+	ErrorCode  string // 机器可读的错误码，例如 llm_throttled、command_denied，服务端未提供时为空
+	RequestID  string
+	Data       json.RawMessage // 错误的附加信息，例如配置校验的 issues
+}
+
+func (e *APIError) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("opsagent API returned %d: %s (request_id %s)", e.StatusCode, e.Message, e.RequestID)
+	}
+	return fmt.Sprintf("opsagent API returned %d: %s", e.StatusCode, e.Message)
+}
+
+// envelope /api/v2 的统一响应格式
+type envelope struct {
+	Code      int             `json:"code"`
+	Message   string          `json:"message"`
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"request_id"`
+}
+
+// do 发送请求并将 data 解码到 out，按 shouldRetry 的规则以指数退避重试
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	retries := c.Retries
+	if retries == 0 {
+		retries = defaultRetries
+	}
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, path, payload)
+		retry, wait := shouldRetry(method, resp, err)
+		if retry && method != http.MethodGet && resp != nil && hasErrorCode(resp) {
+			// 错误来自 OpsAgent 本身（例如 LLM 限流），服务端已经重试过，不再重复提交
+			retry = false
+		}
+		if !retry || attempt >= retries {
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			return decode(resp, out)
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if wait <= 0 {
+			wait = backoff << attempt
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, path string, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.BaseURL, "/")+apiPrefix+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	c.authorize(req.Header)
+
+	httpClient := c.HTTP
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// authorize 添加认证请求头
+func (c *Client) authorize(header http.Header) {
+	if c.Token != "" {
+		header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.APIKey != "" {
+		header.Set("X-API-Key", c.APIKey)
+	}
+}
+
+// shouldRetry 判断请求是否可以重试，以及服务端通过 Retry-After 要求的等待时间
+// 网关错误（502/503/504）和网络错误时重试；POST 请求可能已在服务端执行，只在连接失败和网关返回的 502/503 时重试
+func shouldRetry(method string, resp *http.Response, err error) (bool, time.Duration) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false, 0
+		}
+		if method == http.MethodGet {
+			return true, 0
+		}
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial", 0
+	}
+
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+	case http.StatusGatewayTimeout:
+		if method != http.MethodGet {
+			return false, 0
+		}
+	default:
+		return false, 0
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		return true, time.Duration(seconds) * time.Second
+	}
+	return true, 0
+}
+
+// hasErrorCode 判断错误响应是否由 OpsAgent 返回（带 error_code），而不是前面的网关；读取后恢复响应体
+func hasErrorCode(resp *http.Response) bool {
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return false
+	}
+	var env struct {
+		Data struct {
+			ErrorCode string `json:"error_code"`
+		} `json:"data"`
+	}
+	return json.Unmarshal(data, &env) == nil && env.Data.ErrorCode != ""
+}
+
+// decode 解包统一响应格式，失败响应返回 APIError
+func decode(resp *http.Response, out interface{}) error {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		if resp.StatusCode >= http.StatusBadRequest {
+			return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest || env.Code != 0 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: env.Message, RequestID: env.RequestID, Data: env.Data}
+		var data struct {
+			ErrorCode string `json:"error_code"`
+		}
+		if json.Unmarshal(env.Data, &data) == nil {
+			apiErr.ErrorCode = data.ErrorCode
+		}
+		return apiErr
+	}
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func writeEnvelope(w http.ResponseWriter, status int, message string, data interface{}) {
+	code := 0
+	if status >= http.StatusBadRequest {
+		code = status
+	}
+	raw, _ := json.Marshal(data)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope{Code: code, Message: message, Data: raw, RequestID: "req-1"})
+}
+
+func TestLoginAndExecute(t *testing.T) {
+	var attempts int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/login", func(w http.ResponseWriter, r *http.Request) {
+		writeEnvelope(w, http.StatusOK, "", LoginResponse{Token: "jwt-token", Role: "admin"})
+	})
+	mux.HandleFunc("/api/v2/execute", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer jwt-token" {
+			writeEnvelope(w, http.StatusUnauthorized, "未授权", nil)
+			return
+		}
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			writeEnvelope(w, http.StatusServiceUnavailable, "服务繁忙", nil)
+			return
+		}
+		var req ExecuteRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		writeEnvelope(w, http.StatusOK, "", ExecuteResponse{Status: "success", Message: "answer to " + req.Instructions, InteractionID: "i-1"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, Backoff: time.Millisecond}
+	login, err := c.Login(context.Background(), "admin", "secret")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if login.Role != "admin" || c.Token != "jwt-token" {
+		t.Fatalf("unexpected login result %+v, token %q", login, c.Token)
+	}
+
+	resp, err := c.Execute(context.Background(), ExecuteRequest{Instructions: "pods"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if resp.Message != "answer to pods" || resp.InteractionID != "i-1" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 1 retry after 503, got %d attempts", attempts)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		writeEnvelope(w, http.StatusForbidden, "权限不足", map[string]string{"required": "admin"})
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, Backoff: time.Millisecond}
+	_, err := c.Execute(context.Background(), ExecuteRequest{Instructions: "delete pod"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "权限不足" || apiErr.RequestID != "req-1" {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+	if attempts != 1 {
+		t.Errorf("4xx must not be retried, got %d attempts", attempts)
+	}
+}
+
+func TestListInteractions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/audit/interactions" {
+			writeEnvelope(w, http.StatusNotFound, "not found", nil)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("username") != "alice" || q.Get("order") != "desc" || q.Get("limit") != "10" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		writeEnvelope(w, http.StatusOK, "", map[string]interface{}{
+			"interactions": []Interaction{{ID: "i-1", Username: "alice"}},
+			"next_cursor":  "c-2",
+		})
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, APIKey: "key"}
+	items, cursor, err := c.ListInteractions(context.Background(), AuditQuery{
+		Filters: map[string]string{"username": "alice"},
+		Desc:    true,
+		Limit:   10,
+	})
+	if err != nil {
+		t.Fatalf("ListInteractions: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != "i-1" || cursor != "c-2" {
+		t.Errorf("unexpected result %+v, cursor %q", items, cursor)
+	}
+}
+
+func TestChatAsk(t *testing.T) {
+	var apiKey, model string
+	server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		apiKey = ws.Request().Header.Get("X-API-Key")
+		model = ws.Request().URL.Query().Get("model")
+		websocket.JSON.Send(ws, ChatEvent{Type: ChatEventSession, SessionID: "s-1"})
+		var msg chatMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			return
+		}
+		websocket.JSON.Send(ws, ChatEvent{Type: ChatEventProgress, SessionID: "s-1", Stage: "querying_cluster"})
+		websocket.JSON.Send(ws, ChatEvent{Type: ChatEventAnswer, SessionID: "s-1", Message: "echo " + msg.Content, Turns: 1})
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL, APIKey: "key"}
+	stream, err := c.Chat(context.Background(), ChatOptions{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	defer stream.Disconnect()
+	if stream.SessionID != "s-1" || apiKey != "key" || model != "gpt-4o" {
+		t.Fatalf("unexpected session %q, api key %q, model %q", stream.SessionID, apiKey, model)
+	}
+
+	var stages []string
+	answer, err := stream.Ask(context.Background(), "hello", func(e ChatEvent) { stages = append(stages, e.Stage) })
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if answer.Message != "echo hello" || len(stages) != 1 || stages[0] != "querying_cluster" {
+		t.Errorf("unexpected answer %+v, progress %v", answer, stages)
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// LoginResponse 登录结果
+type LoginResponse struct {
+	Token string `json:"token"`
+	Role  string `json:"role"` // viewer、operator 或 admin
+	Note  string `json:"note,omitempty"`
+}
+
+// ExecuteRequest 执行问题的请求，字段与 /api/v2/execute 相同
+type ExecuteRequest struct {
+	Instructions   string   `json:"instructions"`
+	Args           string   `json:"args"`
+	Provider       string   `json:"provider,omitempty"`
+	BaseURL        string   `json:"baseUrl,omitempty"`
+	CurrentModel   string   `json:"currentModel,omitempty"`
+	Cluster        string   `json:"cluster,omitempty"`
+	Clusters       []string `json:"clusters,omitempty"` // 跨集群问题的目标集群，每个集群单独回答
+	SelectedModels []string `json:"selectedModels,omitempty"`
+	ConversationID string   `json:"conversationId,omitempty"`
+	Language       string   `json:"language,omitempty"` // 回答语言（zh/en）
+	NoCache        bool     `json:"noCache,omitempty"`  // 不使用回答缓存
+}
+
+// ToolCall 工具调用历史（服务端启用 show-thought 时返回）
+type ToolCall struct {
+	Name        string `json:"name"`
+	Input       string `json:"input"`
+	Observation string `json:"observation"`
+	Idempotency string `json:"idempotency,omitempty"`
+	Cached      bool   `json:"cached,omitempty"`
+}
+
+// Command 回答依据的命令，集群相关参数已替换为占位符
+type Command struct {
+	Tool         string            `json:"tool"`
+	Command      string            `json:"command"`
+	Placeholders map[string]string `json:"placeholders,omitempty"`
+}
+
+// TokenUsage 本次请求的 token 用量
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	UserDailyUsed    int `json:"user_daily_used,omitempty"`
+	UserDailyLimit   int `json:"user_daily_limit,omitempty"`
+}
+
+// ClusterAnswer 跨集群问题中单个集群的回答
+type ClusterAnswer struct {
+	Cluster      string     `json:"cluster"`
+	Message      string     `json:"message"`
+	Error        string     `json:"error,omitempty"`
+	ErrorCode    string     `json:"error_code,omitempty"`
+	ToolsHistory []ToolCall `json:"tools_history,omitempty"`
+}
+
+// ExecuteResponse 执行结果
+// Status 为 success、partial（部分集群失败）、needs_confirmation（集群名称需要确认）、
+// pending_approval（变更命令等待审批）或 handed_off（转交值班人员）
+type ExecuteResponse struct {
+	Status         string          `json:"status"`
+	Message        string          `json:"message"`
+	InteractionID  string          `json:"interaction_id"`
+	SessionID      string          `json:"session_id,omitempty"`
+	ConversationID string          `json:"conversation_id,omitempty"`
+	Language       string          `json:"language,omitempty"`
+	Translated     bool            `json:"translated,omitempty"`
+	Revised        bool            `json:"revised,omitempty"`
+	Cached         bool            `json:"cached,omitempty"` // 回答来自回答缓存
+	CachedAt       time.Time       `json:"cached_at,omitempty"`
+	Answers        []ClusterAnswer `json:"answers,omitempty"`
+	ToolsHistory   []ToolCall      `json:"tools_history,omitempty"`
+	Commands       []Command       `json:"commands,omitempty"`
+	TokenUsage     *TokenUsage     `json:"token_usage,omitempty"`
+	TargetErrors   json.RawMessage `json:"target_errors,omitempty"`
+	// 集群名称需要确认时的候选集群
+	ClusterConfirmation  json.RawMessage `json:"cluster_confirmation,omitempty"`
+	ClusterConfirmations json.RawMessage `json:"cluster_confirmations,omitempty"`
+}
+
+// Login 使用用户名和密码登录，成功后后续请求使用返回的令牌
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResponse, error) {
+	var resp LoginResponse
+	body := map[string]string{"username": username, "password": password}
+	if err := c.do(ctx, http.MethodPost, "/login", body, &resp); err != nil {
+		return nil, err
+	}
+	c.Token = resp.Token
+	return &resp, nil
+}
+
+// Execute 提交问题并等待回答；Args 为空时使用单个空格，满足接口的必填校验
+func (c *Client) Execute(ctx context.Context, req ExecuteRequest) (*ExecuteResponse, error) {
+	if req.Args == "" {
+		req.Args = " "
+	}
+	var resp ExecuteResponse
+	if err := c.do(ctx, http.MethodPost, "/execute", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
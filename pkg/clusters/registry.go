@@ -0,0 +1,342 @@
+package clusters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 集群登记的来源
+const (
+	SourceConfig = "config" // clusters.registry 配置，只能通过修改配置文件变更
+	SourceAPI    = "api"    // 通过 /api/clusters 管理，保存在 clusters.file 中
+	SourceRepo   = "git"    // 配置仓库 config_repo 中登记，只能通过提交到仓库变更
+)
+
+var (
+	// ErrNotFound 集群未登记
+	ErrNotFound = errors.New("cluster not found")
+	// ErrReadOnly 配置文件或配置仓库中登记的集群不能通过接口修改
+	ErrReadOnly = errors.New("cluster is defined in the config file or config repository")
+
+	nameRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+)
+
+// InClusterContext 使用集群内 ServiceAccount 访问的集群对应的 context 名称
+const InClusterContext = "in-cluster"
+
+// Cluster 登记的集群：用户使用的名称对应的 kubeconfig context、默认命名空间、别名和 kubeconfig 文件
+// InCluster 为 true 时不使用 kubeconfig，通过服务所在集群的 ServiceAccount 访问（client-go in-cluster 配置）
+type Cluster struct {
+	Name        string    `mapstructure:"name" json:"name"`
+	Context     string    `mapstructure:"context" json:"context"`
+	Namespace   string    `mapstructure:"namespace" json:"namespace,omitempty"`
+	Aliases     []string  `mapstructure:"aliases" json:"aliases,omitempty"`
+	Kubeconfig  string    `mapstructure:"kubeconfig" json:"kubeconfig,omitempty"` // 为空时使用默认 kubeconfig
+	InCluster   bool      `mapstructure:"in_cluster" json:"in_cluster,omitempty"`
+	Description string    `mapstructure:"description" json:"description,omitempty"`
+	Source      string    `mapstructure:"-" json:"source"`
+	UpdatedAt   time.Time `mapstructure:"-" json:"updated_at,omitempty"`
+}
+
+// Registry 集群登记：配置文件中的集群只读，接口登记的集群持久化为 JSON 文件
+type Registry struct {
+	mu       sync.RWMutex
+	path     string
+	clusters map[string]*Cluster
+}
+
+var (
+	defaultRegistry *Registry
+	registryOnce    sync.Once
+)
+
+// Default 获取根据 clusters.registry 和 clusters.file 创建的全局集群登记
+func Default() *Registry {
+	registryOnce.Do(func() {
+		config := utils.GetConfig()
+		var configured []Cluster
+		if err := config.UnmarshalKey("clusters.registry", &configured); err != nil {
+			utils.Error("解析集群登记配置失败", zap.Error(err))
+		}
+		path := config.GetString("clusters.file")
+		if path == "" {
+			path = filepath.Join("data", "clusters.json")
+		}
+		registry, err := NewRegistry(configured, path)
+		if err != nil {
+			utils.Error("加载集群登记失败", zap.Error(err))
+			registry = &Registry{path: path, clusters: map[string]*Cluster{}}
+		}
+		defaultRegistry = registry
+	})
+	return defaultRegistry
+}
+
+// NewRegistry 创建集群登记，path 为空时接口登记的集群只保存在内存中
+func NewRegistry(configured []Cluster, path string) (*Registry, error) {
+	r := &Registry{path: path, clusters: map[string]*Cluster{}}
+	for i := range configured {
+		c := configured[i]
+		c.Source = SourceConfig
+		if err := r.validate(&c, ""); err != nil {
+			return nil, fmt.Errorf("clusters.registry 第 %d 项: %v", i+1, err)
+		}
+		r.clusters[c.Name] = &c
+	}
+	if path == "" {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored []*Cluster
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("解析集群登记文件失败: %v", err)
+	}
+	for _, c := range stored {
+		// 配置文件中同名的集群优先
+		if _, ok := r.clusters[c.Name]; ok {
+			continue
+		}
+		c.Source = SourceAPI
+		r.clusters[c.Name] = c
+	}
+	return r, nil
+}
+
+// List 按名称返回所有登记的集群
+func (r *Registry) List() []Cluster {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Cluster, 0, len(r.clusters))
+	for _, c := range r.clusters {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Get 按名称获取登记的集群
+func (r *Registry) Get(name string) (Cluster, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.clusters[name]
+	if !ok {
+		return Cluster{}, false
+	}
+	return *c, true
+}
+
+// Target 返回名称对应的 kubeconfig context 和 kubeconfig 文件，未登记的名称视为默认 kubeconfig 中的 context
+func (r *Registry) Target(name string) (string, string) {
+	if c, ok := r.Get(name); ok {
+		return c.Context, c.Kubeconfig
+	}
+	return name, ""
+}
+
+// InCluster 名称对应的集群是否通过集群内 ServiceAccount 访问
+func (r *Registry) InCluster(name string) bool {
+	c, ok := r.Get(name)
+	return ok && c.InCluster
+}
+
+// Aliases 返回别名到集群名称的映射，用于集群解析
+func (r *Registry) Aliases() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	aliases := map[string]string{}
+	for _, c := range r.clusters {
+		for _, alias := range c.Aliases {
+			aliases[alias] = c.Name
+		}
+	}
+	return aliases
+}
+
+// Create 登记新的集群
+func (r *Registry) Create(c Cluster) (Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.clusters[c.Name]; ok {
+		return Cluster{}, fmt.Errorf("集群 %s 已登记", c.Name)
+	}
+	return r.put(c, "")
+}
+
+// Update 修改接口登记的集群，名称不可修改
+func (r *Registry) Update(name string, c Cluster) (Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.clusters[name]
+	if !ok {
+		return Cluster{}, ErrNotFound
+	}
+	if existing.Source != SourceAPI {
+		return Cluster{}, ErrReadOnly
+	}
+	c.Name = name
+	return r.put(c, name)
+}
+
+// Delete 删除接口登记的集群
+func (r *Registry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	existing, ok := r.clusters[name]
+	if !ok {
+		return ErrNotFound
+	}
+	if existing.Source != SourceAPI {
+		return ErrReadOnly
+	}
+	delete(r.clusters, name)
+	if err := r.save(); err != nil {
+		r.clusters[name] = existing
+		return err
+	}
+	return nil
+}
+
+// SetRepoClusters 用配置仓库中的集群替换之前从仓库加载的集群，任一集群无效时不做任何修改
+// 与配置文件或接口登记的集群同名时返回错误，已有的登记优先
+func (r *Registry) SetRepoClusters(list []Cluster) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := &Registry{clusters: map[string]*Cluster{}}
+	for name, c := range r.clusters {
+		if c.Source != SourceRepo {
+			next.clusters[name] = c
+		}
+	}
+	now := time.Now()
+	for i := range list {
+		c := list[i]
+		c.Source = SourceRepo
+		c.UpdatedAt = now
+		if err := next.validate(&c, ""); err != nil {
+			return fmt.Errorf("第 %d 项: %v", i+1, err)
+		}
+		if existing, ok := next.clusters[c.Name]; ok {
+			if existing.Source == SourceRepo {
+				return fmt.Errorf("集群 %s 重复登记", c.Name)
+			}
+			return fmt.Errorf("集群 %s 已在%s中登记", c.Name, sourceName(existing.Source))
+		}
+		next.clusters[c.Name] = &c
+	}
+	r.clusters = next.clusters
+	return nil
+}
+
+func sourceName(source string) string {
+	if source == SourceConfig {
+		return "配置文件"
+	}
+	return "接口"
+}
+
+// put 校验并保存集群，调用方需持有写锁
+func (r *Registry) put(c Cluster, replacing string) (Cluster, error) {
+	c.Source = SourceAPI
+	c.UpdatedAt = time.Now()
+	if err := r.validate(&c, replacing); err != nil {
+		return Cluster{}, err
+	}
+	if c.Kubeconfig != "" {
+		if _, err := os.Stat(c.Kubeconfig); err != nil {
+			return Cluster{}, fmt.Errorf("kubeconfig 文件不可用: %v", err)
+		}
+	}
+
+	previous := r.clusters[c.Name]
+	r.clusters[c.Name] = &c
+	if err := r.save(); err != nil {
+		if previous != nil {
+			r.clusters[c.Name] = previous
+		} else {
+			delete(r.clusters, c.Name)
+		}
+		return Cluster{}, err
+	}
+	return c, nil
+}
+
+// validate 校验名称、context 以及别名是否与其他集群冲突，replacing 为正在修改的集群名称
+func (r *Registry) validate(c *Cluster, replacing string) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if !nameRe.MatchString(c.Name) {
+		return fmt.Errorf("集群名称 %q 无效，只能包含字母、数字、点、下划线和连字符", c.Name)
+	}
+	if c.InCluster {
+		if c.Kubeconfig != "" {
+			return fmt.Errorf("集群 %s 使用集群内 ServiceAccount 访问，不能同时指定 kubeconfig", c.Name)
+		}
+		c.Context = InClusterContext
+	}
+	if strings.TrimSpace(c.Context) == "" {
+		return fmt.Errorf("集群 %s 缺少 context", c.Name)
+	}
+	if c.Context == InClusterContext && !c.InCluster {
+		return fmt.Errorf("context %s 保留给集群内访问，请设置 in_cluster", InClusterContext)
+	}
+	for _, alias := range c.Aliases {
+		for name, other := range r.clusters {
+			if name == c.Name || name == replacing {
+				continue
+			}
+			if alias == name || contains(other.Aliases, alias) {
+				return fmt.Errorf("别名 %s 已被集群 %s 使用", alias, name)
+			}
+		}
+	}
+	return nil
+}
+
+// save 将接口登记的集群写入文件，调用方需持有写锁
+func (r *Registry) save() error {
+	if r.path == "" {
+		return nil
+	}
+	stored := make([]*Cluster, 0, len(r.clusters))
+	for _, c := range r.clusters {
+		if c.Source == SourceAPI {
+			stored = append(stored, c)
+		}
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Name < stored[j].Name })
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
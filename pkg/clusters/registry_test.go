@@ -0,0 +1,76 @@
+package clusters
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegistryCRUD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.json")
+	configured := []Cluster{{Name: "prod", Context: "arn:prod", Aliases: []string{"生产"}}}
+	r, err := NewRegistry(configured, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Create(Cluster{Name: "staging", Context: "ctx-staging", Namespace: "shop", Aliases: []string{"测试"}}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := r.Create(Cluster{Name: "dev", Context: "ctx-dev", Aliases: []string{"生产"}}); err == nil {
+		t.Error("expected alias conflict")
+	}
+	if _, err := r.Create(Cluster{Name: "bad name", Context: "x"}); err == nil {
+		t.Error("expected invalid name error")
+	}
+	if _, err := r.Update("prod", Cluster{Context: "other"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("update config cluster: got %v, want ErrReadOnly", err)
+	}
+	if err := r.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := r.Update("staging", Cluster{Context: "ctx-staging-2", Aliases: []string{"测试"}}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	// 重新加载后接口登记的集群仍在
+	reloaded, err := NewRegistry(configured, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reloaded.List()); got != 2 {
+		t.Fatalf("reloaded %d clusters, want 2", got)
+	}
+	if ctx, kubeconfig := reloaded.Target("staging"); ctx != "ctx-staging-2" || kubeconfig != "" {
+		t.Errorf("Target(staging) = %q, %q", ctx, kubeconfig)
+	}
+	if ctx, _ := reloaded.Target("unregistered"); ctx != "unregistered" {
+		t.Errorf("Target(unregistered) = %q", ctx)
+	}
+	if got := reloaded.Aliases()["测试"]; got != "staging" {
+		t.Errorf("alias 测试 -> %q, want staging", got)
+	}
+
+	if err := reloaded.Delete("staging"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := reloaded.Get("staging"); ok {
+		t.Error("staging still registered after delete")
+	}
+}
+
+func TestRegistryInCluster(t *testing.T) {
+	r, err := NewRegistry([]Cluster{{Name: "local", InCluster: true}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx, _ := r.Target("local"); ctx != InClusterContext || !r.InCluster("local") {
+		t.Errorf("Target(local) = %q, InCluster = %v", ctx, r.InCluster("local"))
+	}
+	if _, err := r.Create(Cluster{Name: "other", InCluster: true, Kubeconfig: "/tmp/config"}); err == nil {
+		t.Error("expected in_cluster with a kubeconfig to be rejected")
+	}
+	if _, err := r.Create(Cluster{Name: "other", Context: InClusterContext}); err == nil {
+		t.Error("expected the reserved in-cluster context to require in_cluster")
+	}
+}
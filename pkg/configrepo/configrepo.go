@@ -0,0 +1,224 @@
+// Package configrepo 从 Git 配置仓库加载集群登记和服务登记，定期拉取或由仓库 webhook 触发刷新，
+// 运维配置的变更经过仓库的评审流程，回滚只需在仓库中回退提交，无需登录服务器修改文件
+package configrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// 触发同步的来源
+const (
+	TriggerStartup  = "startup"
+	TriggerInterval = "interval"
+	TriggerWebhook  = "webhook"
+	TriggerManual   = "manual"
+)
+
+const (
+	defaultInterval     = 5 * time.Minute
+	defaultClustersFile = "clusters.yaml"
+	defaultServicesFile = "services.yaml"
+	syncTimeout         = 2 * time.Minute
+)
+
+// Status 配置仓库的同步状态
+type Status struct {
+	URL       string    `json:"url"`
+	Ref       string    `json:"ref,omitempty"`
+	Dir       string    `json:"dir"`
+	Commit    string    `json:"commit,omitempty"`    // 当前生效的提交
+	SyncedAt  time.Time `json:"synced_at,omitempty"` // 最近一次应用新提交的时间
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	Trigger   string    `json:"trigger,omitempty"` // 最近一次同步的触发来源
+	Clusters  int       `json:"clusters"`
+	Services  int       `json:"services"`
+	Error     string    `json:"error,omitempty"` // 最近一次同步失败的原因，失败时继续使用上一次成功加载的配置
+}
+
+// Repo 配置仓库
+type Repo struct {
+	url, ref, dir  string
+	clustersFile   string
+	servicesFile   string
+	applyClusters  func([]clusters.Cluster) error
+	applyServices  func([]knowledge.Service)
+	invalidPrompts func()
+
+	syncMu sync.Mutex // 串行执行同步
+	mu     sync.RWMutex
+	status Status
+}
+
+var (
+	defaultRepo *Repo
+	defaultOnce sync.Once
+)
+
+// Enabled 是否启用配置仓库，配置项 config_repo.enabled 和 config_repo.url
+func Enabled() bool {
+	config := utils.GetConfig()
+	return config.GetBool("config_repo.enabled") && config.GetString("config_repo.url") != ""
+}
+
+// Default 获取根据 config_repo 配置创建的全局配置仓库
+func Default() *Repo {
+	defaultOnce.Do(func() {
+		config := utils.GetConfig()
+		r := New(config.GetString("config_repo.url"), config.GetString("config_repo.ref"), config.GetString("config_repo.dir"))
+		if file := config.GetString("config_repo.files.clusters"); file != "" {
+			r.clustersFile = file
+		}
+		if file := config.GetString("config_repo.files.services"); file != "" {
+			r.servicesFile = file
+		}
+		defaultRepo = r
+	})
+	return defaultRepo
+}
+
+// New 创建配置仓库，加载的集群和服务分别写入全局集群登记和服务登记，dir 为空时使用系统临时目录下的 opsagent-config
+func New(url, ref, dir string) *Repo {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "opsagent-config")
+	}
+	return &Repo{
+		url:           url,
+		ref:           ref,
+		dir:           dir,
+		clustersFile:  defaultClustersFile,
+		servicesFile:  defaultServicesFile,
+		applyClusters: func(list []clusters.Cluster) error { return clusters.Default().SetRepoClusters(list) },
+		applyServices: knowledge.SetRepoServices,
+		// 提示的 path 来源可以指向仓库中的文件，提交变更后重新读取
+		invalidPrompts: func() { utils.GetPromptCache().Invalidate("") },
+		status:         Status{URL: url, Ref: ref, Dir: dir},
+	}
+}
+
+// Start 启动时同步一次，之后按 config_repo.interval 定期拉取，interval 为负数时只由 webhook 或手动触发
+func Start(ctx context.Context) {
+	r := Default()
+	if _, err := r.Sync(ctx, TriggerStartup); err != nil {
+		utils.Error("同步配置仓库失败", zap.String("url", r.url), zap.Error(err))
+	}
+
+	interval := defaultInterval
+	if utils.GetConfig().IsSet("config_repo.interval") {
+		interval = utils.GetConfig().GetDuration("config_repo.interval")
+	}
+	if interval <= 0 {
+		utils.Info("配置仓库已加载，未启用定期拉取", zap.String("url", r.url))
+		return
+	}
+	utils.Info("配置仓库定期拉取已启动",
+		zap.String("url", r.url),
+		zap.Duration("interval", interval),
+	)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Sync(ctx, TriggerInterval); err != nil {
+					utils.Warn("同步配置仓库失败", zap.String("url", r.url), zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// Sync 拉取仓库，提交变化时重新加载集群登记和服务登记并使提示缓存失效
+// 文件解析或校验失败时不应用任何变更，继续使用上一次成功加载的配置
+func (r *Repo) Sync(ctx context.Context, trigger string) (Status, error) {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	commit, err := utils.SyncGitRepo(ctx, r.url, r.ref, r.dir)
+	if err == nil && commit != r.Status().Commit {
+		err = r.apply(commit)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.CheckedAt = time.Now()
+	r.status.Trigger = trigger
+	r.status.Error = ""
+	if err != nil {
+		r.status.Error = err.Error()
+	}
+	return r.status, err
+}
+
+// apply 读取并应用仓库中的配置文件
+func (r *Repo) apply(commit string) error {
+	var clusterList []clusters.Cluster
+	if err := r.readFile(r.clustersFile, &clusterList); err != nil {
+		return err
+	}
+	var services []knowledge.Service
+	if err := r.readFile(r.servicesFile, &services); err != nil {
+		return err
+	}
+	if err := r.applyClusters(clusterList); err != nil {
+		return fmt.Errorf("%s: %v", r.clustersFile, err)
+	}
+	r.applyServices(services)
+	r.invalidPrompts()
+
+	r.mu.Lock()
+	previous := r.status.Commit
+	r.status.Commit = commit
+	r.status.SyncedAt = time.Now()
+	r.status.Clusters = len(clusterList)
+	r.status.Services = len(services)
+	r.mu.Unlock()
+
+	utils.Info("配置仓库已更新",
+		zap.String("url", r.url),
+		zap.String("previous", previous),
+		zap.String("commit", commit),
+		zap.Int("clusters", len(clusterList)),
+		zap.Int("services", len(services)),
+	)
+	return nil
+}
+
+// readFile 解析仓库中的 YAML 列表文件，文件不存在时视为空列表
+func (r *Repo) readFile(name string, out interface{}) error {
+	data, err := os.ReadFile(filepath.Join(r.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(data, out); err != nil {
+		return fmt.Errorf("解析 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// Status 返回配置仓库的同步状态
+func (r *Repo) Status() Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.status
+}
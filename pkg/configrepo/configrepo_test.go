@@ -0,0 +1,97 @@
+package configrepo
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+)
+
+func TestSync(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	origin := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", origin, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	commit := func(files map[string]string) {
+		t.Helper()
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(origin, name), []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", "update")
+	}
+	git("init", "-q")
+	commit(map[string]string{
+		"clusters.yaml": "- name: staging\n  context: ctx-staging\n  aliases: [测试]\n",
+		"services.yaml": "- name: order-api\n  namespace: order\n  aliases: [订单]\n",
+	})
+
+	registry, err := clusters.NewRegistry([]clusters.Cluster{{Name: "prod", Context: "ctx-prod"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var services []knowledge.Service
+	invalidated := 0
+	r := New(origin, "", filepath.Join(t.TempDir(), "work"))
+	r.applyClusters = registry.SetRepoClusters
+	r.applyServices = func(list []knowledge.Service) { services = list }
+	r.invalidPrompts = func() { invalidated++ }
+
+	status, err := r.Sync(context.Background(), TriggerManual)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if status.Commit == "" || status.Clusters != 1 || status.Services != 1 || invalidated != 1 {
+		t.Fatalf("status = %+v, invalidated %d", status, invalidated)
+	}
+	if c, ok := registry.Get("staging"); !ok || c.Source != clusters.SourceRepo {
+		t.Fatalf("staging = %+v, %v", c, ok)
+	}
+	if _, err := registry.Update("staging", clusters.Cluster{Context: "x"}); err != clusters.ErrReadOnly {
+		t.Errorf("update repo cluster: got %v, want ErrReadOnly", err)
+	}
+	if len(services) != 1 || services[0].Name != "order-api" {
+		t.Errorf("services = %+v", services)
+	}
+
+	// 未变化的提交不重新加载
+	if _, err := r.Sync(context.Background(), TriggerInterval); err != nil || invalidated != 1 {
+		t.Fatalf("resync: %v, invalidated %d", err, invalidated)
+	}
+
+	// 与配置文件中的集群同名时不应用，继续使用上一次的配置
+	previous := status.Commit
+	commit(map[string]string{"clusters.yaml": "- name: prod\n  context: other\n"})
+	status, err = r.Sync(context.Background(), TriggerWebhook)
+	if err == nil || status.Commit != previous || status.Error == "" {
+		t.Fatalf("conflicting commit applied: %+v, %v", status, err)
+	}
+	if _, ok := registry.Get("staging"); !ok {
+		t.Error("staging removed after failed sync")
+	}
+
+	// 修复后重新加载，仓库中删除的集群随之移除
+	commit(map[string]string{"clusters.yaml": "[]\n"})
+	if status, err = r.Sync(context.Background(), TriggerWebhook); err != nil || status.Clusters != 0 {
+		t.Fatalf("sync after fix: %+v, %v", status, err)
+	}
+	if _, ok := registry.Get("staging"); ok {
+		t.Error("staging still registered after removal from repo")
+	}
+	if c, _ := registry.Get("prod"); c.Context != "ctx-prod" {
+		t.Errorf("prod context = %q", c.Context)
+	}
+}
@@ -0,0 +1,599 @@
+// Package credentials 检查 kubeconfig 中各 context 的凭据有效期，临近过期时执行刷新钩子并发送告警，
+// 避免查询过程中因证书或令牌过期出现 Unauthorized
+package credentials
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// 凭据类型
+const (
+	KindCertificate = "certificate" // 客户端证书
+	KindToken       = "token"       // 静态令牌（JWT 或 EKS 预签名令牌）
+	KindExec        = "exec"        // exec 凭据插件，有效期取插件返回的 expirationTimestamp
+	KindUnknown     = "unknown"     // 无法判断有效期，例如 basic auth 或非 JWT 令牌
+)
+
+// 刷新方式
+const (
+	RotationCommand = "command"
+	RotationPlugin  = "plugin"
+)
+
+const (
+	defaultCheckInterval = 5 * time.Minute
+	defaultRefreshBefore = 15 * time.Minute
+	defaultWarnBefore    = 72 * time.Hour
+	defaultTimeout       = time.Minute
+	// alertInterval 同一 context 重复告警的最小间隔
+	alertInterval = 24 * time.Hour
+	// eksTokenTTL EKS 预签名令牌的有效期
+	eksTokenTTL = 15 * time.Minute
+)
+
+// Rotation context 的凭据刷新钩子，通过 clusters.credentials.rotations 配置，command 和 plugin 二选一
+type Rotation struct {
+	Context string `mapstructure:"context"`
+	// Command 刷新命令（云厂商 CLI/SDK），由 sh -c 执行，KUBECONFIG 指向凭据所在的文件，
+	// 例如 aws eks update-kubeconfig --name prod --alias prod
+	Command string `mapstructure:"command"`
+	// Plugin exec 凭据插件的命令和参数，返回的令牌或证书写入 kubeconfig，
+	// 例如 ["aws", "eks", "get-token", "--cluster-name", "prod"]
+	Plugin  []string      `mapstructure:"plugin"`
+	Timeout time.Duration `mapstructure:"timeout"` // 默认 1m
+}
+
+func (r Rotation) kind() string {
+	if len(r.Plugin) > 0 {
+		return RotationPlugin
+	}
+	return RotationCommand
+}
+
+// Status 一个 context 的凭据状态
+type Status struct {
+	Context    string     `json:"context"`
+	Cluster    string     `json:"cluster,omitempty"`    // 集群登记名称
+	Kubeconfig string     `json:"kubeconfig,omitempty"` // 凭据所在的 kubeconfig 文件
+	Kind       string     `json:"kind"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Rotation   string     `json:"rotation,omitempty"` // 配置的刷新方式：command 或 plugin
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	CheckedAt  time.Time  `json:"checked_at"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ExpiresIn 距离过期的时间，无法判断有效期时返回 false
+func (s Status) ExpiresIn(now time.Time) (time.Duration, bool) {
+	if s.ExpiresAt == nil {
+		return 0, false
+	}
+	return s.ExpiresAt.Sub(now), true
+}
+
+// target 需要检查的 context，kubeconfig 为空时使用默认 kubeconfig
+type target struct {
+	context    string
+	kubeconfig string
+	cluster    string
+}
+
+// Manager 定期检查凭据、执行刷新钩子并发送告警
+type Manager struct {
+	mu        sync.Mutex
+	statuses  map[string]*Status
+	alerted   map[string]time.Time
+	rotations map[string]Rotation
+	notifier  *notify.Notifier
+	now       func() time.Time
+}
+
+var (
+	defaultManager *Manager
+	defaultOnce    sync.Once
+)
+
+// Default 获取根据 clusters.credentials 配置创建的全局管理器
+func Default() *Manager {
+	defaultOnce.Do(func() {
+		var rotations []Rotation
+		if err := utils.GetConfig().UnmarshalKey("clusters.credentials.rotations", &rotations); err != nil {
+			utils.Error("解析凭据刷新配置失败", zap.Error(err))
+		}
+		defaultManager = NewManager(rotations, notify.Default())
+	})
+	return defaultManager
+}
+
+// NewManager 创建凭据管理器，notifier 为空时不发送告警
+func NewManager(rotations []Rotation, notifier *notify.Notifier) *Manager {
+	m := &Manager{
+		statuses:  map[string]*Status{},
+		alerted:   map[string]time.Time{},
+		rotations: map[string]Rotation{},
+		notifier:  notifier,
+		now:       time.Now,
+	}
+	for _, rotation := range rotations {
+		if rotation.Context != "" && (rotation.Command != "" || len(rotation.Plugin) > 0) {
+			m.rotations[rotation.Context] = rotation
+		}
+	}
+	return m
+}
+
+// Enabled 是否启用凭据检查，配置项 clusters.credentials.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("clusters.credentials.enabled")
+}
+
+// Start 启动后台检查：启动时检查一次，之后按 clusters.credentials.check_interval 定期检查
+func Start(ctx context.Context) {
+	m := Default()
+	interval := utils.GetConfig().GetDuration("clusters.credentials.check_interval")
+	if interval <= 0 {
+		interval = defaultCheckInterval
+	}
+	utils.Info("集群凭据检查已启动",
+		zap.Duration("interval", interval),
+		zap.Int("rotations", len(m.rotations)),
+	)
+	go func() {
+		m.Check(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Check 检查所有 context 的凭据：进入 refresh_before 时执行刷新钩子，进入 warn_before 时发送告警
+func (m *Manager) Check(ctx context.Context) []Status {
+	refreshBefore, warnBefore := thresholds()
+	var statuses []Status
+	for _, t := range targets() {
+		status := m.inspect(ctx, t)
+		if rotation, ok := m.rotations[t.context]; ok {
+			if in, known := status.ExpiresIn(m.now()); status.Error != "" || (known && in < refreshBefore) {
+				status = m.rotate(ctx, t, rotation)
+			}
+		}
+		m.alert(ctx, status, warnBefore)
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Rotate 立即执行 context 的刷新钩子，返回刷新后的凭据状态
+func (m *Manager) Rotate(ctx context.Context, kubeContext string) (Status, error) {
+	rotation, ok := m.rotations[kubeContext]
+	if !ok {
+		return Status{}, fmt.Errorf("context %s 未配置凭据刷新", kubeContext)
+	}
+	for _, t := range targets() {
+		if t.context == kubeContext {
+			status := m.rotate(ctx, t, rotation)
+			if status.Error != "" {
+				return status, errors.New(status.Error)
+			}
+			return status, nil
+		}
+	}
+	return Status{}, fmt.Errorf("kubeconfig 中不存在 context %s", kubeContext)
+}
+
+// Statuses 返回最近一次检查的结果，按 context 排序
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	statuses := make([]Status, 0, len(m.statuses))
+	for _, status := range m.statuses {
+		statuses = append(statuses, *status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Context < statuses[j].Context })
+	return statuses
+}
+
+// inspect 检查凭据并记录状态，保留上次刷新的时间
+func (m *Manager) inspect(ctx context.Context, t target) Status {
+	status := Inspect(ctx, t.context, t.kubeconfig)
+	status.Cluster = t.cluster
+	status.CheckedAt = m.now()
+	if rotation, ok := m.rotations[t.context]; ok {
+		status.Rotation = rotation.kind()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if previous, ok := m.statuses[t.context]; ok {
+		status.RotatedAt = previous.RotatedAt
+	}
+	m.statuses[t.context] = &status
+	return status
+}
+
+// rotate 执行刷新钩子并重新检查凭据
+func (m *Manager) rotate(ctx context.Context, t target, rotation Rotation) Status {
+	logger := utils.GetLogger().Named("credentials").With(zap.String("context", t.context))
+	timeout := rotation.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	rotateCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	if rotation.kind() == RotationPlugin {
+		err = rotateWithPlugin(rotateCtx, t, rotation.Plugin)
+	} else {
+		err = rotateWithCommand(rotateCtx, t, rotation.Command)
+	}
+	if err != nil {
+		logger.Warn("刷新集群凭据失败", zap.String("rotation", rotation.kind()), zap.Error(err))
+		utils.GetPerfStats().IncrCounter("kube_credentials_rotate_failed")
+		status := m.inspect(ctx, t)
+		status.Error = "刷新凭据失败: " + err.Error()
+		m.mu.Lock()
+		m.statuses[t.context] = &status
+		m.mu.Unlock()
+		return status
+	}
+
+	now := m.now()
+	m.mu.Lock()
+	if previous, ok := m.statuses[t.context]; ok {
+		previous.RotatedAt = &now
+	}
+	m.mu.Unlock()
+	status := m.inspect(ctx, t)
+	logger.Info("集群凭据已刷新",
+		zap.String("rotation", rotation.kind()),
+		zap.Timep("expiresAt", status.ExpiresAt),
+	)
+	utils.GetPerfStats().IncrCounter("kube_credentials_rotated")
+	return status
+}
+
+// alert 凭据即将过期、已过期或检查失败时发送告警，同一 context 每天最多告警一次
+func (m *Manager) alert(ctx context.Context, status Status, warnBefore time.Duration) {
+	now := m.now()
+	in, known := status.ExpiresIn(now)
+	var msg notify.Message
+	switch {
+	case status.Error != "":
+		msg = notify.Message{Title: "集群凭据异常", Level: notify.LevelCritical,
+			Text: fmt.Sprintf("context %s 的凭据检查失败：%s", status.Context, status.Error)}
+	case known && in <= 0:
+		msg = notify.Message{Title: "集群凭据已过期", Level: notify.LevelCritical,
+			Text: fmt.Sprintf("context %s 的%s已于 %s 过期，查询该集群会返回 Unauthorized", status.Context, kindName(status.Kind), status.ExpiresAt.Format(time.RFC3339))}
+	case known && in < warnBefore:
+		msg = notify.Message{Title: "集群凭据即将过期", Level: notify.LevelWarning,
+			Text: fmt.Sprintf("context %s 的%s将于 %s 过期（剩余 %s）", status.Context, kindName(status.Kind), status.ExpiresAt.Format(time.RFC3339), in.Round(time.Minute))}
+	default:
+		m.mu.Lock()
+		delete(m.alerted, status.Context)
+		m.mu.Unlock()
+		return
+	}
+	if status.Rotation == "" {
+		msg.Text += "；未配置 clusters.credentials.rotations，请手动更新 kubeconfig"
+	}
+
+	key := status.Context + "|" + msg.Title
+	m.mu.Lock()
+	if last, ok := m.alerted[key]; ok && now.Sub(last) < alertInterval {
+		m.mu.Unlock()
+		return
+	}
+	m.alerted[key] = now
+	m.mu.Unlock()
+
+	utils.Warn(msg.Title, zap.String("context", status.Context), zap.String("detail", msg.Text))
+	if m.notifier == nil || !m.notifier.Enabled() {
+		return
+	}
+	if err := m.notifier.Send(ctx, msg); err != nil {
+		utils.Warn("发送凭据告警失败", zap.String("context", status.Context), zap.Error(err))
+	}
+}
+
+func kindName(kind string) string {
+	switch kind {
+	case KindCertificate:
+		return "客户端证书"
+	case KindExec:
+		return "exec 插件凭据"
+	}
+	return "令牌"
+}
+
+// thresholds 返回 clusters.credentials.refresh_before 和 warn_before
+func thresholds() (refreshBefore, warnBefore time.Duration) {
+	config := utils.GetConfig()
+	refreshBefore = config.GetDuration("clusters.credentials.refresh_before")
+	if refreshBefore <= 0 {
+		refreshBefore = defaultRefreshBefore
+	}
+	warnBefore = config.GetDuration("clusters.credentials.warn_before")
+	if warnBefore <= 0 {
+		warnBefore = defaultWarnBefore
+	}
+	return refreshBefore, warnBefore
+}
+
+// targets 返回需要检查的 context：登记的集群和默认 kubeconfig 中的所有 context
+func targets() []target {
+	var list []target
+	seen := map[string]bool{}
+	for _, c := range clusters.Default().List() {
+		// 集群内 ServiceAccount 的令牌由 kubelet 自动轮换
+		if c.InCluster {
+			continue
+		}
+		list = append(list, target{context: c.Context, kubeconfig: c.Kubeconfig, cluster: c.Name})
+		if c.Kubeconfig == "" {
+			seen[c.Context] = true
+		}
+	}
+	if config, err := clientcmd.NewDefaultClientConfigLoadingRules().Load(); err == nil {
+		names := make([]string, 0, len(config.Contexts))
+		for name := range config.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if !seen[name] {
+				list = append(list, target{context: name})
+			}
+		}
+	}
+	return list
+}
+
+// loadContext 读取 context 的用户凭据及其所在的 kubeconfig 文件
+func loadContext(kubeContext, kubeconfig string) (*clientcmdapi.AuthInfo, string, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	config, err := rules.Load()
+	if err != nil {
+		return nil, "", "", err
+	}
+	c, ok := config.Contexts[kubeContext]
+	if !ok {
+		return nil, "", "", fmt.Errorf("kubeconfig 中不存在 context %s", kubeContext)
+	}
+	authInfo, ok := config.AuthInfos[c.AuthInfo]
+	if !ok {
+		return nil, "", "", fmt.Errorf("kubeconfig 中不存在用户 %s", c.AuthInfo)
+	}
+	return authInfo, c.AuthInfo, authInfo.LocationOfOrigin, nil
+}
+
+// Inspect 检查 context 的凭据类型和过期时间；exec 插件会被执行一次以获取 expirationTimestamp
+func Inspect(ctx context.Context, kubeContext, kubeconfig string) Status {
+	status := Status{Context: kubeContext, Kind: KindUnknown}
+	authInfo, _, location, err := loadContext(kubeContext, kubeconfig)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Kubeconfig = location
+
+	switch {
+	case len(authInfo.ClientCertificateData) > 0 || authInfo.ClientCertificate != "":
+		status.Kind = KindCertificate
+		data := authInfo.ClientCertificateData
+		if len(data) == 0 {
+			if data, err = os.ReadFile(authInfo.ClientCertificate); err != nil {
+				status.Error = err.Error()
+				return status
+			}
+		}
+		expiresAt, err := certificateExpiry(data)
+		if err != nil {
+			status.Error = err.Error()
+			return status
+		}
+		status.ExpiresAt = &expiresAt
+	case authInfo.Token != "" || authInfo.TokenFile != "":
+		token := authInfo.Token
+		if token == "" {
+			data, err := os.ReadFile(authInfo.TokenFile)
+			if err != nil {
+				status.Kind = KindToken
+				status.Error = err.Error()
+				return status
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if expiresAt, ok := tokenExpiry(token); ok {
+			status.Kind = KindToken
+			status.ExpiresAt = &expiresAt
+		}
+	case authInfo.Exec != nil:
+		status.Kind = KindExec
+		timeoutCtx, cancel := context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+		credential, err := runPlugin(timeoutCtx, authInfo.Exec.APIVersion, append([]string{authInfo.Exec.Command}, authInfo.Exec.Args...), execEnv(authInfo.Exec))
+		if err != nil {
+			status.Error = "exec 凭据插件执行失败: " + err.Error()
+			return status
+		}
+		status.ExpiresAt = credential.Status.ExpirationTimestamp
+	}
+	return status
+}
+
+// certificateExpiry 返回 PEM 证书的过期时间
+func certificateExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return time.Time{}, errors.New("无法解析客户端证书")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
+
+// tokenExpiry 解析 JWT 的 exp 或 EKS 预签名令牌（k8s-aws-v1.）的签名时间，不校验签名
+func tokenExpiry(token string) (time.Time, bool) {
+	if encoded, ok := strings.CutPrefix(token, "k8s-aws-v1."); ok {
+		data, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil {
+			return time.Time{}, false
+		}
+		presigned, err := url.Parse(string(data))
+		if err != nil {
+			return time.Time{}, false
+		}
+		signedAt, err := time.Parse("20060102T150405Z", presigned.Query().Get("X-Amz-Date"))
+		if err != nil {
+			return time.Time{}, false
+		}
+		return signedAt.Add(eksTokenTTL), true
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == "" {
+		return time.Time{}, false
+	}
+	exp, err := strconv.ParseInt(claims.Exp.String(), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(exp, 0), true
+}
+
+// execCredential exec 凭据插件的输出（client.authentication.k8s.io ExecCredential）
+type execCredential struct {
+	Status struct {
+		Token                 string     `json:"token"`
+		ClientCertificateData string     `json:"clientCertificateData"`
+		ClientKeyData         string     `json:"clientKeyData"`
+		ExpirationTimestamp   *time.Time `json:"expirationTimestamp"`
+	} `json:"status"`
+}
+
+func execEnv(config *clientcmdapi.ExecConfig) []string {
+	env := make([]string, 0, len(config.Env))
+	for _, e := range config.Env {
+		env = append(env, e.Name+"="+e.Value)
+	}
+	return env
+}
+
+// runPlugin 以非交互方式执行 exec 凭据插件并解析输出
+func runPlugin(ctx context.Context, apiVersion string, argv, env []string) (*execCredential, error) {
+	if len(argv) == 0 || argv[0] == "" {
+		return nil, errors.New("未配置插件命令")
+	}
+	if apiVersion == "" {
+		apiVersion = "client.authentication.k8s.io/v1"
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Env = append(cmd.Env, fmt.Sprintf(`KUBERNETES_EXEC_INFO={"apiVersion":%q,"kind":"ExecCredential","spec":{"interactive":false}}`, apiVersion))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	var credential execCredential
+	if err := json.Unmarshal(out, &credential); err != nil {
+		return nil, fmt.Errorf("解析插件输出失败: %v", err)
+	}
+	if credential.Status.Token == "" && credential.Status.ClientCertificateData == "" {
+		return nil, errors.New("插件未返回令牌或证书")
+	}
+	return &credential, nil
+}
+
+// rotateWithCommand 执行刷新命令，KUBECONFIG 指向凭据所在的文件
+func rotateWithCommand(ctx context.Context, t target, command string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = os.Environ()
+	if _, _, location, err := loadContext(t.context, t.kubeconfig); err == nil && location != "" {
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+location)
+	} else if t.kubeconfig != "" {
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+t.kubeconfig)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// rotateWithPlugin 执行 exec 凭据插件，将返回的令牌或证书写入 context 用户所在的 kubeconfig 文件
+func rotateWithPlugin(ctx context.Context, t target, plugin []string) error {
+	_, user, location, err := loadContext(t.context, t.kubeconfig)
+	if err != nil {
+		return err
+	}
+	if location == "" {
+		return errors.New("无法确定凭据所在的 kubeconfig 文件")
+	}
+	credential, err := runPlugin(ctx, "", plugin, nil)
+	if err != nil {
+		return err
+	}
+
+	config, err := clientcmd.LoadFromFile(location)
+	if err != nil {
+		return err
+	}
+	authInfo, ok := config.AuthInfos[user]
+	if !ok {
+		return fmt.Errorf("%s 中不存在用户 %s", location, user)
+	}
+	if credential.Status.Token != "" {
+		authInfo.Token, authInfo.TokenFile = credential.Status.Token, ""
+	}
+	if credential.Status.ClientCertificateData != "" {
+		authInfo.ClientCertificateData, authInfo.ClientCertificate = []byte(credential.Status.ClientCertificateData), ""
+		authInfo.ClientKeyData, authInfo.ClientKey = []byte(credential.Status.ClientKeyData), ""
+	}
+	return clientcmd.WriteToFile(*config, location)
+}
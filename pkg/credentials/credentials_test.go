@@ -0,0 +1,72 @@
+package credentials
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func jwt(exp time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix())))
+	return "eyJhbGciOiJub25lIn0." + payload + ".sig"
+}
+
+func TestTokenExpiry(t *testing.T) {
+	exp := time.Unix(1893456000, 0)
+	if got, ok := tokenExpiry(jwt(exp)); !ok || !got.Equal(exp) {
+		t.Errorf("tokenExpiry(jwt) = %v, %v; want %v", got, ok, exp)
+	}
+	presigned := "https://sts.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Date=20260101T100000Z&X-Amz-Expires=60"
+	eks := "k8s-aws-v1." + base64.RawURLEncoding.EncodeToString([]byte(presigned))
+	if got, ok := tokenExpiry(eks); !ok || !got.Equal(time.Date(2026, 1, 1, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("tokenExpiry(eks) = %v, %v", got, ok)
+	}
+	if _, ok := tokenExpiry("static-token"); ok {
+		t.Error("static token should have no expiry")
+	}
+}
+
+func TestCheckRotatesWithPlugin(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://127.0.0.1:6443
+users:
+- name: prod-admin
+  user:
+    token: %s
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: prod-admin
+current-context: prod
+`, jwt(time.Now().Add(5*time.Minute)))), 0600)
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	fresh := jwt(time.Now().Add(time.Hour).Truncate(time.Second))
+	output := fmt.Sprintf(`{"apiVersion":"client.authentication.k8s.io/v1","kind":"ExecCredential","status":{"token":%q}}`, fresh)
+	m := NewManager([]Rotation{{Context: "prod", Plugin: []string{"sh", "-c", "echo '" + output + "'"}}}, nil)
+
+	statuses := m.Check(context.Background())
+	if len(statuses) != 1 {
+		t.Fatalf("Check() returned %d statuses, want 1", len(statuses))
+	}
+	status := statuses[0]
+	if status.Error != "" || status.RotatedAt == nil || status.ExpiresAt == nil || time.Until(*status.ExpiresAt) < 50*time.Minute {
+		t.Errorf("status after rotation = %+v", status)
+	}
+	config, err := clientcmd.LoadFromFile(kubeconfig)
+	if err != nil || config.AuthInfos["prod-admin"].Token != fresh {
+		t.Errorf("kubeconfig token not replaced: %v", err)
+	}
+}
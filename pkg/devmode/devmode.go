@@ -0,0 +1,126 @@
+// Package devmode 本地开发模式：kubectl、trivy 返回固定的模拟数据，LLM 使用确定性的模拟服务商，
+// 集群登记为模拟集群，前端和提示开发不需要集群和 LLM 即可运行完整的服务
+package devmode
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+//go:embed fixtures
+var fixtures embed.FS
+
+var enabled atomic.Bool
+
+// Clusters 开发模式登记的模拟集群，所有集群返回相同的模拟数据
+var Clusters = []clusters.Cluster{
+	{Name: "dev-cn", Context: "dev-cn", Namespace: "shop", Aliases: []string{"国内", "cn"}, Description: "开发模式模拟集群"},
+	{Name: "dev-eu", Context: "dev-eu", Namespace: "shop", Aliases: []string{"欧洲", "eu"}, Description: "开发模式模拟集群"},
+}
+
+var (
+	clientset     k8s.Interface
+	clientsetErr  error
+	clientsetOnce sync.Once
+)
+
+// Enabled 是否处于开发模式
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Enable 进入开发模式，需要在处理请求、初始化集群登记之前调用
+//   - kubectl、trivy 工具替换为返回 fixtures 中模拟数据的实现，不执行任何命令
+//   - 所有 LLM 请求使用模拟服务商，不需要 API Key
+//   - 集群登记替换为 Clusters，直接调用 API 的工具（rollout、quotacheck、nodepools）读取 fixtures/cluster.yaml
+func Enable() error {
+	for _, spec := range []tools.ToolSpec{
+		{Name: "kubectl", Run: mockKubectl},
+		{Name: "trivy", Run: mockTrivy},
+	} {
+		if err := replaceTool(spec); err != nil {
+			return err
+		}
+	}
+
+	config := utils.GetConfig()
+	// 与配置文件中的 clusters.registry 格式相同，配置校验和集群登记按配置解析
+	registry := make([]interface{}, 0, len(Clusters))
+	for _, c := range Clusters {
+		registry = append(registry, map[string]interface{}{
+			"name":        c.Name,
+			"context":     c.Context,
+			"namespace":   c.Namespace,
+			"aliases":     c.Aliases,
+			"description": c.Description,
+		})
+	}
+	config.Set("clusters.registry", registry)
+	config.Set("clusters.file", "")
+	llms.UseMockProvider()
+	kubernetes.SetClientsetFunc(func(string) (k8s.Interface, error) {
+		clientsetOnce.Do(func() {
+			clientset, clientsetErr = fakeClientset()
+		})
+		return clientset, clientsetErr
+	})
+	enabled.Store(true)
+	return nil
+}
+
+// replaceTool 替换已注册工具的实现，保留说明、超时和幂等性等声明
+func replaceTool(mock tools.ToolSpec) error {
+	spec, ok := tools.Registry.Get(mock.Name)
+	if !ok {
+		return fmt.Errorf("工具 %s 未注册", mock.Name)
+	}
+	spec.Run = mock.Run
+	tools.Registry.Unregister(spec.Name)
+	return tools.Registry.Register(spec)
+}
+
+// fakeClientset 创建包含 fixtures/cluster.yaml 中对象的模拟 clientset
+func fakeClientset() (k8s.Interface, error) {
+	data, err := fixtures.ReadFile("fixtures/cluster.yaml")
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	decoder := scheme.Codecs.UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return nil, fmt.Errorf("解析模拟集群对象失败: %v", err)
+		}
+		objects = append(objects, obj)
+	}
+	return fake.NewSimpleClientset(objects...), nil
+}
@@ -0,0 +1,70 @@
+package devmode
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMockKubectl(t *testing.T) {
+	ctx := context.Background()
+
+	out, err := mockKubectl(ctx, "kubectl get pods -n shop --context=dev-eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out, "\n")
+	if !strings.HasPrefix(lines[0], "NAME ") || len(lines) != 6 {
+		t.Errorf("expected shop pods without NAMESPACE column, got:\n%s", out)
+	}
+
+	out, _ = mockKubectl(ctx, "kubectl get deploy/payment-api -n shop -o wide")
+	if !strings.Contains(out, "payment-api:v2.4.1") || strings.Contains(out, "gateway") {
+		t.Errorf("expected only payment-api, got:\n%s", out)
+	}
+
+	out, _ = mockKubectl(ctx, "kubectl get pods -A | grep CrashLoopBackOff")
+	if strings.Count(out, "\n") != 0 || !strings.Contains(out, "payment-api-6b8d7f9c4-t5wnh") {
+		t.Errorf("unexpected grep output:\n%s", out)
+	}
+
+	out, _ = mockKubectl(ctx, "kubectl get pods")
+	if out != "No resources found in default namespace." {
+		t.Errorf("unexpected default namespace output: %q", out)
+	}
+
+	out, err = mockKubectl(ctx, "kubectl delete pod payment-api-6b8d7f9c4-t5wnh -n shop")
+	if err != nil || !strings.Contains(out, "不执行变更命令") {
+		t.Errorf("expected mutating command to be ignored, got %q, %v", out, err)
+	}
+
+	if _, err := mockKubectl(ctx, "kubectl get ingresses -A"); err == nil {
+		t.Error("expected error for resource without fixture")
+	}
+}
+
+func TestFakeClientset(t *testing.T) {
+	client, err := fakeClientset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil || len(nodes.Items) != 3 {
+		t.Fatalf("nodes = %v, %v", nodes, err)
+	}
+
+	history, err := kubernetes.RevisionHistory(ctx, client, "shop", "payment-api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history.Revisions) != 2 || history.Revisions[0].Revision != 2 {
+		t.Fatalf("unexpected revisions: %+v", history.Revisions)
+	}
+	if !strings.Contains(history.Format(5), "FEATURE_REFUND_V2") {
+		t.Errorf("expected env change in history:\n%s", history.Format(5))
+	}
+}
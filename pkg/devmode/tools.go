@@ -0,0 +1,161 @@
+package devmode
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// contextArgRe 匹配 kubectl 命令中的 --context 参数，模拟集群的数据都相同
+var contextArgRe = regexp.MustCompile(`--context[=\s]+'?"?[^'"\s]+'?"?`)
+
+// resourceAliases kubectl 资源简称和单数形式对应的模拟数据名称
+var resourceAliases = map[string]string{
+	"po": "pods", "pod": "pods",
+	"deploy": "deployments", "deployment": "deployments", "deployments.apps": "deployments",
+	"no": "nodes", "node": "nodes",
+	"svc": "services", "service": "services",
+	"ev": "events", "event": "events",
+	"ns": "namespaces", "namespace": "namespaces",
+}
+
+// readOnlyVerbs 模拟数据覆盖的 kubectl 子命令，其他子命令视为变更，不会执行
+var readOnlyVerbs = map[string]bool{"get": true, "describe": true, "top": true, "logs": true}
+
+// mockKubectl 按子命令和资源类型返回 fixtures/kubectl 中的模拟输出，
+// 支持 -n/-A 过滤命名空间、按名称过滤以及管道中的 grep
+func mockKubectl(ctx context.Context, command string) (string, error) {
+	command = contextArgRe.ReplaceAllString(command, "")
+	var grep string
+	if before, after, ok := strings.Cut(command, "|"); ok {
+		command = before
+		if fields := strings.Fields(after); len(fields) >= 2 && fields[0] == "grep" {
+			grep = strings.Trim(fields[len(fields)-1], `'"`)
+		}
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(strings.TrimSpace(command), "kubectl"))
+	var args []string
+	namespace, allNamespaces := "default", false
+	for i := 0; i < len(fields); i++ {
+		switch f := fields[i]; {
+		case f == "-A" || f == "--all-namespaces":
+			allNamespaces = true
+		case (f == "-n" || f == "--namespace") && i+1 < len(fields):
+			namespace = fields[i+1]
+			i++
+		case strings.HasPrefix(f, "--namespace="):
+			namespace = strings.TrimPrefix(f, "--namespace=")
+		case (f == "-o" || f == "-l" || f == "-c" || f == "--tail") && i+1 < len(fields):
+			i++
+		case strings.HasPrefix(f, "-"):
+		default:
+			args = append(args, f)
+		}
+	}
+	if len(args) == 0 {
+		err := fmt.Errorf("开发模式：无法识别的 kubectl 命令 %q", command)
+		return err.Error(), err
+	}
+	verb := args[0]
+	if !readOnlyVerbs[verb] {
+		return fmt.Sprintf("开发模式不执行变更命令，已忽略：kubectl %s", strings.Join(fields, " ")), nil
+	}
+
+	var resource, name string
+	if verb != "logs" && len(args) > 1 {
+		resource, name, _ = strings.Cut(args[1], "/")
+		if alias, ok := resourceAliases[resource]; ok {
+			resource = alias
+		}
+		if name == "" && len(args) > 2 {
+			name = args[2]
+		}
+	}
+	file := verb
+	if resource != "" {
+		file += "_" + resource
+	}
+	data, err := fixtures.ReadFile("fixtures/kubectl/" + file + ".txt")
+	if err != nil {
+		err := fmt.Errorf("开发模式没有 kubectl %s %s 的模拟数据，可用的命令：%s", verb, resource, strings.Join(availableKubectl(), ", "))
+		return err.Error(), err
+	}
+	if verb == "logs" {
+		return string(data), nil
+	}
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if strings.HasPrefix(lines[0], "NAMESPACE") && !allNamespaces {
+		lines = filterNamespace(lines, namespace)
+		if len(lines) == 1 {
+			return fmt.Sprintf("No resources found in %s namespace.", namespace), nil
+		}
+	}
+	if name != "" {
+		lines = filterRows(lines, func(row string) bool { return strings.Contains(row, name) })
+		if len(lines) == 1 {
+			return fmt.Sprintf("Error from server (NotFound): %s %q not found", resource, name), nil
+		}
+	}
+	if grep != "" {
+		// grep 不保留表头
+		lines = filterRows(lines, func(row string) bool { return strings.Contains(row, grep) })[1:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// filterNamespace 保留命名空间匹配的行并去掉 NAMESPACE 列，与不带 -A 的 kubectl 输出一致
+func filterNamespace(lines []string, namespace string) []string {
+	header := lines[0]
+	offset := len("NAMESPACE")
+	for offset < len(header) && header[offset] == ' ' {
+		offset++
+	}
+	result := []string{header[offset:]}
+	for _, row := range lines[1:] {
+		if fields := strings.Fields(row); len(fields) > 0 && fields[0] == namespace && len(row) > offset {
+			result = append(result, row[offset:])
+		}
+	}
+	return result
+}
+
+// filterRows 保留表头和满足条件的行
+func filterRows(lines []string, keep func(string) bool) []string {
+	result := lines[:1:1]
+	for _, row := range lines[1:] {
+		if keep(row) {
+			result = append(result, row)
+		}
+	}
+	return result
+}
+
+// availableKubectl 列出有模拟数据的 kubectl 命令
+func availableKubectl() []string {
+	entries, _ := fs.ReadDir(fixtures, "fixtures/kubectl")
+	commands := make([]string, 0, len(entries))
+	for _, e := range entries {
+		commands = append(commands, strings.ReplaceAll(strings.TrimSuffix(e.Name(), ".txt"), "_", " "))
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// mockTrivy 返回固定的漏洞扫描结果
+func mockTrivy(ctx context.Context, image string) (string, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		err := fmt.Errorf("请提供镜像名称")
+		return err.Error(), err
+	}
+	data, err := fixtures.ReadFile("fixtures/trivy.txt")
+	if err != nil {
+		return err.Error(), err
+	}
+	return strings.ReplaceAll(string(data), "{{IMAGE}}", image), nil
+}
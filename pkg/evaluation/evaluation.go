@@ -0,0 +1,158 @@
+// Package evaluation 对比同一问题在两个模型上的执行结果（工具选择、迭代次数、token 用量和回答），
+// 为更换模型提供依据
+package evaluation
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 执行结果状态
+const (
+	StatusSuccess = "success"
+	StatusError   = "error"
+)
+
+// ToolCall 执行中的一次工具调用
+type ToolCall struct {
+	Name  string `json:"name"`
+	Input string `json:"input"`
+}
+
+// Run 一个模型的执行结果，完整的工具输出见 InteractionID 对应的审计记录
+type Run struct {
+	Provider         string     `json:"provider,omitempty"`
+	Model            string     `json:"model"`
+	InteractionID    string     `json:"interaction_id"`
+	Status           string     `json:"status"`
+	Answer           string     `json:"answer,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	ErrorCode        string     `json:"error_code,omitempty"`
+	ToolCalls        []ToolCall `json:"tool_calls"`
+	Iterations       int        `json:"iterations"` // LLM 调用次数
+	PromptTokens     int        `json:"prompt_tokens"`
+	CompletionTokens int        `json:"completion_tokens"`
+	DurationMs       int64      `json:"duration_ms"`
+}
+
+// Comparison 候选模型相对基准模型的差异，差值均为候选减基准
+type Comparison struct {
+	BothSucceeded      bool     `json:"both_succeeded"`
+	SameToolSequence   bool     `json:"same_tool_sequence"` // 工具及输入的调用顺序完全一致
+	CommonTools        []string `json:"common_tools"`
+	BaselineOnlyTools  []string `json:"baseline_only_tools"`
+	CandidateOnlyTools []string `json:"candidate_only_tools"`
+	IterationDelta     int      `json:"iteration_delta"`
+	ToolCallDelta      int      `json:"tool_call_delta"`
+	TokenDelta         int      `json:"token_delta"`
+	DurationDeltaMs    int64    `json:"duration_delta_ms"`
+	// AnswerSimilarity 两个回答的词语重合度（Jaccard，0-1），中文按单字计，只作为粗略参考
+	AnswerSimilarity float64 `json:"answer_similarity"`
+}
+
+// Result 一次评测的完整结果
+type Result struct {
+	Baseline   Run        `json:"baseline"`
+	Candidate  Run        `json:"candidate"`
+	Comparison Comparison `json:"comparison"`
+}
+
+// Compare 对比基准模型和候选模型的执行结果
+func Compare(baseline, candidate Run) Comparison {
+	c := Comparison{
+		BothSucceeded:    baseline.Status == StatusSuccess && candidate.Status == StatusSuccess,
+		SameToolSequence: sameSequence(baseline.ToolCalls, candidate.ToolCalls),
+		IterationDelta:   candidate.Iterations - baseline.Iterations,
+		ToolCallDelta:    len(candidate.ToolCalls) - len(baseline.ToolCalls),
+		TokenDelta:       candidate.PromptTokens + candidate.CompletionTokens - baseline.PromptTokens - baseline.CompletionTokens,
+		DurationDeltaMs:  candidate.DurationMs - baseline.DurationMs,
+		AnswerSimilarity: similarity(baseline.Answer, candidate.Answer),
+	}
+
+	baselineTools, candidateTools := toolNames(baseline.ToolCalls), toolNames(candidate.ToolCalls)
+	c.CommonTools, c.BaselineOnlyTools, c.CandidateOnlyTools = []string{}, []string{}, []string{}
+	for _, name := range sortedKeys(baselineTools) {
+		if candidateTools[name] {
+			c.CommonTools = append(c.CommonTools, name)
+		} else {
+			c.BaselineOnlyTools = append(c.BaselineOnlyTools, name)
+		}
+	}
+	for _, name := range sortedKeys(candidateTools) {
+		if !baselineTools[name] {
+			c.CandidateOnlyTools = append(c.CandidateOnlyTools, name)
+		}
+	}
+	return c
+}
+
+func sameSequence(a, b []ToolCall) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || strings.TrimSpace(a[i].Input) != strings.TrimSpace(b[i].Input) {
+			return false
+		}
+	}
+	return true
+}
+
+func toolNames(calls []ToolCall) map[string]bool {
+	names := map[string]bool{}
+	for _, call := range calls {
+		names[call.Name] = true
+	}
+	return names
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// similarity 计算两段文本词语集合的 Jaccard 系数，两段都为空时返回 1
+func similarity(a, b string) float64 {
+	termsA, termsB := terms(a), terms(b)
+	if len(termsA) == 0 && len(termsB) == 0 {
+		return 1
+	}
+	common := 0
+	for term := range termsA {
+		if termsB[term] {
+			common++
+		}
+	}
+	union := len(termsA) + len(termsB) - common
+	return float64(int(float64(common)/float64(union)*1000+0.5)) / 1000
+}
+
+// terms 将文本拆分为小写的英文单词、数字和单个汉字
+func terms(s string) map[string]bool {
+	set := map[string]bool{}
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			set[string(word)] = true
+			word = word[:0]
+		}
+	}
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			set[string(r)] = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.':
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return set
+}
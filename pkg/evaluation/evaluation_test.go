@@ -0,0 +1,50 @@
+package evaluation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	baseline := Run{
+		Model:  "gpt-4o",
+		Status: StatusSuccess,
+		Answer: "payment-api 有 3 个 Pod 处于 Running 状态",
+		ToolCalls: []ToolCall{
+			{Name: "kubectl", Input: "get pods -n shop"},
+			{Name: "jq", Input: ".items[].status.phase"},
+		},
+		Iterations: 3, PromptTokens: 1000, CompletionTokens: 200, DurationMs: 4000,
+	}
+	candidate := Run{
+		Model:      "qwen-max",
+		Status:     StatusSuccess,
+		Answer:     "payment-api 有 3 个 Pod 正在 Running",
+		ToolCalls:  []ToolCall{{Name: "kubectl", Input: "get pods -n shop"}, {Name: "trivy", Input: "image nginx"}},
+		Iterations: 2, PromptTokens: 800, CompletionTokens: 100, DurationMs: 2500,
+	}
+
+	got := Compare(baseline, candidate)
+	if !got.BothSucceeded || got.SameToolSequence {
+		t.Errorf("BothSucceeded = %v, SameToolSequence = %v", got.BothSucceeded, got.SameToolSequence)
+	}
+	if !reflect.DeepEqual(got.CommonTools, []string{"kubectl"}) ||
+		!reflect.DeepEqual(got.BaselineOnlyTools, []string{"jq"}) ||
+		!reflect.DeepEqual(got.CandidateOnlyTools, []string{"trivy"}) {
+		t.Errorf("tools = %v / %v / %v", got.CommonTools, got.BaselineOnlyTools, got.CandidateOnlyTools)
+	}
+	if got.IterationDelta != -1 || got.ToolCallDelta != 0 || got.TokenDelta != -300 || got.DurationDeltaMs != -1500 {
+		t.Errorf("deltas = %+v", got)
+	}
+	if got.AnswerSimilarity < 0.4 || got.AnswerSimilarity >= 1 {
+		t.Errorf("AnswerSimilarity = %v", got.AnswerSimilarity)
+	}
+
+	if same := Compare(baseline, baseline); !same.SameToolSequence || same.AnswerSimilarity != 1 {
+		t.Errorf("Compare(baseline, baseline) = %+v", same)
+	}
+	candidate.Status = StatusError
+	if got := Compare(baseline, candidate); got.BothSucceeded {
+		t.Error("BothSucceeded with failed candidate")
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+	"github.com/myysophia/OpsAgent/pkg/prompts"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// aliasMiningDays 默认挖掘最近多少天的审计记录
+	aliasMiningDays = 30
+	// aliasMiningMaxInteractions 单次挖掘最多读取的交互数量
+	aliasMiningMaxInteractions = 5000
+	// aliasMiningPageSize 分页读取审计记录的每页数量
+	aliasMiningPageSize = 200
+)
+
+// AddServiceAliasRequest 添加服务别名请求结构
+type AddServiceAliasRequest struct {
+	Alias       string `json:"alias" binding:"required"`
+	Service     string `json:"service" binding:"required"`
+	Kind        string `json:"kind"` // 工作负载类型，例如 deployment、statefulset
+	Namespace   string `json:"namespace"`
+	Cluster     string `json:"cluster"`
+	Description string `json:"description"`
+}
+
+// UpdateServiceAliasRequest 修改服务别名请求结构，alias 不为空时重命名别名
+type UpdateServiceAliasRequest struct {
+	Alias       string `json:"alias"`
+	Service     string `json:"service" binding:"required"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Cluster     string `json:"cluster"`
+	Description string `json:"description"`
+}
+
+// ListServiceAliases 列出已登记的服务别名
+func ListServiceAliases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"aliases": knowledge.Default().List(),
+		"status":  "success",
+	})
+}
+
+// AddServiceAlias 管理员将别名添加到服务登记，下一次请求的系统提示即可使用
+func AddServiceAlias(c *gin.Context) {
+	var req AddServiceAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias := knowledge.Alias{
+		Alias:       req.Alias,
+		Service:     req.Service,
+		Kind:        req.Kind,
+		Namespace:   req.Namespace,
+		Cluster:     req.Cluster,
+		Description: req.Description,
+		CreatedBy:   c.GetString("username"),
+	}
+	if err := knowledge.Default().Add(alias); err != nil {
+		utils.Warn("添加服务别名失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	utils.Info("已添加服务别名",
+		zap.String("alias", req.Alias),
+		zap.String("service", req.Service),
+		zap.String("admin", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"alias":  alias,
+		"status": "success",
+	})
+}
+
+// UpdateServiceAlias 修改服务别名指向的服务、命名空间或说明
+func UpdateServiceAlias(c *gin.Context) {
+	var req UpdateServiceAliasRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := knowledge.Default().Update(c.Param("alias"), knowledge.Alias{
+		Alias:       req.Alias,
+		Service:     req.Service,
+		Kind:        req.Kind,
+		Namespace:   req.Namespace,
+		Cluster:     req.Cluster,
+		Description: req.Description,
+	})
+	if errors.Is(err, knowledge.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return
+	}
+	if err != nil {
+		utils.Warn("修改服务别名失败", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	utils.Info("已修改服务别名",
+		zap.String("alias", c.Param("alias")),
+		zap.String("service", alias.Service),
+		zap.String("admin", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"alias":  alias,
+		"status": "success",
+	})
+}
+
+// DeleteServiceAlias 删除服务别名
+func DeleteServiceAlias(c *gin.Context) {
+	err := knowledge.Default().Delete(c.Param("alias"))
+	if errors.Is(err, knowledge.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alias not found"})
+		return
+	}
+	if err != nil {
+		utils.Warn("删除服务别名失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	utils.Info("已删除服务别名",
+		zap.String("alias", c.Param("alias")),
+		zap.String("admin", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// SuggestServiceAliases 从审计记录中挖掘服务别名建议
+// 参数 days 指定挖掘的天数（默认 30，最多 90），min_occurrences 指定最少出现次数
+func SuggestServiceAliases(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(aliasMiningDays)))
+	if err != nil || days <= 0 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days 应为 1-90 之间的整数"})
+		return
+	}
+	minOccurrences := prompts.AliasMinOccurrences()
+	if value := c.Query("min_occurrences"); value != "" {
+		if minOccurrences, err = strconv.Atoi(value); err != nil || minOccurrences <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_occurrences 应为正整数"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now()
+	q := audit.Query{
+		Filters: map[string]string{"status": audit.StatusSuccess},
+		Since:   now.Add(-time.Duration(days) * 24 * time.Hour),
+		Until:   now,
+		Desc:    true,
+		Limit:   aliasMiningPageSize,
+	}
+	var interactions []audit.Interaction
+	for len(interactions) < aliasMiningMaxInteractions {
+		page, next, err := store.ListInteractions(ctx, q)
+		if err != nil {
+			utils.Warn("读取审计记录失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ids := make([]string, len(page))
+		for i := range page {
+			ids[i] = page[i].ID
+		}
+		calls, err := store.ToolCalls(ctx, ids)
+		if err != nil {
+			utils.Warn("读取工具调用记录失败", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range page {
+			page[i].ToolCalls = calls[page[i].ID]
+		}
+		interactions = append(interactions, page...)
+
+		if next == "" {
+			break
+		}
+		q.Cursor = next
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"suggestions":  prompts.SuggestAliases(interactions, knowledge.Services(), minOccurrences),
+		"interactions": len(interactions),
+		"status":       "success",
+	})
+}
+
+// resolveServiceAliases 解析问题中对应多个服务的别名：picks 中指定了选择的使用该服务，
+// 否则置信度达到 prompts.service_confidence_threshold 时自动选择得分最高的服务，其余的返回由用户选择
+// 已确定的服务以说明的形式加入系统提示，要求助手只查询这些服务
+func resolveServiceAliases(logger *zap.Logger, question, cluster string, picks map[string]string) (string, []knowledge.ServiceResolution, error) {
+	resolutions := knowledge.ResolveServices(knowledge.Services(), question, cluster, knowledge.ServiceConfidenceThreshold())
+	if len(resolutions) == 0 {
+		return "", nil, nil
+	}
+
+	var (
+		lines   []string
+		pending []knowledge.ServiceResolution
+	)
+	for _, resolution := range resolutions {
+		chosen := resolution.Candidates[0]
+		if ref, ok := lookupPick(picks, resolution.Alias); ok {
+			if chosen, ok = resolution.Pick(ref); !ok {
+				return "", nil, fmt.Errorf("%q 不是 %q 的候选服务", ref, resolution.Alias)
+			}
+		} else if resolution.NeedsConfirmation {
+			logger.Info("服务别名对应多个服务，等待用户选择",
+				zap.String("alias", resolution.Alias),
+				zap.String("candidate", resolution.Candidate),
+				zap.Float64("confidence", resolution.Confidence),
+			)
+			pending = append(pending, resolution)
+			continue
+		}
+		logger.Info("已确定服务别名对应的服务",
+			zap.String("alias", resolution.Alias),
+			zap.String("service", chosen.Ref()),
+			zap.Float64("confidence", resolution.Confidence),
+		)
+		kind := chosen.Kind
+		if kind == "" {
+			kind = "deployment"
+		}
+		line := fmt.Sprintf("- %q 指 %s %s", resolution.Alias, kind, chosen.Name)
+		if chosen.Namespace != "" {
+			line += "，命名空间 " + chosen.Namespace
+		}
+		if chosen.Cluster != "" {
+			line += "，集群 " + chosen.Cluster
+		}
+		lines = append(lines, line)
+	}
+	if len(pending) > 0 {
+		return "", pending, nil
+	}
+	return "用户问题中提到的以下服务已确定，只查询这些服务，不要按名称模糊查找其他同名服务，也不要把其他服务的结果混入回答：\n" +
+		strings.Join(lines, "\n"), nil, nil
+}
+
+// lookupPick 按别名查找用户的选择，别名不区分大小写
+func lookupPick(picks map[string]string, alias string) (string, bool) {
+	for name, ref := range picks {
+		if strings.EqualFold(strings.TrimSpace(name), alias) {
+			return ref, true
+		}
+	}
+	return "", false
+}
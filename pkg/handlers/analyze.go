@@ -4,6 +4,11 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
 )
 
 // AnalyzeRequest 分析请求结构
@@ -22,12 +27,29 @@ func Analyze(c *gin.Context) {
 	model := c.DefaultQuery("model", "gpt-4o")
 	cluster := c.DefaultQuery("cluster", "default")
 
+	if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), "", model, nil); err != nil {
+		utils.Warn("模型不在租户允许列表中",
+			zap.String("tenant", tenantOf(c)),
+			zap.String("model", model),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	dataAt := time.Now()
 	// TODO: 实现实际的分析逻辑
 	result := fmt.Sprintf("Analyzing resource %s using model %s on cluster %s",
 		req.Resource, model, cluster)
 
-	c.JSON(http.StatusOK, gin.H{
+	responseData := gin.H{
 		"message": result,
 		"status":  "success",
-	})
+	}
+	if answerFooterEnabled() {
+		provenance := newProvenance(cluster, model, "", dataAt, false)
+		responseData["message"] = appendAnswerFooter(result, provenance)
+		responseData["provenance"] = provenance
+	}
+	c.JSON(http.StatusOK, responseData)
 } 
\ No newline at end of file
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/answercache"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/sessions"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+// answerCacheKey 返回可以使用回答缓存时的缓存键，不同服务商和模型的回答分别缓存
+// 请求指定 noCache 或 Cache-Control: no-cache、追问（会话已有历史）、跨集群问题时不使用缓存
+func answerCacheKey(c *gin.Context, req ExecuteRequest, session *sessions.Session, question string, kubeContexts []string, language, model string) (string, bool) {
+	if !answercache.Enabled() || req.NoCache || strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache") {
+		return "", false
+	}
+	if req.ConversationID != "" || (session != nil && session.Turns() > 0) || len(kubeContexts) > 1 {
+		return "", false
+	}
+	var cluster string
+	if len(kubeContexts) == 1 {
+		cluster = kubeContexts[0]
+	}
+	return answercache.Key(question, answercache.Scope{
+		Tenant:   tenantOf(c),
+		Cluster:  cluster,
+		Role:     userRole(c),
+		Language: language,
+		Provider: llms.NormalizeProvider(req.Provider),
+		Model:    model,
+	}), true
+}
+
+// cacheableAnswer 回答是否可以缓存：只缓存成功的单集群回答，执行过有副作用的工具时不缓存
+func cacheableAnswer(responseData gin.H, history []ToolHistory) bool {
+	if responseData["status"] != "success" {
+		return false
+	}
+	for _, h := range history {
+		if h.Idempotency == string(tools.IdempotencySideEffecting) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/apikeys"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// CreateAPIKeyRequest 创建 API Key 请求结构
+type CreateAPIKeyRequest struct {
+	Name   string          `json:"name" binding:"required"`
+	Tenant string          `json:"tenant"`
+	Scopes []apikeys.Scope `json:"scopes" binding:"required"`
+}
+
+// llmAPIKey 获取调用 LLM 使用的密钥
+// 启用托管 API Key 后使用服务端配置的密钥，否则沿用请求头中的 X-API-Key
+func llmAPIKey(c *gin.Context) string {
+	if !apikeys.Enabled() {
+		return c.GetHeader("X-API-Key")
+	}
+	return serverLLMKey()
+}
+
+// serverLLMKey 服务端配置的 LLM 密钥，配置项 llm.api_key，为空时读取 OPENAI_API_KEY
+func serverLLMKey() string {
+	if key := utils.GetConfig().GetString("llm.api_key"); key != "" {
+		return key
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// llmBaseURL 返回调用 LLM 使用的地址
+// apiKey 为服务端密钥时只接受配置的服务商地址或 llm.allowed_base_urls 中的地址，其他地址被忽略并使用配置的地址，
+// 避免服务端密钥被发送到调用方指定的地址
+func llmBaseURL(c *gin.Context, apiKey, baseURL string) string {
+	if apiKey == "" || apiKey != serverLLMKey() || llms.BaseURLAllowed(baseURL) {
+		return baseURL
+	}
+	middleware.ContextLogger(c).Warn("使用服务端密钥时忽略请求指定的 LLM 地址",
+		zap.String("baseUrl", baseURL),
+	)
+	return ""
+}
+
+// tenantOf 获取请求所属租户，优先使用托管 API Key 绑定的租户，否则使用 JWT 用户名
+func tenantOf(c *gin.Context) string {
+	if tenant := c.GetString("tenant"); tenant != "" {
+		return tenant
+	}
+	return c.GetString("username")
+}
+
+// userRole 返回当前用户在系统提示中的角色（viewer、operator 或 admin）
+func userRole(c *gin.Context) string {
+	return string(middleware.CurrentRole(c))
+}
+
+// ListAPIKeys 列出所有托管的 API Key
+func ListAPIKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"keys":   apikeys.GetStore().List(),
+		"status": "success",
+	})
+}
+
+// CreateAPIKey 创建新的 API Key
+func CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	raw, key, err := apikeys.GetStore().Create(req.Name, req.Tenant, req.Scopes)
+	if err != nil {
+		utils.Error("创建 API Key 失败", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.Info("创建 API Key",
+		zap.String("key_id", key.ID),
+		zap.String("name", key.Name),
+		zap.String("username", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"key":     raw,
+		"info":    key,
+		"status":  "success",
+		"message": "请妥善保存密钥，之后将无法再次查看",
+	})
+}
+
+// RotateAPIKey 轮换 API Key 的密钥
+func RotateAPIKey(c *gin.Context) {
+	raw, key, err := apikeys.GetStore().Rotate(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.Info("轮换 API Key",
+		zap.String("key_id", key.ID),
+		zap.String("username", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"key":    raw,
+		"info":   key,
+		"status": "success",
+	})
+}
+
+// RevokeAPIKey 删除 API Key
+func RevokeAPIKey(c *gin.Context) {
+	if err := apikeys.GetStore().Revoke(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.Info("删除 API Key",
+		zap.String("key_id", c.Param("id")),
+		zap.String("username", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
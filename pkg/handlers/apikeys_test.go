@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/apikeys"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestLLMBaseURL(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("llm.api_key", "server-key")
+	config.Set("llm.allowed_base_urls", []string{"https://gateway.example.com/v1"})
+	defer func() {
+		config.Set("llm.api_key", nil)
+		config.Set("llm.allowed_base_urls", nil)
+	}()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/execute", nil)
+
+	tests := []struct {
+		apiKey  string
+		baseURL string
+		want    string
+	}{
+		// 调用方自己的密钥可以发送到任意地址
+		{"user-key", "https://llm.example.com/v1", "https://llm.example.com/v1"},
+		// 服务端密钥只能发送到允许的地址
+		{"server-key", "https://attacker.example.com/v1", ""},
+		{"server-key", "https://gateway.example.com/v1", "https://gateway.example.com/v1"},
+		{"server-key", "", ""},
+	}
+	for _, tt := range tests {
+		if got := llmBaseURL(c, tt.apiKey, tt.baseURL); got != tt.want {
+			t.Errorf("llmBaseURL(%q, %q) = %q, want %q", tt.apiKey, tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKeyResponsesOmitHash(t *testing.T) {
+	store, err := apikeys.NewStore(filepath.Join(t.TempDir(), "apikeys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	apikeys.SetStore(store)
+	defer apikeys.SetStore(nil)
+
+	call := func(handler gin.HandlerFunc, method, body string, params gin.Params) map[string]interface{} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/api/admin/apikeys", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = params
+		c.Set("username", "admin")
+		handler(c)
+		if w.Code != 200 {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "hash") {
+			t.Errorf("response contains the key hash: %s", w.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	created := call(CreateAPIKey, "POST", `{"name":"ci","scopes":["execute"]}`, nil)
+	id, _ := created["info"].(map[string]interface{})["id"].(string)
+	call(ListAPIKeys, "GET", "", nil)
+	call(RotateAPIKey, "POST", "", gin.Params{{Key: "id", Value: id}})
+}
@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/approvals"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/users"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// pausedConversation 因等待审批而暂停的对话，审批后据此继续
+type pausedConversation struct {
+	Question    string
+	Provider    string
+	Model       string
+	BaseURL     string
+	KubeContext string
+	ChatHistory []openai.ChatCompletionMessage
+}
+
+// requestApproval 为需要审批的变更命令创建审批记录，返回 202 和审批 ID，对话在该步骤暂停
+func requestApproval(c *gin.Context, record *audit.Interaction, approvalErr *tools.ApprovalRequiredError, paused pausedConversation) {
+	logger := middleware.ContextLogger(c)
+
+	messages, _ := json.Marshal(paused.ChatHistory)
+	approval := &audit.Approval{
+		InteractionID: record.ID,
+		Username:      record.Username,
+		Question:      paused.Question,
+		Tool:          approvalErr.Tool,
+		Input:         approvalErr.Command,
+		Verb:          approvalErr.Verb,
+		KubeContext:   paused.KubeContext,
+		Provider:      paused.Provider,
+		Model:         paused.Model,
+		BaseURL:       paused.BaseURL,
+		Messages:      string(messages),
+	}
+	if err := approvals.Default().Create(c.Request.Context(), approval); err != nil {
+		logger.Error("创建审批失败",
+			zap.Error(err),
+		)
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("执行失败: %v", err)})
+		return
+	}
+
+	if approvals.IMEnabled() {
+		// 卡片发送失败不影响审批，仍可通过 /api/approvals/:id/approve|reject 审批
+		go func(approval audit.Approval) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := approvals.SendCard(ctx, &approval); err != nil {
+				logger.Warn("发送审批卡片失败",
+					zap.String("approval_id", approval.ID),
+					zap.Error(err),
+				)
+			}
+		}(*approval)
+	}
+
+	message := fmt.Sprintf("变更命令 `%s` 需要审批后执行，审批 ID：%s", approval.Input, approval.ID)
+	record.Status = audit.StatusPendingApproval
+	record.Answer = message
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":         audit.StatusPendingApproval,
+		"message":        message,
+		"approval_id":    approval.ID,
+		"approval":       approval,
+		"interaction_id": record.ID,
+	})
+}
+
+// ListApprovals 列出审批，管理员可以查看全部，其他用户只能查看自己发起的审批
+func ListApprovals(c *gin.Context) {
+	username := c.GetString("username")
+	owner := c.Query("username")
+	if !middleware.IsAdmin(username) {
+		owner = username
+	}
+
+	list, err := approvals.Default().List(c.Request.Context(), c.Query("status"), owner)
+	if err != nil {
+		utils.Error("查询审批失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": list,
+		"total":     len(list),
+	})
+}
+
+// GetApproval 获取单个审批
+func GetApproval(c *gin.Context) {
+	approval, ok := loadApproval(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"approval": approval,
+		"status":   approval.Status,
+	})
+}
+
+// ApproveApproval 审批通过：执行命令后继续对话，返回最终答案
+func ApproveApproval(c *gin.Context) {
+	reviewApproval(c, true)
+}
+
+// RejectApproval 拒绝审批：命令不执行，将拒绝原因交给 LLM 后继续对话
+func RejectApproval(c *gin.Context) {
+	reviewApproval(c, false)
+}
+
+// loadApproval 读取审批并校验访问权限，失败时已写入响应
+func loadApproval(c *gin.Context) (*audit.Approval, bool) {
+	approval, err := approvals.Default().Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, approvals.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return nil, false
+	}
+	if err != nil {
+		utils.Error("查询审批失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if username := c.GetString("username"); approval.Username != username && !middleware.IsAdmin(username) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return nil, false
+	}
+	return approval, true
+}
+
+// ApprovalCallback 钉钉/企业微信审批卡片按钮的回调，不经过 JWT 认证：
+// 校验链接签名后用 OAuth 授权码识别点击人，映射到的用户需为管理员，审批后继续暂停的对话
+func ApprovalCallback(c *gin.Context) {
+	logger := middleware.ContextLogger(c)
+
+	id, approve, err := approvals.VerifyAction(c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	// 钉钉回调参数为 authCode，企业微信为 code
+	code := c.Query("code")
+	if code == "" {
+		code = c.Query("authCode")
+	}
+	if code == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization code"})
+		return
+	}
+	reviewer, err := approvals.ResolveApprover(c.Request.Context(), code)
+	if err != nil {
+		logger.Warn("审批卡片回调身份校验失败",
+			zap.String("approval_id", id),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if !middleware.IsAdmin(reviewer) {
+		logger.Warn("非管理员通过审批卡片审批",
+			zap.String("approval_id", id),
+			zap.String("username", reviewer),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+		return
+	}
+	c.Set("username", reviewer)
+
+	approval, err := approvals.Default().Get(c.Request.Context(), id)
+	if errors.Is(err, approvals.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Approval not found"})
+		return
+	}
+	if err != nil {
+		utils.Error("查询审批失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// 浏览器中打开的回调不携带 X-API-Key，使用服务端配置的 LLM 密钥
+	resumeApproval(c, approval, reviewer, serverLLMKey(), approve)
+}
+
+// reviewApproval 处理审批，审批后在当前请求中继续暂停的对话
+func reviewApproval(c *gin.Context, approve bool) {
+	approval, ok := loadApproval(c)
+	if !ok {
+		return
+	}
+	resumeApproval(c, approval, c.GetString("username"), llmAPIKey(c), approve)
+}
+
+// resumeApproval 由 reviewer 审批，通过时执行命令，然后以申请人的身份继续对话并写入响应
+func resumeApproval(c *gin.Context, approval *audit.Approval, reviewer, apiKey string, approve bool) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("approval_resume")()
+	logger := middleware.ContextLogger(c)
+
+	// 继续对话需要调用 LLM，先校验 API Key，避免审批状态已变更却无法继续
+	if apiKey == "" && llms.RequiresAPIKey(approval.Provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
+		return
+	}
+	var chatHistory []openai.ChatCompletionMessage
+	if err := json.Unmarshal([]byte(approval.Messages), &chatHistory); err != nil || len(chatHistory) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Approval conversation is not available"})
+		return
+	}
+
+	manager := approvals.Default()
+	approval, err := manager.Review(c.Request.Context(), approval.ID, reviewer, approve)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 以申请人的身份继续对话，配额、集群等上下文与原请求一致
+	ctx := tools.WithUser(c.Request.Context(), approval.Username)
+	ctx = tools.WithRole(ctx, string(users.RoleOf(approval.Username)))
+	// 审批记录不保存租户，按 JWT 用户的租户（用户名）校验模型允许列表
+	ctx = tools.WithTenant(ctx, approval.Username)
+	ctx, _ = tools.WithRetryBudget(ctx)
+	ctx, _ = tools.WithResultCache(ctx)
+	ctx = llms.WithProvider(ctx, approval.Provider)
+	if approval.KubeContext != "" {
+		ctx = tools.WithKubeContext(ctx, approval.KubeContext)
+	}
+
+	startTime := time.Now()
+	record := &audit.Interaction{
+		ID:        audit.NewInteractionID(),
+		Username:  approval.Username,
+		Model:     approval.Model,
+		Cluster:   approval.KubeContext,
+		Question:  approval.Question,
+		Status:    audit.StatusSuccess,
+		CreatedAt: startTime,
+	}
+	ctx, receiptLog := tools.WithReceipts(ctx, record.ID)
+	defer func() {
+		record.DurationMs = time.Since(startTime).Milliseconds()
+		record.Receipts = receiptLog.List()
+		audit.RecordContext(c.Request.Context(), record)
+	}()
+	logger = middleware.WithLogFields(c,
+		zap.String(utils.LogFieldInteraction, record.ID),
+		zap.String("approval_id", approval.ID),
+	)
+	// 审批通过的命令和继续对话中的工具调用都写入本次交互的审计记录
+	// 暂停前的对话查询的数据不晚于审批创建的时间
+	dataAt := newDataClock(approval.CreatedAt)
+	ctx = assistants.WithEvents(ctx, record.ID, auditToolCalls(record), dataAt.subscriber())
+
+	finalStatus := approvals.StatusRejected
+	observation := assistants.RejectedObservation(approval.Tool, approval.Input, reviewer)
+	if approve {
+		finalStatus = approvals.StatusExecuted
+		if observation, err = assistants.RunApprovedTool(ctx, approval.Model, approval.Tool, approval.Input); err != nil {
+			record.Status = audit.StatusError
+			record.Error = err.Error()
+			manager.Complete(ctx, approval, approvals.StatusFailed, "", "", err)
+			respondError(c, fmt.Sprintf("执行失败: %v", err), err)
+			return
+		}
+	}
+
+	messages := assistants.ResumeMessages(chatHistory, approval.Tool, approval.Input, observation, approval.Model)
+	baseURL := llmBaseURL(c, apiKey, approval.BaseURL)
+	response, chatHistory, err := assistants.AssistantWithContext(ctx, approval.Model, messages, 8192, true, true, defaultMaxIterations, apiKey, baseURL)
+
+	// 继续的对话中再次提出变更命令时创建新的审批
+	var approvalErr *tools.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		manager.Complete(ctx, approval, finalStatus, observation, "", nil)
+		requestApproval(c, record, approvalErr, pausedConversation{
+			Question:    approval.Question,
+			Provider:    approval.Provider,
+			Model:       approval.Model,
+			BaseURL:     baseURL,
+			KubeContext: approval.KubeContext,
+			ChatHistory: chatHistory,
+		})
+		return
+	}
+	if err != nil {
+		logger.Error("审批后继续对话失败",
+			zap.Error(err),
+		)
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+		manager.Complete(ctx, approval, approvals.StatusFailed, observation, "", err)
+		respondError(c, fmt.Sprintf("执行失败: %v", err), err)
+		return
+	}
+
+	toolsHistory := extractToolsHistory(chatHistory)
+	answer := parseFinalAnswer(approval.Model, response)
+	record.Answer = answer
+	manager.Complete(ctx, approval, finalStatus, observation, answer, nil)
+
+	responseData := gin.H{
+		"message":        answer,
+		"status":         "success",
+		"approval":       approval,
+		"tools_history":  toolsHistory,
+		"interaction_id": record.ID,
+	}
+	if answerFooterEnabled() {
+		provenance := newProvenance(approval.KubeContext, approval.Model, record.ID, dataAt.Time(), false)
+		responseData["message"] = appendAnswerFooter(answer, provenance)
+		responseData["provenance"] = provenance
+	}
+	c.JSON(http.StatusOK, responseData)
+}
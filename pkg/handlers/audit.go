@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/receipts"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// auditReservedParams 审计查询中不作为过滤字段的参数
+var auditReservedParams = map[string]bool{
+	"sort":   true,
+	"order":  true,
+	"limit":  true,
+	"cursor": true,
+	"since":  true,
+	"until":  true,
+}
+
+// parseAuditQuery 从请求参数构建审计查询条件
+func parseAuditQuery(c *gin.Context) (audit.Query, error) {
+	q := audit.Query{
+		Filters: map[string]string{},
+		SortBy:  c.DefaultQuery("sort", "created_at"),
+		Desc:    c.DefaultQuery("order", "desc") == "desc",
+		Cursor:  c.Query("cursor"),
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return q, fmt.Errorf("无效的 limit: %s", limit)
+		}
+		q.Limit = n
+	}
+
+	for name, target := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return q, fmt.Errorf("无效的 %s，应为 RFC3339 格式: %s", name, value)
+			}
+			*target = t
+		}
+	}
+
+	// 其余参数均视为过滤字段，由查询构建器按白名单校验
+	for key, values := range c.Request.URL.Query() {
+		if auditReservedParams[key] || len(values) == 0 {
+			continue
+		}
+		q.Filters[key] = values[0]
+	}
+	return q, nil
+}
+
+// ListAuditInteractions 分页查询审计记录
+func ListAuditInteractions(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	q, err := parseAuditQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	interactions, next, err := store.ListInteractions(c.Request.Context(), q)
+	if err != nil {
+		utils.Warn("查询审计记录失败", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"interactions": interactions,
+		"next_cursor":  next,
+		"status":       "success",
+	})
+}
+
+// GetAuditInteraction 获取单个交互的审计详情
+func GetAuditInteraction(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	interaction, err := store.GetInteraction(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+	if err != nil {
+		utils.Error("获取审计记录失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"interaction": interaction,
+		"timeline":    interaction.Timeline(),
+		"status":      "success",
+	})
+}
+
+// AuditReceipt 执行回执及其签名校验结果
+type AuditReceipt struct {
+	audit.ToolReceipt
+	Verified bool   `json:"verified"`
+	Error    string `json:"error,omitempty"`
+}
+
+// GetAuditReceipts 获取交互的工具执行回执，并使用本服务和 receipts.trusted_keys 中的公钥校验签名
+func GetAuditReceipts(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	list, err := store.ToolReceipts(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		utils.Error("获取执行回执失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	keys := receipts.TrustedKeys()
+	result := make([]AuditReceipt, 0, len(list))
+	verified := 0
+	for _, r := range list {
+		item := AuditReceipt{ToolReceipt: r, Verified: true}
+		if err := receipts.Verify(r, keys); err != nil {
+			item.Verified, item.Error = false, err.Error()
+		} else {
+			verified++
+		}
+		result = append(result, item)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"receipts": result,
+		"verified": verified == len(result),
+		"status":   "success",
+	})
+}
+
+// BackfillAuditUsage 立即补齐历史交互的工具调用耗时、观察结果 token 数和 token 用量，启动时的后台补齐未完成或被关闭时使用
+func BackfillAuditUsage(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	batchSize, _ := strconv.Atoi(c.Query("batch_size"))
+	result, err := store.Backfill(c.Request.Context(), batchSize)
+	if err != nil {
+		utils.Error("补齐历史交互的用量失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error", "result": result})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"result": result,
+		"status": "success",
+	})
+}
@@ -4,17 +4,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/users"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
 )
 
-const (
-	DEFAULT_USERNAME = "admin"
-	DEFAULT_PASSWORD = "novastar"
-)
-
 // LoginRequest 登录请求结构
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
@@ -30,17 +26,21 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 使用默认账户验证
-	if req.Username != DEFAULT_USERNAME || req.Password != DEFAULT_PASSWORD {
+	// 按 auth.users 验证，未配置用户时使用内置账号
+	store := users.Default()
+	user, ok := store.Authenticate(req.Username, req.Password)
+	if !ok {
 		utils.Warn("登录失败：用户名或密码错误",
 			zap.String("username", req.Username))
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
+	role := users.RoleOf(user.Username)
 
-	// 创建 JWT token
+	// 创建 JWT token，角色写入令牌声明
 	claims := &middleware.Claims{
-		Username: req.Username,
+		Username: user.Username,
+		Role:     string(role),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -65,9 +65,13 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	utils.Info("登录成功", zap.String("username", req.Username))
-	c.JSON(http.StatusOK, gin.H{
+	utils.Info("登录成功", zap.String("username", user.Username), zap.String("role", string(role)))
+	resp := gin.H{
 		"token": tokenString,
-		"note":  "Default credentials: admin/novastar",
-	})
+		"role":  role,
+	}
+	if store.Builtin() {
+		resp["note"] = "Default credentials: admin/novastar, configure auth.users for production"
+	}
+	c.JSON(http.StatusOK, resp)
 }
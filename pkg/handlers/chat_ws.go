@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/prompts"
+	"github.com/myysophia/OpsAgent/pkg/sessions"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// WebSocket 消息类型
+const (
+	wsTypeMessage  = "message"  // 客户端提问
+	wsTypeReset    = "reset"    // 客户端清空会话历史
+	wsTypeClose    = "close"    // 客户端结束会话
+	wsTypeSession  = "session"  // 服务端返回会话信息
+	wsTypeAnswer   = "answer"   // 服务端返回回答
+	wsTypeError    = "error"    // 服务端返回错误
+	wsTypeProgress = "progress" // 服务端返回处理进度
+)
+
+const defaultProgressInterval = 5 * time.Second
+
+// ChatMessage WebSocket 客户端消息
+type ChatMessage struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// ChatEvent WebSocket 服务端消息
+type ChatEvent struct {
+	Type          string        `json:"type"`
+	SessionID     string        `json:"session_id,omitempty"`
+	Turns         int           `json:"turns,omitempty"`
+	Message       string        `json:"message,omitempty"`
+	InteractionID string        `json:"interaction_id,omitempty"`
+	ToolsHistory  []ToolHistory `json:"tools_history,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	ErrorCode     string        `json:"error_code,omitempty"` // 机器可读的错误码，例如 llm_throttled、parse_error
+	// 处理进度：progress 消息中为当前阶段及已停留时间，answer、error 消息中为各阶段耗时
+	Stage          string                   `json:"stage,omitempty"`
+	Detail         string                   `json:"detail,omitempty"`
+	StageElapsedMs int64                    `json:"stage_elapsed_ms,omitempty"`
+	ElapsedMs      int64                    `json:"elapsed_ms,omitempty"`
+	Stages         []assistants.StageTiming `json:"stages,omitempty"`
+	// 启用 answer.footer 时 answer 消息中回答的来源信息
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// ChatWS 多轮对话的 WebSocket 接口，对话历史由服务端会话保存
+// 查询参数：
+//   - session_id: 恢复已有会话，为空时创建新会话
+//   - provider: LLM 服务商（openai、anthropic、gemini、ollama），默认 openai，仅在创建会话时生效
+//   - model: 使用的模型，默认 gpt-4
+//   - cluster: 目标集群，仅在创建会话时生效
+//   - baseUrl: LLM 服务地址，使用服务端密钥时只接受配置的服务商地址和 llm.allowed_base_urls
+//   - showThought: 是否返回工具调用历史
+func ChatWS(c *gin.Context) {
+	logger := middleware.ContextLogger(c)
+	username := c.GetString("username")
+
+	apiKey := llmAPIKey(c)
+	manager := sessions.GetManager()
+	var session *sessions.Session
+	if id := c.Query("session_id"); id != "" {
+		s, ok := manager.Get(id, username)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在或已过期"})
+			return
+		}
+		session = s
+	} else {
+		provider := c.Query("provider")
+		model := c.DefaultQuery("model", "gpt-4")
+		if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), provider, model, nil); err != nil {
+			logger.Warn("模型不在租户允许列表中",
+				zap.String("tenant", tenantOf(c)),
+				zap.String("model", model),
+				zap.Error(err),
+			)
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var kubeContext string
+		if cluster := c.Query("cluster"); cluster != "" {
+			resolution, err := tools.ResolveCluster(cluster)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if resolution.NeedsConfirmation {
+				c.JSON(http.StatusOK, gin.H{
+					"status":               "needs_confirmation",
+					"message":              "无法确定集群 " + cluster + "，请从候选集群中确认后重新连接",
+					"cluster_confirmation": resolution,
+				})
+				return
+			}
+			kubeContext = resolution.Candidate
+		}
+		session = manager.Create(username, provider, model, kubeContext)
+	}
+
+	if apiKey == "" && llms.RequiresAPIKey(session.Provider) {
+		logger.Error("缺少 API Key")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
+		return
+	}
+
+	logger = middleware.WithLogFields(c, zap.String(utils.LogFieldSession, session.ID))
+	showThought := c.Query("showThought") == "true"
+	baseURL := llmBaseURL(c, apiKey, c.Query("baseUrl"))
+
+	server := websocket.Server{
+		// 鉴权已由 JWT 中间件完成，跨域由 CORS 配置统一放开
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			logger.Info("WebSocket 会话已连接", zap.String("provider", session.Provider), zap.String("model", session.Model), zap.String("cluster", session.Cluster))
+
+			// 进度心跳与回答来自不同 goroutine，发送需要串行
+			var sendMu sync.Mutex
+			send := func(event ChatEvent) bool {
+				sendMu.Lock()
+				defer sendMu.Unlock()
+				event.SessionID = session.ID
+				if err := websocket.JSON.Send(ws, event); err != nil {
+					logger.Warn("WebSocket 发送失败", zap.Error(err))
+					return false
+				}
+				return true
+			}
+			if !send(ChatEvent{Type: wsTypeSession, Turns: session.Turns()}) {
+				return
+			}
+
+			for {
+				var msg ChatMessage
+				if err := websocket.JSON.Receive(ws, &msg); err != nil {
+					if !errors.Is(err, io.EOF) {
+						logger.Warn("WebSocket 接收失败", zap.Error(err))
+					}
+					return
+				}
+
+				switch msg.Type {
+				case "", wsTypeMessage:
+					if strings.TrimSpace(msg.Content) == "" {
+						send(ChatEvent{Type: wsTypeError, Error: "消息内容不能为空"})
+						continue
+					}
+					if !send(runChatTurn(c, session, strings.TrimSpace(msg.Content), apiKey, baseURL, showThought, send)) {
+						return
+					}
+				case wsTypeReset:
+					session.Reset()
+					if !send(ChatEvent{Type: wsTypeSession}) {
+						return
+					}
+				case wsTypeClose:
+					manager.Close(session.ID)
+					logger.Info("WebSocket 会话已结束")
+					return
+				default:
+					send(ChatEvent{Type: wsTypeError, Error: "不支持的消息类型: " + msg.Type})
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// progressInterval 停留在同一阶段时重复发送进度的间隔，配置项 sessions.progress_interval，默认 5s，设置为 0 时只在阶段切换时发送
+func progressInterval() time.Duration {
+	config := utils.GetConfig()
+	if !config.IsSet("sessions.progress_interval") {
+		return defaultProgressInterval
+	}
+	return config.GetDuration("sessions.progress_interval")
+}
+
+// runChatTurn 执行一轮对话：系统提示 + 会话历史 + 本轮问题，完成后将问答写入会话和审计记录
+// 执行过程中通过 send 发送进度（解析上下文 → 查询集群 → 分析 → 生成回答）及各阶段耗时
+func runChatTurn(c *gin.Context, session *sessions.Session, question, apiKey, baseURL string, showThought bool, send func(ChatEvent) bool) ChatEvent {
+	defer session.BeginTurn()()
+
+	progress := assistants.NewProgress(func(e assistants.ProgressEvent) {
+		send(ChatEvent{Type: wsTypeProgress, Stage: e.Stage, Detail: e.Detail, StageElapsedMs: e.StageElapsedMs, ElapsedMs: e.ElapsedMs})
+	}, progressInterval())
+	defer progress.Finish()
+	progress.Enter(assistants.StageResolvingContext, "")
+
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("chat_ws_turn")()
+	logger := middleware.ContextLogger(c)
+
+	startTime := time.Now()
+	record := &audit.Interaction{
+		ID:        audit.NewInteractionID(),
+		Username:  session.Username,
+		Model:     session.Model,
+		Cluster:   session.Cluster,
+		Question:  question,
+		Status:    audit.StatusSuccess,
+		CreatedAt: startTime,
+	}
+	// 采样本次交互中 OpsAgent 自身的资源消耗，用于定位导致资源尖峰的问题
+	sampler := utils.StartResourceSampler("chat_ws")
+	var receiptLog *tools.ReceiptLog
+	defer func() {
+		record.DurationMs = time.Since(startTime).Milliseconds()
+		record.Receipts = receiptLog.List()
+		sampler.Stop(record.ID, record.Question)
+		audit.RecordContext(c.Request.Context(), record)
+	}()
+
+	prompt := prompts.Get(c.Request.Context(), "execute", prompts.Builtin("execute"))
+	record.PromptName, record.PromptVersion, record.PromptHash = prompt.Name, prompt.Version, prompt.Hash
+	vars := prompts.NewVars(prompts.Options{
+		UserRole: userRole(c),
+		Cluster:  session.Cluster,
+		Question: question,
+	})
+	content := prompts.MustRender(prompt.Text, vars)
+	recordSystemPrompt(logger, prompt.Name, vars, content, session.Model)
+	messages := []openai.ChatCompletionMessage{{
+		Role:    openai.ChatMessageRoleSystem,
+		Content: content,
+	}}
+	messages = append(messages, session.History()...)
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question})
+
+	ctx := tools.WithUser(c.Request.Context(), session.Username)
+	ctx = tools.WithRole(ctx, string(middleware.CurrentRole(c)))
+	ctx = tools.WithTenant(ctx, tenantOf(c))
+	ctx, _ = tools.WithRetryBudget(ctx)
+	ctx, _ = tools.WithResultCache(ctx)
+	ctx, receiptLog = tools.WithReceipts(ctx, record.ID)
+	ctx = llms.WithProvider(ctx, session.Provider)
+	ctx, _ = llms.WithTokenBudget(ctx, session.Username)
+	if session.Cluster != "" {
+		ctx = tools.WithKubeContext(ctx, session.Cluster)
+	}
+	ctx = utils.WithLogger(ctx, logger.With(zap.String(utils.LogFieldInteraction, record.ID)))
+	ctx = assistants.WithProgress(ctx, progress)
+	dataAt := newDataClock(time.Time{})
+	ctx = assistants.WithEvents(ctx, record.ID, auditToolCalls(record), dataAt.subscriber())
+
+	response, chatHistory, err := assistants.AssistantWithContext(ctx, session.Model, messages, 8192, true, true, defaultMaxIterations, apiKey, baseURL)
+	toolsHistory := extractToolsHistory(chatHistory)
+	if err != nil {
+		// 说明卡在哪个阶段，例如查询集群超时和 LLM 响应超时需要不同的处理
+		stage, elapsed := progress.Stage()
+		logger.Error("WebSocket 对话执行失败", zap.String("stage", stage), zap.Duration("stage_elapsed", elapsed), zap.Error(err))
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+		return ChatEvent{
+			Type:          wsTypeError,
+			InteractionID: record.ID,
+			Error:         fmt.Sprintf("执行失败（%s 阶段，已停留 %s）: %v", stage, elapsed.Round(time.Millisecond), err),
+			ErrorCode:     errorCode(err),
+			Stage:         stage,
+			Stages:        progress.Finish(),
+		}
+	}
+
+	progress.Enter(assistants.StageComposingAnswer, "")
+	answer := parseFinalAnswer(session.Model, response)
+	record.Answer = answer
+	session.AddTurn(question, answer)
+
+	event := ChatEvent{Type: wsTypeAnswer, Message: answer, InteractionID: record.ID, Turns: session.Turns(), Stages: progress.Finish()}
+	if answerFooterEnabled() {
+		provenance := newProvenance(session.Cluster, session.Model, record.ID, dataAt.Time(), false)
+		event.Message = appendAnswerFooter(answer, provenance)
+		event.Provenance = &provenance
+	}
+	if showThought {
+		event.ToolsHistory = toolsHistory
+	}
+	return event
+}
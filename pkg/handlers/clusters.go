@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ClusterRequest 登记或修改集群的请求
+type ClusterRequest struct {
+	Name        string   `json:"name"`    // 修改时忽略
+	Context     string   `json:"context"` // in_cluster 为 true 时忽略
+	Namespace   string   `json:"namespace"`
+	Aliases     []string `json:"aliases"`
+	Kubeconfig  string   `json:"kubeconfig"`
+	InCluster   bool     `json:"in_cluster"`
+	Description string   `json:"description"`
+}
+
+func (r ClusterRequest) cluster() clusters.Cluster {
+	return clusters.Cluster{
+		Name:        r.Name,
+		Context:     r.Context,
+		Namespace:   r.Namespace,
+		Aliases:     r.Aliases,
+		Kubeconfig:  r.Kubeconfig,
+		InCluster:   r.InCluster,
+		Description: r.Description,
+	}
+}
+
+// ListClusters 列出登记的集群
+func ListClusters(c *gin.Context) {
+	list := clusters.Default().List()
+	c.JSON(http.StatusOK, gin.H{
+		"clusters": list,
+		"total":    len(list),
+	})
+}
+
+// GetCluster 获取登记的集群
+func GetCluster(c *gin.Context) {
+	cluster, ok := clusters.Default().Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"cluster": cluster})
+}
+
+// CreateCluster 登记集群，登记后即可在提问中使用集群名称或别名
+func CreateCluster(c *gin.Context) {
+	var req ClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+	cluster, err := clusters.Default().Create(req.cluster())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	utils.Info("登记集群",
+		zap.String("cluster", cluster.Name),
+		zap.String("context", cluster.Context),
+		zap.String("admin", c.GetString("username")),
+	)
+	c.JSON(http.StatusCreated, gin.H{"cluster": cluster})
+}
+
+// UpdateCluster 修改接口登记的集群，配置文件中登记的集群只读
+func UpdateCluster(c *gin.Context) {
+	var req ClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+	cluster, err := clusters.Default().Update(c.Param("name"), req.cluster())
+	if err != nil {
+		clusterError(c, err)
+		return
+	}
+	utils.Info("修改集群登记",
+		zap.String("cluster", cluster.Name),
+		zap.String("context", cluster.Context),
+		zap.String("admin", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{"cluster": cluster})
+}
+
+// DeleteCluster 删除接口登记的集群
+func DeleteCluster(c *gin.Context) {
+	if err := clusters.Default().Delete(c.Param("name")); err != nil {
+		clusterError(c, err)
+		return
+	}
+	utils.Info("删除集群登记",
+		zap.String("cluster", c.Param("name")),
+		zap.String("admin", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{"message": "Cluster deleted"})
+}
+
+func clusterError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, clusters.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found"})
+	case errors.Is(err, clusters.ErrReadOnly):
+		c.JSON(http.StatusConflict, gin.H{"error": "Cluster is defined in the config file or config repository and cannot be changed via the API"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/configrepo"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ConfigRepoWebhook 配置仓库的 push webhook，校验通过后在后台拉取仓库
+// 支持 GitHub 的 X-Hub-Signature-256 签名、GitLab 的 X-Gitlab-Token 和 Bearer token，密钥为 config_repo.webhook_secret
+func ConfigRepoWebhook(c *gin.Context) {
+	secret := utils.GetConfig().GetString("config_repo.webhook_secret")
+	if !configrepo.Enabled() || secret == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Config repository webhook is not enabled"})
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !verifyRepoWebhook(c.Request.Header, body, secret) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	go func() {
+		if _, err := configrepo.Default().Sync(context.Background(), configrepo.TriggerWebhook); err != nil {
+			utils.Warn("webhook 触发的配置仓库同步失败", zap.Error(err))
+		}
+	}()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Sync scheduled"})
+}
+
+// verifyRepoWebhook 校验 webhook 请求的签名或令牌
+func verifyRepoWebhook(header http.Header, body []byte, secret string) bool {
+	if signature := header.Get("X-Hub-Signature-256"); signature != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(signature), []byte(expected))
+	}
+	token := header.Get("X-Gitlab-Token")
+	if token == "" {
+		token = strings.TrimPrefix(header.Get("Authorization"), "Bearer ")
+	}
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// GetConfigRepo 查询配置仓库当前生效的提交和最近一次同步结果
+func GetConfigRepo(c *gin.Context) {
+	if !configrepo.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Config repository is not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"repo":   configrepo.Default().Status(),
+		"status": "success",
+	})
+}
+
+// SyncConfigRepo 管理员立即拉取配置仓库并应用变更
+func SyncConfigRepo(c *gin.Context) {
+	if !configrepo.Enabled() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Config repository is not enabled"})
+		return
+	}
+	status, err := configrepo.Default().Sync(c.Request.Context(), configrepo.TriggerManual)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "repo": status})
+		return
+	}
+	utils.Info("管理员同步了配置仓库",
+		zap.String("commit", status.Commit),
+		zap.String("admin", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"repo":   status,
+		"status": "success",
+	})
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/credentials"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// ListCredentials 查询各 context 的凭据类型和过期时间，refresh=true 时立即重新检查
+func ListCredentials(c *gin.Context) {
+	manager := credentials.Default()
+	statuses := manager.Statuses()
+	if c.Query("refresh") == "true" || len(statuses) == 0 {
+		statuses = manager.Check(c.Request.Context())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"credentials": statuses,
+		"total":       len(statuses),
+		"status":      "success",
+	})
+}
+
+// RotateCredentials 管理员立即执行 context 配置的凭据刷新钩子
+func RotateCredentials(c *gin.Context) {
+	kubeContext := c.Param("context")
+	status, err := credentials.Default().Rotate(c.Request.Context(), kubeContext)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "credential": status})
+		return
+	}
+	utils.Info("管理员刷新了集群凭据",
+		zap.String("context", kubeContext),
+		zap.String("admin", c.GetString("username")),
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"credential": status,
+		"status":     "success",
+	})
+}
@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/ownership"
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
 )
 
 // DiagnoseRequest 诊断请求结构
 type DiagnoseRequest struct {
 	Name      string `json:"name" binding:"required"`
 	Namespace string `json:"namespace" binding:"required"`
+	// ErrorsSince 错误开始出现的时间（RFC3339），用于关联此前的发布，为空时使用当前时间
+	ErrorsSince string `json:"errors_since"`
 }
 
 // Diagnose 处理诊断请求
@@ -23,12 +35,128 @@ func Diagnose(c *gin.Context) {
 	model := c.DefaultQuery("model", "gpt-4o")
 	cluster := c.DefaultQuery("cluster", "default")
 
+	if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), "", model, nil); err != nil {
+		utils.Warn("模型不在租户允许列表中",
+			zap.String("tenant", tenantOf(c)),
+			zap.String("model", model),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 诊断依据的发布、修订和日志在下面查询，来源说明的数据时间取查询开始的时间
+	dataAt := time.Now()
 	// TODO: 实现实际的诊断逻辑
 	result := fmt.Sprintf("Diagnosing pod %s in namespace %s using model %s on cluster %s",
 		req.Name, req.Namespace, model, cluster)
 
-	c.JSON(http.StatusOK, gin.H{
+	responseData := gin.H{
 		"message": result,
 		"status":  "success",
-	})
-} 
\ No newline at end of file
+	}
+
+	// 关联错误出现前的发布，例如 14:02 开始报错、13:58 更换了镜像
+	incident := time.Now()
+	if req.ErrorsSince != "" {
+		t, err := time.Parse(time.RFC3339, req.ErrorsSince)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("errors_since 格式错误: %v", err)})
+			return
+		}
+		incident = t
+	}
+	if changes := recentChanges(cluster, req.Namespace, incident); len(changes) > 0 {
+		lines := make([]string, 0, len(changes))
+		for _, change := range changes {
+			lines = append(lines, "- "+change.Summary())
+		}
+		result += "\n\n最近变更:\n" + strings.Join(lines, "\n")
+		responseData["message"] = result
+		responseData["recent_changes"] = changes
+	}
+
+	// 附加 Deployment 的发布状态和修订差异，说明最近的发布具体改了什么
+	if history, err := rolloutHistory(c.Request.Context(), cluster, req.Namespace, req.Name); err != nil {
+		utils.Warn("查询发布历史失败",
+			zap.String("namespace", req.Namespace),
+			zap.String("name", req.Name),
+			zap.Error(err),
+		)
+	} else if history != nil {
+		result += "\n\n发布历史:\n" + history.Format(3)
+		responseData["message"] = result
+		responseData["rollout"] = history
+	}
+
+	// 从日志系统拉取出错前后的历史日志，Pod 崩溃重建后 kubectl logs 只能看到当前容器
+	if tools.LogSearchEnabled() {
+		entries, err := tools.SearchLogs(c.Request.Context(), tools.LogQuery{
+			Namespace: req.Namespace,
+			Pod:       req.Name,
+			Start:     incident.Add(-time.Hour),
+			End:       time.Now(),
+			Limit:     50,
+		})
+		if err != nil {
+			utils.Warn("查询历史日志失败",
+				zap.String("namespace", req.Namespace),
+				zap.String("pod", req.Name),
+				zap.Error(err),
+			)
+		} else if len(entries) > 0 {
+			result += "\n\n历史日志:\n" + tools.FormatLogEntries(entries)
+			responseData["message"] = result
+			responseData["historical_logs"] = entries
+		}
+	}
+
+	// 附加负责团队，便于直接联系或升级
+	if team, ok := ownership.Load().Lookup(cluster, req.Namespace, req.Name); ok {
+		responseData["message"] = result + "\n\n" + team.Summary()
+		responseData["owner"] = team
+	}
+	if answerFooterEnabled() {
+		provenance := newProvenance(cluster, model, "", dataAt, false)
+		responseData["message"] = appendAnswerFooter(responseData["message"].(string), provenance)
+		responseData["provenance"] = provenance
+	}
+	c.JSON(http.StatusOK, responseData)
+} 
+
+// recentChanges 返回错误出现前 changes.window（默认 1h）内命名空间中的发布
+// cluster 为 default 时使用 kubeconfig 当前 context
+func recentChanges(cluster, namespace string, incident time.Time) []kubernetes.CorrelatedChange {
+	if cluster == "default" {
+		cluster = kubernetes.InClusterName
+		if _, current, err := kubernetes.ListContexts(); err == nil && current != "" {
+			cluster = current
+		}
+	}
+	window := utils.GetConfig().GetDuration("changes.window")
+	if window <= 0 {
+		window = time.Hour
+	}
+	events := kubernetes.Rollouts().Recent(cluster, namespace, incident.Add(-window), incident)
+	return kubernetes.CorrelateChanges(events, incident, window)
+}
+
+// rolloutHistory 返回诊断对象所属 Deployment 的发布历史，name 可以是 Deployment 或 Pod 名称
+// 不属于 Deployment 时返回 nil
+func rolloutHistory(ctx context.Context, cluster, namespace, name string) (*kubernetes.DeploymentHistory, error) {
+	kubeContext := cluster
+	if kubeContext == "default" {
+		kubeContext = ""
+	}
+	client, err := kubernetes.ClientsetForContext(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	deployment, err := kubernetes.DeploymentForWorkload(ctx, client, namespace, name)
+	if err != nil || deployment == "" {
+		return nil, err
+	}
+	return kubernetes.RevisionHistory(ctx, client, namespace, deployment)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/sashabaranov/go-openai"
+)
+
+// 不属于工具、助手和 LLM 错误类型的错误码
+const (
+	errCodeTimeout   = "timeout"
+	errCodeCancelled = "cancelled"
+	errCodeInternal  = "internal_error"
+)
+
+// errorCodeStatus 错误码对应的 HTTP 状态码
+var errorCodeStatus = map[string]int{
+	tools.ErrCodeToolNotFound:      http.StatusNotFound,
+	tools.ErrCodeToolTimeout:       http.StatusGatewayTimeout,
+	tools.ErrCodeCommandDenied:     http.StatusForbidden,
+	tools.ErrCodeApprovalRequired:  http.StatusAccepted,
+	tools.ErrCodeQuotaExceeded:     http.StatusTooManyRequests,
+	tools.ErrCodeTargetUnavailable: http.StatusServiceUnavailable,
+	llms.ErrCodeLLMError:           http.StatusBadGateway,
+	llms.ErrCodeLLMThrottled:       http.StatusServiceUnavailable,
+	llms.ErrCodeBudgetExceeded:     http.StatusTooManyRequests,
+	assistants.ErrCodeParseError:   http.StatusBadGateway,
+	errCodeTimeout:                 http.StatusGatewayTimeout,
+	// 客户端已断开，状态码沿用 nginx 的 499
+	errCodeCancelled: 499,
+}
+
+// errorCode 返回错误的错误码，错误链中第一个实现 ErrorCode 的错误优先
+func errorCode(err error) string {
+	var coded interface{ ErrorCode() string }
+	var apiErr *openai.APIError
+	var requestErr *openai.RequestError
+	switch {
+	case errors.As(err, &coded):
+		return coded.ErrorCode()
+	case errors.As(err, &apiErr), errors.As(err, &requestErr):
+		return llms.ErrCodeLLMError
+	case errors.Is(err, context.DeadlineExceeded):
+		return errCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return errCodeCancelled
+	}
+	return errCodeInternal
+}
+
+// errorStatus 返回错误对应的 HTTP 状态码和错误码，未识别的错误为 500 internal_error
+func errorStatus(err error) (int, string) {
+	code := errorCode(err)
+	if status, ok := errorCodeStatus[code]; ok {
+		return status, code
+	}
+	return http.StatusInternalServerError, code
+}
+
+// respondError 按错误类型返回状态码，error_code 为机器可读的错误码，message 为错误说明
+func respondError(c *gin.Context, message string, err error) {
+	status, code := errorStatus(err)
+	c.JSON(status, gin.H{"error": message, "error_code": code})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/evaluation"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/prompts"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// EvaluationRequest 模型评测请求：同一问题分别在基准模型和候选模型上执行
+type EvaluationRequest struct {
+	Question  string          `json:"question" binding:"required"`
+	Cluster   string          `json:"cluster"`
+	Baseline  EvaluationModel `json:"baseline"`
+	Candidate EvaluationModel `json:"candidate"`
+}
+
+// EvaluationModel 参与评测的模型，provider 和 baseUrl 的含义与 Execute 请求相同
+type EvaluationModel struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model" binding:"required"`
+	BaseUrl  string `json:"baseUrl"`
+}
+
+// label 评测记录中的模型名称，指定了服务商时为 provider/model
+func (m EvaluationModel) label() string {
+	if m.Provider == "" {
+		return m.Model
+	}
+	return m.Provider + "/" + m.Model
+}
+
+// CreateEvaluation 在两个模型上并行执行同一问题，保存两次执行的审计记录并返回工具选择、迭代次数和回答的对比
+// 需要审批的变更命令不会执行，该模型的结果记为失败
+func CreateEvaluation(c *gin.Context) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("evaluation_total")()
+	logger := middleware.ContextLogger(c)
+
+	var req EvaluationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+	apiKey := llmAPIKey(c)
+	req.Baseline.BaseUrl = llmBaseURL(c, apiKey, req.Baseline.BaseUrl)
+	req.Candidate.BaseUrl = llmBaseURL(c, apiKey, req.Candidate.BaseUrl)
+	for _, m := range []EvaluationModel{req.Baseline, req.Candidate} {
+		if apiKey == "" && llms.RequiresAPIKey(m.Provider) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
+			return
+		}
+		if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), m.Provider, m.Model, nil); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var kubeContext string
+	if req.Cluster != "" && req.Cluster != "default" {
+		resolution, err := tools.ResolveCluster(req.Cluster)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if resolution.NeedsConfirmation {
+			c.JSON(http.StatusOK, gin.H{
+				"status":               "needs_confirmation",
+				"message":              fmt.Sprintf("无法确定集群 %q，请从候选集群中确认后重新提交", req.Cluster),
+				"cluster_confirmation": resolution,
+			})
+			return
+		}
+		kubeContext = resolution.Candidate
+	}
+
+	// 两个模型使用相同的系统提示
+	question := strings.TrimSpace(req.Question)
+	prompt := prompts.Get(c.Request.Context(), "execute", prompts.Builtin("execute"))
+	vars := prompts.NewVars(prompts.Options{UserRole: userRole(c), Cluster: kubeContext, Question: question})
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: prompts.MustRender(prompt.Text, vars)},
+		{Role: openai.ChatMessageRoleUser, Content: question},
+	}
+
+	username := c.GetString("username")
+	ctx := tools.WithUser(c.Request.Context(), username)
+	ctx = tools.WithRole(ctx, userRole(c))
+	ctx = tools.WithTenant(ctx, tenantOf(c))
+	runs := make([]evaluation.Run, 2)
+	var wg sync.WaitGroup
+	for i, m := range []EvaluationModel{req.Baseline, req.Candidate} {
+		wg.Add(1)
+		go func(i int, m EvaluationModel) {
+			defer wg.Done()
+			record := &audit.Interaction{
+				ID:            audit.NewInteractionID(),
+				Username:      username,
+				Model:         m.Model,
+				Cluster:       kubeContext,
+				Question:      question,
+				CreatedAt:     time.Now(),
+				PromptName:    prompt.Name,
+				PromptVersion: prompt.Version,
+				PromptHash:    prompt.Hash,
+			}
+			runs[i] = runEvaluation(ctx, logger, record, m, messages, kubeContext, apiKey)
+			audit.RecordContext(ctx, record)
+		}(i, m)
+	}
+	wg.Wait()
+
+	result := evaluation.Result{Baseline: runs[0], Candidate: runs[1]}
+	result.Comparison = evaluation.Compare(result.Baseline, result.Candidate)
+	evaluationID := audit.NewInteractionID()
+	stored := false
+	if store := audit.GetStore(); store != nil {
+		data, _ := json.Marshal(result)
+		err := store.SaveEvaluation(c.Request.Context(), &audit.Evaluation{
+			ID:                     evaluationID,
+			Username:               username,
+			Question:               question,
+			Cluster:                kubeContext,
+			BaselineModel:          req.Baseline.label(),
+			CandidateModel:         req.Candidate.label(),
+			BaselineInteractionID:  result.Baseline.InteractionID,
+			CandidateInteractionID: result.Candidate.InteractionID,
+			Result:                 data,
+			CreatedAt:              time.Now(),
+		})
+		if err != nil {
+			logger.Warn("保存评测记录失败", zap.String("evaluation_id", evaluationID), zap.Error(err))
+		}
+		stored = err == nil
+	}
+
+	logger.Info("模型评测完成",
+		zap.String("evaluation_id", evaluationID),
+		zap.String("baseline", req.Baseline.label()),
+		zap.String("candidate", req.Candidate.label()),
+		zap.Bool("same_tool_sequence", result.Comparison.SameToolSequence),
+		zap.Float64("answer_similarity", result.Comparison.AnswerSimilarity),
+	)
+	responseData := gin.H{
+		"evaluation_id": evaluationID,
+		"question":      question,
+		"baseline":      result.Baseline,
+		"candidate":     result.Candidate,
+		"comparison":    result.Comparison,
+		"stored":        stored,
+		"status":        "success",
+	}
+	switch {
+	case result.Baseline.Status != evaluation.StatusSuccess && result.Candidate.Status != evaluation.StatusSuccess:
+		responseData["status"] = "error"
+		c.JSON(http.StatusInternalServerError, responseData)
+		return
+	case !result.Comparison.BothSucceeded:
+		responseData["status"] = "partial"
+	}
+	c.JSON(http.StatusOK, responseData)
+}
+
+// runEvaluation 使用指定模型执行问题，结果写入审计记录
+// 每个模型使用独立的重试预算、工具结果缓存和 token 预算，互不影响
+func runEvaluation(ctx context.Context, logger *zap.Logger, record *audit.Interaction, m EvaluationModel, messages []openai.ChatCompletionMessage, kubeContext, apiKey string) evaluation.Run {
+	ctx, _ = tools.WithRetryBudget(ctx)
+	ctx, _ = tools.WithResultCache(ctx)
+	ctx, receiptLog := tools.WithReceipts(ctx, record.ID)
+	ctx = llms.WithProvider(ctx, m.Provider)
+	ctx, tokenBudget := llms.WithTokenBudget(ctx, record.Username)
+	if kubeContext != "" {
+		ctx = tools.WithKubeContext(ctx, kubeContext)
+	}
+	ctx = utils.WithLogger(ctx, logger.With(zap.String(utils.LogFieldModel, m.Model)))
+	ctx = assistants.WithEvents(ctx, record.ID, auditToolCalls(record))
+
+	run := evaluation.Run{Provider: m.Provider, Model: m.Model, InteractionID: record.ID,
+		Status: evaluation.StatusSuccess, ToolCalls: []evaluation.ToolCall{}}
+	response, _, err := assistants.AssistantWithContext(ctx, m.Model, append([]openai.ChatCompletionMessage(nil), messages...),
+		8192, true, true, defaultMaxIterations, apiKey, m.BaseUrl)
+	record.DurationMs = time.Since(record.CreatedAt).Milliseconds()
+
+	for _, call := range record.ToolCalls {
+		run.ToolCalls = append(run.ToolCalls, evaluation.ToolCall{Name: call.Name, Input: call.Input})
+	}
+	record.Receipts = receiptLog.List()
+	usage := tokenBudget.Usage()
+	record.PromptTokens, record.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+	record.LLMCalls = auditLLMCalls(usage)
+	run.Iterations = len(usage.Iterations)
+	run.PromptTokens, run.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+	run.DurationMs = record.DurationMs
+
+	var approvalErr *tools.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		err = fmt.Errorf("模型提出了需要审批的变更命令，评测中不会执行: %s", approvalErr.Command)
+	}
+	if err != nil {
+		logger.Warn("评测模型执行失败", zap.String("model", m.label()), zap.Error(err))
+		run.Status, run.Error, run.ErrorCode = evaluation.StatusError, err.Error(), errorCode(err)
+		record.Status, record.Error = audit.StatusError, err.Error()
+		return run
+	}
+	run.Answer = parseFinalAnswer(m.Model, response)
+	record.Status, record.Answer = audit.StatusSuccess, run.Answer
+	return run
+}
+
+// auditLLMCalls 将 token 预算记录的每轮 LLM 调用转换为审计记录
+func auditLLMCalls(usage llms.TokenUsage) []audit.LLMCall {
+	var calls []audit.LLMCall
+	for _, call := range usage.Iterations {
+		calls = append(calls, audit.LLMCall{
+			Seq:              call.Iteration,
+			Model:            call.Model,
+			PromptTokens:     call.PromptTokens,
+			CompletionTokens: call.CompletionTokens,
+			LatencyMs:        call.LatencyMs,
+			FinishReason:     call.FinishReason,
+			CreatedAt:        call.StartedAt,
+		})
+	}
+	return calls
+}
+
+// ListEvaluations 按时间倒序列出评测记录，model 按基准或候选模型过滤
+func ListEvaluations(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+	evaluations, err := store.ListEvaluations(c.Request.Context(), c.Query("model"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if evaluations == nil {
+		evaluations = []*audit.Evaluation{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"evaluations": evaluations,
+		"total":       len(evaluations),
+		"status":      "success",
+	})
+}
+
+// GetEvaluation 获取评测记录，两次执行的工具输出可通过审计接口按 interaction_id 查询
+func GetEvaluation(c *gin.Context) {
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+	e, err := store.GetEvaluation(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, audit.ErrEvaluationNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Evaluation not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"evaluation": e,
+		"status":     "success",
+	})
+}
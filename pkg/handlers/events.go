@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"go.uber.org/zap"
+)
+
+// sseEventResult ExecuteStream 最后推送的事件，data 为 Execute 的响应
+const sseEventResult = "result"
+
+// auditToolCalls 返回把 tool_completed 事件写入审计记录的订阅者，工具调用按完成顺序编号
+// 跨集群查询时各集群并发执行，追加时加锁
+func auditToolCalls(record *audit.Interaction) assistants.Subscriber {
+	var mu sync.Mutex
+	return func(e assistants.Event) {
+		if e.Type != assistants.EventToolCompleted {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		record.ToolCalls = append(record.ToolCalls, audit.ToolCall{
+			Seq:         len(record.ToolCalls) + 1,
+			Name:        e.Tool,
+			Input:       e.Input,
+			Observation: e.Observation,
+			DurationMs:  e.DurationMs,
+		})
+	}
+}
+
+// eventStream 以 SSE 推送 Assistant 事件，并缓存 Execute 写出的响应，执行结束后作为 result 事件推送
+type eventStream struct {
+	gin.ResponseWriter
+	mu      sync.Mutex
+	started bool
+	status  int
+	body    bytes.Buffer
+}
+
+func (s *eventStream) WriteHeader(code int) {
+	s.status = code
+}
+
+func (s *eventStream) WriteHeaderNow() {}
+
+func (s *eventStream) Write(data []byte) (int, error) {
+	return s.body.Write(data)
+}
+
+func (s *eventStream) WriteString(str string) (int, error) {
+	return s.body.WriteString(str)
+}
+
+func (s *eventStream) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// send 推送一个事件，第一个事件之前写出 SSE 响应头；跨集群查询时各集群并发调用
+func (s *eventStream) send(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Execute 写响应时会把 Content-Type 改为 application/json，每次推送前恢复，统一响应格式中间件据此直接写出
+	header := s.ResponseWriter.Header()
+	header.Set("Content-Type", "text/event-stream")
+	if !s.started {
+		header.Set("Cache-Control", "no-cache")
+		header.Set("X-Accel-Buffering", "no")
+		s.ResponseWriter.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+	if _, err := fmt.Fprintf(s.ResponseWriter, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	s.ResponseWriter.Flush()
+	return nil
+}
+
+// ExecuteStream 与 Execute 相同，执行过程中以 SSE（text/event-stream）推送 Assistant 事件：
+// event 为事件类型（iteration_started、tool_selected、tool_completed、parse_failed、final_answer），data 为事件的 JSON；
+// 结束时推送 result 事件，data 为 {"status_code": Execute 的状态码, "response": Execute 的响应}
+// 没有执行 Assistant 就返回的请求（参数错误、命中回答缓存等）按普通 JSON 响应返回
+func ExecuteStream(c *gin.Context) {
+	logger := middleware.ContextLogger(c)
+	original := c.Writer
+	stream := &eventStream{ResponseWriter: original}
+	c.Writer = stream
+	c.Request = c.Request.WithContext(assistants.WithEvents(c.Request.Context(), "", func(e assistants.Event) {
+		if err := stream.send(e.Type, e); err != nil {
+			logger.Debug("推送 Assistant 事件失败", zap.String("type", e.Type), zap.Error(err))
+		}
+	}))
+	Execute(c)
+	c.Writer = original
+
+	if !stream.started {
+		original.WriteHeader(stream.Status())
+		original.Write(stream.body.Bytes())
+		return
+	}
+	response := json.RawMessage(stream.body.Bytes())
+	if !json.Valid(response) {
+		response, _ = json.Marshal(stream.body.String())
+	}
+	if err := stream.send(sseEventResult, gin.H{"status_code": stream.Status(), "response": response}); err != nil {
+		logger.Debug("推送执行结果失败", zap.Error(err))
+	}
+}
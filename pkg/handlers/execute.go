@@ -1,15 +1,31 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/sashabaranov/go-openai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/myysophia/OpsAgent/pkg/answercache"
 	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/charts"
+	"github.com/myysophia/OpsAgent/pkg/handoff"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/memory"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/prompts"
+	"github.com/myysophia/OpsAgent/pkg/sessions"
+	"github.com/myysophia/OpsAgent/pkg/tools"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 )
 
@@ -21,7 +37,13 @@ type ExecuteRequest struct {
 	BaseUrl        string   `json:"baseUrl"`
 	CurrentModel   string   `json:"currentModel"`
 	Cluster        string   `json:"cluster"`
+	Clusters       []string `json:"clusters"` // 跨集群问题的目标集群，每个集群单独回答
 	SelectedModels []string `json:"selectedModels"`
+	ConversationID string   `json:"conversationId"`
+	Language       string   `json:"language"` // 最终回答语言（zh/en），为空时使用配置 answer.language
+	NoCache        bool     `json:"noCache"`  // 不使用回答缓存，也可以通过 Cache-Control: no-cache 请求头指定
+	// 问题中的别名对应多个服务时用户选择的服务：别名 → 候选服务引用（needs_confirmation 响应中的 candidates）
+	Services map[string]string `json:"services"`
 }
 
 // AIResponse AI 响应结构
@@ -41,63 +63,10 @@ type ToolHistory struct {
 	Name        string `json:"name"`
 	Input       string `json:"input"`
 	Observation string `json:"observation"`
+	Idempotency string `json:"idempotency,omitempty"` // pure-read、cacheable 或 side-effecting
+	Cached      bool   `json:"cached,omitempty"`      // 本次交互内重复的调用，结果来自缓存
 }
 
-const executeSystemPrompt_cn = `您是Kubernetes和云原生网络的技术专家，您的任务是遵循链式思维方法，确保彻底性和准确性，同时遵守约束。
-
-可用工具：
-- kubectl：用于执行 Kubernetes 命令。必须使用正确语法（例如 'kubectl get pods' 而非 'kubectl get pod'），避免使用 -o json/yaml 全量输出。
-- python：用于复杂逻辑或调用 Kubernetes Python SDK。输入：Python 脚本，输出：通过 print(...) 返回。
-- trivy：用于扫描镜像漏洞。输入：镜像名称，输出：漏洞报告。
-- jq：用于处理 JSON 数据。输入：有效的 jq 表达式，始终使用 'test()' 进行名称匹配。
-
-您采取的步骤如下：
-1. 问题识别：清楚定义问题，描述目标。
-2. 诊断命令：根据问题选择工具
-3. 输出解释：分析工具输出，描述结果。如果输出为空，必须明确告知用户未找到相关信息。
-4. 故障排除策略：根据输出制定策略。
-5. 可行解决方案：提出解决方案，确保命令准确。
-
-严格约束：
-- 避免使用 -o json/yaml 全量输出，优先使用 jsonpath 、--go-template、 custom-columns 进行查询,注意用户输入都是模糊的,筛选时需要模糊匹配。
-- 使用 --no-headers 选项减少不必要的输出。
-- jq 表达式中，名称匹配必须使用 'test()'，避免使用 '=='。
-- 命令参数涉及特殊字符（如 []、()、"）时，优先使用单引号 ' 包裹，避免 Shell 解析错误。
-- 避免在 zsh 中使用未转义的双引号（如 \"），防止触发模式匹配。
-- 当使用awk时使用单引号（如 '{print $1}'），避免双引号转义导致语法错误。
-
-重要提示：始终使用以下 JSON 格式返回响应：
-{
-  "question": "<用户的输入问题>",
-  "thought": "<您的分析和思考过程>",
-  "action": {
-    "name": "<工具名称>",
-    "input": "<工具输入>"
-  },
-  "observation": "",
-  "final_answer": "<最终答案,只有在完成所有流程且无需采取任何行动后才能确定,请使用markdown格式输出>"
-}
-
-注意：
-1. observation字段必须保持为空字符串，不要填写任何内容，系统会自动填充
-2. final_answer必须是有意义的回答，不能包含模板文本或占位符
-3. 如果需要执行工具，填写action字段；如果已经得到答案，可以直接在final_answer中回复
-4. 禁止在任何字段中使用类似"<工具执行结果，由外部填充>"这样的模板文本
-5. 当工具执行结果为空时，不要直接返回"未找到相关信息"，而是：
-   - 分析可能的原因
-   - 提供改进建议
-   - 询问用户是否需要进一步澄清
-
-当结果为空时，应该这样处理：
-1. 首先尝试使用更宽松的查询,但是总应该避免全量输出(-ojson/yaml)，例如使用 jsonpath 或 custom-columns 来获取特定字段。
-2. 如果仍然为空，在 final_answer 中提供：
-   - 当前查询条件说明
-   - 可能的原因（如命名空间问题、权限问题等）
-   - 建议的解决方案
-   - 是否需要用户提供更多信息
-目标：
-在 Kubernetes 和云原生网络领域内识别问题根本原因，提供清晰、可行的解决方案，同时保持诊断和故障排除的运营约束。`
-
 const (
 	defaultMaxIterations = 5
 )
@@ -109,8 +78,8 @@ func Execute(c *gin.Context) {
 	// 开始整体执行计时
 	defer perfStats.TraceFunc("execute_total")()
 
-	// 获取 logger
-	logger := utils.GetLogger()
+	// 获取请求级 logger
+	logger := middleware.ContextLogger(c)
 
 	// 获取是否显示思考过程的配置
 	// 首先尝试从URL参数获取
@@ -134,14 +103,6 @@ func Execute(c *gin.Context) {
 		zap.Bool("show-thought", showThought),
 	)
 
-	// 获取API Key
-	apiKey := c.GetHeader("X-API-Key")
-	if apiKey == "" {
-		logger.Error("缺少 API Key")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
-		return
-	}
-
 	// 解析请求体
 	var req ExecuteRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -152,6 +113,15 @@ func Execute(c *gin.Context) {
 		return
 	}
 
+	// 获取调用 LLM 的 API Key，本地 Ollama 等服务商不需要
+	apiKey := llmAPIKey(c)
+	req.BaseUrl = llmBaseURL(c, apiKey, req.BaseUrl)
+	if apiKey == "" && llms.RequiresAPIKey(req.Provider) {
+		logger.Error("缺少 API Key")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
+		return
+	}
+
 	// 记录请求信息
 	logger.Debug("Execute 接口收到请求",
 		zap.String("instructions", req.Instructions),
@@ -164,11 +134,86 @@ func Execute(c *gin.Context) {
 		zap.String("apiKey", "***"),
 	)
 
+	// 回答语言与系统提示语言相互独立，不一致时要求模型按回答语言输出，必要时再翻译
+	answerLanguage, err := llms.AnswerLanguage(req.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 确定使用的模型
 	executeModel := req.CurrentModel
 	if executeModel == "" {
 		executeModel = "gpt-4"
 	}
+	logger = middleware.WithLogFields(c,
+		zap.String(utils.LogFieldModel, executeModel),
+		zap.String(utils.LogFieldCluster, req.Cluster),
+	)
+	if req.ConversationID != "" && c.GetHeader("X-Session-ID") == "" {
+		logger = middleware.WithLogFields(c, zap.String(utils.LogFieldSession, req.ConversationID))
+	}
+
+	// 校验租户允许使用的模型
+	if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), req.Provider, executeModel, req.SelectedModels); err != nil {
+		logger.Warn("模型不在租户允许列表中",
+			zap.String("tenant", tenantOf(c)),
+			zap.String("provider", req.Provider),
+			zap.Strings("selectedModels", req.SelectedModels),
+			zap.Error(err),
+		)
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 解析目标集群，置信度不足时返回候选项由用户确认，避免查询发往错误的集群
+	targets := executeTargets(req)
+	var kubeContexts []string
+	var confirmations []*tools.ClusterResolution
+	for _, target := range targets {
+		resolution, err := tools.ResolveCluster(target)
+		if err != nil {
+			logger.Warn("解析集群失败",
+				zap.String("cluster", target),
+				zap.Error(err),
+			)
+			if len(targets) > 1 {
+				// 跨集群查询时按原名称查询，失败会体现在该集群的回答中
+				kubeContexts = append(kubeContexts, target)
+			}
+			continue
+		}
+		if resolution.NeedsConfirmation {
+			logger.Info("集群解析置信度不足，等待用户确认",
+				zap.String("cluster", target),
+				zap.String("candidate", resolution.Candidate),
+				zap.Float64("confidence", resolution.Confidence),
+				zap.Any("alternatives", resolution.Alternatives),
+			)
+			confirmations = append(confirmations, resolution)
+			continue
+		}
+		kubeContexts = append(kubeContexts, resolution.Candidate)
+	}
+	if len(confirmations) > 0 {
+		responseData := gin.H{"status": "needs_confirmation"}
+		if len(targets) == 1 {
+			responseData["message"] = fmt.Sprintf("无法确定集群 %q，请从候选集群中确认后重新提交", targets[0])
+			responseData["cluster_confirmation"] = confirmations[0]
+		} else {
+			responseData["message"] = "部分集群无法确定，请从候选集群中确认后重新提交"
+			responseData["cluster_confirmations"] = confirmations
+		}
+		c.JSON(http.StatusOK, responseData)
+		return
+	}
+	var kubeContext string
+	if len(kubeContexts) == 1 {
+		kubeContext = kubeContexts[0]
+	}
+	if len(kubeContexts) > 0 {
+		logger = middleware.WithLogFields(c, zap.Strings("kube_context", kubeContexts))
+	}
 
 	// 构建执行指令
 	instructions := req.Instructions
@@ -180,68 +225,387 @@ func Execute(c *gin.Context) {
 	cleanInstructions := strings.TrimPrefix(instructions, "execute")
 	cleanInstructions = strings.TrimSpace(cleanInstructions)
 	logger.Debug("Execute 执行参数",
-		zap.String("model", executeModel),
 		zap.String("instructions", cleanInstructions),
 		zap.String("baseUrl", req.BaseUrl),
-		zap.String("cluster", req.Cluster),
 	)
 
-	// 构建 OpenAI 消息
-	messages := []openai.ChatCompletionMessage{
-		{
+	// 问题中的服务别名对应多个服务时，置信度足够则自动选择，否则返回候选服务由用户选择，避免把多个服务混在一个回答中
+	serviceNote, serviceConfirmations, err := resolveServiceAliases(logger, cleanInstructions, kubeContext, req.Services)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(serviceConfirmations) > 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"status":                "needs_confirmation",
+			"message":               fmt.Sprintf("%q 对应多个服务，请选择后重新提交（请求中的 services 字段）", serviceConfirmations[0].Alias),
+			"service_confirmations": serviceConfirmations,
+		})
+		return
+	}
+
+	// 审计记录，在请求结束时异步写入
+	startTime := time.Now()
+	record := &audit.Interaction{
+		ID:        audit.NewInteractionID(),
+		Username:  c.GetString("username"),
+		Model:     executeModel,
+		Cluster:   req.Cluster,
+		Question:  cleanInstructions,
+		Status:    audit.StatusSuccess,
+		CreatedAt: startTime,
+	}
+	if len(kubeContexts) > 0 {
+		record.Cluster = strings.Join(kubeContexts, ",")
+	}
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String(utils.LogFieldInteraction, record.ID))
+	// 采样本次交互中 OpsAgent 自身的资源消耗，用于定位导致资源尖峰的问题
+	sampler := utils.StartResourceSampler("execute")
+	// 本次请求的 token 预算，记录每轮 LLM 调用的用量
+	var tokenBudget *llms.TokenBudget
+	// 本次交互中工具执行的签名回执
+	var receiptLog *tools.ReceiptLog
+	defer func() {
+		record.DurationMs = time.Since(startTime).Milliseconds()
+		if tokenBudget != nil {
+			usage := tokenBudget.Usage()
+			record.PromptTokens, record.CompletionTokens = usage.PromptTokens, usage.CompletionTokens
+			record.LLMCalls = auditLLMCalls(usage)
+		}
+		record.Receipts = receiptLog.List()
+		sampler.Stop(record.ID, record.Question)
+		audit.RecordContext(c.Request.Context(), record)
+	}()
+	logger = middleware.WithLogFields(c, zap.String(utils.LogFieldInteraction, record.ID))
+
+	// 会话历史的向量化使用与对话相同的 LLM 配置
+	// 其他服务商没有兼容的向量化接口，使用 llm.providers.openai 的配置，未配置时只保留最近的会话历史
+	var embedder memory.Embedder
+	conversationKey := memory.ConversationKey{User: c.GetString("username"), Tenant: tenantOf(c), ID: req.ConversationID}
+	if req.ConversationID != "" {
+		if err := memory.GetConversations().Open(conversationKey); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		embedderKey, embedderURL := apiKey, req.BaseUrl
+		if llms.NormalizeProvider(req.Provider) != llms.ProviderOpenAI {
+			config := utils.GetConfig()
+			embedderKey, embedderURL = config.GetString("llm.providers.openai.api_key"), config.GetString("llm.providers.openai.base_url")
+		}
+		client, err := llms.NewOpenAIClient(embedderKey, embedderURL)
+		switch {
+		case err == nil:
+			embedder = client
+		case llms.NormalizeProvider(req.Provider) == llms.ProviderOpenAI:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("执行失败: %v", err)})
+			return
+		default:
+			logger.Warn("未配置向量化服务，会话只保留最近的历史", zap.String("provider", req.Provider))
+		}
+		defer func() {
+			if record.Status == audit.StatusSuccess && record.Answer != "" {
+				go func() {
+					if err := memory.GetConversations().Record(context.Background(), embedder, conversationKey, cleanInstructions, record.Answer); err != nil {
+						logger.Warn("记录会话历史失败", zap.String("conversation_id", req.ConversationID), zap.Error(err))
+					}
+				}()
+			}
+		}()
+	}
+
+	// 未指定 conversationId 时按 session_id Cookie 关联会话，追问（例如"那 EU 集群呢？"）时携带最近几轮问答
+	var session *sessions.Session
+	if req.ConversationID == "" && executeHistoryTurns() > 0 {
+		session = executeSession(c, req.Provider, executeModel, kubeContext)
+		defer session.BeginTurn()()
+		if c.GetHeader("X-Session-ID") == "" {
+			logger = middleware.WithLogFields(c, zap.String(utils.LogFieldSession, session.ID))
+		}
+		defer func() {
+			if record.Status == audit.StatusSuccess && record.Answer != "" {
+				session.AddTurn(cleanInstructions, record.Answer)
+			}
+		}()
+	}
+
+	// 相同的只读问题在有效期内直接返回最近的回答，不再调用 LLM 和查询集群
+	// 选择的服务、服务商和模型不同时回答不同，一并作为缓存键
+	cacheKey, useCache := answerCacheKey(c, req, session, strings.TrimSpace(cleanInstructions+"\n"+serviceNote), kubeContexts, answerLanguage, executeModel)
+	if useCache {
+		if entry, ok := answercache.Default().Get(c.Request.Context(), cacheKey); ok {
+			perfStats.IncrCounter("answer_cache_hit")
+			logger.Info("命中回答缓存",
+				zap.String("source_interaction", entry.InteractionID),
+				zap.Time("cached_at", entry.CreatedAt),
+			)
+			record.Answer = entry.Answer
+			record.UsageSource = audit.UsageCached
+			responseData := gin.H{
+				"message":               entry.Answer,
+				"status":                "success",
+				"cached":                true,
+				"cached_at":             entry.CreatedAt,
+				"source_interaction_id": entry.InteractionID,
+				"interaction_id":        record.ID,
+			}
+			if answerFooterEnabled() {
+				provenance := newProvenance(record.Cluster, entry.Model, record.ID, entry.CreatedAt, true)
+				responseData["message"] = appendAnswerFooter(entry.Answer, provenance)
+				responseData["provenance"] = provenance
+			}
+			if answerLanguage != "" {
+				responseData["language"] = answerLanguage
+			}
+			if session != nil {
+				responseData["session_id"] = session.ID
+			}
+			c.JSON(http.StatusOK, responseData)
+			return
+		}
+		perfStats.IncrCounter("answer_cache_miss")
+	}
+
+	// 工具调用上下文，按目标（集群、外部 API）限制不可达时的重试次数
+	ctx := tools.WithUser(c.Request.Context(), c.GetString("username"))
+	ctx = tools.WithRole(ctx, string(middleware.CurrentRole(c)))
+	ctx = tools.WithTenant(ctx, tenantOf(c))
+	ctx, budget := tools.WithRetryBudget(ctx)
+	// 同一交互内重复的只读查询使用缓存结果
+	ctx, _ = tools.WithResultCache(ctx)
+	ctx, receiptLog = tools.WithReceipts(ctx, record.ID)
+	// 按请求的 provider 选择 LLM 服务商（OpenAI 兼容、Anthropic、Gemini、Ollama）
+	ctx = llms.WithProvider(ctx, req.Provider)
+	ctx, tokenBudget = llms.WithTokenBudget(ctx, c.GetString("username"))
+	// 用户当天的 token 预算已用尽时直接拒绝，不再调用 LLM
+	if err := tokenBudget.Check(0); err != nil {
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "error_code": errorCode(err), "token_usage": tokenBudget.Usage()})
+		return
+	}
+	// 工具调用由 Assistant 的 tool_completed 事件写入审计记录，跨集群查询时各集群的调用按完成顺序编号
+	dataAt := newDataClock(time.Time{})
+	ctx = assistants.WithEvents(ctx, record.ID, auditToolCalls(record), dataAt.subscriber())
+
+	// 从工具输出中提取的图表数据，便于前端直接绘图
+	var chartData []charts.Chart
+	// 实际执行过的命令，集群相关参数已替换为占位符，便于用户复制后手动验证
+	var commands []tools.CopyableCommand
+	// 单集群回答的工具调用历史，启用回答审阅时作为审阅依据
+	var reviewHistory []ToolHistory
+	reviewable := false
+
+	// respond 返回成功响应并记录最终答案
+	respond := func(responseData gin.H) {
+		if message, ok := responseData["message"].(string); ok {
+			if reviewable && responseData["status"] == "success" && answerReviewEnabled() {
+				revised, ok := reviewAnswer(logger, record, cleanInstructions, message, reviewHistory, req.Provider, executeModel, apiKey, req.BaseUrl)
+				if ok {
+					message = revised
+					responseData["message"] = message
+				}
+				responseData["revised"] = ok
+			}
+			// 置信度低时不交付草稿，转交值班人员
+			if reviewable && responseData["status"] == "success" && handoff.Enabled() {
+				if bundle := handOffLowConfidence(ctx, logger, record, message, reviewHistory, len(budget.Failures())); bundle != nil {
+					message = handoffNotice(bundle)
+					responseData["status"] = audit.StatusHandedOff
+					responseData["draft"] = bundle.Draft
+					responseData["handoff"] = bundle
+					responseData["message"] = message
+				}
+			}
+			if answerLanguage != "" {
+				responseData["language"] = answerLanguage
+				if translated, ok := translateAnswer(logger, message, answerLanguage, req.Provider, executeModel, apiKey, req.BaseUrl); ok {
+					message = translated
+					responseData["message"] = message
+					responseData["translated"] = true
+				}
+			}
+			record.Answer = message
+			if useCache && reviewable && cacheableAnswer(responseData, reviewHistory) {
+				answercache.Default().Set(c.Request.Context(), cacheKey, answercache.Entry{
+					Answer:        message,
+					InteractionID: record.ID,
+					Model:         executeModel,
+				})
+			}
+			// 来源说明只附加在返回的回答中，审计、会话历史和缓存中保存原始回答
+			if answerFooterEnabled() && message != "" {
+				provenance := newProvenance(record.Cluster, executeModel, record.ID, dataAt.Time(), false)
+				responseData["message"] = appendAnswerFooter(message, provenance)
+				responseData["provenance"] = provenance
+			}
+		}
+		responseData["interaction_id"] = record.ID
+		responseData["token_usage"] = tokenBudget.Usage()
+		if len(chartData) > 0 {
+			responseData["chart_data"] = chartData
+		}
+		if len(commands) > 0 {
+			responseData["commands"] = commands
+		}
+		if req.ConversationID != "" {
+			responseData["conversation_id"] = req.ConversationID
+		}
+		if session != nil {
+			responseData["session_id"] = session.ID
+		}
+		// 部分目标不可达时明确返回各目标的错误，而不是只体现在回答中
+		if failures := budget.Failures(); len(failures) > 0 {
+			responseData["target_errors"] = failures
+		}
+		c.JSON(http.StatusOK, responseData)
+	}
+
+	// 构建 OpenAI 消息，长会话只携带最近及与问题相关的历史轮次
+	// 配置了远程或本地文件提示时优先使用，不可用时回退到内置提示
+	prompt := prompts.Get(c.Request.Context(), "execute", prompts.Builtin("execute"))
+	promptTemplate := prompt.Text
+	record.PromptName, record.PromptVersion, record.PromptHash = prompt.Name, prompt.Version, prompt.Hash
+	logger = middleware.WithLogFields(c, zap.String("prompt_version", prompt.Version))
+	systemPrompt := func(cluster string) openai.ChatCompletionMessage {
+		vars := prompts.NewVars(prompts.Options{
+			UserRole: userRole(c),
+			Cluster:  cluster,
+			Question: cleanInstructions,
+		})
+		content := prompts.MustRender(promptTemplate, vars)
+		if serviceNote != "" {
+			content += "\n\n" + serviceNote
+		}
+		recordSystemPrompt(logger, prompt.Name, vars, content, executeModel)
+		if answerLanguage != "" && llms.DetectLanguage(content) != answerLanguage {
+			content += "\n\n" + llms.LanguageInstruction(answerLanguage)
+		}
+		return openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: executeSystemPrompt_cn,
-		},
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: cleanInstructions,
-		},
+			Content: content,
+		}
+	}
+	messages := []openai.ChatCompletionMessage{systemPrompt(kubeContext)}
+	if req.ConversationID != "" {
+		history, retrieval, err := memory.GetConversations().History(c.Request.Context(), embedder, conversationKey, cleanInstructions)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+			return
+		}
+		messages = append(messages, history...)
+		if retrieval != nil {
+			record.RAGCalls = append(record.RAGCalls, audit.RAGCall{
+				Seq:          len(record.RAGCalls) + 1,
+				Kind:         "conversation_history",
+				Query:        retrieval.Query,
+				Context:      retrieval.Context,
+				LatencyMs:    retrieval.Latency.Milliseconds(),
+				PromptTokens: retrieval.PromptTokens,
+				TotalTokens:  retrieval.TotalTokens,
+				Error:        retrieval.Error,
+				CreatedAt:    time.Now(),
+			})
+		}
+	} else if session != nil {
+		messages = append(messages, session.Recent(executeHistoryTurns())...)
+	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: cleanInstructions,
+	})
+
+	// 跨集群问题：每个集群单独执行，分别返回回答，单个集群失败不影响其他集群
+	if len(kubeContexts) > 1 {
+		answers := runClusterQueries(ctx, kubeContexts, func(cluster string) []openai.ChatCompletionMessage {
+			return append([]openai.ChatCompletionMessage{systemPrompt(cluster)}, messages[1:]...)
+		}, executeModel, apiKey, req.BaseUrl)
+
+		var failed int
+		for _, answer := range answers {
+			if answer.Error != "" {
+				failed++
+			}
+			if showThought {
+				answer.ToolsHistory = answer.toolsHistory
+			}
+		}
+
+		responseData := gin.H{
+			"message": mergeClusterAnswers(answers),
+			"answers": answers,
+			"status":  "success",
+		}
+		switch {
+		case failed == len(answers):
+			record.Status = audit.StatusError
+			record.Error = "所有集群查询均失败"
+			record.Answer = responseData["message"].(string)
+			responseData["status"] = "error"
+			responseData["interaction_id"] = record.ID
+			c.JSON(http.StatusInternalServerError, responseData)
+			return
+		case failed > 0:
+			responseData["status"] = "partial"
+		}
+		respond(responseData)
+		return
 	}
 
 	// 开始 AI 助手执行计时
 	perfStats.StartTimer("execute_assistant")
 
 	// 调用 AI 助手
-	response, chatHistory, err := assistants.AssistantWithConfig(executeModel, messages, 8192, true, true, defaultMaxIterations, apiKey, req.BaseUrl)
+	if kubeContext != "" {
+		ctx = tools.WithKubeContext(ctx, kubeContext)
+	}
+	response, chatHistory, err := assistants.AssistantWithContext(ctx, executeModel, messages, 8192, true, true, defaultMaxIterations, apiKey, req.BaseUrl)
 
 	// 停止 AI 助手执行计时
-	assistantDuration := perfStats.StopTimer("execute_assistant")
+	assistantDuration := perfStats.StopSpanTimer(ctx, "execute_assistant")
 	logger.Info("AI助手执行完成",
 		zap.Duration("duration", assistantDuration),
 	)
 
+	// 助手提出的变更命令需要人工审批，对话暂停并返回审批 ID
+	var approvalErr *tools.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		requestApproval(c, record, approvalErr, pausedConversation{
+			Question:    cleanInstructions,
+			Provider:    req.Provider,
+			Model:       executeModel,
+			BaseURL:     req.BaseUrl,
+			KubeContext: kubeContext,
+			ChatHistory: chatHistory,
+		})
+		return
+	}
+
+	// token 预算用尽时返回 429 和已消耗的用量
+	var budgetErr *llms.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		record.Status = audit.StatusError
+		record.Error = budgetErr.Error()
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": budgetErr.Error(), "error_code": budgetErr.ErrorCode(), "token_usage": tokenBudget.Usage()})
+		return
+	}
+
 	if err != nil {
 		logger.Error("Execute 执行失败",
+			zap.String("error_code", errorCode(err)),
 			zap.Error(err),
 		)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("执行失败: %v", err),
-		})
+		record.Status = audit.StatusError
+		record.Error = err.Error()
+		respondError(c, fmt.Sprintf("执行失败: %v", err), err)
 		return
 	}
 
 	// 提取工具使用历史
-	var toolsHistory []ToolHistory
-	for i := 0; i < len(chatHistory); i++ {
-		if chatHistory[i].Role == openai.ChatMessageRoleUser && i > 0 {
-			var toolPrompt map[string]interface{}
-			if err := json.Unmarshal([]byte(chatHistory[i].Content), &toolPrompt); err == nil {
-				if action, ok := toolPrompt["action"].(map[string]interface{}); ok {
-					name, _ := action["name"].(string)
-					input, _ := action["input"].(string)
-					observation, _ := toolPrompt["observation"].(string)
-
-					if name != "" && input != "" {
-						toolsHistory = append(toolsHistory, ToolHistory{
-							Name:        name,
-							Input:       input,
-							Observation: observation,
-						})
-					}
-				}
-			}
-		}
-	}
+	toolsHistory := extractToolsHistory(chatHistory)
+	reviewHistory, reviewable = toolsHistory, true
+
+	chartData = charts.Extract(chartInputs(toolsHistory))
+	commands = copyableCommands(toolsHistory, kubeContext != "")
 
 	// 开始响应解析计时
 	perfStats.StartTimer("execute_response_parse")
@@ -273,12 +637,13 @@ func Execute(c *gin.Context) {
 		observation, _ := utils.ExtractField(response, "observation")
 
 		if extractErr == nil && finalAnswer != "" {
+			utils.RecordParsePath(executeModel, utils.ParsePathExtractField)
 			logger.Debug("成功使用工具函数提取final_answer",
 				zap.String("final_answer", finalAnswer),
 				zap.String("thought", thought),
 			)
 
-			parseDuration := perfStats.StopTimer("execute_response_parse")
+			parseDuration := perfStats.StopSpanTimer(ctx, "execute_response_parse")
 			logger.Debug("响应解析完成（工具函数提取）",
 				zap.Duration("duration", parseDuration),
 			)
@@ -297,19 +662,20 @@ func Execute(c *gin.Context) {
 				responseData["tools_history"] = toolsHistory
 			}
 
-			c.JSON(http.StatusOK, responseData)
+			respond(responseData)
 			return
 		}
 
 		// 尝试清理 JSON 后解析
 		cleanedJSON := utils.CleanJSON(response)
 		if err2 := json.Unmarshal([]byte(cleanedJSON), &aiResp); err2 == nil && aiResp.FinalAnswer != "" {
+			utils.RecordParsePath(executeModel, utils.ParsePathCleanJSON)
 			logger.Debug("成功从清理后的JSON中提取final_answer",
 				zap.String("final_answer", aiResp.FinalAnswer),
 				zap.String("thought", aiResp.Thought),
 			)
 
-			parseDuration := perfStats.StopTimer("execute_response_parse")
+			parseDuration := perfStats.StopSpanTimer(ctx, "execute_response_parse")
 			logger.Debug("响应解析完成（清理JSON后解析）",
 				zap.Duration("duration", parseDuration),
 			)
@@ -328,7 +694,7 @@ func Execute(c *gin.Context) {
 				responseData["tools_history"] = toolsHistory
 			}
 
-			c.JSON(http.StatusOK, responseData)
+			respond(responseData)
 			return
 		}
 
@@ -336,11 +702,12 @@ func Execute(c *gin.Context) {
 		var genericResp map[string]interface{}
 		if err2 := json.Unmarshal([]byte(response), &genericResp); err2 == nil {
 			if finalAnswer, ok := genericResp["final_answer"].(string); ok && finalAnswer != "" {
+				utils.RecordParsePath(executeModel, utils.ParsePathGenericMap)
 				logger.Debug("成功从非标准JSON中提取final_answer",
 					zap.String("final_answer", finalAnswer),
 				)
 
-				parseDuration := perfStats.StopTimer("execute_response_parse")
+				parseDuration := perfStats.StopSpanTimer(ctx, "execute_response_parse")
 				logger.Debug("响应解析完成（非标准JSON提取）",
 					zap.Duration("duration", parseDuration),
 				)
@@ -378,12 +745,13 @@ func Execute(c *gin.Context) {
 					responseData["tools_history"] = toolsHistory
 				}
 
-				c.JSON(http.StatusOK, responseData)
+				respond(responseData)
 				return
 			}
 		}
 
-		parseDuration := perfStats.StopTimer("execute_response_parse")
+		utils.RecordParsePath(executeModel, utils.ParsePathRaw)
+		parseDuration := perfStats.StopSpanTimer(ctx, "execute_response_parse")
 		logger.Debug("所有解析方法均失败，返回原始响应",
 			zap.Duration("duration", parseDuration),
 		)
@@ -399,11 +767,12 @@ func Execute(c *gin.Context) {
 			responseData["tools_history"] = toolsHistory
 		}
 
-		c.JSON(http.StatusOK, responseData)
+		respond(responseData)
 		return
 	}
 
-	parseDuration := perfStats.StopTimer("execute_response_parse")
+	utils.RecordParsePath(executeModel, utils.ParsePathStandard)
+	parseDuration := perfStats.StopSpanTimer(ctx, "execute_response_parse")
 	logger.Debug("响应解析完成（标准格式）",
 		zap.Duration("duration", parseDuration),
 	)
@@ -423,7 +792,7 @@ func Execute(c *gin.Context) {
 			responseData["tools_history"] = toolsHistory
 		}
 
-		c.JSON(http.StatusOK, responseData)
+		respond(responseData)
 	} else {
 		responseData := gin.H{
 			"message": "指令正在执行中，请稍候...",
@@ -439,6 +808,102 @@ func Execute(c *gin.Context) {
 			responseData["tools_history"] = toolsHistory
 		}
 
-		c.JSON(http.StatusOK, responseData)
+		respond(responseData)
+	}
+}
+
+// extractToolsHistory 从对话历史中提取工具调用记录
+func extractToolsHistory(chatHistory []openai.ChatCompletionMessage) []ToolHistory {
+	var toolsHistory []ToolHistory
+	for i := 1; i < len(chatHistory); i++ {
+		if chatHistory[i].Role != openai.ChatMessageRoleUser {
+			continue
+		}
+		var toolPrompt map[string]interface{}
+		if err := json.Unmarshal([]byte(chatHistory[i].Content), &toolPrompt); err != nil {
+			continue
+		}
+		if action, ok := toolPrompt["action"].(map[string]interface{}); ok {
+			name, _ := action["name"].(string)
+			input, _ := action["input"].(string)
+			observation, _ := toolPrompt["observation"].(string)
+
+			if name != "" && input != "" {
+				toolsHistory = append(toolsHistory, ToolHistory{
+					Name:        name,
+					Input:       input,
+					Observation: observation,
+					Idempotency: string(tools.Classify(name, input)),
+					Cached:      tools.IsCachedResult(observation),
+				})
+			}
+		}
 	}
+	return toolsHistory
+}
+
+// translateAnswer 回答语言与要求不一致时翻译回答，翻译失败时返回 false，由调用方保留原回答
+func translateAnswer(logger *zap.Logger, message, lang, provider, model, apiKey, baseURL string) (string, bool) {
+	if strings.TrimSpace(message) == "" || llms.DetectLanguage(message) == lang {
+		return "", false
+	}
+
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("execute_translate")()
+
+	client, err := llms.NewProvider(provider, apiKey, baseURL)
+	if err == nil {
+		var translated string
+		if translated, err = llms.Translate(client, model, message, lang); err == nil && translated != "" {
+			logger.Info("回答已翻译",
+				zap.String("language", lang),
+			)
+			return translated, true
+		}
+	}
+	logger.Warn("回答翻译失败，返回原回答",
+		zap.String("language", lang),
+		zap.Error(err),
+	)
+	return "", false
+}
+
+// copyableCommands 从工具调用记录中提取可复制的命令，相同命令只保留一次
+func copyableCommands(toolsHistory []ToolHistory, withContext bool) []tools.CopyableCommand {
+	var commands []tools.CopyableCommand
+	seen := make(map[string]bool)
+	for _, history := range toolsHistory {
+		command, ok := tools.CopyableCommandFor(history.Name, history.Input, withContext)
+		if !ok || seen[command.Command] {
+			continue
+		}
+		seen[command.Command] = true
+		commands = append(commands, command)
+	}
+	return commands
+}
+
+// chartInputs 将工具调用记录转换为图表/表格提取的输入
+func chartInputs(toolsHistory []ToolHistory) []charts.Input {
+	inputs := make([]charts.Input, 0, len(toolsHistory))
+	for _, history := range toolsHistory {
+		inputs = append(inputs, charts.Input{Tool: history.Name, Input: history.Input, Output: history.Observation})
+	}
+	return inputs
+}
+
+// recordSystemPrompt 记录系统提示的 token 数，按问题裁剪的提示与完整提示分开统计，便于对比裁剪效果
+func recordSystemPrompt(logger *zap.Logger, name string, vars prompts.Vars, content, model string) {
+	tokens := llms.EstimateTokens([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleSystem, Content: content}}, model)
+	mode := "full"
+	if vars.Sections() != nil {
+		mode = "trimmed"
+	}
+	utils.RecordSystemPrompt(name, mode, tokens)
+	logger.Debug("系统提示已生成",
+		zap.String("mode", mode),
+		zap.Strings("topics", vars.Topics),
+		zap.Strings("sections", vars.Sections()),
+		zap.Int("tokens", tokens),
+	)
 }
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// defaultFooterTemplate 未配置 answer.footer.template 时使用的来源说明，空字段不显示
+const defaultFooterTemplate = `---
+> {{if .Cluster}}集群 {{.Cluster}}{{if and .Context (ne .Context .Cluster)}}（context {{.Context}}）{{end}} · {{end}}数据时间 {{.DataAt}}{{if .Cached}}（缓存）{{end}} · 模型 {{.Model}}{{if .InteractionID}} · 交互 ID {{.InteractionID}}{{end}}`
+
+// defaultFooter 解析后的默认模板
+var defaultFooter = template.Must(template.New("footer").Parse(defaultFooterTemplate))
+
+// footerCache 解析后的 answer.footer.template，模板内容变化（配置热加载）时才重新解析
+var footerCache struct {
+	sync.Mutex
+	text string
+	tmpl *template.Template
+}
+
+// Provenance 回答的来源信息，截图贴到工单时可据此追溯到审计记录
+type Provenance struct {
+	Cluster       string `json:"cluster,omitempty"` // 请求中的集群名称，跨集群问题为逗号分隔的集群列表
+	Context       string `json:"context,omitempty"` // 集群对应的 kubeconfig context
+	DataAt        string `json:"data_at"`           // 回答所依据数据的查询时间，命中回答缓存时为缓存时间
+	Cached        bool   `json:"cached,omitempty"`
+	Model         string `json:"model"`
+	InteractionID string `json:"interaction_id,omitempty"` // 诊断、分析接口没有审计记录，为空
+}
+
+// dataClock 记录回答所依据数据的查询时间：最早一次工具调用完成的时间，
+// 回答中最旧的数据决定了它可能过时多少；通过 assistants.WithEvents 订阅工具事件，跨集群查询时并发调用
+type dataClock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+// newDataClock 创建数据时间记录，since 非零时作为已知的最早数据时间，例如审批暂停前的对话
+func newDataClock(since time.Time) *dataClock {
+	return &dataClock{at: since}
+}
+
+// subscriber 返回记录工具调用完成时间的订阅者
+func (d *dataClock) subscriber() assistants.Subscriber {
+	return func(e assistants.Event) {
+		if e.Type != assistants.EventToolCompleted {
+			return
+		}
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.at.IsZero() || e.Time.Before(d.at) {
+			d.at = e.Time
+		}
+	}
+}
+
+// Time 返回数据时间，没有查询过数据时返回当前时间
+func (d *dataClock) Time() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.at.IsZero() {
+		return time.Now()
+	}
+	return d.at
+}
+
+// answerFooterEnabled 是否在最终回答后附加来源说明，配置项 answer.footer.enabled
+func answerFooterEnabled() bool {
+	return utils.GetConfig().GetBool("answer.footer.enabled")
+}
+
+// newProvenance 生成回答的来源信息，时间按 answer.footer.timezone（默认 UTC）显示
+func newProvenance(cluster, model, interactionID string, dataAt time.Time, cached bool) Provenance {
+	loc := time.UTC
+	if name := utils.GetConfig().GetString("answer.footer.timezone"); name != "" {
+		if l, err := time.LoadLocation(name); err == nil {
+			loc = l
+		}
+	}
+	var contexts []string
+	for _, name := range strings.Split(cluster, ",") {
+		if name = strings.TrimSpace(name); name == "" || name == "default" {
+			continue
+		}
+		if clusters.Default().InCluster(name) {
+			contexts = append(contexts, clusters.InClusterContext)
+			continue
+		}
+		kubeContext, _ := clusters.Default().Target(name)
+		contexts = append(contexts, kubeContext)
+	}
+	if cluster == "default" {
+		cluster = ""
+	}
+	return Provenance{
+		Cluster:       cluster,
+		Context:       strings.Join(contexts, ","),
+		DataAt:        dataAt.In(loc).Format("2006-01-02 15:04:05 MST"),
+		Cached:        cached,
+		Model:         model,
+		InteractionID: interactionID,
+	}
+}
+
+// footerTemplate 返回来源说明模板，由 answer.footer.template 配置（Go text/template，字段见 Provenance），
+// 未配置或模板无效时使用默认模板
+func footerTemplate() *template.Template {
+	text := utils.GetConfig().GetString("answer.footer.template")
+	if text == "" {
+		return defaultFooter
+	}
+	footerCache.Lock()
+	defer footerCache.Unlock()
+	if footerCache.tmpl != nil && footerCache.text == text {
+		return footerCache.tmpl
+	}
+	tmpl, err := template.New("footer").Parse(text)
+	if err != nil {
+		utils.Warn("answer.footer.template 无效，使用默认模板", zap.Error(err))
+		tmpl = defaultFooter
+	}
+	footerCache.text, footerCache.tmpl = text, tmpl
+	return tmpl
+}
+
+// appendAnswerFooter 在回答末尾附加来源说明
+// 审计记录、会话历史和回答缓存中保存的是不带来源说明的回答
+func appendAnswerFooter(answer string, provenance Provenance) string {
+	var b strings.Builder
+	if err := footerTemplate().Execute(&b, provenance); err != nil {
+		utils.Warn("生成回答来源说明失败", zap.Error(err))
+		return answer
+	}
+	footer := strings.TrimSpace(b.String())
+	if footer == "" {
+		return answer
+	}
+	return strings.TrimRight(answer, "\n") + "\n\n" + footer
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestAppendAnswerFooter(t *testing.T) {
+	config := utils.GetConfig()
+	defer config.Set("answer.footer.template", nil)
+
+	provenance := Provenance{Cluster: "prod", Context: "prod-ctx", DataAt: "2026-10-17 08:00:00 UTC", Model: "gpt-4o", InteractionID: "i-1"}
+	got := appendAnswerFooter("answer\n", provenance)
+	want := "answer\n\n---\n> 集群 prod（context prod-ctx） · 数据时间 2026-10-17 08:00:00 UTC · 模型 gpt-4o · 交互 ID i-1"
+	if got != want {
+		t.Errorf("default footer = %q, want %q", got, want)
+	}
+	// 没有审计记录时不显示交互 ID
+	provenance.InteractionID = ""
+	if got := appendAnswerFooter("answer", provenance); strings.Contains(got, "交互 ID") {
+		t.Errorf("footer without interaction = %q", got)
+	}
+
+	config.Set("answer.footer.template", "via {{.Model}}")
+	if got := appendAnswerFooter("answer", provenance); got != "answer\n\nvia gpt-4o" {
+		t.Errorf("custom footer = %q", got)
+	}
+	// 模板只在内容变化时重新解析
+	if footerTemplate() != footerTemplate() {
+		t.Error("footer template should be parsed once")
+	}
+	config.Set("answer.footer.template", "{{.Model")
+	if got := appendAnswerFooter("answer", provenance); !strings.Contains(got, "模型 gpt-4o") {
+		t.Errorf("invalid template should fall back to the default, got %q", got)
+	}
+}
+
+func TestDataClock(t *testing.T) {
+	first := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	clock := newDataClock(time.Time{})
+	if at := clock.Time(); time.Since(at) > time.Minute {
+		t.Errorf("clock without tool calls = %v, want now", at)
+	}
+
+	record := clock.subscriber()
+	record(assistants.Event{Type: assistants.EventToolSelected, Time: first.Add(-time.Hour)})
+	record(assistants.Event{Type: assistants.EventToolCompleted, Time: first.Add(time.Minute)})
+	record(assistants.Event{Type: assistants.EventToolCompleted, Time: first})
+	if at := clock.Time(); !at.Equal(first) {
+		t.Errorf("clock = %v, want the earliest tool call %v", at, first)
+	}
+
+	// 审批继续的对话保留暂停前的数据时间
+	paused := first.Add(-time.Hour)
+	clock = newDataClock(paused)
+	clock.subscriber()(assistants.Event{Type: assistants.EventToolCompleted, Time: first})
+	if at := clock.Time(); !at.Equal(paused) {
+		t.Errorf("clock = %v, want %v", at, paused)
+	}
+}
+
+func TestAnalyzeFooter(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("answer.footer.enabled", true)
+	defer config.Set("answer.footer.enabled", nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/analyze?cluster=default", strings.NewReader(`{"resource":"deployment/web"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	Analyze(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Message    string     `json:"message"`
+		Provenance Provenance `json:"provenance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Message, "数据时间 "+resp.Provenance.DataAt) || resp.Provenance.Model != "gpt-4o" {
+		t.Errorf("analyze response = %+v", resp)
+	}
+}
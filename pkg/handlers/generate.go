@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/policy"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/myysophia/OpsAgent/pkg/workflows"
+	"go.uber.org/zap"
+)
+
+// GenerateRequest 清单生成请求结构
+type GenerateRequest struct {
+	Prompt       string `json:"prompt" binding:"required"`
+	Provider     string `json:"provider"`
+	BaseUrl      string `json:"baseUrl"`
+	CurrentModel string `json:"currentModel"`
+	// Apply 生成并通过 dry-run 后提交应用请求，等待审批；也可以通过查询参数 apply=true 指定
+	Apply bool `json:"apply"`
+}
+
+// ApplyManifestRequest 清单应用请求结构
+type ApplyManifestRequest struct {
+	Manifest string `json:"manifest" binding:"required"`
+}
+
+// ReviewApplyRequest 审批请求结构
+type ReviewApplyRequest struct {
+	Hash string `json:"hash"`
+}
+
+// Generate 按说明生成 Kubernetes YAML 清单并执行服务端 dry-run，返回清单和校验结果
+// 指定 apply 时通过校验的清单进入审批流程，与 POST /generate/apply 相同，不会直接修改集群
+// 生成的清单作为回答写入审计记录，调用方指定的 baseUrl 不会收到服务端密钥（见 llmBaseURL）
+func Generate(c *gin.Context) {
+	logger := middleware.ContextLogger(c)
+
+	var req GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	apply := req.Apply || c.Query("apply") == "true"
+
+	apiKey := llmAPIKey(c)
+	req.BaseUrl = llmBaseURL(c, apiKey, req.BaseUrl)
+	if apiKey == "" && llms.RequiresAPIKey(req.Provider) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing API Key"})
+		return
+	}
+	model := req.CurrentModel
+	if model == "" {
+		model = "gpt-4"
+	}
+	if err := policy.ValidateModelSelection(tenantOf(c), userRole(c), req.Provider, model, nil); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 审计记录，在请求结束时异步写入
+	startTime := time.Now()
+	record := &audit.Interaction{
+		ID:        audit.NewInteractionID(),
+		Username:  c.GetString("username"),
+		Model:     model,
+		Question:  req.Prompt,
+		Status:    audit.StatusSuccess,
+		CreatedAt: startTime,
+	}
+	defer func() {
+		record.DurationMs = time.Since(startTime).Milliseconds()
+		audit.RecordContext(c.Request.Context(), record)
+	}()
+	logger = middleware.WithLogFields(c, zap.String(utils.LogFieldInteraction, record.ID))
+	fail := func(status int, response gin.H) {
+		record.Status = audit.StatusError
+		record.Error, _ = response["error"].(string)
+		response["interaction_id"] = record.ID
+		c.JSON(status, response)
+	}
+
+	client, err := llms.NewProvider(req.Provider, apiKey, req.BaseUrl)
+	if err != nil {
+		fail(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	manifest, err := workflows.GenerateManifest(client, model, req.Prompt)
+	if err != nil {
+		logger.Error("生成清单失败", zap.String("model", model), zap.Error(err))
+		fail(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	record.Answer = manifest
+
+	if !apply {
+		diff, err := workflows.PreviewApply(c.Request.Context(), manifest)
+		if err != nil {
+			logger.Warn("生成的清单 dry-run 失败", zap.Error(err))
+			fail(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "yaml": manifest})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"yaml":           manifest,
+			"diff":           diff,
+			"status":         "success",
+			"interaction_id": record.ID,
+		})
+		return
+	}
+
+	applyReq, err := workflows.SubmitApply(c.Request.Context(), manifest, c.GetString("username"))
+	if err != nil {
+		logger.Warn("提交生成的清单失败", zap.Error(err))
+		response := gin.H{"error": err.Error(), "yaml": manifest}
+		var validationErr *workflows.ManifestValidationError
+		if errors.As(err, &validationErr) {
+			response["errors"], response["diff"] = validationErr.Errors, validationErr.Diff
+		}
+		fail(http.StatusUnprocessableEntity, response)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"yaml":           manifest,
+		"diff":           &kubernetes.ManifestDiff{Valid: true, Objects: applyReq.Diff},
+		"apply":          applyReq,
+		"status":         applyReq.Status,
+		"interaction_id": record.ID,
+	})
+}
+
+// SubmitApply 提交清单应用请求，校验并 dry-run 后等待审批
+func SubmitApply(c *gin.Context) {
+	var req ApplyManifestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	applyReq, err := workflows.SubmitApply(c.Request.Context(), req.Manifest, c.GetString("username"))
+	if err != nil {
+		utils.Warn("提交清单应用请求失败", zap.Error(err))
+		response := gin.H{"error": err.Error()}
+		var validationErr *workflows.ManifestValidationError
+		if errors.As(err, &validationErr) {
+			response["errors"], response["diff"] = validationErr.Errors, validationErr.Diff
+		}
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"apply":  applyReq,
+		"status": applyReq.Status,
+	})
+}
+
+// DiffManifest 对清单执行服务端 dry-run 并返回相对集群中现有对象的 diff，不创建应用请求，也不修改集群
+func DiffManifest(c *gin.Context) {
+	var req ApplyManifestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := workflows.PreviewApply(c.Request.Context(), req.Manifest)
+	if err != nil {
+		utils.Warn("清单 dry-run 失败", zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"diff":   diff,
+		"status": "success",
+	})
+}
+
+// GetApply 获取清单应用请求
+func GetApply(c *gin.Context) {
+	applyReq, err := workflows.GetApply(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		applyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"apply":  applyReq,
+		"status": applyReq.Status,
+	})
+}
+
+// ApproveApply 审批通过并应用清单
+func ApproveApply(c *gin.Context) {
+	reviewApply(c, true)
+}
+
+// RejectApply 拒绝清单应用请求
+func RejectApply(c *gin.Context) {
+	reviewApply(c, false)
+}
+
+// reviewApply 处理审批请求
+func reviewApply(c *gin.Context, approve bool) {
+	var req ReviewApplyRequest
+	if approve {
+		if err := c.ShouldBindJSON(&req); err != nil || req.Hash == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hash is required to approve"})
+			return
+		}
+	}
+
+	applyReq, err := workflows.ReviewApply(c.Request.Context(), c.Param("id"), c.GetString("username"), approve, req.Hash)
+	if err != nil {
+		applyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"apply":  applyReq,
+		"status": applyReq.Status,
+	})
+}
+
+// applyError 返回应用请求操作失败的响应，请求不存在时返回 404，其余为状态冲突
+func applyError(c *gin.Context, err error) {
+	if errors.Is(err, workflows.ErrApplyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Apply request not found"})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+}
+
+// GetApplyDrift 检测单个已应用清单的漂移
+func GetApplyDrift(c *gin.Context) {
+	report, err := workflows.CheckDrift(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		applyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"drift": report})
+}
+
+// ListDrift 检测所有已应用清单的漂移
+func ListDrift(c *gin.Context) {
+	reports, err := workflows.CheckAllDrift(c.Request.Context())
+	if err != nil {
+		utils.Error("漂移检测失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	drifted, failed := 0, 0
+	for _, report := range reports {
+		if report.Drifted {
+			drifted++
+		}
+		if report.Error != "" {
+			failed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+		"total":   len(reports),
+		"drifted": drifted,
+		"failed":  failed,
+	})
+}
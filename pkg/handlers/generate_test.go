@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/workflows"
+)
+
+const generatedManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+  namespace: default
+data:
+  key: value`
+
+// fakeManifestLLM 模拟 OpenAI 兼容接口，总是返回 generatedManifest
+func fakeManifestLLM(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":     "chatcmpl-1",
+			"object": "chat.completion",
+			"model":  "gpt-4",
+			"choices": []map[string]interface{}{{
+				"index":         0,
+				"message":       map[string]string{"role": "assistant", "content": "```yaml\n" + generatedManifest + "\n```"},
+				"finish_reason": "stop",
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// serveGenerate 以 alice 的身份调用 Generate，返回状态码和响应
+func serveGenerate(t *testing.T, baseURL string, apply bool) (int, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(GenerateRequest{Prompt: "创建名为 demo 的 ConfigMap", BaseUrl: baseURL, Apply: apply})
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/generate", strings.NewReader(string(body)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Request.Header.Set("X-API-Key", "user-key")
+	c.Set("username", "alice")
+	Generate(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+	if resp["interaction_id"] == "" || resp["interaction_id"] == nil {
+		t.Errorf("response should carry the audit interaction id: %v", resp)
+	}
+	return w.Code, resp
+}
+
+func TestGenerateDryRunFailure(t *testing.T) {
+	server := fakeManifestLLM(t)
+	workflows.SetManifestFuncs(func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+		return nil, errors.New("connection refused")
+	}, nil)
+	defer workflows.SetManifestFuncs(nil, nil)
+
+	code, resp := serveGenerate(t, server.URL, false)
+	if code != http.StatusUnprocessableEntity || resp["yaml"] != generatedManifest || !strings.Contains(resp["error"].(string), "dry-run") {
+		t.Errorf("Generate = %d %v, want 422 with the manifest", code, resp)
+	}
+
+	// 有对象被 API Server 拒绝时不创建应用请求
+	workflows.SetManifestFuncs(func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+		return &kubernetes.ManifestDiff{Errors: []string{"ConfigMap/default/demo: forbidden"}}, nil
+	}, nil)
+	code, resp = serveGenerate(t, server.URL, true)
+	if code != http.StatusUnprocessableEntity || resp["errors"] == nil || resp["apply"] != nil {
+		t.Errorf("Generate(apply) = %d %v, want 422 with errors", code, resp)
+	}
+}
+
+func TestGenerateApply(t *testing.T) {
+	server := fakeManifestLLM(t)
+	var applied []string
+	workflows.SetManifestFuncs(
+		func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+			return &kubernetes.ManifestDiff{Valid: true, Objects: []kubernetes.ObjectDiff{{Object: "ConfigMap/default/demo", Action: "create"}}}, nil
+		},
+		func(manifest, id, hash string) ([]string, error) {
+			applied = append(applied, manifest)
+			return []string{"ConfigMap/default/demo"}, nil
+		},
+	)
+	defer workflows.SetManifestFuncs(nil, nil)
+
+	// 不指定 apply 时只返回清单和 diff
+	code, resp := serveGenerate(t, server.URL, false)
+	if code != http.StatusOK || resp["yaml"] != generatedManifest || resp["apply"] != nil {
+		t.Errorf("Generate = %d %v", code, resp)
+	}
+
+	review := func(id, reviewer string, approve bool, hash string) (int, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body, _ := json.Marshal(ReviewApplyRequest{Hash: hash})
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/generate/apply/"+id, strings.NewReader(string(body)))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Set("username", reviewer)
+		if approve {
+			ApproveApply(c)
+		} else {
+			RejectApply(c)
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// 指定 apply 时进入审批，审批前不修改集群
+	code, resp = serveGenerate(t, server.URL, true)
+	if code != http.StatusAccepted || resp["status"] != workflows.ApplyStatusPending {
+		t.Fatalf("Generate(apply) = %d %v, want 202 pending", code, resp)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("manifest applied before approval")
+	}
+	request := resp["apply"].(map[string]interface{})
+	id, hash := request["id"].(string), request["hash"].(string)
+
+	if code, resp := review(id, "bob", true, "wrong-hash"); code != http.StatusConflict || len(applied) != 0 {
+		t.Errorf("approve with a wrong hash = %d %v", code, resp)
+	}
+	if code, resp := review(id, "bob", true, hash); code != http.StatusOK || resp["status"] != workflows.ApplyStatusApplied {
+		t.Errorf("approve = %d %v, want applied", code, resp)
+	}
+	if len(applied) != 1 || applied[0] != generatedManifest {
+		t.Errorf("applied = %q, want the generated manifest once", applied)
+	}
+
+	// 拒绝的请求不会被应用
+	_, resp = serveGenerate(t, server.URL, true)
+	id = resp["apply"].(map[string]interface{})["id"].(string)
+	if code, resp := review(id, "bob", false, ""); code != http.StatusOK || resp["status"] != workflows.ApplyStatusRejected {
+		t.Errorf("reject = %d %v, want rejected", code, resp)
+	}
+	if len(applied) != 1 {
+		t.Errorf("rejected manifest was applied")
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/handoff"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// HandoffRequest 手动转交请求
+type HandoffRequest struct {
+	InteractionID string `json:"interaction_id" binding:"required"`
+	Reason        string `json:"reason"`
+}
+
+// handOffLowConfidence 评估回答置信度，低于 handoff.confidence_threshold 时转交值班人员，未转交时返回 nil
+func handOffLowConfidence(ctx context.Context, logger *zap.Logger, record *audit.Interaction, draft string,
+	history []ToolHistory, targetErrors int) *handoff.Bundle {
+	signals := handoff.Signals{Answer: draft, TargetErrors: targetErrors}
+	for _, h := range history {
+		signals.Observations = append(signals.Observations, h.Observation)
+	}
+	for _, d := range record.Drafts {
+		if d.Verdict == "revise" {
+			signals.ReviewRejected = true
+		}
+	}
+
+	assessment := handoff.Assess(signals)
+	if assessment.Confidence >= handoff.Threshold() {
+		return nil
+	}
+	logger.Info("回答置信度低，转交值班人员",
+		zap.Float64("confidence", assessment.Confidence),
+		zap.Strings("reasons", assessment.Reasons),
+	)
+	bundle := handoff.Handoff(ctx, record, draft, assessment.Reason(), record.Username, &assessment.Confidence)
+	record.Status = audit.StatusHandedOff
+	return bundle
+}
+
+// handoffNotice 转交后返回给用户的说明
+func handoffNotice(b *handoff.Bundle) string {
+	notice := fmt.Sprintf("这个问题暂时无法给出可靠的回答（%s），已转交值班人员处理，转交 ID：%s。", b.Reason, b.ID)
+	if !b.Notified {
+		notice += "通知值班人员失败，请联系值班人员并提供转交 ID。"
+	}
+	return notice
+}
+
+// CreateHandoff 将交互打包（问题、工具调用记录、集群状态）并发送给值班人员，需要启用审计
+// 只有提问人和管理员可以转交
+func CreateHandoff(c *gin.Context) {
+	var req HandoffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("请求格式错误: %v", err)})
+		return
+	}
+	store := audit.GetStore()
+	if store == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+
+	interaction, err := store.GetInteraction(c.Request.Context(), req.InteractionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+	if err != nil {
+		utils.Error("获取审计记录失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	username := c.GetString("username")
+	if interaction.Username != username && !middleware.IsAdmin(username) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+
+	reason := req.Reason
+	if reason == "" {
+		reason = "用户请求人工处理"
+	}
+	bundle := handoff.Handoff(c.Request.Context(), interaction, interaction.Answer, reason, username, nil)
+	c.JSON(http.StatusCreated, gin.H{
+		"handoff": bundle,
+		"status":  "success",
+	})
+}
+
+// GetHandoff 获取转交包，登录用户均可通过转交 ID 查看
+func GetHandoff(c *gin.Context) {
+	bundle, ok := handoff.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Handoff not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"handoff": bundle,
+		"status":  "success",
+	})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/kubeaudit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// KubeAuditWebhook 接收 apiserver webhook 审计后端推送的 EventList 并写入审计存储
+// apiserver 的 webhook kubeconfig 中使用 kube_audit.webhook_token 作为 Bearer token，路径中的 cluster 为集群名称
+func KubeAuditWebhook(c *gin.Context) {
+	token := utils.GetConfig().GetString("kube_audit.webhook_token")
+	if !kubeaudit.Enabled() || token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Kubernetes audit webhook is not enabled"})
+		return
+	}
+	provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return
+	}
+
+	cluster := c.Param("cluster")
+	inserted, err := kubeaudit.Ingest(c.Request.Context(), cluster, c.Request.Body)
+	if errors.Is(err, kubeaudit.ErrStoreDisabled) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Audit is not enabled"})
+		return
+	}
+	if err != nil {
+		utils.Warn("接收集群审计日志失败", zap.String("cluster", cluster), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"received": inserted})
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// Metrics 以 Prometheus 文本格式导出指标
+// 包括请求数和耗时、LLM token 用量、工具调用耗时、性能统计中的计时和计数，以及审计写入队列状态
+func Metrics(c *gin.Context) {
+	var b strings.Builder
+	utils.WritePrometheusMetrics(&b)
+	if stats, ok := audit.Stats(); ok {
+		writeAuditMetrics(&b, stats)
+	}
+	writeParsePathMetrics(&b, utils.ParsePathCounts())
+	writeEndpointMetrics(&b, llms.EndpointHealth())
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// AdminStats 管理员查看服务内部状态
+func AdminStats(c *gin.Context) {
+	auditStats := gin.H{"enabled": false}
+	if stats, ok := audit.Stats(); ok {
+		auditStats = gin.H{"enabled": true, "queue": stats}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"audit":         auditStats,
+		"llm_endpoints": llms.EndpointHealth(),
+		"status":        "success",
+	})
+}
+
+// writeAuditMetrics 写入审计写入队列指标
+func writeAuditMetrics(b *strings.Builder, stats audit.QueueStats) {
+	metric := func(name, kind, help string, value interface{}) {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, kind, name, value)
+	}
+	metric("opsagent_audit_queue_depth", "gauge", "Audit records waiting to be written.", stats.Depth)
+	metric("opsagent_audit_queue_capacity", "gauge", "Capacity of the audit write queue.", stats.Capacity)
+	metric("opsagent_audit_oldest_pending_seconds", "gauge", "Age of the oldest audit record waiting to be written.", stats.OldestPendingSeconds)
+	metric("opsagent_audit_records_dropped_total", "counter", "Audit records dropped because the queue was full.", stats.Dropped)
+	metric("opsagent_audit_records_committed_total", "counter", "Audit records committed to the database.", stats.Committed)
+	metric("opsagent_audit_records_failed_total", "counter", "Audit records that could not be written.", stats.FailedRecords)
+	metric("opsagent_audit_batches_total", "counter", "Audit batch commits attempted.", stats.Batches)
+	metric("opsagent_audit_batches_failed_total", "counter", "Audit batch commits that failed.", stats.FailedBatches)
+	metric("opsagent_audit_batch_commit_last_seconds", "gauge", "Latency of the last audit batch commit.", stats.LastCommitMs/1000)
+	metric("opsagent_audit_batch_commit_avg_seconds", "gauge", "Average latency of audit batch commits.", stats.AvgCommitMs/1000)
+}
+
+// writeParsePathMetrics 写入 LLM 响应解析路径指标，回退路径占比上升说明提示或模型的格式遵循度下降
+func writeParsePathMetrics(b *strings.Builder, counts []utils.ParsePathCount) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP opsagent_response_parse_total LLM responses parsed, by model and parsing path (standard, extract_field, clean_json, generic_map, raw).\n# TYPE opsagent_response_parse_total counter\n")
+	for _, count := range counts {
+		fmt.Fprintf(b, "opsagent_response_parse_total{model=%q,path=%q} %d\n", count.Model, count.Path, count.Count)
+	}
+}
+
+// writeEndpointMetrics 写入配置了多个地址的 LLM 端点健康状态
+func writeEndpointMetrics(b *strings.Builder, statuses []llms.EndpointStatus) {
+	if len(statuses) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP opsagent_llm_endpoint_up Whether the LLM endpoint is currently used for requests (1) or skipped after failures (0).\n# TYPE opsagent_llm_endpoint_up gauge\n")
+	for _, status := range statuses {
+		up := 0
+		if status.Healthy {
+			up = 1
+		}
+		fmt.Fprintf(b, "opsagent_llm_endpoint_up{provider=%q,endpoint=%q} %d\n", status.Provider, status.Endpoint, up)
+	}
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/assistants"
+	"github.com/myysophia/OpsAgent/pkg/charts"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// 跨集群查询的最大并发数
+const maxParallelClusters = 4
+
+// ClusterAnswer 跨集群问题中单个集群的回答
+type ClusterAnswer struct {
+	Cluster      string                  `json:"cluster"`
+	Summary      string                  `json:"summary"`
+	Tables       []charts.Table          `json:"tables,omitempty"`
+	Commands     []tools.CopyableCommand `json:"commands,omitempty"`
+	Error        string                  `json:"error,omitempty"`
+	ErrorCode    string                  `json:"error_code,omitempty"`
+	TargetErrors []tools.TargetFailure   `json:"target_errors,omitempty"`
+	DurationMs   int64                   `json:"duration_ms"`
+	ToolsHistory []ToolHistory           `json:"tools_history,omitempty"`
+	toolsHistory []ToolHistory
+}
+
+// executeTargets 返回请求的目标集群，cluster 字段也支持以逗号分隔多个集群
+func executeTargets(req ExecuteRequest) []string {
+	targets := req.Clusters
+	if len(targets) == 0 && req.Cluster != "" && req.Cluster != "default" {
+		targets = strings.Split(req.Cluster, ",")
+	}
+
+	seen := map[string]bool{}
+	var result []string
+	for _, target := range targets {
+		target = strings.TrimSpace(target)
+		if target == "" || seen[target] {
+			continue
+		}
+		seen[target] = true
+		result = append(result, target)
+	}
+	return result
+}
+
+// runClusterQueries 在每个集群上分别执行问题，按集群顺序返回回答
+func runClusterQueries(ctx context.Context, clusters []string, messagesFor func(cluster string) []openai.ChatCompletionMessage, model, apiKey, baseURL string) []*ClusterAnswer {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("execute_multi_cluster")()
+
+	logger := utils.LoggerFromContext(ctx)
+	answers := make([]*ClusterAnswer, len(clusters))
+	sem := make(chan struct{}, maxParallelClusters)
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			answer := &ClusterAnswer{Cluster: cluster}
+			answers[i] = answer
+
+			// 每个集群独立的重试预算，不可达的集群不会占用其他集群的迭代次数
+			clusterCtx, budget := tools.WithRetryBudget(tools.WithKubeContext(ctx, cluster))
+			clusterCtx, _ = tools.WithResultCache(clusterCtx)
+			clusterCtx = utils.WithLogger(clusterCtx, logger.With(zap.String(utils.LogFieldCluster, cluster)))
+			response, chatHistory, err := assistants.AssistantWithContext(clusterCtx, model, messagesFor(cluster), 8192, true, true, defaultMaxIterations, apiKey, baseURL)
+			answer.DurationMs = time.Since(start).Milliseconds()
+			answer.toolsHistory = extractToolsHistory(chatHistory)
+			answer.Tables = charts.ExtractTables(chartInputs(answer.toolsHistory))
+			answer.Commands = copyableCommands(answer.toolsHistory, true)
+			answer.TargetErrors = budget.Failures()
+			if failure := budget.Failure(cluster); failure != nil && failure.Exhausted {
+				// 集群不可达时即使 LLM 给出了总结也标记为失败，避免部分失败被隐藏在叙述中
+				answer.Error = fmt.Sprintf("集群不可达: %s", failure.LastError)
+				answer.ErrorCode = tools.ErrCodeTargetUnavailable
+			}
+			if err != nil {
+				answer.Error = err.Error()
+				answer.ErrorCode = errorCode(err)
+				logger.Warn("集群查询失败",
+					zap.String("cluster", cluster),
+					zap.Error(err),
+				)
+				return
+			}
+			answer.Summary = parseFinalAnswer(model, response)
+		}(i, cluster)
+	}
+	wg.Wait()
+	return answers
+}
+
+// parseFinalAnswer 从 AI 响应中提取最终答案，无法解析时返回原始响应，并按模型记录使用的解析路径
+func parseFinalAnswer(model, response string) string {
+	var aiResp AIResponse
+	if err := json.Unmarshal([]byte(response), &aiResp); err == nil && aiResp.FinalAnswer != "" {
+		utils.RecordParsePath(model, utils.ParsePathStandard)
+		return aiResp.FinalAnswer
+	}
+	if finalAnswer, err := utils.ExtractField(response, "final_answer"); err == nil && finalAnswer != "" {
+		utils.RecordParsePath(model, utils.ParsePathExtractField)
+		return finalAnswer
+	}
+	if err := json.Unmarshal([]byte(utils.CleanJSON(response)), &aiResp); err == nil && aiResp.FinalAnswer != "" {
+		utils.RecordParsePath(model, utils.ParsePathCleanJSON)
+		return aiResp.FinalAnswer
+	}
+	utils.RecordParsePath(model, utils.ParsePathRaw)
+	return response
+}
+
+// mergeClusterAnswers 将各集群的回答合并为一段叙述，失败的集群单独列出
+func mergeClusterAnswers(answers []*ClusterAnswer) string {
+	var b strings.Builder
+	var failed []string
+	for _, answer := range answers {
+		fmt.Fprintf(&b, "### %s\n\n", answer.Cluster)
+		if answer.Error != "" {
+			fmt.Fprintf(&b, "> ⚠️ 查询失败：%s\n\n", answer.Error)
+			failed = append(failed, answer.Cluster)
+			continue
+		}
+		b.WriteString(strings.TrimSpace(answer.Summary))
+		b.WriteString("\n\n")
+	}
+	if len(failed) > 0 {
+		fmt.Fprintf(&b, "**注意：%d/%d 个集群查询失败（%s），以上结论不包含这些集群。**\n", len(failed), len(answers), strings.Join(failed, ", "))
+	}
+	return strings.TrimSpace(b.String())
+}
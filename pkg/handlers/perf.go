@@ -4,16 +4,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
+	"strconv"
 
 	"github.com/myysophia/OpsAgent/pkg/utils"
 )
 
+// defaultTopResources 默认返回资源消耗最高的交互数量
+const defaultTopResources = 10
+
 // PerfStats 获取性能统计信息
+// 参数 resources_by 指定交互资源消耗的排序维度（cpu、heap、goroutines、alloc），resources_top 指定返回数量
 func PerfStats(c *gin.Context) {
 	logger := c.MustGet("logger").(*zap.Logger)
 	perfStats := utils.GetPerfStats()
 
 	stats := perfStats.GetStats()
+	top, err := strconv.Atoi(c.DefaultQuery("resources_top", strconv.Itoa(defaultTopResources)))
+	if err != nil || top <= 0 {
+		top = defaultTopResources
+	}
+	stats["resources"] = perfStats.TopResourceUsage(c.DefaultQuery("resources_by", "cpu"), top)
+	stats["parsePaths"] = utils.ParsePathSummaries()
 	logger.Debug("获取性能统计信息",
 		zap.Any("stats", stats),
 	)
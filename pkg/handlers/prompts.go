@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// InvalidatePromptsRequest 使提示缓存失效请求结构
+type InvalidatePromptsRequest struct {
+	Name string `json:"name"` // 为空时使全部提示失效
+}
+
+// ListPrompts 获取远程提示缓存的状态
+func ListPrompts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"prompts": utils.GetPromptCache().Status(),
+		"status":  "success",
+	})
+}
+
+// InvalidatePrompts 管理员使提示缓存失效，远程提示更新后无需等待过期即可生效
+func InvalidatePrompts(c *gin.Context) {
+	var req InvalidatePromptsRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalidated := utils.GetPromptCache().Invalidate(req.Name)
+	utils.Info("提示缓存已失效",
+		zap.String("name", req.Name),
+		zap.Strings("invalidated", invalidated),
+		zap.String("admin", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, gin.H{
+		"invalidated": invalidated,
+		"status":      "success",
+	})
+}
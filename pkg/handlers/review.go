@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// reviewEvidenceTokens 交给审阅模型的工具观察结果的 token 上限
+const reviewEvidenceTokens = 4096
+
+// answerReviewEnabled 是否在返回回答前由审阅模型检查，配置项 answer.review.enabled
+func answerReviewEnabled() bool {
+	return utils.GetConfig().GetBool("answer.review.enabled")
+}
+
+// reviewAnswer 由审阅模型对照工具观察结果检查回答草稿，未通过时由原模型按审阅意见修改一次
+// 各版回答和审阅意见记录到审计中；审阅或修改失败时返回 false，由调用方保留草稿
+func reviewAnswer(logger *zap.Logger, record *audit.Interaction, question, draft string, history []ToolHistory,
+	provider, model, apiKey, baseURL string) (string, bool) {
+	if strings.TrimSpace(draft) == "" {
+		return "", false
+	}
+
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("execute_review")()
+
+	config := utils.GetConfig()
+	reviewerProvider, reviewerModel := provider, model
+	reviewerKey, reviewerURL := apiKey, baseURL
+	if p := config.GetString("answer.review.provider"); p != "" && llms.NormalizeProvider(p) != llms.NormalizeProvider(provider) {
+		// 使用其他服务商审阅时，请求中的 API Key 和地址不适用，使用该服务商的配置
+		reviewerProvider, reviewerKey, reviewerURL = p, "", ""
+	}
+	if m := config.GetString("answer.review.model"); m != "" {
+		reviewerModel = m
+	}
+
+	evidence := llms.ConstrictPrompt(reviewEvidence(history), model, reviewEvidenceTokens)
+	first := audit.AnswerDraft{Seq: 1, Answer: draft, Reviewer: reviewerModel, CreatedAt: time.Now()}
+
+	reviewer, err := llms.NewProvider(reviewerProvider, reviewerKey, reviewerURL)
+	var review llms.Review
+	if err == nil {
+		review, err = llms.ReviewAnswer(reviewer, reviewerModel, question, draft, evidence)
+	}
+	if err != nil {
+		logger.Warn("回答审阅失败，直接返回草稿",
+			zap.String("reviewer", reviewerModel),
+			zap.Error(err),
+		)
+		perfStats.IncrCounter("answer_review_error")
+		first.Verdict = "approved"
+		first.ReviewError = err.Error()
+		record.Drafts = append(record.Drafts, first)
+		return "", false
+	}
+
+	if review.Approved {
+		perfStats.IncrCounter("answer_review_approved")
+		first.Verdict = "approved"
+		record.Drafts = append(record.Drafts, first)
+		return "", false
+	}
+	first.Verdict = "revise"
+	first.Critique = review.Critique
+	record.Drafts = append(record.Drafts, first)
+	logger.Info("回答审阅未通过，按审阅意见修改",
+		zap.String("reviewer", reviewerModel),
+		zap.String("critique", review.Critique),
+	)
+
+	// 只修改一次，修改后的回答不再审阅
+	client, err := llms.NewProvider(provider, apiKey, baseURL)
+	var revised string
+	if err == nil {
+		revised, err = llms.ReviseAnswer(client, model, question, draft, review.Critique, evidence)
+	}
+	if err != nil || revised == "" {
+		logger.Warn("按审阅意见修改回答失败，返回草稿",
+			zap.Error(err),
+		)
+		perfStats.IncrCounter("answer_review_error")
+		return "", false
+	}
+	perfStats.IncrCounter("answer_review_revised")
+	record.Drafts = append(record.Drafts, audit.AnswerDraft{
+		Seq:       2,
+		Answer:    revised,
+		Verdict:   "revised",
+		CreatedAt: time.Now(),
+	})
+	return revised, true
+}
+
+// reviewEvidence 将工具调用历史整理为审阅材料
+func reviewEvidence(history []ToolHistory) string {
+	var b strings.Builder
+	for i, h := range history {
+		fmt.Fprintf(&b, "### %d. %s: %s\n%s\n\n", i+1, h.Name, h.Input, strings.TrimSpace(h.Observation))
+	}
+	return b.String()
+}
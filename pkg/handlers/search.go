@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/inventory"
+)
+
+// Search 在全部集群的资源清单中按名称搜索资源
+// 查询参数：name（名称的一部分）、kind、namespace、cluster（可重复或以逗号分隔，为空时搜索全部集群）、limit
+func Search(c *gin.Context) {
+	query := inventory.Query{
+		Kind:      c.Query("kind"),
+		Namespace: c.Query("namespace"),
+		Name:      strings.TrimSpace(c.Query("name")),
+	}
+	if query.Name == "" && query.Kind == "" && query.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name、kind、namespace 至少需要指定一个"})
+		return
+	}
+	for _, value := range c.QueryArray("cluster") {
+		for _, cluster := range strings.Split(value, ",") {
+			if cluster = strings.TrimSpace(cluster); cluster != "" {
+				query.Clusters = append(query.Clusters, cluster)
+			}
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须是正整数"})
+			return
+		}
+		query.Limit = n
+	}
+
+	result, err := inventory.Default().Search(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
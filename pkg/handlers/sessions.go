@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/sessions"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const defaultExecuteHistoryTurns = 5
+
+// executeHistoryTurns Execute 接口追问时携带的最近轮次，配置项 sessions.history_turns，默认 5，设置为 0 时关闭
+func executeHistoryTurns() int {
+	config := utils.GetConfig()
+	if !config.IsSet("sessions.history_turns") {
+		return defaultExecuteHistoryTurns
+	}
+	return config.GetInt("sessions.history_turns")
+}
+
+// executeSession 返回 session_id Cookie 对应的会话
+// Cookie 不存在、会话已过期或属于其他用户时创建新会话，并在响应中写入新的 Cookie
+func executeSession(c *gin.Context, provider, model, cluster string) *sessions.Session {
+	manager := sessions.GetManager()
+	username := c.GetString("username")
+	if id, err := c.Cookie(sessions.CookieName); err == nil && id != "" {
+		if session, ok := manager.Get(id, username); ok {
+			return session
+		}
+	}
+
+	session := manager.Create(username, provider, model, cluster)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     sessions.CookieName,
+		Value:    session.ID,
+		Path:     "/",
+		MaxAge:   int(manager.IdleTimeout().Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return session
+}
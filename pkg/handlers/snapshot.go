@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/snapshots"
+)
+
+// snapshotNamespaces 读取 namespace 参数（可重复或以逗号分隔）
+func snapshotNamespaces(c *gin.Context) []string {
+	var namespaces []string
+	for _, value := range c.QueryArray("namespace") {
+		for _, namespace := range strings.Split(value, ",") {
+			if namespace = strings.TrimSpace(namespace); namespace != "" {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+	return namespaces
+}
+
+// snapshotTime 读取时间参数：RFC3339、日期或 24h、7d 这样的时长，未指定时返回零值
+func snapshotTime(c *gin.Context, name string, now time.Time) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := snapshots.ParseTime(value, now)
+	if err != nil {
+		return t, fmt.Errorf("无效的 %s，应为 RFC3339 时间、2006-01-02 日期或 24h、7d 这样的时长: %s", name, value)
+	}
+	return t, nil
+}
+
+// snapshotError 返回读取快照失败的响应
+func snapshotError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, snapshots.ErrStoreDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "历史快照保存在审计数据库中，未启用 audit.enabled"})
+	case errors.Is(err, audit.ErrSnapshotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "指定时间之前没有该集群的快照"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetSnapshot 返回集群的结构化快照：资源元数据、工作负载的副本数、镜像和资源配置、ConfigMap 内容的哈希
+// 查询参数：cluster（为空时使用当前 context）、namespace（可重复或以逗号分隔，为空时包含全部命名空间）、
+// at（指定时返回该时刻之前最近的历史快照，否则获取实时快照）
+func GetSnapshot(c *gin.Context) {
+	ctx := c.Request.Context()
+	cluster := c.Query("cluster")
+	namespaces := snapshotNamespaces(c)
+	at, err := snapshotTime(c, "at", time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if at.IsZero() {
+		snapshot, err := snapshots.Capture(ctx, cluster, namespaces)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, snapshot)
+		return
+	}
+
+	snapshot, err := snapshots.At(ctx, cluster, at)
+	if err != nil {
+		snapshotError(c, err)
+		return
+	}
+	if len(namespaces) > 0 {
+		// 历史快照包含全部命名空间，与实时快照一样只保留这些命名空间及其中的资源
+		scoped := *snapshot
+		scoped.Objects = []kubernetes.SnapshotObject{}
+		for _, o := range snapshot.Objects {
+			if slices.Contains(namespaces, o.Namespace) || (o.Kind == "namespaces" && slices.Contains(namespaces, o.Name)) {
+				scoped.Objects = append(scoped.Objects, o)
+			}
+		}
+		scoped.Namespaces = namespaces
+		snapshot = &scoped
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// DiffSnapshot 比较集群两个时刻的快照，返回新增、删除和变化（镜像、副本数、资源配置、ConfigMap 内容等）的资源
+// 查询参数：cluster、namespace（同 GetSnapshot）、from（默认 24h）、to（为空时与实时快照比较）
+func DiffSnapshot(c *gin.Context) {
+	now := time.Now()
+	from, err := snapshotTime(c, "from", now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if from.IsZero() {
+		from = now.Add(-24 * time.Hour)
+	}
+	to, err := snapshotTime(c, "to", now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !to.IsZero() && !to.After(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to 必须晚于 from"})
+		return
+	}
+
+	diff, err := snapshots.Compare(c.Request.Context(), c.Query("cluster"), from, to, snapshotNamespaces(c))
+	if err != nil {
+		snapshotError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
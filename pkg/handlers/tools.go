@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/middleware"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// OverrideQuotaRequest 覆盖工具配额请求结构
+type OverrideQuotaRequest struct {
+	Username string `json:"username" binding:"required"`
+	Tool     string `json:"tool" binding:"required"`
+	Limit    int    `json:"limit"` // -1 表示当天不限制
+}
+
+// GetToolQuotas 获取工具配额使用情况，管理员可通过 username 参数查询其他用户
+func GetToolQuotas(c *gin.Context) {
+	username := c.GetString("username")
+	if target := c.Query("username"); target != "" && target != username {
+		if !middleware.IsAdmin(username) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+			return
+		}
+		username = target
+	}
+
+	usage, err := tools.GetQuotaManager().Usage(c.Request.Context(), username)
+	if err != nil {
+		utils.Error("获取工具配额失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username": username,
+		"quotas":   usage,
+		"status":   "success",
+	})
+}
+
+// OverrideToolQuota 管理员为用户覆盖当天的工具配额
+func OverrideToolQuota(c *gin.Context) {
+	var req OverrideQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit < -1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be -1 (unlimited) or a non-negative number"})
+		return
+	}
+
+	if err := tools.GetQuotaManager().Override(c.Request.Context(), req.Username, req.Tool, req.Limit); err != nil {
+		utils.Error("覆盖工具配额失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	utils.Info("覆盖工具配额",
+		zap.String("username", req.Username),
+		zap.String("tool", req.Tool),
+		zap.Int("limit", req.Limit),
+		zap.String("admin", c.GetString("username")),
+	)
+
+	usage, err := tools.GetQuotaManager().Usage(c.Request.Context(), req.Username)
+	if err != nil {
+		utils.Error("获取工具配额失败", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"username": req.Username,
+		"quotas":   usage,
+		"status":   "success",
+	})
+}
@@ -0,0 +1,81 @@
+package handoff
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Signals 评估回答置信度的依据
+type Signals struct {
+	Answer         string
+	Observations   []string // 各次工具调用的观察结果
+	ReviewRejected bool     // 审阅模型认为草稿与观察结果不符
+	TargetErrors   int      // 不可达的集群或外部 API 数量
+}
+
+// Assessment 回答置信度评估结果
+type Assessment struct {
+	Confidence float64  `json:"confidence"`
+	Reasons    []string `json:"reasons,omitempty"`
+}
+
+// Reason 评估扣分原因，用于转交通知
+func (a Assessment) Reason() string {
+	if len(a.Reasons) == 0 {
+		return "置信度低"
+	}
+	return strings.Join(a.Reasons, "；")
+}
+
+// failureMarkers 工具调用失败时观察结果中的标记，见 assistants 中的工具调用
+var failureMarkers = []string{"failed with error", "is not available", "policy violation", "quota", "timed out", "unreachable"}
+
+// hedgePhrases 回答中表示无法确定的措辞
+var hedgePhrases = []string{"无法确定", "不确定", "无法判断", "可能是", "也许", "猜测", "信息不足", "无法获取",
+	"not sure", "unable to determine", "cannot determine", "might be", "possibly"}
+
+// Assess 按工具失败、审阅结论、是否有观察依据和回答措辞估算回答的置信度（0-1）
+func Assess(s Signals) Assessment {
+	score := 1.0
+	var reasons []string
+	deduct := func(points float64, reason string) {
+		score -= points
+		reasons = append(reasons, reason)
+	}
+
+	if len(s.Observations) == 0 {
+		deduct(0.2, "回答没有工具调用结果作为依据")
+	}
+	failed := 0
+	for _, observation := range s.Observations {
+		lower := strings.ToLower(observation)
+		for _, marker := range failureMarkers {
+			if strings.Contains(lower, marker) {
+				failed++
+				break
+			}
+		}
+	}
+	if failed > 0 {
+		deduct(math.Min(0.15*float64(failed), 0.45), fmt.Sprintf("%d 次工具调用失败", failed))
+	}
+	if s.TargetErrors > 0 {
+		deduct(0.2, fmt.Sprintf("%d 个目标不可达", s.TargetErrors))
+	}
+	if s.ReviewRejected {
+		deduct(0.3, "审阅模型认为回答与观察结果不符")
+	}
+	lower := strings.ToLower(s.Answer)
+	for _, phrase := range hedgePhrases {
+		if strings.Contains(lower, phrase) {
+			deduct(0.2, "回答包含不确定的措辞")
+			break
+		}
+	}
+	if strings.TrimSpace(s.Answer) == "" {
+		deduct(0.5, "没有得到回答")
+	}
+
+	return Assessment{Confidence: math.Round(math.Max(score, 0)*100) / 100, Reasons: reasons}
+}
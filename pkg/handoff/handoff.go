@@ -0,0 +1,250 @@
+package handoff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultThreshold 回答置信度低于该值时转交人工
+	defaultThreshold = 0.5
+	// maxSnapshotChars 单个集群快照保留的字符数，保留输出末尾（事件按时间排序，最新的在最后）
+	maxSnapshotChars = 4000
+	// maxBundles 内存中保留的转交包数量
+	maxBundles = 200
+)
+
+// defaultSnapshots 未配置 handoff.snapshots 时采集的集群状态
+var defaultSnapshots = []string{
+	"kubectl get nodes -o wide",
+	"kubectl get pods -A --field-selector=status.phase!=Running,status.phase!=Succeeded",
+	"kubectl get events -A --field-selector=type=Warning --sort-by=.lastTimestamp",
+}
+
+// Snapshot 转交时采集的一份集群状态
+type Snapshot struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Bundle 转交给值班人员的上下文包：问题、工具调用记录、助手的回答草稿以及转交时的集群状态
+type Bundle struct {
+	ID            string           `json:"id"`
+	InteractionID string           `json:"interaction_id"`
+	Username      string           `json:"username"`
+	Cluster       string           `json:"cluster,omitempty"`
+	Question      string           `json:"question"`
+	Draft         string           `json:"draft,omitempty"`
+	Reason        string           `json:"reason"`
+	Confidence    *float64         `json:"confidence,omitempty"` // 手动转交时为空
+	ToolCalls     []audit.ToolCall `json:"tool_calls"`
+	Snapshots     []Snapshot       `json:"snapshots"`
+	RequestedBy   string           `json:"requested_by"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Notified      bool             `json:"notified"`
+	NotifyError   string           `json:"notify_error,omitempty"`
+}
+
+// Enabled 是否在回答置信度低时自动转交人工，配置项 handoff.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("handoff.enabled")
+}
+
+// Threshold 自动转交的置信度阈值，配置项 handoff.confidence_threshold，默认 0.5
+func Threshold() float64 {
+	if config := utils.GetConfig(); config.IsSet("handoff.confidence_threshold") {
+		return config.GetFloat64("handoff.confidence_threshold")
+	}
+	return defaultThreshold
+}
+
+// Build 根据交互记录创建转交包并采集集群状态，interaction.Cluster 为多个集群时不采集
+func Build(ctx context.Context, interaction *audit.Interaction, draft, reason, requestedBy string) *Bundle {
+	b := &Bundle{
+		ID:            audit.NewInteractionID(),
+		InteractionID: interaction.ID,
+		Username:      interaction.Username,
+		Cluster:       interaction.Cluster,
+		Question:      interaction.Question,
+		Draft:         draft,
+		Reason:        reason,
+		ToolCalls:     append([]audit.ToolCall(nil), interaction.ToolCalls...),
+		RequestedBy:   requestedBy,
+		CreatedAt:     time.Now(),
+	}
+	if !strings.Contains(b.Cluster, ",") {
+		b.Snapshots = collectSnapshots(ctx, b.Cluster)
+	}
+	return b
+}
+
+// collectSnapshots 执行 handoff.snapshots 中的只读命令采集集群状态
+// 快照不计入申请人的工具配额，仍受 kubectl 只读策略约束
+func collectSnapshots(ctx context.Context, cluster string) []Snapshot {
+	commands := defaultSnapshots
+	if config := utils.GetConfig(); config.IsSet("handoff.snapshots") {
+		commands = config.GetStringSlice("handoff.snapshots")
+	}
+
+	snapshotCtx := context.WithoutCancel(ctx)
+	if cluster != "" {
+		snapshotCtx = tools.WithKubeContext(snapshotCtx, cluster)
+	}
+	snapshots := make([]Snapshot, 0, len(commands))
+	for _, command := range commands {
+		output, err := tools.Invoke(snapshotCtx, "kubectl", command)
+		snapshot := Snapshot{Command: command, Output: tail(strings.TrimSpace(output), maxSnapshotChars)}
+		if err != nil {
+			snapshot.Error = err.Error()
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+// Post 将转交包发送到 handoff.channel 渠道（为空时发送到所有渠道），结果记录在 b.Notified 中
+func Post(ctx context.Context, b *Bundle) error {
+	notifier := notify.Default()
+	if !notifier.Enabled() {
+		b.NotifyError = "未配置通知渠道"
+		return fmt.Errorf("未配置通知渠道")
+	}
+
+	msg := notify.Message{
+		Title: fmt.Sprintf("需要人工接手：%s", truncate(b.Question, 40)),
+		Text:  Summary(b),
+		Level: notify.LevelWarning,
+	}
+	var err error
+	if channel := utils.GetConfig().GetString("handoff.channel"); channel != "" {
+		err = notifier.SendTo(ctx, channel, msg)
+	} else {
+		err = notifier.Send(ctx, msg)
+	}
+	if err != nil {
+		b.NotifyError = err.Error()
+		return err
+	}
+	b.Notified = true
+	return nil
+}
+
+// Summary 转交通知的正文，值班人员不打开链接也能了解上下文
+func Summary(b *Bundle) string {
+	var s strings.Builder
+	fmt.Fprintf(&s, "- 提问人：%s\n", b.Username)
+	if b.Cluster != "" {
+		fmt.Fprintf(&s, "- 集群：%s\n", b.Cluster)
+	}
+	fmt.Fprintf(&s, "- 问题：%s\n", b.Question)
+	fmt.Fprintf(&s, "- 转交原因：%s\n", b.Reason)
+	if b.Confidence != nil {
+		fmt.Fprintf(&s, "- 置信度：%.2f\n", *b.Confidence)
+	}
+	if b.RequestedBy != "" && b.RequestedBy != b.Username {
+		fmt.Fprintf(&s, "- 转交人：%s\n", b.RequestedBy)
+	}
+	if base := utils.GetConfig().GetString("handoff.base_url"); base != "" {
+		fmt.Fprintf(&s, "- 完整上下文：%s/api/v2/handoffs/%s\n", strings.TrimRight(base, "/"), b.ID)
+	}
+
+	if len(b.ToolCalls) > 0 {
+		s.WriteString("\n**工具调用**\n\n")
+		for _, call := range b.ToolCalls {
+			fmt.Fprintf(&s, "%d. %s: `%s`\n", call.Seq, call.Name, truncate(call.Input, 120))
+		}
+	}
+	if b.Draft != "" {
+		fmt.Fprintf(&s, "\n**助手的回答草稿（未交付）**\n\n%s\n", truncate(b.Draft, 800))
+	}
+	return s.String()
+}
+
+// store 内存中的转交包，超过 maxBundles 时淘汰最早的记录
+type store struct {
+	mu      sync.Mutex
+	bundles map[string]*Bundle
+}
+
+var defaultStore = &store{bundles: map[string]*Bundle{}}
+
+// Save 保存转交包，便于通过 /api/handoffs/:id 分享
+func Save(b *Bundle) {
+	defaultStore.mu.Lock()
+	defer defaultStore.mu.Unlock()
+	defaultStore.bundles[b.ID] = b
+	if len(defaultStore.bundles) <= maxBundles {
+		return
+	}
+	ids := make([]string, 0, len(defaultStore.bundles))
+	for id := range defaultStore.bundles {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return defaultStore.bundles[ids[i]].CreatedAt.Before(defaultStore.bundles[ids[j]].CreatedAt)
+	})
+	for _, id := range ids[:len(ids)-maxBundles] {
+		delete(defaultStore.bundles, id)
+	}
+}
+
+// Get 获取转交包
+func Get(id string) (*Bundle, bool) {
+	defaultStore.mu.Lock()
+	defer defaultStore.mu.Unlock()
+	b, ok := defaultStore.bundles[id]
+	return b, ok
+}
+
+// Handoff 创建、保存并发送转交包，发送失败只记录日志，转交包仍可通过接口查看
+func Handoff(ctx context.Context, interaction *audit.Interaction, draft, reason, requestedBy string, confidence *float64) *Bundle {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("handoff")()
+
+	b := Build(ctx, interaction, draft, reason, requestedBy)
+	b.Confidence = confidence
+	if err := Post(ctx, b); err != nil {
+		utils.LoggerFromContext(ctx).Warn("发送转交通知失败",
+			zap.String("handoff_id", b.ID),
+			zap.Error(err),
+		)
+	}
+	Save(b)
+	perfStats.IncrCounter("handoff_created")
+	utils.LoggerFromContext(ctx).Info("交互已转交人工",
+		zap.String("handoff_id", b.ID),
+		zap.String("interaction_id", b.InteractionID),
+		zap.String("reason", reason),
+	)
+	return b
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return string(runes[:n]) + "..."
+}
+
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[len(s)-n:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return "...\n" + s
+}
@@ -0,0 +1,76 @@
+package handoff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+func TestAssess(t *testing.T) {
+	grounded := Assess(Signals{
+		Answer:       "payment-api 因 OOMKilled 重启了 7 次，建议将内存限制调整到 1Gi。",
+		Observations: []string{"payment-api-7d9f 0/1 CrashLoopBackOff 7", "Last State: Terminated Reason: OOMKilled"},
+	})
+	if grounded.Confidence != 1 || len(grounded.Reasons) != 0 {
+		t.Errorf("Assess(grounded) = %+v, want full confidence", grounded)
+	}
+
+	shaky := Assess(Signals{
+		Answer: "可能是网络问题，无法确定具体原因。",
+		Observations: []string{
+			"Tool kubectl failed with error exit status 1. Considering refine the inputs for the tool.",
+			"Tool promql is not available. Considering switch to other supported tools.",
+		},
+		ReviewRejected: true,
+	})
+	if shaky.Confidence >= defaultThreshold || len(shaky.Reasons) != 3 {
+		t.Errorf("Assess(shaky) = %+v, want low confidence with 3 reasons", shaky)
+	}
+	if !strings.Contains(shaky.Reason(), "2 次工具调用失败") {
+		t.Errorf("Reason() = %q", shaky.Reason())
+	}
+}
+
+func TestBuildCollectsSnapshots(t *testing.T) {
+	var inputs []string
+	original, _ := tools.Registry.Get("kubectl")
+	tools.Registry.Unregister("kubectl")
+	tools.Registry.Register(tools.ToolSpec{Name: "kubectl", Idempotency: tools.IdempotencyPureRead, Run: func(ctx context.Context, input string) (string, error) {
+		inputs = append(inputs, input)
+		return "node-1 Ready", nil
+	}})
+	defer func() {
+		tools.Registry.Unregister("kubectl")
+		tools.Registry.Register(original)
+	}()
+
+	interaction := &audit.Interaction{
+		ID:        "i-1",
+		Username:  "alice",
+		Cluster:   "prod-east",
+		Question:  "为什么 payment-api 一直重启？",
+		ToolCalls: []audit.ToolCall{{Seq: 1, Name: "kubectl", Input: "get pods -n shop"}},
+	}
+	b := Build(context.Background(), interaction, "可能是网络问题", "置信度低", "alice")
+	if len(b.Snapshots) != len(defaultSnapshots) || b.Snapshots[0].Output != "node-1 Ready" {
+		t.Fatalf("Build() snapshots = %+v", b.Snapshots)
+	}
+	if !strings.Contains(inputs[0], "--context 'prod-east' get nodes") {
+		t.Errorf("snapshot should run against the interaction cluster, got %q", inputs[0])
+	}
+
+	summary := Summary(b)
+	for _, want := range []string{"alice", "prod-east", "1. kubectl: `get pods -n shop`", "可能是网络问题"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Summary() missing %q:\n%s", want, summary)
+		}
+	}
+
+	interaction.Cluster = "prod-east,prod-west"
+	if b := Build(context.Background(), interaction, "", "置信度低", "alice"); len(b.Snapshots) != 0 {
+		t.Errorf("multi-cluster interactions should not collect snapshots, got %d", len(b.Snapshots))
+	}
+}
@@ -0,0 +1,280 @@
+// Package inventory 在内存中缓存各集群资源的元数据清单（与集群快照相同，只有名称、标签、属主等元数据），
+// 用于跨集群按名称搜索资源，回答"payment 部署在哪些集群"这类问题
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultTTL 清单的缓存时间，超过后下一次搜索时重新列出
+	defaultTTL = 5 * time.Minute
+	// refreshTimeout 单个集群一次刷新的超时时间
+	refreshTimeout = 2 * time.Minute
+	// maxParallelClusters 同时刷新的集群数
+	maxParallelClusters = 4
+	// DefaultLimit 未指定 limit 时返回的匹配数
+	DefaultLimit = 100
+	// MaxLimit 单次搜索最多返回的匹配数
+	MaxLimit = 1000
+)
+
+// kindShortNames kubectl 常用简写对应的资源名称
+var kindShortNames = map[string]string{
+	"ns": "namespaces", "no": "nodes", "deploy": "deployments", "sts": "statefulsets", "ds": "daemonsets",
+	"cj": "cronjobs", "svc": "services", "ing": "ingresses", "cm": "configmaps",
+	"pvc": "persistentvolumeclaims", "hpa": "horizontalpodautoscalers",
+}
+
+// Match 搜索命中的资源
+type Match struct {
+	Cluster string `json:"cluster"`
+	kubernetes.SnapshotObject
+}
+
+// ClusterStatus 搜索时各集群清单的状态，刷新失败时仍使用上一次成功的清单（RefreshedAt 为其时间）
+type ClusterStatus struct {
+	Cluster     string    `json:"cluster"`
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"`
+	Objects     int       `json:"objects"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Query 搜索条件，Name 为名称中包含的文本（不区分大小写），Kind、Namespace 为空时不限制
+type Query struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Clusters  []string // 为空时搜索全部集群，见 Clusters
+	Limit     int
+}
+
+// Result 搜索结果，Total 为截断前的匹配数
+type Result struct {
+	Matches   []Match         `json:"matches"`
+	Total     int             `json:"total"`
+	Truncated bool            `json:"truncated"`
+	Clusters  []ClusterStatus `json:"clusters"`
+}
+
+// Cache 各集群资源清单的缓存，清单在搜索时按需刷新
+type Cache struct {
+	ttl     time.Duration
+	kinds   []string
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	mu          sync.Mutex // 同一集群同时只刷新一次
+	snapshot    *kubernetes.Snapshot
+	refreshedAt time.Time
+}
+
+var (
+	defaultCache *Cache
+	cacheOnce    sync.Once
+)
+
+// Default 获取根据 inventory.ttl 和 inventory.kinds 创建的全局清单缓存
+func Default() *Cache {
+	cacheOnce.Do(func() {
+		config := utils.GetConfig()
+		defaultCache = NewCache(config.GetDuration("inventory.ttl"), config.GetStringSlice("inventory.kinds"))
+	})
+	return defaultCache
+}
+
+// NewCache 创建清单缓存，ttl 不大于 0 时使用默认的 5 分钟，kinds 为空时使用 kubernetes.DefaultSnapshotKinds
+func NewCache(ttl time.Duration, kinds []string) *Cache {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	if len(kinds) == 0 {
+		kinds = kubernetes.DefaultSnapshotKinds
+	}
+	return &Cache{ttl: ttl, kinds: kinds, entries: map[string]*entry{}}
+}
+
+// Kinds 缓存中包含的资源类型
+func (c *Cache) Kinds() []string {
+	return c.kinds
+}
+
+// NormalizeKind 将 deployment、deploy、Deployment.apps 等写法转换为清单中的资源名称，不在清单中的类型返回错误
+func (c *Cache) NormalizeKind(kind string) (string, error) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if kind == "" {
+		return "", nil
+	}
+	if i := strings.IndexByte(kind, '.'); i > 0 {
+		kind = kind[:i]
+	}
+	candidates := []string{kind, kind + "s", kind + "es", strings.TrimSuffix(kind, "y") + "ies"}
+	if full, ok := kindShortNames[kind]; ok {
+		candidates = append([]string{full}, candidates...)
+	}
+	for _, candidate := range candidates {
+		for _, k := range c.kinds {
+			if k == candidate {
+				return k, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("资源清单中没有 %q 类型，可搜索的类型: %s", kind, strings.Join(c.kinds, ", "))
+}
+
+// Search 在各集群的资源清单中搜索，过期的清单先重新列出，单个集群失败不影响其他集群
+func (c *Cache) Search(ctx context.Context, q Query) (*Result, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("inventory_search")()
+
+	kind, err := c.NormalizeKind(q.Kind)
+	if err != nil {
+		return nil, err
+	}
+	targets := q.Clusters
+	if len(targets) == 0 {
+		targets = Clusters()
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	statuses := make([]ClusterStatus, len(targets))
+	snapshots := make([]*kubernetes.Snapshot, len(targets))
+	sem := make(chan struct{}, maxParallelClusters)
+	var wg sync.WaitGroup
+	for i, cluster := range targets {
+		wg.Add(1)
+		go func(i int, cluster string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			snapshot, refreshedAt, err := c.get(ctx, cluster)
+			status := ClusterStatus{Cluster: cluster, RefreshedAt: refreshedAt}
+			if snapshot != nil {
+				status.Objects = len(snapshot.Objects)
+			}
+			if err != nil {
+				status.Error = err.Error()
+			}
+			statuses[i], snapshots[i] = status, snapshot
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	result := &Result{Matches: []Match{}, Clusters: statuses}
+	term := strings.ToLower(q.Name)
+	for i, snapshot := range snapshots {
+		if snapshot == nil {
+			continue
+		}
+		for _, o := range snapshot.Objects {
+			if (kind != "" && o.Kind != kind) || (q.Namespace != "" && o.Namespace != q.Namespace) ||
+				!strings.Contains(strings.ToLower(o.Name), term) {
+				continue
+			}
+			result.Total++
+			if len(result.Matches) < limit {
+				result.Matches = append(result.Matches, Match{Cluster: targets[i], SnapshotObject: o})
+			}
+		}
+	}
+	result.Truncated = result.Total > len(result.Matches)
+	return result, nil
+}
+
+// Invalidate 清除集群的清单，下一次搜索时重新列出；cluster 为空时清除全部
+func (c *Cache) Invalidate(cluster string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cluster == "" {
+		c.entries = map[string]*entry{}
+		return
+	}
+	delete(c.entries, cluster)
+}
+
+// get 返回集群的清单，过期时重新列出；列出失败时返回上一次成功的清单和错误
+func (c *Cache) get(ctx context.Context, cluster string) (*kubernetes.Snapshot, time.Time, error) {
+	c.mu.Lock()
+	e, ok := c.entries[cluster]
+	if !ok {
+		e = &entry{}
+		c.entries[cluster] = e
+	}
+	c.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.snapshot != nil && time.Since(e.refreshedAt) < c.ttl {
+		return e.snapshot, e.refreshedAt, nil
+	}
+
+	snapshot, err := c.list(ctx, cluster)
+	if err != nil {
+		utils.Warn("刷新集群资源清单失败", zap.String("cluster", cluster), zap.Error(err))
+		return e.snapshot, e.refreshedAt, err
+	}
+	e.snapshot, e.refreshedAt = snapshot, snapshot.TakenAt
+	return e.snapshot, e.refreshedAt, nil
+}
+
+func (c *Cache) list(ctx context.Context, cluster string) (*kubernetes.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+	client, err := kubernetes.ClientsetForContext(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.TakeSnapshot(ctx, client, cluster, c.kinds, nil)
+}
+
+// Clusters 默认搜索的集群：配置项 inventory.clusters，未配置时为登记的集群加上 kubeconfig 中
+// 未被登记集群使用的 context；都没有时（集群内运行）为 in-cluster
+func Clusters() []string {
+	if configured := utils.GetConfig().GetStringSlice("inventory.clusters"); len(configured) > 0 {
+		return configured
+	}
+	registry := clusters.Default()
+	var names []string
+	registered := map[string]bool{}
+	for _, c := range registry.List() {
+		names = append(names, c.Name)
+		if c.Kubeconfig == "" && !c.InCluster {
+			registered[c.Context] = true
+		}
+	}
+	contexts, _, err := kubernetes.ListContexts()
+	if err != nil {
+		utils.Warn("读取 kubeconfig 失败，只搜索登记的集群", zap.Error(err))
+	}
+	for _, name := range contexts {
+		if !registered[name] {
+			if _, ok := registry.Get(name); !ok {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return []string{clusters.InClusterContext}
+	}
+	sort.Strings(names)
+	return names
+}
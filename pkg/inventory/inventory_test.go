@@ -0,0 +1,99 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSearch(t *testing.T) {
+	prod := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "payment-api", Namespace: "shop"}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "payment-api", Namespace: "shop"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "order-api", Namespace: "shop"}},
+	)
+	staging := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "Payment-worker", Namespace: "jobs"}},
+	)
+	var mu sync.Mutex
+	calls := map[string]int{}
+	kubernetes.SetClientsetFunc(func(kubeContext string) (k8s.Interface, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[kubeContext]++
+		switch kubeContext {
+		case "prod":
+			return prod, nil
+		case "staging":
+			return staging, nil
+		}
+		return nil, errors.New("context not found")
+	})
+	defer kubernetes.SetClientsetFunc(nil)
+
+	cache := NewCache(time.Hour, []string{"deployments", "services"})
+	ctx := context.Background()
+	result, err := cache.Search(ctx, Query{Name: "payment", Clusters: []string{"prod", "staging", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || len(result.Matches) != 3 {
+		t.Fatalf("matches = %+v, want 3", result.Matches)
+	}
+	if m := result.Matches[2]; m.Cluster != "staging" || m.Namespace != "jobs" || m.Name != "Payment-worker" {
+		t.Errorf("match = %+v, want staging jobs/Payment-worker", m)
+	}
+	if status := result.Clusters[2]; status.Cluster != "missing" || status.Error == "" {
+		t.Errorf("status = %+v, want error for missing cluster", status)
+	}
+
+	result, err = cache.Search(ctx, Query{Kind: "deploy", Name: "payment", Clusters: []string{"prod", "staging"}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(result.Matches) != 1 || !result.Truncated {
+		t.Errorf("result = %+v, want 1 of 2 deployments", result)
+	}
+	if calls["prod"] != 1 || calls["staging"] != 1 {
+		t.Errorf("clusters listed %v times, want once within ttl", calls)
+	}
+
+	cache.Invalidate("prod")
+	if _, err := cache.Search(ctx, Query{Clusters: []string{"prod"}}); err != nil {
+		t.Fatal(err)
+	}
+	if calls["prod"] != 2 {
+		t.Errorf("prod listed %d times after invalidate, want 2", calls["prod"])
+	}
+
+	if _, err := cache.Search(ctx, Query{Kind: "secrets"}); err == nil {
+		t.Error("expected error for kind not in inventory")
+	}
+}
+
+func TestNormalizeKind(t *testing.T) {
+	cache := NewCache(0, nil)
+	for input, want := range map[string]string{
+		"":                 "",
+		"deploy":           "deployments",
+		"Deployment":       "deployments",
+		"deployments.apps": "deployments",
+		"ingress":          "ingresses",
+		"svc":              "services",
+		"NetworkPolicy":    "",
+	} {
+		got, err := cache.NormalizeKind(input)
+		if got != want || (want == "" && input != "" && err == nil) {
+			t.Errorf("NormalizeKind(%q) = %q, %v, want %q", input, got, err, want)
+		}
+	}
+}
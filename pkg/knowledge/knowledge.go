@@ -0,0 +1,320 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// ErrNotFound 别名未登记
+var ErrNotFound = errors.New("alias not found")
+
+// Service 系统提示中介绍的服务，来自 prompts.services 配置和别名知识库
+type Service struct {
+	Name        string   `mapstructure:"name" json:"name"`
+	Kind        string   `mapstructure:"kind" json:"kind,omitempty"` // 工作负载类型，为空时按 Deployment 处理
+	Namespace   string   `mapstructure:"namespace" json:"namespace,omitempty"`
+	Cluster     string   `mapstructure:"cluster" json:"cluster,omitempty"`
+	Description string   `mapstructure:"description" json:"description,omitempty"`
+	Aliases     []string `mapstructure:"aliases" json:"aliases,omitempty"` // 用户常用的简称，例如 "支付"、"pay"
+}
+
+// Alias 管理员登记的服务别名（中文名、简称）到 K8s 资源名称的映射
+type Alias struct {
+	Alias       string    `json:"alias"`
+	Service     string    `json:"service"`
+	Kind        string    `json:"kind,omitempty"`
+	Namespace   string    `json:"namespace,omitempty"`
+	Cluster     string    `json:"cluster,omitempty"`
+	Description string    `json:"description,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
+}
+
+// Store 服务别名知识库，持久化为 JSON 文件
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	aliases []Alias
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// Default 获取全局服务别名知识库，文件由 prompts.aliases_file 配置
+func Default() *Store {
+	defaultStoreOnce.Do(func() {
+		path := utils.GetConfig().GetString("prompts.aliases_file")
+		if path == "" {
+			path = filepath.Join("data", "service_aliases.json")
+		}
+
+		store, err := NewStore(path)
+		if err != nil {
+			utils.Error(fmt.Sprintf("加载服务别名失败: %v", err))
+			store = &Store{path: path}
+		}
+		defaultStore = store
+	})
+	return defaultStore
+}
+
+// NewStore 从文件创建服务别名知识库，文件不存在时创建空知识库
+func NewStore(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.aliases); err != nil {
+		return nil, fmt.Errorf("解析服务别名文件失败: %v", err)
+	}
+	return s, nil
+}
+
+// List 列出所有服务别名
+func (s *Store) List() []Alias {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Alias(nil), s.aliases...)
+}
+
+// Get 获取服务别名（不区分大小写）
+func (s *Store) Get(alias string) (Alias, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if i, ok := s.index(alias); ok {
+		return s.aliases[i], true
+	}
+	return Alias{}, false
+}
+
+// Add 添加服务别名，同名别名（不区分大小写）会被替换
+func (s *Store) Add(alias Alias) error {
+	alias.Alias = strings.TrimSpace(alias.Alias)
+	alias.Service = strings.TrimSpace(alias.Service)
+	if alias.Alias == "" || alias.Service == "" {
+		return fmt.Errorf("别名和服务名称不能为空")
+	}
+	if alias.CreatedAt.IsZero() {
+		alias.CreatedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replace(alias.Alias, alias)
+}
+
+// Update 修改已登记的服务别名，alias 可以改名，保留原创建人和创建时间
+func (s *Store) Update(name string, alias Alias) (Alias, error) {
+	alias.Alias = strings.TrimSpace(alias.Alias)
+	alias.Service = strings.TrimSpace(alias.Service)
+	if alias.Alias == "" {
+		alias.Alias = name
+	}
+	if alias.Service == "" {
+		return Alias{}, fmt.Errorf("服务名称不能为空")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.index(name)
+	if !ok {
+		return Alias{}, ErrNotFound
+	}
+	if _, taken := s.index(alias.Alias); taken && !strings.EqualFold(alias.Alias, name) {
+		return Alias{}, fmt.Errorf("别名 %s 已登记", alias.Alias)
+	}
+	alias.CreatedBy, alias.CreatedAt = s.aliases[i].CreatedBy, s.aliases[i].CreatedAt
+	alias.UpdatedAt = time.Now()
+	if err := s.replace(name, alias); err != nil {
+		return Alias{}, err
+	}
+	return alias, nil
+}
+
+// Delete 删除服务别名
+func (s *Store) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.index(name); !ok {
+		return ErrNotFound
+	}
+	return s.replace(name, Alias{})
+}
+
+// replace 删除名为 name 的别名并追加 alias（alias 为空时只删除）后保存，调用方需持有写锁
+func (s *Store) replace(name string, alias Alias) error {
+	aliases := make([]Alias, 0, len(s.aliases)+1)
+	for _, existing := range s.aliases {
+		if !strings.EqualFold(existing.Alias, name) && !strings.EqualFold(existing.Alias, alias.Alias) {
+			aliases = append(aliases, existing)
+		}
+	}
+	if alias.Alias != "" {
+		aliases = append(aliases, alias)
+	}
+	previous := s.aliases
+	s.aliases = aliases
+	if err := s.save(); err != nil {
+		s.aliases = previous
+		return err
+	}
+	return nil
+}
+
+func (s *Store) index(name string) (int, bool) {
+	for i, existing := range s.aliases {
+		if strings.EqualFold(existing.Alias, name) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// save 将知识库写入文件，调用方需持有写锁
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.aliases, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+var (
+	repoServicesMu sync.RWMutex
+	repoServices   []Service
+)
+
+// SetRepoServices 设置从配置仓库 config_repo 加载的服务登记，替换之前加载的服务
+func SetRepoServices(services []Service) {
+	repoServicesMu.Lock()
+	defer repoServicesMu.Unlock()
+	repoServices = append([]Service(nil), services...)
+}
+
+// Services 返回 prompts.services 中配置的服务和配置仓库中登记的服务，并合并知识库中的别名
+// 配置仓库中与 prompts.services 同名且同命名空间的服务以配置文件为准；别名指向的服务未配置时追加为新的服务
+func Services() []Service {
+	var services []Service
+	if err := utils.GetConfig().UnmarshalKey("prompts.services", &services); err != nil {
+		utils.Error(fmt.Sprintf("解析 prompts.services 失败: %v", err))
+	}
+
+	repoServicesMu.RLock()
+	for _, repo := range repoServices {
+		configured := false
+		for _, s := range services {
+			if strings.EqualFold(s.Name, repo.Name) && s.Namespace == repo.Namespace {
+				configured = true
+				break
+			}
+		}
+		if !configured {
+			repo.Aliases = append([]string(nil), repo.Aliases...)
+			services = append(services, repo)
+		}
+	}
+	repoServicesMu.RUnlock()
+	return MergeAliases(services, Default().List())
+}
+
+// MergeAliases 将别名合并到服务列表中
+func MergeAliases(services []Service, aliases []Alias) []Service {
+	for _, alias := range aliases {
+		merged := false
+		for i := range services {
+			s := &services[i]
+			if strings.EqualFold(s.Name, alias.Service) &&
+				(alias.Namespace == "" || s.Namespace == "" || s.Namespace == alias.Namespace) {
+				s.Aliases = appendAlias(s.Aliases, alias.Alias)
+				if s.Kind == "" {
+					s.Kind = alias.Kind
+				}
+				if s.Description == "" {
+					s.Description = alias.Description
+				}
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			services = append(services, Service{
+				Name:        alias.Service,
+				Kind:        alias.Kind,
+				Namespace:   alias.Namespace,
+				Cluster:     alias.Cluster,
+				Description: alias.Description,
+				Aliases:     []string{alias.Alias},
+			})
+		}
+	}
+	return services
+}
+
+func appendAlias(aliases []string, alias string) []string {
+	for _, existing := range aliases {
+		if strings.EqualFold(existing, alias) {
+			return aliases
+		}
+	}
+	return append(aliases, alias)
+}
+
+// Lookup 按名称、别名或说明查找服务，精确匹配别名或名称的排在前面，term 为空时返回全部服务
+func Lookup(services []Service, term string) []Service {
+	term = strings.ToLower(strings.TrimSpace(term))
+	if term == "" {
+		return services
+	}
+
+	type match struct {
+		service Service
+		score   int
+	}
+	var matches []match
+	for _, s := range services {
+		score := 0
+		for _, name := range append([]string{s.Name}, s.Aliases...) {
+			name = strings.ToLower(name)
+			switch {
+			case name == "":
+			case name == term:
+				score = max(score, 3)
+			case strings.Contains(name, term) || strings.Contains(term, name):
+				score = max(score, 2)
+			}
+		}
+		if score == 0 && strings.Contains(strings.ToLower(s.Description), term) {
+			score = 1
+		}
+		if score > 0 {
+			matches = append(matches, match{s, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+
+	result := make([]Service, len(matches))
+	for i, m := range matches {
+		result[i] = m.service
+	}
+	return result
+}
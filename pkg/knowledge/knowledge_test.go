@@ -0,0 +1,96 @@
+package knowledge
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aliases.json")
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Add(Alias{Alias: "pay", Service: "payment-api", Namespace: "shop"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Add(Alias{Alias: "PAY", Service: "payment-gateway", Namespace: "shop"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Add(Alias{Alias: " "}); err == nil {
+		t.Error("expected error for empty alias")
+	}
+
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aliases := reloaded.List()
+	if len(aliases) != 1 || aliases[0].Service != "payment-gateway" {
+		t.Fatalf("expected replaced alias to persist, got %+v", aliases)
+	}
+
+	services := MergeAliases([]Service{{Name: "payment-gateway", Namespace: "shop"}}, append(aliases, Alias{Alias: "inv", Service: "inventory"}))
+	if len(services) != 2 || len(services[0].Aliases) != 1 || services[1].Name != "inventory" {
+		t.Errorf("unexpected merged services: %+v", services)
+	}
+}
+
+func TestStoreUpdateDelete(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "aliases.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Add(Alias{Alias: "支付", Service: "payment-api", CreatedBy: "alice"})
+	store.Add(Alias{Alias: "订单", Service: "order-api"})
+
+	updated, err := store.Update("支付", Alias{Alias: "支付网关", Service: "payment-gateway", Kind: "statefulset"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.CreatedBy != "alice" || updated.UpdatedAt.IsZero() {
+		t.Errorf("expected creator to be kept, got %+v", updated)
+	}
+	if _, ok := store.Get("支付"); ok {
+		t.Error("expected the old alias to be renamed")
+	}
+	if _, err := store.Update("支付网关", Alias{Alias: "订单", Service: "x"}); err == nil {
+		t.Error("expected error when renaming onto an existing alias")
+	}
+	if _, err := store.Update("missing", Alias{Service: "x"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete("订单"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("订单"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if got := store.List(); len(got) != 1 || got[0].Alias != "支付网关" {
+		t.Errorf("unexpected aliases: %+v", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	services := []Service{
+		{Name: "payment-api", Aliases: []string{"支付", "pay"}, Description: "支付服务"},
+		{Name: "gateway", Aliases: []string{"网关"}, Description: "入口网关，转发到 payment-api"},
+		{Name: "order-api", Aliases: []string{"订单"}},
+	}
+	got := Lookup(services, "支付")
+	if len(got) != 1 || got[0].Name != "payment-api" {
+		t.Errorf("expected payment-api, got %+v", got)
+	}
+	// 名称匹配排在说明匹配之前
+	got = Lookup(services, "payment")
+	if len(got) != 2 || got[0].Name != "payment-api" || got[1].Name != "gateway" {
+		t.Errorf("expected payment-api before gateway, got %+v", got)
+	}
+	if got := Lookup(services, "入口"); len(got) != 1 || got[0].Name != "gateway" {
+		t.Errorf("expected description match, got %+v", got)
+	}
+	if got := Lookup(services, ""); len(got) != 3 {
+		t.Errorf("expected all services for an empty term, got %+v", got)
+	}
+}
@@ -0,0 +1,197 @@
+package knowledge
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const (
+	// defaultServiceConfidenceThreshold 默认置信度阈值，低于该值需要用户从候选服务中选择
+	defaultServiceConfidenceThreshold = 0.8
+	// serviceAmbiguityMargin 最佳候选与次佳候选的分差低于该值时降低置信度
+	serviceAmbiguityMargin = 0.15
+)
+
+// ServiceCandidate 别名对应的候选服务及其得分
+type ServiceCandidate struct {
+	Service
+	Score float64 `json:"score"`
+}
+
+// Ref 候选服务的引用，用户选择时提交该值："命名空间/名称"，带集群时为"集群:命名空间/名称"
+func (c ServiceCandidate) Ref() string {
+	ref := c.Name
+	if c.Namespace != "" {
+		ref = c.Namespace + "/" + ref
+	}
+	if c.Cluster != "" {
+		ref = c.Cluster + ":" + ref
+	}
+	return ref
+}
+
+// ServiceResolution 问题中提到的别名对应多个服务时的解析结果
+// NeedsConfirmation 为 true 时不应直接使用 Candidate，需由用户从 Candidates 中选择
+type ServiceResolution struct {
+	Alias             string             `json:"alias"`
+	Candidate         string             `json:"candidate"` // 得分最高的候选服务的引用
+	Confidence        float64            `json:"confidence"`
+	Threshold         float64            `json:"threshold"`
+	NeedsConfirmation bool               `json:"needs_confirmation"`
+	Candidates        []ServiceCandidate `json:"candidates"`
+}
+
+// Pick 按引用选择候选服务，ref 可以是 Ref 的完整形式，也可以省略集群或命名空间，只匹配唯一的候选服务时有效
+func (r ServiceResolution) Pick(ref string) (ServiceCandidate, bool) {
+	ref = strings.TrimSpace(ref)
+	var picked []ServiceCandidate
+	for _, c := range r.Candidates {
+		full := c.Ref()
+		if strings.EqualFold(full, ref) {
+			return c, true
+		}
+		if strings.HasSuffix(strings.ToLower(full), strings.ToLower(ref)) &&
+			(len(full) == len(ref) || strings.ContainsRune(":/", rune(full[len(full)-len(ref)-1]))) {
+			picked = append(picked, c)
+		}
+	}
+	if len(picked) != 1 {
+		return ServiceCandidate{}, false
+	}
+	return picked[0], true
+}
+
+// Chosen 得分最高的候选服务
+func (r ServiceResolution) Chosen() ServiceCandidate {
+	return r.Candidates[0]
+}
+
+// ResolveServices 找出问题中提到的、对应多个服务的名称或别名（例如多个命名空间中都登记为"低代码后端"的服务），
+// 按目标集群、问题中提到的命名空间和资源名称为候选服务打分
+// 只有一个匹配服务的别名不返回；较长的别名包含较短的别名时只按较长的别名解析
+func ResolveServices(services []Service, question, cluster string, threshold float64) []ServiceResolution {
+	lower := strings.ToLower(question)
+
+	type key struct{ name, namespace, cluster string }
+	matched := map[string][]Service{}
+	seen := map[string]map[key]bool{}
+	for _, s := range services {
+		for _, term := range append([]string{s.Name}, s.Aliases...) {
+			term = strings.ToLower(strings.TrimSpace(term))
+			if term == "" || !mentions(lower, term) {
+				continue
+			}
+			k := key{strings.ToLower(s.Name), s.Namespace, s.Cluster}
+			if seen[term] == nil {
+				seen[term] = map[key]bool{}
+			}
+			if !seen[term][k] {
+				seen[term][k] = true
+				matched[term] = append(matched[term], s)
+			}
+		}
+	}
+
+	terms := make([]string, 0, len(matched))
+	for term := range matched {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	var resolutions []ServiceResolution
+	for _, term := range terms {
+		if len(matched[term]) < 2 || containedInLonger(term, terms) {
+			continue
+		}
+		candidates := make([]ServiceCandidate, 0, len(matched[term]))
+		for _, s := range matched[term] {
+			candidates = append(candidates, ServiceCandidate{Service: s, Score: scoreService(s, lower, term, cluster)})
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].Score != candidates[j].Score {
+				return candidates[i].Score > candidates[j].Score
+			}
+			return candidates[i].Ref() < candidates[j].Ref()
+		})
+
+		confidence := candidates[0].Score
+		if margin := candidates[0].Score - candidates[1].Score; margin < serviceAmbiguityMargin {
+			confidence = roundScore(confidence * (0.5 + margin/(2*serviceAmbiguityMargin)))
+		}
+		resolutions = append(resolutions, ServiceResolution{
+			Alias:             term,
+			Candidate:         candidates[0].Ref(),
+			Confidence:        confidence,
+			Threshold:         threshold,
+			NeedsConfirmation: confidence < threshold,
+			Candidates:        candidates,
+		})
+	}
+	return resolutions
+}
+
+// ServiceConfidenceThreshold 自动选择候选服务的置信度阈值，配置项 prompts.service_confidence_threshold
+func ServiceConfidenceThreshold() float64 {
+	config := utils.GetConfig()
+	if config.IsSet("prompts.service_confidence_threshold") {
+		return config.GetFloat64("prompts.service_confidence_threshold")
+	}
+	return defaultServiceConfidenceThreshold
+}
+
+// scoreService 候选服务与问题的匹配程度，在 [0, 1] 之间：
+// 服务登记在目标集群加分、登记在其他集群减分，问题中提到服务的命名空间或资源名称时加分
+func scoreService(s Service, question, term, cluster string) float64 {
+	score := 0.5
+	if cluster != "" && s.Cluster != "" {
+		if strings.EqualFold(s.Cluster, cluster) {
+			score += 0.3
+		} else {
+			score -= 0.3
+		}
+	}
+	if s.Namespace != "" && mentions(question, strings.ToLower(s.Namespace)) {
+		score += 0.3
+	}
+	if name := strings.ToLower(s.Name); name != term && mentions(question, name) {
+		score += 0.4
+	}
+	return roundScore(math.Min(score, 1))
+}
+
+// mentions 判断问题中是否提到 term，英文和数字组成的名称需要完整出现，避免 pay 匹配 payment
+func mentions(question, term string) bool {
+	for offset := 0; ; {
+		i := strings.Index(question[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		if (start == 0 || !isNameByte(question[start-1]) || !isNameByte(term[0])) &&
+			(end == len(question) || !isNameByte(question[end]) || !isNameByte(term[len(term)-1])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '-' || b == '_' || b == '.'
+}
+
+// containedInLonger 判断 term 是否是问题中提到的其他名称的一部分，例如"订单"之于"订单后端"
+func containedInLonger(term string, terms []string) bool {
+	for _, other := range terms {
+		if other != term && strings.Contains(other, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func roundScore(f float64) float64 {
+	return math.Round(f*100) / 100
+}
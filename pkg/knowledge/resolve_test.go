@@ -0,0 +1,45 @@
+package knowledge
+
+import "testing"
+
+func TestResolveServices(t *testing.T) {
+	services := []Service{
+		{Name: "lowcode-api", Namespace: "lowcode", Cluster: "prod", Aliases: []string{"低代码后端"}},
+		{Name: "lowcode-server", Namespace: "platform", Aliases: []string{"低代码后端"}},
+		{Name: "payment-api", Namespace: "shop", Aliases: []string{"pay"}},
+	}
+
+	// 两个服务得分相同，需要用户选择
+	resolutions := ResolveServices(services, "低代码后端为什么一直重启", "", 0.8)
+	if len(resolutions) != 1 || !resolutions[0].NeedsConfirmation || len(resolutions[0].Candidates) != 2 {
+		t.Fatalf("expected an ambiguous alias, got %+v", resolutions)
+	}
+	if c, ok := resolutions[0].Pick("platform/lowcode-server"); !ok || c.Name != "lowcode-server" {
+		t.Errorf("Pick(namespace/name) = %+v, %v", c, ok)
+	}
+	if c, ok := resolutions[0].Pick("lowcode-api"); !ok || c.Namespace != "lowcode" {
+		t.Errorf("Pick(name) = %+v, %v", c, ok)
+	}
+	if _, ok := resolutions[0].Pick("api"); ok {
+		t.Error("expected a partial name not to pick a candidate")
+	}
+
+	// 目标集群和命名空间可以自动确定
+	for _, tt := range []struct{ question, cluster, want string }{
+		{"低代码后端为什么一直重启", "prod", "prod:lowcode/lowcode-api"},
+		{"platform 里的低代码后端为什么一直重启", "", "platform/lowcode-server"},
+	} {
+		resolutions := ResolveServices(services, tt.question, tt.cluster, 0.8)
+		if len(resolutions) != 1 || resolutions[0].NeedsConfirmation || resolutions[0].Candidate != tt.want {
+			t.Errorf("ResolveServices(%q, %q) = %+v, want %s", tt.question, tt.cluster, resolutions, tt.want)
+		}
+	}
+
+	// 只有一个匹配的别名和未完整出现的英文别名不需要解析
+	if resolutions := ResolveServices(services, "payment 的 pay 接口报错", "", 0.8); len(resolutions) != 0 {
+		t.Errorf("expected no ambiguity, got %+v", resolutions)
+	}
+	if mentions("payment-api 报错", "pay") || !mentions("pay 报错", "pay") {
+		t.Error("expected ASCII aliases to match whole words only")
+	}
+}
@@ -0,0 +1,204 @@
+package kubeaudit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// maxDetailRunes 请求内容摘要保留的字符数
+const maxDetailRunes = 300
+
+// defaultVerbs 默认只记录会修改资源的请求
+var defaultVerbs = []string{"create", "update", "patch", "delete", "deletecollection"}
+
+// ErrStoreDisabled 未启用审计存储，无法保存集群审计日志
+var ErrStoreDisabled = errors.New("audit store is not enabled")
+
+// event apiserver 审计事件（audit.k8s.io/v1 Event）中用到的字段
+// webhook 后端推送 EventList，日志后端每行一个 Event，两者共用该结构
+type event struct {
+	Kind             string   `json:"kind"`
+	Items            []event  `json:"items"`
+	AuditID          string   `json:"auditID"`
+	Stage            string   `json:"stage"`
+	Verb             string   `json:"verb"`
+	User             user     `json:"user"`
+	ImpersonatedUser *user    `json:"impersonatedUser"`
+	SourceIPs        []string `json:"sourceIPs"`
+	UserAgent        string   `json:"userAgent"`
+	ObjectRef        *struct {
+		Resource    string `json:"resource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		Subresource string `json:"subresource"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	RequestObject  json.RawMessage `json:"requestObject"`
+	StageTimestamp time.Time       `json:"stageTimestamp"`
+}
+
+type user struct {
+	Username string `json:"username"`
+}
+
+// Enabled 是否启用集群审计日志接入，配置项 kube_audit.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("kube_audit.enabled")
+}
+
+// Parse 解析 apiserver 审计日志，支持 gzip 压缩、JSON Lines（日志后端）和 EventList（webhook 后端）
+// 只保留成功完成的变更请求，动作由 kube_audit.verbs 配置，kube_audit.exclude_users 中前缀匹配的用户忽略
+func Parse(r io.Reader, cluster string) ([]audit.KubeAuditEvent, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	filter := newFilter()
+	var events []audit.KubeAuditEvent
+	decoder := json.NewDecoder(br)
+	for {
+		var e event
+		err := decoder.Decode(&e)
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, fmt.Errorf("解析审计日志失败: %v", err)
+		}
+		items := []event{e}
+		if e.Kind == "EventList" {
+			items = e.Items
+		}
+		for _, item := range items {
+			if filter.include(item) {
+				events = append(events, convert(cluster, item))
+			}
+		}
+	}
+}
+
+// Ingest 解析审计日志并写入审计存储，返回新写入的事件数量
+func Ingest(ctx context.Context, cluster string, r io.Reader) (int, error) {
+	store := audit.GetStore()
+	if store == nil {
+		return 0, ErrStoreDisabled
+	}
+	events, err := Parse(r, cluster)
+	if len(events) == 0 {
+		return 0, err
+	}
+	inserted, saveErr := store.SaveKubeAuditEvents(ctx, events)
+	if saveErr != nil {
+		return 0, saveErr
+	}
+	utils.GetPerfStats().IncrCounter("kube_audit_ingest")
+	return inserted, err
+}
+
+type filter struct {
+	verbs        map[string]bool
+	excludeUsers []string
+}
+
+func newFilter() filter {
+	config := utils.GetConfig()
+	verbs := defaultVerbs
+	if config.IsSet("kube_audit.verbs") {
+		verbs = config.GetStringSlice("kube_audit.verbs")
+	}
+	f := filter{verbs: map[string]bool{}, excludeUsers: config.GetStringSlice("kube_audit.exclude_users")}
+	for _, verb := range verbs {
+		f.verbs[strings.ToLower(verb)] = true
+	}
+	return f
+}
+
+func (f filter) include(e event) bool {
+	if e.Stage != "ResponseComplete" || e.ObjectRef == nil || !f.verbs[e.Verb] {
+		return false
+	}
+	if e.ResponseStatus != nil && (e.ResponseStatus.Code < 200 || e.ResponseStatus.Code >= 300) {
+		return false
+	}
+	for _, prefix := range f.excludeUsers {
+		if prefix != "" && strings.HasPrefix(e.User.Username, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func convert(cluster string, e event) audit.KubeAuditEvent {
+	username := e.User.Username
+	if e.ImpersonatedUser != nil && e.ImpersonatedUser.Username != "" {
+		username = fmt.Sprintf("%s (via %s)", e.ImpersonatedUser.Username, e.User.Username)
+	}
+	out := audit.KubeAuditEvent{
+		Cluster:     cluster,
+		AuditID:     e.AuditID,
+		Time:        e.StageTimestamp,
+		Verb:        e.Verb,
+		Username:    username,
+		UserAgent:   e.UserAgent,
+		Namespace:   e.ObjectRef.Namespace,
+		Resource:    e.ObjectRef.Resource,
+		Subresource: e.ObjectRef.Subresource,
+		Name:        e.ObjectRef.Name,
+		Detail:      detail(e),
+	}
+	if len(e.SourceIPs) > 0 {
+		out.SourceIP = e.SourceIPs[0]
+	}
+	if e.ResponseStatus != nil {
+		out.ResponseCode = e.ResponseStatus.Code
+	}
+	return out
+}
+
+// detail 请求内容摘要：scale 记录目标副本数，patch 记录补丁内容，其他请求的完整对象过大不记录
+func detail(e event) string {
+	if len(e.RequestObject) == 0 {
+		return ""
+	}
+	if e.ObjectRef.Subresource == "scale" {
+		var scale struct {
+			Spec struct {
+				Replicas *int `json:"replicas"`
+			} `json:"spec"`
+		}
+		if err := json.Unmarshal(e.RequestObject, &scale); err == nil && scale.Spec.Replicas != nil {
+			return fmt.Sprintf("replicas=%d", *scale.Spec.Replicas)
+		}
+	}
+	if e.Verb != "patch" {
+		return ""
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, e.RequestObject); err != nil {
+		return ""
+	}
+	runes := []rune(compact.String())
+	if len(runes) > maxDetailRunes {
+		return string(runes[:maxDetailRunes]) + "..."
+	}
+	return string(runes)
+}
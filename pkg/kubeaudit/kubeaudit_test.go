@@ -0,0 +1,55 @@
+package kubeaudit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+const auditLines = `{"kind":"Event","auditID":"a1","stage":"ResponseComplete","verb":"patch","user":{"username":"alice"},"sourceIPs":["10.0.0.8"],"userAgent":"kubectl/v1.29.2 (linux/amd64)","objectRef":{"resource":"deployments","namespace":"edge","name":"gateway","subresource":"scale"},"responseStatus":{"code":200},"requestObject":{"spec":{"replicas":6}},"stageTimestamp":"2025-03-01T10:00:00Z"}
+{"kind":"Event","auditID":"a2","stage":"ResponseComplete","verb":"get","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"edge"},"responseStatus":{"code":200},"stageTimestamp":"2025-03-01T10:01:00Z"}
+{"kind":"Event","auditID":"a3","stage":"RequestReceived","verb":"delete","user":{"username":"bob"},"objectRef":{"resource":"pods","namespace":"edge","name":"gateway-1"},"stageTimestamp":"2025-03-01T10:02:00Z"}
+{"kind":"Event","auditID":"a4","stage":"ResponseComplete","verb":"update","user":{"username":"carol"},"objectRef":{"resource":"configmaps","namespace":"edge","name":"gateway"},"responseStatus":{"code":409},"stageTimestamp":"2025-03-01T10:03:00Z"}
+`
+
+func TestParseLines(t *testing.T) {
+	events, err := Parse(strings.NewReader(auditLines), "cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 只保留成功完成的变更请求
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %+v", events)
+	}
+	e := events[0]
+	if e.Cluster != "cn" || e.Username != "alice" || e.Resource != "deployments" || e.Subresource != "scale" ||
+		e.Name != "gateway" || e.Detail != "replicas=6" || e.SourceIP != "10.0.0.8" {
+		t.Errorf("unexpected event: %+v", e)
+	}
+}
+
+func TestParseEventListGzip(t *testing.T) {
+	list := `{"kind":"EventList","items":[
+		{"auditID":"b1","stage":"ResponseComplete","verb":"patch","user":{"username":"system:serviceaccount:argocd:argocd-application-controller"},"impersonatedUser":{"username":"dave"},"objectRef":{"resource":"deployments","namespace":"edge","name":"gateway"},"responseStatus":{"code":200},"requestObject":{"spec": {"template": {"spec": {"containers": [{"name": "gateway", "image": "gateway:v2"}]}}}},"stageTimestamp":"2025-03-01T11:00:00Z"},
+		{"auditID":"b2","stage":"ResponseComplete","verb":"delete","user":{"username":"erin"},"objectRef":{"resource":"pods","namespace":"edge","name":"gateway-1"},"responseStatus":{"code":200},"stageTimestamp":"2025-03-01T11:01:00Z"}
+	]}`
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(list))
+	gz.Close()
+
+	events, err := Parse(&buf, "cn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", events)
+	}
+	if got := events[0].Username; got != "dave (via system:serviceaccount:argocd:argocd-application-controller)" {
+		t.Errorf("unexpected impersonated username %q", got)
+	}
+	if got := events[0].Detail; got != `{"spec":{"template":{"spec":{"containers":[{"name":"gateway","image":"gateway:v2"}]}}}}` {
+		t.Errorf("expected compact patch detail, got %q", got)
+	}
+}
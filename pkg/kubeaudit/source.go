@@ -0,0 +1,214 @@
+package kubeaudit
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+	// maxObjectsPerPoll 每次轮询最多处理的对象数，积压时分多次处理
+	maxObjectsPerPoll = 200
+	// maxObjectSize 单个审计日志对象的大小上限
+	maxObjectSize = 256 << 20
+)
+
+// Source 存放集群审计日志的 S3 兼容存储（AWS S3、MinIO、OSS 的 S3 兼容接口）
+// apiserver 日志后端轮转的文件按对象名顺序处理，对象名需要按时间递增（例如包含时间戳）
+type Source struct {
+	Cluster         string `mapstructure:"cluster"`
+	Endpoint        string `mapstructure:"endpoint"`
+	Bucket          string `mapstructure:"bucket"`
+	Prefix          string `mapstructure:"prefix"`
+	Region          string `mapstructure:"region"`
+	AccessKeyID     string `mapstructure:"access_key_id"` // 为空时读取 AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+}
+
+// ID 游标中使用的来源标识
+func (s Source) ID() string {
+	return "s3:" + s.Cluster + ":" + s.Bucket + "/" + s.Prefix
+}
+
+var (
+	httpClient     *http.Client
+	httpClientOnce sync.Once
+)
+
+// Start 为 kube_audit.sources 中的每个来源启动后台轮询，每 kube_audit.interval（默认 5m）拉取新的审计日志对象
+func Start(ctx context.Context) {
+	var sources []Source
+	if err := utils.GetConfig().UnmarshalKey("kube_audit.sources", &sources); err != nil {
+		utils.Error("解析集群审计日志来源配置失败", zap.Error(err))
+		return
+	}
+	interval := utils.GetConfig().GetDuration("kube_audit.interval")
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	for _, source := range sources {
+		if source.Cluster == "" || source.Endpoint == "" || source.Bucket == "" {
+			utils.Warn("集群审计日志来源缺少 cluster、endpoint 或 bucket，已忽略", zap.String("source", source.ID()))
+			continue
+		}
+		go poll(ctx, source, interval)
+	}
+}
+
+func poll(ctx context.Context, source Source, interval time.Duration) {
+	logger := utils.GetLogger().Named("kube_audit").With(zap.String("cluster", source.Cluster))
+	logger.Info("开始接入集群审计日志", zap.String("source", source.ID()))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := Sync(ctx, source); err != nil {
+			logger.Warn("拉取集群审计日志失败", zap.Error(err))
+		} else if n > 0 {
+			logger.Info("已接入集群审计日志", zap.Int("events", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync 处理来源中游标之后的新对象，返回新写入的事件数量
+// 每个对象处理完成后推进游标，失败时下次轮询从失败的对象重新开始
+func Sync(ctx context.Context, source Source) (int, error) {
+	store := audit.GetStore()
+	if store == nil {
+		return 0, ErrStoreDisabled
+	}
+	cursor, err := store.KubeAuditCursor(ctx, source.ID())
+	if err != nil {
+		return 0, err
+	}
+	keys, err := listObjects(ctx, source, cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, key := range keys {
+		n, err := ingestObject(ctx, source, key)
+		if err != nil {
+			return total, fmt.Errorf("处理审计日志 %s 失败: %v", key, err)
+		}
+		total += n
+		if err := store.SetKubeAuditCursor(ctx, source.ID(), key); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// listBucketResult ListObjectsV2 响应
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listObjects 按对象名顺序列出 startAfter 之后的对象，最多 maxObjectsPerPoll 个
+func listObjects(ctx context.Context, source Source, startAfter string) ([]string, error) {
+	var keys []string
+	token := ""
+	for len(keys) < maxObjectsPerPoll {
+		query := url.Values{"list-type": {"2"}, "prefix": {source.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		} else if startAfter != "" {
+			query.Set("start-after", startAfter)
+		}
+		resp, err := do(ctx, source, "/"+source.Bucket, query)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析对象列表失败: %v", err)
+		}
+		for _, c := range result.Contents {
+			if !strings.HasSuffix(c.Key, "/") {
+				keys = append(keys, c.Key)
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if len(keys) > maxObjectsPerPoll {
+		keys = keys[:maxObjectsPerPoll]
+	}
+	return keys, nil
+}
+
+func ingestObject(ctx context.Context, source Source, key string) (int, error) {
+	resp, err := do(ctx, source, "/"+path.Join(source.Bucket, key), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return Ingest(ctx, source.Cluster, io.LimitReader(resp.Body, maxObjectSize))
+}
+
+// do 发送签名的 GET 请求，非 2xx 响应返回错误
+func do(ctx context.Context, source Source, objectPath string, query url.Values) (*http.Response, error) {
+	httpClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("kube_audit")
+		if err != nil {
+			utils.Warn("创建集群审计日志 HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = http.DefaultClient
+		}
+		httpClient = client
+	})
+
+	rawURL := strings.TrimSuffix(source.Endpoint, "/") + objectPath
+	if len(query) > 0 {
+		rawURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	accessKeyID, secretAccessKey := source.AccessKeyID, source.SecretAccessKey
+	if accessKeyID == "" {
+		accessKeyID, secretAccessKey = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	region := source.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	utils.SignS3Request(req, accessKeyID, secretAccessKey, region, time.Now())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s 返回 HTTP %d: %s", objectPath, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
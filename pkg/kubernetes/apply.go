@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"path/filepath"
 
@@ -20,6 +21,9 @@ import (
 	"k8s.io/client-go/util/homedir"
 )
 
+// fieldManager is the field manager used for server-side apply.
+const fieldManager = "application/apply-patch"
+
 // GetKubeConfig gets kubeconfig.
 func GetKubeConfig() (*rest.Config, error) {
 	config, err := rest.InClusterConfig()
@@ -36,68 +40,140 @@ func GetKubeConfig() (*rest.Config, error) {
 
 // ApplyYaml applies the manifests into Kubernetes cluster.
 func ApplyYaml(manifests string) error {
-	config, err := GetKubeConfig()
-	if err != nil {
-		return err
-	}
+	_, err := applyManifests(manifests, metav1.ApplyOptions{FieldManager: fieldManager})
+	return err
+}
 
-	// Create a new clientset which include all needed client APIs
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-	dynamicclient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return err
-	}
+// DryRunYaml applies the manifests with server-side dry-run and returns the affected objects.
+func DryRunYaml(manifests string) ([]string, error) {
+	return applyManifests(manifests, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+}
 
-	// Decode the yaml file into a Kubernetes object
+// ParseYaml decodes the manifests into unstructured objects and validates the required fields.
+func ParseYaml(manifests string) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
 	decode := yaml.NewYAMLOrJSONDecoder(bytes.NewReader([]byte(manifests)), 100)
 	for {
 		var rawObj runtime.RawExtension
-		if err = decode.Decode(&rawObj); err != nil {
+		if err := decode.Decode(&rawObj); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return nil, err
+		}
+		if len(bytes.TrimSpace(rawObj.Raw)) == 0 {
+			continue
 		}
 
-		obj, gvk, err := yamlserializer.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(rawObj.Raw, nil, nil)
+		obj, _, err := yamlserializer.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(rawObj.Raw, nil, nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		unstructuredMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		unstructuredObj := &unstructured.Unstructured{Object: unstructuredMap}
-		if unstructuredObj.GetNamespace() == "" {
-			unstructuredObj.SetNamespace("default")
+		if unstructuredObj.GetAPIVersion() == "" || unstructuredObj.GetKind() == "" {
+			return nil, fmt.Errorf("manifest is missing apiVersion or kind")
 		}
-
-		grs, err := restmapper.GetAPIGroupResources(clientset.Discovery())
-		if err != nil {
-			return err
+		if unstructuredObj.GetName() == "" {
+			return nil, fmt.Errorf("%s is missing metadata.name", unstructuredObj.GetKind())
 		}
+		objects = append(objects, unstructuredObj)
+	}
+
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("no Kubernetes objects found in manifests")
+	}
+	return objects, nil
+}
 
-		mapping, err := restmapper.NewDiscoveryRESTMapper(grs).RESTMapping(gvk.GroupKind(), gvk.Version)
+// ObjectRef returns a human-readable reference of the object, e.g. Deployment/default/nginx.
+func ObjectRef(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return fmt.Sprintf("%s/%s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s/%s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
+
+// applyManifests applies the manifests with the given options and returns the affected objects.
+func applyManifests(manifests string, opts metav1.ApplyOptions, mutate ...func(*unstructured.Unstructured)) ([]string, error) {
+	objects, err := ParseYaml(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceFor, err := newResourceResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []string
+	for _, unstructuredObj := range objects {
+		dri, err := resourceFor(unstructuredObj)
 		if err != nil {
-			return err
+			return applied, err
 		}
-
-		var dri dynamic.ResourceInterface
-		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
-			dri = dynamicclient.Resource(mapping.Resource).Namespace(unstructuredObj.GetNamespace())
-		} else {
-			dri = dynamicclient.Resource(mapping.Resource)
+		for _, fn := range mutate {
+			fn(unstructuredObj)
 		}
 
-		if _, err := dri.Apply(context.Background(), unstructuredObj.GetName(), unstructuredObj, metav1.ApplyOptions{FieldManager: "application/apply-patch"}); err != nil {
-			return err
+		if _, err := dri.Apply(context.Background(), unstructuredObj.GetName(), unstructuredObj, opts); err != nil {
+			return applied, fmt.Errorf("%s: %v", ObjectRef(unstructuredObj), err)
 		}
+		applied = append(applied, ObjectRef(unstructuredObj))
 	}
 
-	return nil
+	return applied, nil
+}
+
+// newResourceResolver returns a function mapping objects to their dynamic resource clients.
+// Namespaced objects without a namespace are defaulted to "default".
+func newResourceResolver() (func(*unstructured.Unstructured) (dynamic.ResourceInterface, error), error) {
+	config, err := GetKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return resourceResolverForConfig(config)
+}
+
+// resourceResolverForConfig is newResourceResolver for the cluster of the given config.
+func resourceResolverForConfig(config *rest.Config) (func(*unstructured.Unstructured) (dynamic.ResourceInterface, error), error) {
+	// Create a new clientset which include all needed client APIs
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicclient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	grs, err := restmapper.GetAPIGroupResources(clientset.Discovery())
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(grs)
+
+	return func(obj *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return nil, err
+		}
+
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace("default")
+			}
+			return dynamicclient.Resource(mapping.Resource).Namespace(obj.GetNamespace()), nil
+		}
+		return dynamicclient.Resource(mapping.Resource), nil
+	}, nil
 }
@@ -0,0 +1,278 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupported is returned by ParseCommand for commands the native executor does not
+// handle; callers fall back to running kubectl through the shell.
+var ErrUnsupported = errors.New("not supported by the native kubectl executor")
+
+// Command is a kubectl invocation that the native executor runs with client-go.
+type Command struct {
+	Verb          string   // get, describe, logs, top or events
+	Args          []string // positional arguments after the verb
+	Namespace     string
+	AllNamespaces bool
+	Selector      string
+	FieldSelector string
+	Output        string // get: "", wide, yaml, json, name, jsonpath=..., custom-columns=...
+	NoHeaders     bool
+	Container     string
+	Tail          int64 // logs --tail, -1 when not set
+	Since         time.Duration
+	Previous      bool
+	Timestamps    bool
+	Containers    bool   // top pods --containers
+	SortBy        string // top --sort-by: cpu or memory
+	For           string // events --for kind/name
+	Types         []string
+	Context       string
+	Kubeconfig    string
+}
+
+// commandFlag describes a kubectl flag understood by the native executor.
+type commandFlag struct {
+	value bool   // takes a value
+	verbs string // verbs accepting the flag, empty for all
+	set   func(*Command, string) error
+}
+
+var commandFlags = map[string]*commandFlag{
+	"namespace": {value: true, set: func(c *Command, v string) error { c.Namespace = v; return nil }},
+	"all-namespaces": {verbs: "get describe top events", set: func(c *Command, v string) (err error) {
+		c.AllNamespaces, err = strconv.ParseBool(v)
+		return err
+	}},
+	"context":        {value: true, set: func(c *Command, v string) error { c.Context = v; return nil }},
+	"kubeconfig":     {value: true, set: func(c *Command, v string) error { c.Kubeconfig = v; return nil }},
+	"selector":       {value: true, verbs: "get describe logs top", set: func(c *Command, v string) error { c.Selector = v; return nil }},
+	"field-selector": {value: true, verbs: "get", set: func(c *Command, v string) error { c.FieldSelector = v; return nil }},
+	"output":         {value: true, verbs: "get", set: func(c *Command, v string) error { c.Output = v; return nil }},
+	"no-headers": {verbs: "get top events", set: func(c *Command, v string) (err error) {
+		c.NoHeaders, err = strconv.ParseBool(v)
+		return err
+	}},
+	"container": {value: true, verbs: "logs", set: func(c *Command, v string) error { c.Container = v; return nil }},
+	"tail": {value: true, verbs: "logs", set: func(c *Command, v string) (err error) {
+		c.Tail, err = strconv.ParseInt(v, 10, 64)
+		return err
+	}},
+	"since": {value: true, verbs: "logs", set: func(c *Command, v string) (err error) {
+		c.Since, err = time.ParseDuration(v)
+		return err
+	}},
+	"previous": {verbs: "logs", set: func(c *Command, v string) (err error) {
+		c.Previous, err = strconv.ParseBool(v)
+		return err
+	}},
+	"timestamps": {verbs: "logs", set: func(c *Command, v string) (err error) {
+		c.Timestamps, err = strconv.ParseBool(v)
+		return err
+	}},
+	"containers": {verbs: "top", set: func(c *Command, v string) (err error) {
+		c.Containers, err = strconv.ParseBool(v)
+		return err
+	}},
+	"sort-by": {value: true, verbs: "top", set: func(c *Command, v string) error {
+		if v != "cpu" && v != "memory" {
+			return fmt.Errorf("%w: --sort-by %s", ErrUnsupported, v)
+		}
+		c.SortBy = v
+		return nil
+	}},
+	"for":   {value: true, verbs: "events", set: func(c *Command, v string) error { c.For = v; return nil }},
+	"types": {value: true, verbs: "events", set: func(c *Command, v string) error { c.Types = strings.Split(v, ","); return nil }},
+}
+
+// commandShorthands maps single-letter flags to their long names.
+var commandShorthands = map[string]string{
+	"n": "namespace", "A": "all-namespaces", "l": "selector", "o": "output", "c": "container", "p": "previous",
+}
+
+// nativeVerbs are the kubectl subcommands handled by the native executor.
+var nativeVerbs = map[string]bool{"get": true, "describe": true, "logs": true, "top": true, "events": true}
+
+// ParseCommand parses a kubectl command line without invoking a shell. Quotes and
+// backslash escapes are interpreted the way bash would; commands using pipes,
+// redirections, variable expansion or command substitution, other subcommands, and
+// flags the native executor does not implement return an error wrapping ErrUnsupported.
+func ParseCommand(command string) (*Command, error) {
+	words, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(words) > 0 && words[0] == "kubectl" {
+		words = words[1:]
+	}
+
+	cmd := &Command{Tail: -1}
+	var positionals, flags []string
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if word == "--" {
+			return nil, fmt.Errorf("%w: arguments after --", ErrUnsupported)
+		}
+		if !strings.HasPrefix(word, "-") || word == "-" {
+			positionals = append(positionals, word)
+			continue
+		}
+
+		var name, value string
+		hasValue := false
+		if strings.HasPrefix(word, "--") {
+			name = word[2:]
+			if j := strings.IndexByte(name, '='); j >= 0 {
+				name, value, hasValue = name[:j], name[j+1:], true
+			}
+		} else {
+			long, ok := commandShorthands[word[1:2]]
+			if !ok {
+				return nil, fmt.Errorf("%w: flag %s", ErrUnsupported, word)
+			}
+			name = long
+			if rest := strings.TrimPrefix(word[2:], "="); rest != "" {
+				value, hasValue = rest, true
+			}
+		}
+		flag, ok := commandFlags[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: flag --%s", ErrUnsupported, name)
+		}
+		if !hasValue {
+			if flag.value {
+				if i+1 >= len(words) {
+					return nil, fmt.Errorf("flag --%s needs a value", name)
+				}
+				i++
+				value = words[i]
+			} else {
+				value = "true"
+			}
+		}
+		if err := flag.set(cmd, value); err != nil {
+			return nil, fmt.Errorf("invalid value %q for --%s: %w", value, name, err)
+		}
+		flags = append(flags, name)
+	}
+
+	if len(positionals) == 0 || !nativeVerbs[positionals[0]] {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, strings.Join(positionals, " "))
+	}
+	cmd.Verb, cmd.Args = positionals[0], positionals[1:]
+	for _, name := range flags {
+		if verbs := commandFlags[name].verbs; verbs != "" && !strings.Contains(" "+verbs+" ", " "+cmd.Verb+" ") {
+			return nil, fmt.Errorf("%w: flag --%s for %s", ErrUnsupported, name, cmd.Verb)
+		}
+	}
+	if err := cmd.validate(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// validate rejects argument combinations the native executor does not implement.
+func (c *Command) validate() error {
+	switch c.Verb {
+	case "get":
+		if len(c.Args) == 0 {
+			return errors.New("get needs a resource type")
+		}
+		switch format, _, _ := strings.Cut(c.Output, "="); format {
+		case "", "wide", "yaml", "json", "name", "jsonpath", "custom-columns":
+		default:
+			return fmt.Errorf("%w: output format %s", ErrUnsupported, c.Output)
+		}
+	case "describe":
+		if len(c.Args) == 0 {
+			return errors.New("describe needs a resource type")
+		}
+	case "logs":
+		if len(c.Args) > 2 || (len(c.Args) == 0 && c.Selector == "") {
+			return fmt.Errorf("%w: logs arguments %v", ErrUnsupported, c.Args)
+		}
+	case "top":
+		if len(c.Args) == 0 || len(c.Args) > 2 {
+			return errors.New("top needs pods or nodes")
+		}
+		switch c.Args[0] {
+		case "pod", "pods", "po", "node", "nodes", "no":
+		default:
+			return fmt.Errorf("%w: top %s", ErrUnsupported, c.Args[0])
+		}
+	case "events":
+		if len(c.Args) > 0 {
+			return fmt.Errorf("%w: events arguments %v", ErrUnsupported, c.Args)
+		}
+	}
+	return nil
+}
+
+// splitCommand splits a command line into words with bash quoting rules. Unquoted shell
+// operators and expansions ($, `, |, &, ;, <, >, parentheses) are not interpreted and
+// return ErrUnsupported, so such commands still run through the shell.
+func splitCommand(command string) ([]string, error) {
+	var (
+		words   []string
+		current strings.Builder
+		inWord  bool
+	)
+	flush := func() {
+		if inWord {
+			words = append(words, current.String())
+			current.Reset()
+			inWord = false
+		}
+	}
+	for i := 0; i < len(command); i++ {
+		ch := command[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			flush()
+		case ch == '\'':
+			end := strings.IndexByte(command[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			current.WriteString(command[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case ch == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i >= len(command) {
+					return nil, errors.New("unterminated double quote")
+				}
+				c := command[i]
+				if c == '"' {
+					break
+				}
+				if c == '$' || c == '`' {
+					return nil, fmt.Errorf("%w: expansion inside double quotes", ErrUnsupported)
+				}
+				if c == '\\' && i+1 < len(command) && strings.IndexByte("\"\\$`\n", command[i+1]) >= 0 {
+					i++
+					c = command[i]
+				}
+				current.WriteByte(c)
+			}
+		case ch == '\\':
+			if i+1 < len(command) {
+				i++
+				current.WriteByte(command[i])
+				inWord = true
+			}
+		case strings.IndexByte("|&;<>()$`", ch) >= 0:
+			return nil, fmt.Errorf("%w: shell operator %q", ErrUnsupported, ch)
+		default:
+			current.WriteByte(ch)
+			inWord = true
+		}
+	}
+	flush()
+	return words, nil
+}
@@ -0,0 +1,64 @@
+package kubernetes
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCommand(t *testing.T) {
+	cmd, err := ParseCommand(`kubectl get pods -n "shop" -l 'app in (api, worker)' -o=jsonpath='{.items[*].metadata.name}' --context prod-cn`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Command{Verb: "get", Args: []string{"pods"}, Namespace: "shop", Selector: "app in (api, worker)",
+		Output: "jsonpath={.items[*].metadata.name}", Context: "prod-cn", Tail: -1}
+	if !reflect.DeepEqual(cmd, want) {
+		t.Errorf("ParseCommand() = %+v, want %+v", cmd, want)
+	}
+
+	cmd, err = ParseCommand(`logs deploy/payment-api -c app --tail=50 --since 1h -p -nshop`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Verb != "logs" || cmd.Container != "app" || cmd.Tail != 50 || cmd.Since != time.Hour || !cmd.Previous || cmd.Namespace != "shop" {
+		t.Errorf("unexpected logs command %+v", cmd)
+	}
+
+	cmd, err = ParseCommand(`kubectl get pods -o custom-columns=NAME:.metadata.name,NODE:.spec.nodeName --no-headers -A`)
+	if err != nil || cmd.Output != "custom-columns=NAME:.metadata.name,NODE:.spec.nodeName" || !cmd.NoHeaders || !cmd.AllNamespaces {
+		t.Errorf("ParseCommand(custom-columns) = %+v, %v", cmd, err)
+	}
+
+	for _, command := range []string{
+		"kubectl get pods -A | grep CrashLoopBackOff",
+		"kubectl get pods -n $NS",
+		`kubectl get pods -o "jsonpath={$.items}"`,
+		"kubectl delete pod api-0",
+		"kubectl get pods -w",
+		"kubectl get pods --sort-by=.metadata.creationTimestamp",
+		"kubectl get pods -o go-template='{{.metadata.name}}'",
+		"kubectl logs api-0 -f",
+		"kubectl logs --tail 10 api-0 -A",
+		"kubectl exec api-0 -- env",
+	} {
+		if _, err := ParseCommand(command); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("ParseCommand(%q) error = %v, want ErrUnsupported", command, err)
+		}
+	}
+	if _, err := ParseCommand(`kubectl get pods -l 'app=api`); err == nil {
+		t.Error("expected unterminated quote error")
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	words, err := splitCommand(`get pods -l "app=\"api\"" name\ with\ spaces ''`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"get", "pods", "-l", `app="api"`, "name with spaces", ""}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("splitCommand() = %q, want %q", words, want)
+	}
+}
@@ -0,0 +1,251 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultQuotaThreshold is the used/hard ratio at which a quota is reported as nearly exhausted.
+const DefaultQuotaThreshold = 0.8
+
+// Compliance finding severities.
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// QuotaUsage is the usage of one resource limited by a ResourceQuota.
+type QuotaUsage struct {
+	Namespace string  `json:"namespace"`
+	Quota     string  `json:"quota"`
+	Resource  string  `json:"resource"`
+	Used      string  `json:"used"`
+	Hard      string  `json:"hard"`
+	Ratio     float64 `json:"ratio"`
+}
+
+// ComplianceFinding is a namespace near its quota, or a workload whose containers
+// violate the LimitRange or run without requests/limits.
+type ComplianceFinding struct {
+	Severity  string `json:"severity"`
+	Namespace string `json:"namespace"`
+	Workload  string `json:"workload,omitempty"` // kind/name, e.g. Deployment/web
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+}
+
+// ComplianceReport is the result of comparing ResourceQuotas and LimitRanges with actual requests.
+type ComplianceReport struct {
+	Namespaces int                 `json:"namespaces"`
+	Quotas     []QuotaUsage        `json:"quotas"`
+	Findings   []ComplianceFinding `json:"findings"`
+}
+
+// CheckCompliance reads the quotas, limit ranges and pods of a namespace (all
+// namespaces when empty) and analyzes them.
+func CheckCompliance(ctx context.Context, client kubernetes.Interface, namespace string, threshold float64) (*ComplianceReport, error) {
+	quotas, err := client.CoreV1().ResourceQuotas(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	limitRanges, err := client.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeCompliance(quotas.Items, limitRanges.Items, pods.Items, threshold), nil
+}
+
+// AnalyzeCompliance flags quotas whose usage reached threshold (critical when
+// exhausted), containers without cpu/memory requests or limits, and containers
+// outside the min/max of their namespace's LimitRange. Pods are grouped by their
+// owning workload so a Deployment with 20 replicas is reported once.
+func AnalyzeCompliance(quotas []corev1.ResourceQuota, limitRanges []corev1.LimitRange, pods []corev1.Pod, threshold float64) *ComplianceReport {
+	if threshold <= 0 {
+		threshold = DefaultQuotaThreshold
+	}
+	report := &ComplianceReport{}
+	namespaces := map[string]bool{}
+
+	for _, q := range quotas {
+		namespaces[q.Namespace] = true
+		for name, hard := range q.Status.Hard {
+			used := q.Status.Used[name]
+			usage := QuotaUsage{
+				Namespace: q.Namespace,
+				Quota:     q.Name,
+				Resource:  string(name),
+				Used:      used.String(),
+				Hard:      hard.String(),
+			}
+			if hard.MilliValue() > 0 {
+				usage.Ratio = float64(used.MilliValue()) / float64(hard.MilliValue())
+			} else if used.MilliValue() > 0 {
+				usage.Ratio = 1
+			}
+			report.Quotas = append(report.Quotas, usage)
+			if usage.Ratio >= threshold {
+				severity := SeverityWarning
+				if usage.Ratio >= 1 {
+					severity = SeverityCritical
+				}
+				report.Findings = append(report.Findings, ComplianceFinding{
+					Severity:  severity,
+					Namespace: q.Namespace,
+					Message: fmt.Sprintf("quota %s: %s used %s of %s (%.0f%%)",
+						q.Name, name, usage.Used, usage.Hard, usage.Ratio*100),
+				})
+			}
+		}
+	}
+
+	containerLimits := map[string][]corev1.LimitRangeItem{}
+	for _, lr := range limitRanges {
+		namespaces[lr.Namespace] = true
+		for _, item := range lr.Spec.Limits {
+			if item.Type == corev1.LimitTypeContainer {
+				containerLimits[lr.Namespace] = append(containerLimits[lr.Namespace], item)
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, pod := range pods {
+		namespaces[pod.Namespace] = true
+		workload := workloadOf(&pod)
+		for _, c := range pod.Spec.Containers {
+			key := pod.Namespace + "/" + workload + "/" + c.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			for _, message := range containerViolations(c, containerLimits[pod.Namespace]) {
+				report.Findings = append(report.Findings, ComplianceFinding{
+					Severity:  SeverityWarning,
+					Namespace: pod.Namespace,
+					Workload:  workload,
+					Container: c.Name,
+					Message:   message,
+				})
+			}
+		}
+	}
+	report.Namespaces = len(namespaces)
+
+	sort.Slice(report.Quotas, func(i, j int) bool {
+		if report.Quotas[i].Ratio != report.Quotas[j].Ratio {
+			return report.Quotas[i].Ratio > report.Quotas[j].Ratio
+		}
+		return report.Quotas[i].Namespace+report.Quotas[i].Resource < report.Quotas[j].Namespace+report.Quotas[j].Resource
+	})
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		a, b := report.Findings[i], report.Findings[j]
+		if a.Severity != b.Severity {
+			return a.Severity == SeverityCritical
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		return a.Container+a.Message < b.Container+b.Message
+	})
+	return report
+}
+
+// containerViolations lists missing cpu/memory requests and limits, and values
+// outside the LimitRange min/max.
+func containerViolations(c corev1.Container, limits []corev1.LimitRangeItem) []string {
+	var violations []string
+	var missing []string
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		if _, ok := c.Resources.Requests[name]; !ok {
+			missing = append(missing, string(name)+" request")
+		}
+		if _, ok := c.Resources.Limits[name]; !ok {
+			missing = append(missing, string(name)+" limit")
+		}
+	}
+	if len(missing) > 0 {
+		violations = append(violations, "missing "+strings.Join(missing, ", "))
+	}
+
+	var outOfRange []string
+	for _, item := range limits {
+		for name, max := range item.Max {
+			if limit, ok := c.Resources.Limits[name]; ok && limit.Cmp(max) > 0 {
+				outOfRange = append(outOfRange, fmt.Sprintf("%s limit %s exceeds LimitRange max %s", name, limit.String(), max.String()))
+			}
+		}
+		for name, min := range item.Min {
+			if request, ok := c.Resources.Requests[name]; ok && request.Cmp(min) < 0 {
+				outOfRange = append(outOfRange, fmt.Sprintf("%s request %s is below LimitRange min %s", name, request.String(), min.String()))
+			}
+		}
+		for name, ratio := range item.MaxLimitRequestRatio {
+			limit, hasLimit := c.Resources.Limits[name]
+			request, hasRequest := c.Resources.Requests[name]
+			if hasLimit && hasRequest && request.MilliValue() > 0 &&
+				float64(limit.MilliValue())/float64(request.MilliValue()) > ratio.AsApproximateFloat64() {
+				outOfRange = append(outOfRange, fmt.Sprintf("%s limit/request ratio exceeds LimitRange max %s", name, ratio.String()))
+			}
+		}
+	}
+	sort.Strings(outOfRange)
+	return append(violations, outOfRange...)
+}
+
+// workloadOf returns kind/name of the controller owning the pod. Pods of a
+// Deployment are attributed to the Deployment by stripping the pod-template-hash
+// from the ReplicaSet name.
+func workloadOf(pod *corev1.Pod) string {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return "Pod/" + pod.Name
+	}
+	if owner.Kind == "ReplicaSet" {
+		if hash := pod.Labels["pod-template-hash"]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			return "Deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+	}
+	return owner.Kind + "/" + owner.Name
+}
+
+// Format renders the findings followed by the quotas closest to their limits.
+func (r *ComplianceReport) Format(maxQuotas int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "checked %d namespaces: %d findings\n", r.Namespaces, len(r.Findings))
+	for _, f := range r.Findings {
+		target := f.Namespace
+		if f.Workload != "" {
+			target += " " + f.Workload
+		}
+		if f.Container != "" {
+			target += " container " + f.Container
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", f.Severity, target, f.Message)
+	}
+	quotas := r.Quotas
+	if maxQuotas > 0 && len(quotas) > maxQuotas {
+		quotas = quotas[:maxQuotas]
+	}
+	if len(quotas) > 0 {
+		b.WriteString("quota usage:\n")
+		for _, q := range quotas {
+			fmt.Fprintf(&b, "  %s/%s %s %s/%s (%.0f%%)\n", q.Namespace, q.Quota, q.Resource, q.Used, q.Hard, q.Ratio*100)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnalyzeCompliance(t *testing.T) {
+	quotas := []corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "shop"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("10"), corev1.ResourceRequestsMemory: resource.MustParse("20Gi")},
+			Used: corev1.ResourceList{corev1.ResourceRequestsCPU: resource.MustParse("9"), corev1.ResourceRequestsMemory: resource.MustParse("4Gi")},
+		},
+	}}
+	limitRanges := []corev1.LimitRange{{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "shop"},
+		Spec: corev1.LimitRangeSpec{Limits: []corev1.LimitRangeItem{{
+			Type: corev1.LimitTypeContainer,
+			Max:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+		}}},
+	}}
+	controller := true
+	pod := func(name string, resources corev1.ResourceRequirements) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"pod-template-hash": "5d8f"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f", Controller: &controller}}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "web", Resources: resources}}},
+		}
+	}
+	oversized := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+	}
+	report := AnalyzeCompliance(quotas, limitRanges, []corev1.Pod{
+		pod("web-5d8f-a", oversized), pod("web-5d8f-b", oversized),
+		{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "tools"}, Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "sh"}}}},
+	}, 0)
+
+	if report.Namespaces != 2 {
+		t.Errorf("expected 2 namespaces, got %d", report.Namespaces)
+	}
+	if len(report.Findings) != 3 {
+		t.Fatalf("expected 3 findings (quota, limit range, missing limits), got %+v", report.Findings)
+	}
+	if f := report.Findings[0]; f.Severity != SeverityWarning || f.Workload != "" || !strings.Contains(f.Message, "requests.cpu used 9 of 10") {
+		t.Errorf("unexpected quota finding: %+v", f)
+	}
+	if f := report.Findings[1]; f.Workload != "Deployment/web" || !strings.Contains(f.Message, "memory limit 4Gi exceeds LimitRange max 2Gi") {
+		t.Errorf("unexpected limit range finding: %+v", f)
+	}
+	if f := report.Findings[2]; f.Workload != "Pod/debug" || f.Message != "missing cpu request, cpu limit, memory request, memory limit" {
+		t.Errorf("unexpected missing limits finding: %+v", f)
+	}
+	if report.Quotas[0].Resource != "requests.cpu" {
+		t.Errorf("expected the fullest quota first, got %+v", report.Quotas)
+	}
+}
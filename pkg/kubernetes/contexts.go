@@ -0,0 +1,141 @@
+package kubernetes
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// ambiguityMargin is the score gap below which the runner-up context makes the
+// best match ambiguous.
+const ambiguityMargin = 0.15
+
+// ContextCandidate is a kubeconfig context scored against a cluster hint.
+type ContextCandidate struct {
+	Context string  `json:"context"`
+	Score   float64 `json:"score"`
+}
+
+// ContextResolution is the result of resolving a cluster hint to a kubeconfig context.
+type ContextResolution struct {
+	Query        string             `json:"query"`
+	Candidate    string             `json:"candidate"`
+	Confidence   float64            `json:"confidence"`
+	Alternatives []ContextCandidate `json:"alternatives,omitempty"`
+}
+
+// ListContexts returns the contexts defined in the default kubeconfig and the current context.
+func ListContexts() ([]string, string, error) {
+	config, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return nil, "", err
+	}
+
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, config.CurrentContext, nil
+}
+
+// ResolveContext scores every context against the cluster hint and returns the best
+// candidate with the runners-up as alternatives. aliases maps friendly names to
+// context names. Only exact, alias and case-insensitive matches reach a confidence
+// above 0.9; fuzzy matches are further discounted when another context scores close.
+func ResolveContext(query string, contexts []string, aliases map[string]string, maxAlternatives int) ContextResolution {
+	resolution := ContextResolution{Query: query}
+
+	candidates := make([]ContextCandidate, 0, len(contexts))
+	for _, name := range contexts {
+		if score := scoreContext(query, name, aliases); score > 0 {
+			candidates = append(candidates, ContextCandidate{Context: name, Score: round(score)})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].Context < candidates[j].Context
+	})
+	if len(candidates) == 0 {
+		return resolution
+	}
+
+	best := candidates[0]
+	resolution.Candidate = best.Context
+	resolution.Confidence = best.Score
+	if len(candidates) > 1 {
+		if margin := best.Score - candidates[1].Score; margin < ambiguityMargin {
+			resolution.Confidence = round(best.Score * (0.5 + margin/(2*ambiguityMargin)))
+		}
+	}
+
+	alternatives := candidates[1:]
+	if len(alternatives) > maxAlternatives {
+		alternatives = alternatives[:maxAlternatives]
+	}
+	resolution.Alternatives = alternatives
+	return resolution
+}
+
+// scoreContext returns how well the context name matches the cluster hint, in [0, 1].
+func scoreContext(query, name string, aliases map[string]string) float64 {
+	if query == name {
+		return 1
+	}
+	q, n := strings.ToLower(strings.TrimSpace(query)), strings.ToLower(name)
+	for alias, target := range aliases {
+		if strings.ToLower(alias) == q && target == name {
+			return 1
+		}
+	}
+	if q == "" {
+		return 0
+	}
+	if q == n {
+		return 0.95
+	}
+
+	var score float64
+	if strings.Contains(n, q) {
+		score = 0.9
+	}
+
+	// Token match, e.g. "prod" matches both prod-east and prod-west.
+	queryTokens, nameTokens := tokenize(q), tokenize(n)
+	if len(queryTokens) > 0 {
+		matched := 0
+		for _, qt := range queryTokens {
+			for _, nt := range nameTokens {
+				if qt == nt || (len(qt) >= 3 && strings.HasPrefix(nt, qt)) {
+					matched++
+					break
+				}
+			}
+		}
+		score = math.Max(score, 0.85*float64(matched)/float64(len(queryTokens)))
+	}
+
+	// Edit distance tolerates typos.
+	similarity := 1 - float64(utils.Levenshtein(q, n))/float64(max(len(q), len(n)))
+	score = math.Max(score, 0.8*similarity)
+	if score < 0.3 {
+		return 0
+	}
+	return score
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,41 @@
+package kubernetes
+
+import "testing"
+
+func TestResolveContext(t *testing.T) {
+	contexts := []string{
+		"arn:aws:eks:us-east-1:123456789012:cluster/prod-east",
+		"prod-west",
+		"staging",
+	}
+	aliases := map[string]string{"prod": "prod-west"}
+
+	tests := []struct {
+		query     string
+		candidate string
+		confident bool
+	}{
+		{query: "staging", candidate: "staging", confident: true},
+		{query: "Staging", candidate: "staging", confident: true},
+		{query: "prod", candidate: "prod-west", confident: true},
+		{query: "prod-east", candidate: "arn:aws:eks:us-east-1:123456789012:cluster/prod-east", confident: true},
+		{query: "prd-west", candidate: "prod-west", confident: false},
+		{query: "production", candidate: "prod-west", confident: false},
+		{query: "dev", candidate: "", confident: false},
+	}
+	for _, tt := range tests {
+		r := ResolveContext(tt.query, contexts, aliases, 3)
+		if r.Candidate != tt.candidate {
+			t.Errorf("%s: expected candidate %q, got %q (%+v)", tt.query, tt.candidate, r.Candidate, r)
+		}
+		if confident := r.Confidence >= 0.8; confident != tt.confident {
+			t.Errorf("%s: expected confident=%v, got confidence %v", tt.query, tt.confident, r.Confidence)
+		}
+	}
+
+	// 没有别名时 prod 同时匹配两个生产集群，需要用户确认
+	r := ResolveContext("prod", contexts, nil, 3)
+	if r.Confidence >= 0.8 || len(r.Alternatives) == 0 {
+		t.Errorf("expected ambiguous resolution, got %+v", r)
+	}
+}
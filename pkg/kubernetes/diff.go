@@ -0,0 +1,282 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// Actions of an ObjectDiff.
+const (
+	DiffActionCreate    = "create"
+	DiffActionUpdate    = "update"
+	DiffActionUnchanged = "unchanged"
+	DiffActionInvalid   = "invalid"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around each change.
+	diffContext = 3
+	// maxDiffCells bounds the size of the LCS table; larger inputs are shown as a full replacement.
+	maxDiffCells = 1 << 22
+)
+
+// ManifestDiff is the result of validating manifests with a server-side dry-run apply
+// and diffing the result against the live objects, like kubectl apply --dry-run=server
+// followed by kubectl diff.
+type ManifestDiff struct {
+	Valid   bool         `json:"valid"`
+	Objects []ObjectDiff `json:"objects"`
+	Errors  []string     `json:"errors,omitempty"`
+}
+
+// ObjectDiff describes what applying the manifest would do to one object.
+type ObjectDiff struct {
+	Object string `json:"object"`
+	Action string `json:"action"`         // create, update, unchanged or invalid
+	Diff   string `json:"diff,omitempty"` // unified diff from the live object to the dry-run result
+	Error  string `json:"error,omitempty"`
+}
+
+// Changed reports whether applying the manifests would create or modify any object.
+func (d *ManifestDiff) Changed() bool {
+	for _, obj := range d.Objects {
+		if obj.Action == DiffActionCreate || obj.Action == DiffActionUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffYaml runs a server-side dry-run apply of every object in the manifests against the
+// cluster of kubeContext (the default kubeconfig when empty) and diffs the result against
+// the live object. Objects rejected by the API server are reported as invalid instead of
+// aborting, so all validation errors are returned at once. Nothing is persisted.
+func DiffYaml(ctx context.Context, kubeContext, manifests string) (*ManifestDiff, error) {
+	objects, err := ParseYaml(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	var config *rest.Config
+	if kubeContext == "" {
+		config, err = GetKubeConfig()
+	} else {
+		config, err = ConfigForContext(kubeContext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resourceFor, err := resourceResolverForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ManifestDiff{Valid: true}
+	for _, obj := range objects {
+		diff := ObjectDiff{Object: ObjectRef(obj)}
+		dri, err := resourceFor(obj)
+		if err != nil {
+			diff.Action, diff.Error = DiffActionInvalid, err.Error()
+		} else {
+			diff = dryRunObject(ctx, dri.Get, dri.Apply, obj)
+		}
+		if diff.Action == DiffActionInvalid {
+			result.Valid = false
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", diff.Object, diff.Error))
+		}
+		result.Objects = append(result.Objects, diff)
+	}
+	return result, nil
+}
+
+// dryRunObject applies obj with server-side dry-run and diffs the result against the live object.
+func dryRunObject(
+	ctx context.Context,
+	get func(context.Context, string, metav1.GetOptions, ...string) (*unstructured.Unstructured, error),
+	apply func(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions, ...string) (*unstructured.Unstructured, error),
+	obj *unstructured.Unstructured,
+) ObjectDiff {
+	ref := ObjectRef(obj)
+	live, err := get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		live, err = nil, nil
+	}
+	if err != nil {
+		return ObjectDiff{Object: ref, Action: DiffActionInvalid, Error: err.Error()}
+	}
+
+	merged, err := apply(ctx, obj.GetName(), obj.DeepCopy(), metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		DryRun:       []string{metav1.DryRunAll},
+	})
+	if err != nil {
+		return ObjectDiff{Object: ref, Action: DiffActionInvalid, Error: err.Error()}
+	}
+	diff, err := CompareObjects(ref, live, merged)
+	if err != nil {
+		return ObjectDiff{Object: ref, Action: DiffActionInvalid, Error: err.Error()}
+	}
+	return diff
+}
+
+// CompareObjects diffs the live object (nil when it does not exist) against the dry-run
+// result. managedFields are left out as kubectl diff does.
+func CompareObjects(ref string, live, merged *unstructured.Unstructured) (ObjectDiff, error) {
+	to, err := diffYaml(merged)
+	if err != nil {
+		return ObjectDiff{}, err
+	}
+	if live == nil {
+		return ObjectDiff{Object: ref, Action: DiffActionCreate, Diff: UnifiedDiff("/dev/null", "merged/"+ref, "", to)}, nil
+	}
+	from, err := diffYaml(live)
+	if err != nil {
+		return ObjectDiff{}, err
+	}
+	diff := ObjectDiff{Object: ref, Action: DiffActionUnchanged, Diff: UnifiedDiff("live/"+ref, "merged/"+ref, from, to)}
+	if diff.Diff != "" {
+		diff.Action = DiffActionUpdate
+	}
+	return diff, nil
+}
+
+func diffYaml(obj *unstructured.Unstructured) (string, error) {
+	obj = obj.DeepCopy()
+	unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
+	data, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// UnifiedDiff returns the line diff from a to b in unified format, or "" when they are equal.
+func UnifiedDiff(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	ops := diffLines(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	// aPos and bPos are the number of lines of a and b before each op.
+	aPos, bPos := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for k, op := range ops {
+		aPos[k+1], bPos[k+1] = aPos[k], bPos[k]
+		if op.kind != '+' {
+			aPos[k+1]++
+		}
+		if op.kind != '-' {
+			bPos[k+1]++
+		}
+	}
+
+	for idx := 0; idx < len(ops); {
+		if ops[idx].kind == ' ' {
+			idx++
+			continue
+		}
+		start, end := max(idx-diffContext, 0), idx
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*diffContext {
+				end = min(end+diffContext, len(ops))
+				break
+			}
+			end = run
+		}
+
+		aCount, bCount := aPos[end]-aPos[start], bPos[end]-bPos[start]
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(aPos[start], aCount), hunkRange(bPos[start], bCount))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.text)
+			out.WriteByte('\n')
+		}
+		idx = end
+	}
+	return out.String()
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	text string
+}
+
+// diffLines computes a line-level edit script from the longest common subsequence.
+func diffLines(a, b []string) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// hunkRange formats the line range of a hunk header; empty ranges refer to the line before.
+func hunkRange(pos, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", pos)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", pos+1)
+	}
+	return fmt.Sprintf("%d,%d", pos+1, count)
+}
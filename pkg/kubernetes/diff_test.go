@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	expected := `--- live
++++ merged
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -9,3 +9,4 @@
+ i
+ j
+ k
++l
+`
+	if diff := UnifiedDiff("live", "merged", a, b); diff != expected {
+		t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", diff, expected)
+	}
+
+	if diff := UnifiedDiff("live", "merged", a, a); diff != "" {
+		t.Errorf("UnifiedDiff() of equal input = %q", diff)
+	}
+	if diff := UnifiedDiff("/dev/null", "merged", "", "x\ny\n"); !strings.Contains(diff, "@@ -0,0 +1,2 @@\n+x\n+y\n") {
+		t.Errorf("UnifiedDiff() of new object =\n%s", diff)
+	}
+}
+
+func TestDryRunObject(t *testing.T) {
+	ctx := context.Background()
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "shop"},
+		"spec":       map[string]interface{}{"replicas": int64(3)},
+	}}
+	live := obj.DeepCopy()
+	live.Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+	live.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl"}})
+
+	getLive := func(context.Context, string, metav1.GetOptions, ...string) (*unstructured.Unstructured, error) {
+		return live, nil
+	}
+	var dryRun []string
+	apply := func(_ context.Context, _ string, obj *unstructured.Unstructured, opts metav1.ApplyOptions, _ ...string) (*unstructured.Unstructured, error) {
+		dryRun = opts.DryRun
+		return obj, nil
+	}
+
+	diff := dryRunObject(ctx, getLive, apply, obj)
+	if diff.Action != DiffActionUpdate || len(dryRun) != 1 || dryRun[0] != metav1.DryRunAll {
+		t.Fatalf("dryRunObject() = %+v, dry-run %v", diff, dryRun)
+	}
+	if !strings.Contains(diff.Diff, "-  replicas: 2\n+  replicas: 3\n") || strings.Contains(diff.Diff, "managedFields") {
+		t.Errorf("diff =\n%s", diff.Diff)
+	}
+
+	notFound := func(context.Context, string, metav1.GetOptions, ...string) (*unstructured.Unstructured, error) {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, "nginx")
+	}
+	if diff := dryRunObject(ctx, notFound, apply, obj); diff.Action != DiffActionCreate || !strings.Contains(diff.Diff, "+kind: Deployment") {
+		t.Errorf("dryRunObject() for new object = %+v", diff)
+	}
+
+	reject := func(context.Context, string, *unstructured.Unstructured, metav1.ApplyOptions, ...string) (*unstructured.Unstructured, error) {
+		return nil, apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "nginx", nil)
+	}
+	if diff := dryRunObject(ctx, notFound, reject, obj); diff.Action != DiffActionInvalid || diff.Error == "" {
+		t.Errorf("dryRunObject() for rejected object = %+v", diff)
+	}
+}
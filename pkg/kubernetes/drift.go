@@ -0,0 +1,149 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ApplyIDLabel marks resources applied through OpsAgent with the ID of the apply
+	// request. A dedicated label is used so that app.kubernetes.io/managed-by set by
+	// Helm or other tools is left untouched.
+	ApplyIDLabel = "opsagent.io/apply-id"
+	// InteractionIDAnnotation records the interaction that applied the resource.
+	InteractionIDAnnotation = "opsagent.io/interaction-id"
+	// ManifestHashAnnotation records the hash of the manifest that applied the resource.
+	ManifestHashAnnotation = "opsagent.io/manifest-hash"
+)
+
+// ObjectDrift describes how a live object differs from its stored manifest.
+type ObjectDrift struct {
+	Object  string   `json:"object"`
+	Missing bool     `json:"missing,omitempty"`
+	Fields  []string `json:"fields,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ApplyTrackedYaml applies the manifests and tags every object with the apply ID
+// label, the interaction ID and the manifest hash, so that drift can be traced back
+// to the interaction that created it.
+func ApplyTrackedYaml(manifests, interactionID, hash string) ([]string, error) {
+	return applyManifests(manifests, metav1.ApplyOptions{FieldManager: fieldManager}, func(obj *unstructured.Unstructured) {
+		trackObject(obj, interactionID, hash)
+	})
+}
+
+// trackObject adds the OpsAgent tracking label and annotations, keeping existing ones.
+func trackObject(obj *unstructured.Unstructured, interactionID, hash string) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ApplyIDLabel] = interactionID
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[InteractionIDAnnotation] = interactionID
+	annotations[ManifestHashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+}
+
+// DetectDrift compares the live objects against the stored manifests and returns
+// the objects that were modified or deleted outside of OpsAgent.
+func DetectDrift(manifests, interactionID string) ([]ObjectDrift, error) {
+	objects, err := ParseYaml(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceFor, err := newResourceResolver()
+	if err != nil {
+		return nil, err
+	}
+
+	var drifts []ObjectDrift
+	for _, desired := range objects {
+		dri, err := resourceFor(desired)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := ObjectRef(desired)
+		live, err := dri.Get(context.Background(), desired.GetName(), metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				drifts = append(drifts, ObjectDrift{Object: ref, Missing: true, Message: "object has been deleted"})
+				continue
+			}
+			return nil, fmt.Errorf("%s: %v", ref, err)
+		}
+
+		drift := ObjectDrift{Object: ref, Fields: DiffObject(desired.Object, live.Object)}
+		if owner := live.GetAnnotations()[InteractionIDAnnotation]; owner != interactionID {
+			drift.Message = fmt.Sprintf("object is now owned by interaction %q", owner)
+		}
+		if len(drift.Fields) > 0 || drift.Message != "" {
+			drifts = append(drifts, drift)
+		}
+	}
+
+	return drifts, nil
+}
+
+// DiffObject returns the paths of fields declared in desired whose live value differs.
+// Fields not declared in desired (defaults, status, server-managed metadata) are ignored.
+func DiffObject(desired, live map[string]interface{}) []string {
+	var fields []string
+	diffValue("", desired, live, &fields)
+	sort.Strings(fields)
+	return fields
+}
+
+func diffValue(path string, desired, live interface{}, fields *[]string) {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			*fields = append(*fields, path)
+			return
+		}
+		for k, v := range d {
+			diffValue(joinPath(path, k), v, l[k], fields)
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			*fields = append(*fields, path)
+			return
+		}
+		for i := range d {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], fields)
+		}
+	default:
+		// Compare scalars by their string form so that int64/float64 decoded from
+		// YAML and JSON are treated as equal.
+		if live == nil || fmt.Sprint(desired) != fmt.Sprint(live) {
+			*fields = append(*fields, path)
+		}
+	}
+}
+
+func joinPath(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		key = fmt.Sprintf("[%q]", key)
+		return path + key
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestDiffObject(t *testing.T) {
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":   "nginx",
+			"labels": map[string]interface{}{"app.kubernetes.io/name": "nginx"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx:1.25"},
+					},
+				},
+			},
+		},
+	}
+
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name":            "nginx",
+			"resourceVersion": "12345",
+			"labels":          map[string]interface{}{"app.kubernetes.io/name": "nginx"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": float64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx:1.25", "imagePullPolicy": "IfNotPresent"},
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{"replicas": int64(2)},
+	}
+
+	if fields := DiffObject(desired, live); len(fields) != 0 {
+		t.Fatalf("expected no drift, got %v", fields)
+	}
+
+	live["spec"].(map[string]interface{})["replicas"] = int64(5)
+	containers := live["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})
+	containers[0].(map[string]interface{})["image"] = "nginx:latest"
+	delete(live["metadata"].(map[string]interface{}), "labels")
+
+	expected := []string{
+		`metadata.labels`,
+		`spec.replicas`,
+		`spec.template.spec.containers[0].image`,
+	}
+	if fields := DiffObject(desired, live); !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("expected %v, got %v", expected, fields)
+	}
+}
+
+func TestTrackObject(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetLabels(map[string]string{"app.kubernetes.io/managed-by": "Helm"})
+
+	trackObject(obj, "ap1", "h1")
+
+	want := map[string]string{"app.kubernetes.io/managed-by": "Helm", ApplyIDLabel: "ap1"}
+	if got := obj.GetLabels(); !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+	if got := obj.GetAnnotations(); got[InteractionIDAnnotation] != "ap1" || got[ManifestHashAnnotation] != "h1" {
+		t.Errorf("annotations = %v", got)
+	}
+}
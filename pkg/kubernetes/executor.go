@@ -0,0 +1,831 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+)
+
+// tableAccept asks the API server to render list and get responses as tables, which
+// carry the same columns kubectl prints, including CRD additionalPrinterColumns.
+const tableAccept = "application/json;as=Table;v=v1;g=meta.k8s.io,application/json"
+
+// lastAppliedAnnotation is left out of describe output, it repeats the whole object.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// Executor runs the read-only kubectl commands parsed by ParseCommand with client-go,
+// so arguments reach the API server exactly as written, without shell quoting.
+type Executor struct {
+	client    kubernetes.Interface
+	dynamic   dynamic.Interface
+	mapper    meta.RESTMapper
+	rest      rest.Interface // raw requests: server-side tables and the metrics API
+	namespace string         // default namespace of the kubeconfig context
+}
+
+// NewExecutor creates an executor for a cluster; namespace is used when the command
+// does not specify one.
+func NewExecutor(config *rest.Config, namespace string) (*Executor, error) {
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	discovery := memory.NewMemCacheClient(client.Discovery())
+	mapper := restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(discovery), discovery, nil)
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	return &Executor{
+		client:    client,
+		dynamic:   dynamicClient,
+		mapper:    mapper,
+		rest:      client.Discovery().RESTClient(),
+		namespace: namespace,
+	}, nil
+}
+
+var (
+	executorsMu sync.Mutex
+	executors   = map[string]*Executor{}
+)
+
+// RunCommand runs cmd on the cluster selected by its --kubeconfig and --context flags,
+// falling back to the default loading rules and the in-cluster config. Executors and
+// their discovery caches are kept per kubeconfig and context.
+func RunCommand(ctx context.Context, cmd *Command) (string, error) {
+	key := cmd.Kubeconfig + "\x00" + cmd.Context
+	executorsMu.Lock()
+	e, ok := executors[key]
+	executorsMu.Unlock()
+	if !ok {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = cmd.Kubeconfig
+		clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: cmd.Context})
+		config, err := clientConfig.ClientConfig()
+		if err != nil {
+			return "", err
+		}
+		namespace, _, _ := clientConfig.Namespace()
+		if e, err = NewExecutor(config, namespace); err != nil {
+			return "", err
+		}
+		executorsMu.Lock()
+		executors[key] = e
+		executorsMu.Unlock()
+	}
+	return e.Run(ctx, cmd)
+}
+
+// Run executes cmd. Failures are returned both as the error and as output formatted
+// like kubectl's, e.g. `Error from server (NotFound): pods "x" not found`.
+func (e *Executor) Run(ctx context.Context, cmd *Command) (string, error) {
+	namespace := cmd.Namespace
+	if namespace == "" {
+		namespace = e.namespace
+	}
+	if cmd.AllNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	var (
+		output string
+		err    error
+	)
+	switch cmd.Verb {
+	case "get":
+		output, err = e.get(ctx, cmd, namespace)
+	case "describe":
+		output, err = e.describe(ctx, cmd, namespace)
+	case "logs":
+		output, err = e.logs(ctx, cmd, namespace)
+	case "top":
+		output, err = e.top(ctx, cmd, namespace)
+	case "events":
+		output, err = e.events(ctx, cmd, namespace)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupported, cmd.Verb)
+	}
+	if err != nil {
+		err = commandError(err)
+		return strings.TrimSpace(output + "\n" + err.Error()), err
+	}
+	return output, nil
+}
+
+// commandError formats API errors the way kubectl prints them.
+func commandError(err error) error {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		s := status.Status()
+		return fmt.Errorf("Error from server (%s): %s", s.Reason, s.Message)
+	}
+	if strings.HasPrefix(err.Error(), "error: ") {
+		return err
+	}
+	return fmt.Errorf("error: %w", err)
+}
+
+// resourceRequest is a resource type and the names requested for it; no names means
+// all objects matching the selectors.
+type resourceRequest struct {
+	mapping *meta.RESTMapping
+	names   []string
+}
+
+// resources resolves kubectl resource arguments: "pods", "pods,svc", "pod a b" or "pod/a svc/b".
+func (e *Executor) resources(args []string) ([]resourceRequest, error) {
+	var requests []resourceRequest
+	if strings.Contains(args[0], "/") {
+		for _, arg := range args {
+			resource, name, ok := strings.Cut(arg, "/")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("there is no need to specify a resource type as a separate argument when passing arguments in resource/name form")
+			}
+			mapping, err := e.mapping(resource)
+			if err != nil {
+				return nil, err
+			}
+			if n := len(requests); n > 0 && requests[n-1].mapping.Resource == mapping.Resource {
+				requests[n-1].names = append(requests[n-1].names, name)
+				continue
+			}
+			requests = append(requests, resourceRequest{mapping: mapping, names: []string{name}})
+		}
+		return requests, nil
+	}
+
+	types := strings.Split(args[0], ",")
+	if len(types) > 1 && len(args) > 1 {
+		return nil, fmt.Errorf("names cannot be used with multiple resource types, use resource/name instead")
+	}
+	for _, resource := range types {
+		mapping, err := e.mapping(resource)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, resourceRequest{mapping: mapping, names: args[1:]})
+	}
+	return requests, nil
+}
+
+// mapping resolves a resource argument such as po, deploy, deployments.apps or
+// certificates.v1.cert-manager.io to its REST mapping.
+func (e *Executor) mapping(resource string) (*meta.RESTMapping, error) {
+	gvr, gr := schema.ParseResourceArg(strings.ToLower(resource))
+	var (
+		full schema.GroupVersionResource
+		err  error
+	)
+	if gvr != nil {
+		full, err = e.mapper.ResourceFor(*gvr)
+	}
+	if gvr == nil || err != nil {
+		full, err = e.mapper.ResourceFor(gr.WithVersion(""))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the server doesn't have a resource type %q", resource)
+	}
+	gvk, err := e.mapper.KindFor(full)
+	if err != nil {
+		return nil, err
+	}
+	return e.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// resourceInterface returns the dynamic client for a mapping, scoped to namespace for
+// namespaced resources.
+func (e *Executor) resourceInterface(mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		return e.dynamic.Resource(mapping.Resource).Namespace(namespace)
+	}
+	return e.dynamic.Resource(mapping.Resource)
+}
+
+// objects fetches the requested objects, without managed fields as kubectl shows them.
+func (e *Executor) objects(ctx context.Context, req resourceRequest, namespace string, cmd *Command) ([]unstructured.Unstructured, error) {
+	client := e.resourceInterface(req.mapping, namespace)
+	var items []unstructured.Unstructured
+	if len(req.names) > 0 {
+		if namespace == metav1.NamespaceAll && req.mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			return nil, fmt.Errorf("a resource cannot be retrieved by name across all namespaces")
+		}
+		for _, name := range req.names {
+			obj, err := client.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return items, err
+			}
+			items = append(items, *obj)
+		}
+	} else {
+		list, err := client.List(ctx, metav1.ListOptions{LabelSelector: cmd.Selector, FieldSelector: cmd.FieldSelector})
+		if err != nil {
+			return nil, err
+		}
+		items = list.Items
+	}
+	for i := range items {
+		items[i].SetManagedFields(nil)
+	}
+	return items, nil
+}
+
+// get prints resources as server-side tables, or in the requested output format.
+func (e *Executor) get(ctx context.Context, cmd *Command, namespace string) (string, error) {
+	requests, err := e.resources(cmd.Args)
+	if err != nil {
+		return "", err
+	}
+	format, template, _ := strings.Cut(cmd.Output, "=")
+	if format == "" || format == "wide" {
+		return e.getTables(ctx, cmd, requests, namespace)
+	}
+
+	var items []unstructured.Unstructured
+	for _, req := range requests {
+		objs, err := e.objects(ctx, req, namespace, cmd)
+		items = append(items, objs...)
+		if err != nil {
+			var output string
+			if len(items) > 0 {
+				output, _ = formatObjects(items, format, template, false, cmd.NoHeaders)
+			}
+			return output, err
+		}
+	}
+	single := len(requests) == 1 && len(requests[0].names) == 1
+	if len(items) == 0 && format != "json" && format != "yaml" {
+		return noResources(namespace, requests), nil
+	}
+	return formatObjects(items, format, template, single, cmd.NoHeaders)
+}
+
+// formatObjects renders objects as yaml, json, name, jsonpath or custom-columns. A single
+// object requested by name is printed on its own, anything else as a List.
+func formatObjects(items []unstructured.Unstructured, format, template string, single, noHeaders bool) (string, error) {
+	var data interface{}
+	if single && len(items) == 1 {
+		data = items[0].Object
+	} else {
+		list := make([]interface{}, len(items))
+		for i := range items {
+			list[i] = items[i].Object
+		}
+		data = map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": list, "metadata": map[string]interface{}{"resourceVersion": ""}}
+	}
+
+	switch format {
+	case "yaml":
+		out, err := yaml.Marshal(data)
+		return string(out), err
+	case "json":
+		out, err := json.MarshalIndent(data, "", "    ")
+		return string(out) + "\n", err
+	case "name":
+		var b strings.Builder
+		for i := range items {
+			b.WriteString(objectName(&items[i]) + "\n")
+		}
+		return b.String(), nil
+	case "jsonpath":
+		jp := jsonpath.New("out").AllowMissingKeys(true)
+		if err := jp.Parse(relaxedJSONPath(template)); err != nil {
+			return "", fmt.Errorf("error parsing jsonpath %s, %v", template, err)
+		}
+		var b bytes.Buffer
+		if err := jp.Execute(&b, data); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	case "custom-columns":
+		return customColumns(items, template, noHeaders)
+	}
+	return "", fmt.Errorf("%w: output format %s", ErrUnsupported, format)
+}
+
+// relaxedJSONPath accepts templates without braces, e.g. .metadata.name, like kubectl.
+func relaxedJSONPath(template string) string {
+	template = strings.TrimSpace(template)
+	if strings.Contains(template, "{") {
+		return template
+	}
+	if !strings.HasPrefix(template, ".") {
+		template = "." + template
+	}
+	return "{" + template + "}"
+}
+
+// customColumns renders -o custom-columns=HEADER:.path,... with one row per object.
+func customColumns(items []unstructured.Unstructured, spec string, noHeaders bool) (string, error) {
+	type column struct {
+		header string
+		parser *jsonpath.JSONPath
+	}
+	var columns []column
+	for _, part := range strings.Split(spec, ",") {
+		header, path, ok := strings.Cut(part, ":")
+		if !ok {
+			return "", fmt.Errorf("expected <header>:<json-path-expr> in custom-columns, got %q", part)
+		}
+		parser := jsonpath.New(header).AllowMissingKeys(true)
+		if err := parser.Parse(relaxedJSONPath(path)); err != nil {
+			return "", fmt.Errorf("error parsing jsonpath %s, %v", path, err)
+		}
+		columns = append(columns, column{header, parser})
+	}
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 6, 4, 3, ' ', 0)
+	if !noHeaders {
+		headers := make([]string, len(columns))
+		for i, c := range columns {
+			headers[i] = c.header
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+	}
+	for _, item := range items {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			results, err := c.parser.FindResults(item.Object)
+			if err != nil {
+				return "", err
+			}
+			var values []string
+			for _, result := range results {
+				for _, value := range result {
+					values = append(values, fmt.Sprint(value.Interface()))
+				}
+			}
+			cells[i] = strings.Join(values, ",")
+			if cells[i] == "" {
+				cells[i] = "<none>"
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	return b.String(), nil
+}
+
+// getTables prints the server-side tables of the requested resources with the columns
+// kubectl shows; -o wide includes the lower priority columns.
+func (e *Executor) getTables(ctx context.Context, cmd *Command, requests []resourceRequest, namespace string) (string, error) {
+	var (
+		b    strings.Builder
+		errs []string
+	)
+	printed := 0
+	for _, req := range requests {
+		var tables []*metav1.Table
+		names := req.names
+		if len(names) == 0 {
+			names = []string{""}
+		}
+		for _, name := range names {
+			table, err := e.table(ctx, req.mapping, namespace, name, cmd)
+			if err != nil {
+				if name == "" || !apierrors.IsNotFound(err) {
+					return b.String(), err
+				}
+				errs = append(errs, commandError(err).Error())
+				continue
+			}
+			tables = append(tables, table)
+		}
+		rows := 0
+		for _, table := range tables {
+			rows += len(table.Rows)
+		}
+		if rows == 0 {
+			continue
+		}
+		if printed > 0 {
+			b.WriteString("\n")
+		}
+		printed++
+		b.WriteString(formatTables(tables, cmd.Output == "wide", namespace == metav1.NamespaceAll && req.mapping.Scope.Name() == meta.RESTScopeNameNamespace, cmd.NoHeaders))
+	}
+	if len(errs) > 0 {
+		return b.String(), errors.New(strings.Join(errs, "\n"))
+	}
+	if printed == 0 {
+		return noResources(namespace, requests), nil
+	}
+	return b.String(), nil
+}
+
+// table fetches a resource, or a list of resources when name is empty, as a Table.
+func (e *Executor) table(ctx context.Context, mapping *meta.RESTMapping, namespace, name string, cmd *Command) (*metav1.Table, error) {
+	if e.rest == nil {
+		return nil, errors.New("no REST client for table requests")
+	}
+	req := e.rest.Get().AbsPath(resourcePath(mapping, namespace, name)...).
+		SetHeader("Accept", tableAccept).
+		Param("includeObject", string(metav1.IncludeMetadata))
+	if name == "" {
+		if cmd.Selector != "" {
+			req = req.Param("labelSelector", cmd.Selector)
+		}
+		if cmd.FieldSelector != "" {
+			req = req.Param("fieldSelector", cmd.FieldSelector)
+		}
+	}
+	data, err := req.DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table := &metav1.Table{}
+	if err := json.Unmarshal(data, table); err != nil {
+		return nil, err
+	}
+	if table.Kind != "Table" {
+		return nil, fmt.Errorf("the server did not return a table for %s", mapping.Resource.Resource)
+	}
+	return table, nil
+}
+
+// resourcePath is the API path of a resource collection, or of an object when name is set.
+func resourcePath(mapping *meta.RESTMapping, namespace, name string) []string {
+	gvr := mapping.Resource
+	path := []string{"/apis", gvr.Group, gvr.Version}
+	if gvr.Group == "" {
+		path = []string{"/api", gvr.Version}
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace && namespace != "" {
+		path = append(path, "namespaces", namespace)
+	}
+	path = append(path, gvr.Resource)
+	if name != "" {
+		path = append(path, name)
+	}
+	return path
+}
+
+// formatTables prints tables that share the same columns, adding a NAMESPACE column for
+// queries across all namespaces.
+func formatTables(tables []*metav1.Table, wide, withNamespace, noHeaders bool) string {
+	var columns []int
+	for i, c := range tables[0].ColumnDefinitions {
+		if c.Priority == 0 || wide {
+			columns = append(columns, i)
+		}
+	}
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 6, 4, 3, ' ', 0)
+	if !noHeaders {
+		var headers []string
+		if withNamespace {
+			headers = append(headers, "NAMESPACE")
+		}
+		for _, i := range columns {
+			headers = append(headers, strings.ToUpper(tables[0].ColumnDefinitions[i].Name))
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+	}
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			var cells []string
+			if withNamespace {
+				var object metav1.PartialObjectMetadata
+				_ = json.Unmarshal(row.Object.Raw, &object)
+				cells = append(cells, object.Namespace)
+			}
+			for _, i := range columns {
+				cell := "<none>"
+				if i < len(row.Cells) {
+					cell = cellString(row.Cells[i])
+				}
+				cells = append(cells, cell)
+			}
+			fmt.Fprintln(w, strings.Join(cells, "\t"))
+		}
+	}
+	w.Flush()
+	return b.String()
+}
+
+// cellString formats a table cell; JSON numbers decode as float64.
+func cellString(cell interface{}) string {
+	switch v := cell.(type) {
+	case nil:
+		return "<none>"
+	case float64:
+		if v == float64(int64(v)) {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprint(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(cell)
+}
+
+// noResources is kubectl's message for an empty result.
+func noResources(namespace string, requests []resourceRequest) string {
+	if namespace == metav1.NamespaceAll || requests[0].mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return "No resources found\n"
+	}
+	return fmt.Sprintf("No resources found in %s namespace.\n", namespace)
+}
+
+// objectName is the kind/name form printed by -o name, e.g. deployment.apps/api.
+func objectName(obj *unstructured.Unstructured) string {
+	gvk := obj.GroupVersionKind()
+	kind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	return kind + "/" + obj.GetName()
+}
+
+// describe prints each object as YAML, without managed fields and the last applied
+// configuration, followed by the events that refer to it.
+func (e *Executor) describe(ctx context.Context, cmd *Command, namespace string) (string, error) {
+	requests, err := e.resources(cmd.Args)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, req := range requests {
+		items, err := e.objects(ctx, req, namespace, cmd)
+		if err != nil {
+			return b.String(), err
+		}
+		for i := range items {
+			obj := &items[i]
+			if annotations := obj.GetAnnotations(); annotations[lastAppliedAnnotation] != "" {
+				delete(annotations, lastAppliedAnnotation)
+				obj.SetAnnotations(annotations)
+			}
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+			fmt.Fprintf(&b, "Name:       %s\n", obj.GetName())
+			if obj.GetNamespace() != "" {
+				fmt.Fprintf(&b, "Namespace:  %s\n", obj.GetNamespace())
+			}
+			fmt.Fprintf(&b, "Kind:       %s\n", obj.GetKind())
+			data, err := yaml.Marshal(obj.Object)
+			if err != nil {
+				return b.String(), err
+			}
+			b.Write(data)
+
+			eventNamespace := obj.GetNamespace()
+			events, err := e.client.CoreV1().Events(eventNamespace).List(ctx, metav1.ListOptions{FieldSelector: "involvedObject.uid=" + string(obj.GetUID())})
+			if err != nil {
+				fmt.Fprintf(&b, "Events:  <unavailable: %v>", err)
+				continue
+			}
+			b.WriteString(describeEvents(events.Items))
+		}
+	}
+	if b.Len() == 0 {
+		return noResources(namespace, requests), nil
+	}
+	return b.String(), nil
+}
+
+// describeEvents formats events in the layout of kubectl describe.
+func describeEvents(events []corev1.Event) string {
+	if len(events) == 0 {
+		return "Events:  <none>"
+	}
+	sortEvents(events)
+	var b bytes.Buffer
+	b.WriteString("Events:\n")
+	w := tabwriter.NewWriter(&b, 6, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  Type\tReason\tAge\tFrom\tMessage")
+	fmt.Fprintln(w, "  ----\t------\t----\t----\t-------")
+	for _, ev := range events {
+		age := eventAge(ev)
+		if ev.Count > 1 {
+			age = fmt.Sprintf("%s (x%d over %s)", age, ev.Count, duration.HumanDuration(time.Since(ev.FirstTimestamp.Time)))
+		}
+		from := ev.Source.Component
+		if from == "" {
+			from = ev.ReportingController
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", ev.Type, ev.Reason, age, from, strings.TrimSpace(ev.Message))
+	}
+	w.Flush()
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// events lists events like kubectl events: oldest first, optionally only those of one
+// object (--for kind/name) or of some types (--types Warning).
+func (e *Executor) events(ctx context.Context, cmd *Command, namespace string) (string, error) {
+	opts := metav1.ListOptions{}
+	if cmd.For != "" {
+		resource, name, ok := strings.Cut(cmd.For, "/")
+		if !ok || name == "" {
+			return "", fmt.Errorf("--for must be in resource/name form")
+		}
+		mapping, err := e.mapping(resource)
+		if err != nil {
+			return "", err
+		}
+		opts.FieldSelector = fmt.Sprintf("involvedObject.kind=%s,involvedObject.name=%s", mapping.GroupVersionKind.Kind, name)
+	}
+	list, err := e.client.CoreV1().Events(namespace).List(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	events := list.Items[:0]
+	for _, ev := range list.Items {
+		if len(cmd.Types) == 0 || containsFold(cmd.Types, ev.Type) {
+			events = append(events, ev)
+		}
+	}
+	if len(events) == 0 {
+		if namespace == metav1.NamespaceAll {
+			return "No events found.\n", nil
+		}
+		return fmt.Sprintf("No events found in %s namespace.\n", namespace), nil
+	}
+	sortEvents(events)
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 6, 4, 3, ' ', 0)
+	if !cmd.NoHeaders {
+		if namespace == metav1.NamespaceAll {
+			fmt.Fprint(w, "NAMESPACE\t")
+		}
+		fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+	}
+	for _, ev := range events {
+		if namespace == metav1.NamespaceAll {
+			fmt.Fprintf(w, "%s\t", ev.Namespace)
+		}
+		object := strings.ToLower(ev.InvolvedObject.Kind) + "/" + ev.InvolvedObject.Name
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", eventAge(ev), ev.Type, ev.Reason, object, strings.TrimSpace(ev.Message))
+	}
+	w.Flush()
+	return b.String(), nil
+}
+
+// eventTime is the last time an event was seen.
+func eventTime(ev corev1.Event) time.Time {
+	switch {
+	case !ev.LastTimestamp.IsZero():
+		return ev.LastTimestamp.Time
+	case !ev.EventTime.IsZero():
+		return ev.EventTime.Time
+	case !ev.FirstTimestamp.IsZero():
+		return ev.FirstTimestamp.Time
+	}
+	return ev.CreationTimestamp.Time
+}
+
+func eventAge(ev corev1.Event) string {
+	return duration.HumanDuration(time.Since(eventTime(ev)))
+}
+
+func sortEvents(events []corev1.Event) {
+	sort.SliceStable(events, func(i, j int) bool { return eventTime(events[i]).Before(eventTime(events[j])) })
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// logs prints container logs of a pod, of a pod selected from a workload (deploy/api),
+// or of every pod matching -l, which like kubectl defaults to the last 10 lines each.
+func (e *Executor) logs(ctx context.Context, cmd *Command, namespace string) (string, error) {
+	var pods []corev1.Pod
+	tail := cmd.Tail
+	if cmd.Selector != "" {
+		list, err := e.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: cmd.Selector})
+		if err != nil {
+			return "", err
+		}
+		if len(list.Items) == 0 {
+			return fmt.Sprintf("No resources found in %s namespace.\n", namespace), nil
+		}
+		pods = list.Items
+		if tail < 0 {
+			tail = 10
+		}
+	} else {
+		pod, err := e.logsPod(ctx, namespace, cmd.Args[0])
+		if err != nil {
+			return "", err
+		}
+		pods = []corev1.Pod{*pod}
+	}
+	container := cmd.Container
+	if container == "" && len(cmd.Args) == 2 {
+		container = cmd.Args[1]
+	}
+
+	var b strings.Builder
+	for i := range pods {
+		opts := &corev1.PodLogOptions{Container: container, Previous: cmd.Previous, Timestamps: cmd.Timestamps}
+		if opts.Container == "" {
+			opts.Container = defaultContainer(&pods[i])
+		}
+		if tail >= 0 {
+			opts.TailLines = &tail
+		}
+		if cmd.Since > 0 {
+			seconds := int64(cmd.Since.Seconds())
+			opts.SinceSeconds = &seconds
+		}
+		data, err := e.client.CoreV1().Pods(pods[i].Namespace).GetLogs(pods[i].Name, opts).DoRaw(ctx)
+		if err != nil {
+			return b.String(), err
+		}
+		b.Write(data)
+	}
+	return b.String(), nil
+}
+
+// logsPod resolves the argument of kubectl logs to a pod. For workloads the pod is picked
+// by the workload's selector, preferring running and then newer pods.
+func (e *Executor) logsPod(ctx context.Context, namespace, arg string) (*corev1.Pod, error) {
+	resource, name, ok := strings.Cut(arg, "/")
+	if !ok {
+		name, resource = arg, "pods"
+	}
+	mapping, err := e.mapping(resource)
+	if err != nil {
+		return nil, err
+	}
+	if mapping.Resource.Group == "" && mapping.Resource.Resource == "pods" {
+		return e.client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+
+	obj, err := e.resourceInterface(mapping, namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selector, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	if !found {
+		// Services and ReplicationControllers use a plain label map.
+		selector, found, _ = unstructured.NestedStringMap(obj.Object, "spec", "selector")
+	}
+	if !found || len(selector) == 0 {
+		return nil, fmt.Errorf("cannot get the logs from %s: selector not found", objectName(obj))
+	}
+	list, err := e.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) == 0 {
+		return nil, fmt.Errorf("no pods found for %s", objectName(obj))
+	}
+	pods := list.Items
+	sort.SliceStable(pods, func(i, j int) bool {
+		ri, rj := pods[i].Status.Phase == corev1.PodRunning, pods[j].Status.Phase == corev1.PodRunning
+		if ri != rj {
+			return ri
+		}
+		return pods[j].CreationTimestamp.Before(&pods[i].CreationTimestamp)
+	})
+	return &pods[0], nil
+}
+
+// defaultContainer is the container kubectl logs uses when none is given.
+func defaultContainer(pod *corev1.Pod) string {
+	if name := pod.Annotations["kubectl.kubernetes.io/default-container"]; name != "" {
+		return name
+	}
+	if len(pod.Spec.Containers) > 0 {
+		return pod.Spec.Containers[0].Name
+	}
+	return ""
+}
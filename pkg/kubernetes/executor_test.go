@@ -0,0 +1,114 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// newTestExecutor 使用 fake clientset 和 dynamic client 创建执行器，不请求 server-side table
+func newTestExecutor(objects ...runtime.Object) *Executor {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Event"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	return &Executor{
+		client:    fake.NewSimpleClientset(objects...),
+		dynamic:   dynamicfake.NewSimpleDynamicClient(scheme.Scheme, objects...),
+		mapper:    mapper,
+		namespace: "default",
+	}
+}
+
+func TestExecutor(t *testing.T) {
+	ctx := context.Background()
+	now := metav1.NewTime(time.Now().Add(-time.Minute))
+	pod := func(name, phase string) *corev1.Pod {
+		return &corev1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", UID: types.UID("uid-" + name), Labels: map[string]string{"app": "api"}, CreationTimestamp: now},
+			Spec:       corev1.PodSpec{NodeName: "node-1", Containers: []corev1.Container{{Name: "app"}, {Name: "sidecar"}}},
+			Status:     corev1.PodStatus{Phase: corev1.PodPhase(phase)},
+		}
+	}
+	e := newTestExecutor(
+		pod("api-1", "Running"),
+		pod("api-2", "Pending"),
+		&appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "shop"},
+			Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}},
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "api-1.1", Namespace: "shop"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "api-1", UID: "uid-api-1"},
+			Type:           "Warning", Reason: "BackOff", Message: "Back-off restarting failed container",
+			LastTimestamp: now,
+		},
+	)
+
+	run := func(command string) string {
+		t.Helper()
+		cmd, err := ParseCommand(command)
+		if err != nil {
+			t.Fatalf("ParseCommand(%q) error = %v", command, err)
+		}
+		output, err := e.Run(ctx, cmd)
+		if err != nil {
+			t.Fatalf("Run(%q) error = %v: %s", command, err, output)
+		}
+		return output
+	}
+
+	if out := run(`kubectl get pods -n shop -o name`); out != "pod/api-1\npod/api-2\n" {
+		t.Errorf("-o name = %q", out)
+	}
+	if out := run(`kubectl get pod api-1 -n shop -o jsonpath="{.spec.nodeName} {.status.phase}"`); out != "node-1 Running" {
+		t.Errorf("-o jsonpath = %q", out)
+	}
+	out := run(`kubectl get pods -n shop -l app=api -o custom-columns='NAME:.metadata.name,PHASE:.status.phase,CONTAINERS:.spec.containers[*].name'`)
+	if !strings.Contains(out, "api-2") || !strings.Contains(out, "Pending") || !strings.Contains(out, "app,sidecar") {
+		t.Errorf("-o custom-columns = %q", out)
+	}
+	if out := run(`kubectl get pod/api-1 -n shop -o yaml`); !strings.Contains(out, "name: api-1") || strings.Contains(out, "kind: List") {
+		t.Errorf("-o yaml = %q", out)
+	}
+
+	if out := run(`kubectl describe pod api-1 -n shop`); !strings.Contains(out, "Name:       api-1") || !strings.Contains(out, "BackOff") {
+		t.Errorf("describe = %q", out)
+	}
+	if out := run(`kubectl events -n shop --types=Warning`); !strings.Contains(out, "pod/api-1") || !strings.Contains(out, "LAST SEEN") {
+		t.Errorf("events = %q", out)
+	}
+	if out := run(`kubectl events -n shop --types Normal`); !strings.Contains(out, "No events found in shop namespace") {
+		t.Errorf("events --types Normal = %q", out)
+	}
+
+	// Deployment 的日志取自按选择器找到的运行中的 Pod
+	if out := run(`kubectl logs deployment/api -n shop --tail 20`); out != "fake logs" {
+		t.Errorf("logs = %q", out)
+	}
+	if out := run(`kubectl logs -l app=api -n shop`); out != "fake logsfake logs" {
+		t.Errorf("logs -l = %q", out)
+	}
+
+	cmd, _ := ParseCommand(`kubectl get pod missing -n shop -o yaml`)
+	if out, err := e.Run(ctx, cmd); err == nil || !strings.HasPrefix(out, "Error from server (NotFound)") {
+		t.Errorf("expected a kubectl style NotFound error, got %q, %v", out, err)
+	}
+	cmd, _ = ParseCommand(`kubectl get widgets`)
+	if _, err := e.Run(ctx, cmd); err == nil || !strings.Contains(err.Error(), `doesn't have a resource type "widgets"`) {
+		t.Errorf("expected unknown resource type error, got %v", err)
+	}
+}
@@ -0,0 +1,396 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// restartedAtAnnotation is set on the pod template by `kubectl rollout restart`.
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// DeploymentHistory is the rollout status of a Deployment together with the
+// revisions still retained as ReplicaSets (spec.revisionHistoryLimit).
+type DeploymentHistory struct {
+	Namespace  string     `json:"namespace"`
+	Deployment string     `json:"deployment"`
+	Status     string     `json:"status"`
+	Replicas   int32      `json:"replicas"`
+	Updated    int32      `json:"updated"`
+	Ready      int32      `json:"ready"`
+	Available  int32      `json:"available"`
+	Paused     bool       `json:"paused,omitempty"`
+	Revisions  []Revision `json:"revisions"` // newest first
+}
+
+// Revision is one Deployment revision and what changed compared to the revision before it.
+type Revision struct {
+	Revision    int64     `json:"revision"`
+	ReplicaSet  string    `json:"replica_set"`
+	CreatedAt   time.Time `json:"created_at"`
+	Replicas    int32     `json:"replicas"`
+	Current     bool      `json:"current,omitempty"`
+	ChangeCause string    `json:"change_cause,omitempty"`
+	Images      []string  `json:"images"`
+	Changes     []string  `json:"changes,omitempty"`
+}
+
+// RevisionHistory reads the Deployment and its ReplicaSets and returns its rollout history.
+func RevisionHistory(ctx context.Context, client kubernetes.Interface, namespace, name string) (*DeploymentHistory, error) {
+	deployment, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+
+	history := &DeploymentHistory{
+		Namespace:  namespace,
+		Deployment: name,
+		Status:     rolloutStatus(deployment),
+		Replicas:   deployment.Status.Replicas,
+		Updated:    deployment.Status.UpdatedReplicas,
+		Ready:      deployment.Status.ReadyReplicas,
+		Available:  deployment.Status.AvailableReplicas,
+		Paused:     deployment.Spec.Paused,
+	}
+
+	var owned []*appsv1.ReplicaSet
+	for i := range replicaSets.Items {
+		rs := &replicaSets.Items[i]
+		if metav1.IsControlledBy(rs, deployment) && rs.Annotations[revisionAnnotation] != "" {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return revisionOf(owned[i]) < revisionOf(owned[j]) })
+
+	current := deployment.Annotations[revisionAnnotation]
+	var previous *appsv1.ReplicaSet
+	for _, rs := range owned {
+		revision := Revision{
+			Revision:    revisionOf(rs),
+			ReplicaSet:  rs.Name,
+			CreatedAt:   rs.CreationTimestamp.Time,
+			Replicas:    rs.Status.Replicas,
+			Current:     rs.Annotations[revisionAnnotation] == current,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			Images:      containerImages(rs.Spec.Template.Spec.Containers),
+		}
+		if previous != nil {
+			revision.Changes = DiffPodTemplates(previous.Spec.Template, rs.Spec.Template)
+		}
+		history.Revisions = append(history.Revisions, revision)
+		previous = rs
+	}
+	for i, j := 0, len(history.Revisions)-1; i < j; i, j = i+1, j-1 {
+		history.Revisions[i], history.Revisions[j] = history.Revisions[j], history.Revisions[i]
+	}
+	return history, nil
+}
+
+// DeploymentForWorkload resolves a Deployment name from either a Deployment name or
+// a pod name, following the pod's ReplicaSet owner. It returns "" when name belongs
+// to neither, e.g. a StatefulSet pod.
+func DeploymentForWorkload(ctx context.Context, client kubernetes.Interface, namespace, name string) (string, error) {
+	_, err := client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return name, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.Kind != "ReplicaSet" {
+		return "", nil
+	}
+	rs, err := client.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+		return owner.Name, nil
+	}
+	return "", nil
+}
+
+// rolloutStatus summarizes the rollout like `kubectl rollout status`.
+func rolloutStatus(d *appsv1.Deployment) string {
+	if d.Spec.Paused {
+		return "paused"
+	}
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return "failed: " + c.Message
+		}
+	}
+	if d.Generation > d.Status.ObservedGeneration {
+		return "progressing: waiting for the controller to observe the new spec"
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	switch {
+	case d.Status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("progressing: %d of %d replicas updated", d.Status.UpdatedReplicas, replicas)
+	case d.Status.Replicas > d.Status.UpdatedReplicas:
+		return fmt.Sprintf("progressing: %d old replicas pending termination", d.Status.Replicas-d.Status.UpdatedReplicas)
+	case d.Status.AvailableReplicas < d.Status.UpdatedReplicas:
+		return fmt.Sprintf("progressing: %d of %d updated replicas available", d.Status.AvailableReplicas, d.Status.UpdatedReplicas)
+	}
+	return "complete"
+}
+
+func revisionOf(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+func containerImages(containers []corev1.Container) []string {
+	images := make([]string, 0, len(containers))
+	for _, c := range containers {
+		images = append(images, c.Name+"="+c.Image)
+	}
+	return images
+}
+
+// DiffPodTemplates lists the differences between two pod templates that usually explain
+// a rollout: images, env var names, resources, command/args, referenced ConfigMaps and
+// Secrets, and `kubectl rollout restart`. Env var values are never included.
+func DiffPodTemplates(prev, cur corev1.PodTemplateSpec) []string {
+	var changes []string
+	if prev.Annotations[restartedAtAnnotation] != cur.Annotations[restartedAtAnnotation] && cur.Annotations[restartedAtAnnotation] != "" {
+		changes = append(changes, "restarted at "+cur.Annotations[restartedAtAnnotation])
+	}
+
+	prevContainers := map[string]corev1.Container{}
+	for _, c := range prev.Spec.Containers {
+		prevContainers[c.Name] = c
+	}
+	for _, c := range cur.Spec.Containers {
+		p, ok := prevContainers[c.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("container %s added (%s)", c.Name, c.Image))
+			continue
+		}
+		delete(prevContainers, c.Name)
+		changes = append(changes, diffContainers(p, c)...)
+	}
+	removed := make([]string, 0, len(prevContainers))
+	for name := range prevContainers {
+		removed = append(removed, name)
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		changes = append(changes, "container "+name+" removed")
+	}
+
+	changes = append(changes, diffSets("configmap", configRefs(prev, "configmap"), configRefs(cur, "configmap"))...)
+	changes = append(changes, diffSets("secret", configRefs(prev, "secret"), configRefs(cur, "secret"))...)
+	return changes
+}
+
+func diffContainers(prev, cur corev1.Container) []string {
+	var changes []string
+	prefix := "container " + cur.Name + ": "
+	if prev.Image != cur.Image {
+		changes = append(changes, fmt.Sprintf("%simage %s -> %s", prefix, prev.Image, cur.Image))
+	}
+
+	prevEnv, curEnv := envValues(prev.Env), envValues(cur.Env)
+	var added, removed, changed []string
+	for name, value := range curEnv {
+		if old, ok := prevEnv[name]; !ok {
+			added = append(added, name)
+		} else if old != value {
+			changed = append(changed, name)
+		}
+	}
+	for name := range prevEnv {
+		if _, ok := curEnv[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	for _, group := range []struct {
+		verb  string
+		names []string
+	}{{"added", added}, {"removed", removed}, {"changed", changed}} {
+		if len(group.names) > 0 {
+			sort.Strings(group.names)
+			changes = append(changes, fmt.Sprintf("%senv %s %s", prefix, group.verb, strings.Join(group.names, ",")))
+		}
+	}
+
+	if !resourcesEqual(prev.Resources, cur.Resources) {
+		changes = append(changes, fmt.Sprintf("%sresources %s -> %s", prefix, formatResources(prev.Resources), formatResources(cur.Resources)))
+	}
+	if strings.Join(prev.Command, " ") != strings.Join(cur.Command, " ") {
+		changes = append(changes, fmt.Sprintf("%scommand %q -> %q", prefix, strings.Join(prev.Command, " "), strings.Join(cur.Command, " ")))
+	}
+	if strings.Join(prev.Args, " ") != strings.Join(cur.Args, " ") {
+		changes = append(changes, fmt.Sprintf("%sargs %q -> %q", prefix, strings.Join(prev.Args, " "), strings.Join(cur.Args, " ")))
+	}
+	return changes
+}
+
+// envValues maps env var names to a comparable representation of their source.
+func envValues(env []corev1.EnvVar) map[string]string {
+	values := make(map[string]string, len(env))
+	for _, e := range env {
+		value := e.Value
+		if e.ValueFrom != nil {
+			value = e.ValueFrom.String()
+		}
+		values[e.Name] = value
+	}
+	return values
+}
+
+func resourcesEqual(a, b corev1.ResourceRequirements) bool {
+	return formatResources(a) == formatResources(b)
+}
+
+// formatResources renders requests and limits, e.g. "cpu=100m/500m,memory=128Mi/256Mi".
+func formatResources(r corev1.ResourceRequirements) string {
+	names := map[corev1.ResourceName]bool{}
+	for name := range r.Requests {
+		names[name] = true
+	}
+	for name := range r.Limits {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, string(name))
+	}
+	sort.Strings(sorted)
+
+	parts := make([]string, 0, len(sorted))
+	for _, name := range sorted {
+		request, limit := "-", "-"
+		if q, ok := r.Requests[corev1.ResourceName(name)]; ok {
+			request = q.String()
+		}
+		if q, ok := r.Limits[corev1.ResourceName(name)]; ok {
+			limit = q.String()
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s/%s", name, request, limit))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
+
+// configRefs returns the ConfigMaps or Secrets referenced by volumes, envFrom and env.
+func configRefs(template corev1.PodTemplateSpec, kind string) map[string]bool {
+	refs := map[string]bool{}
+	for _, v := range template.Spec.Volumes {
+		switch {
+		case kind == "configmap" && v.ConfigMap != nil:
+			refs[v.ConfigMap.Name] = true
+		case kind == "secret" && v.Secret != nil:
+			refs[v.Secret.SecretName] = true
+		}
+	}
+	containers := append(append([]corev1.Container(nil), template.Spec.InitContainers...), template.Spec.Containers...)
+	for _, c := range containers {
+		for _, from := range c.EnvFrom {
+			switch {
+			case kind == "configmap" && from.ConfigMapRef != nil:
+				refs[from.ConfigMapRef.Name] = true
+			case kind == "secret" && from.SecretRef != nil:
+				refs[from.SecretRef.Name] = true
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom == nil {
+				continue
+			}
+			switch {
+			case kind == "configmap" && e.ValueFrom.ConfigMapKeyRef != nil:
+				refs[e.ValueFrom.ConfigMapKeyRef.Name] = true
+			case kind == "secret" && e.ValueFrom.SecretKeyRef != nil:
+				refs[e.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+	return refs
+}
+
+func diffSets(kind string, prev, cur map[string]bool) []string {
+	var added, removed []string
+	for name := range cur {
+		if !prev[name] {
+			added = append(added, name)
+		}
+	}
+	for name := range prev {
+		if !cur[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	var changes []string
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("%s added %s", kind, strings.Join(added, ",")))
+	}
+	if len(removed) > 0 {
+		changes = append(changes, fmt.Sprintf("%s removed %s", kind, strings.Join(removed, ",")))
+	}
+	return changes
+}
+
+// Format renders the status and at most limit revisions (all when limit <= 0), newest first.
+func (h *DeploymentHistory) Format(limit int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "deployment %s/%s: %s (replicas %d, updated %d, ready %d, available %d)\n",
+		h.Namespace, h.Deployment, h.Status, h.Replicas, h.Updated, h.Ready, h.Available)
+	revisions := h.Revisions
+	if limit > 0 && len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+	for _, r := range revisions {
+		current := ""
+		if r.Current {
+			current = " (current)"
+		}
+		fmt.Fprintf(&b, "revision %d%s %s %s replicas=%d %s\n", r.Revision, current,
+			r.CreatedAt.UTC().Format(time.RFC3339), r.ReplicaSet, r.Replicas, strings.Join(r.Images, ","))
+		if r.ChangeCause != "" {
+			fmt.Fprintf(&b, "  change-cause: %s\n", r.ChangeCause)
+		}
+		for _, change := range r.Changes {
+			fmt.Fprintf(&b, "  - %s\n", change)
+		}
+	}
+	if len(h.Revisions) > len(revisions) {
+		fmt.Fprintf(&b, "... %d older revisions omitted\n", len(h.Revisions)-len(revisions))
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiffPodTemplates(t *testing.T) {
+	prev := corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+		Containers: []corev1.Container{{
+			Name:  "web",
+			Image: "web:v1",
+			Env:   []corev1.EnvVar{{Name: "MODE", Value: "a"}, {Name: "OLD", Value: "x"}},
+			EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "web-config-1"},
+			}}},
+		}},
+	}}
+	cur := corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{restartedAtAnnotation: "2025-03-01T13:58:00Z"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "web",
+			Image: "web:v2",
+			Env:   []corev1.EnvVar{{Name: "MODE", Value: "secret-value"}, {Name: "NEW", Value: "y"}},
+			EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "web-config-2"},
+			}}},
+			Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")}},
+		}}},
+	}
+
+	got := strings.Join(DiffPodTemplates(prev, cur), "\n")
+	for _, want := range []string{
+		"restarted at 2025-03-01T13:58:00Z",
+		"container web: image web:v1 -> web:v2",
+		"container web: env added NEW",
+		"container web: env removed OLD",
+		"container web: env changed MODE",
+		"container web: resources none -> memory=-/256Mi",
+		"configmap added web-config-2",
+		"configmap removed web-config-1",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("diff missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "secret-value") {
+		t.Errorf("diff leaks env value:\n%s", got)
+	}
+}
+
+func TestRevisionHistory(t *testing.T) {
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", UID: "d1",
+			Annotations: map[string]string{revisionAnnotation: "2"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, AvailableReplicas: 2},
+	}
+	controller := true
+	replicaSet := func(name, revision, image string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"app": "web"},
+				Annotations:     map[string]string{revisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "web", UID: "d1", Controller: &controller}}},
+			Spec: appsv1.ReplicaSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "web", Image: image}},
+			}}},
+		}
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web-b-x1", Namespace: "shop",
+		OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-b", Controller: &controller}}}}
+	client := fake.NewSimpleClientset(deployment, replicaSet("web-a", "1", "web:v1"), replicaSet("web-b", "2", "web:v2"), pod)
+
+	history, err := RevisionHistory(context.Background(), client, "shop", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if history.Status != "complete" || len(history.Revisions) != 2 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+	newest := history.Revisions[0]
+	if newest.Revision != 2 || !newest.Current || len(newest.Changes) != 1 || newest.Changes[0] != "container web: image web:v1 -> web:v2" {
+		t.Errorf("unexpected newest revision: %+v", newest)
+	}
+
+	name, err := DeploymentForWorkload(context.Background(), client, "shop", "web-b-x1")
+	if err != nil || name != "web" {
+		t.Errorf("DeploymentForWorkload(pod) = %q, %v", name, err)
+	}
+}
@@ -0,0 +1,61 @@
+package kubernetes
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// serviceAccountDir is where the kubelet mounts the service account token, CA and namespace.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+var (
+	inClusterKubeconfigOnce sync.Once
+	inClusterKubeconfigPath string
+	inClusterKubeconfigErr  error
+)
+
+// InClusterKubeconfig returns a kubeconfig file that lets kubectl use the service account
+// of the pod, with clusters.InClusterContext as its only context. The file references the
+// mounted token and CA instead of copying them, so kubelet token rotation keeps working and
+// no credentials are written to disk. It is created once per process under the temp dir.
+func InClusterKubeconfig() (string, error) {
+	inClusterKubeconfigOnce.Do(func() {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			inClusterKubeconfigErr = rest.ErrNotInCluster
+			return
+		}
+		config := inClusterKubeconfig("https://"+net.JoinHostPort(host, port), serviceAccountDir)
+		path := filepath.Join(os.TempDir(), "opsagent-in-cluster.kubeconfig")
+		if inClusterKubeconfigErr = clientcmd.WriteToFile(*config, path); inClusterKubeconfigErr == nil {
+			inClusterKubeconfigPath = path
+		}
+	})
+	return inClusterKubeconfigPath, inClusterKubeconfigErr
+}
+
+// inClusterKubeconfig builds a kubeconfig for server using the token and CA files in dir.
+func inClusterKubeconfig(server, dir string) *clientcmdapi.Config {
+	name := clusters.InClusterContext
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{
+		Server:               server,
+		CertificateAuthority: filepath.Join(dir, "ca.crt"),
+	}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{TokenFile: filepath.Join(dir, "token")}
+	context := &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	if namespace, err := os.ReadFile(filepath.Join(dir, "namespace")); err == nil {
+		context.Namespace = strings.TrimSpace(string(namespace))
+	}
+	config.Contexts[name] = context
+	config.CurrentContext = name
+	return config
+}
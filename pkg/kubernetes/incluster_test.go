@@ -0,0 +1,27 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+)
+
+func TestInClusterKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "namespace"), []byte("opsagent\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := inClusterKubeconfig("https://10.96.0.1:443", dir)
+	context := config.Contexts[config.CurrentContext]
+	if config.CurrentContext != clusters.InClusterContext || context == nil || context.Namespace != "opsagent" {
+		t.Fatalf("unexpected context %q: %+v", config.CurrentContext, context)
+	}
+	if user := config.AuthInfos[context.AuthInfo]; user.TokenFile != filepath.Join(dir, "token") || user.Token != "" {
+		t.Errorf("expected the token file to be referenced instead of copied, got %+v", user)
+	}
+	if cluster := config.Clusters[context.Cluster]; cluster.Server != "https://10.96.0.1:443" || cluster.CertificateAuthority != filepath.Join(dir, "ca.crt") {
+		t.Errorf("unexpected cluster %+v", cluster)
+	}
+}
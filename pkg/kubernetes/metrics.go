@@ -0,0 +1,220 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// metricsPath is the resource metrics API served by metrics-server.
+const metricsPath = "/apis/metrics.k8s.io/v1beta1"
+
+// errMetricsUnavailable is kubectl's message when metrics-server is not installed.
+var errMetricsUnavailable = errors.New("error: Metrics API not available")
+
+// containerMetrics is the usage of one container in a PodMetrics object.
+type containerMetrics struct {
+	Name  string              `json:"name"`
+	Usage corev1.ResourceList `json:"usage"`
+}
+
+// podMetrics mirrors metrics.k8s.io/v1beta1 PodMetrics.
+type podMetrics struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Containers        []containerMetrics `json:"containers"`
+}
+
+// nodeMetrics mirrors metrics.k8s.io/v1beta1 NodeMetrics.
+type nodeMetrics struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Usage             corev1.ResourceList `json:"usage"`
+}
+
+// usageRow is one line of kubectl top output.
+type usageRow struct {
+	namespace, pod, name string
+	cpu, memory          resource.Quantity
+	capacity             corev1.ResourceList // nodes: allocatable, for the percentage columns
+}
+
+// top prints CPU and memory usage of pods or nodes from the metrics API.
+func (e *Executor) top(ctx context.Context, cmd *Command, namespace string) (string, error) {
+	if e.rest == nil {
+		return "", errors.New("no REST client for the metrics API")
+	}
+	var name string
+	if len(cmd.Args) == 2 {
+		name = cmd.Args[1]
+	}
+	switch cmd.Args[0] {
+	case "node", "nodes", "no":
+		return e.topNodes(ctx, cmd, name)
+	}
+
+	path := []string{metricsPath}
+	if namespace != metav1.NamespaceAll {
+		path = append(path, "namespaces", namespace)
+	}
+	path = append(path, "pods")
+	var items []podMetrics
+	if name != "" {
+		var item podMetrics
+		if err := e.metrics(ctx, path, name, "", &item); err != nil {
+			return "", err
+		}
+		items = []podMetrics{item}
+	} else {
+		var list struct {
+			Items []podMetrics `json:"items"`
+		}
+		if err := e.metrics(ctx, path, "", cmd.Selector, &list); err != nil {
+			return "", err
+		}
+		items = list.Items
+	}
+	if len(items) == 0 {
+		if namespace == metav1.NamespaceAll {
+			return "No resources found\n", nil
+		}
+		return fmt.Sprintf("No resources found in %s namespace.\n", namespace), nil
+	}
+
+	var rows []usageRow
+	for _, item := range items {
+		total := usageRow{namespace: item.Namespace, name: item.Name}
+		for _, c := range item.Containers {
+			if cmd.Containers {
+				rows = append(rows, usageRow{namespace: item.Namespace, pod: item.Name, name: c.Name, cpu: c.Usage[corev1.ResourceCPU], memory: c.Usage[corev1.ResourceMemory]})
+			}
+			total.cpu.Add(c.Usage[corev1.ResourceCPU])
+			total.memory.Add(c.Usage[corev1.ResourceMemory])
+		}
+		if !cmd.Containers {
+			rows = append(rows, total)
+		}
+	}
+	return formatUsage(rows, cmd, namespace == metav1.NamespaceAll, false), nil
+}
+
+// topNodes prints node usage with the percentage of allocatable resources.
+func (e *Executor) topNodes(ctx context.Context, cmd *Command, name string) (string, error) {
+	path := []string{metricsPath, "nodes"}
+	var items []nodeMetrics
+	if name != "" {
+		var item nodeMetrics
+		if err := e.metrics(ctx, path, name, "", &item); err != nil {
+			return "", err
+		}
+		items = []nodeMetrics{item}
+	} else {
+		var list struct {
+			Items []nodeMetrics `json:"items"`
+		}
+		if err := e.metrics(ctx, path, "", cmd.Selector, &list); err != nil {
+			return "", err
+		}
+		items = list.Items
+	}
+
+	allocatable := map[string]corev1.ResourceList{}
+	if nodes, err := e.client.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: cmd.Selector}); err == nil {
+		for _, node := range nodes.Items {
+			allocatable[node.Name] = node.Status.Allocatable
+		}
+	}
+	rows := make([]usageRow, 0, len(items))
+	for _, item := range items {
+		rows = append(rows, usageRow{name: item.Name, cpu: item.Usage[corev1.ResourceCPU], memory: item.Usage[corev1.ResourceMemory], capacity: allocatable[item.Name]})
+	}
+	if len(rows) == 0 {
+		return "No resources found\n", nil
+	}
+	return formatUsage(rows, cmd, false, true), nil
+}
+
+// metrics fetches the metrics of a collection, or of one object when name is set, into
+// out. A missing API is reported like kubectl.
+func (e *Executor) metrics(ctx context.Context, path []string, name, selector string, out interface{}) error {
+	if name != "" {
+		path = append(append([]string(nil), path...), name)
+	}
+	req := e.rest.Get().AbsPath(path...)
+	if selector != "" {
+		req = req.Param("labelSelector", selector)
+	}
+	data, err := req.DoRaw(ctx)
+	if err != nil {
+		if apierrors.IsNotFound(err) && name == "" {
+			return errMetricsUnavailable
+		}
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// formatUsage prints usage rows in the layout of kubectl top, CPU in millicores and
+// memory in MiB.
+func formatUsage(rows []usageRow, cmd *Command, withNamespace, nodes bool) string {
+	switch cmd.SortBy {
+	case "cpu":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].cpu.Cmp(rows[j].cpu) > 0 })
+	case "memory":
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].memory.Cmp(rows[j].memory) > 0 })
+	}
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 6, 4, 3, ' ', 0)
+	if !cmd.NoHeaders {
+		switch {
+		case nodes:
+			fmt.Fprintln(w, "NAME\tCPU(cores)\tCPU(%)\tMEMORY(bytes)\tMEMORY(%)")
+		case cmd.Containers:
+			if withNamespace {
+				fmt.Fprint(w, "NAMESPACE\t")
+			}
+			fmt.Fprintln(w, "POD\tNAME\tCPU(cores)\tMEMORY(bytes)")
+		default:
+			if withNamespace {
+				fmt.Fprint(w, "NAMESPACE\t")
+			}
+			fmt.Fprintln(w, "NAME\tCPU(cores)\tMEMORY(bytes)")
+		}
+	}
+	for _, row := range rows {
+		cpu, memory := fmt.Sprintf("%dm", row.cpu.MilliValue()), fmt.Sprintf("%dMi", row.memory.Value()/(1024*1024))
+		switch {
+		case nodes:
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", row.name, cpu, percent(row.cpu, row.capacity, corev1.ResourceCPU), memory, percent(row.memory, row.capacity, corev1.ResourceMemory))
+		case cmd.Containers:
+			if withNamespace {
+				fmt.Fprintf(w, "%s\t", row.namespace)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.pod, row.name, cpu, memory)
+		default:
+			if withNamespace {
+				fmt.Fprintf(w, "%s\t", row.namespace)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", row.name, cpu, memory)
+		}
+	}
+	w.Flush()
+	return b.String()
+}
+
+// percent is usage as a percentage of the allocatable amount, <unknown> when unavailable.
+func percent(usage resource.Quantity, capacity corev1.ResourceList, name corev1.ResourceName) string {
+	total, ok := capacity[name]
+	if !ok || total.IsZero() {
+		return "<unknown>"
+	}
+	return fmt.Sprintf("%d%%", usage.MilliValue()*100/total.MilliValue())
+}
@@ -0,0 +1,223 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultNodePoolLabels are the node labels that identify the node pool on common platforms.
+var DefaultNodePoolLabels = []string{
+	"cloud.google.com/gke-nodepool",
+	"eks.amazonaws.com/nodegroup",
+	"karpenter.sh/nodepool",
+	"kubernetes.azure.com/agentpool",
+	"agentpool",
+	"alibabacloud.com/nodepool-id",
+	"node.kubernetes.io/pool",
+}
+
+const (
+	// noPool groups nodes without any node pool label.
+	noPool = "<none>"
+	// instanceTypeLabel is the well-known label of the node instance type.
+	instanceTypeLabel = "node.kubernetes.io/instance-type"
+)
+
+// NodePool summarizes the nodes of one pool and the pods scheduled on them.
+// CPU is in millicores and memory in bytes; requests only count running and pending pods.
+type NodePool struct {
+	Name           string         `json:"name"`
+	Nodes          int            `json:"nodes"`
+	Ready          int            `json:"ready"`
+	InstanceTypes  []string       `json:"instance_types,omitempty"`
+	Taints         []string       `json:"taints,omitempty"`
+	CPUAllocatable int64          `json:"cpu_allocatable"`
+	CPURequested   int64          `json:"cpu_requested"`
+	MemAllocatable int64          `json:"memory_allocatable"`
+	MemRequested   int64          `json:"memory_requested"`
+	GPUAllocatable int64          `json:"gpu_allocatable"`
+	GPURequested   int64          `json:"gpu_requested"`
+	Pods           int            `json:"pods"`
+	Namespaces     map[string]int `json:"namespaces,omitempty"` // pod count per namespace
+	podNames       []string
+}
+
+// Matches reports whether the pool name, one of its namespaces or one of its pods
+// contains the filter. An empty filter matches every pool.
+func (p NodePool) Matches(filter string) bool {
+	filter = strings.ToLower(strings.TrimSpace(filter))
+	if filter == "" || strings.Contains(strings.ToLower(p.Name), filter) {
+		return true
+	}
+	for namespace := range p.Namespaces {
+		if strings.Contains(strings.ToLower(namespace), filter) {
+			return true
+		}
+	}
+	for _, pod := range p.podNames {
+		if strings.Contains(strings.ToLower(pod), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// TopNamespaces returns up to n namespaces with the most pods in the pool.
+func (p NodePool) TopNamespaces(n int) []string {
+	namespaces := make([]string, 0, len(p.Namespaces))
+	for namespace := range p.Namespaces {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if p.Namespaces[namespaces[i]] != p.Namespaces[namespaces[j]] {
+			return p.Namespaces[namespaces[i]] > p.Namespaces[namespaces[j]]
+		}
+		return namespaces[i] < namespaces[j]
+	})
+	if len(namespaces) > n {
+		namespaces = namespaces[:n]
+	}
+	for i, namespace := range namespaces {
+		namespaces[i] = fmt.Sprintf("%s(%d)", namespace, p.Namespaces[namespace])
+	}
+	return namespaces
+}
+
+// ListNodePools lists the nodes and pods of a kubeconfig context (current context when
+// empty) and groups them by node pool.
+func ListNodePools(ctx context.Context, kubeContext string, poolLabels []string) ([]NodePool, error) {
+	clientset, err := ClientsetForContext(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return GroupNodePools(nodes.Items, pods.Items, poolLabels), nil
+}
+
+// GroupNodePools groups nodes by the first matching pool label and sums the
+// allocatable capacity and the requests of the pods bound to them.
+func GroupNodePools(nodes []corev1.Node, pods []corev1.Pod, poolLabels []string) []NodePool {
+	if len(poolLabels) == 0 {
+		poolLabels = DefaultNodePoolLabels
+	}
+
+	pools := map[string]*NodePool{}
+	nodePool := map[string]*NodePool{}
+	instanceTypes := map[string]map[string]bool{}
+	taints := map[string]map[string]bool{}
+	for _, node := range nodes {
+		name := poolName(node.Labels, poolLabels)
+		pool, ok := pools[name]
+		if !ok {
+			pool = &NodePool{Name: name, Namespaces: map[string]int{}}
+			pools[name] = pool
+			instanceTypes[name] = map[string]bool{}
+			taints[name] = map[string]bool{}
+		}
+		nodePool[node.Name] = pool
+
+		pool.Nodes++
+		if nodeReady(node) {
+			pool.Ready++
+		}
+		if instanceType := node.Labels[instanceTypeLabel]; instanceType != "" {
+			instanceTypes[name][instanceType] = true
+		}
+		for _, taint := range node.Spec.Taints {
+			taints[name][taintString(taint)] = true
+		}
+		pool.CPUAllocatable += node.Status.Allocatable.Cpu().MilliValue()
+		pool.MemAllocatable += node.Status.Allocatable.Memory().Value()
+		pool.GPUAllocatable += gpuCount(node.Status.Allocatable)
+	}
+
+	for _, pod := range pods {
+		pool, ok := nodePool[pod.Spec.NodeName]
+		if !ok {
+			continue
+		}
+		pool.Pods++
+		pool.Namespaces[pod.Namespace]++
+		pool.podNames = append(pool.podNames, pod.Name)
+		for _, container := range pod.Spec.Containers {
+			requests := container.Resources.Requests
+			pool.CPURequested += requests.Cpu().MilliValue()
+			pool.MemRequested += requests.Memory().Value()
+			pool.GPURequested += gpuCount(requests)
+		}
+	}
+
+	result := make([]NodePool, 0, len(pools))
+	for name, pool := range pools {
+		pool.InstanceTypes = sortedKeys(instanceTypes[name])
+		pool.Taints = sortedKeys(taints[name])
+		result = append(result, *pool)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func poolName(labels map[string]string, poolLabels []string) string {
+	for _, label := range poolLabels {
+		if value := labels[label]; value != "" {
+			return value
+		}
+	}
+	return noPool
+}
+
+func nodeReady(node corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue && !node.Spec.Unschedulable
+		}
+	}
+	return false
+}
+
+func taintString(taint corev1.Taint) string {
+	if taint.Value == "" {
+		return fmt.Sprintf("%s:%s", taint.Key, taint.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", taint.Key, taint.Value, taint.Effect)
+}
+
+// gpuCount sums extended resources named <vendor>/gpu, e.g. nvidia.com/gpu.
+func gpuCount(resources corev1.ResourceList) int64 {
+	var count int64
+	for name, quantity := range resources {
+		if strings.HasSuffix(string(name), "/gpu") {
+			count += quantity.Value()
+		}
+	}
+	return count
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatMemory formats bytes as Gi with one decimal.
+func FormatMemory(bytes int64) string {
+	return fmt.Sprintf("%.1fGi", float64(bytes)/(1<<30))
+}
@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGroupNodePools(t *testing.T) {
+	node := func(name, pool, gpus string, taints ...corev1.Taint) corev1.Node {
+		allocatable := corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("4"),
+			corev1.ResourceMemory: resource.MustParse("16Gi"),
+		}
+		if gpus != "" {
+			allocatable["nvidia.com/gpu"] = resource.MustParse(gpus)
+		}
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{
+				"cloud.google.com/gke-nodepool": pool,
+				instanceTypeLabel:               "n1-standard-4",
+			}},
+			Spec: corev1.NodeSpec{Taints: taints},
+			Status: corev1.NodeStatus{
+				Allocatable: allocatable,
+				Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	pod := func(namespace, name, nodeName, cpu, gpus string) corev1.Pod {
+		requests := corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		if gpus != "" {
+			requests["nvidia.com/gpu"] = resource.MustParse(gpus)
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Spec: corev1.PodSpec{
+				NodeName:   nodeName,
+				Containers: []corev1.Container{{Resources: corev1.ResourceRequirements{Requests: requests}}},
+			},
+		}
+	}
+
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "present", Effect: corev1.TaintEffectNoSchedule}
+	pools := GroupNodePools(
+		[]corev1.Node{node("gpu-1", "gpu", "2", gpuTaint), node("gpu-2", "gpu", "2", gpuTaint), node("cpu-1", "default", "")},
+		[]corev1.Pod{
+			pod("digital-twin", "twin-sim-0", "gpu-1", "1", "1"),
+			pod("digital-twin", "twin-sim-1", "gpu-2", "1", "1"),
+			pod("web", "web-abc", "cpu-1", "500m", ""),
+			pod("web", "unscheduled", "", "1", ""),
+		},
+		nil,
+	)
+
+	if len(pools) != 2 || pools[0].Name != "default" || pools[1].Name != "gpu" {
+		t.Fatalf("unexpected pools: %+v", pools)
+	}
+	gpu := pools[1]
+	if gpu.Nodes != 2 || gpu.Ready != 2 || gpu.Pods != 2 {
+		t.Errorf("unexpected gpu pool counts: %+v", gpu)
+	}
+	if gpu.GPUAllocatable != 4 || gpu.GPURequested != 2 || gpu.CPURequested != 2000 || gpu.CPUAllocatable != 8000 {
+		t.Errorf("unexpected gpu pool capacity: %+v", gpu)
+	}
+	if !reflect.DeepEqual(gpu.Taints, []string{"nvidia.com/gpu=present:NoSchedule"}) {
+		t.Errorf("taints = %v", gpu.Taints)
+	}
+	if !gpu.Matches("digital") || !gpu.Matches("twin-sim") || pools[0].Matches("twin") {
+		t.Error("unexpected filter matches")
+	}
+	if got := pools[0].TopNamespaces(3); !reflect.DeepEqual(got, []string{"web(1)"}) {
+		t.Errorf("TopNamespaces() = %v", got)
+	}
+}
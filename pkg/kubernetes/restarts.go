@@ -0,0 +1,167 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// oomKilledReason is the termination reason of a container killed by the kernel OOM killer.
+const oomKilledReason = "OOMKilled"
+
+// WorkloadRestarts aggregates the container restarts of one workload in one cluster.
+type WorkloadRestarts struct {
+	Cluster     string    `json:"cluster,omitempty"`
+	Namespace   string    `json:"namespace"`
+	Workload    string    `json:"workload"` // kind/name, e.g. Deployment/web
+	Pods        int       `json:"pods"`
+	Restarts    int32     `json:"restarts"`
+	OOMKills    int       `json:"oom_kills"`
+	LastRestart time.Time `json:"last_restart"`
+	LastReason  string    `json:"last_reason,omitempty"`
+}
+
+// RestartReport is the restart and OOMKill correlation of one or more clusters over a
+// window. Times are normalized to Location so on-call rotations in different time zones
+// read the same report.
+type RestartReport struct {
+	Since     time.Time          `json:"since"`
+	Until     time.Time          `json:"until"`
+	Location  string             `json:"location"`
+	Workloads []WorkloadRestarts `json:"workloads"`
+	// ByHour counts the last restart of each container by hour of day in Location,
+	// which shows whether restarts cluster around batch jobs or traffic peaks.
+	ByHour [24]int `json:"by_hour"`
+}
+
+// CollectRestarts lists the pods of a namespace (all namespaces when empty) and
+// aggregates the restarts whose last termination finished after since.
+func CollectRestarts(ctx context.Context, client kubernetes.Interface, cluster, namespace string, since time.Time) ([]WorkloadRestarts, error) {
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeRestarts(cluster, pods.Items, since), nil
+}
+
+// AnalyzeRestarts groups containers that restarted within the window by owning
+// workload. Restart counts come from container statuses, so a container counts when
+// its last termination finished after since; restarts of pods that were already
+// deleted are not included.
+func AnalyzeRestarts(cluster string, pods []corev1.Pod, since time.Time) []WorkloadRestarts {
+	byWorkload := map[string]*WorkloadRestarts{}
+	for i := range pods {
+		pod := &pods[i]
+		workload := workloadOf(pod)
+		counted := false
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, status := range statuses {
+			terminated := status.LastTerminationState.Terminated
+			if status.RestartCount == 0 || terminated == nil || terminated.FinishedAt.Time.Before(since) {
+				continue
+			}
+			key := pod.Namespace + "/" + workload
+			w := byWorkload[key]
+			if w == nil {
+				w = &WorkloadRestarts{Cluster: cluster, Namespace: pod.Namespace, Workload: workload}
+				byWorkload[key] = w
+			}
+			if !counted {
+				w.Pods++
+				counted = true
+			}
+			w.Restarts += status.RestartCount
+			if terminated.Reason == oomKilledReason {
+				w.OOMKills++
+			}
+			if terminated.FinishedAt.Time.After(w.LastRestart) {
+				w.LastRestart = terminated.FinishedAt.Time
+				w.LastReason = terminated.Reason
+			}
+		}
+	}
+
+	workloads := make([]WorkloadRestarts, 0, len(byWorkload))
+	for _, w := range byWorkload {
+		workloads = append(workloads, *w)
+	}
+	return workloads
+}
+
+// NewRestartReport merges the workloads of several clusters, most restarts first, and
+// normalizes the last restart times to loc.
+func NewRestartReport(workloads []WorkloadRestarts, since, until time.Time, loc *time.Location) *RestartReport {
+	if loc == nil {
+		loc = time.UTC
+	}
+	report := &RestartReport{
+		Since:     since.In(loc),
+		Until:     until.In(loc),
+		Location:  loc.String(),
+		Workloads: workloads,
+	}
+	for i := range report.Workloads {
+		w := &report.Workloads[i]
+		w.LastRestart = w.LastRestart.In(loc)
+		report.ByHour[w.LastRestart.Hour()]++
+	}
+	sort.Slice(report.Workloads, func(i, j int) bool {
+		a, b := report.Workloads[i], report.Workloads[j]
+		if a.Restarts != b.Restarts {
+			return a.Restarts > b.Restarts
+		}
+		if a.OOMKills != b.OOMKills {
+			return a.OOMKills > b.OOMKills
+		}
+		return a.Cluster+"/"+a.Namespace+"/"+a.Workload < b.Cluster+"/"+b.Namespace+"/"+b.Workload
+	})
+	return report
+}
+
+// TotalOOMKills returns the number of containers whose last termination was an OOMKill.
+func (r *RestartReport) TotalOOMKills() int {
+	total := 0
+	for _, w := range r.Workloads {
+		total += w.OOMKills
+	}
+	return total
+}
+
+// Format renders the workloads with the most restarts and the hour-of-day distribution.
+func (r *RestartReport) Format(maxWorkloads int) string {
+	const layout = "2006-01-02 15:04 MST"
+	var b strings.Builder
+	fmt.Fprintf(&b, "restarts between %s and %s (%s): %d workloads, %d OOMKills\n",
+		r.Since.Format(layout), r.Until.Format(layout), r.Location, len(r.Workloads), r.TotalOOMKills())
+	for i, w := range r.Workloads {
+		if maxWorkloads > 0 && i == maxWorkloads {
+			fmt.Fprintf(&b, "... %d more workloads\n", len(r.Workloads)-maxWorkloads)
+			break
+		}
+		target := w.Namespace + " " + w.Workload
+		if w.Cluster != "" {
+			target = w.Cluster + " " + target
+		}
+		fmt.Fprintf(&b, "%s: %d restarts in %d pods, %d OOMKills, last %s", target, w.Restarts, w.Pods, w.OOMKills, w.LastRestart.Format(layout))
+		if w.LastReason != "" {
+			fmt.Fprintf(&b, " (%s)", w.LastReason)
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Workloads) > 0 {
+		var hours []string
+		for hour, count := range r.ByHour {
+			if count > 0 {
+				hours = append(hours, fmt.Sprintf("%02d:00=%d", hour, count))
+			}
+		}
+		fmt.Fprintf(&b, "last restart by hour (%s): %s\n", r.Location, strings.Join(hours, " "))
+	}
+	return b.String()
+}
@@ -0,0 +1,50 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnalyzeRestarts(t *testing.T) {
+	now := time.Date(2026, 10, 16, 2, 30, 0, 0, time.UTC)
+	controller := true
+	pod := func(name string, restarts int32, reason string, finished time.Time) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop", Labels: map[string]string{"pod-template-hash": "5d8f"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-5d8f", Controller: &controller}}},
+			Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "web",
+				RestartCount: restarts,
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason: reason, FinishedAt: metav1.NewTime(finished),
+				}},
+			}}},
+		}
+	}
+	workloads := AnalyzeRestarts("prod", []corev1.Pod{
+		pod("web-5d8f-a", 3, "OOMKilled", now.Add(-time.Hour)),
+		pod("web-5d8f-b", 1, "Error", now.Add(-2*time.Hour)),
+		pod("web-5d8f-c", 7, "Error", now.Add(-48*time.Hour)),
+		{ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "tools"}},
+	}, now.Add(-24*time.Hour))
+
+	if len(workloads) != 1 {
+		t.Fatalf("expected 1 workload, got %+v", workloads)
+	}
+	if w := workloads[0]; w.Workload != "Deployment/web" || w.Pods != 2 || w.Restarts != 4 || w.OOMKills != 1 || w.LastReason != "OOMKilled" {
+		t.Errorf("unexpected workload: %+v", w)
+	}
+
+	shanghai := time.FixedZone("CST", 8*3600)
+	report := NewRestartReport(workloads, now.Add(-24*time.Hour), now, shanghai)
+	if report.ByHour[9] != 1 {
+		t.Errorf("expected the last restart at 09:00 CST, got %v", report.ByHour)
+	}
+	if out := report.Format(0); !strings.Contains(out, "prod shop Deployment/web: 4 restarts in 2 pods, 1 OOMKills, last 2026-10-16 09:30 CST (OOMKilled)") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
@@ -0,0 +1,297 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// revisionAnnotation is set by the deployment controller on every ReplicaSet it owns.
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// changeCauseAnnotation records why a rollout happened, e.g. the kubectl command.
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+
+	defaultMaxRolloutEvents = 1000
+
+	// InClusterName is the cluster name used for rollouts recorded with the in-cluster config.
+	InClusterName = "in-cluster"
+)
+
+// RolloutEvent is a Deployment rollout observed through its ReplicaSets.
+type RolloutEvent struct {
+	Cluster        string    `json:"cluster"`
+	Namespace      string    `json:"namespace"`
+	Deployment     string    `json:"deployment"`
+	Revision       string    `json:"revision"`
+	Images         []string  `json:"images"`
+	PreviousImages []string  `json:"previous_images,omitempty"`
+	ChangeCause    string    `json:"change_cause,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// ImageChanged reports whether the rollout changed any container image.
+func (e RolloutEvent) ImageChanged() bool {
+	return len(e.PreviousImages) > 0 && strings.Join(e.Images, ",") != strings.Join(e.PreviousImages, ",")
+}
+
+// RolloutRecorder keeps the most recent rollout events of every watched cluster in memory.
+type RolloutRecorder struct {
+	mu        sync.RWMutex
+	maxEvents int
+	events    map[string][]RolloutEvent // cluster -> events ordered by time
+}
+
+// NewRolloutRecorder creates a recorder keeping at most maxEvents events per cluster.
+func NewRolloutRecorder(maxEvents int) *RolloutRecorder {
+	if maxEvents <= 0 {
+		maxEvents = defaultMaxRolloutEvents
+	}
+	return &RolloutRecorder{maxEvents: maxEvents, events: map[string][]RolloutEvent{}}
+}
+
+var (
+	rolloutRecorder     *RolloutRecorder
+	rolloutRecorderOnce sync.Once
+)
+
+// Rollouts returns the process wide rollout recorder, sized by changes.max_events.
+func Rollouts() *RolloutRecorder {
+	rolloutRecorderOnce.Do(func() {
+		rolloutRecorder = NewRolloutRecorder(utils.GetConfig().GetInt("changes.max_events"))
+	})
+	return rolloutRecorder
+}
+
+// Record adds a rollout event. Events for a revision that is already recorded are ignored.
+// PreviousImages is filled from the preceding rollout of the same Deployment when unset.
+func (r *RolloutRecorder) Record(event RolloutEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events[event.Cluster]
+	var previous *RolloutEvent
+	for i := range events {
+		e := &events[i]
+		if e.Namespace != event.Namespace || e.Deployment != event.Deployment {
+			continue
+		}
+		if e.Revision == event.Revision {
+			return
+		}
+		if !e.Time.After(event.Time) && (previous == nil || e.Time.After(previous.Time)) {
+			previous = e
+		}
+	}
+	if previous != nil && len(event.PreviousImages) == 0 {
+		event.PreviousImages = previous.Images
+	}
+
+	events = append(events, event)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	if len(events) > r.maxEvents {
+		events = events[len(events)-r.maxEvents:]
+	}
+	r.events[event.Cluster] = events
+}
+
+// Recent returns the rollouts of a cluster in [since, until], newest first.
+// An empty namespace matches every namespace.
+func (r *RolloutRecorder) Recent(cluster, namespace string, since, until time.Time) []RolloutEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []RolloutEvent
+	for _, e := range r.events[cluster] {
+		if (namespace == "" || e.Namespace == namespace) && !e.Time.Before(since) && !e.Time.After(until) {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+	return events
+}
+
+// Clusters returns the clusters with recorded rollouts.
+func (r *RolloutRecorder) Clusters() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clusters := make([]string, 0, len(r.events))
+	for cluster := range r.events {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+	return clusters
+}
+
+// ConfigForContext returns the REST config of a kubeconfig context, or the current
+// context when kubeContext is empty. Names registered in the cluster registry are
+// mapped to their context and kubeconfig file; clusters registered with in_cluster
+// use the service account of the pod (client-go in-cluster config).
+func ConfigForContext(kubeContext string) (*rest.Config, error) {
+	if kubeContext == clusters.InClusterContext || clusters.Default().InCluster(kubeContext) {
+		return rest.InClusterConfig()
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeContext != "" {
+		var kubeconfig string
+		kubeContext, kubeconfig = clusters.Default().Target(kubeContext)
+		if kubeconfig != "" {
+			rules.ExplicitPath = kubeconfig
+		}
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{CurrentContext: kubeContext},
+	).ClientConfig()
+}
+
+// clientsetFunc replaces the clientsets returned by ClientsetForContext, see SetClientsetFunc.
+var clientsetFunc func(kubeContext string) (kubernetes.Interface, error)
+
+// SetClientsetFunc makes ClientsetForContext return clientsets created by fn instead of
+// connecting to the clusters, e.g. fake clientsets serving fixtures in development mode.
+// It must be called before serving requests; nil restores the default behavior.
+func SetClientsetFunc(fn func(kubeContext string) (kubernetes.Interface, error)) {
+	clientsetFunc = fn
+}
+
+// ClientsetForContext returns a clientset for a kubeconfig context. When kubeContext is
+// empty and no kubeconfig is available, the in-cluster config is used.
+func ClientsetForContext(kubeContext string) (kubernetes.Interface, error) {
+	if clientsetFunc != nil {
+		return clientsetFunc(kubeContext)
+	}
+	config, err := ConfigForContext(kubeContext)
+	if err != nil {
+		if kubeContext != "" {
+			return nil, err
+		}
+		if config, err = GetKubeConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// WatchRollouts records the rollouts of a cluster until ctx is done. ReplicaSets that
+// already exist are recorded with their creation time, so recent history is available
+// right after start; later revisions, including rollbacks that reuse an old ReplicaSet,
+// are recorded when they are observed.
+func WatchRollouts(ctx context.Context, cluster string, config *rest.Config, recorder *RolloutRecorder) error {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	factory := informers.NewSharedInformerFactory(clientset, 0)
+	informer := factory.Apps().V1().ReplicaSets().Informer()
+	_, err = informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if rs, ok := obj.(*appsv1.ReplicaSet); ok {
+				if event, ok := rolloutEvent(cluster, rs, rs.CreationTimestamp.Time); ok {
+					recorder.Record(event)
+				}
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldRS, ok1 := oldObj.(*appsv1.ReplicaSet)
+			rs, ok2 := newObj.(*appsv1.ReplicaSet)
+			if !ok1 || !ok2 || oldRS.Annotations[revisionAnnotation] == rs.Annotations[revisionAnnotation] {
+				return
+			}
+			if event, ok := rolloutEvent(cluster, rs, time.Now()); ok {
+				recorder.Record(event)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	for informerType, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to sync %v informer for cluster %s", informerType, cluster)
+		}
+	}
+	<-ctx.Done()
+	factory.Shutdown()
+	return nil
+}
+
+// rolloutEvent converts a ReplicaSet owned by a Deployment into a rollout event.
+func rolloutEvent(cluster string, rs *appsv1.ReplicaSet, at time.Time) (RolloutEvent, bool) {
+	revision := rs.Annotations[revisionAnnotation]
+	var deployment string
+	for _, owner := range rs.OwnerReferences {
+		if owner.Kind == "Deployment" {
+			deployment = owner.Name
+		}
+	}
+	if revision == "" || deployment == "" {
+		return RolloutEvent{}, false
+	}
+
+	return RolloutEvent{
+		Cluster:     cluster,
+		Namespace:   rs.Namespace,
+		Deployment:  deployment,
+		Revision:    revision,
+		Images:      containerImages(rs.Spec.Template.Spec.Containers),
+		ChangeCause: rs.Annotations[changeCauseAnnotation],
+		Time:        at,
+	}, true
+}
+
+// CorrelatedChange is a rollout that happened shortly before an incident.
+type CorrelatedChange struct {
+	RolloutEvent
+	// Before is how long before the incident the rollout happened.
+	Before time.Duration `json:"before"`
+}
+
+// Summary describes the change, e.g. "13:58 default/web image nginx=nginx:1.26 -> nginx=nginx:1.27 (4m0s before)".
+func (c CorrelatedChange) Summary() string {
+	change := "rollout revision " + c.Revision
+	if c.ImageChanged() {
+		change = fmt.Sprintf("image %s -> %s", strings.Join(c.PreviousImages, ","), strings.Join(c.Images, ","))
+	}
+	summary := fmt.Sprintf("%s %s/%s %s (%s before)", c.Time.Format("15:04"), c.Namespace, c.Deployment, change, c.Before.Round(time.Second))
+	if c.ChangeCause != "" {
+		summary += ": " + c.ChangeCause
+	}
+	return summary
+}
+
+// CorrelateChanges returns the rollouts within window before the incident, closest first.
+// Image changes are listed before other rollouts at the same distance.
+func CorrelateChanges(events []RolloutEvent, incident time.Time, window time.Duration) []CorrelatedChange {
+	var changes []CorrelatedChange
+	for _, e := range events {
+		before := incident.Sub(e.Time)
+		if before < 0 || before > window {
+			continue
+		}
+		changes = append(changes, CorrelatedChange{RolloutEvent: e, Before: before})
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Before != changes[j].Before {
+			return changes[i].Before < changes[j].Before
+		}
+		return changes[i].ImageChanged() && !changes[j].ImageChanged()
+	})
+	return changes
+}
@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRolloutRecorder(t *testing.T) {
+	base := time.Date(2025, 3, 1, 13, 0, 0, 0, time.UTC)
+	recorder := NewRolloutRecorder(10)
+	recorder.Record(RolloutEvent{Cluster: "prod", Namespace: "shop", Deployment: "web", Revision: "1", Images: []string{"nginx=nginx:1.26"}, Time: base})
+	recorder.Record(RolloutEvent{Cluster: "prod", Namespace: "shop", Deployment: "web", Revision: "2", Images: []string{"nginx=nginx:1.27"}, Time: base.Add(58 * time.Minute)})
+	recorder.Record(RolloutEvent{Cluster: "prod", Namespace: "shop", Deployment: "web", Revision: "2", Images: []string{"nginx=nginx:1.27"}, Time: base.Add(59 * time.Minute)})
+	recorder.Record(RolloutEvent{Cluster: "prod", Namespace: "billing", Deployment: "api", Revision: "5", Images: []string{"api=api:v5"}, Time: base.Add(30 * time.Minute)})
+
+	events := recorder.Recent("prod", "shop", base, base.Add(time.Hour))
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events (duplicate revision ignored), got %d", len(events))
+	}
+	if events[0].Revision != "2" || !events[0].ImageChanged() {
+		t.Errorf("expected newest event to be an image change, got %+v", events[0])
+	}
+	if got := strings.Join(events[0].PreviousImages, ","); got != "nginx=nginx:1.26" {
+		t.Errorf("previous images = %q", got)
+	}
+	if n := len(recorder.Recent("prod", "", base, base.Add(time.Hour))); n != 3 {
+		t.Errorf("expected 3 events across namespaces, got %d", n)
+	}
+}
+
+func TestCorrelateChanges(t *testing.T) {
+	incident := time.Date(2025, 3, 1, 14, 2, 0, 0, time.UTC)
+	events := []RolloutEvent{
+		{Namespace: "shop", Deployment: "web", Revision: "2", Images: []string{"nginx=nginx:1.27"}, PreviousImages: []string{"nginx=nginx:1.26"}, Time: incident.Add(-4 * time.Minute)},
+		{Namespace: "shop", Deployment: "cart", Revision: "7", Images: []string{"cart=cart:v7"}, Time: incident.Add(-20 * time.Minute)},
+		{Namespace: "shop", Deployment: "old", Revision: "3", Time: incident.Add(-3 * time.Hour)},
+		{Namespace: "shop", Deployment: "later", Revision: "4", Time: incident.Add(time.Minute)},
+	}
+
+	changes := CorrelateChanges(events, incident, time.Hour)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 correlated changes, got %d", len(changes))
+	}
+	if changes[0].Deployment != "web" || changes[0].Before != 4*time.Minute {
+		t.Errorf("expected web 4m before first, got %+v", changes[0])
+	}
+	want := "13:58 shop/web image nginx=nginx:1.26 -> nginx=nginx:1.27 (4m0s before)"
+	if got := changes[0].Summary(); got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
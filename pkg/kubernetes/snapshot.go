@@ -0,0 +1,289 @@
+package kubernetes
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DefaultSnapshotKinds are the resource kinds recorded when snapshots.kinds is not configured.
+// Secrets are never listed: listing them would transfer their data even though only metadata is kept.
+var DefaultSnapshotKinds = []string{
+	"namespaces", "nodes", "deployments", "statefulsets", "daemonsets", "cronjobs",
+	"services", "ingresses", "configmaps", "persistentvolumeclaims", "horizontalpodautoscalers",
+}
+
+// SnapshotObject is the metadata of one object in a snapshot. For workloads the
+// replicas, images and resources of the pod template are recorded, for ConfigMaps a
+// hash of the data; the rest of the spec, status, annotations and data are left out.
+type SnapshotObject struct {
+	Kind       string              `json:"kind"` // resource name, e.g. deployments
+	Namespace  string              `json:"namespace,omitempty"`
+	Name       string              `json:"name"`
+	UID        string              `json:"uid"`
+	Generation int64               `json:"generation,omitempty"`
+	Labels     map[string]string   `json:"labels,omitempty"`
+	Owner      string              `json:"owner,omitempty"` // kind/name of the controller
+	CreatedAt  time.Time           `json:"created_at"`
+	Replicas   *int32              `json:"replicas,omitempty"`
+	Containers []SnapshotContainer `json:"containers,omitempty"`
+	DataHash   string              `json:"data_hash,omitempty"` // SHA-256 of the ConfigMap data and binaryData
+}
+
+// SnapshotContainer is a container of a workload's pod template, init containers included.
+type SnapshotContainer struct {
+	Name     string            `json:"name"`
+	Image    string            `json:"image"`
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// Snapshot is the metadata of the listed resource kinds of a cluster at one time.
+type Snapshot struct {
+	Cluster string    `json:"cluster"`
+	TakenAt time.Time `json:"taken_at"`
+	// Kinds and Namespaces are the listed resource kinds and namespaces, empty in
+	// snapshots taken before they were recorded; empty Namespaces means all namespaces.
+	Kinds      []string         `json:"kinds,omitempty"`
+	Namespaces []string         `json:"namespaces,omitempty"`
+	Objects    []SnapshotObject `json:"objects"`
+}
+
+// snapshotLister lists one resource kind in namespace, all namespaces when empty.
+// Cluster-scoped kinds ignore the namespace.
+type snapshotLister func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error)
+
+// clusterScopedKinds are the snapshot kinds that do not belong to a namespace.
+var clusterScopedKinds = map[string]bool{"namespaces": true, "nodes": true}
+
+// snapshotListers are the supported snapshot kinds.
+var snapshotListers = map[string]snapshotLister{
+	"namespaces": func(ctx context.Context, client kubernetes.Interface, _ string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().Namespaces().List(ctx, opts)
+	},
+	"nodes": func(ctx context.Context, client kubernetes.Interface, _ string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().Nodes().List(ctx, opts)
+	},
+	"deployments": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.AppsV1().Deployments(namespace).List(ctx, opts)
+	},
+	"statefulsets": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.AppsV1().StatefulSets(namespace).List(ctx, opts)
+	},
+	"daemonsets": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.AppsV1().DaemonSets(namespace).List(ctx, opts)
+	},
+	"cronjobs": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.BatchV1().CronJobs(namespace).List(ctx, opts)
+	},
+	"services": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().Services(namespace).List(ctx, opts)
+	},
+	"ingresses": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.NetworkingV1().Ingresses(namespace).List(ctx, opts)
+	},
+	"configmaps": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().ConfigMaps(namespace).List(ctx, opts)
+	},
+	"persistentvolumeclaims": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts)
+	},
+	"horizontalpodautoscalers": func(ctx context.Context, client kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+		return client.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, opts)
+	},
+}
+
+// SnapshotKinds returns the resource kinds that can be recorded in snapshots.
+func SnapshotKinds() []string {
+	kinds := make([]string, 0, len(snapshotListers))
+	for kind := range snapshotListers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// TakeSnapshot lists the metadata of kinds (DefaultSnapshotKinds when empty) in
+// namespaces, all namespaces when empty. When namespaces are selected, only those
+// Namespace objects are recorded and the other cluster-scoped kinds are skipped.
+// Only get/list permissions are needed; nothing is written to the cluster.
+func TakeSnapshot(ctx context.Context, client kubernetes.Interface, cluster string, kinds, namespaces []string) (*Snapshot, error) {
+	if len(kinds) == 0 {
+		kinds = DefaultSnapshotKinds
+	}
+	snapshot := &Snapshot{Cluster: cluster, TakenAt: time.Now().UTC(), Kinds: kinds, Namespaces: namespaces}
+	for _, kind := range kinds {
+		lister, ok := snapshotListers[kind]
+		if !ok {
+			return nil, fmt.Errorf("unsupported snapshot kind %q, supported: %s", kind, strings.Join(SnapshotKinds(), ", "))
+		}
+		scopes := []string{""}
+		switch {
+		case len(namespaces) == 0:
+		case kind == "namespaces":
+		case clusterScopedKinds[kind]:
+			continue
+		default:
+			scopes = namespaces
+		}
+		for _, namespace := range scopes {
+			list, err := lister(ctx, client, namespace, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("list %s: %w", kind, err)
+			}
+			items, err := apimeta.ExtractList(list)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range items {
+				object, err := snapshotObject(kind, item)
+				if err != nil {
+					return nil, err
+				}
+				if kind == "namespaces" && len(namespaces) > 0 && !slices.Contains(namespaces, object.Name) {
+					continue
+				}
+				snapshot.Objects = append(snapshot.Objects, object)
+			}
+		}
+	}
+	sort.Slice(snapshot.Objects, func(i, j int) bool {
+		return snapshot.Objects[i].key() < snapshot.Objects[j].key()
+	})
+	return snapshot, nil
+}
+
+// snapshotObject records the metadata of item and, for workloads and ConfigMaps, the
+// fields compared by DiffSnapshots.
+func snapshotObject(kind string, item runtime.Object) (SnapshotObject, error) {
+	meta, err := apimeta.Accessor(item)
+	if err != nil {
+		return SnapshotObject{}, err
+	}
+	object := SnapshotObject{
+		Kind:       kind,
+		Namespace:  meta.GetNamespace(),
+		Name:       meta.GetName(),
+		UID:        string(meta.GetUID()),
+		Generation: meta.GetGeneration(),
+		Labels:     meta.GetLabels(),
+		CreatedAt:  meta.GetCreationTimestamp().Time.UTC(),
+	}
+	for _, owner := range meta.GetOwnerReferences() {
+		if owner.Controller != nil && *owner.Controller {
+			object.Owner = owner.Kind + "/" + owner.Name
+		}
+	}
+
+	var podSpec *corev1.PodSpec
+	switch o := item.(type) {
+	case *appsv1.Deployment:
+		podSpec, object.Replicas = &o.Spec.Template.Spec, o.Spec.Replicas
+	case *appsv1.StatefulSet:
+		podSpec, object.Replicas = &o.Spec.Template.Spec, o.Spec.Replicas
+	case *appsv1.DaemonSet:
+		podSpec = &o.Spec.Template.Spec
+	case *batchv1.CronJob:
+		podSpec = &o.Spec.JobTemplate.Spec.Template.Spec
+	case *corev1.ConfigMap:
+		object.DataHash = configMapHash(o)
+	}
+	if podSpec != nil {
+		for _, c := range append(append([]corev1.Container(nil), podSpec.InitContainers...), podSpec.Containers...) {
+			object.Containers = append(object.Containers, SnapshotContainer{
+				Name:     c.Name,
+				Image:    c.Image,
+				Requests: resourceStrings(c.Resources.Requests),
+				Limits:   resourceStrings(c.Resources.Limits),
+			})
+		}
+	}
+	return object, nil
+}
+
+// configMapHash hashes the keys and values of the ConfigMap so data changes can be
+// detected without recording the data.
+func configMapHash(cm *corev1.ConfigMap) string {
+	h := sha256.New()
+	for _, key := range sortedKeys(cm.Data) {
+		fmt.Fprintf(h, "%s\x00%s\x00", key, cm.Data[key])
+	}
+	for _, key := range sortedKeys(cm.BinaryData) {
+		fmt.Fprintf(h, "%s\x00", key)
+		h.Write(cm.BinaryData[key])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func resourceStrings(list corev1.ResourceList) map[string]string {
+	if len(list) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(list))
+	for name, quantity := range list {
+		m[string(name)] = quantity.String()
+	}
+	return m
+}
+
+// key identifies the object within a snapshot.
+func (o SnapshotObject) key() string {
+	return o.Kind + "/" + o.Namespace + "/" + o.Name
+}
+
+// EncodeSnapshot serializes the snapshot as gzip-compressed JSON.
+func EncodeSnapshot(snapshot *Snapshot) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(snapshot); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeSnapshot parses a snapshot encoded by EncodeSnapshot.
+func DecodeSnapshot(data []byte) (*Snapshot, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var snapshot Snapshot
+	if err := json.NewDecoder(io.LimitReader(gz, 1<<30)).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Filter returns the objects of kind (any when empty) in namespace (any when empty)
+// whose name contains term.
+func (s *Snapshot) Filter(kind, namespace, term string) []SnapshotObject {
+	var objects []SnapshotObject
+	for _, o := range s.Objects {
+		if (kind == "" || o.Kind == kind) && (namespace == "" || o.Namespace == namespace) && strings.Contains(o.Name, term) {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
@@ -0,0 +1,197 @@
+package kubernetes
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// SnapshotDiff is what changed in a cluster between two snapshots.
+type SnapshotDiff struct {
+	Cluster string    `json:"cluster"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	// Kinds and Namespaces are the compared resource kinds and namespaces: the ones
+	// recorded in both snapshots, empty when not restricted.
+	Kinds      []string         `json:"kinds,omitempty"`
+	Namespaces []string         `json:"namespaces,omitempty"`
+	Added      []SnapshotObject `json:"added"`
+	Removed    []SnapshotObject `json:"removed"`
+	Changed    []ObjectChanges  `json:"changed"`
+}
+
+// ObjectChanges lists the changed fields of an object present in both snapshots.
+type ObjectChanges struct {
+	Kind      string        `json:"kind"`
+	Namespace string        `json:"namespace,omitempty"`
+	Name      string        `json:"name"`
+	Changes   []FieldChange `json:"changes"`
+}
+
+// FieldChange is one changed field, e.g. image[web], replicas, requests.cpu[web],
+// data or labels.app. From is empty for added fields and To for removed ones.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// Empty reports whether nothing changed.
+func (d *SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffSnapshots compares two snapshots of a cluster. Only the kinds and namespaces
+// recorded in both snapshots are compared, further restricted to namespaces when not
+// empty, so a snapshot of selected namespaces can be compared with a full one.
+// Snapshots taken before kinds and workload details were recorded are compared by
+// metadata (uid, labels, owner and generation) only.
+func DiffSnapshots(from, to *Snapshot, namespaces []string) *SnapshotDiff {
+	diff := &SnapshotDiff{
+		Cluster:    to.Cluster,
+		From:       from.TakenAt,
+		To:         to.TakenAt,
+		Kinds:      intersectScope(from.Kinds, to.Kinds),
+		Namespaces: intersectScope(intersectScope(from.Namespaces, to.Namespaces), namespaces),
+		Added:      []SnapshotObject{},
+		Removed:    []SnapshotObject{},
+		Changed:    []ObjectChanges{},
+	}
+	detailed := len(from.Kinds) > 0 && len(to.Kinds) > 0
+	restricted := len(from.Namespaces) > 0 || len(to.Namespaces) > 0 || len(namespaces) > 0
+	inScope := func(o SnapshotObject) bool {
+		if len(diff.Kinds) > 0 && !slices.Contains(diff.Kinds, o.Kind) {
+			return false
+		}
+		switch {
+		case !restricted:
+			return true
+		case o.Kind == "namespaces":
+			return slices.Contains(diff.Namespaces, o.Name)
+		case o.Namespace == "":
+			return false
+		default:
+			return slices.Contains(diff.Namespaces, o.Namespace)
+		}
+	}
+
+	before := map[string]SnapshotObject{}
+	for _, o := range from.Objects {
+		if inScope(o) {
+			before[o.key()] = o
+		}
+	}
+	seen := map[string]bool{}
+	for _, o := range to.Objects {
+		if !inScope(o) {
+			continue
+		}
+		seen[o.key()] = true
+		old, ok := before[o.key()]
+		if !ok {
+			diff.Added = append(diff.Added, o)
+			continue
+		}
+		if changes := compareSnapshotObjects(old, o, detailed); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ObjectChanges{Kind: o.Kind, Namespace: o.Namespace, Name: o.Name, Changes: changes})
+		}
+	}
+	for _, o := range from.Objects {
+		if inScope(o) && !seen[o.key()] {
+			diff.Removed = append(diff.Removed, o)
+		}
+	}
+	return diff
+}
+
+// intersectScope intersects two kind or namespace selections where empty means all.
+// The result is non-nil but empty when the selections do not overlap.
+func intersectScope(a, b []string) []string {
+	switch {
+	case len(a) == 0:
+		return b
+	case len(b) == 0:
+		return a
+	}
+	result := []string{}
+	for _, v := range a {
+		if slices.Contains(b, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// compareSnapshotObjects lists the changes from a to b, comparing replicas, containers
+// and data only when detailed. A generation change is only reported when no recorded
+// field changed, as the rest of the spec is not recorded.
+func compareSnapshotObjects(a, b SnapshotObject, detailed bool) []FieldChange {
+	var changes []FieldChange
+	change := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, FieldChange{Field: field, From: from, To: to})
+		}
+	}
+	change("uid", a.UID, b.UID) // deleted and recreated
+	if detailed {
+		change("replicas", formatReplicas(a.Replicas), formatReplicas(b.Replicas))
+		containers := map[string]SnapshotContainer{}
+		for _, c := range a.Containers {
+			containers[c.Name] = c
+		}
+		for _, c := range b.Containers {
+			old, ok := containers[c.Name]
+			delete(containers, c.Name)
+			if !ok {
+				change(fmt.Sprintf("container[%s]", c.Name), "", c.Image)
+				continue
+			}
+			change(fmt.Sprintf("image[%s]", c.Name), old.Image, c.Image)
+			changes = append(changes, compareMaps("requests.", fmt.Sprintf("[%s]", c.Name), old.Requests, c.Requests)...)
+			changes = append(changes, compareMaps("limits.", fmt.Sprintf("[%s]", c.Name), old.Limits, c.Limits)...)
+		}
+		for _, name := range sortedKeys(containers) {
+			change(fmt.Sprintf("container[%s]", name), containers[name].Image, "")
+		}
+		change("data", shortHash(a.DataHash), shortHash(b.DataHash))
+	}
+	changes = append(changes, compareMaps("labels.", "", a.Labels, b.Labels)...)
+	change("owner", a.Owner, b.Owner)
+	if len(changes) == 0 && a.Generation != b.Generation {
+		change("generation", fmt.Sprint(a.Generation), fmt.Sprint(b.Generation))
+	}
+	return changes
+}
+
+// compareMaps lists the changed keys as prefix+key+suffix, sorted by key.
+func compareMaps(prefix, suffix string, a, b map[string]string) []FieldChange {
+	keys := map[string]bool{}
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	var changes []FieldChange
+	for _, key := range sortedKeys(keys) {
+		if a[key] != b[key] {
+			changes = append(changes, FieldChange{Field: prefix + key + suffix, From: a[key], To: b[key]})
+		}
+	}
+	return changes
+}
+
+func formatReplicas(replicas *int32) string {
+	if replicas == nil {
+		return ""
+	}
+	return fmt.Sprint(*replicas)
+}
+
+// shortHash abbreviates a data hash for display.
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
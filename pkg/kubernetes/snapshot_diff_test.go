@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	one, three := int32(1), int32(3)
+	from := &Snapshot{
+		Cluster: "prod",
+		TakenAt: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC),
+		Kinds:   []string{"namespaces", "nodes", "deployments", "configmaps"},
+		Objects: []SnapshotObject{
+			{Kind: "configmaps", Namespace: "shop", Name: "web-config", UID: "c1", DataHash: "aaaaaaaaaaaaaaaa"},
+			{Kind: "deployments", Namespace: "billing", Name: "rates", UID: "d0"},
+			{Kind: "deployments", Namespace: "shop", Name: "cart", UID: "d2"},
+			{Kind: "deployments", Namespace: "shop", Name: "web", UID: "d1", Generation: 4, Replicas: &one,
+				Containers: []SnapshotContainer{{Name: "web", Image: "shop/web:1.2", Requests: map[string]string{"cpu": "250m"}}}},
+			{Kind: "nodes", Name: "node-1", UID: "n1"},
+		},
+	}
+	to := &Snapshot{
+		Cluster:    "prod",
+		TakenAt:    time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+		Kinds:      []string{"namespaces", "nodes", "deployments", "configmaps"},
+		Namespaces: []string{"shop"},
+		Objects: []SnapshotObject{
+			{Kind: "configmaps", Namespace: "shop", Name: "web-config", UID: "c1", DataHash: "bbbbbbbbbbbbbbbb"},
+			{Kind: "deployments", Namespace: "shop", Name: "search", UID: "d3"},
+			{Kind: "deployments", Namespace: "shop", Name: "web", UID: "d1", Generation: 6, Replicas: &three,
+				Containers: []SnapshotContainer{{Name: "web", Image: "shop/web:1.3", Requests: map[string]string{"cpu": "500m"}}}},
+		},
+	}
+
+	// billing and the node are outside the namespaces of the second snapshot
+	diff := DiffSnapshots(from, to, nil)
+	if len(diff.Namespaces) != 1 || len(diff.Added) != 1 || diff.Added[0].Name != "search" ||
+		len(diff.Removed) != 1 || diff.Removed[0].Name != "cart" || len(diff.Changed) != 2 {
+		t.Fatalf("unexpected diff: %+v", diff)
+	}
+	if config := diff.Changed[0]; config.Name != "web-config" || len(config.Changes) != 1 ||
+		config.Changes[0] != (FieldChange{Field: "data", From: "aaaaaaaaaaaa", To: "bbbbbbbbbbbb"}) {
+		t.Errorf("unexpected configmap changes: %+v", config)
+	}
+	want := []FieldChange{
+		{Field: "replicas", From: "1", To: "3"},
+		{Field: "image[web]", From: "shop/web:1.2", To: "shop/web:1.3"},
+		{Field: "requests.cpu[web]", From: "250m", To: "500m"},
+	}
+	if web := diff.Changed[1]; len(web.Changes) != len(want) {
+		t.Errorf("unexpected deployment changes: %+v", web)
+	} else {
+		for i := range want {
+			if web.Changes[i] != want[i] {
+				t.Errorf("change %d = %+v, want %+v", i, web.Changes[i], want[i])
+			}
+		}
+	}
+
+	if diff := DiffSnapshots(from, from, nil); !diff.Empty() {
+		t.Errorf("expected no changes between identical snapshots, got %+v", diff)
+	}
+
+	// snapshots taken before workload details were recorded are compared by metadata only
+	legacy := *from
+	legacy.Kinds = nil
+	legacy.Objects = []SnapshotObject{{Kind: "deployments", Namespace: "shop", Name: "web", UID: "d1", Generation: 4}}
+	diff = DiffSnapshots(&legacy, to, []string{"shop"})
+	if len(diff.Changed) != 1 || len(diff.Changed[0].Changes) != 1 || diff.Changed[0].Changes[0].Field != "generation" {
+		t.Errorf("unexpected diff with a legacy snapshot: %+v", diff.Changed)
+	}
+}
@@ -0,0 +1,79 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTakeSnapshot(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Labels: map[string]string{"app": "web"},
+			Annotations: map[string]string{"kubectl.kubernetes.io/last-applied-configuration": "{}"}}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "token", Namespace: "shop"}, Data: map[string][]byte{"k": []byte("v")}},
+	)
+	snapshot, err := TakeSnapshot(context.Background(), client, "prod", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Objects) != 2 || snapshot.Objects[0].Kind != "deployments" || snapshot.Objects[1].Kind != "namespaces" {
+		t.Fatalf("expected the deployment and namespace without secrets, got %+v", snapshot.Objects)
+	}
+
+	data, err := EncodeSnapshot(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeSnapshot(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := decoded.Filter("deployments", "shop", "we"); len(found) != 1 || found[0].Labels["app"] != "web" || decoded.Cluster != "prod" {
+		t.Errorf("unexpected decoded snapshot: %+v", decoded)
+	}
+
+	if _, err := TakeSnapshot(context.Background(), client, "prod", []string{"secrets"}, nil); err == nil {
+		t.Error("expected error for unsupported kind")
+	}
+}
+
+func TestTakeSnapshotWorkloads(t *testing.T) {
+	replicas := int32(3)
+	client := fake.NewSimpleClientset(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "billing"}},
+		&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"}, Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "web", Image: "shop/web:1.2",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}},
+			}}}},
+		}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "web-config", Namespace: "shop"}, Data: map[string]string{"mode": "fast"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "rates", Namespace: "billing"}},
+	)
+	snapshot, err := TakeSnapshot(context.Background(), client, "prod", []string{"namespaces", "nodes", "deployments", "configmaps"}, []string{"shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Objects) != 3 || len(snapshot.Namespaces) != 1 || len(snapshot.Kinds) != 4 {
+		t.Fatalf("expected the shop namespace, deployment and configmap only, got %+v", snapshot)
+	}
+
+	web := snapshot.Filter("deployments", "shop", "web")
+	if len(web) != 1 || web[0].Replicas == nil || *web[0].Replicas != 3 || len(web[0].Containers) != 1 ||
+		web[0].Containers[0].Image != "shop/web:1.2" || web[0].Containers[0].Requests["cpu"] != "250m" {
+		t.Errorf("unexpected deployment: %+v", web)
+	}
+	config := snapshot.Filter("configmaps", "shop", "web-config")
+	if len(config) != 1 || len(config[0].DataHash) != 64 {
+		t.Errorf("expected a data hash, got %+v", config)
+	}
+}
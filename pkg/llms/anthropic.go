@@ -0,0 +1,108 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com"
+	anthropicVersion          = "2023-06-01"
+	defaultAnthropicMaxTokens = 4096
+)
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float32            `json:"temperature"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// newAnthropicProvider 创建 Anthropic Claude 客户端（Messages API）
+func newAnthropicProvider(apiKey, baseURL string) (Provider, error) {
+	if apiKey == "" && !cassetteReplay() {
+		return nil, fmt.Errorf("anthropic API key is not set")
+	}
+	if baseURL == "" {
+		baseURL = defaultAnthropicBaseURL
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/v1/messages"
+	headers := map[string]string{
+		"x-api-key":         apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	return newHTTPProvider(ProviderAnthropic, func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error) {
+		system, turns := splitMessages(req.Messages)
+		// Messages API 要求第一条消息为用户消息
+		if len(turns) > 0 && turns[0].Role != openai.ChatMessageRoleUser {
+			turns = append([]chatTurn{{Role: openai.ChatMessageRoleUser, Content: "继续"}}, turns...)
+		}
+		body := anthropicRequest{
+			Model:       req.Model,
+			System:      system,
+			MaxTokens:   req.MaxTokens,
+			Temperature: 0,
+		}
+		if body.MaxTokens <= 0 {
+			body.MaxTokens = defaultAnthropicMaxTokens
+		}
+		for _, turn := range turns {
+			body.Messages = append(body.Messages, anthropicMessage{Role: turn.Role, Content: turn.Content})
+		}
+
+		var resp anthropicResponse
+		if err := postJSON(ctx, ProviderAnthropic, url, headers, body, &resp); err != nil {
+			return "", openai.Usage{}, err
+		}
+		var text strings.Builder
+		for _, block := range resp.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+		if text.Len() == 0 {
+			return "", openai.Usage{}, fmt.Errorf("anthropic returned no text content (stop_reason: %s)", resp.StopReason)
+		}
+		usage := openai.Usage{
+			PromptTokens:     resp.Usage.InputTokens,
+			CompletionTokens: resp.Usage.OutputTokens,
+			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
+		}
+		return text.String(), usage, nil
+	}), nil
+}
@@ -0,0 +1,265 @@
+package llms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultObservationTokens 单次工具观察结果交给 LLM 的默认 token 上限
+const defaultObservationTokens = 1024
+
+// encodings 按模型缓存的 tiktoken 编码，加载失败时缓存 nil，避免每次调用都重新下载词表
+var encodings sync.Map
+
+// encodingFor 返回模型的 tiktoken 编码，非 OpenAI 模型（qwen、claude、gemini 等）使用 cl100k_base 近似
+func encodingFor(model string) *tiktoken.Tiktoken {
+	if cached, ok := encodings.Load(model); ok {
+		return cached.(*tiktoken.Tiktoken)
+	}
+	tkm, err := tiktoken.EncodingForModel(model)
+	if err != nil {
+		tkm, err = tiktoken.GetEncoding("cl100k_base")
+	}
+	if err != nil {
+		tkm = nil
+	}
+	encodings.Store(model, tkm)
+	return tkm
+}
+
+// CountTokens 使用 tiktoken 计算文本的 token 数，无法加载词表时按字符数估算
+func CountTokens(text, model string) int {
+	if tkm := encodingFor(model); tkm != nil {
+		return len(tkm.Encode(text, nil, nil))
+	}
+	// 中英文混合文本大约每 2 个字符一个 token，偏保守估算
+	return (utf8.RuneCountInString(text) + 1) / 2
+}
+
+// CountMessageTokens 计算消息作为提示发送时的 token 数，包括每条消息的格式开销
+func CountMessageTokens(messages []openai.ChatCompletionMessage, model string) int {
+	total := 3 // 每次回复以 <|start|>assistant<|message|> 开头
+	for _, message := range messages {
+		total += 3 + CountTokens(message.Content, model) + CountTokens(message.Role, model)
+		if message.Name != "" {
+			total += 1 + CountTokens(message.Name, model)
+		}
+		for _, call := range message.ToolCalls {
+			total += CountTokens(call.Function.Name, model) + CountTokens(call.Function.Arguments, model)
+		}
+	}
+	return total
+}
+
+// ObservationTokens 单次工具观察结果的 token 上限，配置项 llm.budget.observation_tokens
+func ObservationTokens() int {
+	if n := utils.GetConfig().GetInt("llm.budget.observation_tokens"); n > 0 {
+		return n
+	}
+	return defaultObservationTokens
+}
+
+// IterationUsage 一次 LLM 调用的 token 用量、耗时和结束原因
+type IterationUsage struct {
+	Iteration        int       `json:"iteration"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	FinishReason     string    `json:"finish_reason,omitempty"` // stop、length、tool_calls 等，服务商未返回时为空
+	StartedAt        time.Time `json:"started_at"`
+}
+
+// TokenUsage 一次请求的 token 用量
+type TokenUsage struct {
+	PromptTokens     int              `json:"prompt_tokens"`
+	CompletionTokens int              `json:"completion_tokens"`
+	TotalTokens      int              `json:"total_tokens"`
+	Iterations       []IterationUsage `json:"iterations,omitempty"`
+	// RequestLimit、UserDailyLimit 为 0 表示不限制
+	RequestLimit   int `json:"request_limit,omitempty"`
+	UserDailyUsed  int `json:"user_daily_used,omitempty"`
+	UserDailyLimit int `json:"user_daily_limit,omitempty"`
+}
+
+// BudgetExceededError 请求或用户的 token 预算已用尽
+type BudgetExceededError struct {
+	Scope   string // request 或 user
+	User    string
+	Used    int
+	Needed  int
+	Limit   int
+	ResetAt time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	if e.Scope == "user" {
+		return fmt.Sprintf("用户 %s 今日的 token 预算已用尽（已用 %d，本次需要 %d，上限 %d），%s 重置",
+			e.User, e.Used, e.Needed, e.Limit, e.ResetAt.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("本次请求的 token 预算已用尽（已用 %d，下一轮需要 %d，上限 %d），请缩小问题范围后重试",
+		e.Used, e.Needed, e.Limit)
+}
+
+func (e *BudgetExceededError) ErrorCode() string { return ErrCodeBudgetExceeded }
+
+// TokenBudget 单次请求的 token 预算，记录每轮 LLM 调用的用量
+// 配置项 llm.budget.per_request 限制单次请求，llm.budget.per_user_daily 限制用户每天的总量，0 表示不限制
+type TokenBudget struct {
+	mu      sync.Mutex
+	user    string
+	limit   int
+	usage   TokenUsage
+	manager *BudgetManager
+}
+
+type tokenBudgetKey struct{}
+
+// NewTokenBudget 创建请求的 token 预算，user 为空（如命令行调用）时不受每日预算限制
+func NewTokenBudget(user string) *TokenBudget {
+	return &TokenBudget{
+		user:    user,
+		limit:   utils.GetConfig().GetInt("llm.budget.per_request"),
+		manager: GetBudgetManager(),
+	}
+}
+
+// WithTokenBudget 为请求附加新的 token 预算
+func WithTokenBudget(ctx context.Context, user string) (context.Context, *TokenBudget) {
+	budget := NewTokenBudget(user)
+	return context.WithValue(ctx, tokenBudgetKey{}, budget), budget
+}
+
+// TokenBudgetFromContext 获取请求的 token 预算，未附加时返回 nil
+func TokenBudgetFromContext(ctx context.Context) *TokenBudget {
+	budget, _ := ctx.Value(tokenBudgetKey{}).(*TokenBudget)
+	return budget
+}
+
+// Check 检查发送 promptTokens 个 token 的提示是否超出请求或用户的预算
+func (b *TokenBudget) Check(promptTokens int) error {
+	b.mu.Lock()
+	used := b.usage.TotalTokens
+	b.mu.Unlock()
+
+	if b.limit > 0 && used+promptTokens > b.limit {
+		return &BudgetExceededError{Scope: "request", Used: used, Needed: promptTokens, Limit: b.limit}
+	}
+	return b.manager.Check(b.user, promptTokens)
+}
+
+// Record 记录一轮 LLM 调用的用量，同时计入用户当天的用量
+func (b *TokenBudget) Record(model string, promptTokens, completionTokens int) {
+	b.RecordCall(IterationUsage{Model: model, PromptTokens: promptTokens, CompletionTokens: completionTokens})
+}
+
+// RecordCall 记录一轮 LLM 调用的用量、耗时和结束原因，Iteration 按调用顺序编号
+func (b *TokenBudget) RecordCall(call IterationUsage) {
+	b.mu.Lock()
+	b.usage.PromptTokens += call.PromptTokens
+	b.usage.CompletionTokens += call.CompletionTokens
+	b.usage.TotalTokens += call.PromptTokens + call.CompletionTokens
+	call.Iteration = len(b.usage.Iterations) + 1
+	b.usage.Iterations = append(b.usage.Iterations, call)
+	b.mu.Unlock()
+
+	b.manager.Add(b.user, call.PromptTokens+call.CompletionTokens)
+}
+
+// Usage 返回本次请求的用量及预算
+func (b *TokenBudget) Usage() TokenUsage {
+	b.mu.Lock()
+	usage := b.usage
+	usage.Iterations = append([]IterationUsage(nil), b.usage.Iterations...)
+	b.mu.Unlock()
+
+	usage.RequestLimit = b.limit
+	if b.user != "" {
+		usage.UserDailyUsed, usage.UserDailyLimit = b.manager.Used(b.user), b.manager.limit()
+	}
+	return usage
+}
+
+// BudgetManager 按用户统计每天消耗的 token 数
+type BudgetManager struct {
+	mu   sync.Mutex
+	day  string
+	used map[string]int
+	now  func() time.Time
+}
+
+var (
+	budgetManager     *BudgetManager
+	budgetManagerOnce sync.Once
+)
+
+// GetBudgetManager 获取全局 token 预算管理器
+func GetBudgetManager() *BudgetManager {
+	budgetManagerOnce.Do(func() {
+		budgetManager = NewBudgetManager()
+	})
+	return budgetManager
+}
+
+// NewBudgetManager 创建 token 预算管理器
+func NewBudgetManager() *BudgetManager {
+	return &BudgetManager{used: map[string]int{}, now: time.Now}
+}
+
+// Check 检查用户当天的用量加上 tokens 是否超出 llm.budget.per_user_daily
+func (m *BudgetManager) Check(user string, tokens int) error {
+	limit := m.limit()
+	if user == "" || limit <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotate()
+	if used := m.used[user]; used+tokens > limit {
+		return &BudgetExceededError{Scope: "user", User: user, Used: used, Needed: tokens, Limit: limit, ResetAt: m.resetAt()}
+	}
+	return nil
+}
+
+// Add 累计用户当天的用量
+func (m *BudgetManager) Add(user string, tokens int) {
+	if user == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotate()
+	m.used[user] += tokens
+}
+
+// Used 返回用户当天已消耗的 token 数
+func (m *BudgetManager) Used(user string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotate()
+	return m.used[user]
+}
+
+func (m *BudgetManager) limit() int {
+	return utils.GetConfig().GetInt("llm.budget.per_user_daily")
+}
+
+// rotate 跨天时清空用量，调用方需持有锁
+func (m *BudgetManager) rotate() {
+	if day := m.now().Format("2006-01-02"); day != m.day {
+		m.day = day
+		m.used = map[string]int{}
+	}
+}
+
+func (m *BudgetManager) resetAt() time.Time {
+	now := m.now()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
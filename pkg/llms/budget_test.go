@@ -0,0 +1,67 @@
+package llms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestTokenBudget(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("llm.budget.per_request", 1000)
+	config.Set("llm.budget.per_user_daily", 1500)
+	defer config.Set("llm.budget.per_request", 0)
+	defer config.Set("llm.budget.per_user_daily", 0)
+
+	manager := NewBudgetManager()
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+
+	budget := NewTokenBudget("alice")
+	budget.manager = manager
+	if err := budget.Check(600); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	budget.Record("gpt-4o", 600, 100)
+	var exceeded *BudgetExceededError
+	if err := budget.Check(400); !errors.As(err, &exceeded) || exceeded.Scope != "request" {
+		t.Errorf("expected the request budget to be exceeded, got %v", err)
+	}
+
+	// 同一用户的下一个请求受每日预算限制
+	next := NewTokenBudget("alice")
+	next.manager = manager
+	next.Record("gpt-4o", 700, 50)
+	if err := next.Check(100); !errors.As(err, &exceeded) || exceeded.Scope != "user" || exceeded.Used != 1450 {
+		t.Errorf("expected the daily budget to be exceeded, got %v", err)
+	}
+	if usage := next.Usage(); usage.TotalTokens != 750 || len(usage.Iterations) != 1 || usage.UserDailyUsed != 1450 {
+		t.Errorf("Usage() = %+v", usage)
+	}
+
+	next.RecordCall(IterationUsage{Model: "gpt-4o", PromptTokens: 20, CompletionTokens: 5, LatencyMs: 800, FinishReason: "length"})
+	if calls := next.Usage().Iterations; len(calls) != 2 || calls[1].Iteration != 2 || calls[1].LatencyMs != 800 || calls[1].FinishReason != "length" {
+		t.Errorf("Iterations = %+v", calls)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if err := manager.Check("alice", 100); err != nil {
+		t.Errorf("expected the daily budget to reset, got %v", err)
+	}
+}
+
+func TestTokenBudgetFromContext(t *testing.T) {
+	if TokenBudgetFromContext(context.Background()) != nil {
+		t.Error("expected no budget on a bare context")
+	}
+	ctx, budget := WithTokenBudget(context.Background(), "")
+	if TokenBudgetFromContext(ctx) != budget {
+		t.Error("expected the attached budget")
+	}
+	if CountMessageTokens(nil, "gpt-4o") != 3 || CountTokens("kubectl get pods", "qwen-plus") <= 0 {
+		t.Error("unexpected token counts")
+	}
+}
@@ -0,0 +1,160 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// 录制/回放模式，通过 llm.cassette.mode 配置
+const (
+	CassetteOff    = "off"    // 不录制也不回放
+	CassetteRecord = "record" // 总是请求 LLM 并覆盖录制
+	CassetteReplay = "replay" // 只回放，未录制的请求返回 ErrCassetteMiss
+	CassetteAuto   = "auto"   // 已录制则回放，否则请求 LLM 并录制
+)
+
+// 默认的录制目录
+const defaultCassetteDir = "testdata/cassettes"
+
+// ErrCassetteMiss 回放模式下请求未录制
+var ErrCassetteMiss = errors.New("cassette miss")
+
+// Cassette 将 LLM 请求/响应按请求哈希保存到磁盘，用于测试和演示中的确定性回放
+// 回放模式下不需要 API Key，便于离线开发 handler 和 assistant
+type Cassette struct {
+	Mode string
+	Dir  string
+	// IgnoreSystem 计算哈希时忽略 system 消息，系统提示包含日期等易变内容时使用
+	IgnoreSystem bool
+}
+
+// cassetteEntry 录制文件内容
+type cassetteEntry struct {
+	Kind       string          `json:"kind"`
+	Key        string          `json:"key"`
+	RecordedAt time.Time       `json:"recorded_at"`
+	Request    json.RawMessage `json:"request"`
+	Response   json.RawMessage `json:"response"`
+}
+
+// CassetteFromConfig 根据配置创建录制器，未启用时返回 nil
+// 配置项：llm.cassette.mode、llm.cassette.dir、llm.cassette.ignore_system
+func CassetteFromConfig() *Cassette {
+	config := utils.GetConfig()
+	mode := config.GetString("llm.cassette.mode")
+	if mode == "" || mode == CassetteOff {
+		return nil
+	}
+	dir := config.GetString("llm.cassette.dir")
+	if dir == "" {
+		dir = defaultCassetteDir
+	}
+	return &Cassette{
+		Mode:         mode,
+		Dir:          dir,
+		IgnoreSystem: config.GetBool("llm.cassette.ignore_system"),
+	}
+}
+
+// Do 回放已录制的响应，或调用 call 获取响应并按模式录制
+// kind 区分请求类型（chat、embeddings），resp 必须为指针
+func (c *Cassette) Do(kind string, req, resp interface{}, call func() error) error {
+	key, err := c.Key(kind, req)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(c.Dir, kind, key+".json")
+
+	if c.Mode == CassetteReplay || c.Mode == CassetteAuto {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var entry cassetteEntry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return fmt.Errorf("解析录制文件 %s 失败: %v", path, err)
+			}
+			return json.Unmarshal(entry.Response, resp)
+		case !os.IsNotExist(err):
+			return err
+		case c.Mode == CassetteReplay:
+			return fmt.Errorf("%w: %s", ErrCassetteMiss, path)
+		}
+	}
+
+	if err := call(); err != nil {
+		return err
+	}
+	// 录制失败不影响本次请求
+	if err := c.save(path, kind, key, req, resp); err != nil {
+		utils.Warn("保存 LLM 录制失败", zap.String("path", path), zap.Error(err))
+	}
+	return nil
+}
+
+// Key 计算请求的哈希
+func (c *Cassette) Key(kind string, req interface{}) (string, error) {
+	if chat, ok := req.(*openai.ChatCompletionRequest); ok && c.IgnoreSystem {
+		normalized := *chat
+		normalized.Messages = nil
+		for _, message := range chat.Messages {
+			if message.Role != openai.ChatMessageRoleSystem {
+				normalized.Messages = append(normalized.Messages, message)
+			}
+		}
+		req = &normalized
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(kind+"\n"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (c *Cassette) save(path, kind, key string, req, resp interface{}) error {
+	request, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	response, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cassetteEntry{
+		Kind:       kind,
+		Key:        key,
+		RecordedAt: time.Now(),
+		Request:    request,
+		Response:   response,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建录制目录失败: %v", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,75 @@
+package llms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	req := &openai.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "list pods"}},
+	}
+
+	calls := 0
+	call := func(resp *openai.ChatCompletionResponse) func() error {
+		return func() error {
+			calls++
+			resp.Choices = []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "recorded"}}}
+			return nil
+		}
+	}
+
+	recorder := &Cassette{Mode: CassetteAuto, Dir: dir}
+	var first openai.ChatCompletionResponse
+	if err := recorder.Do("chat", req, &first, call(&first)); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	replayer := &Cassette{Mode: CassetteReplay, Dir: dir}
+	var replayed openai.ChatCompletionResponse
+	if err := replayer.Do("chat", req, &replayed, call(&replayed)); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 upstream call, got %d", calls)
+	}
+	if got := replayed.Choices[0].Message.Content; got != "recorded" {
+		t.Errorf("replayed content = %q, want recorded", got)
+	}
+
+	other := &openai.ChatCompletionRequest{Model: "gpt-4o"}
+	var missed openai.ChatCompletionResponse
+	if err := replayer.Do("chat", other, &missed, call(&missed)); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("expected ErrCassetteMiss, got %v", err)
+	}
+}
+
+func TestCassetteKeyIgnoreSystem(t *testing.T) {
+	request := func(system string) *openai.ChatCompletionRequest {
+		return &openai.ChatCompletionRequest{
+			Model: "gpt-4o",
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleSystem, Content: system},
+				{Role: openai.ChatMessageRoleUser, Content: "list pods"},
+			},
+		}
+	}
+
+	c := &Cassette{}
+	a, _ := c.Key("chat", request("今天是 2025-03-01"))
+	b, _ := c.Key("chat", request("今天是 2025-03-02"))
+	if a == b {
+		t.Error("expected different keys when system prompts differ")
+	}
+
+	c.IgnoreSystem = true
+	a, _ = c.Key("chat", request("今天是 2025-03-01"))
+	b, _ = c.Key("chat", request("今天是 2025-03-02"))
+	if a != b {
+		t.Error("expected equal keys when ignoring system prompts")
+	}
+}
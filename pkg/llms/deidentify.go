@@ -0,0 +1,287 @@
+package llms
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxPseudonyms 假名映射表的容量，超出后淘汰最久未使用的假名，正在进行的对话中的假名不受影响
+var maxPseudonyms = 100000
+
+// defaultDeidentifyRanges 未配置 llm.deidentify.ip_ranges 时视为内部地址的网段
+var defaultDeidentifyRanges = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10"}
+
+var (
+	ipv4Regexp      = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	pseudonymRegexp = regexp.MustCompile(`\b[a-z][a-z0-9_]*-[0-9a-f]{8}\b`)
+	labelRegexp     = regexp.MustCompile(`[^a-z0-9_]+`)
+)
+
+// pseudonyms 假名到原值的映射，同一原值总是得到同一假名，因此多轮迭代和多个请求可以共用
+var pseudonyms = struct {
+	mu        sync.Mutex
+	order     *list.List // 最近使用的在前
+	originals map[string]*list.Element
+	secret    []byte
+	once      sync.Once
+}{order: list.New(), originals: map[string]*list.Element{}}
+
+type pseudonymEntry struct {
+	pseudonym string
+	original  string
+}
+
+// deidentifierCache 根据当前配置创建的 Deidentifier，配置变化（热加载）时才重新编译正则
+var deidentifierCache struct {
+	sync.Mutex
+	signature string
+	d         *Deidentifier
+}
+
+// pseudonymSecret 计算假名的密钥，配置项 llm.deidentify.secret，为空时每次启动随机生成
+// 使用录制/回放时需要配置固定的密钥，否则请求哈希每次启动都不同
+func pseudonymSecret() []byte {
+	if secret := utils.GetConfig().GetString("llm.deidentify.secret"); secret != "" {
+		return []byte(secret)
+	}
+	pseudonyms.once.Do(func() {
+		pseudonyms.secret = make([]byte, 32)
+		rand.Read(pseudonyms.secret)
+	})
+	return pseudonyms.secret
+}
+
+// pseudonymize 返回原值的假名（<label>-<8 位十六进制>）并记录映射
+func pseudonymize(label, value string, secret []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(label + "\x00" + value))
+	pseudonym := label + "-" + hex.EncodeToString(mac.Sum(nil)[:4])
+
+	pseudonyms.mu.Lock()
+	defer pseudonyms.mu.Unlock()
+	if elem, ok := pseudonyms.originals[pseudonym]; ok {
+		elem.Value.(*pseudonymEntry).original = value
+		pseudonyms.order.MoveToFront(elem)
+		return pseudonym
+	}
+	pseudonyms.originals[pseudonym] = pseudonyms.order.PushFront(&pseudonymEntry{pseudonym: pseudonym, original: value})
+	for pseudonyms.order.Len() > maxPseudonyms {
+		oldest := pseudonyms.order.Back()
+		pseudonyms.order.Remove(oldest)
+		delete(pseudonyms.originals, oldest.Value.(*pseudonymEntry).pseudonym)
+	}
+	return pseudonym
+}
+
+// Reidentify 将文本中的假名还原为原值，未记录的假名保持不变
+func Reidentify(text string) string {
+	if !strings.Contains(text, "-") {
+		return text
+	}
+	pseudonyms.mu.Lock()
+	defer pseudonyms.mu.Unlock()
+	return pseudonymRegexp.ReplaceAllStringFunc(text, func(token string) string {
+		if elem, ok := pseudonyms.originals[token]; ok {
+			pseudonyms.order.MoveToFront(elem)
+			return elem.Value.(*pseudonymEntry).original
+		}
+		return token
+	})
+}
+
+// identifierPattern 客户标识等自定义识别规则
+type identifierPattern struct {
+	label string
+	re    *regexp.Regexp
+}
+
+// Deidentifier 识别文本中的内部主机名、内网 IP 和客户标识，替换为可还原的假名
+// 配置项：
+//   - llm.deidentify.internal_domains: 内部域名后缀，例如 corp.example.com，匹配的主机名替换为 host-xxxxxxxx
+//   - llm.deidentify.ip_ranges: 视为内部地址的网段，默认 RFC 1918 私有网段和 100.64.0.0/10
+//   - llm.deidentify.patterns: 名称到正则的映射，例如 customer: "CUST-[0-9]{6}"，匹配的内容替换为 <名称>-xxxxxxxx
+//   - llm.deidentify.secret: 计算假名的密钥
+type Deidentifier struct {
+	hosts    *regexp.Regexp
+	ranges   []*net.IPNet
+	patterns []identifierPattern
+	secret   []byte
+}
+
+// NewDeidentifier 根据配置创建 Deidentifier
+func NewDeidentifier() (*Deidentifier, error) {
+	config := utils.GetConfig()
+	d := &Deidentifier{secret: pseudonymSecret()}
+
+	var suffixes []string
+	for _, domain := range config.GetStringSlice("llm.deidentify.internal_domains") {
+		if domain = strings.Trim(strings.TrimSpace(domain), "."); domain != "" {
+			suffixes = append(suffixes, regexp.QuoteMeta(domain))
+		}
+	}
+	if len(suffixes) > 0 {
+		d.hosts = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)*(?:` + strings.Join(suffixes, "|") + `)\b`)
+	}
+
+	ranges := config.GetStringSlice("llm.deidentify.ip_ranges")
+	if len(ranges) == 0 {
+		ranges = defaultDeidentifyRanges
+	}
+	for _, cidr := range ranges {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm.deidentify.ip_ranges entry %q: %v", cidr, err)
+		}
+		d.ranges = append(d.ranges, network)
+	}
+
+	patterns := config.GetStringMapString("llm.deidentify.patterns")
+	labels := make([]string, 0, len(patterns))
+	for label := range patterns {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		re, err := regexp.Compile(patterns[label])
+		if err != nil {
+			return nil, fmt.Errorf("invalid llm.deidentify.patterns.%s: %v", label, err)
+		}
+		// 假名的名称部分只保留小写字母、数字和下划线，保证能被 Reidentify 识别
+		name := strings.Trim(labelRegexp.ReplaceAllString(strings.ToLower(label), "_"), "_")
+		if name == "" || name[0] < 'a' || name[0] > 'z' {
+			name = "id_" + name
+		}
+		d.patterns = append(d.patterns, identifierPattern{label: name, re: re})
+	}
+	return d, nil
+}
+
+// currentDeidentifier 返回根据当前配置创建的 Deidentifier，配置未变化时复用
+func currentDeidentifier() (*Deidentifier, error) {
+	config := utils.GetConfig()
+	signature := fmt.Sprint(
+		config.GetStringSlice("llm.deidentify.internal_domains"),
+		config.GetStringSlice("llm.deidentify.ip_ranges"),
+		config.GetStringMapString("llm.deidentify.patterns"),
+		config.GetString("llm.deidentify.secret"),
+	)
+
+	deidentifierCache.Lock()
+	defer deidentifierCache.Unlock()
+	if deidentifierCache.d != nil && deidentifierCache.signature == signature {
+		return deidentifierCache.d, nil
+	}
+	d, err := NewDeidentifier()
+	if err != nil {
+		return nil, err
+	}
+	deidentifierCache.signature, deidentifierCache.d = signature, d
+	return d, nil
+}
+
+// Deidentify 替换文本中的客户标识、内部主机名和内网 IP
+func (d *Deidentifier) Deidentify(text string) string {
+	for _, pattern := range d.patterns {
+		text = pattern.re.ReplaceAllStringFunc(text, func(match string) string {
+			return pseudonymize(pattern.label, match, d.secret)
+		})
+	}
+	if d.hosts != nil {
+		text = d.hosts.ReplaceAllStringFunc(text, func(match string) string {
+			return pseudonymize("host", strings.ToLower(match), d.secret)
+		})
+	}
+	return ipv4Regexp.ReplaceAllStringFunc(text, func(match string) string {
+		if ip := net.ParseIP(match); ip != nil && d.internal(ip) {
+			return pseudonymize("ip", match, d.secret)
+		}
+		return match
+	})
+}
+
+func (d *Deidentifier) internal(ip net.IP) bool {
+	for _, network := range d.ranges {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// deidentifyHook 发送前将消息中的内部主机名、内网 IP 和客户标识替换为假名，收到响应后还原，
+// 模型输出的命令和最终回答中使用原值，第三方 LLM 只看到假名
+type deidentifyHook struct{}
+
+func (deidentifyHook) Name() string { return "deidentify" }
+
+func (deidentifyHook) BeforeChat(req *openai.ChatCompletionRequest) error {
+	d, err := currentDeidentifier()
+	if err != nil {
+		return err
+	}
+	// 复制消息，避免修改调用方的对话历史
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		message.Content = d.Deidentify(message.Content)
+		if len(message.MultiContent) > 0 {
+			parts := append([]openai.ChatMessagePart(nil), message.MultiContent...)
+			for j := range parts {
+				parts[j].Text = d.Deidentify(parts[j].Text)
+			}
+			message.MultiContent = parts
+		}
+		if len(message.ToolCalls) > 0 {
+			calls := append([]openai.ToolCall(nil), message.ToolCalls...)
+			for j := range calls {
+				calls[j].Function.Arguments = d.Deidentify(calls[j].Function.Arguments)
+			}
+			message.ToolCalls = calls
+		}
+		messages[i] = message
+	}
+	req.Messages = messages
+	return nil
+}
+
+func (deidentifyHook) AfterChat(req *openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error {
+	for i := range resp.Choices {
+		message := &resp.Choices[i].Message
+		message.Content = Reidentify(message.Content)
+		for j := range message.ToolCalls {
+			message.ToolCalls[j].Function.Arguments = Reidentify(message.ToolCalls[j].Function.Arguments)
+		}
+	}
+	return nil
+}
+
+// deidentifyTexts 启用了 deidentify 钩子时替换待向量化文本中的内部标识，向量化请求同样发往第三方服务
+// 同一原值的假名相同，替换后的文本仍可用于相似度检索
+func deidentifyTexts(hooks []ChatHook, texts []string) ([]string, error) {
+	for _, hook := range hooks {
+		if _, ok := hook.(deidentifyHook); !ok {
+			continue
+		}
+		d, err := currentDeidentifier()
+		if err != nil {
+			return nil, err
+		}
+		result := make([]string, len(texts))
+		for i, text := range texts {
+			result[i] = d.Deidentify(text)
+		}
+		return result, nil
+	}
+	return texts, nil
+}
@@ -0,0 +1,88 @@
+package llms
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestDeidentifyHook(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("llm.hooks", []string{"deidentify"})
+	config.Set("llm.deidentify.internal_domains", []string{"corp.example.com"})
+	config.Set("llm.deidentify.patterns", map[string]string{"customer": `CUST-[0-9]{6}`})
+	defer config.Set("llm.hooks", nil)
+	defer config.Set("llm.deidentify.internal_domains", nil)
+	defer config.Set("llm.deidentify.patterns", nil)
+
+	question := "CUST-004217 reports db01.corp.example.com (10.2.3.4) is slow, upstream 8.8.8.8 is fine"
+	hostPseudonym := regexp.MustCompile(`host-[0-9a-f]{8}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for _, secret := range []string{"CUST-004217", "db01.corp.example.com", "10.2.3.4"} {
+			if strings.Contains(string(body), secret) {
+				t.Errorf("request leaked %q: %s", secret, body)
+			}
+		}
+		if !strings.Contains(string(body), "8.8.8.8") {
+			t.Errorf("public IP should be kept: %s", body)
+		}
+		reply, _ := json.Marshal(map[string]interface{}{
+			"message": map[string]string{"role": "assistant", "content": "ping " + hostPseudonym.FindString(string(body))},
+		})
+		w.Write(reply)
+	}))
+	defer srv.Close()
+
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: question}}
+	got, err := newOllamaProvider(srv.URL).Chat("qwen2.5", 0, messages)
+	if err != nil || got != "ping db01.corp.example.com" {
+		t.Errorf("Chat() = %q, %v; want the pseudonym restored", got, err)
+	}
+	if messages[0].Content != question {
+		t.Errorf("caller's messages modified: %q", messages[0].Content)
+	}
+}
+
+func TestPseudonymsEvictLeastRecentlyUsed(t *testing.T) {
+	defer func(n int) { maxPseudonyms = n }(maxPseudonyms)
+	maxPseudonyms = 2
+
+	secret := []byte("test")
+	a := pseudonymize("host", "a.corp", secret)
+	b := pseudonymize("host", "b.corp", secret)
+	// 还原时同样刷新假名的使用时间，容量满时淘汰最久未使用的 b
+	if got := Reidentify(a); got != "a.corp" {
+		t.Fatalf("Reidentify(%s) = %q", a, got)
+	}
+	c := pseudonymize("host", "c.corp", secret)
+	if got := Reidentify(a + " " + b + " " + c); got != "a.corp "+b+" c.corp" {
+		t.Errorf("Reidentify() = %q, want only %s evicted", got, b)
+	}
+}
+
+func TestCurrentDeidentifierReusedUntilConfigChanges(t *testing.T) {
+	config := utils.GetConfig()
+	defer config.Set("llm.deidentify.internal_domains", nil)
+
+	config.Set("llm.deidentify.internal_domains", []string{"corp.example.com"})
+	first, err := currentDeidentifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := currentDeidentifier(); again != first {
+		t.Error("expected the deidentifier to be reused while the config is unchanged")
+	}
+	config.Set("llm.deidentify.internal_domains", []string{"corp.example.org"})
+	changed, _ := currentDeidentifier()
+	if changed == first || changed.Deidentify("db01.corp.example.org") == "db01.corp.example.org" {
+		t.Error("expected the deidentifier to be rebuilt after the config changed")
+	}
+}
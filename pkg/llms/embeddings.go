@@ -0,0 +1,72 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// EmbeddingModel 返回向量化使用的模型，通过 llm.embedding_model 配置
+func EmbeddingModel() string {
+	if model := utils.GetConfig().GetString("llm.embedding_model"); model != "" {
+		return model
+	}
+	return string(openai.SmallEmbedding3)
+}
+
+// Embed 将文本转换为向量，返回的向量与输入顺序一致
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, _, err := c.EmbedWithUsage(ctx, texts)
+	return embeddings, err
+}
+
+// EmbedWithUsage 将文本转换为向量，并返回响应中的 usage（DashScope 兼容模式同样返回该字段）
+func (c *OpenAIClient) EmbedWithUsage(ctx context.Context, texts []string) ([][]float32, openai.Usage, error) {
+	input, err := deidentifyTexts(c.Hooks, texts)
+	if err != nil {
+		return nil, openai.Usage{}, err
+	}
+	req := openai.EmbeddingRequestStrings{
+		Input: input,
+		Model: openai.EmbeddingModel(EmbeddingModel()),
+	}
+	var resp openai.EmbeddingResponse
+	if c.Cassette != nil {
+		err = c.Cassette.Do("embeddings", &req, &resp, func() error {
+			resp, err = c.Client.CreateEmbeddings(ctx, req)
+			return err
+		})
+	} else {
+		resp, err = c.Client.CreateEmbeddings(ctx, req)
+	}
+	if err != nil {
+		return nil, openai.Usage{}, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, resp.Usage, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(texts) {
+			return nil, resp.Usage, fmt.Errorf("unexpected embedding index %d", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+	return embeddings, resp.Usage, nil
+}
@@ -0,0 +1,324 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFailoverCooldown    = time.Minute
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	failoverRetriesPerEndpoint = 1
+	failoverBackoffPerEndpoint = 0
+)
+
+// EndpointStatus LLM 端点的健康状态
+type EndpointStatus struct {
+	Provider  string    `json:"provider"`
+	Endpoint  string    `json:"endpoint"`
+	Healthy   bool      `json:"healthy"`
+	Failures  int       `json:"failures"` // 连续失败次数
+	LastError string    `json:"last_error,omitempty"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	DownUntil time.Time `json:"down_until,omitempty"`
+}
+
+// endpointHealth 配置了多个地址的服务商端点健康状态，请求失败和后台健康检查共同维护
+var endpointHealth = struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointStatus
+	checker   sync.Once
+}{endpoints: map[string]*EndpointStatus{}}
+
+func endpointKey(provider, endpoint string) string {
+	return provider + "|" + endpoint
+}
+
+// trackEndpoint 登记需要健康检查的端点
+func trackEndpoint(provider, endpoint string) {
+	endpointHealth.mu.Lock()
+	defer endpointHealth.mu.Unlock()
+	if _, ok := endpointHealth.endpoints[endpointKey(provider, endpoint)]; !ok {
+		endpointHealth.endpoints[endpointKey(provider, endpoint)] = &EndpointStatus{Provider: provider, Endpoint: endpoint, Healthy: true}
+	}
+}
+
+// endpointAvailable 端点健康，或不健康但已过冷却时间（允许再次尝试）
+func endpointAvailable(provider, endpoint string, now time.Time) bool {
+	endpointHealth.mu.Lock()
+	defer endpointHealth.mu.Unlock()
+	status, ok := endpointHealth.endpoints[endpointKey(provider, endpoint)]
+	return !ok || status.Healthy || now.After(status.DownUntil)
+}
+
+// markEndpoint 记录一次请求或健康检查的结果，失败时在冷却时间内跳过该端点
+func markEndpoint(provider, endpoint string, err error, cooldown time.Duration) {
+	endpointHealth.mu.Lock()
+	defer endpointHealth.mu.Unlock()
+	status, ok := endpointHealth.endpoints[endpointKey(provider, endpoint)]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	status.LastCheck = now
+	if err == nil {
+		status.Healthy, status.Failures, status.LastError, status.DownUntil = true, 0, "", time.Time{}
+		return
+	}
+	status.Healthy = false
+	status.Failures++
+	status.LastError = err.Error()
+	status.DownUntil = now.Add(cooldown)
+}
+
+// EndpointHealth 返回所有多地址端点的健康状态，按服务商和端点排序
+func EndpointHealth() []EndpointStatus {
+	endpointHealth.mu.Lock()
+	statuses := make([]EndpointStatus, 0, len(endpointHealth.endpoints))
+	for _, status := range endpointHealth.endpoints {
+		statuses = append(statuses, *status)
+	}
+	endpointHealth.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Provider != statuses[j].Provider {
+			return statuses[i].Provider < statuses[j].Provider
+		}
+		return statuses[i].Endpoint < statuses[j].Endpoint
+	})
+	return statuses
+}
+
+// failoverConfig 故障转移配置
+// 配置项：
+//   - llm.failover.cooldown: 端点失败后跳过的时间，默认 1m
+//   - llm.failover.health_check_interval: 后台健康检查间隔，默认 30s，小于 0 时不检查
+func failoverConfig() (cooldown, interval time.Duration) {
+	config := utils.GetConfig()
+	cooldown = config.GetDuration("llm.failover.cooldown")
+	if cooldown <= 0 {
+		cooldown = defaultFailoverCooldown
+	}
+	interval = config.GetDuration("llm.failover.health_check_interval")
+	if interval == 0 {
+		interval = defaultHealthCheckInterval
+	}
+	return cooldown, interval
+}
+
+// startHealthChecker 启动后台健康检查，定期探测所有登记的端点，故障端点恢复后提前重新启用
+func startHealthChecker() {
+	endpointHealth.checker.Do(func() {
+		cooldown, interval := failoverConfig()
+		if interval < 0 {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				checkEndpointHealth(context.Background(), cooldown)
+			}
+		}()
+	})
+}
+
+// checkEndpointHealth 探测所有登记的端点
+func checkEndpointHealth(ctx context.Context, cooldown time.Duration) {
+	var wg sync.WaitGroup
+	for _, status := range EndpointHealth() {
+		wg.Add(1)
+		go func(status EndpointStatus) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, defaultHealthCheckTimeout)
+			defer cancel()
+			err := probeEndpoint(ctx, status.Endpoint)
+			if err == nil && !status.Healthy {
+				utils.Info("LLM 端点已恢复",
+					zap.String("provider", status.Provider),
+					zap.String("endpoint", status.Endpoint),
+				)
+			}
+			markEndpoint(status.Provider, status.Endpoint, err, cooldown)
+		}(status)
+	}
+	wg.Wait()
+}
+
+// probeEndpoint 探测端点是否可用：连接失败或 5xx 视为不可用，401/404 等说明服务在线
+func probeEndpoint(ctx context.Context, endpoint string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("health check returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// failoverable 错误是否说明端点不可用（连接失败、限流、服务端错误），可以切换到下一个端点
+// 请求本身的错误（400、401 等）切换端点也无法解决，直接返回
+func failoverable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var (
+		apiErr      *openai.APIError
+		requestErr  *openai.RequestError
+		providerErr *ProviderError
+		netErr      net.Error
+	)
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.HTTPStatusCode == http.StatusTooManyRequests || apiErr.HTTPStatusCode >= 500
+	case errors.As(err, &requestErr):
+		return requestErr.HTTPStatusCode == http.StatusTooManyRequests || requestErr.HTTPStatusCode >= 500
+	case errors.As(err, &providerErr):
+		return providerErr.StatusCode == http.StatusTooManyRequests || providerErr.StatusCode >= 500
+	case errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+// failoverProvider 在同一服务商的多个地址间故障转移（例如 Azure 区域 A → 区域 B）
+// 按配置顺序优先使用健康的端点；所有端点都不可用时仍按顺序尝试一遍
+type failoverProvider struct {
+	name      string
+	endpoints []string
+	providers []Provider
+	cooldown  time.Duration
+	// last 最近一次成功的端点序号
+	last atomic.Int32
+}
+
+// failoverToolCaller 支持原生工具调用的多地址服务商（OpenAI 兼容接口）
+type failoverToolCaller struct {
+	*failoverProvider
+}
+
+// newFailoverProvider 为每个地址创建客户端，单个端点不再重试，失败后直接切换到下一个端点
+func newFailoverProvider(provider, apiKey string, baseURLs []string) (Provider, error) {
+	cooldown, _ := failoverConfig()
+	p := &failoverProvider{name: provider, cooldown: cooldown}
+	for _, baseURL := range baseURLs {
+		client, err := newProvider(provider, apiKey, baseURL)
+		if err != nil {
+			return nil, err
+		}
+		switch c := client.(type) {
+		case *OpenAIClient:
+			c.Retries, c.Backoff = failoverRetriesPerEndpoint, failoverBackoffPerEndpoint
+		case *httpProvider:
+			c.retries, c.backoff = failoverRetriesPerEndpoint, failoverBackoffPerEndpoint
+		}
+		trackEndpoint(provider, baseURL)
+		p.endpoints = append(p.endpoints, baseURL)
+		p.providers = append(p.providers, client)
+	}
+	startHealthChecker()
+
+	if _, ok := p.providers[0].(ToolCaller); ok {
+		return &failoverToolCaller{p}, nil
+	}
+	return p, nil
+}
+
+// Name 返回服务商名称
+func (p *failoverProvider) Name() string { return p.name }
+
+// Chat 依次在可用端点上执行对话
+func (p *failoverProvider) Chat(model string, maxTokens int, messages []openai.ChatCompletionMessage) (string, error) {
+	var text string
+	err := p.do(func(provider Provider) (err error) {
+		text, err = provider.Chat(model, maxTokens, messages)
+		return err
+	})
+	return text, err
+}
+
+// LastFinishReason 返回最近一次成功的端点上对话的结束原因
+func (p *failoverProvider) LastFinishReason() string {
+	return FinishReason(p.providers[p.last.Load()])
+}
+
+// ChatWithTools 依次在可用端点上执行携带工具定义的对话
+func (p *failoverToolCaller) ChatWithTools(model string, maxTokens int, messages []openai.ChatCompletionMessage, tools []openai.Tool) (openai.ChatCompletionMessage, error) {
+	var message openai.ChatCompletionMessage
+	err := p.do(func(provider Provider) (err error) {
+		message, err = provider.(ToolCaller).ChatWithTools(model, maxTokens, messages, tools)
+		return err
+	})
+	return message, err
+}
+
+// do 按端点顺序执行请求，端点不可用时标记故障并切换到下一个端点
+func (p *failoverProvider) do(call func(Provider) error) error {
+	var lastErr error
+	for _, i := range p.order(time.Now()) {
+		endpoint := p.endpoints[i]
+		err := call(p.providers[i])
+		if err == nil {
+			markEndpoint(p.name, endpoint, nil, p.cooldown)
+			p.last.Store(int32(i))
+			return nil
+		}
+		if !failoverable(err) {
+			return err
+		}
+
+		markEndpoint(p.name, endpoint, err, p.cooldown)
+		utils.GetPerfStats().IncrCounter("llm_failover_" + p.name)
+		utils.Warn("LLM 端点不可用，切换到下一个端点",
+			zap.String("provider", p.name),
+			zap.String("endpoint", endpoint),
+			zap.Error(err),
+		)
+		lastErr = err
+	}
+	return fmt.Errorf("all %d %s endpoints failed: %w", len(p.endpoints), p.name, lastErr)
+}
+
+// order 返回尝试端点的顺序：可用的端点按配置顺序在前，冷却中的端点在后作为兜底
+func (p *failoverProvider) order(now time.Time) []int {
+	var available, cooling []int
+	for i, endpoint := range p.endpoints {
+		if endpointAvailable(p.name, endpoint, now) {
+			available = append(available, i)
+		} else {
+			cooling = append(cooling, i)
+		}
+	}
+	return append(available, cooling...)
+}
@@ -0,0 +1,73 @@
+package llms
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestFailoverProvider(t *testing.T) {
+	utils.GetConfig().Set("llm.failover.health_check_interval", "-1s")
+	defer utils.GetConfig().Set("llm.failover.health_check_interval", nil)
+
+	var primaryCalls, secondaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		http.Error(w, `{"error":"service unavailable"}`, http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryCalls++
+		w.Write([]byte(`{"message":{"role":"assistant","content":"It was OOMKilled."}}`))
+	}))
+	defer secondary.Close()
+
+	provider, err := newFailoverProvider(ProviderOllama, "", []string{primary.URL, secondary.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := provider.Chat("qwen2.5", 0, providerTestMessages)
+		if err != nil || got != "It was OOMKilled." {
+			t.Fatalf("Chat() = %q, %v", got, err)
+		}
+	}
+	if reason := FinishReason(provider); reason != "stop" {
+		t.Errorf("FinishReason() = %q, want the secondary endpoint's stop", reason)
+	}
+	// 主端点失败后在冷却时间内跳过，不再重试
+	if primaryCalls != 1 || secondaryCalls != 2 {
+		t.Errorf("primary called %d times, secondary %d times", primaryCalls, secondaryCalls)
+	}
+
+	for _, status := range EndpointHealth() {
+		if status.Endpoint == primary.URL && (status.Healthy || status.Failures != 1) {
+			t.Errorf("primary endpoint status = %+v, want unhealthy", status)
+		}
+		if status.Endpoint == secondary.URL && !status.Healthy {
+			t.Errorf("secondary endpoint status = %+v, want healthy", status)
+		}
+	}
+}
+
+func TestFailoverNotOnClientError(t *testing.T) {
+	var secondaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid model"}`, http.StatusBadRequest)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryCalls++
+	}))
+	defer secondary.Close()
+
+	provider, err := newFailoverProvider(ProviderOllama, "", []string{primary.URL, secondary.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Chat("qwen2.5", 0, providerTestMessages); err == nil || secondaryCalls != 0 {
+		t.Errorf("Chat() error = %v, secondary called %d times; want the 400 returned without failover", err, secondaryCalls)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultGeminiBaseURL = "https://generativelanguage.googleapis.com"
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      float32 `json:"temperature"`
+	MaxOutputTokens  int     `json:"maxOutputTokens,omitempty"`
+	ResponseMimeType string  `json:"responseMimeType,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent         `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent        `json:"contents"`
+	GenerationConfig  geminiGenerationConfig `json:"generationConfig"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// newGeminiProvider 创建 Google Gemini 客户端（generateContent API）
+func newGeminiProvider(apiKey, baseURL string) (Provider, error) {
+	if apiKey == "" && !cassetteReplay() {
+		return nil, fmt.Errorf("gemini API key is not set")
+	}
+	if baseURL == "" {
+		baseURL = defaultGeminiBaseURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	headers := map[string]string{"x-goog-api-key": apiKey}
+
+	return newHTTPProvider(ProviderGemini, func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error) {
+		system, turns := splitMessages(req.Messages)
+		body := geminiRequest{
+			GenerationConfig: geminiGenerationConfig{MaxOutputTokens: req.MaxTokens},
+		}
+		if system != "" {
+			body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+		}
+		if wantsJSON(req) {
+			body.GenerationConfig.ResponseMimeType = "application/json"
+		}
+		for _, turn := range turns {
+			role := "user"
+			if turn.Role == openai.ChatMessageRoleAssistant {
+				role = "model"
+			}
+			body.Contents = append(body.Contents, geminiContent{Role: role, Parts: []geminiPart{{Text: turn.Content}}})
+		}
+
+		endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent", baseURL, url.PathEscape(req.Model))
+		var resp geminiResponse
+		if err := postJSON(ctx, ProviderGemini, endpoint, headers, body, &resp); err != nil {
+			return "", openai.Usage{}, err
+		}
+		if len(resp.Candidates) == 0 {
+			return "", openai.Usage{}, fmt.Errorf("gemini returned no candidates")
+		}
+		var text strings.Builder
+		for _, part := range resp.Candidates[0].Content.Parts {
+			text.WriteString(part.Text)
+		}
+		if text.Len() == 0 {
+			return "", openai.Usage{}, fmt.Errorf("gemini returned no text content (finish_reason: %s)", resp.Candidates[0].FinishReason)
+		}
+		usage := openai.Usage{
+			PromptTokens:     resp.UsageMetadata.PromptTokenCount,
+			CompletionTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:      resp.UsageMetadata.TotalTokenCount,
+		}
+		return text.String(), usage, nil
+	}), nil
+}
@@ -0,0 +1,112 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// ChatHook 是对话请求/响应的钩子接口
+// 可用于实现 PII 清洗、提示压缩或特定提供商的参数调整，而无需修改客户端本身
+type ChatHook interface {
+	// Name 返回钩子名称，用于在配置 llm.hooks 中引用
+	Name() string
+	// BeforeChat 在请求发送前调用，可以修改请求
+	BeforeChat(req *openai.ChatCompletionRequest) error
+	// AfterChat 在收到响应后调用，可以修改响应
+	AfterChat(req *openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error
+}
+
+var (
+	chatHooks   = map[string]ChatHook{}
+	chatHooksMu sync.RWMutex
+)
+
+func init() {
+	RegisterChatHook(compactHook{})
+	RegisterChatHook(jsonResponseHook{})
+	RegisterChatHook(deidentifyHook{})
+}
+
+// RegisterChatHook 注册对话钩子，同名钩子会被覆盖
+func RegisterChatHook(hook ChatHook) {
+	chatHooksMu.Lock()
+	defer chatHooksMu.Unlock()
+	chatHooks[hook.Name()] = hook
+}
+
+// EnabledChatHooks 按配置 llm.hooks 的顺序返回启用的钩子
+func EnabledChatHooks() []ChatHook {
+	chatHooksMu.RLock()
+	defer chatHooksMu.RUnlock()
+
+	var hooks []ChatHook
+	for _, name := range utils.GetConfig().GetStringSlice("llm.hooks") {
+		hook, ok := chatHooks[name]
+		if !ok {
+			utils.Warn("未知的 LLM 钩子，已跳过", zap.String("hook", name))
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks
+}
+
+var blankLinesRegexp = regexp.MustCompile(`\n{3,}`)
+
+// compactHook 压缩提示中的多余空白，减少 token 消耗
+type compactHook struct{}
+
+func (compactHook) Name() string { return "compact" }
+
+func (compactHook) BeforeChat(req *openai.ChatCompletionRequest) error {
+	// 复制消息，避免修改调用方的对话历史
+	messages := make([]openai.ChatCompletionMessage, len(req.Messages))
+	for i, message := range req.Messages {
+		lines := strings.Split(message.Content, "\n")
+		for j, line := range lines {
+			lines[j] = strings.TrimRight(line, " \t")
+		}
+		message.Content = blankLinesRegexp.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+		messages[i] = message
+	}
+	req.Messages = messages
+	return nil
+}
+
+func (compactHook) AfterChat(req *openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error {
+	return nil
+}
+
+// jsonResponseHook 要求模型以 JSON 对象格式响应（需提供商支持 response_format）
+type jsonResponseHook struct{}
+
+func (jsonResponseHook) Name() string { return "json_response" }
+
+func (jsonResponseHook) BeforeChat(req *openai.ChatCompletionRequest) error {
+	req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+	}
+	return nil
+}
+
+func (jsonResponseHook) AfterChat(req *openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package llms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// recordingHook 记录调用顺序的测试钩子
+type recordingHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordingHook) Name() string { return h.name }
+
+func (h recordingHook) BeforeChat(req *openai.ChatCompletionRequest) error {
+	*h.calls = append(*h.calls, "before:"+h.name)
+	return nil
+}
+
+func (h recordingHook) AfterChat(req *openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error {
+	*h.calls = append(*h.calls, "after:"+h.name)
+	resp.Choices[0].Message.Content += " [" + h.name + "]"
+	return nil
+}
+
+func TestChatHooks(t *testing.T) {
+	var calls []string
+	RegisterChatHook(recordingHook{name: "first", calls: &calls})
+	RegisterChatHook(recordingHook{name: "second", calls: &calls})
+	defer func() {
+		chatHooksMu.Lock()
+		delete(chatHooks, "first")
+		delete(chatHooks, "second")
+		chatHooksMu.Unlock()
+	}()
+	config := utils.GetConfig()
+	config.Set("llm.hooks", []string{"second", "missing", "compact", "json_response", "first"})
+	defer config.Set("llm.hooks", nil)
+
+	var sent openai.ChatCompletionRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "ok"}}},
+		})
+	}))
+	defer srv.Close()
+
+	client, err := NewOpenAIClient("sk-test", srv.URL+"/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(client.Hooks) != 4 {
+		t.Fatalf("expected 4 enabled hooks without the unknown one, got %d", len(client.Hooks))
+	}
+	history := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "pods   \n\n\n\nnodes"}}
+	got, err := client.Chat("gpt-4o", 0, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 钩子按 llm.hooks 的顺序执行，响应依次经过各钩子
+	want := []string{"before:second", "before:first", "after:second", "after:first"}
+	if len(calls) != len(want) {
+		t.Fatalf("hook calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("hook call %d = %s, want %s", i, calls[i], want[i])
+		}
+	}
+	if got != "ok [second] [first]" {
+		t.Errorf("Chat() = %q", got)
+	}
+	if sent.Messages[0].Content != "pods\n\nnodes" || sent.ResponseFormat == nil || sent.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeJSONObject {
+		t.Errorf("unexpected request: %+v", sent)
+	}
+	// compact 不修改调用方保存的对话历史
+	if history[0].Content != "pods   \n\n\n\nnodes" {
+		t.Errorf("caller's history modified: %q", history[0].Content)
+	}
+}
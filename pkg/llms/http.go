@@ -0,0 +1,49 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+var (
+	httpClient     *http.Client
+	httpClientOnce sync.Once
+)
+
+// HTTPClient 返回所有 LLM 客户端（对话、向量化、预热）共享的 HTTP 客户端
+// 共享连接池使预热建立的连接和 TLS 会话可以被后续请求复用，
+// 超时、代理和 CA 等通过 http_client 及 http_client.clients.llm 配置
+func HTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("llm")
+		if err != nil {
+			utils.Error("创建 LLM HTTP 客户端失败，使用默认配置", zap.Error(err))
+			client, _ = utils.DefaultHTTPClientConfig().NewClient()
+		}
+		httpClient = client
+	})
+	return httpClient
+}
+
+// CloseIdleConnections 关闭共享 HTTP 客户端的空闲连接，服务退出时调用
+func CloseIdleConnections() {
+	if httpClient != nil {
+		httpClient.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// 支持的回答语言
+const (
+	LanguageZH = "zh"
+	LanguageEN = "en"
+)
+
+// hanRatioThreshold 汉字占字母类字符的比例超过该值时判定为中文
+const hanRatioThreshold = 0.2
+
+// codeSpanRe 匹配 markdown 代码块和行内代码，命令和资源名称不参与语言判断
+var codeSpanRe = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// languageNames 翻译提示中使用的语言名称
+var languageNames = map[string]string{
+	LanguageZH: "Simplified Chinese (简体中文)",
+	LanguageEN: "English",
+}
+
+// NormalizeLanguage 规范化语言代码，支持 zh、zh-CN、cn、en、en-US 等写法，空字符串原样返回
+func NormalizeLanguage(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	switch {
+	case lang == "":
+		return "", nil
+	case lang == "cn" || lang == "chinese" || lang == LanguageZH || strings.HasPrefix(lang, "zh-") || strings.HasPrefix(lang, "zh_"):
+		return LanguageZH, nil
+	case lang == "english" || lang == LanguageEN || strings.HasPrefix(lang, "en-") || strings.HasPrefix(lang, "en_"):
+		return LanguageEN, nil
+	}
+	return "", fmt.Errorf("不支持的回答语言: %s（可选 zh、en）", lang)
+}
+
+// AnswerLanguage 返回最终回答使用的语言：优先使用请求指定的语言，其次为配置 answer.language
+// 返回空字符串表示不限定，回答语言与提示语言一致
+func AnswerLanguage(requested string) (string, error) {
+	if requested != "" {
+		return NormalizeLanguage(requested)
+	}
+	return NormalizeLanguage(utils.GetConfig().GetString("answer.language"))
+}
+
+// DetectLanguage 根据汉字比例粗略判断文本语言，代码块中的内容不参与判断
+func DetectLanguage(text string) string {
+	text = codeSpanRe.ReplaceAllString(text, " ")
+
+	var han, letters int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+			letters++
+		case unicode.IsLetter(r):
+			letters++
+		}
+	}
+	if letters > 0 && float64(han)/float64(letters) >= hanRatioThreshold {
+		return LanguageZH
+	}
+	return LanguageEN
+}
+
+// LanguageInstruction 要求模型使用指定语言输出最终答案的提示，追加在系统提示末尾
+func LanguageInstruction(lang string) string {
+	switch lang {
+	case LanguageEN:
+		return "回答语言：final_answer 必须使用英文（English）输出，命令、资源名称和日志原文保持不变。"
+	case LanguageZH:
+		return "Answer language: write final_answer in Simplified Chinese (简体中文). Keep commands, resource names and log lines unchanged."
+	}
+	return ""
+}
+
+// Translate 将回答翻译为指定语言，保留 markdown 格式、代码块、命令和资源名称
+func Translate(provider Provider, model, text, lang string) (string, error) {
+	name, ok := languageNames[lang]
+	if !ok {
+		return "", fmt.Errorf("不支持的回答语言: %s", lang)
+	}
+
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role: openai.ChatMessageRoleSystem,
+			Content: fmt.Sprintf("You translate answers written by a Kubernetes operations assistant into %s. "+
+				"Keep the markdown structure, code blocks, inline code, commands, resource names, numbers and log lines unchanged. "+
+				"Output only the translated text.", name),
+		},
+		{Role: openai.ChatMessageRoleUser, Content: text},
+	}
+	translated, err := provider.Chat(model, 0, messages)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(translated), nil
+}
@@ -0,0 +1,29 @@
+package llms
+
+import "testing"
+
+func TestNormalizeLanguage(t *testing.T) {
+	for input, want := range map[string]string{"": "", "zh": LanguageZH, "zh-CN": LanguageZH, "CN": LanguageZH, "en": LanguageEN, "en_US": LanguageEN, "English": LanguageEN} {
+		got, err := NormalizeLanguage(input)
+		if err != nil || got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := NormalizeLanguage("fr"); err == nil {
+		t.Error("expected error for unsupported language")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"Pod payment-api 因为 OOMKilled 重启了 3 次，建议调大内存限制。":                                 LanguageZH,
+		"The pod payment-api restarted 3 times because it was OOMKilled.":                LanguageEN,
+		"The pod is crashing, run:\n```\nkubectl logs payment-api -n 支付 --previous\n```": LanguageEN,
+		"执行 `kubectl rollout restart deployment/payment-api -n shop` 重启服务":               LanguageZH,
+	}
+	for text, want := range tests {
+		if got := DetectLanguage(text); got != want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MockAnswerPrefix 模拟服务商回答的前缀，便于在界面上区分真实回答
+const MockAnswerPrefix = "【开发模式模拟回答】"
+
+// maxMockObservationLines 模拟回答中引用的工具输出行数
+const maxMockObservationLines = 20
+
+// mockOnly 启用后所有请求都使用模拟服务商，见 UseMockProvider
+var mockOnly atomic.Bool
+
+// UseMockProvider 让所有请求都使用模拟服务商（开发模式），不再请求任何 LLM 服务，也不需要 API Key
+func UseMockProvider() {
+	mockOnly.Store(true)
+}
+
+// mockToolRules 按问题中的关键词选择工具，未匹配时查询 Pod 列表
+var mockToolRules = []struct {
+	keywords []string
+	tool     string
+	input    string
+}{
+	{[]string{"漏洞", "cve", "trivy", "vulnerab", "扫描"}, "trivy", "nginx:1.25"},
+	{[]string{"镜像", "版本", "image", "version"}, "kubectl", "kubectl get deployments -A -o wide"},
+	{[]string{"节点", "node"}, "kubectl", "kubectl get nodes -o wide"},
+	{[]string{"事件", "报错", "异常", "event", "error"}, "kubectl", "kubectl get events -A"},
+	{[]string{"服务", "service", "svc"}, "kubectl", "kubectl get services -A"},
+}
+
+// mockImageRe 匹配问题中的镜像名称，扫描漏洞时作为 trivy 的输入
+var mockImageRe = regexp.MustCompile(`[a-z0-9][a-z0-9./_-]*:[a-zA-Z0-9._-]+`)
+
+// mockToolPrompt 与 tools.ToolPrompt 相同的 ReAct 格式
+type mockToolPrompt struct {
+	Question string `json:"question"`
+	Thought  string `json:"thought"`
+	Action   struct {
+		Name  string `json:"name"`
+		Input string `json:"input"`
+	} `json:"action"`
+	Observation string `json:"observation"`
+	FinalAnswer string `json:"final_answer"`
+}
+
+// newMockProvider 创建确定性的模拟服务商：使用 ReAct 格式的系统提示时，
+// 先按问题中的关键词调用一次工具，拿到工具输出后引用输出作为最终回答；其他请求原样返回最后一条消息
+func newMockProvider() Provider {
+	return newHTTPProvider(ProviderMock, func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error) {
+		text := mockReply(req.Messages)
+		usage := openai.Usage{PromptTokens: estimateTokens(req.Messages), CompletionTokens: utf8.RuneCountInString(text) / 4}
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+		return text, usage, nil
+	})
+}
+
+func mockReply(messages []openai.ChatCompletionMessage) string {
+	if len(messages) == 0 {
+		return MockAnswerPrefix
+	}
+	last := messages[len(messages)-1].Content
+	var react bool
+	for _, m := range messages {
+		if m.Role == openai.ChatMessageRoleSystem && strings.Contains(m.Content, "final_answer") {
+			react = true
+		}
+	}
+	if !react {
+		return MockAnswerPrefix + last
+	}
+
+	var prompt mockToolPrompt
+	if err := json.Unmarshal([]byte(last), &prompt); err == nil && prompt.Observation != "" {
+		lines := strings.Split(strings.TrimSpace(prompt.Observation), "\n")
+		if len(lines) > maxMockObservationLines {
+			lines = append(lines[:maxMockObservationLines], "...")
+		}
+		prompt.Thought = "已获得工具输出，整理回答"
+		prompt.FinalAnswer = MockAnswerPrefix + "根据 " + prompt.Action.Name + " 的输出：\n" + strings.Join(lines, "\n")
+		data, _ := json.Marshal(prompt)
+		return string(data)
+	}
+
+	prompt = mockToolPrompt{Question: last, Thought: "需要先查询集群"}
+	prompt.Action.Name, prompt.Action.Input = "kubectl", "kubectl get pods -A"
+	question := strings.ToLower(last)
+	for _, rule := range mockToolRules {
+		if containsAny(question, rule.keywords) {
+			prompt.Action.Name, prompt.Action.Input = rule.tool, rule.input
+			break
+		}
+	}
+	if prompt.Action.Name == "trivy" {
+		if image := mockImageRe.FindString(last); image != "" {
+			prompt.Action.Input = image
+		}
+	}
+	data, _ := json.Marshal(prompt)
+	return string(data)
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// estimateTokens 粗略估算 token 数（约 4 个字符一个 token），模拟服务商不使用分词器
+func estimateTokens(messages []openai.ChatCompletionMessage) int {
+	var n int
+	for _, m := range messages {
+		n += utf8.RuneCountInString(m.Content)
+	}
+	return n / 4
+}
@@ -0,0 +1,36 @@
+package llms
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestMockReply(t *testing.T) {
+	system := openai.ChatCompletionMessage{Role: openai.ChatMessageRoleSystem, Content: `按 JSON 格式回答，包含 action 和 final_answer`}
+	messages := []openai.ChatCompletionMessage{system, {Role: openai.ChatMessageRoleUser, Content: "扫描 nginx:1.25.3 的漏洞"}}
+
+	var prompt mockToolPrompt
+	if err := json.Unmarshal([]byte(mockReply(messages)), &prompt); err != nil {
+		t.Fatal(err)
+	}
+	if prompt.Action.Name != "trivy" || prompt.Action.Input != "nginx:1.25.3" || prompt.FinalAnswer != "" {
+		t.Fatalf("unexpected first reply: %+v", prompt)
+	}
+
+	prompt.Observation = "Total: 3 (HIGH: 2, CRITICAL: 1)"
+	observation, _ := json.Marshal(prompt)
+	messages = append(messages, openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: string(observation)})
+	if err := json.Unmarshal([]byte(mockReply(messages)), &prompt); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(prompt.FinalAnswer, MockAnswerPrefix) || !strings.Contains(prompt.FinalAnswer, "CRITICAL: 1") {
+		t.Errorf("unexpected final answer: %q", prompt.FinalAnswer)
+	}
+
+	if got := mockReply([]openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hello"}}); got != MockAnswerPrefix+"hello" {
+		t.Errorf("unexpected plain reply: %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   string          `json:"format,omitempty"`
+	Options  ollamaOptions   `json:"options"`
+}
+
+type ollamaResponse struct {
+	Message         ollamaMessage `json:"message"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// newOllamaProvider 创建本地 Ollama 客户端（/api/chat），不需要 API Key
+func newOllamaProvider(baseURL string) Provider {
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/api/chat"
+
+	return newHTTPProvider(ProviderOllama, func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error) {
+		body := ollamaRequest{
+			Model:   req.Model,
+			Options: ollamaOptions{NumPredict: req.MaxTokens},
+		}
+		if wantsJSON(req) {
+			body.Format = "json"
+		}
+		for _, m := range req.Messages {
+			role := m.Role
+			if role != openai.ChatMessageRoleSystem && role != openai.ChatMessageRoleAssistant {
+				role = openai.ChatMessageRoleUser
+			}
+			body.Messages = append(body.Messages, ollamaMessage{Role: role, Content: m.Content})
+		}
+
+		var resp ollamaResponse
+		if err := postJSON(ctx, ProviderOllama, url, nil, body, &resp); err != nil {
+			return "", openai.Usage{}, err
+		}
+		usage := openai.Usage{
+			PromptTokens:     resp.PromptEvalCount,
+			CompletionTokens: resp.EvalCount,
+			TotalTokens:      resp.PromptEvalCount + resp.EvalCount,
+		}
+		return resp.Message.Content, usage, nil
+	})
+}
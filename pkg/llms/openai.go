@@ -18,10 +18,14 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"regexp"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/myysophia/OpsAgent/pkg/chaos"
+	"github.com/myysophia/OpsAgent/pkg/utils"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -29,19 +33,29 @@ import (
 type OpenAIClient struct {
 	*openai.Client
 
-	Retries int           // 重试次数
-	Backoff time.Duration // 重试间隔
+	Retries  int           // 重试次数
+	Backoff  time.Duration // 重试间隔
+	Hooks    []ChatHook    // 请求/响应钩子
+	Cassette *Cassette     // 录制/回放，为 nil 时直接请求 LLM
+
+	finishReason atomic.Value // 最近一次对话的结束原因
 }
 
 // NewOpenAIClient 创建新的 OpenAI 客户端
 // 支持标准 OpenAI API 和 Azure OpenAI API
 func NewOpenAIClient(apiKey string, baseURL string) (*OpenAIClient, error) {
 	//apiKey := os.Getenv("OPENAI_API_KEY")
+	cassette := CassetteFromConfig()
+	if apiKey == "" && cassette != nil && cassette.Mode == CassetteReplay {
+		// 回放模式不会请求 LLM，不需要真实的 API Key
+		apiKey = "cassette-replay"
+	}
 	if apiKey == "" {
 		return nil, fmt.Errorf("OPENAI_API_KEY is not set")
 	}
 
 	config := openai.DefaultConfig(apiKey)
+	config.HTTPClient = HTTPClient()
 	//baseURL := os.Getenv("OPENAI_API_BASE")
 	if baseURL != "" {
 		config.BaseURL = baseURL
@@ -56,9 +70,11 @@ func NewOpenAIClient(apiKey string, baseURL string) (*OpenAIClient, error) {
 	}
 
 	return &OpenAIClient{
-		Retries: 5,
-		Backoff: time.Second,
-		Client:  openai.NewClientWithConfig(config),
+		Retries:  5,
+		Backoff:  time.Second,
+		Hooks:    EnabledChatHooks(),
+		Cassette: cassette,
+		Client:   openai.NewClientWithConfig(config),
 	}, nil
 }
 
@@ -67,19 +83,58 @@ func NewOpenAIClient(apiKey string, baseURL string) (*OpenAIClient, error) {
 // - maxTokens: 最大 token 数量
 // - prompts: 对话历史
 func (c *OpenAIClient) Chat(model string, maxTokens int, prompts []openai.ChatCompletionMessage) (string, error) {
-	req := openai.ChatCompletionRequest{
+	message, err := c.chat(openai.ChatCompletionRequest{
 		Model:       model,
 		MaxTokens:   maxTokens,
 		Temperature: math.SmallestNonzeroFloat32,
 		Messages:    prompts,
+	})
+	if err != nil {
+		return "", err
 	}
+	return string(message.Content), nil
+}
 
+// ChatWithTools 使用原生工具调用（function calling）执行对话，返回的消息可能包含工具调用
+// tools 为空时等同于普通对话
+func (c *OpenAIClient) ChatWithTools(model string, maxTokens int, prompts []openai.ChatCompletionMessage, tools []openai.Tool) (openai.ChatCompletionMessage, error) {
+	return c.chat(openai.ChatCompletionRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: math.SmallestNonzeroFloat32,
+		Messages:    prompts,
+		Tools:       tools,
+	})
+}
+
+// LastFinishReason 返回最近一次对话的结束原因
+func (c *OpenAIClient) LastFinishReason() string {
+	reason, _ := c.finishReason.Load().(string)
+	return reason
+}
+
+// chat 执行请求钩子并发送请求，限流和服务端错误时按指数退避重试
+func (c *OpenAIClient) chat(req openai.ChatCompletionRequest) (openai.ChatCompletionMessage, error) {
+	for _, hook := range c.Hooks {
+		if err := hook.BeforeChat(&req); err != nil {
+			return openai.ChatCompletionMessage{}, fmt.Errorf("chat hook %s failed: %v", hook.Name(), err)
+		}
+	}
+
+	var lastErr error
 	backoff := c.Backoff
 	for try := 0; try < c.Retries; try++ {
-		resp, err := c.Client.CreateChatCompletion(context.Background(), req)
+		resp, err := c.createChatCompletion(context.Background(), req)
 
 		if err == nil {
-			return string(resp.Choices[0].Message.Content), nil
+			for _, hook := range c.Hooks {
+				if err := hook.AfterChat(&req, &resp); err != nil {
+					return openai.ChatCompletionMessage{}, fmt.Errorf("chat hook %s failed: %v", hook.Name(), err)
+				}
+			}
+			utils.RecordLLMUsage(ProviderOpenAI, req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+			c.finishReason.Store(string(resp.Choices[0].FinishReason))
+			return resp.Choices[0].Message, nil
 		}
 
 		e := &openai.APIError{}
@@ -87,18 +142,34 @@ func (c *OpenAIClient) Chat(model string, maxTokens int, prompts []openai.ChatCo
 		if errors.As(err, &e) {
 			switch e.HTTPStatusCode {
 			case 401:
-				return "", err
+				return openai.ChatCompletionMessage{}, err
 			case 429, 500:
+				lastErr = err
 				time.Sleep(backoff)
 				backoff *= 2
 				continue
 			default:
-				return "", err
+				return openai.ChatCompletionMessage{}, err
 			}
 		}
 
-		return "", err
+		return openai.ChatCompletionMessage{}, err
 	}
 
-	return "", fmt.Errorf("OpenAI request throttled after retrying %d times", c.Retries)
+	return openai.ChatCompletionMessage{}, &ThrottledError{Provider: "OpenAI", Retries: c.Retries, Err: lastErr}
+}
+
+// createChatCompletion 发送对话请求，启用录制/回放时经由 Cassette
+func (c *OpenAIClient) createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (resp openai.ChatCompletionResponse, err error) {
+	if err := chaos.Inject(chaos.TargetLLM); err != nil {
+		return resp, &openai.APIError{HTTPStatusCode: http.StatusInternalServerError, Message: err.Error()}
+	}
+	if c.Cassette == nil {
+		return c.Client.CreateChatCompletion(ctx, req)
+	}
+	err = c.Cassette.Do("chat", &req, &resp, func() error {
+		resp, err = c.Client.CreateChatCompletion(ctx, req)
+		return err
+	})
+	return resp, err
 }
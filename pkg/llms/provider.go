@@ -0,0 +1,387 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/chaos"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+// 支持的 LLM 服务商，未识别的名称按 OpenAI 兼容接口处理（如 Azure、DeepSeek、通义千问）
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+	ProviderOllama    = "ollama"
+	ProviderMock      = "mock" // 开发模式的模拟服务商，返回确定性的回答
+)
+
+// Provider LLM 服务商的对话接口
+// 所有实现都使用 go-openai 的消息结构作为统一格式，由各实现转换为服务商的请求格式
+type Provider interface {
+	// Name 返回服务商名称
+	Name() string
+	// Chat 执行一次对话，返回模型输出的文本
+	Chat(model string, maxTokens int, messages []openai.ChatCompletionMessage) (string, error)
+}
+
+// ToolCaller 支持原生工具调用（function calling）的服务商
+// 目前只有 OpenAI 兼容接口实现，其他服务商使用基于提示的 ReAct 循环
+type ToolCaller interface {
+	// ChatWithTools 执行一次携带工具定义的对话，返回模型的消息（文本或工具调用）
+	ChatWithTools(model string, maxTokens int, messages []openai.ChatCompletionMessage, tools []openai.Tool) (openai.ChatCompletionMessage, error)
+}
+
+// FinishReasoner 可以报告最近一次对话结束原因的服务商
+type FinishReasoner interface {
+	// LastFinishReason 返回最近一次成功对话的结束原因（stop、length、tool_calls 等）
+	LastFinishReason() string
+}
+
+// FinishReason 返回服务商最近一次对话的结束原因，服务商不支持时返回空
+func FinishReason(provider interface{}) string {
+	if reasoner, ok := provider.(FinishReasoner); ok {
+		return reasoner.LastFinishReason()
+	}
+	return ""
+}
+
+// Name 返回服务商名称
+func (c *OpenAIClient) Name() string { return ProviderOpenAI }
+
+// NormalizeProvider 规范化服务商名称，claude、google 等别名映射到对应的服务商
+// 启用 UseMockProvider 后总是返回 mock
+func NormalizeProvider(name string) string {
+	if mockOnly.Load() {
+		return ProviderMock
+	}
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case ProviderAnthropic, "claude":
+		return ProviderAnthropic
+	case ProviderGemini, "google":
+		return ProviderGemini
+	case ProviderOllama:
+		return ProviderOllama
+	case ProviderMock:
+		return ProviderMock
+	}
+	return ProviderOpenAI
+}
+
+// RequiresAPIKey 请求是否需要携带 API Key：本地 Ollama、模拟服务商和已配置 llm.providers.<name>.api_key 的服务商不需要
+func RequiresAPIKey(provider string) bool {
+	provider = NormalizeProvider(provider)
+	if provider == ProviderOllama || provider == ProviderMock {
+		return false
+	}
+	return utils.GetConfig().GetString("llm.providers."+provider+".api_key") == ""
+}
+
+// BaseURLAllowed 请求指定的地址是否可以与服务端密钥一起使用：
+// 为空、是 llm.providers 中配置的服务商地址，或在 llm.allowed_base_urls 中
+func BaseURLAllowed(baseURL string) bool {
+	if baseURL == "" {
+		return true
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	for _, allowed := range append(providerEndpoints(), utils.GetConfig().GetStringSlice("llm.allowed_base_urls")...) {
+		if strings.TrimSuffix(allowed, "/") == baseURL {
+			return true
+		}
+	}
+	return false
+}
+
+// NewProvider 创建指定服务商的对话客户端
+// 服务商的 API Key 和地址可通过 llm.providers.<name>.api_key、llm.providers.<name>.base_url 配置，
+// 配置的 API Key 优先于请求携带的 API Key，请求指定的 baseURL 优先于配置的地址；
+// 使用配置的 API Key 时只接受 BaseURLAllowed 的地址，避免密钥被发送到调用方指定的地址
+// 配置了 llm.providers.<name>.base_urls 且请求未指定 baseURL 时，按顺序在多个地址间自动故障转移
+func NewProvider(name, apiKey, baseURL string) (Provider, error) {
+	provider := NormalizeProvider(name)
+	config := utils.GetConfig()
+	if key := config.GetString("llm.providers." + provider + ".api_key"); key != "" {
+		apiKey = key
+		if !BaseURLAllowed(baseURL) {
+			utils.Warn("请求指定的 LLM 地址不在允许列表中，使用配置的服务商地址",
+				zap.String("provider", provider),
+				zap.String("baseUrl", baseURL),
+			)
+			baseURL = ""
+		}
+	}
+	if baseURL == "" {
+		if baseURLs := config.GetStringSlice("llm.providers." + provider + ".base_urls"); len(baseURLs) > 0 {
+			return newFailoverProvider(provider, apiKey, baseURLs)
+		}
+		baseURL = config.GetString("llm.providers." + provider + ".base_url")
+	}
+	return newProvider(provider, apiKey, baseURL)
+}
+
+// newProvider 创建单个地址的服务商客户端
+func newProvider(provider, apiKey, baseURL string) (Provider, error) {
+	switch provider {
+	case ProviderAnthropic:
+		return newAnthropicProvider(apiKey, baseURL)
+	case ProviderGemini:
+		return newGeminiProvider(apiKey, baseURL)
+	case ProviderOllama:
+		return newOllamaProvider(baseURL), nil
+	case ProviderMock:
+		return newMockProvider(), nil
+	}
+	return NewOpenAIClient(apiKey, baseURL)
+}
+
+// cassetteReplay 是否处于回放模式，回放时不会请求服务商，不需要 API Key
+func cassetteReplay() bool {
+	cassette := CassetteFromConfig()
+	return cassette != nil && cassette.Mode == CassetteReplay
+}
+
+type providerKey struct{}
+
+// WithProvider 在上下文中记录请求选择的服务商
+func WithProvider(ctx context.Context, provider string) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// ProviderFromContext 获取上下文中记录的服务商，未设置时返回空字符串（OpenAI 兼容接口）
+func ProviderFromContext(ctx context.Context) string {
+	provider, _ := ctx.Value(providerKey{}).(string)
+	return provider
+}
+
+// ProviderError 服务商返回的错误响应
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s request failed with status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+func (e *ProviderError) ErrorCode() string { return ErrCodeLLMError }
+
+// LLM 调用错误的错误码
+const (
+	ErrCodeLLMError       = "llm_error"
+	ErrCodeLLMThrottled   = "llm_throttled"
+	ErrCodeBudgetExceeded = "token_budget_exceeded"
+)
+
+// ThrottledError 服务商持续限流或返回服务端错误，重试次数已用尽，Err 为最后一次的错误
+type ThrottledError struct {
+	Provider string
+	Retries  int
+	Err      error
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s request throttled after retrying %d times: %v", e.Provider, e.Retries, e.Err)
+}
+
+func (e *ThrottledError) Unwrap() error { return e.Err }
+
+func (e *ThrottledError) ErrorCode() string { return ErrCodeLLMThrottled }
+
+// httpProvider 基于 HTTP JSON 接口的服务商实现，复用对话钩子、录制回放和重试逻辑
+type httpProvider struct {
+	name     string
+	retries  int
+	backoff  time.Duration
+	hooks    []ChatHook
+	cassette *Cassette
+	// finishReason 最近一次对话的结束原因
+	finishReason atomic.Value
+	// send 将统一格式的请求发送到服务商，返回模型输出的文本和 token 用量
+	send func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error)
+}
+
+func newHTTPProvider(name string, send func(ctx context.Context, req openai.ChatCompletionRequest) (string, openai.Usage, error)) *httpProvider {
+	return &httpProvider{
+		name:     name,
+		retries:  5,
+		backoff:  time.Second,
+		hooks:    EnabledChatHooks(),
+		cassette: CassetteFromConfig(),
+		send:     send,
+	}
+}
+
+// Name 返回服务商名称
+func (p *httpProvider) Name() string { return p.name }
+
+// LastFinishReason 返回最近一次对话的结束原因
+func (p *httpProvider) LastFinishReason() string {
+	reason, _ := p.finishReason.Load().(string)
+	return reason
+}
+
+// Chat 执行一次对话，限流和服务端错误时按指数退避重试
+func (p *httpProvider) Chat(model string, maxTokens int, messages []openai.ChatCompletionMessage) (string, error) {
+	req := openai.ChatCompletionRequest{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: math.SmallestNonzeroFloat32,
+		Messages:    messages,
+	}
+	for _, hook := range p.hooks {
+		if err := hook.BeforeChat(&req); err != nil {
+			return "", fmt.Errorf("chat hook %s failed: %v", hook.Name(), err)
+		}
+	}
+
+	var lastErr error
+	backoff := p.backoff
+	for try := 0; try < p.retries; try++ {
+		var resp openai.ChatCompletionResponse
+		err := p.complete(context.Background(), req, &resp)
+		if err == nil {
+			for _, hook := range p.hooks {
+				if err := hook.AfterChat(&req, &resp); err != nil {
+					return "", fmt.Errorf("chat hook %s failed: %v", hook.Name(), err)
+				}
+			}
+			utils.RecordLLMUsage(p.name, req.Model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+			p.finishReason.Store(string(resp.Choices[0].FinishReason))
+			return resp.Choices[0].Message.Content, nil
+		}
+
+		var providerErr *ProviderError
+		if errors.As(err, &providerErr) && (providerErr.StatusCode == http.StatusTooManyRequests || providerErr.StatusCode >= 500) {
+			lastErr = err
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		return "", err
+	}
+	return "", &ThrottledError{Provider: p.name, Retries: p.retries, Err: lastErr}
+}
+
+// complete 发送请求并将结果转换为 OpenAI 响应格式，启用录制/回放时经由 Cassette
+func (p *httpProvider) complete(ctx context.Context, req openai.ChatCompletionRequest, resp *openai.ChatCompletionResponse) error {
+	// 注入的故障按服务端错误返回，经过与真实故障相同的重试和故障转移
+	if err := chaos.Inject(chaos.TargetLLM); err != nil {
+		return &ProviderError{Provider: p.name, StatusCode: http.StatusInternalServerError, Body: err.Error()}
+	}
+	call := func() error {
+		text, usage, err := p.send(ctx, req)
+		if err != nil {
+			return err
+		}
+		*resp = openai.ChatCompletionResponse{
+			Object: "chat.completion",
+			Model:  req.Model,
+			Usage:  usage,
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: text},
+				FinishReason: openai.FinishReasonStop,
+			}},
+		}
+		return nil
+	}
+	if p.cassette == nil {
+		return call()
+	}
+	return p.cassette.Do(p.name+"_chat", &req, resp, call)
+}
+
+// postJSON 发送 JSON 请求并解析 JSON 响应，非 2xx 响应返回 ProviderError
+func postJSON(ctx context.Context, provider, url string, headers map[string]string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &ProviderError{Provider: provider, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("解析 %s 响应失败: %v", provider, err)
+	}
+	return nil
+}
+
+// chatTurn 转换后的一条对话消息
+type chatTurn struct {
+	Role    string
+	Content string
+}
+
+// splitMessages 将 OpenAI 格式的消息拆分为系统提示和对话轮次
+// 工具和函数结果作为用户消息，相邻的同角色消息合并，以满足要求角色交替的服务商
+func splitMessages(messages []openai.ChatCompletionMessage) (string, []chatTurn) {
+	var (
+		system []string
+		turns  []chatTurn
+	)
+	for _, m := range messages {
+		role := openai.ChatMessageRoleUser
+		switch m.Role {
+		case openai.ChatMessageRoleSystem:
+			system = append(system, m.Content)
+			continue
+		case openai.ChatMessageRoleAssistant:
+			role = openai.ChatMessageRoleAssistant
+		}
+		if n := len(turns); n > 0 && turns[n-1].Role == role {
+			turns[n-1].Content += "\n\n" + m.Content
+			continue
+		}
+		turns = append(turns, chatTurn{Role: role, Content: m.Content})
+	}
+	return strings.Join(system, "\n\n"), turns
+}
+
+// wantsJSON 请求是否要求 JSON 对象格式的响应（json_response 钩子）
+func wantsJSON(req openai.ChatCompletionRequest) bool {
+	return req.ResponseFormat != nil && req.ResponseFormat.Type == openai.ChatCompletionResponseFormatTypeJSONObject
+}
@@ -0,0 +1,175 @@
+package llms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+var providerTestMessages = []openai.ChatCompletionMessage{
+	{Role: openai.ChatMessageRoleSystem, Content: "You are an ops assistant."},
+	{Role: openai.ChatMessageRoleUser, Content: "Why is payment-api restarting?"},
+	{Role: openai.ChatMessageRoleAssistant, Content: "Let me check."},
+	{Role: openai.ChatMessageRoleUser, Content: "Observation: OOMKilled"},
+	{Role: openai.ChatMessageRoleUser, Content: "Continue."},
+}
+
+func TestNormalizeProvider(t *testing.T) {
+	for input, want := range map[string]string{"": ProviderOpenAI, "azure": ProviderOpenAI, "Claude": ProviderAnthropic, "anthropic": ProviderAnthropic, "google": ProviderGemini, " ollama ": ProviderOllama} {
+		if got := NormalizeProvider(input); got != want {
+			t.Errorf("NormalizeProvider(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSplitMessages(t *testing.T) {
+	system, turns := splitMessages(providerTestMessages)
+	if system != "You are an ops assistant." {
+		t.Errorf("system = %q", system)
+	}
+	if len(turns) != 3 || turns[2].Role != openai.ChatMessageRoleUser || turns[2].Content != "Observation: OOMKilled\n\nContinue." {
+		t.Errorf("turns = %+v, want consecutive user messages merged", turns)
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("x-api-key") != "sk-ant" || r.Header.Get("anthropic-version") == "" {
+			t.Errorf("unexpected request %s, headers %v", r.URL.Path, r.Header)
+		}
+		var req anthropicRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.System != "You are an ops assistant." || len(req.Messages) != 3 || req.MaxTokens != defaultAnthropicMaxTokens {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"It was OOMKilled."}],"stop_reason":"end_turn"}`))
+	}))
+	defer srv.Close()
+
+	provider, err := newAnthropicProvider("sk-ant", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := provider.Chat("claude-sonnet", 0, providerTestMessages)
+	if err != nil || got != "It was OOMKilled." {
+		t.Errorf("Chat() = %q, %v", got, err)
+	}
+}
+
+func TestGeminiProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-pro:generateContent" || r.Header.Get("x-goog-api-key") != "g-key" {
+			t.Errorf("unexpected request %s, headers %v", r.URL.Path, r.Header)
+		}
+		var req geminiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.SystemInstruction == nil || len(req.Contents) != 3 || req.Contents[1].Role != "model" {
+			t.Errorf("unexpected request body %+v", req)
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"text":"It was "},{"text":"OOMKilled."}]},"finishReason":"STOP"}]}`))
+	}))
+	defer srv.Close()
+
+	provider, err := newGeminiProvider("g-key", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := provider.Chat("gemini-pro", 1024, providerTestMessages)
+	if err != nil || got != "It was OOMKilled." {
+		t.Errorf("Chat() = %q, %v", got, err)
+	}
+}
+
+func TestOllamaProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ollamaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if r.URL.Path != "/api/chat" || req.Stream || len(req.Messages) != len(providerTestMessages) {
+			t.Errorf("unexpected request %s %+v", r.URL.Path, req)
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"It was OOMKilled."}}`))
+	}))
+	defer srv.Close()
+
+	got, err := newOllamaProvider(srv.URL).Chat("qwen2.5", 0, providerTestMessages)
+	if err != nil || got != "It was OOMKilled." {
+		t.Errorf("Chat() = %q, %v", got, err)
+	}
+}
+
+func TestProviderErrorNotRetried(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := newOllamaProvider(srv.URL).Chat("qwen2.5", 0, providerTestMessages)
+	if providerErr, ok := err.(*ProviderError); !ok || providerErr.StatusCode != http.StatusUnauthorized || calls != 1 {
+		t.Errorf("Chat() error = %v after %d calls, want a single 401 ProviderError", err, calls)
+	}
+}
+
+func TestBaseURLAllowed(t *testing.T) {
+	var configuredHits, attackerHits int
+	configured := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		configuredHits++
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer configured.Close()
+	attacker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attackerHits++
+		if r.Header.Get("Authorization") == "Bearer server-key" {
+			t.Error("server key sent to a caller-supplied base URL")
+		}
+	}))
+	defer attacker.Close()
+
+	config := utils.GetConfig()
+	config.Set("llm.providers", map[string]interface{}{
+		"openai": map[string]interface{}{"api_key": "server-key", "base_url": configured.URL + "/v1"},
+	})
+	config.Set("llm.allowed_base_urls", []string{"https://gateway.example.com/v1/"})
+	defer func() {
+		config.Set("llm.providers", nil)
+		config.Set("llm.allowed_base_urls", nil)
+	}()
+
+	tests := []struct {
+		baseURL string
+		want    bool
+	}{
+		{"", true},
+		{configured.URL + "/v1/", true},
+		{"https://gateway.example.com/v1", true},
+		{attacker.URL + "/v1", false},
+	}
+	for _, tt := range tests {
+		if got := BaseURLAllowed(tt.baseURL); got != tt.want {
+			t.Errorf("BaseURLAllowed(%q) = %v, want %v", tt.baseURL, got, tt.want)
+		}
+	}
+
+	// 使用配置的密钥时忽略不在允许列表中的地址，请求发送到配置的服务商地址
+	provider, err := NewProvider("openai", "", attacker.URL+"/v1")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if _, err := provider.Chat("qwen-max", 16, providerTestMessages); err != nil {
+		t.Fatalf("Chat() error = %v", err)
+	}
+	if configuredHits != 1 || attackerHits != 0 {
+		t.Errorf("configured hits = %d, attacker hits = %d, want 1 and 0", configuredHits, attackerHits)
+	}
+}
@@ -0,0 +1,76 @@
+package llms
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Review 审阅模型对回答草稿的结论
+type Review struct {
+	Approved bool   `json:"approved"`
+	Critique string `json:"critique"`
+}
+
+const reviewSystemPrompt = `You review answers drafted by a Kubernetes operations assistant before they are delivered to the user.
+Check the draft strictly against the tool observations: every resource name, number, status and conclusion must be supported by them,
+nothing important in the observations may be contradicted or left out, and the answer must actually address the question.
+Do not ask for information the observations do not contain and do not rewrite the answer yourself.
+Reply with JSON only: {"approved": true or false, "critique": "<concrete problems to fix, empty when approved>"}`
+
+const reviseSystemPrompt = `You are a Kubernetes operations assistant. A reviewer found problems in your drafted answer.
+Rewrite the answer so that it fixes the problems, using only facts from the tool observations. Keep the language, markdown structure
+and commands of the draft where they are correct. Output only the revised answer.`
+
+// ReviewAnswer 由审阅模型对照工具观察结果检查回答草稿
+// evidence 为交互中的工具调用及其观察结果
+func ReviewAnswer(provider Provider, model, question, draft, evidence string) (Review, error) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: reviewSystemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: reviewMaterial(question, draft, evidence)},
+	}
+	content, err := provider.Chat(model, 0, messages)
+	if err != nil {
+		return Review{}, err
+	}
+	return parseReview(content)
+}
+
+// ReviseAnswer 按审阅意见修改回答草稿
+func ReviseAnswer(provider Provider, model, question, draft, critique, evidence string) (string, error) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: reviseSystemPrompt},
+		{Role: openai.ChatMessageRoleUser, Content: reviewMaterial(question, draft, evidence) + "\n\n## Reviewer critique\n" + critique},
+	}
+	revised, err := provider.Chat(model, 0, messages)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(revised), nil
+}
+
+func reviewMaterial(question, draft, evidence string) string {
+	if strings.TrimSpace(evidence) == "" {
+		evidence = "(no tools were called)"
+	}
+	return fmt.Sprintf("## Question\n%s\n\n## Tool observations\n%s\n\n## Draft answer\n%s", question, evidence, draft)
+}
+
+// parseReview 解析审阅结论，容忍 JSON 前后的说明文字和代码块
+func parseReview(content string) (Review, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return Review{}, fmt.Errorf("审阅结果不是 JSON: %s", content)
+	}
+	var review Review
+	if err := json.Unmarshal([]byte(content[start:end+1]), &review); err != nil {
+		return Review{}, fmt.Errorf("解析审阅结果失败: %v", err)
+	}
+	review.Critique = strings.TrimSpace(review.Critique)
+	if !review.Approved && review.Critique == "" {
+		return Review{}, fmt.Errorf("审阅未通过但没有给出意见")
+	}
+	return review, nil
+}
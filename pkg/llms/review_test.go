@@ -0,0 +1,59 @@
+package llms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// scriptedProvider 按顺序返回预设的回复，并记录收到的消息
+type scriptedProvider struct {
+	replies  []string
+	received [][]openai.ChatCompletionMessage
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+func (p *scriptedProvider) Chat(model string, maxTokens int, messages []openai.ChatCompletionMessage) (string, error) {
+	p.received = append(p.received, messages)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return reply, nil
+}
+
+func TestReviewAndReviseAnswer(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		"```json\n{\"approved\": false, \"critique\": \"The pod restarted 7 times, not 3.\"}\n```",
+		"payment-api restarted 7 times because it was OOMKilled.",
+	}}
+	evidence := "### 1. kubectl: get pod payment-api\npayment-api 0/1 CrashLoopBackOff 7"
+	draft := "payment-api restarted 3 times because it was OOMKilled."
+
+	review, err := ReviewAnswer(provider, "reviewer", "why does payment-api restart?", draft, evidence)
+	if err != nil || review.Approved || review.Critique != "The pod restarted 7 times, not 3." {
+		t.Fatalf("ReviewAnswer() = %+v, %v", review, err)
+	}
+	if content := provider.received[0][1].Content; !strings.Contains(content, evidence) || !strings.Contains(content, draft) {
+		t.Errorf("review material is missing the evidence or the draft: %q", content)
+	}
+
+	revised, err := ReviseAnswer(provider, "gpt-4o", "why does payment-api restart?", draft, review.Critique, evidence)
+	if err != nil || revised != "payment-api restarted 7 times because it was OOMKilled." {
+		t.Errorf("ReviseAnswer() = %q, %v", revised, err)
+	}
+	if content := provider.received[1][1].Content; !strings.Contains(content, review.Critique) {
+		t.Errorf("revision request is missing the critique: %q", content)
+	}
+}
+
+func TestParseReview(t *testing.T) {
+	if review, err := parseReview(`{"approved": true, "critique": ""}`); err != nil || !review.Approved {
+		t.Errorf("parseReview() = %+v, %v", review, err)
+	}
+	for _, content := range []string{"looks good", `{"approved": false}`, `{"approved": "yes"}`} {
+		if _, err := parseReview(content); err == nil {
+			t.Errorf("parseReview(%q) expected error", content)
+		}
+	}
+}
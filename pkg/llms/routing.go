@@ -0,0 +1,92 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+// RoutingDecision 记录一次模型路由的决策结果
+type RoutingDecision struct {
+	OriginalModel string // 请求指定的模型
+	Model         string // 实际使用的模型
+	PromptTokens  int    // 组装后提示的 token 数
+	Threshold     int    // 触发路由的 token 阈值
+	Routed        bool   // 是否切换到了长上下文模型
+	Denied        bool   // 超过阈值但长上下文模型不在允许列表中，保留原模型
+}
+
+// EstimateTokens 估算消息的 token 数
+// 优先使用 tiktoken 计数，当模型编码不可用（如自建模型或离线环境）时按字符数粗略估算
+func EstimateTokens(messages []openai.ChatCompletionMessage, model string) int {
+	if n := NumTokensFromMessages(messages, model); n > 0 {
+		return n
+	}
+
+	total := 3
+	for _, message := range messages {
+		// 中英文混合文本大约每 2 个字符一个 token，偏保守估算
+		total += 3 + utf8.RuneCountInString(message.Content)/2 + utf8.RuneCountInString(message.Role)
+	}
+	return total
+}
+
+// routingThreshold 计算模型触发长上下文路由的阈值
+func routingThreshold(model string, maxTokens int) int {
+	if threshold := utils.GetConfig().GetInt("llm.routing.threshold"); threshold > 0 {
+		return threshold
+	}
+
+	// 未配置阈值时，为补全预留部分上下文窗口（最多四分之一）
+	limit := GetTokenLimits(model)
+	reserve := maxTokens
+	if reserve > limit/4 {
+		reserve = limit / 4
+	}
+	return limit - reserve
+}
+
+// RouteModel 根据组装后的提示长度选择模型
+// 当提示 token 数超过阈值且配置了 llm.routing.long_context_model 时，
+// 请求将被路由到长上下文模型，避免在 8k 模型上出现 context length 错误
+// allow 不为空时校验长上下文模型（如租户的模型允许列表），不允许时保留原模型
+func RouteModel(model string, maxTokens int, messages []openai.ChatCompletionMessage, allow func(model string) error) RoutingDecision {
+	decision := RoutingDecision{
+		OriginalModel: model,
+		Model:         model,
+	}
+
+	longContextModel := utils.GetConfig().GetString("llm.routing.long_context_model")
+	if longContextModel == "" || strings.EqualFold(longContextModel, model) {
+		return decision
+	}
+
+	decision.PromptTokens = EstimateTokens(messages, model)
+	decision.Threshold = routingThreshold(model, maxTokens)
+	if decision.PromptTokens <= decision.Threshold {
+		return decision
+	}
+	if allow != nil && allow(longContextModel) != nil {
+		decision.Denied = true
+		return decision
+	}
+	decision.Model = longContextModel
+	decision.Routed = true
+
+	return decision
+}
@@ -0,0 +1,76 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestRouteModel(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("llm.routing.long_context_model", "qwen-long")
+	config.Set("llm.routing.threshold", 100)
+	defer config.Set("llm.routing.long_context_model", "")
+	defer config.Set("llm.routing.threshold", 0)
+
+	tests := []struct {
+		name      string
+		model     string
+		content   string
+		wantModel string
+	}{
+		{
+			name:      "short prompt",
+			model:     "custom-8k",
+			content:   "hello",
+			wantModel: "custom-8k",
+		},
+		{
+			name:      "long prompt",
+			model:     "custom-8k",
+			content:   strings.Repeat("kubectl get pods ", 100),
+			wantModel: "qwen-long",
+		},
+		{
+			name:      "already long context model",
+			model:     "qwen-long",
+			content:   strings.Repeat("kubectl get pods ", 100),
+			wantModel: "qwen-long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: tt.content}}
+			got := RouteModel(tt.model, 1024, messages, nil)
+			if got.Model != tt.wantModel {
+				t.Errorf("RouteModel() = %v, want %v", got.Model, tt.wantModel)
+			}
+			if got.Routed != (tt.wantModel != tt.model) {
+				t.Errorf("RouteModel() routed = %v", got.Routed)
+			}
+		})
+	}
+
+	// 长上下文模型不在允许列表中时保留原模型
+	messages := []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: strings.Repeat("kubectl get pods ", 100)}}
+	deny := func(model string) error { return fmt.Errorf("model %q is not allowed", model) }
+	if got := RouteModel("custom-8k", 1024, messages, deny); got.Model != "custom-8k" || got.Routed || !got.Denied {
+		t.Errorf("RouteModel() with a denied long context model = %+v", got)
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"math"
 	"strings"
 
+	"github.com/myysophia/OpsAgent/pkg/utils"
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/sashabaranov/go-openai"
+	"github.com/spf13/cast"
 )
 
 var tokenLimitsPerModel = map[string]int{
@@ -46,8 +48,17 @@ var tokenLimitsPerModel = map[string]int{
 }
 
 // GetTokenLimits returns the maximum number of tokens for the given model.
+// Self-hosted (e.g. quantized) models could declare their context window via llm.token_limits.
 func GetTokenLimits(model string) int {
 	model = strings.ToLower(model)
+	for name, limit := range utils.GetConfig().GetStringMap("llm.token_limits") {
+		if strings.EqualFold(name, model) {
+			if maxTokens := cast.ToInt(limit); maxTokens > 0 {
+				return maxTokens
+			}
+		}
+	}
+
 	if maxTokens, ok := tokenLimitsPerModel[model]; ok {
 		return maxTokens
 	}
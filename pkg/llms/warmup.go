@@ -0,0 +1,180 @@
+/*
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package llms
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+const defaultWarmupTimeout = 10 * time.Second
+
+// WarmupResult 单个端点的预热结果
+type WarmupResult struct {
+	Endpoint string        `json:"endpoint"`
+	DNS      time.Duration `json:"dns"`
+	Connect  time.Duration `json:"connect"`
+	Ping     time.Duration `json:"ping,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// WarmUp 预热配置的 LLM 端点：预解析 DNS、建立 TLS 连接并放入共享连接池，
+// 可选地发送一次极小的补全请求，以消除部署后首个请求的延迟尖峰
+func WarmUp(ctx context.Context) []WarmupResult {
+	if !utils.GetConfig().GetBool("llm.warmup.enabled") {
+		return nil
+	}
+
+	results := CheckEndpoints(ctx)
+	if len(results) == 0 {
+		utils.Info("未配置 llm.warmup.endpoints 或 llm.providers.<name>.base_url，跳过 LLM 端点预热")
+	}
+	for _, result := range results {
+		if result.Error != "" {
+			utils.Warn("LLM 端点预热失败",
+				zap.String("endpoint", result.Endpoint),
+				zap.String("error", result.Error),
+			)
+			continue
+		}
+		utils.Info("LLM 端点预热完成",
+			zap.String("endpoint", result.Endpoint),
+			zap.Duration("dns", result.DNS),
+			zap.Duration("connect", result.Connect),
+			zap.Duration("ping", result.Ping),
+		)
+	}
+	return results
+}
+
+// CheckEndpoints 检查配置的 LLM 端点的 DNS 解析、连接以及可选的补全请求，
+// 不受 llm.warmup.enabled 影响，供启动预热和 doctor 命令使用
+// 未配置 llm.warmup.endpoints 时检查 llm.providers 中配置的服务商地址，都未配置时不检查任何端点
+func CheckEndpoints(ctx context.Context) []WarmupResult {
+	config := utils.GetConfig()
+	endpoints := config.GetStringSlice("llm.warmup.endpoints")
+	if len(endpoints) == 0 {
+		endpoints = providerEndpoints()
+	}
+	timeout := config.GetDuration("llm.warmup.timeout")
+	if timeout <= 0 {
+		timeout = defaultWarmupTimeout
+	}
+
+	results := make([]WarmupResult, len(endpoints))
+	var wg sync.WaitGroup
+	for i, endpoint := range endpoints {
+		wg.Add(1)
+		go func(i int, endpoint string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			results[i] = warmUpEndpoint(ctx, endpoint, config.GetString("llm.warmup.ping_model"))
+		}(i, endpoint)
+	}
+	wg.Wait()
+	return results
+}
+
+// providerEndpoints 返回 llm.providers.<name>.base_url 和 base_urls 中配置的服务商地址
+func providerEndpoints() []string {
+	config := utils.GetConfig()
+	providers := make([]string, 0)
+	for name := range config.GetStringMap("llm.providers") {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+
+	var endpoints []string
+	for _, name := range providers {
+		for _, endpoint := range append(config.GetStringSlice("llm.providers."+name+".base_urls"), config.GetString("llm.providers."+name+".base_url")) {
+			if endpoint != "" && !slices.Contains(endpoints, endpoint) {
+				endpoints = append(endpoints, endpoint)
+			}
+		}
+	}
+	return endpoints
+}
+
+// warmUpEndpoint 预热单个端点
+func warmUpEndpoint(ctx context.Context, endpoint, pingModel string) WarmupResult {
+	result := WarmupResult{Endpoint: endpoint}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		result.Error = fmt.Sprintf("invalid endpoint: %s", endpoint)
+		return result
+	}
+
+	start := time.Now()
+	if _, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err != nil {
+		result.Error = fmt.Sprintf("dns lookup failed: %v", err)
+		return result
+	}
+	result.DNS = time.Since(start)
+
+	// 任何 HTTP 响应（包括 401/404）都说明连接和 TLS 会话已建立并进入连接池
+	start = time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("connect failed: %v", err)
+		return result
+	}
+	resp.Body.Close()
+	result.Connect = time.Since(start)
+
+	if pingModel == "" {
+		return result
+	}
+
+	apiKey := utils.GetConfig().GetString("llm.api_key")
+	if apiKey == "" {
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+	client, err := NewOpenAIClient(apiKey, endpoint)
+	if err != nil {
+		result.Error = fmt.Sprintf("ping skipped: %v", err)
+		return result
+	}
+
+	start = time.Now()
+	_, err = client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model:     pingModel,
+		MaxTokens: 1,
+		Messages:  []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "ping"}},
+	})
+	if err != nil {
+		result.Error = fmt.Sprintf("ping failed: %v", err)
+		return result
+	}
+	result.Ping = time.Since(start)
+	return result
+}
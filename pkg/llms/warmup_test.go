@@ -0,0 +1,30 @@
+package llms
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestWarmupEndpoints(t *testing.T) {
+	config := utils.GetConfig()
+	defer config.Set("llm.providers", nil)
+
+	// 未配置任何服务商地址时不预热，也不会访问 api.openai.com
+	config.Set("llm.providers", map[string]interface{}{})
+	if results := CheckEndpoints(context.Background()); len(results) != 0 {
+		t.Errorf("CheckEndpoints() without endpoints = %+v, want none", results)
+	}
+
+	config.Set("llm.providers", map[string]interface{}{
+		"qwen":   map[string]interface{}{"base_url": "https://dashscope.example.com/v1"},
+		"openai": map[string]interface{}{"base_urls": []string{"https://llm-a.example.com/v1", "https://llm-b.example.com/v1"}},
+		"ollama": map[string]interface{}{"base_url": "https://llm-a.example.com/v1"},
+	})
+	want := []string{"https://llm-a.example.com/v1", "https://llm-b.example.com/v1", "https://dashscope.example.com/v1"}
+	if got := providerEndpoints(); !reflect.DeepEqual(got, want) {
+		t.Errorf("providerEndpoints() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRecentTurns      = 2
+	defaultTopK             = 3
+	defaultMaxTurns         = 500
+	defaultMaxConversations = 1000
+	defaultConversationTTL  = 7 * 24 * time.Hour
+	maxTurnRunes            = 2000
+)
+
+// ErrConversationNotFound 会话不存在、已过期或属于其他用户
+// 不区分这几种情况，避免泄露其他用户的会话是否存在
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationKey 会话标识，会话归属于第一次使用它的用户和租户
+type ConversationKey struct {
+	User   string
+	Tenant string
+	ID     string
+}
+
+// conversation 会话的归属和最近使用时间
+type conversation struct {
+	user     string
+	tenant   string
+	lastUsed time.Time
+}
+
+// Embedder 文本向量化接口
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// usageEmbedder 可以返回 token 用量的向量化接口
+type usageEmbedder interface {
+	EmbedWithUsage(ctx context.Context, texts []string) ([][]float32, openai.Usage, error)
+}
+
+// Retrieval 一次历史检索的结果，用于审计
+type Retrieval struct {
+	Query        string
+	Context      []string
+	Latency      time.Duration
+	PromptTokens int
+	TotalTokens  int
+	Error        string
+}
+
+// Conversations 基于向量检索的会话历史
+// 长会话不再把完整的线性历史放入提示，而是只保留最近几轮并检索与当前问题最相关的历史轮次
+// 会话只能由创建它的用户和租户访问，超过 memory.ttl 未使用时删除，会话数超过 memory.max_conversations 时删除最久未使用的会话
+type Conversations struct {
+	store         *VectorStore
+	seq           int64
+	conversations map[string]*conversation
+	now           func() time.Time
+	mu            sync.Mutex
+}
+
+var (
+	conversations     *Conversations
+	conversationsOnce sync.Once
+)
+
+// GetConversations 获取全局会话历史
+func GetConversations() *Conversations {
+	conversationsOnce.Do(func() {
+		conversations = NewConversations(NewVectorStore())
+	})
+	return conversations
+}
+
+// NewConversations 创建会话历史
+func NewConversations(store *VectorStore) *Conversations {
+	return &Conversations{store: store, conversations: map[string]*conversation{}, now: time.Now}
+}
+
+// Open 校验当前用户可以访问会话，会话不存在时创建并归属于当前用户和租户
+// 会话属于其他用户或租户时返回 ErrConversationNotFound
+func (c *Conversations) Open(key ConversationKey) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.expireLocked(now)
+	conv, ok := c.conversations[key.ID]
+	if ok && (conv.user != key.User || conv.tenant != key.Tenant) {
+		return ErrConversationNotFound
+	}
+	if ok {
+		conv.lastUsed = now
+		return nil
+	}
+	c.conversations[key.ID] = &conversation{user: key.User, tenant: key.Tenant, lastUsed: now}
+	c.evictLocked(configInt("memory.max_conversations", defaultMaxConversations))
+	return nil
+}
+
+// expireLocked 删除超过 memory.ttl 未使用的会话，需持有 c.mu
+func (c *Conversations) expireLocked(now time.Time) {
+	ttl := utils.GetConfig().GetDuration("memory.ttl")
+	if ttl <= 0 {
+		ttl = defaultConversationTTL
+	}
+	for id, conv := range c.conversations {
+		if now.Sub(conv.lastUsed) > ttl {
+			c.deleteLocked(id)
+		}
+	}
+}
+
+// evictLocked 会话数超过 max 时删除最久未使用的会话，需持有 c.mu
+func (c *Conversations) evictLocked(max int) {
+	for len(c.conversations) > max {
+		oldest := ""
+		for id, conv := range c.conversations {
+			if oldest == "" || conv.lastUsed.Before(c.conversations[oldest].lastUsed) {
+				oldest = id
+			}
+		}
+		c.deleteLocked(oldest)
+	}
+}
+
+func (c *Conversations) deleteLocked(id string) {
+	delete(c.conversations, id)
+	c.store.Delete(id)
+}
+
+// Record 记录一轮对话，向量化失败或 embedder 为 nil 时该轮仍会保留用于最近历史
+// 会话属于其他用户或租户时返回 ErrConversationNotFound
+func (c *Conversations) Record(ctx context.Context, embedder Embedder, key ConversationKey, question, answer string) error {
+	if err := c.Open(key); err != nil {
+		return err
+	}
+	conversationID := key.ID
+	text := formatTurn(question, answer)
+
+	var embedding []float32
+	if embedder != nil {
+		if embeddings, err := embedder.Embed(ctx, []string{text}); err != nil {
+			utils.Warn("会话历史向量化失败",
+				zap.String("conversation_id", conversationID),
+				zap.Error(err),
+			)
+		} else {
+			embedding = embeddings[0]
+		}
+	}
+
+	c.mu.Lock()
+	c.seq++
+	id := fmt.Sprintf("%s-%d", conversationID, c.seq)
+	c.mu.Unlock()
+
+	c.store.Add(Document{
+		ID:        id,
+		Namespace: conversationID,
+		Text:      text,
+		Embedding: embedding,
+		Metadata:  map[string]string{"question": question},
+		CreatedAt: time.Now(),
+	})
+	c.store.Prune(conversationID, configInt("memory.max_turns", defaultMaxTurns))
+	return nil
+}
+
+// History 返回与当前问题相关的历史消息：最近 memory.recent_turns 轮，
+// 加上更早历史中与问题最相关的 memory.top_k 轮，按时间顺序排列
+// 发生向量检索时同时返回检索记录，否则为 nil；会话属于其他用户或租户时返回 ErrConversationNotFound
+func (c *Conversations) History(ctx context.Context, embedder Embedder, key ConversationKey, question string) ([]openai.ChatCompletionMessage, *Retrieval, error) {
+	if err := c.Open(key); err != nil {
+		return nil, nil, err
+	}
+	conversationID := key.ID
+	turns := c.store.List(conversationID)
+	if len(turns) == 0 {
+		return nil, nil, nil
+	}
+	var retrieval *Retrieval
+
+	recent := configInt("memory.recent_turns", defaultRecentTurns)
+	if recent > len(turns) {
+		recent = len(turns)
+	}
+	selected := append([]Document(nil), turns[len(turns)-recent:]...)
+
+	// embedder 为 nil 时（服务商不支持向量化）只使用最近的会话历史
+	if older := len(turns) - recent; older > 0 && embedder != nil {
+		recentIDs := map[string]bool{}
+		for _, doc := range selected {
+			recentIDs[doc.ID] = true
+		}
+
+		retrieval = &Retrieval{Query: question}
+		start := time.Now()
+		var (
+			embeddings [][]float32
+			usage      openai.Usage
+			err        error
+		)
+		if ue, ok := embedder.(usageEmbedder); ok {
+			embeddings, usage, err = ue.EmbedWithUsage(ctx, []string{question})
+		} else {
+			embeddings, err = embedder.Embed(ctx, []string{question})
+		}
+		retrieval.PromptTokens = usage.PromptTokens
+		retrieval.TotalTokens = usage.TotalTokens
+
+		if err != nil {
+			retrieval.Error = err.Error()
+			utils.Warn("问题向量化失败，仅使用最近的会话历史",
+				zap.String("conversation_id", conversationID),
+				zap.Error(err),
+			)
+		} else {
+			results := c.store.Search(conversationID, embeddings[0], configInt("memory.top_k", defaultTopK), func(doc Document) bool {
+				return recentIDs[doc.ID] || len(doc.Embedding) == 0
+			})
+			for _, result := range results {
+				selected = append(selected, result.Document)
+				retrieval.Context = append(retrieval.Context, result.Text)
+			}
+			utils.Debug("检索会话历史",
+				zap.String("conversation_id", conversationID),
+				zap.Int("total_turns", len(turns)),
+				zap.Int("retrieved", len(results)),
+			)
+		}
+		retrieval.Latency = time.Since(start)
+	}
+
+	position := make(map[string]int, len(turns))
+	for i, doc := range turns {
+		position[doc.ID] = i
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return position[selected[i].ID] < position[selected[j].ID]
+	})
+
+	messages := make([]openai.ChatCompletionMessage, 0, len(selected))
+	for _, doc := range selected {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleUser,
+			Content: "Previous conversation turn:\n" + doc.Text,
+		})
+	}
+	return messages, retrieval, nil
+}
+
+// Forget 删除会话历史
+func (c *Conversations) Forget(conversationID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLocked(conversationID)
+}
+
+func formatTurn(question, answer string) string {
+	return fmt.Sprintf("Q: %s\nA: %s", truncateRunes(question, maxTurnRunes), truncateRunes(answer, maxTurnRunes))
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+func configInt(key string, def int) int {
+	if v := utils.GetConfig().GetInt(key); v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// keywordEmbedder 按关键词生成向量，用于测试检索逻辑
+type keywordEmbedder struct {
+	keywords []string
+}
+
+func (e keywordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, len(e.keywords))
+		for j, kw := range e.keywords {
+			if strings.Contains(text, kw) {
+				vec[j] = 1
+			}
+		}
+		embeddings[i] = vec
+	}
+	return embeddings, nil
+}
+
+func TestConversationHistory(t *testing.T) {
+	embedder := keywordEmbedder{keywords: []string{"nginx", "redis", "ingress", "node"}}
+	utils.GetConfig().Set("memory.top_k", 1)
+	c := NewConversations(NewVectorStore())
+	ctx := context.Background()
+	conv := ConversationKey{User: "alice", Tenant: "ops", ID: "conv"}
+
+	c.Record(ctx, embedder, conv, "why is redis crashing", "OOMKilled")
+	c.Record(ctx, embedder, conv, "list ingress", "3 ingresses")
+	c.Record(ctx, embedder, conv, "scale nginx", "scaled to 3")
+	c.Record(ctx, embedder, conv, "check node status", "all ready")
+	c.Record(ctx, embedder, conv, "what about pods", "all running")
+	c.Record(ctx, embedder, ConversationKey{User: "alice", Tenant: "ops", ID: "other"}, "redis memory", "2Gi")
+
+	messages, retrieval, err := c.History(ctx, embedder, conv, "increase redis memory limit")
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if retrieval == nil || len(retrieval.Context) != 1 || !strings.Contains(retrieval.Context[0], "redis crashing") {
+		t.Errorf("unexpected retrieval: %+v", retrieval)
+	}
+
+	var got []string
+	for _, m := range messages {
+		got = append(got, m.Content)
+	}
+	joined := strings.Join(got, "\n")
+
+	// 最近两轮 + 最相关的一轮历史，按时间顺序
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d:\n%s", len(messages), joined)
+	}
+	if !strings.Contains(got[0], "redis crashing") {
+		t.Errorf("expected the most relevant turn first in chronological order, got:\n%s", joined)
+	}
+	if !strings.Contains(got[len(got)-1], "what about pods") {
+		t.Errorf("expected the latest turn last, got:\n%s", joined)
+	}
+	if strings.Contains(joined, "2Gi") {
+		t.Errorf("history leaked across conversations:\n%s", joined)
+	}
+
+	if messages, _, _ := c.History(ctx, embedder, ConversationKey{User: "alice", Tenant: "ops", ID: "missing"}, "anything"); len(messages) != 0 {
+		t.Errorf("expected no history for unknown conversation, got %d", len(messages))
+	}
+}
+
+func TestConversationAccess(t *testing.T) {
+	c := NewConversations(NewVectorStore())
+	ctx := context.Background()
+	owner := ConversationKey{User: "alice", Tenant: "ops", ID: "conv"}
+	if err := c.Record(ctx, nil, owner, "list pods", "3 pods"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	for _, key := range []ConversationKey{
+		{User: "bob", Tenant: "ops", ID: "conv"},
+		{User: "alice", Tenant: "dev", ID: "conv"},
+	} {
+		if _, _, err := c.History(ctx, nil, key, "anything"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("History(%+v) error = %v, want ErrConversationNotFound", key, err)
+		}
+		if err := c.Record(ctx, nil, key, "delete pods", "done"); !errors.Is(err, ErrConversationNotFound) {
+			t.Errorf("Record(%+v) error = %v, want ErrConversationNotFound", key, err)
+		}
+	}
+	if messages, _, err := c.History(ctx, nil, owner, "anything"); err != nil || len(messages) != 1 {
+		t.Errorf("owner History() = %d messages, %v, want 1", len(messages), err)
+	}
+}
+
+func TestConversationExpiry(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("memory.ttl", "1h")
+	config.Set("memory.max_conversations", 2)
+	defer func() {
+		config.Set("memory.ttl", nil)
+		config.Set("memory.max_conversations", nil)
+	}()
+
+	now := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	c := NewConversations(NewVectorStore())
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+	key := func(id string) ConversationKey { return ConversationKey{User: "alice", Tenant: "ops", ID: id} }
+	turns := func(id string) int {
+		messages, _, _ := c.History(ctx, nil, key(id), "anything")
+		return len(messages)
+	}
+
+	for i := 1; i <= 3; i++ {
+		c.Record(ctx, nil, key(fmt.Sprintf("conv%d", i)), "q", "a")
+		now = now.Add(time.Minute)
+	}
+	// 超过 max_conversations 时删除最久未使用的会话
+	if n := len(c.conversations); n != 2 {
+		t.Errorf("conversations = %d, want 2", n)
+	}
+	if turns("conv1") != 0 || turns("conv3") != 1 {
+		t.Errorf("expected conv1 to be evicted and conv3 kept")
+	}
+
+	// 超过 ttl 未使用的会话被删除，其他用户可以重新使用该 ID
+	now = now.Add(2 * time.Hour)
+	if err := c.Open(ConversationKey{User: "bob", Tenant: "ops", ID: "conv3"}); err != nil {
+		t.Errorf("Open() after expiry error = %v", err)
+	}
+	if len(c.store.List("conv3")) != 0 {
+		t.Error("expired conversation history was kept")
+	}
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Document 向量存储中的文档
+type Document struct {
+	ID        string            `json:"id"`
+	Namespace string            `json:"namespace"`
+	Text      string            `json:"text"`
+	Embedding []float32         `json:"-"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// ScoredDocument 带相似度得分的检索结果
+type ScoredDocument struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// VectorStore 内存向量存储，按命名空间隔离文档
+type VectorStore struct {
+	mu   sync.RWMutex
+	docs map[string][]Document
+}
+
+// NewVectorStore 创建内存向量存储
+func NewVectorStore() *VectorStore {
+	return &VectorStore{docs: map[string][]Document{}}
+}
+
+// Add 添加文档
+func (s *VectorStore) Add(doc Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[doc.Namespace] = append(s.docs[doc.Namespace], doc)
+}
+
+// List 按添加顺序返回命名空间内的所有文档
+func (s *VectorStore) List(namespace string) []Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Document(nil), s.docs[namespace]...)
+}
+
+// Search 在命名空间内按余弦相似度返回最相关的 k 个文档，skip 返回 true 的文档不参与检索
+func (s *VectorStore) Search(namespace string, query []float32, k int, skip func(Document) bool) []ScoredDocument {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var results []ScoredDocument
+	for _, doc := range s.docs[namespace] {
+		if skip != nil && skip(doc) {
+			continue
+		}
+		results = append(results, ScoredDocument{Document: doc, Score: cosine(query, doc.Embedding)})
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Prune 只保留命名空间内最新的 max 个文档
+func (s *VectorStore) Prune(namespace string, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if docs := s.docs[namespace]; len(docs) > max {
+		s.docs[namespace] = append([]Document(nil), docs[len(docs)-max:]...)
+	}
+}
+
+// Delete 删除命名空间内的所有文档
+func (s *VectorStore) Delete(namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.docs, namespace)
+}
+
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/users"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// IsAdmin 判断用户是否为管理员：auth.users 中角色为 admin 或在 auth.admins 列表中
+func IsAdmin(username string) bool {
+	return users.RoleOf(username) == users.RoleAdmin
+}
+
+// CurrentRole 返回当前请求用户的角色，优先使用令牌中的角色声明，需在 JWTAuth 之后使用
+func CurrentRole(c *gin.Context) users.Role {
+	if role := users.Role(c.GetString("role")); role.Valid() {
+		return role
+	}
+	return users.RoleOf(c.GetString("username"))
+}
+
+// RequireRole 角色权限校验中间件，当前用户的角色不低于 min 时放行，需在 JWTAuth 之后使用
+func RequireRole(min users.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if role := CurrentRole(c); !role.AtLeast(min) {
+			utils.Warn("用户角色无权访问接口",
+				zap.String("username", c.GetString("username")),
+				zap.String("role", string(role)),
+				zap.String("required", string(min)),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Role " + string(min) + " or higher required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// AdminOnly 管理员权限校验中间件，需在 JWTAuth 之后使用
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username := c.GetString("username")
+		if !CurrentRole(c).AtLeast(users.RoleAdmin) {
+			utils.Warn("非管理员访问管理接口",
+				zap.String("username", username),
+				zap.String("path", c.Request.URL.Path),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin privileges required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/apikeys"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// APIKeyScope 托管 API Key 权限范围校验中间件
+// 仅在 apikeys.enabled 为 true 时生效，否则保持旧的 X-API-Key 转发行为
+func APIKeyScope(scope apikeys.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !apikeys.Enabled() {
+			c.Next()
+			return
+		}
+
+		key, ok := apikeys.GetStore().Authenticate(c.GetHeader("X-API-Key"))
+		if !ok {
+			utils.Warn("API Key 无效", zap.String("path", c.Request.URL.Path))
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API Key"})
+			return
+		}
+
+		if !key.HasScope(scope) {
+			utils.Warn("API Key 权限不足",
+				zap.String("key_id", key.ID),
+				zap.String("scope", string(scope)),
+			)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API Key does not have the required scope"})
+			return
+		}
+
+		c.Set("apiKeyID", key.ID)
+		if key.Tenant != "" {
+			c.Set("tenant", key.Tenant)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Envelope /api/v2 统一响应格式
+// code 为 0 表示成功，否则为 HTTP 状态码；成功时 data 为原响应体，失败时 message 为错误信息
+type Envelope struct {
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id"`
+}
+
+// envelopeWriter 缓存处理器写出的响应体，请求结束后再改写为统一响应格式
+// SSE 响应（Content-Type 为 text/event-stream）直接写出，不缓存
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *envelopeWriter) streaming() bool {
+	return strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream")
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	if w.streaming() {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	if w.streaming() {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+// ResponseEnvelope 将处理器的 JSON 响应包装为 Envelope，处理器无需修改
+// WebSocket 升级请求、SSE 和非 JSON 响应（如 Prometheus 指标）保持原样
+func ResponseEnvelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &envelopeWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		body := writer.body.Bytes()
+		if len(body) == 0 || !strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+			original.Write(body)
+			return
+		}
+
+		envelope, err := wrapResponse(original.Status(), body, GetRequestID(c))
+		if err != nil {
+			ContextLogger(c).Warn("响应无法包装为统一格式，按原样返回",
+				zap.String("path", c.Request.URL.Path),
+				zap.Error(err),
+			)
+			original.Write(body)
+			return
+		}
+		original.Header().Del("Content-Length")
+		original.Write(envelope)
+	}
+}
+
+// wrapResponse 将原 JSON 响应体改写为 Envelope
+// 失败响应的 error 字段作为 message，其余字段（如 issues、retry_after）保留在 data 中
+func wrapResponse(status int, body []byte, requestID string) ([]byte, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, err
+	}
+
+	envelope := Envelope{Message: "ok", Data: data, RequestID: requestID}
+	if status >= http.StatusBadRequest {
+		envelope.Code = status
+		envelope.Message = http.StatusText(status)
+		envelope.Data = nil
+		if fields, ok := data.(map[string]interface{}); ok {
+			if message, ok := fields["error"].(string); ok {
+				envelope.Message = message
+				delete(fields, "error")
+			}
+			if len(fields) > 0 {
+				envelope.Data = fields
+			}
+		}
+	}
+	return json.Marshal(envelope)
+}
+
+// Deprecated 标记旧版接口已弃用：响应中添加 Deprecation 头和指向新版接口的 Link 头，
+// 配置 api.legacy.sunset（YYYY-MM-DD）时添加 Sunset 头告知下线日期
+// successor 为新版接口的路径前缀，例如旧版 /api 对应 /api/v2
+func Deprecated(prefix, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		if path := c.Request.URL.Path; strings.HasPrefix(path, prefix) {
+			c.Header("Link", "<"+successor+strings.TrimPrefix(path, prefix)+`>; rel="successor-version"`)
+		}
+		if sunset := utils.GetConfig().GetString("api.legacy.sunset"); sunset != "" {
+			if t, err := time.Parse("2006-01-02", sunset); err == nil {
+				c.Header("Sunset", t.UTC().Format(http.TimeFormat))
+			}
+		}
+		utils.GetPerfStats().IncrCounter("api_deprecated_requests")
+		c.Next()
+	}
+}
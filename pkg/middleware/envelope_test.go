@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResponseEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	v2 := r.Group("/api/v2", ResponseEnvelope())
+	v2.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"version": "v1.0.18"})
+	})
+	v2.GET("/quota", func(c *gin.Context) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "quota exceeded", "retry_after": 30})
+	})
+	v2.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("event: final_answer\ndata: {}\n\n")
+		c.Writer.Flush()
+	})
+	r.GET("/api/version", Deprecated("/api", "/api/v2"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"version": "v1.0.18"})
+	})
+
+	get := func(path string) (*httptest.ResponseRecorder, Envelope) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(RequestIDHeader, "req-1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var envelope Envelope
+		json.Unmarshal(w.Body.Bytes(), &envelope)
+		return w, envelope
+	}
+
+	w, envelope := get("/api/v2/version")
+	data, _ := envelope.Data.(map[string]interface{})
+	if w.Code != http.StatusOK || envelope.Code != 0 || envelope.RequestID != "req-1" || data["version"] != "v1.0.18" {
+		t.Errorf("success envelope = %d %+v", w.Code, envelope)
+	}
+
+	w, envelope = get("/api/v2/quota")
+	data, _ = envelope.Data.(map[string]interface{})
+	if w.Code != http.StatusTooManyRequests || envelope.Code != http.StatusTooManyRequests ||
+		envelope.Message != "quota exceeded" || data["retry_after"] != float64(30) {
+		t.Errorf("error envelope = %d %+v", w.Code, envelope)
+	}
+
+	// SSE 响应不包装
+	w, _ = get("/api/v2/stream")
+	if body := w.Body.String(); body != "event: final_answer\ndata: {}\n\n" || !w.Flushed {
+		t.Errorf("stream body = %q, flushed %v", body, w.Flushed)
+	}
+
+	w, _ = get("/api/version")
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Link") != `</api/v2/version>; rel="successor-version"` {
+		t.Errorf("legacy headers = %v", w.Header())
+	}
+	if body := w.Body.String(); body != `{"version":"v1.0.18"}` {
+		t.Errorf("legacy body = %s, want unchanged", body)
+	}
+}
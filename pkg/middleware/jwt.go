@@ -3,14 +3,17 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/myysophia/OpsAgent/pkg/users"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 	"go.uber.org/zap"
 	"net/http"
+	"strings"
 )
 
 // Claims JWT 声明结构
 type Claims struct {
 	Username string `json:"username"`
+	Role     string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -19,6 +22,10 @@ func JWTAuth() gin.HandlerFunc {
 	logger := utils.GetLogger().Named("jwt")
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
+		// 浏览器无法为 WebSocket 握手设置请求头，允许通过 token 查询参数传递令牌
+		if tokenString == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			tokenString = c.Query("token")
+		}
 		if tokenString == "" {
 			utils.Error("缺少授权令牌")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing authorization token"})
@@ -59,6 +66,13 @@ func JWTAuth() gin.HandlerFunc {
 
 		utils.Debug("令牌验证成功", zap.String("username", claims.Username))
 		c.Set("username", claims.Username)
+		// 升级前签发的令牌没有角色声明，按用户配置确定角色
+		role := users.Role(claims.Role)
+		if !role.Valid() {
+			role = users.RoleOf(claims.Username)
+		}
+		c.Set("role", string(role))
+		WithLogFields(c, zap.String(utils.LogFieldUser, claims.Username))
 		c.Next()
 	}
 }
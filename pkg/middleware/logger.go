@@ -50,13 +50,17 @@ func RequestLogger() gin.HandlerFunc {
 }
 
 // Logger 注入 logger 到 Gin 上下文
+// 请求携带 X-Session-ID 时，日志自动带上 session 字段
 func Logger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 获取全局 logger
 		logger := utils.GetLogger()
+		if session := c.GetHeader("X-Session-ID"); session != "" {
+			logger = logger.With(zap.String(utils.LogFieldSession, session))
+		}
 
 		// 注入 logger 到上下文
-		c.Set("logger", logger)
+		setContextLogger(c, logger)
 
 		// 记录请求信息
 		logger.Debug("收到请求",
@@ -68,3 +72,27 @@ func Logger() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ContextLogger 获取请求级日志记录器，已包含 user、session 等请求字段
+func ContextLogger(c *gin.Context) *zap.Logger {
+	if value, ok := c.Get("logger"); ok {
+		if logger, ok := value.(*zap.Logger); ok {
+			return logger
+		}
+	}
+	return utils.LoggerFromContext(c.Request.Context())
+}
+
+// WithLogFields 为请求级日志记录器追加字段，后续处理器和通过请求上下文
+// 传递到 assistants、tools 的日志都会带上这些字段
+func WithLogFields(c *gin.Context, fields ...zap.Field) *zap.Logger {
+	logger := ContextLogger(c).With(fields...)
+	setContextLogger(c, logger)
+	return logger
+}
+
+// setContextLogger 同时写入 Gin 上下文和请求上下文
+func setContextLogger(c *gin.Context, logger *zap.Logger) {
+	c.Set("logger", logger)
+	c.Request = c.Request.WithContext(utils.WithLogger(c.Request.Context(), logger))
+}
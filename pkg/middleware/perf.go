@@ -36,3 +36,18 @@ func PerfStats() gin.HandlerFunc {
 		perfStats.RecordMetric(c.Request.URL.Path, duration)
 	}
 }
+
+// Metrics 记录 HTTP 请求数和耗时，导出为 Prometheus 指标
+// 按路由模板统计，未匹配路由的请求统一记为 unmatched
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		utils.RecordHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
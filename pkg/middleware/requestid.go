@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求 ID 头，客户端可以自带，否则由服务端生成
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID 为每个请求分配请求 ID：写入响应头、Gin 上下文（request_id）和请求级日志
+// 需在 Logger 之后使用，以便日志字段附加到请求级日志记录器上
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		c.Set("request_id", id)
+		c.Header(RequestIDHeader, id)
+		WithLogFields(c, zap.String(utils.LogFieldRequest, id))
+		c.Next()
+	}
+}
+
+// GetRequestID 获取当前请求的请求 ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("request_id")
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
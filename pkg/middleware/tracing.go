@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.uber.org/zap"
+)
+
+// Tracing 为每个请求创建根 span，请求携带 traceparent 头时作为上游 trace 的子 span
+// span 写入请求上下文，assistant、LLM 调用、工具调用和审计写入的 span 都挂在其下
+// 需在 RequestID 之后使用，trace ID 会附加到请求级日志
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		ctx, span := utils.StartSpan(ctx, c.Request.Method+" "+route,
+			semconv.HTTPRequestMethodKey.String(c.Request.Method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(c.Request.URL.Path),
+			attribute.String(utils.LogFieldRequest, GetRequestID(c)),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		if traceID := utils.TraceIDFromContext(ctx); traceID != "" {
+			WithLogFields(c, zap.String(utils.LogFieldTrace, traceID))
+		}
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if username := c.GetString("username"); username != "" {
+			span.SetAttributes(attribute.String(utils.LogFieldUser, username))
+		}
+		if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Button 卡片按钮，点击后在钉钉/企业微信内置浏览器中打开 URL
+type Button struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// Card 带按钮的交互卡片，用于审批等需要群内成员操作的通知
+type Card struct {
+	Title   string   `json:"title"`
+	Text    string   `json:"text"`
+	Level   string   `json:"level"`
+	URL     string   `json:"url,omitempty"` // 点击卡片本身打开的链接，企业微信卡片必填
+	Buttons []Button `json:"buttons"`
+}
+
+// SendCard 发送交互卡片，name 为空时发送到所有渠道
+func (n *Notifier) SendCard(ctx context.Context, name string, card Card) error {
+	var errs []error
+	found := false
+	for _, channel := range n.channels {
+		if name != "" && channel.Name != name {
+			continue
+		}
+		found = true
+		if err := n.postJSON(ctx, channel, cardPayload(channel.Type, card)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !found && name != "" {
+		return fmt.Errorf("未配置通知渠道 %s", name)
+	}
+	return errors.Join(errs...)
+}
+
+// cardPayload 按渠道类型构造卡片请求体：钉钉使用 actionCard，企业微信使用 template_card 文本通知卡片
+func cardPayload(channelType string, card Card) interface{} {
+	switch channelType {
+	case TypeDingTalk:
+		btns := make([]map[string]string, 0, len(card.Buttons))
+		for _, b := range card.Buttons {
+			btns = append(btns, map[string]string{"title": b.Label, "actionURL": b.URL})
+		}
+		return map[string]interface{}{
+			"msgtype": "actionCard",
+			"actionCard": map[string]interface{}{
+				"title":          card.Title,
+				"text":           fmt.Sprintf("### [%s] %s\n\n%s", card.Level, card.Title, card.Text),
+				"btnOrientation": "1",
+				"btns":           btns,
+			},
+		}
+	case TypeWeCom:
+		// 企业微信群机器人的卡片只支持跳转链接，最多 3 个
+		jumps := make([]map[string]interface{}, 0, len(card.Buttons))
+		for i, b := range card.Buttons {
+			if i == 3 {
+				break
+			}
+			jumps = append(jumps, map[string]interface{}{"type": 1, "title": b.Label, "url": b.URL})
+		}
+		templateCard := map[string]interface{}{
+			"card_type":      "text_notice",
+			"main_title":     map[string]string{"title": card.Title, "desc": card.Level},
+			"sub_title_text": card.Text,
+			"jump_list":      jumps,
+		}
+		if card.URL != "" {
+			templateCard["card_action"] = map[string]interface{}{"type": 1, "url": card.URL}
+		}
+		return map[string]interface{}{
+			"msgtype":       "template_card",
+			"template_card": templateCard,
+		}
+	default:
+		return card
+	}
+}
@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 通知级别
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// 渠道类型
+const (
+	TypeWebhook  = "webhook"  // 以 JSON 格式 POST Message
+	TypeDingTalk = "dingtalk" // 钉钉群机器人
+	TypeWeCom    = "wecom"    // 企业微信群机器人
+)
+
+// Message 通知内容
+type Message struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	Level string `json:"level"`
+}
+
+// Channel 通知渠道，通过 notify.channels 配置
+type Channel struct {
+	Name string `mapstructure:"name" json:"name"`
+	Type string `mapstructure:"type" json:"type"` // 为空时为 webhook
+	URL  string `mapstructure:"url" json:"-"`
+}
+
+// Notifier 向配置的渠道发送通知
+type Notifier struct {
+	channels []Channel
+	client   *http.Client
+}
+
+var (
+	defaultNotifier *Notifier
+	defaultOnce     sync.Once
+)
+
+// Default 获取根据配置创建的全局通知器
+func Default() *Notifier {
+	defaultOnce.Do(func() {
+		var channels []Channel
+		if err := utils.GetConfig().UnmarshalKey("notify.channels", &channels); err != nil {
+			utils.Warn("解析通知渠道配置失败", zap.Error(err))
+		}
+		client, err := utils.NewHTTPClient("notify")
+		if err != nil {
+			utils.Warn("创建通知 HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = &http.Client{Timeout: 10 * time.Second}
+		}
+		defaultNotifier = New(channels, client)
+	})
+	return defaultNotifier
+}
+
+// New 创建通知器
+func New(channels []Channel, client *http.Client) *Notifier {
+	return &Notifier{channels: channels, client: client}
+}
+
+// Enabled 是否配置了通知渠道
+func (n *Notifier) Enabled() bool {
+	return len(n.channels) > 0
+}
+
+// Channels 返回配置的渠道
+func (n *Notifier) Channels() []Channel {
+	return append([]Channel(nil), n.channels...)
+}
+
+// Send 向所有渠道发送通知
+func (n *Notifier) Send(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, channel := range n.channels {
+		if err := n.post(ctx, channel, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SendTo 向指定名称的渠道发送通知
+func (n *Notifier) SendTo(ctx context.Context, name string, msg Message) error {
+	for _, channel := range n.channels {
+		if channel.Name == name {
+			return n.post(ctx, channel, msg)
+		}
+	}
+	return fmt.Errorf("未配置通知渠道 %s", name)
+}
+
+func (n *Notifier) post(ctx context.Context, channel Channel, msg Message) error {
+	return n.postJSON(ctx, channel, payload(channel.Type, msg))
+}
+
+// postJSON 以 JSON 格式向渠道发送请求体
+func (n *Notifier) postJSON(ctx context.Context, channel Channel, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("通知渠道 %s: %v", channel.Name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("通知渠道 %s: %v", channel.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("通知渠道 %s: HTTP %d", channel.Name, resp.StatusCode)
+	}
+	return nil
+}
+
+// payload 按渠道类型构造请求体
+func payload(channelType string, msg Message) interface{} {
+	text := fmt.Sprintf("### [%s] %s\n\n%s", msg.Level, msg.Title, msg.Text)
+	switch channelType {
+	case TypeDingTalk:
+		return map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": msg.Title, "text": text},
+		}
+	case TypeWeCom:
+		return map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": text},
+		}
+	default:
+		return msg
+	}
+}
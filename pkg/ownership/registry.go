@@ -0,0 +1,115 @@
+package ownership
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// Team 负责团队及其联系方式
+type Team struct {
+	Name       string `mapstructure:"-" json:"name"`
+	Contact    string `mapstructure:"contact" json:"contact,omitempty"`       // 日常联系人或群
+	Escalation string `mapstructure:"escalation" json:"escalation,omitempty"` // 升级联系人
+	Channel    string `mapstructure:"channel" json:"channel,omitempty"`       // notify.channels 中的渠道名称
+}
+
+// Service 服务与团队的归属关系
+// Name、Namespace、Cluster 支持通配符，Namespace、Cluster 为空时匹配全部
+type Service struct {
+	Name      string `mapstructure:"name" json:"name"`
+	Namespace string `mapstructure:"namespace" json:"namespace,omitempty"`
+	Cluster   string `mapstructure:"cluster" json:"cluster,omitempty"`
+	Team      string `mapstructure:"team" json:"team"`
+}
+
+// Registry 服务 → 团队 → 联系方式/通知渠道 的归属登记
+type Registry struct {
+	teams    map[string]Team
+	services []Service
+}
+
+// Load 从配置 ownership.teams 和 ownership.services 加载归属登记
+func Load() *Registry {
+	config := utils.GetConfig()
+
+	teams := map[string]Team{}
+	if err := config.UnmarshalKey("ownership.teams", &teams); err != nil {
+		utils.Error(fmt.Sprintf("解析 ownership.teams 失败: %v", err))
+	}
+	var services []Service
+	if err := config.UnmarshalKey("ownership.services", &services); err != nil {
+		utils.Error(fmt.Sprintf("解析 ownership.services 失败: %v", err))
+	}
+	return NewRegistry(teams, services)
+}
+
+// NewRegistry 创建归属登记，teams 的键为团队名称
+func NewRegistry(teams map[string]Team, services []Service) *Registry {
+	r := &Registry{teams: make(map[string]Team, len(teams)), services: services}
+	for name, team := range teams {
+		team.Name = name
+		r.teams[strings.ToLower(name)] = team
+	}
+	return r
+}
+
+// Lookup 查找工作负载的负责团队，按配置顺序返回第一个匹配的服务
+// workload 可以是 Deployment 等工作负载名称，也可以是其创建的 Pod 名称
+func (r *Registry) Lookup(cluster, namespace, workload string) (*Team, bool) {
+	for _, service := range r.services {
+		if !match(service.Cluster, cluster) || !match(service.Namespace, namespace) || !matchWorkload(service.Name, workload) {
+			continue
+		}
+		team, ok := r.teams[strings.ToLower(service.Team)]
+		if !ok {
+			team = Team{Name: service.Team}
+		}
+		return &team, true
+	}
+	return nil, false
+}
+
+// Summary 返回附加在回答中的归属说明
+func (t *Team) Summary() string {
+	summary := "负责团队: " + t.Name
+	if t.Contact != "" {
+		summary += "，联系人: " + t.Contact
+	}
+	if t.Escalation != "" {
+		summary += "，升级: " + t.Escalation
+	}
+	return summary
+}
+
+// Route 将通知发送到负责团队的渠道，团队未配置渠道时发送到所有渠道
+func Route(ctx context.Context, notifier *notify.Notifier, team *Team, msg notify.Message) error {
+	if team != nil {
+		msg.Text = strings.TrimSpace(msg.Text + "\n\n" + team.Summary())
+		if team.Channel != "" {
+			return notifier.SendTo(ctx, team.Channel, msg)
+		}
+	}
+	return notifier.Send(ctx, msg)
+}
+
+func match(pattern, value string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(value))
+	return ok
+}
+
+// matchWorkload 在 match 的基础上允许 Pod 名称匹配其所属工作负载，例如 web 匹配 web-7d9f8b-x2kqp
+func matchWorkload(pattern, workload string) bool {
+	if match(pattern, workload) {
+		return true
+	}
+	return pattern != "" && !strings.ContainsAny(pattern, "*?[") &&
+		strings.HasPrefix(strings.ToLower(workload), strings.ToLower(pattern)+"-")
+}
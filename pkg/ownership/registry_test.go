@@ -0,0 +1,46 @@
+package ownership
+
+import "testing"
+
+func TestRegistryLookup(t *testing.T) {
+	registry := NewRegistry(
+		map[string]Team{
+			"payments": {Contact: "#payments", Escalation: "alice", Channel: "payments-ding"},
+			"platform": {Contact: "#platform"},
+		},
+		[]Service{
+			{Name: "order-api", Namespace: "order", Cluster: "prod-*", Team: "payments"},
+			{Name: "*", Namespace: "kube-system", Team: "platform"},
+			{Name: "legacy", Team: "unknown-team"},
+		},
+	)
+
+	tests := []struct {
+		cluster, namespace, workload string
+		team                         string
+	}{
+		{"prod-east", "order", "order-api", "payments"},
+		{"prod-east", "order", "order-api-7d9f8b-x2kqp", "payments"},
+		{"staging", "order", "order-api", ""},
+		{"staging", "kube-system", "coredns", "platform"},
+		{"staging", "default", "legacy", "unknown-team"},
+		{"prod-east", "order", "order-apis", ""},
+	}
+	for _, tt := range tests {
+		team, ok := registry.Lookup(tt.cluster, tt.namespace, tt.workload)
+		if tt.team == "" {
+			if ok {
+				t.Errorf("Lookup(%s, %s, %s) = %s, want no owner", tt.cluster, tt.namespace, tt.workload, team.Name)
+			}
+			continue
+		}
+		if !ok || team.Name != tt.team {
+			t.Errorf("Lookup(%s, %s, %s) = %v, want %s", tt.cluster, tt.namespace, tt.workload, team, tt.team)
+		}
+	}
+
+	team, _ := registry.Lookup("prod-east", "order", "order-api")
+	if got, want := team.Summary(), "负责团队: payments，联系人: #payments，升级: alice"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
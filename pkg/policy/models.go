@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// ModelPolicy 租户可选择的模型和提供商
+// 列表为空表示不限制，支持通配符，例如 "gpt-4o*"
+type ModelPolicy struct {
+	Models    []string `mapstructure:"models" json:"models"`
+	Providers []string `mapstructure:"providers" json:"providers"`
+}
+
+// ModelPolicyFor 获取租户的模型策略，未单独配置的租户使用 models.default
+func ModelPolicyFor(tenant string) ModelPolicy {
+	config := utils.GetConfig()
+
+	var policy ModelPolicy
+	key := "models.tenants." + strings.ToLower(tenant)
+	if tenant == "" || !config.IsSet(key) {
+		key = "models.default"
+	}
+	if err := config.UnmarshalKey(key, &policy); err != nil {
+		utils.Error(fmt.Sprintf("解析模型策略 %s 失败: %v", key, err))
+	}
+	return policy
+}
+
+// Allows 校验提供商和模型是否被允许，model 为空时跳过模型校验
+// 提供商总是按 llms.NormalizeProvider 归一化后校验：空值即 openai，claude、google 分别视为 anthropic、gemini
+func (p ModelPolicy) Allows(provider, model string) error {
+	provider = llms.NormalizeProvider(provider)
+	if !matchAny(normalizeProviders(p.Providers), provider) {
+		return fmt.Errorf("provider %q is not allowed", provider)
+	}
+	if model != "" && !matchAny(p.Models, model) {
+		return fmt.Errorf("model %q is not allowed", model)
+	}
+	return nil
+}
+
+// RolePolicyFor 获取角色（viewer、operator、admin）的模型策略 models.roles.<role>，未配置时返回 false
+func RolePolicyFor(role string) (ModelPolicy, bool) {
+	config := utils.GetConfig()
+
+	var policy ModelPolicy
+	key := "models.roles." + strings.ToLower(role)
+	if role == "" || !config.IsSet(key) {
+		return policy, false
+	}
+	if err := config.UnmarshalKey(key, &policy); err != nil {
+		utils.Error(fmt.Sprintf("解析模型策略 %s 失败: %v", key, err))
+	}
+	return policy, true
+}
+
+// ValidateModelSelection 校验租户和角色选择的当前模型和候选模型，候选模型与当前模型使用同一提供商
+// 同时满足租户策略和角色策略时才允许，角色未配置策略时只校验租户策略
+func ValidateModelSelection(tenant, role, provider, currentModel string, selectedModels []string) error {
+	policies := []ModelPolicy{ModelPolicyFor(tenant)}
+	if policy, ok := RolePolicyFor(role); ok {
+		policies = append(policies, policy)
+	}
+	for _, policy := range policies {
+		if err := policy.Allows(provider, currentModel); err != nil {
+			return err
+		}
+		for _, model := range selectedModels {
+			if err := policy.Allows(provider, model); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeProviders 将策略中不含通配符的提供商别名归一化，与请求的提供商按同一规则比较
+func normalizeProviders(patterns []string) []string {
+	normalized := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		if strings.ContainsAny(pattern, "*?[") {
+			normalized[i] = pattern
+			continue
+		}
+		normalized[i] = llms.NormalizeProvider(pattern)
+	}
+	return normalized
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), value); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestValidateModelSelection(t *testing.T) {
+	config := utils.GetConfig()
+	config.Set("models.default", map[string]interface{}{
+		"models": []string{"gpt-4o-mini", "qwen-*"},
+	})
+	config.Set("models.tenants", map[string]interface{}{
+		"ops": map[string]interface{}{
+			"models":    []string{"gpt-4o*"},
+			"providers": []string{"openai"},
+		},
+		"claude": map[string]interface{}{
+			"providers": []string{"claude"},
+		},
+	})
+
+	config.Set("models.roles", map[string]interface{}{
+		"viewer": map[string]interface{}{
+			"models": []string{"gpt-4o-mini", "qwen-turbo"},
+		},
+	})
+	defer func() {
+		config.Set("models.default", nil)
+		config.Set("models.tenants", nil)
+		config.Set("models.roles", nil)
+	}()
+
+	cases := []struct {
+		tenant   string
+		role     string
+		provider string
+		current  string
+		selected []string
+		allowed  bool
+	}{
+		{"alice", "", "", "qwen-max", nil, true},
+		{"alice", "", "", "gpt-4o", nil, false},
+		{"alice", "", "", "gpt-4o-mini", []string{"gpt-4"}, false},
+		{"ops", "", "openai", "GPT-4o", []string{"gpt-4o-mini"}, true},
+		{"ops", "", "anthropic", "gpt-4o", nil, false},
+		{"ops", "", "openai", "qwen-max", nil, false},
+		// 提供商归一化后校验：空值即 openai，claude 即 anthropic，候选模型同样校验提供商
+		{"ops", "", "", "gpt-4o", nil, true},
+		{"ops", "", "Claude", "gpt-4o", nil, false},
+		{"ops", "", "gemini", "gpt-4o", []string{"gpt-4o-mini"}, false},
+		{"claude", "", "anthropic", "claude-3-5-sonnet", nil, true},
+		{"claude", "", "", "gpt-4o", nil, false},
+		{"claude", "", "openai", "", []string{"gpt-4o"}, false},
+		// 角色策略在租户策略之外进一步限制，未配置的角色只受租户策略限制
+		{"alice", "viewer", "", "qwen-turbo", nil, true},
+		{"alice", "viewer", "", "qwen-max", nil, false},
+		{"alice", "viewer", "", "gpt-4o-mini", []string{"qwen-max"}, false},
+		{"ops", "viewer", "openai", "gpt-4o", nil, false},
+		{"ops", "viewer", "openai", "gpt-4o-mini", nil, true},
+		{"ops", "operator", "openai", "gpt-4o", nil, true},
+	}
+
+	for _, c := range cases {
+		err := ValidateModelSelection(c.tenant, c.role, c.provider, c.current, c.selected)
+		if (err == nil) != c.allowed {
+			t.Errorf("tenant=%s role=%s provider=%s model=%s selected=%v: expected allowed=%v, got %v",
+				c.tenant, c.role, c.provider, c.current, c.selected, c.allowed, err)
+		}
+	}
+}
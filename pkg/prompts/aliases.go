@@ -0,0 +1,256 @@
+package prompts
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// defaultAliasMinOccurrences 别名建议至少需要出现的交互次数
+const defaultAliasMinOccurrences = 3
+
+// AliasMinOccurrences 别名建议的最少出现次数，配置 prompts.alias_min_occurrences
+func AliasMinOccurrences() int {
+	if n := utils.GetConfig().GetInt("prompts.alias_min_occurrences"); n > 0 {
+		return n
+	}
+	return defaultAliasMinOccurrences
+}
+
+// AliasSuggestion 从审计记录中挖掘出的别名建议
+type AliasSuggestion struct {
+	Alias        string   `json:"alias"`
+	Service      string   `json:"service"`
+	Namespace    string   `json:"namespace,omitempty"`
+	Cluster      string   `json:"cluster,omitempty"`
+	Occurrences  int      `json:"occurrences"`
+	Interactions []string `json:"interactions"` // 示例交互 ID，便于人工核对
+}
+
+// maxSuggestionExamples 每条建议保留的示例交互数量
+const maxSuggestionExamples = 3
+
+var (
+	// questionTermRe 问题中可能作为服务简称的英文词
+	questionTermRe = regexp.MustCompile(`[A-Za-z][A-Za-z0-9_-]{2,}`)
+	// podSuffixRe 匹配 Deployment 创建的 Pod 名称后缀，例如 -7d9f8c6b5-x2k4q
+	podSuffixRe = regexp.MustCompile(`-[a-z0-9]{6,10}-[a-z0-9]{5}$`)
+	// commandSeparatorRe 拆分管道和命令组合
+	commandSeparatorRe = regexp.MustCompile(`\|\||&&|[|;]`)
+)
+
+// termStopwords 问题中常见但不可能是服务别名的词
+var termStopwords = map[string]bool{
+	"pod": true, "pods": true, "deploy": true, "deployment": true, "deployments": true,
+	"svc": true, "service": true, "services": true, "node": true, "nodes": true,
+	"namespace": true, "namespaces": true, "log": true, "logs": true, "kubectl": true,
+	"get": true, "describe": true, "the": true, "and": true, "for": true, "error": true,
+	"errors": true, "status": true, "cpu": true, "memory": true, "ingress": true,
+	"configmap": true, "secret": true, "statefulset": true, "daemonset": true, "job": true,
+	"cronjob": true, "events": true, "prod": true, "test": true, "dev": true,
+}
+
+// kubectlValueFlags 带取值的 kubectl 参数，解析资源名称时需要跳过其取值
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "-l": true, "--selector": true, "-o": true, "--output": true,
+	"-c": true, "--container": true, "--context": true, "--since": true, "--tail": true,
+	"--field-selector": true, "--sort-by": true, "--kubeconfig": true, "-f": true, "--filename": true,
+}
+
+// workloadRef 工具调用中引用的工作负载
+type workloadRef struct {
+	Name      string
+	Namespace string
+}
+
+// SuggestAliases 从审计记录中挖掘服务别名建议
+// 用户问题中的简称（例如 "pay"）与本次交互中成功查询的资源名称（例如 "payment-api"）模糊匹配，
+// 且该简称尚未登记为服务名称或别名时，按出现的交互数计数，每个简称只建议出现次数最多的服务
+func SuggestAliases(interactions []audit.Interaction, services []Service, minOccurrences int) []AliasSuggestion {
+	known := map[string]bool{}
+	for _, s := range services {
+		known[strings.ToLower(s.Name)] = true
+		for _, alias := range s.Aliases {
+			known[strings.ToLower(alias)] = true
+		}
+	}
+
+	type key struct{ alias, service, namespace, cluster string }
+	counts := map[key]*AliasSuggestion{}
+	for _, interaction := range interactions {
+		if interaction.Status != audit.StatusSuccess || strings.Contains(interaction.Cluster, ",") {
+			continue
+		}
+		workloads := referencedWorkloads(interaction.ToolCalls)
+		seen := map[key]bool{}
+		for _, term := range questionTerms(interaction.Question) {
+			if known[term] {
+				continue
+			}
+			for _, w := range workloads {
+				if w.Name == term || !strings.Contains(w.Name, term) {
+					continue
+				}
+				k := key{term, w.Name, w.Namespace, interaction.Cluster}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				suggestion, ok := counts[k]
+				if !ok {
+					suggestion = &AliasSuggestion{Alias: term, Service: w.Name, Namespace: w.Namespace, Cluster: interaction.Cluster}
+					counts[k] = suggestion
+				}
+				suggestion.Occurrences++
+				if len(suggestion.Interactions) < maxSuggestionExamples {
+					suggestion.Interactions = append(suggestion.Interactions, interaction.ID)
+				}
+			}
+		}
+	}
+
+	best := map[string]*AliasSuggestion{}
+	for _, suggestion := range counts {
+		if suggestion.Occurrences < minOccurrences {
+			continue
+		}
+		if current, ok := best[suggestion.Alias]; !ok || suggestion.Occurrences > current.Occurrences ||
+			(suggestion.Occurrences == current.Occurrences && suggestion.Service < current.Service) {
+			best[suggestion.Alias] = suggestion
+		}
+	}
+
+	suggestions := make([]AliasSuggestion, 0, len(best))
+	for _, suggestion := range best {
+		suggestions = append(suggestions, *suggestion)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Occurrences != suggestions[j].Occurrences {
+			return suggestions[i].Occurrences > suggestions[j].Occurrences
+		}
+		return suggestions[i].Alias < suggestions[j].Alias
+	})
+	return suggestions
+}
+
+// questionTerms 提取问题中的候选简称，统一转为小写
+func questionTerms(question string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, term := range questionTermRe.FindAllString(question, -1) {
+		term = strings.ToLower(strings.Trim(term, "-_"))
+		if len(term) < 3 || termStopwords[term] || seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// referencedWorkloads 解析成功执行的 kubectl 调用中引用的资源名称，Pod 名称归并为所属工作负载
+func referencedWorkloads(calls []audit.ToolCall) []workloadRef {
+	var refs []workloadRef
+	seen := map[workloadRef]bool{}
+	for _, call := range calls {
+		if call.Name != "kubectl" || failedObservation(call.Observation) {
+			continue
+		}
+		for _, segment := range commandSeparatorRe.Split(call.Input, -1) {
+			for _, ref := range kubectlResourceNames(segment) {
+				if !seen[ref] {
+					seen[ref] = true
+					refs = append(refs, ref)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// failedObservation 判断 kubectl 调用是否失败或没有命中资源
+func failedObservation(observation string) bool {
+	observation = strings.TrimSpace(observation)
+	return observation == "" ||
+		strings.HasPrefix(observation, "Error from server") ||
+		strings.HasPrefix(observation, "error:") ||
+		strings.HasPrefix(observation, "No resources found")
+}
+
+// kubectlResourceNames 解析单条 kubectl 命令中显式指定的资源名称
+func kubectlResourceNames(command string) []workloadRef {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || fields[0] != "kubectl" {
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "-") || !isKubectlVerb(fields[0]) {
+			return nil
+		}
+		fields = append([]string{"kubectl"}, fields...)
+	}
+
+	var (
+		namespace   string
+		positionals []string
+	)
+	for i := 1; i < len(fields); i++ {
+		field := strings.Trim(fields[i], `'"`)
+		switch {
+		case field == "-n" || field == "--namespace":
+			if i+1 < len(fields) {
+				namespace = strings.Trim(fields[i+1], `'"`)
+			}
+			i++
+		case strings.HasPrefix(field, "--namespace="):
+			namespace = strings.TrimPrefix(field, "--namespace=")
+		case kubectlValueFlags[field]:
+			i++
+		case strings.HasPrefix(field, "-"):
+		default:
+			positionals = append(positionals, field)
+		}
+	}
+	if len(positionals) < 2 {
+		return nil
+	}
+
+	verb, args := positionals[0], positionals[1:]
+	if verb == "rollout" && len(args) > 0 {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	var names []string
+	switch {
+	case strings.Contains(args[0], "/"):
+		names = args
+	case verb == "logs" || verb == "exec" || verb == "attach" || verb == "port-forward":
+		names = args[:1]
+	default:
+		names = args[1:]
+	}
+
+	var refs []workloadRef
+	for _, name := range names {
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:]
+		}
+		name = podSuffixRe.ReplaceAllString(strings.ToLower(name), "")
+		if name == "" || strings.ContainsAny(name, "=*{}$") {
+			continue
+		}
+		refs = append(refs, workloadRef{Name: name, Namespace: namespace})
+	}
+	return refs
+}
+
+func isKubectlVerb(word string) bool {
+	switch word {
+	case "get", "describe", "logs", "exec", "attach", "port-forward", "rollout", "scale", "top", "edit", "delete":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package prompts
+
+import (
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+)
+
+func TestSuggestAliases(t *testing.T) {
+	interaction := func(id, question, cluster string, calls ...audit.ToolCall) audit.Interaction {
+		return audit.Interaction{ID: id, Question: question, Cluster: cluster, Status: audit.StatusSuccess, ToolCalls: calls}
+	}
+	kubectl := func(input, observation string) audit.ToolCall {
+		return audit.ToolCall{Name: "kubectl", Input: input, Observation: observation}
+	}
+
+	interactions := []audit.Interaction{
+		interaction("1", "pay 服务的 pod 为什么重启", "prod",
+			kubectl("get pods -n shop | grep pay", "payment-api-7d9f8c6b5-x2k4q 1/1 Running"),
+			kubectl("kubectl logs payment-api-7d9f8c6b5-x2k4q -n shop --tail 100", "panic: nil map")),
+		interaction("2", "查看 pay 的日志", "prod",
+			kubectl("kubectl logs deploy/payment-api -n shop", "started")),
+		interaction("3", "pay 的副本数", "prod",
+			kubectl("kubectl get deployment payment-api -n shop", "payment-api 3/3")),
+		// 失败的查询和已登记的别名不参与计数
+		interaction("4", "pay 状态", "prod",
+			kubectl("kubectl get deploy payment -n shop", "Error from server (NotFound)")),
+		interaction("5", "order 的日志", "prod",
+			kubectl("kubectl logs deploy/order-api -n order", "ok")),
+		interaction("6", "order 的日志", "prod",
+			kubectl("kubectl logs deploy/order-api -n order", "ok")),
+		interaction("7", "order 的日志", "prod",
+			kubectl("kubectl logs deploy/order-api -n order", "ok")),
+	}
+	services := []Service{{Name: "order-api", Namespace: "order", Aliases: []string{"order"}}}
+
+	got := SuggestAliases(interactions, services, 3)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 suggestion, got %+v", got)
+	}
+	want := AliasSuggestion{Alias: "pay", Service: "payment-api", Namespace: "shop", Cluster: "prod", Occurrences: 3}
+	if s := got[0]; s.Alias != want.Alias || s.Service != want.Service || s.Namespace != want.Namespace ||
+		s.Cluster != want.Cluster || s.Occurrences != want.Occurrences || len(s.Interactions) != 3 {
+		t.Errorf("expected %+v, got %+v", want, s)
+	}
+
+	if got := SuggestAliases(interactions, services, 4); len(got) != 0 {
+		t.Errorf("expected no suggestion below min occurrences, got %+v", got)
+	}
+}
@@ -0,0 +1,21 @@
+package prompts
+
+import (
+	"embed"
+	"strings"
+)
+
+// builtinFS 内置的系统提示模板，templates/<name>.tmpl
+// 修改提示无需重新编译：通过 prompts.sources.<name> 配置本地文件或远程地址覆盖内置模板
+//
+//go:embed templates/*.tmpl
+var builtinFS embed.FS
+
+// Builtin 返回内置的系统提示模板，不存在时返回空字符串
+func Builtin(name string) string {
+	data, err := builtinFS.ReadFile("templates/" + name + ".tmpl")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
@@ -0,0 +1,103 @@
+package prompts
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// questionTopic 问题分类，用于只在系统提示中保留与问题相关的工具和约束
+type questionTopic struct {
+	Name     string
+	Keywords []string // 小写匹配的关键词
+	Sections []string // 需要的提示段落，工具名或 shell（命令行引号约束）
+}
+
+// topics 问题分类表，问题可以同时属于多个分类；未命中任何分类时使用完整提示
+var topics = []questionTopic{
+	{Name: "image", Keywords: []string{"镜像", "image", "版本", "version"}, Sections: []string{"kubectl", "jq", "shell", "services", "rollout"}},
+	{Name: "logs", Keywords: []string{"日志", "log", "报错", "异常", "crash", "重启", "restart", "oom"}, Sections: []string{"logs", "restarts", "rollout", "kubectl", "shell", "services"}},
+	{Name: "capacity", Keywords: []string{"节点池", "nodepool", "node pool", "gpu", "调度", "容量", "capacity", "pending", "cpu", "内存", "memory"}, Sections: []string{"nodepools", "calc", "promql", "terraform", "kubectl", "shell"}},
+	{Name: "quota", Keywords: []string{"配额", "quota", "limitrange", "limit range", "requests", "limits", "超卖", "合规"}, Sections: []string{"quotacheck", "kubectl", "shell"}},
+	{Name: "infra", Keywords: []string{"terraform", "节点组", "node group", "nodegroup", "负载均衡", "load balancer", "slb", "alb", "基础设施", "infra", "机型", "instance type"}, Sections: []string{"terraform", "nodepools", "kubectl", "shell"}},
+	{Name: "metrics", Keywords: []string{"使用率", "利用率", "usage", "qps", "延迟", "latency", "趋势", "trend", "过去", "最近", "last hour", "监控", "prometheus", "promql"}, Sections: []string{"promql", "calc", "kubectl", "shell", "services"}},
+	{Name: "aggregate", Keywords: []string{"总和", "总量", "总共", "合计", "total", "sum", "平均", "average", "百分比", "占比", "percent"}, Sections: []string{"calc", "kubectl", "shell"}},
+	{Name: "security", Keywords: []string{"漏洞", "cve", "vulnerab", "扫描", "scan", "trivy"}, Sections: []string{"trivy", "kubectl", "shell"}},
+	{Name: "changes", Keywords: []string{"谁", "who", "变更记录", "发布", "回滚", "rollout", "rollback", "revision", "改了", "修改了", "删除了", "删了", "扩容", "缩容", "scaled", "changed", "deleted", "审计"}, Sections: []string{"kubeaudit", "rollout", "kubectl", "shell", "services"}},
+	{Name: "history", Keywords: []string{"快照", "snapshot", "当时", "上周", "上个月", "那天", "existed", "复盘", "post-incident", "postmortem", "昨天", "变化", "改了什么", "what changed", "since yesterday"}, Sections: []string{"snapshot", "compare", "kubeaudit", "kubectl", "shell"}},
+	{Name: "inventory", Keywords: []string{"哪些集群", "哪个集群", "在哪", "部署在", "所有集群", "which cluster", "where is", "all clusters"}, Sections: []string{"inventory", "kubectl", "shell", "services"}},
+	{Name: "manifest", Keywords: []string{"yaml", "清单", "manifest", "dry-run", "dryrun", "diff", "校验"}, Sections: []string{"dryrun", "kubectl", "shell"}},
+	{Name: "network", Keywords: []string{"网络", "network", "ingress", "dns", "端口", "svc", "service", "endpoint"}, Sections: []string{"kubectl", "shell", "services"}},
+	{Name: "search", Keywords: []string{"搜索", "search", "google", "文档", "docs"}, Sections: []string{"search"}},
+}
+
+// contextAware 是否按问题分类裁剪系统提示，配置项 prompts.context_aware，默认开启
+func contextAware() bool {
+	config := utils.GetConfig()
+	return !config.IsSet("prompts.context_aware") || config.GetBool("prompts.context_aware")
+}
+
+// ClassifyQuestion 按关键词对问题分类，返回命中的分类名（按分类表顺序）
+// 问题中直接提到的工具名（如 "用 python"）也会作为分类返回
+func ClassifyQuestion(question string) []string {
+	question = strings.ToLower(question)
+	var matched []string
+	for _, topic := range topics {
+		for _, keyword := range topic.Keywords {
+			if strings.Contains(question, keyword) {
+				matched = append(matched, topic.Name)
+				break
+			}
+		}
+	}
+	for _, name := range []string{"python", "jq", "trivy", "nodepools", "calc", "promql", "terraform", "kubeaudit", "rollout", "quotacheck", "restarts", "snapshot", "compare", "inventory", "dryrun"} {
+		if strings.Contains(question, name) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// sectionsFor 返回分类需要的提示段落，没有分类时返回 nil 表示使用完整提示
+func sectionsFor(topicNames []string) map[string]bool {
+	if len(topicNames) == 0 {
+		return nil
+	}
+	sections := map[string]bool{}
+	for _, name := range topicNames {
+		found := false
+		for _, topic := range topics {
+			if topic.Name == name {
+				for _, section := range topic.Sections {
+					sections[section] = true
+				}
+				found = true
+			}
+		}
+		// 直接提到的工具名
+		if !found {
+			sections[name] = true
+		}
+	}
+	return sections
+}
+
+// Include 模板中判断是否包含某个段落，例如 {{if .Include "jq"}}...{{end}}
+// 未分类（或关闭 prompts.context_aware）时包含全部段落
+func (v Vars) Include(section string) bool {
+	return v.sections == nil || v.sections[section]
+}
+
+// Sections 本次提示包含的段落，未裁剪时返回 nil
+func (v Vars) Sections() []string {
+	if v.sections == nil {
+		return nil
+	}
+	names := make([]string, 0, len(v.sections))
+	for name := range v.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
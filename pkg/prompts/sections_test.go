@@ -0,0 +1,56 @@
+package prompts
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestClassifyQuestion(t *testing.T) {
+	tests := []struct {
+		question string
+		want     []string
+	}{
+		{"payment-api 当前的镜像版本是什么", []string{"image"}},
+		{"为什么 order 的 pod 一直 Pending，GPU 节点池还有容量吗", []string{"capacity"}},
+		{"扫描 nginx:1.25 的漏洞", []string{"security"}},
+		{"用 python 统计每个命名空间的 pod 数量", []string{"python"}},
+		{"集群整体情况怎么样", nil},
+	}
+	for _, tt := range tests {
+		if got := ClassifyQuestion(tt.question); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ClassifyQuestion(%q) = %v, want %v", tt.question, got, tt.want)
+		}
+	}
+}
+
+func TestNewVarsTrimsUnrelatedSections(t *testing.T) {
+	const tmpl = `{{.Tools}}
+{{- if .Include "jq"}}
+jq 约束
+{{- end}}
+{{- if .Include "nodepools"}}
+节点池约束
+{{- end}}`
+
+	trimmed := MustRender(tmpl, NewVars(Options{Question: "payment-api 当前的镜像版本是什么"}))
+	if !strings.Contains(trimmed, "- kubectl：") || !strings.Contains(trimmed, "jq 约束") {
+		t.Errorf("expected kubectl and jq sections for an image question, got %q", trimmed)
+	}
+	if strings.Contains(trimmed, "- trivy：") || strings.Contains(trimmed, "- python：") || strings.Contains(trimmed, "节点池约束") {
+		t.Errorf("expected unrelated tools to be trimmed, got %q", trimmed)
+	}
+
+	full := MustRender(tmpl, NewVars(Options{Question: "集群整体情况怎么样"}))
+	if !strings.Contains(full, "- trivy：") || !strings.Contains(full, "节点池约束") {
+		t.Errorf("expected full prompt for an unclassified question, got %q", full)
+	}
+
+	utils.GetConfig().Set("prompts.context_aware", false)
+	defer utils.GetConfig().Set("prompts.context_aware", nil)
+	if got := MustRender(tmpl, NewVars(Options{Question: "payment-api 当前的镜像版本是什么"})); got != full {
+		t.Errorf("expected full prompt with context_aware disabled, got %q", got)
+	}
+}
@@ -0,0 +1,66 @@
+package prompts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// 提示来源
+const (
+	SourceBuiltin = "builtin"
+	SourceRemote  = "remote"
+)
+
+// versionCommentRe 匹配提示中声明的版本，例如 {{/* version: 2025-03-01 */}}
+// 模板注释在渲染时会被移除，不会发送给 LLM
+var versionCommentRe = regexp.MustCompile(`\{\{-?\s*/\*\s*version:\s*([^\s*]+)\s*\*/\s*-?\}\}`)
+
+// Prompt 带版本信息的系统提示模板
+type Prompt struct {
+	Name    string
+	Source  string
+	Version string
+	Hash    string
+	Text    string
+}
+
+// Get 获取指定名称的系统提示：配置的来源（远程地址、Git 仓库或本地文件）可用时使用配置的提示，否则使用内置提示
+// 版本优先取提示中声明的版本，其次为远程提示的 ETag（Git 来源为提交哈希），最后为内容哈希
+// 配置了来源名称时版本以来源名称为前缀，例如 git:<提交哈希>
+func Get(ctx context.Context, name, builtin string) Prompt {
+	cache := utils.GetPromptCache()
+	if text, ok := cache.Lookup(ctx, name); ok {
+		source := SourceRemote
+		if named := cache.SourceName(name); named != "" {
+			source = named
+		}
+		return newPrompt(name, source, text, cache.ETag(name))
+	}
+	return newPrompt(name, SourceBuiltin, builtin, "")
+}
+
+func newPrompt(name, source, text, etag string) Prompt {
+	sum := sha256.Sum256([]byte(text))
+	p := Prompt{
+		Name:   name,
+		Source: source,
+		Hash:   hex.EncodeToString(sum[:]),
+		Text:   text,
+	}
+
+	switch {
+	case versionCommentRe.MatchString(text):
+		p.Version = versionCommentRe.FindStringSubmatch(text)[1]
+	case etag != "":
+		p.Version = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	default:
+		p.Version = p.Hash[:12]
+	}
+	p.Version = source + ":" + p.Version
+	return p
+}
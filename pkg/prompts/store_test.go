@@ -0,0 +1,28 @@
+package prompts
+
+import "testing"
+
+func TestNewPromptVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		text    string
+		etag    string
+		version string
+	}{
+		{"declared", SourceRemote, "{{/* version: 3 */}}\nhello", `"abc"`, "remote:3"},
+		{"etag", SourceRemote, "hello", `W/"abc"`, "remote:abc"},
+		{"hash", SourceBuiltin, "hello", "", "builtin:2cf24dba5fb0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newPrompt("execute", tt.source, tt.text, tt.etag)
+			if p.Version != tt.version {
+				t.Errorf("version = %q, want %q", p.Version, tt.version)
+			}
+			if len(p.Hash) != 64 {
+				t.Errorf("hash = %q, want sha256 hex", p.Hash)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// Vars 系统提示模板中可用的变量，每个请求单独计算
+// execute、chat、evaluation 接口和 diagnose 命令的系统提示使用模板渲染；
+// HTTP diagnose、analyze 接口目前只汇总集群数据、不调用 LLM，analyze 命令的提示不包含动态数据
+//
+//	{{.ContextTable}} 登记的集群及 kubeconfig 中的 context 表格
+//	{{.ServiceTable}} prompts.services 中配置的服务表格
+//	{{.Tools}}        可用工具及其说明
+//	{{.Date}}         当前日期
+//	{{.UserRole}}     当前用户角色（viewer、operator 或 admin）
+//	{{.Cluster}}      本次请求的目标集群
+//	{{.Topics}}       问题分类，为空表示未分类（使用完整提示）
+//	{{if .Include "jq"}}...{{end}} 只在问题相关时包含的段落
+type Vars struct {
+	ContextTable string
+	ServiceTable string
+	Tools        string
+	Date         string
+	UserRole     string
+	Cluster      string
+	Topics       []string
+
+	sections map[string]bool // 按问题分类需要的段落，nil 表示全部包含
+}
+
+// Options 计算模板变量所需的请求信息
+type Options struct {
+	UserRole string
+	Cluster  string
+	Question string // 用户问题，用于裁剪与问题无关的工具和约束，为空时使用完整提示
+}
+
+// Service 系统提示中介绍的服务，见 knowledge.Service
+type Service = knowledge.Service
+
+var (
+	templates   = map[[sha256.Size]byte]*template.Template{}
+	templatesMu sync.Mutex
+)
+
+// NewVars 计算本次请求的模板变量
+func NewVars(opts Options) Vars {
+	cluster := opts.Cluster
+	if opts.Cluster == "" {
+		opts.Cluster = "kubeconfig 当前 context"
+	}
+	vars := Vars{
+		ContextTable: contextTable(),
+		ServiceTable: serviceTable(),
+		Date:         time.Now().Format("2006-01-02"),
+		UserRole:     opts.UserRole,
+		Cluster:      opts.Cluster,
+	}
+	if opts.Question != "" && contextAware() {
+		vars.Topics = ClassifyQuestion(opts.Question)
+		vars.sections = sectionsFor(vars.Topics)
+	}
+	vars.Tools = toolList(vars.sections, cluster)
+	return vars
+}
+
+// Render 使用变量渲染系统提示模板，解析后的模板按内容缓存
+func Render(text string, vars Vars) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	key := sha256.Sum256([]byte(text))
+	templatesMu.Lock()
+	tmpl, ok := templates[key]
+	templatesMu.Unlock()
+	if !ok {
+		var err error
+		tmpl, err = template.New("prompt").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", fmt.Errorf("解析提示模板失败: %v", err)
+		}
+		templatesMu.Lock()
+		templates[key] = tmpl
+		templatesMu.Unlock()
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("渲染提示模板失败: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// MustRender 渲染系统提示模板，失败时记录日志并返回原始文本
+func MustRender(text string, vars Vars) string {
+	rendered, err := Render(text, vars)
+	if err != nil {
+		utils.Warn("渲染系统提示失败，使用原始提示",
+			zap.Error(err),
+		)
+		return text
+	}
+	return rendered
+}
+
+// contextTable 生成 kubeconfig context 表格，当前 context 以 * 标记
+func contextTable() string {
+	contexts, current, _ := kubernetes.ListContexts()
+	if registered := clusters.Default().List(); len(registered) > 0 {
+		return registryTable(registered, contexts, current)
+	}
+	if len(contexts) == 0 {
+		return "（无可用的 kubeconfig context）"
+	}
+
+	var b strings.Builder
+	b.WriteString("| 当前 | Context |\n|---|---|\n")
+	for _, name := range contexts {
+		marker := ""
+		if name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "| %s | %s |\n", marker, name)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// registryTable 生成登记集群的表格，默认 kubeconfig 中未登记的 context 附在最后
+func registryTable(registered []clusters.Cluster, contexts []string, current string) string {
+	covered := map[string]bool{}
+	var b strings.Builder
+	b.WriteString("| 当前 | 集群 | Context | 默认命名空间 | 别名 | 说明 |\n|---|---|---|---|---|---|\n")
+	for _, c := range registered {
+		marker := ""
+		if c.Kubeconfig == "" {
+			covered[c.Context] = true
+			if c.Context == current {
+				marker = "*"
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", marker, c.Name, c.Context, c.Namespace, strings.Join(c.Aliases, ", "), c.Description)
+	}
+	for _, name := range contexts {
+		if covered[name] {
+			continue
+		}
+		marker := ""
+		if name == current {
+			marker = "*"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s |  |  |  |\n", marker, name, name)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// serviceTable 生成 prompts.services 中配置的服务表格
+func serviceTable() string {
+	services := knowledge.Services()
+	if len(services) == 0 {
+		return "（未配置服务列表）"
+	}
+
+	var b strings.Builder
+	b.WriteString("| 服务 | 别名 | 命名空间 | 集群 | 说明 |\n|---|---|---|---|---|\n")
+	for _, s := range services {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", s.Name, strings.Join(s.Aliases, ", "), s.Namespace, s.Cluster, s.Description)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// toolList 列出已注册的工具及其说明，sections 不为 nil 时只列出其中的工具
+// 目标集群由 runner 执行工具调用时，不列出 runner 不支持的工具
+func toolList(sections map[string]bool, cluster string) string {
+	specs := tools.Registry.List()
+	lines := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		if sections != nil && !sections[spec.Name] {
+			continue
+		}
+		if !tools.Available(cluster, spec.Name) {
+			continue
+		}
+		if spec.Description != "" {
+			lines = append(lines, fmt.Sprintf("- %s：%s", spec.Name, spec.Description))
+		} else {
+			lines = append(lines, "- "+spec.Name)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
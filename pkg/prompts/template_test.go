@@ -0,0 +1,67 @@
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+func TestRender(t *testing.T) {
+	vars := Vars{
+		Tools:    "- kubectl\n- jq",
+		Date:     "2025-03-01",
+		UserRole: "admin",
+		Cluster:  "prod-east",
+	}
+
+	got, err := Render("日期：{{.Date}} 角色：{{.UserRole}} 集群：{{.Cluster}}\n{{.Tools}}", vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "日期：2025-03-01 角色：admin 集群：prod-east\n- kubectl\n- jq"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+
+	// 不含模板语法的提示原样返回，awk 的单层花括号不受影响
+	plain := "使用 awk '{print $1}' 提取第一列"
+	if got, err := Render(plain, vars); err != nil || got != plain {
+		t.Errorf("expected plain prompt unchanged, got %q (%v)", got, err)
+	}
+
+	if _, err := Render("{{.Unknown}}", vars); err == nil {
+		t.Error("expected error for unknown variable")
+	}
+	if got := MustRender("{{.Unknown}}", vars); got != "{{.Unknown}}" {
+		t.Errorf("expected MustRender to fall back to the raw prompt, got %q", got)
+	}
+
+	if tools := toolList(nil, ""); !strings.Contains(tools, "- kubectl：") {
+		t.Errorf("expected kubectl in tool list, got %q", tools)
+	}
+
+	// 集群的 runner 不支持的工具不列出
+	tools.SetAvailabilityFunc(func(cluster, name string) bool { return cluster != "prod-east" || name != "kubectl" })
+	defer tools.SetAvailabilityFunc(nil)
+	if list := toolList(nil, "prod-east"); strings.Contains(list, "- kubectl：") || !strings.Contains(list, "- jq：") {
+		t.Errorf("expected kubectl to be left out for prod-east, got %q", list)
+	}
+}
+
+func TestBuiltinExecuteTemplate(t *testing.T) {
+	builtin := Builtin("execute")
+	if builtin == "" {
+		t.Fatal("expected the builtin execute template to be embedded")
+	}
+	got, err := Render(builtin, Vars{ContextTable: "| * | prod-east |", Tools: "- kubectl", Cluster: "prod-east"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(got, "| * | prod-east |") || strings.Contains(got, "{{") {
+		t.Errorf("expected variables to be injected, got %q", got)
+	}
+	if Builtin("missing") != "" {
+		t.Error("expected an empty template for an unknown name")
+	}
+}
@@ -0,0 +1,217 @@
+// Package receipts 为每次工具执行生成签名回执（输入和输出的哈希、执行时间、执行方），写入审计，
+// 合规检查时可以证明审计中记录的命令确实被执行过，且输出在执行之后没有被修改
+//
+// 签名使用 Ed25519：执行方（中心服务或集群内 runner）持有私钥，审计方只需要公钥即可校验，
+// 持有审计数据库写权限的人无法伪造回执
+package receipts
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// payloadVersion 签名内容的格式版本，修改 payload 时需要递增
+const payloadVersion = "opsagent-receipt-v2"
+
+// Signer 使用 Ed25519 私钥为工具执行签发回执
+type Signer struct {
+	key      ed25519.PrivateKey
+	keyID    string
+	identity string
+}
+
+// NewSigner 使用私钥创建签发方，identity 为回执中记录的执行方标识
+func NewSigner(key ed25519.PrivateKey, identity string) *Signer {
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey)), identity: identity}
+}
+
+// LoadSigner 读取 PEM（PKCS#8，openssl genpkey -algorithm ed25519 生成）或 base64 编码的 Ed25519 私钥
+// identity 为空时使用主机名
+func LoadSigner(keyFile, identity string) (*Signer, error) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取回执签名私钥失败: %v", err)
+	}
+	key, err := parsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析回执签名私钥 %s 失败: %v", keyFile, err)
+	}
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	return NewSigner(key, identity), nil
+}
+
+func parsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("不是 Ed25519 私钥")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("私钥长度 %d 无效", len(raw))
+}
+
+// ParsePublicKey 解析 PEM（PKIX）或 base64 编码的 Ed25519 公钥
+func ParsePublicKey(data string) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode([]byte(data)); block != nil {
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PublicKey)
+		if !ok {
+			return nil, errors.New("不是 Ed25519 公钥")
+		}
+		return key, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("公钥长度 %d 无效", len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// KeyID 公钥指纹：SHA-256 的前 8 字节
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PublicKey 签发方的公钥，配置到审计方的 receipts.trusted_keys 中用于校验
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Identity 回执中记录的执行方标识
+func (s *Signer) Identity() string {
+	return s.identity
+}
+
+// Sign 为一次工具执行签发回执，input 为实际执行的输入，output 为返回给调用方的输出
+// interactionID 和 seq 为回执在审计中的位置，一并签名，回执不能被复制到其他交互或调换顺序
+func (s *Signer) Sign(interactionID string, seq int, tool, input, output string, executedAt time.Time) audit.ToolReceipt {
+	r := audit.ToolReceipt{
+		InteractionID: interactionID,
+		Seq:           seq,
+		Tool:          tool,
+		InputHash:     Hash(input),
+		OutputHash:    Hash(output),
+		ExecutedAt:    executedAt.UTC(),
+		Runner:        s.identity,
+		KeyID:         s.keyID,
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload(r)))
+	return r
+}
+
+// Hash 计算回执中使用的 SHA-256
+func Hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// payload 签名的内容，包括回执所属的交互和序号
+func payload(r audit.ToolReceipt) []byte {
+	return []byte(strings.Join([]string{
+		payloadVersion, r.InteractionID, strconv.Itoa(r.Seq), r.Tool, r.InputHash, r.OutputHash, r.ExecutedAt.UTC().Format(time.RFC3339Nano), r.Runner, r.KeyID,
+	}, "\n"))
+}
+
+// Verify 使用公钥校验回执签名，keys 按 KeyID 索引
+func Verify(r audit.ToolReceipt, keys map[string]ed25519.PublicKey) error {
+	key, ok := keys[r.KeyID]
+	if !ok {
+		return fmt.Errorf("未知的签名公钥 %s", r.KeyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return fmt.Errorf("签名格式无效: %v", err)
+	}
+	if !ed25519.Verify(key, payload(r), signature) {
+		return errors.New("签名无效，回执内容已被修改")
+	}
+	return nil
+}
+
+var (
+	defaultSigner *Signer
+	defaultErr    error
+	defaultOnce   sync.Once
+)
+
+// Enabled 是否启用执行回执，配置项 receipts.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("receipts.enabled")
+}
+
+// Default 按 receipts.key_file 和 receipts.identity 创建的签发方，未启用时返回 nil
+func Default() *Signer {
+	if !Enabled() {
+		return nil
+	}
+	defaultOnce.Do(func() {
+		config := utils.GetConfig()
+		defaultSigner, defaultErr = LoadSigner(config.GetString("receipts.key_file"), config.GetString("receipts.identity"))
+		if defaultErr != nil {
+			utils.Error("加载回执签名私钥失败，不会签发执行回执", zap.Error(defaultErr))
+			return
+		}
+		utils.Info("已启用工具执行回执",
+			zap.String("identity", defaultSigner.identity),
+			zap.String("key_id", defaultSigner.keyID),
+			zap.String("public_key", base64.StdEncoding.EncodeToString(defaultSigner.PublicKey())),
+		)
+	})
+	return defaultSigner
+}
+
+// TrustedKeys 校验回执时信任的公钥：本服务的公钥和 receipts.trusted_keys 中配置的 runner 公钥
+func TrustedKeys() map[string]ed25519.PublicKey {
+	keys := map[string]ed25519.PublicKey{}
+	if signer := Default(); signer != nil {
+		keys[signer.keyID] = signer.PublicKey()
+	}
+	for _, value := range utils.GetConfig().GetStringSlice("receipts.trusted_keys") {
+		key, err := ParsePublicKey(value)
+		if err != nil {
+			utils.Warn("receipts.trusted_keys 中的公钥无效", zap.Error(err))
+			continue
+		}
+		keys[KeyID(key)] = key
+	}
+	return keys
+}
@@ -0,0 +1,72 @@
+package receipts
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	signer := NewSigner(key, "runner-prod")
+	keys := map[string]ed25519.PublicKey{KeyID(signer.PublicKey()): signer.PublicKey()}
+
+	r := signer.Sign("i-1", 3, "kubectl", "kubectl get pods", "NAME READY", time.Now())
+	if r.Runner != "runner-prod" || r.InputHash != Hash("kubectl get pods") || r.InteractionID != "i-1" || r.Seq != 3 {
+		t.Fatalf("unexpected receipt %+v", r)
+	}
+	if err := Verify(r, keys); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	tampered := r
+	tampered.OutputHash = Hash("No resources found")
+	if err := Verify(tampered, keys); err == nil {
+		t.Error("expected a modified output hash to fail verification")
+	}
+	// 交互和序号参与签名，回执不能被复制到其他交互或调换位置
+	moved := r
+	moved.InteractionID = "i-2"
+	if err := Verify(moved, keys); err == nil {
+		t.Error("expected a receipt moved to another interaction to fail verification")
+	}
+	reordered := r
+	reordered.Seq = 1
+	if err := Verify(reordered, keys); err == nil {
+		t.Error("expected a receipt with a different seq to fail verification")
+	}
+	if err := Verify(r, map[string]ed25519.PublicKey{}); err == nil || !strings.Contains(err.Error(), "未知") {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	public, key, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "receipts.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := LoadSigner(path, "opsagent")
+	if err != nil {
+		t.Fatalf("LoadSigner() error = %v", err)
+	}
+	if !signer.PublicKey().Equal(public) || signer.Identity() != "opsagent" {
+		t.Errorf("unexpected signer %s %s", KeyID(signer.PublicKey()), signer.Identity())
+	}
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(public)
+	parsed, err := ParsePublicKey(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})))
+	if err != nil || !parsed.Equal(public) {
+		t.Errorf("ParsePublicKey() = %v, %v", parsed, err)
+	}
+}
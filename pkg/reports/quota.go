@@ -0,0 +1,70 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+// maxReportFindings 报告中列出的问题条数，其余只给出数量
+const maxReportFindings = 30
+
+// QuotaCompliance 配额合规报告：接近配额的命名空间、未设置 requests/limits 或超出 LimitRange 的工作负载
+func QuotaCompliance(ctx context.Context, cluster string) (notify.Message, error) {
+	report, err := tools.CheckQuotaCompliance(ctx, cluster, "", 0)
+	if err != nil {
+		return notify.Message{}, err
+	}
+	return quotaComplianceMessage(cluster, report), nil
+}
+
+func quotaComplianceMessage(cluster string, report *kubernetes.ComplianceReport) notify.Message {
+	if cluster == "" {
+		cluster = "当前集群"
+	}
+	msg := notify.Message{
+		Title: fmt.Sprintf("配额合规报告 %s", cluster),
+		Level: notify.LevelInfo,
+	}
+
+	var critical, warnings int
+	for _, f := range report.Findings {
+		if f.Severity == kubernetes.SeverityCritical {
+			critical++
+		} else {
+			warnings++
+		}
+	}
+	switch {
+	case critical > 0:
+		msg.Level = notify.LevelCritical
+	case warnings > 0:
+		msg.Level = notify.LevelWarning
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "检查了 %d 个命名空间，%d 个配额已用尽，%d 个警告。\n\n", report.Namespaces, critical, warnings)
+	for i, f := range report.Findings {
+		if i == maxReportFindings {
+			fmt.Fprintf(&b, "- ……另有 %d 条未列出\n", len(report.Findings)-maxReportFindings)
+			break
+		}
+		target := f.Namespace
+		if f.Workload != "" {
+			target += " " + f.Workload
+		}
+		if f.Container != "" {
+			target += "/" + f.Container
+		}
+		fmt.Fprintf(&b, "- [%s] %s: %s\n", f.Severity, target, f.Message)
+	}
+	if len(report.Findings) == 0 {
+		b.WriteString("所有命名空间的配额使用率低于阈值，工作负载均设置了 requests/limits。\n")
+	}
+	msg.Text = strings.TrimSpace(b.String())
+	return msg
+}
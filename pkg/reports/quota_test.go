@@ -0,0 +1,29 @@
+package reports
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+)
+
+func TestQuotaComplianceMessage(t *testing.T) {
+	msg := quotaComplianceMessage("prod", &kubernetes.ComplianceReport{
+		Namespaces: 3,
+		Findings: []kubernetes.ComplianceFinding{
+			{Severity: kubernetes.SeverityCritical, Namespace: "shop", Message: "quota compute: requests.cpu used 10 of 10 (100%)"},
+			{Severity: kubernetes.SeverityWarning, Namespace: "tools", Workload: "Pod/debug", Container: "sh", Message: "missing cpu request"},
+		},
+	})
+	if msg.Level != notify.LevelCritical || msg.Title != "配额合规报告 prod" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "1 个配额已用尽，1 个警告") || !strings.Contains(msg.Text, "tools Pod/debug/sh: missing cpu request") {
+		t.Errorf("unexpected text:\n%s", msg.Text)
+	}
+
+	if msg := quotaComplianceMessage("", &kubernetes.ComplianceReport{Namespaces: 1}); msg.Level != notify.LevelInfo {
+		t.Errorf("expected info level without findings, got %+v", msg)
+	}
+}
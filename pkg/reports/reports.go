@@ -0,0 +1,107 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval = 24 * time.Hour
+	// reportTimeout 单次生成并发送报告的超时时间
+	reportTimeout = 2 * time.Minute
+)
+
+// Template 报告模板，针对一个集群生成一次报告的通知内容
+type Template func(ctx context.Context, cluster string) (notify.Message, error)
+
+// templates 内置报告模板
+var templates = map[string]Template{
+	"quota_compliance":    QuotaCompliance,
+	"restart_correlation": RestartCorrelation,
+}
+
+// Templates 返回内置报告模板名称
+func Templates() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schedule 定时报告，通过 reports.schedules 配置
+type Schedule struct {
+	Template string        `mapstructure:"template"`
+	Cluster  string        `mapstructure:"cluster"`  // kubeconfig context 或集群登记名称，为空时使用当前 context
+	Interval time.Duration `mapstructure:"interval"` // 默认 24h
+	Channel  string        `mapstructure:"channel"`  // 通知渠道名称，为空时发送到所有渠道
+}
+
+// Start 为 reports.schedules 中的每个定时报告启动后台任务，第一次在启动一个周期后执行
+func Start(ctx context.Context) {
+	var schedules []Schedule
+	if err := utils.GetConfig().UnmarshalKey("reports.schedules", &schedules); err != nil {
+		utils.Error("解析定时报告配置失败", zap.Error(err))
+		return
+	}
+	if len(schedules) > 0 && !notify.Default().Enabled() {
+		utils.Warn("未配置通知渠道，定时报告不会启动")
+		return
+	}
+	for _, schedule := range schedules {
+		if _, ok := templates[schedule.Template]; !ok {
+			utils.Warn("未知的报告模板，已忽略", zap.String("template", schedule.Template), zap.Strings("available", Templates()))
+			continue
+		}
+		if schedule.Interval <= 0 {
+			schedule.Interval = defaultInterval
+		}
+		go run(ctx, schedule)
+	}
+}
+
+func run(ctx context.Context, schedule Schedule) {
+	logger := utils.GetLogger().Named("reports").With(
+		zap.String("template", schedule.Template),
+		zap.String("cluster", schedule.Cluster),
+	)
+	logger.Info("定时报告已启动", zap.Duration("interval", schedule.Interval))
+	ticker := time.NewTicker(schedule.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := Send(ctx, schedule); err != nil {
+			logger.Warn("发送定时报告失败", zap.Error(err))
+		}
+	}
+}
+
+// Send 立即生成一次报告并发送到通知渠道
+func Send(ctx context.Context, schedule Schedule) error {
+	template, ok := templates[schedule.Template]
+	if !ok {
+		return fmt.Errorf("未知的报告模板 %s", schedule.Template)
+	}
+	ctx, cancel := context.WithTimeout(ctx, reportTimeout)
+	defer cancel()
+
+	msg, err := template(ctx, schedule.Cluster)
+	if err != nil {
+		return err
+	}
+	if schedule.Channel != "" {
+		return notify.Default().SendTo(ctx, schedule.Channel, msg)
+	}
+	return notify.Default().Send(ctx, msg)
+}
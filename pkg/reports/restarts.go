@@ -0,0 +1,47 @@
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/notify"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+// RestartCorrelation 容器重启报告：按集群和工作负载汇总 restarts.window 内的重启次数和 OOMKill，
+// 时间换算到 restarts.timezone；cluster 可以用逗号分隔多个集群，汇总到同一份报告
+func RestartCorrelation(ctx context.Context, cluster string) (notify.Message, error) {
+	var clusters []string
+	for _, c := range strings.Split(cluster, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			clusters = append(clusters, c)
+		}
+	}
+	report, err := tools.CollectRestarts(ctx, tools.RestartsOptions{Clusters: clusters})
+	if err != nil {
+		return notify.Message{}, err
+	}
+	return restartCorrelationMessage(cluster, report), nil
+}
+
+func restartCorrelationMessage(cluster string, report *kubernetes.RestartReport) notify.Message {
+	if cluster == "" {
+		cluster = "当前集群"
+	}
+	msg := notify.Message{
+		Title: fmt.Sprintf("容器重启报告 %s", cluster),
+		Level: notify.LevelInfo,
+	}
+	if len(report.Workloads) == 0 {
+		msg.Text = fmt.Sprintf("%s 至 %s（%s）期间没有容器重启。",
+			report.Since.Format("01-02 15:04"), report.Until.Format("01-02 15:04"), report.Location)
+		return msg
+	}
+	if report.TotalOOMKills() > 0 {
+		msg.Level = notify.LevelWarning
+	}
+	msg.Text = strings.TrimSpace(report.Format(maxReportFindings))
+	return msg
+}
@@ -0,0 +1,131 @@
+package runners
+
+import (
+	"context"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+const (
+	capabilitiesMethod = "/" + serviceName + "/Capabilities"
+	// defaultCapabilitiesTTL runner 声明的能力缓存时长，可通过 runners.capabilities_ttl 修改
+	defaultCapabilitiesTTL = 5 * time.Minute
+	// capabilitiesRetry 查询能力失败后多久再次尝试，期间按能力未知处理
+	capabilitiesRetry = 30 * time.Second
+	// capabilitiesTimeout 单次查询能力的超时时间
+	capabilitiesTimeout = 5 * time.Second
+)
+
+// toolBinaries 工具依赖的命令，命令不在 runner 的 PATH 中时不声明该工具
+var toolBinaries = map[string]string{
+	"kubectl": "kubectl",
+	"python":  "python3",
+	"trivy":   "trivy",
+	"jq":      "jq",
+}
+
+// lookPath 查找命令，测试中替换
+var lookPath = exec.LookPath
+
+// CapabilitiesRequest 查询 runner 的能力
+type CapabilitiesRequest struct{}
+
+// Capabilities runner 声明的能力，中心服务据此路由工具调用并裁剪系统提示中的工具列表
+type Capabilities struct {
+	Tools    []string `json:"tools"`    // 允许执行且依赖的命令存在的工具
+	Binaries []string `json:"binaries"` // PATH 中存在的命令
+	Contexts []string `json:"contexts"` // 可访问的 kubeconfig context，没有 kubeconfig 时为 in-cluster
+}
+
+// HasTool 是否支持工具 name
+func (c *Capabilities) HasTool(name string) bool {
+	return containsString(c.Tools, name)
+}
+
+// HasContext 是否可以访问 kubeconfig context
+func (c *Capabilities) HasContext(kubeContext string) bool {
+	return kubeContext != "" && containsString(c.Contexts, kubeContext)
+}
+
+// Capabilities 返回本 runner 的能力：允许执行的工具中已注册且依赖的命令存在的部分，以及可访问的 context
+func (s *Server) Capabilities(ctx context.Context, req *CapabilitiesRequest) (*Capabilities, error) {
+	caps := &Capabilities{}
+	found := map[string]bool{}
+	for _, binary := range toolBinaries {
+		if found[binary] {
+			continue
+		}
+		if _, err := lookPath(binary); err == nil {
+			found[binary] = true
+			caps.Binaries = append(caps.Binaries, binary)
+		}
+	}
+	for name := range s.allowed {
+		if _, ok := tools.Registry.Get(name); !ok {
+			continue
+		}
+		if binary, ok := toolBinaries[name]; ok && !found[binary] {
+			continue
+		}
+		caps.Tools = append(caps.Tools, name)
+	}
+	if contexts, _, err := kubernetes.ListContexts(); err == nil && len(contexts) > 0 {
+		caps.Contexts = contexts
+	} else {
+		caps.Contexts = []string{kubernetes.InClusterName}
+	}
+	sort.Strings(caps.Binaries)
+	sort.Strings(caps.Tools)
+	sort.Strings(caps.Contexts)
+	return caps, nil
+}
+
+// cachedCapabilities 缓存的 runner 能力，caps 为 nil 表示查询失败
+type cachedCapabilities struct {
+	caps    *Capabilities
+	expires time.Time
+}
+
+// Capabilities 返回 runner 声明的能力，按 capabilities_ttl 缓存；runner 不可达时返回 nil
+func (c *Client) Capabilities(e Endpoint) *Capabilities {
+	c.mu.Lock()
+	cached, ok := c.capabilities[e.Address]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.caps
+	}
+
+	cached = cachedCapabilities{expires: time.Now().Add(capabilitiesRetry)}
+	if conn, err := c.conn(e); err != nil {
+		utils.Warn("连接 runner 失败", zap.String("address", e.Address), zap.Error(err))
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), capabilitiesTimeout)
+		defer cancel()
+		var caps Capabilities
+		if err := conn.Invoke(ctx, capabilitiesMethod, &CapabilitiesRequest{}, &caps, grpc.CallContentSubtype(codecName)); err != nil {
+			utils.Warn("查询 runner 能力失败", zap.String("address", e.Address), zap.Error(err))
+		} else {
+			cached = cachedCapabilities{caps: &caps, expires: time.Now().Add(c.capabilitiesTTL)}
+		}
+	}
+	c.mu.Lock()
+	c.capabilities[e.Address] = cached
+	c.mu.Unlock()
+	return cached.caps
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
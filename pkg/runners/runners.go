@@ -0,0 +1,324 @@
+// Package runners 在各集群内部署轻量的 runner 执行访问集群的工具调用，结果通过 mTLS gRPC 返回中心服务，
+// 中心服务不再需要持有每个集群的 kubeconfig
+//
+// 协议没有使用 protoc 生成的代码：服务描述在本包中手写，消息以 JSON 编码（content-subtype 为 json）
+package runners
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	serviceName   = "opsagent.runner.v1.Runner"
+	executeMethod = "/" + serviceName + "/Execute"
+	codecName     = "json"
+)
+
+// ExecuteRequest 一次工具调用
+type ExecuteRequest struct {
+	Tool  string `json:"tool"`
+	Input string `json:"input"`
+	User  string `json:"user,omitempty"`
+	Role  string `json:"role,omitempty"`
+	// Context runner 服务多个集群时使用的 kubeconfig context，为空时使用 runner 的默认配置
+	Context string `json:"context,omitempty"`
+	// Approved 中心服务上已审批通过的变更命令，runner 直接放行
+	Approved bool `json:"approved,omitempty"`
+	// InteractionID、Seq 回执在中心服务审计中的位置，runner 签发回执时一并签名
+	InteractionID string `json:"interaction_id,omitempty"`
+	Seq           int    `json:"seq,omitempty"`
+}
+
+// ExecuteResponse 工具调用结果，工具返回的错误放在 Error 中，gRPC 错误只表示调用本身失败
+type ExecuteResponse struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+	// Receipt runner 配置了签名私钥时签发的执行回执
+	Receipt *audit.ToolReceipt `json:"receipt,omitempty"`
+}
+
+// RemoteError runner 上执行工具返回的错误
+type RemoteError struct {
+	Cluster string
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// jsonCodec 以 JSON 编码 gRPC 消息
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return codecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// TLSConfig mTLS 证书配置，中心服务和 runner 使用同一个 CA 签发的证书互相校验
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	CAFile   string `mapstructure:"ca_file"`
+}
+
+// load 读取证书和 CA，server 为 true 时要求并校验客户端证书
+func (c TLSConfig) load(server bool) (*tls.Config, error) {
+	if c.CertFile == "" || c.KeyFile == "" || c.CAFile == "" {
+		return nil, fmt.Errorf("mTLS 需要 cert_file、key_file 和 ca_file")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取证书失败: %v", err)
+	}
+	caPEM, err := os.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 证书失败: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("CA 证书 %s 中没有有效的证书", c.CAFile)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if server {
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+// Endpoint runner 的地址，通过 runners.endpoints 配置
+// 设置了 Cluster 的 runner 只执行该集群的工具调用；未设置时按 runner 声明的 context 路由，一个 runner 可以服务多个集群
+type Endpoint struct {
+	Cluster    string `mapstructure:"cluster"`     // 集群登记名称或 kubeconfig context
+	Address    string `mapstructure:"address"`     // host:port
+	ServerName string `mapstructure:"server_name"` // 校验 runner 证书时使用的名称，为空时使用地址中的主机名
+}
+
+// Client 连接各集群 runner 的客户端
+type Client struct {
+	tlsConfig *tls.Config
+	endpoints []Endpoint
+	// capabilitiesTTL runner 声明的能力缓存时长
+	capabilitiesTTL time.Duration
+
+	mu           sync.Mutex
+	conns        map[string]*grpc.ClientConn
+	capabilities map[string]cachedCapabilities
+}
+
+// NewClient 创建 runner 客户端，连接在第一次调用时建立
+func NewClient(endpoints []Endpoint, tlsConfig TLSConfig) (*Client, error) {
+	config, err := tlsConfig.load(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range endpoints {
+		if e.Address == "" {
+			return nil, fmt.Errorf("runner 缺少 address: %+v", e)
+		}
+	}
+	return &Client{
+		tlsConfig:       config,
+		endpoints:       endpoints,
+		capabilitiesTTL: defaultCapabilitiesTTL,
+		conns:           map[string]*grpc.ClientConn{},
+		capabilities:    map[string]cachedCapabilities{},
+	}, nil
+}
+
+var (
+	defaultClient *Client
+	defaultMu     sync.Mutex
+)
+
+// Start 按 runners.endpoints 和 runners.tls 创建客户端，使访问这些集群的工具调用转发到 runner，
+// 系统提示中只列出 runner 声明支持的工具
+func Start() error {
+	var endpoints []Endpoint
+	config := utils.GetConfig()
+	if err := config.UnmarshalKey("runners.endpoints", &endpoints); err != nil {
+		return fmt.Errorf("解析 runners.endpoints 失败: %v", err)
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+	var tlsConfig TLSConfig
+	if err := config.UnmarshalKey("runners.tls", &tlsConfig); err != nil {
+		return fmt.Errorf("解析 runners.tls 失败: %v", err)
+	}
+	client, err := NewClient(endpoints, tlsConfig)
+	if err != nil {
+		return err
+	}
+	if ttl := config.GetDuration("runners.capabilities_ttl"); ttl > 0 {
+		client.capabilitiesTTL = ttl
+	}
+	defaultMu.Lock()
+	defaultClient = client
+	defaultMu.Unlock()
+	tools.SetRemoteFunc(client.Remote)
+	tools.SetAvailabilityFunc(client.Available)
+	utils.Info("已启用集群 runner", zap.Int("endpoints", len(endpoints)))
+	return nil
+}
+
+// Close 关闭默认客户端的连接，服务退出时调用
+func Close() {
+	defaultMu.Lock()
+	client := defaultClient
+	defaultClient = nil
+	defaultMu.Unlock()
+	if client != nil {
+		tools.SetRemoteFunc(nil)
+		tools.SetAvailabilityFunc(nil)
+		client.Close()
+	}
+}
+
+// route 查找执行集群 cluster 上工具 name 的 runner，cluster 可以是集群登记名称或其对应的 kubeconfig context
+// serves 表示集群由 runner 执行工具调用；ok 表示找到了支持该工具的 runner，kubeContext 为需要 runner 使用的 context
+func (c *Client) route(cluster, name string) (e Endpoint, kubeContext string, serves, ok bool) {
+	if cluster == "" {
+		return Endpoint{}, "", false, false
+	}
+	target, _ := clusters.Default().Target(cluster)
+	for _, e := range c.endpoints {
+		if e.Cluster != "" && e.Cluster != cluster && e.Cluster != target {
+			continue
+		}
+		caps := c.Capabilities(e)
+		if e.Cluster == "" {
+			// 未设置集群的 runner 只服务其声明的 context，能力未知时跳过
+			if caps == nil || !caps.HasContext(target) {
+				continue
+			}
+			kubeContext = target
+		} else if caps != nil && caps.HasContext(target) {
+			kubeContext = target
+		}
+		serves = true
+		// 能力未知（runner 暂时不可达）时仍然转发，由调用返回不可达的原因
+		if caps == nil || caps.HasTool(name) {
+			return e, kubeContext, true, true
+		}
+		kubeContext = ""
+	}
+	return Endpoint{}, "", serves, false
+}
+
+// Remote 实现 tools.RemoteFunc，集群的 runner 都不支持该工具时返回错误而不是在中心服务执行
+func (c *Client) Remote(cluster, name string) (tools.ToolFunc, bool) {
+	e, kubeContext, serves, ok := c.route(cluster, name)
+	if !serves {
+		return nil, false
+	}
+	if !ok {
+		return func(ctx context.Context, input string) (string, error) {
+			err := fmt.Errorf("集群 %s 的 runner 不支持工具 %s", cluster, name)
+			return err.Error(), err
+		}, true
+	}
+	return func(ctx context.Context, input string) (string, error) {
+		interactionID, seq := tools.ReceiptScope(ctx)
+		return c.execute(ctx, cluster, e, ExecuteRequest{
+			Tool:          name,
+			Input:         input,
+			Context:       kubeContext,
+			User:          tools.UserFromContext(ctx),
+			Role:          tools.RoleFromContext(ctx),
+			Approved:      tools.CommandApproved(ctx, name, input),
+			InteractionID: interactionID,
+			Seq:           seq,
+		})
+	}, true
+}
+
+// Available 实现 tools.AvailabilityFunc，集群没有 runner 时由中心服务执行，视为可用
+func (c *Client) Available(cluster, name string) bool {
+	_, _, serves, ok := c.route(cluster, name)
+	return !serves || ok
+}
+
+// Execute 在集群的 runner 上执行一次工具调用
+func (c *Client) Execute(ctx context.Context, cluster string, req ExecuteRequest) (string, error) {
+	e, kubeContext, serves, ok := c.route(cluster, req.Tool)
+	if !serves {
+		err := fmt.Errorf("集群 %s 没有配置 runner", cluster)
+		return err.Error(), err
+	}
+	if !ok {
+		err := fmt.Errorf("集群 %s 的 runner 不支持工具 %s", cluster, req.Tool)
+		return err.Error(), err
+	}
+	if req.Context == "" {
+		req.Context = kubeContext
+	}
+	return c.execute(ctx, cluster, e, req)
+}
+
+func (c *Client) execute(ctx context.Context, cluster string, e Endpoint, req ExecuteRequest) (string, error) {
+	conn, err := c.conn(e)
+	if err != nil {
+		return err.Error(), err
+	}
+	var resp ExecuteResponse
+	if err := conn.Invoke(ctx, executeMethod, &req, &resp, grpc.CallContentSubtype(codecName)); err != nil {
+		err = fmt.Errorf("runner %s 不可达: %w", cluster, err)
+		return err.Error(), err
+	}
+	if resp.Receipt != nil {
+		tools.SetRemoteReceipt(ctx, *resp.Receipt)
+	}
+	if resp.Error != "" {
+		return resp.Output, &RemoteError{Cluster: cluster, Message: resp.Error}
+	}
+	return resp.Output, nil
+}
+
+func (c *Client) conn(e Endpoint) (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if conn, ok := c.conns[e.Address]; ok {
+		return conn, nil
+	}
+	config := c.tlsConfig.Clone()
+	config.ServerName = e.ServerName
+	conn, err := grpc.NewClient(e.Address, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		return nil, err
+	}
+	c.conns[e.Address] = conn
+	return conn, nil
+}
+
+// Close 关闭所有连接
+func (c *Client) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for address, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, address)
+	}
+}
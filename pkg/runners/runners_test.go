@@ -0,0 +1,201 @@
+package runners
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/tools"
+)
+
+// writeCerts 在 dir 中生成 CA 以及由它签发的服务端证书和客户端证书
+func writeCerts(t *testing.T, dir string) (server, client TLSConfig) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "opsagent-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) TLSConfig {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		config := TLSConfig{CertFile: filepath.Join(dir, name+".pem"), KeyFile: filepath.Join(dir, name+"-key.pem"), CAFile: caFile}
+		writePEM(t, config.CertFile, "CERTIFICATE", der)
+		writePEM(t, config.KeyFile, "EC PRIVATE KEY", keyDER)
+		return config
+	}
+	return issue("runner", 2, x509.ExtKeyUsageServerAuth), issue("opsagent", 3, x509.ExtKeyUsageClientAuth)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	serverTLS, clientTLS := writeCerts(t, t.TempDir())
+
+	original, _ := tools.Registry.Get("kubectl")
+	tools.Registry.Unregister("kubectl")
+	tools.Registry.Register(tools.ToolSpec{Name: "kubectl", Idempotency: tools.IdempotencyPureRead, Run: func(ctx context.Context, input string) (string, error) {
+		return "ran " + input + " for " + tools.UserFromContext(ctx), nil
+	}})
+	defer func() {
+		tools.Registry.Unregister("kubectl")
+		tools.Registry.Register(original)
+	}()
+
+	// 先占用一个空闲端口再交给 Serve 监听
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := lis.Addr().String()
+	lis.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- Serve(ctx, address, NewServer([]string{"kubectl"}), serverTLS) }()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	lookPath = func(file string) (string, error) {
+		if file == "kubectl" {
+			return "/usr/local/bin/kubectl", nil
+		}
+		return "", errors.New("not found")
+	}
+	defer func() { lookPath = exec.LookPath }()
+
+	client, err := NewClient([]Endpoint{{Cluster: "prod-cn", Address: address}}, clientTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	waitForRunner(t, client, client.endpoints[0])
+
+	callCtx, callCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer callCancel()
+	output, err := client.Execute(callCtx, "prod-cn", ExecuteRequest{Tool: "kubectl", Input: "kubectl get pods", User: "alice"})
+	if err != nil || output != "ran kubectl get pods for alice" {
+		t.Fatalf("Execute = %q, %v", output, err)
+	}
+
+	var remoteErr *RemoteError
+	if _, err := client.execute(callCtx, "prod-cn", client.endpoints[0], ExecuteRequest{Tool: "python", Input: "print(1)"}); !errors.As(err, &remoteErr) || !strings.Contains(err.Error(), "not allowed") {
+		t.Errorf("expected python to be rejected by the runner, got %v", err)
+	}
+	if _, err := client.execute(callCtx, "prod-cn", client.endpoints[0], ExecuteRequest{Tool: "kubectl", Input: "get pods", Context: "staging"}); !errors.As(err, &remoteErr) {
+		t.Errorf("expected contexts the runner did not advertise to be rejected, got %v", err)
+	}
+
+	// runner 只声明了 kubectl，系统提示中不列出 nodepools，调用时也不会回退到中心服务执行
+	if !client.Available("prod-cn", "kubectl") || client.Available("prod-cn", "nodepools") {
+		t.Errorf("expected only kubectl to be available on prod-cn")
+	}
+	if run, ok := client.Remote("prod-cn", "nodepools"); !ok {
+		t.Error("expected nodepools on prod-cn to be handled by the runner routing")
+	} else if _, err := run(callCtx, ""); err == nil || !strings.Contains(err.Error(), "不支持") {
+		t.Errorf("expected unsupported tool error, got %v", err)
+	}
+	if !client.Available("dev-eu", "nodepools") {
+		t.Error("expected tools on clusters without a runner to stay available")
+	}
+
+	if _, ok := client.Remote("dev-eu", "kubectl"); ok {
+		t.Error("expected clusters without a runner to run locally")
+	}
+}
+
+// waitForRunner 等待 runner 开始监听并返回能力声明
+func waitForRunner(t *testing.T, client *Client, e Endpoint) *Capabilities {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		client.mu.Lock()
+		delete(client.capabilities, e.Address)
+		client.mu.Unlock()
+		if caps := client.Capabilities(e); caps != nil {
+			return caps
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("runner did not start")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	lookPath = func(file string) (string, error) {
+		if file == "kubectl" || file == "jq" {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	defer func() { lookPath = exec.LookPath }()
+
+	caps, err := NewServer([]string{"kubectl", "python", "rollout", "missing"}).Capabilities(context.Background(), &CapabilitiesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(caps.Tools, ",") != "kubectl,rollout" {
+		t.Errorf("expected tools without binaries or registration to be left out, got %v", caps.Tools)
+	}
+	if strings.Join(caps.Binaries, ",") != "jq,kubectl" {
+		t.Errorf("unexpected binaries %v", caps.Binaries)
+	}
+	if len(caps.Contexts) == 0 {
+		t.Error("expected at least the in-cluster context")
+	}
+}
+
+func TestServeRequiresClientCertificate(t *testing.T) {
+	serverTLS, _ := writeCerts(t, t.TempDir())
+	serverTLS.CAFile = ""
+	if err := Serve(context.Background(), "127.0.0.1:0", NewServer(nil), serverTLS); err == nil {
+		t.Error("expected Serve to refuse to start without a client CA")
+	}
+}
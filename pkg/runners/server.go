@@ -0,0 +1,163 @@
+package runners
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/receipts"
+	"github.com/myysophia/OpsAgent/pkg/tools"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// DefaultTools runner 默认允许执行的工具，即访问集群的只读工具
+var DefaultTools = []string{"kubectl", "nodepools", "rollout", "quotacheck", "restarts"}
+
+// runnerServer 手写的 gRPC 服务接口，对应 serviceDesc
+type runnerServer interface {
+	Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error)
+	Capabilities(ctx context.Context, req *CapabilitiesRequest) (*Capabilities, error)
+}
+
+// unaryHandler 解码请求 Req 并调用 call，相当于 protoc 为每个方法生成的 handler
+func unaryHandler[Req any](method string, call func(srv runnerServer, ctx context.Context, req *Req) (interface{}, error)) grpc.MethodHandler {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := new(Req)
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv.(runnerServer), ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: method}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return call(srv.(runnerServer), ctx, req.(*Req))
+		})
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*runnerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Execute",
+			Handler: unaryHandler(executeMethod, func(srv runnerServer, ctx context.Context, req *ExecuteRequest) (interface{}, error) {
+				return srv.Execute(ctx, req)
+			}),
+		},
+		{
+			MethodName: "Capabilities",
+			Handler: unaryHandler(capabilitiesMethod, func(srv runnerServer, ctx context.Context, req *CapabilitiesRequest) (interface{}, error) {
+				return srv.Capabilities(ctx, req)
+			}),
+		},
+	},
+	Metadata: "runner",
+}
+
+// Server runner 服务端，在集群内通过工具注册表执行工具，kubectl 只读策略等检查与中心服务相同
+type Server struct {
+	allowed map[string]bool
+	signer  *receipts.Signer
+}
+
+// NewServer 创建 runner 服务端，allowedTools 为空时使用 DefaultTools
+func NewServer(allowedTools []string) *Server {
+	if len(allowedTools) == 0 {
+		allowedTools = DefaultTools
+	}
+	s := &Server{allowed: map[string]bool{}}
+	for _, name := range allowedTools {
+		s.allowed[name] = true
+	}
+	return s
+}
+
+// SetSigner 为每次执行签发回执，回执随结果返回中心服务写入审计，执行方为本 runner
+func (s *Server) SetSigner(signer *receipts.Signer) {
+	s.signer = signer
+}
+
+// Execute 执行一次工具调用，调用方的用户和角色用于配额和 kubectl 变更审批检查
+func (s *Server) Execute(ctx context.Context, req *ExecuteRequest) (*ExecuteResponse, error) {
+	logger := utils.GetLogger().Named("runner").With(
+		zap.String("tool", req.Tool),
+		zap.String("user", req.User),
+		zap.String("client", clientName(ctx)),
+	)
+	if !s.allowed[req.Tool] {
+		logger.Warn("拒绝执行未允许的工具")
+		return &ExecuteResponse{Error: fmt.Sprintf("tool %s is not allowed on this runner", req.Tool)}, nil
+	}
+	ctx = tools.WithRole(tools.WithUser(ctx, req.User), req.Role)
+	if req.Context != "" {
+		caps, _ := s.Capabilities(ctx, &CapabilitiesRequest{})
+		if !caps.HasContext(req.Context) {
+			logger.Warn("拒绝访问未声明的 context", zap.String("context", req.Context))
+			return &ExecuteResponse{Error: fmt.Sprintf("context %s is not available on this runner", req.Context)}, nil
+		}
+		ctx = tools.WithKubeContext(ctx, req.Context)
+	}
+	if req.Approved {
+		ctx = tools.WithApprovedCommand(ctx, req.Tool, req.Input)
+	}
+	start := time.Now()
+	output, err := tools.Invoke(ctx, req.Tool, req.Input)
+	resp := &ExecuteResponse{Output: output}
+	if s.signer != nil {
+		receipt := s.signer.Sign(req.InteractionID, req.Seq, req.Tool, req.Input, output, start)
+		resp.Receipt = &receipt
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		logger.Info("工具调用失败", zap.Error(err))
+	}
+	return resp, nil
+}
+
+// clientName 返回客户端证书的 CommonName，用于日志
+func clientName(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.PeerCertificates) > 0 {
+		return info.State.PeerCertificates[0].Subject.CommonName
+	}
+	return p.Addr.String()
+}
+
+// NewGRPCServer 创建要求客户端证书的 gRPC 服务
+func NewGRPCServer(server *Server, tlsConfig TLSConfig) (*grpc.Server, error) {
+	config, err := tlsConfig.load(true)
+	if err != nil {
+		return nil, err
+	}
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(config)))
+	s.RegisterService(&serviceDesc, server)
+	return s, nil
+}
+
+// Serve 在 listen 地址上提供 runner 服务，ctx 取消时等待处理中的调用完成后退出
+func Serve(ctx context.Context, listen string, server *Server, tlsConfig TLSConfig) error {
+	s, err := NewGRPCServer(server, tlsConfig)
+	if err != nil {
+		return err
+	}
+	lis, err := net.Listen("tcp", listen)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		s.GracefulStop()
+	}()
+	utils.Info("runner 开始监听", zap.String("address", listen))
+	return s.Serve(lis)
+}
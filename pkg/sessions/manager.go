@@ -0,0 +1,238 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	defaultIdleTimeout = 30 * time.Minute
+	defaultMaxTurns    = 20
+	defaultMaxSessions = 1000
+
+	// CookieName HTTP 接口保存会话 ID 的 Cookie 名称
+	CookieName = "session_id"
+)
+
+// Session 一次多轮对话会话，对话历史保存在服务端
+// 每轮只保留用户问题和最终回答，工具调用过程不进入后续轮次的上下文
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	Provider  string    `json:"provider,omitempty"`
+	Model     string    `json:"model"`
+	Cluster   string    `json:"cluster,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	turnMu     sync.Mutex
+	mu         sync.Mutex
+	history    []openai.ChatCompletionMessage
+	lastActive time.Time
+	maxTurns   int
+}
+
+// BeginTurn 开始一轮对话，同一会话的多个连接按顺序执行，返回结束本轮的函数
+func (s *Session) BeginTurn() func() {
+	s.turnMu.Lock()
+	return s.turnMu.Unlock
+}
+
+// History 返回会话历史的副本
+func (s *Session) History() []openai.ChatCompletionMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]openai.ChatCompletionMessage(nil), s.history...)
+}
+
+// Recent 返回最近 turns 轮的会话历史副本，turns 不大于 0 时返回全部历史
+func (s *Session) Recent(turns int) []openai.ChatCompletionMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := s.history
+	if turns > 0 && len(history) > turns*2 {
+		history = history[len(history)-turns*2:]
+	}
+	return append([]openai.ChatCompletionMessage(nil), history...)
+}
+
+// Turns 返回会话中保留的轮次数
+func (s *Session) Turns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.history) / 2
+}
+
+// AddTurn 记录一轮问答，超过最大轮次时丢弃最早的轮次
+func (s *Session) AddTurn(question, answer string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.history = append(s.history,
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: question},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: answer},
+	)
+	if excess := len(s.history) - s.maxTurns*2; excess > 0 {
+		s.history = append([]openai.ChatCompletionMessage(nil), s.history[excess:]...)
+	}
+	s.lastActive = time.Now()
+}
+
+// Reset 清空会话历史
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = nil
+	s.lastActive = time.Now()
+}
+
+func (s *Session) touch(now time.Time) {
+	s.mu.Lock()
+	s.lastActive = now
+	s.mu.Unlock()
+}
+
+func (s *Session) idleSince(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return now.Sub(s.lastActive)
+}
+
+// Manager 会话管理器，空闲超时的会话会被清理
+type Manager struct {
+	mu          sync.Mutex
+	sessions    map[string]*Session
+	idleTimeout time.Duration
+	maxTurns    int
+	maxSessions int
+}
+
+var (
+	manager     *Manager
+	managerOnce sync.Once
+)
+
+// GetManager 获取全局会话管理器
+// 配置项：
+//   - sessions.idle_timeout: 会话空闲超时，默认 30m
+//   - sessions.max_turns: 每个会话保留的最大轮次，默认 20
+//   - sessions.max_sessions: 同时保留的最大会话数，默认 1000
+func GetManager() *Manager {
+	managerOnce.Do(func() {
+		config := utils.GetConfig()
+		manager = NewManager(config.GetDuration("sessions.idle_timeout"), config.GetInt("sessions.max_turns"), config.GetInt("sessions.max_sessions"))
+	})
+	return manager
+}
+
+// NewManager 创建会话管理器，参数不大于 0 时使用默认值
+func NewManager(idleTimeout time.Duration, maxTurns, maxSessions int) *Manager {
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+	if maxTurns <= 0 {
+		maxTurns = defaultMaxTurns
+	}
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxSessions
+	}
+	return &Manager{
+		sessions:    make(map[string]*Session),
+		idleTimeout: idleTimeout,
+		maxTurns:    maxTurns,
+		maxSessions: maxSessions,
+	}
+}
+
+// Create 创建新会话，会话数达到上限时先清理空闲会话，仍然超限时淘汰最久未活动的会话
+func (m *Manager) Create(username, provider, model, cluster string) *Session {
+	now := time.Now()
+	s := &Session{
+		ID:         newSessionID(),
+		Username:   username,
+		Provider:   provider,
+		Model:      model,
+		Cluster:    cluster,
+		CreatedAt:  now,
+		lastActive: now,
+		maxTurns:   m.maxTurns,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.sessions) >= m.maxSessions {
+		m.sweepLocked(now)
+	}
+	if len(m.sessions) >= m.maxSessions {
+		var oldest *Session
+		for _, candidate := range m.sessions {
+			if oldest == nil || candidate.idleSince(now) > oldest.idleSince(now) {
+				oldest = candidate
+			}
+		}
+		delete(m.sessions, oldest.ID)
+	}
+	m.sessions[s.ID] = s
+	return s
+}
+
+// Get 获取用户的会话，会话不存在、已超时或不属于该用户时返回 false
+func (m *Manager) Get(id, username string) (*Session, bool) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.Username != username {
+		return nil, false
+	}
+	if s.idleSince(now) > m.idleTimeout {
+		delete(m.sessions, id)
+		return nil, false
+	}
+	s.touch(now)
+	return s, true
+}
+
+// IdleTimeout 会话空闲超时，用于设置会话 Cookie 的有效期
+func (m *Manager) IdleTimeout() time.Duration {
+	return m.idleTimeout
+}
+
+// Close 删除会话
+func (m *Manager) Close(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+}
+
+// Sweep 清理空闲超时的会话，返回清理的数量
+func (m *Manager) Sweep() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sweepLocked(time.Now())
+}
+
+func (m *Manager) sweepLocked(now time.Time) int {
+	var removed int
+	for id, s := range m.sessions {
+		if s.idleSince(now) > m.idleTimeout {
+			delete(m.sessions, id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// newSessionID 生成随机会话 ID
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionHistory(t *testing.T) {
+	m := NewManager(time.Minute, 2, 10)
+	s := m.Create("alice", "", "gpt-4", "prod-east")
+
+	s.AddTurn("q1", "a1")
+	s.AddTurn("q2", "a2")
+	s.AddTurn("q3", "a3")
+	history := s.History()
+	if len(history) != 4 || history[0].Content != "q2" || history[3].Content != "a3" {
+		t.Fatalf("expected the two most recent turns, got %+v", history)
+	}
+	if recent := s.Recent(1); len(recent) != 2 || recent[0].Content != "q3" {
+		t.Errorf("expected only the last turn, got %+v", recent)
+	}
+	if s.Turns() != 2 {
+		t.Errorf("expected 2 turns, got %d", s.Turns())
+	}
+
+	s.Reset()
+	if len(s.History()) != 0 {
+		t.Error("expected empty history after reset")
+	}
+}
+
+func TestManagerGet(t *testing.T) {
+	m := NewManager(time.Minute, 0, 2)
+	s := m.Create("alice", "", "gpt-4", "")
+
+	if _, ok := m.Get(s.ID, "bob"); ok {
+		t.Error("expected session of another user to be hidden")
+	}
+	if got, ok := m.Get(s.ID, "alice"); !ok || got != s {
+		t.Fatal("expected to get own session")
+	}
+
+	// 空闲超时的会话被清理
+	s.lastActive = time.Now().Add(-2 * time.Minute)
+	if _, ok := m.Get(s.ID, "alice"); ok {
+		t.Error("expected idle session to expire")
+	}
+
+	// 达到上限时淘汰最久未活动的会话
+	first := m.Create("alice", "", "gpt-4", "")
+	first.lastActive = time.Now().Add(-30 * time.Second)
+	second := m.Create("alice", "", "gpt-4", "")
+	third := m.Create("alice", "", "gpt-4", "")
+	if _, ok := m.Get(first.ID, "alice"); ok {
+		t.Error("expected least recently active session to be evicted")
+	}
+	for _, s := range []*Session{second, third} {
+		if _, ok := m.Get(s.ID, "alice"); !ok {
+			t.Errorf("expected session %s to be kept", s.ID)
+		}
+	}
+
+	m.Close(third.ID)
+	if _, ok := m.Get(third.ID, "alice"); ok {
+		t.Error("expected closed session to be removed")
+	}
+}
@@ -0,0 +1,212 @@
+// Package snapshots 定期保存各集群主要资源的快照（只读，名称、标签、属主等元数据，
+// 以及工作负载的副本数、镜像和资源配置、ConfigMap 内容的哈希），
+// 用于回答"上周二集群里有哪些资源"、"昨天以来有什么变化"这类问题和事后复盘
+package snapshots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval  = 6 * time.Hour
+	defaultRetention = 30 * 24 * time.Hour
+	// snapshotTimeout 单个集群一次快照的超时时间
+	snapshotTimeout = 5 * time.Minute
+)
+
+// ErrStoreDisabled 未启用审计存储，快照保存在审计数据库中
+var ErrStoreDisabled = errors.New("audit store is not enabled")
+
+// Enabled 是否启用集群快照，配置项 snapshots.enabled
+func Enabled() bool {
+	return utils.GetConfig().GetBool("snapshots.enabled")
+}
+
+// Start 为 snapshots.clusters 中的每个集群启动定时快照，未配置时使用当前 context
+// 启动后立即保存一次，之后每 snapshots.interval（默认 6h）保存一次并清理超过 snapshots.retention（默认 30 天）的快照
+func Start(ctx context.Context) {
+	config := utils.GetConfig()
+	if audit.GetStore() == nil {
+		utils.Warn("集群快照保存在审计数据库中，未启用 audit.enabled，不会启动")
+		return
+	}
+	clusters := config.GetStringSlice("snapshots.clusters")
+	if len(clusters) == 0 {
+		_, current, err := kubernetes.ListContexts()
+		if err != nil {
+			utils.Warn("读取 kubeconfig 失败，使用集群内配置保存快照", zap.Error(err))
+		}
+		clusters = []string{current}
+	}
+	interval := config.GetDuration("snapshots.interval")
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	for _, cluster := range clusters {
+		go run(ctx, cluster, interval)
+	}
+}
+
+func run(ctx context.Context, cluster string, interval time.Duration) {
+	logger := utils.GetLogger().Named("snapshots").With(zap.String("cluster", cluster))
+	logger.Info("集群快照已启动", zap.Duration("interval", interval))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if snapshot, err := Take(ctx, cluster); err != nil {
+			logger.Warn("保存集群快照失败", zap.Error(err))
+		} else {
+			logger.Info("已保存集群快照", zap.Int("objects", snapshot.Objects), zap.Int("bytes", snapshot.Size))
+		}
+		if n, err := Prune(ctx); err != nil {
+			logger.Warn("清理过期集群快照失败", zap.Error(err))
+		} else if n > 0 {
+			logger.Info("已清理过期集群快照", zap.Int("deleted", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Take 立即保存一次集群快照，资源类型由 snapshots.kinds 配置
+func Take(ctx context.Context, cluster string) (*audit.ClusterSnapshot, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("cluster_snapshot")()
+
+	store := audit.GetStore()
+	if store == nil {
+		return nil, ErrStoreDisabled
+	}
+	ctx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+
+	client, err := kubernetes.ClientsetForContext(cluster)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := kubernetes.TakeSnapshot(ctx, client, storedName(cluster), utils.GetConfig().GetStringSlice("snapshots.kinds"), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := kubernetes.EncodeSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	record := &audit.ClusterSnapshot{
+		Cluster: snapshot.Cluster,
+		TakenAt: snapshot.TakenAt,
+		Objects: len(snapshot.Objects),
+		Size:    len(data),
+		Data:    data,
+	}
+	if err := store.SaveClusterSnapshot(ctx, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// At 返回集群在 at 时刻或之前最近的一份快照
+func At(ctx context.Context, cluster string, at time.Time) (*kubernetes.Snapshot, error) {
+	store := audit.GetStore()
+	if store == nil {
+		return nil, ErrStoreDisabled
+	}
+	record, err := store.ClusterSnapshotAt(ctx, storedName(cluster), at)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := kubernetes.DecodeSnapshot(record.Data)
+	if err != nil {
+		return nil, fmt.Errorf("解析集群快照失败: %v", err)
+	}
+	return snapshot, nil
+}
+
+// Capture 立即获取集群的实时快照（不保存），namespaces 不为空时只包含这些命名空间的资源
+// 资源类型由 snapshots.kinds 配置，不需要启用审计存储
+func Capture(ctx context.Context, cluster string, namespaces []string) (*kubernetes.Snapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+	client, err := kubernetes.ClientsetForContext(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.TakeSnapshot(ctx, client, storedName(cluster), utils.GetConfig().GetStringSlice("snapshots.kinds"), namespaces)
+}
+
+// Compare 比较集群在 from 时刻的快照与 to 时刻的快照，to 为零值时与当前的实时快照比较
+// namespaces 不为空时只比较这些命名空间的资源
+func Compare(ctx context.Context, cluster string, from, to time.Time, namespaces []string) (*kubernetes.SnapshotDiff, error) {
+	before, err := At(ctx, cluster, from)
+	if err != nil {
+		return nil, err
+	}
+	var after *kubernetes.Snapshot
+	if to.IsZero() {
+		after, err = Capture(ctx, cluster, namespaces)
+	} else {
+		after, err = At(ctx, cluster, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.DiffSnapshots(before, after, namespaces), nil
+}
+
+// ParseTime 解析时间点：RFC3339、日期（当天结束时，UTC）或相对 now 的时长（如 24h、7d）
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	var d time.Duration
+	var err error
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		d, err = time.ParseDuration(days + "h")
+		d *= 24
+	} else {
+		d, err = time.ParseDuration(value)
+	}
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid time %q", value)
+	}
+	return now.Add(-d), nil
+}
+
+// Prune 删除超过 snapshots.retention 的快照
+func Prune(ctx context.Context) (int, error) {
+	store := audit.GetStore()
+	if store == nil {
+		return 0, ErrStoreDisabled
+	}
+	retention := utils.GetConfig().GetDuration("snapshots.retention")
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	return store.DeleteClusterSnapshotsBefore(ctx, time.Now().Add(-retention))
+}
+
+// storedName 快照中记录的集群名称，为空时使用当前 context，没有 kubeconfig（集群内运行）时为 in-cluster
+func storedName(cluster string) string {
+	if cluster != "" {
+		return cluster
+	}
+	if _, current, err := kubernetes.ListContexts(); err == nil && current != "" {
+		return current
+	}
+	return kubernetes.InClusterName
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// 每个目标默认允许的连续连接失败次数
+const defaultRetriesPerTarget = 2
+
+// unreachablePatterns 表示目标（集群、外部 API）不可达的错误特征
+// 命令语法错误、资源不存在等不计入重试预算
+var unreachablePatterns = []string{
+	"unable to connect to the server",
+	"connection refused",
+	"i/o timeout",
+	"no such host",
+	"tls handshake timeout",
+	"context deadline exceeded",
+	"the server is currently unable to handle the request",
+	"network is unreachable",
+	"connection reset by peer",
+}
+
+var kubeContextArgRe = regexp.MustCompile(`--context[=\s]+'?"?([^'"\s]+)`)
+
+// TargetFailure 单个目标的失败情况
+type TargetFailure struct {
+	Target    string `json:"target"`
+	Failures  int    `json:"failures"`
+	LastError string `json:"last_error"`
+	Exhausted bool   `json:"exhausted"`
+}
+
+// TargetUnavailableError 目标已用尽重试预算
+type TargetUnavailableError struct {
+	TargetFailure
+}
+
+func (e *TargetUnavailableError) Error() string {
+	return fmt.Sprintf("目标 %s 不可达，已连续失败 %d 次（%s），不再重试。请不要再查询该目标，基于已获得的信息给出部分结果，并在回答中说明该目标的错误。",
+		e.Target, e.Failures, e.LastError)
+}
+
+// RetryBudget 单次请求内按目标（集群 context、外部 API）限制重试次数
+// 某个目标不可达时，避免 LLM 反复重试耗尽整个请求的迭代次数
+type RetryBudget struct {
+	mu        sync.Mutex
+	perTarget int
+	targets   map[string]*TargetFailure
+}
+
+// NewRetryBudget 创建重试预算，配置项 tools.retry_budget.per_target
+func NewRetryBudget() *RetryBudget {
+	perTarget := defaultRetriesPerTarget
+	if config := utils.GetConfig(); config.IsSet("tools.retry_budget.per_target") {
+		perTarget = config.GetInt("tools.retry_budget.per_target")
+	}
+	return &RetryBudget{perTarget: perTarget, targets: map[string]*TargetFailure{}}
+}
+
+// WithRetryBudget 为请求附加新的重试预算
+func WithRetryBudget(ctx context.Context) (context.Context, *RetryBudget) {
+	budget := NewRetryBudget()
+	return context.WithValue(ctx, retryBudgetKey, budget), budget
+}
+
+// RetryBudgetFromContext 获取请求的重试预算，未附加时返回 nil
+func RetryBudgetFromContext(ctx context.Context) *RetryBudget {
+	budget, _ := ctx.Value(retryBudgetKey).(*RetryBudget)
+	return budget
+}
+
+// Allow 检查目标是否仍有重试预算
+func (b *RetryBudget) Allow(target string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if f, ok := b.targets[target]; ok && f.Exhausted {
+		return &TargetUnavailableError{TargetFailure: *f}
+	}
+	return nil
+}
+
+// Observe 记录一次调用结果：不可达错误累计失败次数，成功则清零
+func (b *RetryBudget) Observe(target, output string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		delete(b.targets, target)
+		return
+	}
+	message := strings.TrimSpace(output)
+	if message == "" {
+		message = err.Error()
+	}
+	if !isUnreachable(message + " " + err.Error()) {
+		return
+	}
+
+	f, ok := b.targets[target]
+	if !ok {
+		f = &TargetFailure{Target: target}
+		b.targets[target] = f
+	}
+	f.Failures++
+	f.LastError = firstLine(message)
+	if b.perTarget > 0 && f.Failures >= b.perTarget {
+		f.Exhausted = true
+	}
+}
+
+// Failure 获取目标的失败情况，没有失败时返回 nil
+func (b *RetryBudget) Failure(target string) *TargetFailure {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if f, ok := b.targets[target]; ok {
+		copied := *f
+		return &copied
+	}
+	return nil
+}
+
+// Failures 返回所有失败的目标，按目标名称排序
+func (b *RetryBudget) Failures() []TargetFailure {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failures := make([]TargetFailure, 0, len(b.targets))
+	for _, f := range b.targets {
+		failures = append(failures, *f)
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Target < failures[j].Target })
+	return failures
+}
+
+// invocationTarget 返回工具调用的目标：访问集群的工具（clusterTools）为集群 context，其他工具为工具本身
+func invocationTarget(ctx context.Context, name, input string) string {
+	if !clusterTools[name] {
+		return name
+	}
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		return m[1]
+	}
+	if kubeContext := KubeContextFromContext(ctx); kubeContext != "" {
+		return kubeContext
+	}
+	return "default"
+}
+
+func isUnreachable(message string) bool {
+	message = strings.ToLower(message)
+	for _, pattern := range unreachablePatterns {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	if runes := []rune(s); len(runes) > 200 {
+		s = string(runes[:200]) + "..."
+	}
+	return s
+}
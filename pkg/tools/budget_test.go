@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRetryBudget(t *testing.T) {
+	unreachable := errors.New("exit status 1")
+	ctx := WithKubeContext(context.Background(), "prod-east")
+	ctx, budget := WithRetryBudget(ctx)
+	budget.perTarget = 2
+
+	target := invocationTarget(ctx, "kubectl", "get pods")
+	if target != "prod-east" {
+		t.Fatalf("expected target prod-east, got %s", target)
+	}
+	if other := invocationTarget(ctx, "kubectl", "kubectl --context=prod-west get pods"); other != "prod-west" {
+		t.Fatalf("expected explicit context prod-west, got %s", other)
+	}
+
+	// 命令错误不计入预算
+	budget.Observe(target, `error: the server doesn't have a resource type "pod1"`, unreachable)
+	if err := budget.Allow(target); err != nil {
+		t.Fatalf("expected command errors not to consume the budget, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		budget.Observe(target, "Unable to connect to the server: dial tcp 10.0.0.1:443: i/o timeout", unreachable)
+	}
+	var unavailable *TargetUnavailableError
+	if err := budget.Allow(target); !errors.As(err, &unavailable) || unavailable.Failures != 2 {
+		t.Fatalf("expected target to be unavailable after 2 failures, got %v", err)
+	}
+	if err := budget.Allow("prod-west"); err != nil {
+		t.Fatalf("expected other targets to keep their budget, got %v", err)
+	}
+
+	failures := budget.Failures()
+	if len(failures) != 1 || !failures[0].Exhausted || failures[0].LastError == "" {
+		t.Errorf("unexpected failures: %+v", failures)
+	}
+}
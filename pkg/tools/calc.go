@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// calcOperations calc 工具支持的运算
+var calcOperations = map[string]string{
+	"sum":     "求和",
+	"avg":     "平均值",
+	"min":     "最小值",
+	"max":     "最大值",
+	"percent": "第一个值占第二个值的百分比",
+	"convert": "单位换算，最后一项为目标单位（如 Gi、Mi、m、cores）",
+}
+
+// calcSeparatorRe 数值之间的分隔符：空白、逗号、分号、竖线
+var calcSeparatorRe = regexp.MustCompile(`[\s,;|]+`)
+
+// memorySuffixRe 带内存单位的数量，如 512Mi、1G
+var memorySuffixRe = regexp.MustCompile(`^[0-9.]+(Ki|Mi|Gi|Ti|Pi|Ei|k|K|M|G|T|P|E)$`)
+
+// binaryUnits 内存单位换算（字节）
+var binaryUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"Ei", 1 << 60}, {"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+}
+
+// Calc 精确计算 Kubernetes 资源数量，避免 LLM 直接对 Mi/Gi、m 等字符串做算术
+// 输入：运算 数量...，例如 "sum 512Mi 1Gi 250Mi"、"percent 1536Mi 4Gi"、"convert 1536Mi Gi"、"sum 100m 250m 1"
+// 数量可以直接粘贴 kubectl 输出（以空白、逗号或换行分隔），<none> 等空值会被跳过
+func Calc(input string) (string, error) {
+	fields := calcSeparatorRe.Split(strings.Trim(strings.TrimSpace(input), `'"`), -1)
+	if len(fields) < 2 {
+		return calcUsage(), fmt.Errorf("calc 输入无效: %q", input)
+	}
+	op := strings.ToLower(fields[0])
+	if _, ok := calcOperations[op]; !ok {
+		return calcUsage(), fmt.Errorf("calc 不支持的运算: %s", op)
+	}
+
+	args := fields[1:]
+	target := ""
+	if op == "convert" {
+		if len(args) != 2 {
+			return calcUsage(), fmt.Errorf("convert 需要一个数量和目标单位")
+		}
+		target, args = args[1], args[:1]
+	}
+
+	var (
+		quantities []resource.Quantity
+		memory     bool
+		skipped    int
+	)
+	for _, arg := range args {
+		if arg == "" || arg == "<none>" || arg == "-" {
+			skipped++
+			continue
+		}
+		q, err := resource.ParseQuantity(arg)
+		if err != nil {
+			return calcUsage(), fmt.Errorf("无法解析数量 %q: %v", arg, err)
+		}
+		if memorySuffixRe.MatchString(arg) {
+			memory = true
+		}
+		quantities = append(quantities, q)
+	}
+	if len(quantities) == 0 {
+		return "", fmt.Errorf("calc 没有可计算的数量")
+	}
+
+	var result string
+	switch op {
+	case "sum", "avg", "min", "max":
+		value := quantities[0].AsApproximateFloat64()
+		total := 0.0
+		for _, q := range quantities {
+			v := q.AsApproximateFloat64()
+			total += v
+			if (op == "min" && v < value) || (op == "max" && v > value) {
+				value = v
+			}
+		}
+		switch op {
+		case "sum":
+			value = total
+		case "avg":
+			value = total / float64(len(quantities))
+		}
+		result = fmt.Sprintf("%s = %s（共 %d 项）", op, formatQuantity(value, memory), len(quantities))
+	case "percent":
+		if len(quantities) != 2 {
+			return calcUsage(), fmt.Errorf("percent 需要两个数量")
+		}
+		whole := quantities[1].AsApproximateFloat64()
+		if whole == 0 {
+			return "", fmt.Errorf("percent 的第二个数量不能为 0")
+		}
+		part := quantities[0].AsApproximateFloat64()
+		result = fmt.Sprintf("percent = %.2f%%（%s / %s）", part/whole*100, formatQuantity(part, memory), formatQuantity(whole, memory))
+	case "convert":
+		converted, err := convertQuantity(quantities[0].AsApproximateFloat64(), target)
+		if err != nil {
+			return calcUsage(), err
+		}
+		result = fmt.Sprintf("%s = %s", args[0], converted)
+	}
+	if skipped > 0 {
+		result += fmt.Sprintf("，跳过 %d 个空值", skipped)
+	}
+	return result, nil
+}
+
+// CalcContext calc 工具实现，计算在本地完成，不需要取消
+func CalcContext(ctx context.Context, input string) (string, error) {
+	return Calc(input)
+}
+
+// formatQuantity 内存以合适的二进制单位和字节数输出，CPU 和数量以核数和毫核输出
+func formatQuantity(value float64, memory bool) string {
+	if memory {
+		for _, unit := range binaryUnits {
+			if math.Abs(value) >= unit.bytes {
+				return fmt.Sprintf("%s%s（%.0f 字节）", trimFloat(value/unit.bytes), unit.suffix, value)
+			}
+		}
+		return fmt.Sprintf("%.0f 字节", value)
+	}
+	return fmt.Sprintf("%s（%.0fm）", trimFloat(value), value*1000)
+}
+
+// convertQuantity 换算到目标单位：内存单位（Ki/Mi/Gi/Ti/Pi/Ei）、m（毫核）或 cores
+func convertQuantity(value float64, target string) (string, error) {
+	switch target {
+	case "m":
+		return fmt.Sprintf("%.0fm", value*1000), nil
+	case "cores", "core":
+		return trimFloat(value) + " cores", nil
+	}
+	for _, unit := range binaryUnits {
+		if unit.suffix == target {
+			return trimFloat(value/unit.bytes) + unit.suffix, nil
+		}
+	}
+	return "", fmt.Errorf("不支持的目标单位: %s", target)
+}
+
+// trimFloat 最多保留两位小数，去掉末尾的 0
+func trimFloat(value float64) string {
+	s := fmt.Sprintf("%.2f", value)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+func calcUsage() string {
+	var b strings.Builder
+	b.WriteString("用法：<运算> <数量>...，数量支持 Kubernetes 资源单位（如 250m、1.5、512Mi、2Gi）\n")
+	for _, op := range []string{"sum", "avg", "min", "max", "percent", "convert"} {
+		fmt.Fprintf(&b, "- %s：%s\n", op, calcOperations[op])
+	}
+	return b.String()
+}
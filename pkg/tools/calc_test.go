@@ -0,0 +1,29 @@
+package tools
+
+import "testing"
+
+func TestCalc(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"sum 512Mi 1Gi 512Mi", "sum = 2Gi（2147483648 字节）（共 3 项）"},
+		{"sum 100m, 250m\n1", "sum = 1.35（1350m）（共 3 项）"},
+		{"max 256Mi <none> 1Gi", "max = 1Gi（1073741824 字节）（共 2 项），跳过 1 个空值"},
+		{"percent 1536Mi 4Gi", "percent = 37.50%（1.5Gi（1610612736 字节） / 4Gi（4294967296 字节））"},
+		{"convert 1536Mi Gi", "1536Mi = 1.5Gi"},
+		{"convert 1.5 m", "1.5 = 1500m"},
+	}
+	for _, tt := range tests {
+		got, err := Calc(tt.input)
+		if err != nil || got != tt.want {
+			t.Errorf("Calc(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"sum", "median 1Gi 2Gi", "sum 1Gi lots", "percent 1Gi 0", "convert 1Gi parsecs"} {
+		if _, err := Calc(input); err == nil {
+			t.Errorf("Calc(%q) expected error", input)
+		}
+	}
+}
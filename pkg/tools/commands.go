@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CopyableCommand 可供用户复制后手动验证的命令
+// 集群凭据和 context 等环境相关的取值被替换为占位符，Placeholders 说明每个占位符的含义
+type CopyableCommand struct {
+	Tool         string            `json:"tool"`
+	Command      string            `json:"command"`
+	Placeholders map[string]string `json:"placeholders,omitempty"`
+}
+
+// commandScrubber 将命令中环境相关的参数替换为占位符
+type commandScrubber struct {
+	re          *regexp.Regexp
+	replacement string
+	placeholder string
+	description string
+}
+
+// contextPlaceholder 目标集群 context 的占位符
+const contextPlaceholder = "<CONTEXT>"
+
+var commandScrubbers = []commandScrubber{
+	{regexp.MustCompile(`(^|\s)KUBECONFIG=\S+`), "${1}KUBECONFIG=<KUBECONFIG>", "<KUBECONFIG>", "kubeconfig 文件路径"},
+	{regexp.MustCompile(`--kubeconfig[=\s]+\S+`), "--kubeconfig <KUBECONFIG>", "<KUBECONFIG>", "kubeconfig 文件路径"},
+	{regexp.MustCompile(`--context[=\s]+\S+`), "--context " + contextPlaceholder, contextPlaceholder, "目标集群在 kubeconfig 中的 context 名称"},
+	{regexp.MustCompile(`--cluster[=\s]+\S+`), "--cluster <CLUSTER>", "<CLUSTER>", "kubeconfig 中的集群名称"},
+	{regexp.MustCompile(`--user[=\s]+\S+`), "--user <USER>", "<USER>", "kubeconfig 中的用户名称"},
+	{regexp.MustCompile(`--token[=\s]+\S+`), "--token <TOKEN>", "<TOKEN>", "访问 API Server 的 Bearer Token"},
+	{regexp.MustCompile(`(--server|\s-s)[=\s]+\S+`), "${1} <API_SERVER>", "<API_SERVER>", "API Server 地址"},
+}
+
+// CopyableCommandFor 将一次工具调用转换为可复制的命令，非命令行工具返回 false
+// withContext 为 true 时表示调用时注入了集群 context，命令中会补充 --context 占位符
+func CopyableCommandFor(name, input string, withContext bool) (CopyableCommand, bool) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return CopyableCommand{}, false
+	}
+
+	var command string
+	switch name {
+	case "kubectl":
+		// 与 Kubectl 工具保持一致的前缀补全规则，保证复制的命令与实际执行的一致
+		command = input
+		if !strings.HasPrefix(command, "kubectl") {
+			command = "kubectl " + command
+		}
+		if withContext && !kubeContextFlagRe.MatchString(command) {
+			command = kubectlCommandRe.ReplaceAllString(command, "${1}kubectl --context "+contextPlaceholder+" ")
+		}
+	case "trivy":
+		command = "trivy image " + input + " --scanners vuln"
+	default:
+		return CopyableCommand{}, false
+	}
+
+	placeholders := map[string]string{}
+	for _, scrubber := range commandScrubbers {
+		if scrubber.re.MatchString(command) {
+			command = scrubber.re.ReplaceAllString(command, scrubber.replacement)
+			placeholders[scrubber.placeholder] = scrubber.description
+		}
+	}
+	if len(placeholders) == 0 {
+		placeholders = nil
+	}
+	return CopyableCommand{Tool: name, Command: command, Placeholders: placeholders}, true
+}
@@ -0,0 +1,37 @@
+package tools
+
+import "testing"
+
+func TestCopyableCommandFor(t *testing.T) {
+	tests := []struct {
+		name, tool, input string
+		withContext       bool
+		command           string
+		placeholders      int
+		ok                bool
+	}{
+		{"prefix and context", "kubectl", "get pods -n shop", true, "kubectl --context <CONTEXT> get pods -n shop", 1, true},
+		{"pipeline", "kubectl", "kubectl get pods -A | grep web | kubectl describe -f -", true,
+			"kubectl --context <CONTEXT> get pods -A | grep web | kubectl --context <CONTEXT> describe -f -", 1, true},
+		{"explicit context scrubbed", "kubectl", "kubectl --context='prod-east' get nodes", false, "kubectl --context <CONTEXT> get nodes", 1, true},
+		{"credentials scrubbed", "kubectl", "kubectl --kubeconfig=/root/.kube/prod --token abc123 -s https://10.0.0.1:6443 get ns", false,
+			"kubectl --kubeconfig <KUBECONFIG> --token <TOKEN> -s <API_SERVER> get ns", 3, true},
+		{"no context", "kubectl", "kubectl get nodes", false, "kubectl get nodes", 0, true},
+		{"trivy", "trivy", "nginx:1.27", false, "trivy image nginx:1.27 --scanners vuln", 0, true},
+		{"not a command", "jq", ".items[]", false, "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CopyableCommandFor(tt.tool, tt.input, tt.withContext)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if got.Command != tt.command {
+				t.Errorf("command = %q, want %q", got.Command, tt.command)
+			}
+			if len(got.Placeholders) != tt.placeholders {
+				t.Errorf("placeholders = %v, want %d", got.Placeholders, tt.placeholders)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/snapshots"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// maxCompareRows 输出中列出的新增、删除和变化的对象数
+const maxCompareRows = 100
+
+// compareFlagRe 匹配输入中的 --since、--to、--kind、-n、--namespace 参数
+var compareFlagRe = regexp.MustCompile(`^(--since|--to|--kind|-n|--namespace)[=\s]+(\S+)\s*`)
+
+// compareQuery 快照比较条件
+type compareQuery struct {
+	Since      time.Time
+	To         time.Time // 零值表示与实时快照比较
+	Kind       string
+	Namespaces []string
+	Name       string // 名称包含的文本
+}
+
+// Compare 比较集群历史快照与现在（或另一个时刻）的差异，回答"昨天以来有什么变化"这类问题
+// 输入：[--context=<集群>] [--since=<RFC3339 时间、2006-01-02 或 24h/7d 表示多久之前，默认 24h>] [--to=<同 --since，默认现在>]
+// [--kind=deployments] [-n <命名空间>[,<命名空间>...]] [名称]
+func Compare(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return CompareContext(ctx, input)
+}
+
+// CompareContext 比较集群快照，ctx 取消时中止
+func CompareContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_compare")()
+
+	if !snapshots.Enabled() || audit.GetStore() == nil {
+		err := fmt.Errorf("compare is not available: 未启用集群快照（snapshots.enabled 和 audit.enabled）")
+		return err.Error(), err
+	}
+	kubeContext := KubeContextFromContext(ctx)
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		kubeContext = m[1]
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	query, err := parseCompareQuery(input, time.Now())
+	if err != nil {
+		return err.Error(), err
+	}
+
+	diff, err := snapshots.Compare(ctx, kubeContext, query.Since, query.To, query.Namespaces)
+	if errors.Is(err, audit.ErrSnapshotNotFound) {
+		return fmt.Sprintf("%s 之前没有该集群的快照，快照从启用 snapshots.enabled 之后开始保存，保留 snapshots.retention",
+			query.Since.Format(time.RFC3339)), nil
+	}
+	if err != nil {
+		return err.Error(), err
+	}
+	return formatSnapshotDiff(filterSnapshotDiff(diff, query.Kind, query.Name)), nil
+}
+
+// parseCompareQuery 解析工具输入中的参数，剩余部分作为名称过滤条件
+func parseCompareQuery(input string, now time.Time) (compareQuery, error) {
+	query := compareQuery{Since: now.Add(-24 * time.Hour)}
+	input = strings.TrimSpace(input)
+	for {
+		m := compareFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		switch m[1] {
+		case "--since", "--to":
+			t, err := snapshots.ParseTime(value, now)
+			if err != nil {
+				return query, fmt.Errorf("%s 取值无效 %q，请使用 RFC3339 时间、2006-01-02 日期或 24h、7d 这样的时长", m[1], value)
+			}
+			if m[1] == "--since" {
+				query.Since = t
+			} else {
+				query.To = t
+			}
+		case "--kind":
+			query.Kind = normalizeKubeAuditResource(value)
+		default:
+			for _, namespace := range strings.Split(value, ",") {
+				if namespace != "" {
+					query.Namespaces = append(query.Namespaces, namespace)
+				}
+			}
+		}
+	}
+	if !query.To.IsZero() && !query.To.After(query.Since) {
+		return query, fmt.Errorf("--to 必须晚于 --since")
+	}
+	query.Name = strings.Trim(strings.TrimSpace(input), `'"`)
+	return query, nil
+}
+
+// filterSnapshotDiff 只保留指定类型、名称包含 name 的对象
+func filterSnapshotDiff(diff *kubernetes.SnapshotDiff, kind, name string) *kubernetes.SnapshotDiff {
+	if kind == "" && name == "" {
+		return diff
+	}
+	match := func(k, n string) bool {
+		return (kind == "" || k == kind) && strings.Contains(n, name)
+	}
+	filtered := *diff
+	filtered.Added, filtered.Removed, filtered.Changed = nil, nil, nil
+	for _, o := range diff.Added {
+		if match(o.Kind, o.Name) {
+			filtered.Added = append(filtered.Added, o)
+		}
+	}
+	for _, o := range diff.Removed {
+		if match(o.Kind, o.Name) {
+			filtered.Removed = append(filtered.Removed, o)
+		}
+	}
+	for _, o := range diff.Changed {
+		if match(o.Kind, o.Name) {
+			filtered.Changed = append(filtered.Changed, o)
+		}
+	}
+	return &filtered
+}
+
+func formatSnapshotDiff(diff *kubernetes.SnapshotDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "changes in %s from snapshot at %s to %s: %d added, %d removed, %d changed\n",
+		diff.Cluster, diff.From.UTC().Format(time.RFC3339), diff.To.UTC().Format(time.RFC3339), len(diff.Added), len(diff.Removed), len(diff.Changed))
+	if diff.Empty() {
+		b.WriteString("no changes\n")
+		return b.String()
+	}
+	rows := 0
+	row := func(format string, args ...interface{}) bool {
+		if rows == maxCompareRows {
+			return false
+		}
+		rows++
+		fmt.Fprintf(&b, format, args...)
+		return true
+	}
+	objectName := func(namespace, name string) string {
+		if namespace != "" {
+			return namespace + "/" + name
+		}
+		return name
+	}
+	for _, o := range diff.Added {
+		if !row("+ %s %s\n", o.Kind, objectName(o.Namespace, o.Name)) {
+			break
+		}
+	}
+	for _, o := range diff.Removed {
+		if !row("- %s %s\n", o.Kind, objectName(o.Namespace, o.Name)) {
+			break
+		}
+	}
+	for _, o := range diff.Changed {
+		changes := make([]string, 0, len(o.Changes))
+		for _, c := range o.Changes {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", c.Field, valueOrNone(c.From), valueOrNone(c.To)))
+		}
+		if !row("~ %s %s %s\n", o.Kind, objectName(o.Namespace, o.Name), strings.Join(changes, "; ")) {
+			break
+		}
+	}
+	if total := len(diff.Added) + len(diff.Removed) + len(diff.Changed); total > rows {
+		fmt.Fprintf(&b, "... %d more objects, use --kind, -n or a name to narrow down\n", total-rows)
+	}
+	return b.String()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+)
+
+func TestParseCompareQuery(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	query, err := parseCompareQuery("", now)
+	if err != nil || !query.Since.Equal(now.Add(-24*time.Hour)) || !query.To.IsZero() {
+		t.Errorf("parseCompareQuery() = %+v, %v", query, err)
+	}
+
+	query, err = parseCompareQuery("--since=2026-10-13 --to=1d --kind=deploy -n shop,billing payment", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 13, 23, 59, 59, 0, time.UTC); !query.Since.Equal(want) || !query.To.Equal(now.Add(-24*time.Hour)) ||
+		query.Kind != "deployments" || len(query.Namespaces) != 2 || query.Name != "payment" {
+		t.Errorf("unexpected query: %+v", query)
+	}
+
+	if _, err := parseCompareQuery("--since=1d --to=2d", now); err == nil {
+		t.Error("expected error for --to before --since")
+	}
+}
+
+func TestFormatSnapshotDiff(t *testing.T) {
+	diff := &kubernetes.SnapshotDiff{
+		Cluster: "prod",
+		Added:   []kubernetes.SnapshotObject{{Kind: "deployments", Namespace: "shop", Name: "search"}},
+		Changed: []kubernetes.ObjectChanges{{Kind: "deployments", Namespace: "shop", Name: "web",
+			Changes: []kubernetes.FieldChange{{Field: "image[web]", From: "shop/web:1.2", To: "shop/web:1.3"}, {Field: "labels.canary", To: "true"}}}},
+	}
+	out := formatSnapshotDiff(filterSnapshotDiff(diff, "deployments", "web"))
+	if !strings.Contains(out, "0 added, 0 removed, 1 changed") ||
+		!strings.Contains(out, "~ deployments shop/web image[web]: shop/web:1.2 -> shop/web:1.3; labels.canary: <none> -> true") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// DryRun 对 YAML 清单执行服务端 dry-run，返回校验错误和相对集群中现有对象的 diff，不会修改集群
+func DryRun(input string) (string, error) {
+	return DryRunContext(context.Background(), input)
+}
+
+// DryRunContext 在目标集群上执行 dry-run，输入为 YAML 清单，可以包含在 ``` 代码块中，多个对象用 --- 分隔
+// 相当于 kubectl apply --dry-run=server 加 kubectl diff；输出超过观察结果上限时按行截断
+func DryRunContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_dryrun")()
+
+	manifest := input
+	if strings.Contains(input, "```") {
+		manifest = utils.ExtractYaml(input)
+	}
+	if strings.TrimSpace(manifest) == "" {
+		err := errors.New("请提供要校验的 YAML 清单")
+		return err.Error(), err
+	}
+
+	diff, err := kubernetes.DiffYaml(ctx, KubeContextFromContext(ctx), manifest)
+	if err != nil {
+		err = fmt.Errorf("dry-run 失败: %v", err)
+		return err.Error(), err
+	}
+	output := formatManifestDiff(diff)
+	if truncated, ok := truncateKubectlOutput(output, 0, llms.ObservationTokens()*kubectlBytesPerToken); ok {
+		logger.Debug("dry-run 输出过大，已截断", zap.Int("bytes", len(output)))
+		output = truncated
+	}
+	return output, nil
+}
+
+// formatManifestDiff 先给出汇总和被拒绝对象的错误，再逐个列出对象的 diff
+func formatManifestDiff(diff *kubernetes.ManifestDiff) string {
+	counts := map[string]int{}
+	for _, obj := range diff.Objects {
+		counts[obj.Action]++
+	}
+
+	var b strings.Builder
+	if diff.Valid {
+		fmt.Fprintf(&b, "dry-run 通过：%d 个新建，%d 个修改，%d 个不变，集群未被修改\n",
+			counts[kubernetes.DiffActionCreate], counts[kubernetes.DiffActionUpdate], counts[kubernetes.DiffActionUnchanged])
+	} else {
+		fmt.Fprintf(&b, "dry-run 失败：%d 个对象被 API Server 拒绝\n", counts[kubernetes.DiffActionInvalid])
+		for _, message := range diff.Errors {
+			fmt.Fprintf(&b, "- %s\n", message)
+		}
+	}
+	for _, obj := range diff.Objects {
+		if obj.Action == kubernetes.DiffActionInvalid {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s %s\n", obj.Action, obj.Object)
+		b.WriteString(obj.Diff)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+)
+
+func TestFormatManifestDiff(t *testing.T) {
+	diff := &kubernetes.ManifestDiff{Valid: true, Objects: []kubernetes.ObjectDiff{
+		{Object: "Deployment/shop/nginx", Action: kubernetes.DiffActionUpdate, Diff: "--- live\n+++ merged\n@@ -1 +1 @@\n-a\n+b\n"},
+		{Object: "Service/shop/nginx", Action: kubernetes.DiffActionUnchanged},
+	}}
+	output := formatManifestDiff(diff)
+	if !strings.HasPrefix(output, "dry-run 通过：0 个新建，1 个修改，1 个不变") || !strings.Contains(output, "update Deployment/shop/nginx\n--- live") {
+		t.Errorf("formatManifestDiff() =\n%s", output)
+	}
+
+	diff = &kubernetes.ManifestDiff{Objects: []kubernetes.ObjectDiff{
+		{Object: "Deployment/shop/nginx", Action: kubernetes.DiffActionInvalid, Error: "spec.replicas: Invalid value"},
+	}, Errors: []string{"Deployment/shop/nginx: spec.replicas: Invalid value"}}
+	if output := formatManifestDiff(diff); !strings.Contains(output, "1 个对象被 API Server 拒绝\n- Deployment/shop/nginx: spec.replicas") {
+		t.Errorf("formatManifestDiff() =\n%s", output)
+	}
+}
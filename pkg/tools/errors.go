@@ -0,0 +1,36 @@
+package tools
+
+import "fmt"
+
+// 工具调用错误的错误码，接口在响应的 error_code 字段中返回，便于调用方按类型处理
+const (
+	ErrCodeToolNotFound      = "tool_not_found"
+	ErrCodeToolTimeout       = "tool_timeout"
+	ErrCodeCommandDenied     = "command_denied"
+	ErrCodeApprovalRequired  = "approval_required"
+	ErrCodeQuotaExceeded     = "tool_quota_exceeded"
+	ErrCodeTargetUnavailable = "target_unavailable"
+)
+
+// ToolNotFoundError 工具未注册，errors.Is(err, ErrToolNotFound) 成立
+type ToolNotFoundError struct {
+	Tool string
+}
+
+func (e *ToolNotFoundError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrToolNotFound, e.Tool)
+}
+
+func (e *ToolNotFoundError) Is(target error) bool { return target == ErrToolNotFound }
+
+func (e *ToolNotFoundError) ErrorCode() string { return ErrCodeToolNotFound }
+
+func (e *ToolTimeoutError) ErrorCode() string { return ErrCodeToolTimeout }
+
+func (e *PolicyViolationError) ErrorCode() string { return ErrCodeCommandDenied }
+
+func (e *ApprovalRequiredError) ErrorCode() string { return ErrCodeApprovalRequired }
+
+func (e *QuotaExceededError) ErrorCode() string { return ErrCodeQuotaExceeded }
+
+func (e *TargetUnavailableError) ErrorCode() string { return ErrCodeTargetUnavailable }
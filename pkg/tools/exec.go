@@ -0,0 +1,18 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// commandWaitDelay 取消后等待输出管道关闭的最长时间，避免残留的子进程阻塞调用方
+const commandWaitDelay = 2 * time.Second
+
+// commandContext 创建随 ctx 取消而结束的命令
+func commandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
@@ -26,12 +26,17 @@ import (
 
 // GoogleSearch returns the results of a Google search for the given query.
 func GoogleSearch(query string) (string, error) {
-	svc, err := customsearch.NewService(context.Background(), option.WithAPIKey(os.Getenv("GOOGLE_API_KEY")))
+	return GoogleSearchContext(context.Background(), query)
+}
+
+// GoogleSearchContext returns the results of a Google search, aborting the request when ctx is cancelled.
+func GoogleSearchContext(ctx context.Context, query string) (string, error) {
+	svc, err := customsearch.NewService(ctx, option.WithAPIKey(os.Getenv("GOOGLE_API_KEY")))
 	if err != nil {
 		return "", err
 	}
 
-	resp, err := svc.Cse.List().Cx(os.Getenv("GOOGLE_CSE_ID")).Q(query).Do()
+	resp, err := svc.Cse.List().Cx(os.Getenv("GOOGLE_CSE_ID")).Q(query).Context(ctx).Do()
 	if err != nil {
 		return "", err
 	}
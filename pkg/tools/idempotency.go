@@ -0,0 +1,160 @@
+package tools
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// Idempotency 工具调用的幂等性分类
+type Idempotency string
+
+const (
+	// IdempotencyPureRead 只读取实时状态，可以安全重试，但结果随时间变化不能缓存
+	IdempotencyPureRead Idempotency = "pure-read"
+	// IdempotencyCacheable 只读且相同输入的结果稳定，可以安全重试和缓存
+	IdempotencyCacheable Idempotency = "cacheable"
+	// IdempotencySideEffecting 可能改变集群或外部状态，不自动重试，需要审批
+	IdempotencySideEffecting Idempotency = "side-effecting"
+)
+
+const (
+	defaultAutoRetryAttempts = 1
+	defaultAutoRetryBackoff  = 500 * time.Millisecond
+)
+
+// RetrySafe 失败后是否可以自动重试
+func (i Idempotency) RetrySafe() bool {
+	return i == IdempotencyPureRead || i == IdempotencyCacheable
+}
+
+// ChangesState 是否会改变状态
+func (i Idempotency) ChangesState() bool {
+	return !i.RetrySafe()
+}
+
+// kubectlReadVerbs 只读的 kubectl 子命令，其余子命令（包括 exec、port-forward、cp 等）按有副作用处理
+var kubectlReadVerbs = map[string]bool{
+	"get":           true,
+	"describe":      true,
+	"logs":          true,
+	"top":           true,
+	"explain":       true,
+	"events":        true,
+	"diff":          true,
+	"api-resources": true,
+	"api-versions":  true,
+	"version":       true,
+	"cluster-info":  true,
+}
+
+// kubectlReadSubcommands 子命令只读与否取决于下一级命令的 kubectl 子命令
+var kubectlReadSubcommands = map[string]map[string]bool{
+	"rollout": {"status": true, "history": true},
+	"auth":    {"can-i": true, "whoami": true},
+	"config":  {"view": true, "get-contexts": true, "current-context": true, "get-clusters": true, "get-users": true},
+}
+
+// kubectlValueFlags 取值的全局参数，值不是子命令；未列出的参数后跟的值会被当作子命令，按有副作用处理
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true, "--context": true, "--kubeconfig": true, "--cluster": true,
+	"--user": true, "--token": true, "-s": true, "--server": true, "--as": true, "--as-group": true, "--as-uid": true,
+	"--request-timeout": true, "-v": true, "--v": true, "--vmodule": true, "--log-file": true, "--cache-dir": true,
+	"--certificate-authority": true, "--client-certificate": true, "--client-key": true, "--tls-server-name": true,
+}
+
+// shellSeparatorRe 拆分管道和命令列表，kubectl 工具的输入经由 bash 执行
+var shellSeparatorRe = regexp.MustCompile(`\|\||&&|[|;&\n]`)
+
+// Classify 返回一次工具调用的幂等性分类
+// kubectl 按命令中出现的每个 kubectl 子命令判断，任一子命令有副作用即视为有副作用
+func Classify(name, input string) Idempotency {
+	spec, ok := Registry.Get(name)
+	if !ok || spec.Idempotency == "" {
+		return IdempotencySideEffecting
+	}
+	class := spec.Idempotency
+	if name == "kubectl" && !kubectlReadOnly(input) {
+		return IdempotencySideEffecting
+	}
+	return class
+}
+
+// kubectlReadOnly 判断 kubectl 工具的输入是否只包含只读子命令
+func kubectlReadOnly(input string) bool {
+	if kubectlShellViolation(input) != "" {
+		return false
+	}
+	for _, commands := range kubectlCommands(input) {
+		if !kubectlCommandReadOnly(commands) {
+			return false
+		}
+	}
+	return true
+}
+
+// kubectlInput 补全工具输入中省略的 kubectl 前缀，与 KubectlContext 的处理一致
+func kubectlInput(input string) string {
+	input = strings.TrimSpace(input)
+	if !strings.HasPrefix(input, "kubectl") {
+		return "kubectl " + input
+	}
+	return input
+}
+
+// kubectlCommands 返回输入中每次 kubectl 调用（按文件名匹配，例如 /usr/bin/kubectl）的子命令（最多两级，跳过参数）
+func kubectlCommands(input string) [][]string {
+	var invocations [][]string
+	for _, segment := range shellSeparatorRe.Split(kubectlInput(input), -1) {
+		fields := strings.Fields(segment)
+		for i, field := range fields {
+			if path.Base(field) == "kubectl" {
+				invocations = append(invocations, kubectlSubcommands(fields[i+1:]))
+			}
+		}
+	}
+	return invocations
+}
+
+func kubectlSubcommands(args []string) []string {
+	var commands []string
+	for i := 0; i < len(args) && len(commands) < 2; i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "-") {
+			if kubectlValueFlags[arg] {
+				i++
+			}
+			continue
+		}
+		commands = append(commands, arg)
+	}
+	return commands
+}
+
+func kubectlCommandReadOnly(commands []string) bool {
+	if len(commands) == 0 {
+		return true
+	}
+	if subcommands, ok := kubectlReadSubcommands[commands[0]]; ok {
+		return len(commands) > 1 && subcommands[commands[1]]
+	}
+	return kubectlReadVerbs[commands[0]]
+}
+
+// autoRetryPolicy 目标暂时不可达时的自动重试策略
+// 配置项 tools.auto_retry.attempts（默认 1，0 表示不重试）和 tools.auto_retry.backoff（默认 500ms）
+func autoRetryPolicy() (int, time.Duration) {
+	config := utils.GetConfig()
+	attempts := defaultAutoRetryAttempts
+	if config.IsSet("tools.auto_retry.attempts") {
+		attempts = config.GetInt("tools.auto_retry.attempts")
+	}
+	backoff := defaultAutoRetryBackoff
+	if config.IsSet("tools.auto_retry.backoff") {
+		backoff = config.GetDuration("tools.auto_retry.backoff")
+	}
+	return attempts, backoff
+}
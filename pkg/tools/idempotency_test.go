@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Idempotency
+	}{
+		{"kubectl", "get pods -n shop", IdempotencyPureRead},
+		{"kubectl", "kubectl -n shop --context prod-east logs payment-api-7d9f --tail 100", IdempotencyPureRead},
+		{"kubectl", "kubectl rollout status deployment/payment-api", IdempotencyPureRead},
+		{"kubectl", "kubectl rollout restart deployment/payment-api", IdempotencySideEffecting},
+		{"kubectl", "kubectl delete pod payment-api-7d9f", IdempotencySideEffecting},
+		{"kubectl", "kubectl get pods -o name | xargs kubectl delete", IdempotencySideEffecting},
+		{"kubectl", "kubectl exec payment-api-7d9f -- env", IdempotencySideEffecting},
+		{"jq", ".items[].metadata.name", IdempotencyCacheable},
+		{"python", "print(1)", IdempotencySideEffecting},
+		{"unregistered", "anything", IdempotencySideEffecting},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.name, tt.input); got != tt.want {
+			t.Errorf("Classify(%q, %q) = %s, want %s", tt.name, tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestInvokeAutoRetry(t *testing.T) {
+	utils.GetConfig().Set("tools.auto_retry.backoff", "1ms")
+	defer utils.GetConfig().Set("tools.auto_retry.backoff", nil)
+
+	var calls int
+	flaky := func(input string) (string, error) {
+		calls++
+		if calls == 1 {
+			return "Unable to connect to the server: dial tcp 10.0.0.1:443: i/o timeout", errors.New("exit status 1")
+		}
+		return "ok", nil
+	}
+	Registry.Register(ToolSpec{Name: "flaky", Idempotency: IdempotencyPureRead, Run: WithoutContext(flaky)})
+	defer Registry.Unregister("flaky")
+
+	if output, err := Invoke(context.Background(), "flaky", "status"); err != nil || output != "ok" || calls != 2 {
+		t.Fatalf("expected read call to be retried once, got %q, %v after %d calls", output, err, calls)
+	}
+
+	// 有副作用的调用不自动重试
+	calls = 0
+	Registry.Unregister("flaky")
+	Registry.Register(ToolSpec{Name: "flaky", Idempotency: IdempotencySideEffecting, Run: WithoutContext(flaky)})
+	if _, err := Invoke(context.Background(), "flaky", "restart"); err == nil || calls != 1 {
+		t.Fatalf("expected side-effecting call not to be retried, got %v after %d calls", err, calls)
+	}
+}
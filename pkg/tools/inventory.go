@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/inventory"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// maxInventoryRows 输出中列出的资源数
+const maxInventoryRows = 50
+
+// inventoryFlagRe 匹配输入中的 --kind、-n、--namespace 参数
+var inventoryFlagRe = regexp.MustCompile(`^(--kind|-n|--namespace)[=\s]+(\S+)\s*`)
+
+// Inventory 在全部集群的资源清单中按名称搜索资源，回答"某个服务部署在哪些集群、哪个命名空间"这类问题
+// 输入：[--context=<集群>[,<集群>...]] [--kind=deployments] [-n <命名空间>] <名称的一部分>
+func Inventory(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return InventoryContext(ctx, input)
+}
+
+// InventoryContext 搜索资源清单，ctx 取消时中止刷新清单
+func InventoryContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_inventory")()
+
+	query := parseInventoryQuery(input)
+	if query.Name == "" && query.Kind == "" && query.Namespace == "" {
+		err := fmt.Errorf("请提供要搜索的资源名称（名称的一部分即可），可以用 --kind、-n 缩小范围")
+		return err.Error(), err
+	}
+	query.Limit = maxInventoryRows
+	result, err := inventory.Default().Search(ctx, query)
+	if err != nil {
+		return err.Error(), err
+	}
+	return formatInventoryResult(result), nil
+}
+
+// parseInventoryQuery 解析集群、资源类型和命名空间，剩余部分作为名称过滤条件
+func parseInventoryQuery(input string) inventory.Query {
+	var query inventory.Query
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		for _, cluster := range strings.Split(m[1], ",") {
+			if cluster = strings.TrimSpace(cluster); cluster != "" {
+				query.Clusters = append(query.Clusters, cluster)
+			}
+		}
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	input = strings.TrimSpace(input)
+	for {
+		m := inventoryFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		if m[1] == "--kind" {
+			query.Kind = value
+		} else {
+			query.Namespace = value
+		}
+	}
+	query.Name = strings.Trim(strings.TrimSpace(input), `'"`)
+	return query
+}
+
+func formatInventoryResult(result *inventory.Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d matching objects in %d clusters\n", result.Total, len(result.Clusters))
+	for _, m := range result.Matches {
+		name := m.Name
+		if m.Namespace != "" {
+			name = m.Namespace + "/" + m.Name
+		}
+		fmt.Fprintf(&b, "%s %s %s created %s", m.Cluster, m.Kind, name, m.CreatedAt.UTC().Format(time.RFC3339))
+		if m.Owner != "" {
+			fmt.Fprintf(&b, " owner %s", m.Owner)
+		}
+		b.WriteString("\n")
+	}
+	if result.Truncated {
+		fmt.Fprintf(&b, "... %d more objects, use --kind, -n, --context or a longer name to narrow down\n", result.Total-len(result.Matches))
+	}
+	for _, status := range result.Clusters {
+		if status.Error == "" {
+			continue
+		}
+		if status.RefreshedAt.IsZero() {
+			fmt.Fprintf(&b, "cluster %s not searched: %s\n", status.Cluster, status.Error)
+		} else {
+			fmt.Fprintf(&b, "cluster %s: refresh failed (%s), using inventory from %s\n",
+				status.Cluster, status.Error, status.RefreshedAt.UTC().Format(time.RFC3339))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/inventory"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+)
+
+func TestParseInventoryQuery(t *testing.T) {
+	query := parseInventoryQuery("--context=prod,staging --kind=deploy -n shop 'payment'")
+	want := inventory.Query{Kind: "deploy", Namespace: "shop", Name: "payment", Clusters: []string{"prod", "staging"}}
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("parseInventoryQuery = %+v, want %+v", query, want)
+	}
+}
+
+func TestFormatInventoryResult(t *testing.T) {
+	refreshed := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	result := &inventory.Result{
+		Matches: []inventory.Match{{Cluster: "prod", SnapshotObject: kubernetes.SnapshotObject{
+			Kind: "deployments", Namespace: "shop", Name: "payment-api", CreatedAt: refreshed,
+		}}},
+		Total:     3,
+		Truncated: true,
+		Clusters: []inventory.ClusterStatus{
+			{Cluster: "prod", RefreshedAt: refreshed, Objects: 10},
+			{Cluster: "staging", RefreshedAt: refreshed, Error: "timeout"},
+			{Cluster: "dev", Error: "context not found"},
+		},
+	}
+	output := formatInventoryResult(result)
+	for _, want := range []string{
+		"3 matching objects in 3 clusters",
+		"prod deployments shop/payment-api created 2026-10-16T12:00:00Z",
+		"... 2 more objects",
+		"cluster staging: refresh failed (timeout), using inventory from 2026-10-16T12:00:00Z",
+		"cluster dev not searched: context not found",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+}
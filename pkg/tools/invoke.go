@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/redact"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ErrToolNotFound 工具未注册
+var ErrToolNotFound = errors.New("tool not found")
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	kubeContextKey
+	retryBudgetKey
+	approvedCommandKey
+	resultCacheKey
+	roleContextKey
+	tenantContextKey
+	receiptLogKey
+	receiptSlotKey
+)
+
+// WithUser 在上下文中记录发起工具调用的用户
+func WithUser(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, userContextKey, username)
+}
+
+// UserFromContext 获取发起工具调用的用户
+func UserFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(userContextKey).(string)
+	return username
+}
+
+// WithRole 在上下文中记录发起工具调用的用户角色，限制可以触发的 kubectl 变更命令
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleContextKey, role)
+}
+
+// RoleFromContext 获取发起工具调用的用户角色，命令行等未记录角色的调用返回空
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleContextKey).(string)
+	return role
+}
+
+// WithTenant 在上下文中记录请求所属的租户，用于校验租户的模型允许列表
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey, tenant)
+}
+
+// TenantFromContext 获取请求所属的租户，未记录时返回空
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey).(string)
+	return tenant
+}
+
+// Invoke 通过工具注册表调用工具，统一执行配额、重试预算、超时等检查
+// kubectl 命令会使用上下文中记录的 kubeconfig context；ctx 取消（如 HTTP 请求中断）时正在执行的命令会被终止
+// 每次调用记录一个 span，输入在写入 span 属性前脱敏
+func Invoke(ctx context.Context, name string, input string) (string, error) {
+	ctx, span := utils.StartSpan(ctx, "execute_tool "+name,
+		attribute.String("gen_ai.operation.name", "execute_tool"),
+		attribute.String("gen_ai.tool.name", name),
+		attribute.String("tool.input", redact.String(input)),
+	)
+	output, err := invoke(ctx, name, input)
+	span.SetAttributes(attribute.Int("tool.output_bytes", len(output)))
+	utils.EndSpan(span, err)
+	return output, err
+}
+
+func invoke(ctx context.Context, name string, input string) (string, error) {
+	tool, ok := Registry.Get(name)
+	if !ok {
+		return "", &ToolNotFoundError{Tool: name}
+	}
+
+	// 只读策略在服务端强制执行，不依赖系统提示中的约定
+	if name == "kubectl" {
+		if err := checkKubectlPolicy(ctx, input); err != nil {
+			var approvalErr *ApprovalRequiredError
+			if errors.As(err, &approvalErr) {
+				utils.LoggerFromContext(ctx).Info("变更命令等待人工审批",
+					zap.String("tool", name),
+					zap.String("input", input),
+					zap.String("username", UserFromContext(ctx)),
+				)
+				return "", err
+			}
+			utils.GetPerfStats().IncrCounter("tool_policy_violation_" + name)
+			utils.LoggerFromContext(ctx).Warn("工具调用违反只读策略，已拒绝执行",
+				zap.String("tool", name),
+				zap.String("input", input),
+				zap.String("username", UserFromContext(ctx)),
+			)
+			return "", err
+		}
+	}
+
+	// 同一交互内重复的只读调用直接返回缓存结果，不消耗配额，也不再访问集群
+	cache := ResultCacheFromContext(ctx)
+	cacheKey := ""
+	if cache != nil && Classify(name, input).RetrySafe() {
+		cacheKey = cacheKeyFor(ctx, name, input)
+		if output, ok := cache.Get(cacheKey); ok {
+			utils.GetPerfStats().IncrCounter("tool_cache_hit_" + name)
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("tool.cache_hit", true))
+			utils.LoggerFromContext(ctx).Debug("工具调用命中交互缓存",
+				zap.String("tool", name),
+				zap.String("input", input),
+			)
+			return CachedResultNote + output, nil
+		}
+	}
+
+	username := UserFromContext(ctx)
+	if err := GetQuotaManager().Consume(ctx, username, name); err != nil {
+		utils.LoggerFromContext(ctx).Warn("工具调用超出配额",
+			zap.String("tool", name),
+			zap.String("username", username),
+			zap.Error(err),
+		)
+		return "", err
+	}
+
+	// 目标已用尽重试预算时不再执行，直接返回不可达原因
+	target := invocationTarget(ctx, name, input)
+	budget := RetryBudgetFromContext(ctx)
+	if budget != nil {
+		if err := budget.Allow(target); err != nil {
+			utils.LoggerFromContext(ctx).Warn("目标已用尽重试预算，跳过工具调用",
+				zap.String("tool", name),
+				zap.String("target", target),
+			)
+			return "", err
+		}
+	}
+
+	// 目标集群部署了 runner 时转发到 runner 执行，否则使用本地的 kubeconfig
+	tool, input, remote := remoteSpec(ctx, tool, input)
+	if remote {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("tool.remote", true))
+	} else if name == "kubectl" || name == "nodepools" {
+		input = withKubeContextFlag(input, KubeContextFromContext(ctx))
+	}
+
+	// 启用执行回执时记录本次执行，runner 执行的调用使用 runner 签发的回执
+	receiptLog := ReceiptsFromContext(ctx)
+	var slot *receiptSlot
+	if receiptLog != nil {
+		ctx, slot = receiptLog.withReceiptSlot(ctx)
+	}
+
+	start := time.Now()
+	output, err := tool.run(ctx, input)
+	// 目标暂时不可达时自动重试，只重试不改变状态的调用，避免重复执行有副作用的命令
+	if err != nil && ctx.Err() == nil && isUnreachable(output+" "+err.Error()) {
+		if class := Classify(name, input); class.RetrySafe() {
+			attempts, backoff := autoRetryPolicy()
+			for attempt := 1; attempt <= attempts && err != nil && isUnreachable(output+" "+err.Error()); attempt++ {
+				utils.LoggerFromContext(ctx).Info("目标暂时不可达，自动重试工具调用",
+					zap.String("tool", name),
+					zap.String("target", target),
+					zap.String("idempotency", string(class)),
+					zap.Int("attempt", attempt),
+				)
+				select {
+				case <-ctx.Done():
+					return output, err
+				case <-time.After(backoff):
+				}
+				output, err = tool.run(ctx, input)
+				trace.SpanFromContext(ctx).SetAttributes(attribute.Int("tool.retries", attempt))
+			}
+		}
+	}
+	utils.RecordToolCall(name, time.Since(start), err)
+	if budget != nil {
+		budget.Observe(target, output, err)
+	}
+	// 输出会作为观察结果发送给 LLM 并写入审计，返回前清除其中的 Secret 数据、令牌和密码
+	output = redact.String(output)
+	if receiptLog != nil {
+		receiptLog.record(slot, name, input, output, start)
+	}
+	if cacheKey != "" && err == nil {
+		cache.Put(cacheKey, output)
+	}
+	return output, err
+}
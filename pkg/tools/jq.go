@@ -1,143 +1,235 @@
 package tools
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"go.uber.org/zap"
-	"os/exec"
+	"regexp"
 	"strings"
 	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 )
 
-// JQ 执行jq命令处理JSON数据
-// 功能特性：
-// 1. 支持复杂的jq表达式
-// 2. 自动验证JSON数据格式
-// 3. 处理管道操作
-// 参数：
-//   - input: 输入格式为 "JSON数据 | jq表达式"
-//
-// 返回：
-//   - string: jq处理后的结果
-//   - error: 处理过程中的错误
+const (
+	// defaultJQMaxInputBytes 未配置 tools.jq.max_input_bytes 时输入 JSON 的上限
+	defaultJQMaxInputBytes = 10 << 20
+	// jqCaptureFactor 读取的 jq 输出最多为输出上限的倍数，超出部分直接丢弃，避免表达式展开出巨大的结果占满内存
+	jqCaptureFactor = 8
+)
+
+// jqForbiddenRe 匹配读取服务端环境变量或文件的 jq 用法：$ENV、env、input_filename、import、include
+var jqForbiddenRe = regexp.MustCompile(`\$ENV\b|(^|[^.\w$"])(env|input_filename|import|include)\b`)
+
+// JQRequest jq 工具的结构化输入，JSON 为要处理的数据，可以是 JSON 值，也可以是包含 JSON 文本的字符串
+type JQRequest struct {
+	Expression string          `json:"expression"`
+	JSON       json.RawMessage `json:"json"`
+	RawOutput  bool            `json:"raw_output,omitempty"` // 字符串结果不带引号输出（jq -r）
+}
+
+// jqSchema jq 工具的参数，原生工具调用时表达式和数据分开传递，不需要处理 shell 引号
+var jqSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"expression": map[string]interface{}{"type": "string", "description": "jq 表达式，例如 .items[] | select(.metadata.name | test(\"api\")) | .metadata.name"},
+		"json":       map[string]interface{}{"type": "string", "description": "要处理的 JSON 文本"},
+		"raw_output": map[string]interface{}{"type": "boolean", "description": "为 true 时字符串结果不带引号输出，相当于 jq -r"},
+	},
+	"required": []string{"expression", "json"},
+}
+
+// JQ 执行 jq 表达式处理 JSON 数据
+// 输入为 JQRequest 的 JSON：{"expression": "...", "json": "...", "raw_output": false}，
+// 也兼容 "JSON数据 | jq表达式" 的写法
 func JQ(input string) (string, error) {
-	// 获取性能统计工具
+	return JQContext(context.Background(), input)
+}
+
+// JQContext 执行 jq 表达式，ctx 取消或超时时终止命令
+// 表达式在执行前先编译检查，不允许读取环境变量和文件；输出超过 tools.jq.max_output_bytes 时按结构截断
+func JQContext(ctx context.Context, input string) (string, error) {
 	perfStats := utils.GetPerfStats()
-	// 开始jq命令执行计时
 	defer perfStats.TraceFunc("jq_command")()
-
-	// 记录开始时间
 	startTime := time.Now()
 
 	logger.Debug("准备执行 jq 命令",
 		zap.String("input", input),
 	)
 
-	// 解析输入，分离JSON数据和jq表达式
-	parts := strings.Split(input, "|")
-	if len(parts) != 2 {
-		return "", fmt.Errorf("输入格式错误，应为: JSON数据 | jq表达式")
+	req, err := parseJQInput(input)
+	if err != nil {
+		return err.Error(), err
 	}
-
-	jsonData := strings.TrimSpace(parts[0])
-	jqExpr := strings.TrimSpace(parts[1])
-
-	// 开始JSON验证计时
-	perfStats.StartTimer("jq_json_validation")
-
-	// 验证JSON数据格式是否有效
-	var jsonObj interface{}
-	if err := json.Unmarshal([]byte(jsonData), &jsonObj); err != nil {
-		// 停止JSON验证计时
-		validationDuration := perfStats.StopTimer("jq_json_validation")
-		logger.Debug("JSON验证失败",
-			zap.Error(err),
-			zap.Duration("duration", validationDuration),
-		)
-
-		return "", fmt.Errorf("无效的JSON数据: %v", err)
+	data, err := req.data()
+	if err != nil {
+		return err.Error(), err
+	}
+	if err := validateJQExpression(ctx, req.Expression); err != nil {
+		return err.Error(), err
 	}
 
-	// 停止JSON验证计时
-	validationDuration := perfStats.StopTimer("jq_json_validation")
-	logger.Debug("JSON验证成功",
-		zap.Duration("duration", validationDuration),
-	)
+	maxBytes := jqMaxOutputBytes()
+	args := []string{}
+	if req.RawOutput {
+		args = append(args, "-r")
+	}
+	cmd := commandContext(ctx, "jq", append(args, jqFilterArg(req.Expression))...)
+	cmd.Stdin = strings.NewReader(data)
+	stdout := &cappedBuffer{limit: maxBytes * jqCaptureFactor}
+	var stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = stdout, &stderr
 
-	// 开始jq执行计时
 	perfStats.StartTimer("jq_execution")
-
-	// 使用管道直接传递数据执行jq命令
-	cmd := exec.Command("jq", jqExpr)
-	cmd.Stdin = strings.NewReader(jsonData)
-
-	// 执行命令并获取输出
-	output, err := cmd.CombinedOutput()
-
-	// 停止jq执行计时
+	err = cmd.Run()
 	executionDuration := perfStats.StopTimer("jq_execution")
-
-	// 记录总执行时间
 	duration := time.Since(startTime)
-
 	if err != nil {
 		logger.Error("jq 命令执行失败",
 			zap.Error(err),
-			zap.String("output", string(output)),
+			zap.String("stderr", stderr.String()),
 			zap.Duration("execution_duration", executionDuration),
-			zap.Duration("total_duration", duration),
 		)
-
-		// 记录失败的命令性能
 		perfStats.RecordMetric("jq_command_failed", duration)
-
-		return strings.TrimSpace(string(output)), err
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return message, fmt.Errorf("jq: %s", message)
 	}
-
-	logger.Debug("jq 命令执行成功",
-		zap.String("output", string(output)),
-		zap.Duration("execution_duration", executionDuration),
-		zap.Duration("total_duration", duration),
-	)
-
-	// 记录成功的命令性能
 	perfStats.RecordMetric("jq_command_success", duration)
 
 	// 记录jq表达式的复杂度（基于表达式长度和特定操作符的数量）
-	complexity := len(jqExpr)
-	complexity += strings.Count(jqExpr, "|") * 2
-	complexity += strings.Count(jqExpr, "select") * 5
-	complexity += strings.Count(jqExpr, "map") * 3
-
+	complexity := len(req.Expression)
+	complexity += strings.Count(req.Expression, "|") * 2
+	complexity += strings.Count(req.Expression, "select") * 5
+	complexity += strings.Count(req.Expression, "map") * 3
 	if complexity > 20 {
 		perfStats.RecordMetric("jq_complex_query", duration)
 	} else {
 		perfStats.RecordMetric("jq_simple_query", duration)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	output := strings.TrimSpace(stdout.String())
+	if stdout.dropped > 0 {
+		output += fmt.Sprintf("\n... 输出过大，已丢弃其余 %d 字节，请缩小表达式的结果（例如只选择需要的字段或使用 length 统计）", stdout.dropped)
+	}
+	if truncated, ok := truncateKubectlOutput(output, 0, maxBytes); ok {
+		logger.Debug("jq 输出过大，已截断",
+			zap.Int("bytes", len(output)),
+			zap.Int("max_bytes", maxBytes),
+		)
+		output = truncated
+	}
+	return output, nil
 }
 
-// processJSONWithJQ 智能处理JSON数据并提取特定字段
-// 功能：
-// 1. 自动构建jq查询表达式
-// 2. 处理复杂的JSON结构
-// 参数：
-//   - jsonData: 原始JSON数据
-//   - query: 要执行的jq查询
-//
-// 返回：
-//   - string: 处理后的结果
-//   - error: 处理过程中的错误
-func processJSONWithJQ(jsonData string, query string) (string, error) {
-	// 获取性能统计工具
-	perfStats := utils.GetPerfStats()
-	// 开始处理计时
-	defer perfStats.TraceFunc("process_json_with_jq")()
+// parseJQInput 解析结构化输入，不是 JQRequest 时按 "JSON数据 | jq表达式" 解析：
+// 先完整读取开头的 JSON 值，其后的 | 之后都是表达式，表达式中的管道不会被误拆
+func parseJQInput(input string) (*JQRequest, error) {
+	input = strings.TrimSpace(input)
+	var req JQRequest
+	if err := json.Unmarshal([]byte(input), &req); err == nil && req.Expression != "" {
+		if len(req.JSON) == 0 {
+			return nil, errors.New(`缺少 json 字段：请在 "json" 中提供要处理的 JSON 数据`)
+		}
+		return &req, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(input))
+	var data json.RawMessage
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf(`输入格式错误，应为 {"expression": "jq 表达式", "json": "JSON 数据"} 或 "JSON数据 | jq表达式": %v`, err)
+	}
+	rest := strings.TrimSpace(input[decoder.InputOffset():])
+	if !strings.HasPrefix(rest, "|") {
+		return nil, errors.New(`输入格式错误，JSON 数据之后应为 "| jq表达式"`)
+	}
+	req.JSON, req.Expression = data, strings.TrimSpace(rest[1:])
+	if req.Expression == "" {
+		return nil, errors.New("缺少 jq 表达式")
+	}
+	return &req, nil
+}
 
-	// 构建完整的jq命令输入
-	input := fmt.Sprintf("%s | %s", jsonData, query)
-	return JQ(input)
+// data 返回要处理的 JSON 文本，json 字段为字符串时取其内容
+func (r *JQRequest) data() (string, error) {
+	data := []byte(r.JSON)
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		data = []byte(text)
+	}
+	if limit := jqMaxInputBytes(); len(data) > limit {
+		return "", fmt.Errorf("JSON 数据 %d 字节，超过上限 %d 字节，请先用 kubectl 的 -l、--field-selector 或 jsonpath 缩小数据", len(data), limit)
+	}
+	if !json.Valid(data) {
+		var v interface{}
+		err := json.Unmarshal(data, &v)
+		return "", fmt.Errorf("无效的JSON数据: %v", err)
+	}
+	return string(data), nil
+}
+
+// validateJQExpression 执行前检查表达式：不允许读取环境变量和文件，并用 jq 编译一次（empty 之后的表达式不会被求值）
+func validateJQExpression(ctx context.Context, expression string) error {
+	if m := jqForbiddenRe.FindString(expression); m != "" {
+		return fmt.Errorf("jq 表达式中不允许使用 %s", strings.TrimLeft(m, " \t\n(|,;"))
+	}
+	cmd := commandContext(ctx, "jq", "-n", jqFilterArg("empty | ("+expression+")"))
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	message := strings.ReplaceAll(strings.TrimSpace(string(output)), " (Unix shell quoting issues?)", "")
+	return fmt.Errorf("jq 表达式无效: %s", message)
+}
+
+// jqFilterArg 以 - 开头的表达式（例如 -1）会被 jq 当作命令行选项，前面加空格
+func jqFilterArg(expression string) string {
+	if strings.HasPrefix(expression, "-") {
+		return " " + expression
+	}
+	return expression
+}
+
+// jqMaxOutputBytes jq 输出交给 LLM 前的上限，配置项 tools.jq.max_output_bytes，默认按 llm.budget.observation_tokens 估算
+func jqMaxOutputBytes() int {
+	if maxBytes := utils.GetConfig().GetInt("tools.jq.max_output_bytes"); maxBytes > 0 {
+		return maxBytes
+	}
+	return llms.ObservationTokens() * kubectlBytesPerToken
+}
+
+// jqMaxInputBytes 输入 JSON 的上限，配置项 tools.jq.max_input_bytes，默认 10MB
+func jqMaxInputBytes() int {
+	if maxBytes := utils.GetConfig().GetInt("tools.jq.max_input_bytes"); maxBytes > 0 {
+		return maxBytes
+	}
+	return defaultJQMaxInputBytes
+}
+
+// cappedBuffer 最多保存 limit 字节，其余写入只计数
+type cappedBuffer struct {
+	bytes.Buffer
+	limit   int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		b.dropped += len(p) - max(room, 0)
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
 }
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseJQInput(t *testing.T) {
+	req, err := parseJQInput(`{"expression": ".items[] | .name", "json": "{\"items\": [{\"name\": \"a|b\"}]}", "raw_output": true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := req.data(); err != nil || req.Expression != ".items[] | .name" || !req.RawOutput || data != `{"items": [{"name": "a|b"}]}` {
+		t.Errorf("structured input = %+v, %q, %v", req, data, err)
+	}
+
+	// 旧写法：表达式中的管道和数据中的 | 都不会被误拆
+	req, err = parseJQInput(`{"items": [{"name": "a|b"}]} | .items[] | select(.name | test("a"))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Expression != `.items[] | select(.name | test("a"))` || string(req.JSON) != `{"items": [{"name": "a|b"}]}` {
+		t.Errorf("legacy input = %+v", req)
+	}
+
+	for _, input := range []string{`{"items": []}`, `{"items": [} | .items`, `{"expression": ".a"}`} {
+		if _, err := parseJQInput(input); err == nil {
+			t.Errorf("parseJQInput(%q) expected error", input)
+		}
+	}
+}
+
+func TestJQ(t *testing.T) {
+	if _, err := exec.LookPath("jq"); err != nil {
+		t.Skip("jq not installed")
+	}
+	ctx := context.Background()
+	output, err := JQContext(ctx, `{"expression": ".items[] | select(.name | test(\"api\")) | .name", "json": {"items": [{"name": "payment-api"}, {"name": "worker"}]}, "raw_output": true}`)
+	if err != nil || output != "payment-api" {
+		t.Errorf("JQContext = %q, %v", output, err)
+	}
+	if output, err := JQContext(ctx, `{"expression": "-1", "json": "null"}`); err != nil || output != "-1" {
+		t.Errorf("expression starting with - = %q, %v", output, err)
+	}
+	if output, err := JQContext(ctx, `{"expression": ".items[] | bad syntax", "json": "{}"}`); err == nil || !strings.Contains(output, "jq 表达式无效") {
+		t.Errorf("invalid expression = %q, %v", output, err)
+	}
+	for _, expression := range []string{"$ENV.OPENAI_API_KEY", "env | keys", `import "x" as x; .`} {
+		input := `{"expression": ` + jsonString(expression) + `, "json": "{}"}`
+		if output, err := JQContext(ctx, input); err == nil || !strings.Contains(output, "不允许") {
+			t.Errorf("JQContext(%s) = %q, %v, want rejected", expression, output, err)
+		}
+	}
+	if output, err := JQContext(ctx, `{"expression": ".env", "json": {"env": "prod"}}`); err != nil || output != `"prod"` {
+		t.Errorf("field named env = %q, %v", output, err)
+	}
+
+	stdout := &cappedBuffer{limit: 4}
+	stdout.Write([]byte("abc"))
+	stdout.Write([]byte("def"))
+	if stdout.String() != "abcd" || stdout.dropped != 2 {
+		t.Errorf("cappedBuffer = %q dropped %d", stdout.String(), stdout.dropped)
+	}
+}
+
+func jsonString(s string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
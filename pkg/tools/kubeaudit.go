@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const defaultKubeAuditSince = 7 * 24 * time.Hour
+
+// kubeAuditFlagRe 匹配输入中的 --resource、--name 等参数
+var kubeAuditFlagRe = regexp.MustCompile(`^--(cluster|namespace|resource|name|verb|user|since|start|end|limit)[=\s]+(\S+)\s*`)
+
+// kubeAuditResources kubectl 常用简称到审计日志中资源名（复数）的映射
+var kubeAuditResources = map[string]string{
+	"deploy": "deployments", "sts": "statefulsets", "ds": "daemonsets", "rs": "replicasets",
+	"po": "pods", "svc": "services", "cm": "configmaps", "ing": "ingresses", "hpa": "horizontalpodautoscalers",
+	"no": "nodes", "ns": "namespaces", "pvc": "persistentvolumeclaims", "pv": "persistentvolumes",
+	"sa": "serviceaccounts", "netpol": "networkpolicies", "pdb": "poddisruptionbudgets",
+	"ingress": "ingresses",
+}
+
+// KubeAudit 查询集群 apiserver 审计日志中的变更记录，回答"谁在什么时候修改了某个资源"
+// 输入：[--cluster=<集群>] [--namespace=<ns>] [--resource=deployments] [--name=<名称前缀>] [--verb=patch] [--user=<用户前缀>]
+// [--since=24h | --start=<RFC3339> --end=<RFC3339>] [--limit=20]
+// 未指定 --cluster 时使用本次请求的目标集群
+func KubeAudit(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return KubeAuditContext(ctx, input)
+}
+
+// KubeAuditContext 查询集群审计日志，ctx 取消时中止查询
+func KubeAuditContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_kubeaudit")()
+
+	store := audit.GetStore()
+	if store == nil || !utils.GetConfig().GetBool("kube_audit.enabled") {
+		err := fmt.Errorf("kubeaudit is not available: 未启用集群审计日志接入（kube_audit.enabled 和 audit.enabled）")
+		return err.Error(), err
+	}
+	query, err := parseKubeAuditQuery(input, time.Now())
+	if err != nil {
+		return err.Error(), err
+	}
+	if query.Cluster == "" {
+		query.Cluster = KubeContextFromContext(ctx)
+	}
+
+	events, err := store.QueryKubeAuditEvents(ctx, query)
+	if err != nil {
+		return err.Error(), err
+	}
+	if len(events) == 0 {
+		return fmt.Sprintf("%s 至 %s 之间没有匹配的变更记录。审计日志只记录成功的变更请求，请放宽时间范围、检查资源名称，"+
+			"或确认该集群已接入审计日志", query.Since.Format(time.RFC3339), query.Until.Format(time.RFC3339)), nil
+	}
+	return formatKubeAuditEvents(events), nil
+}
+
+// parseKubeAuditQuery 解析工具输入中的参数，剩余部分作为资源名称
+func parseKubeAuditQuery(input string, now time.Time) (audit.KubeAuditQuery, error) {
+	query := audit.KubeAuditQuery{Until: now}
+	since := defaultKubeAuditSince
+	input = strings.TrimSpace(input)
+	for {
+		m := kubeAuditFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		switch m[1] {
+		case "cluster":
+			query.Cluster = value
+		case "namespace":
+			query.Namespace = value
+		case "resource":
+			query.Resource = normalizeKubeAuditResource(value)
+		case "name":
+			query.Name = value
+		case "verb":
+			query.Verb = strings.ToLower(value)
+		case "user":
+			query.Username = value
+		case "since":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return query, fmt.Errorf("--since 取值无效 %q，请使用 24h、72h 等时长", value)
+			}
+			since = d
+		case "start", "end":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("--%s 取值无效 %q，请使用 RFC3339 格式", m[1], value)
+			}
+			if m[1] == "start" {
+				query.Since = t
+			} else {
+				query.Until = t
+			}
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return query, fmt.Errorf("--limit 取值无效 %q", value)
+			}
+			query.Limit = n
+		}
+	}
+	if query.Since.IsZero() {
+		query.Since = query.Until.Add(-since)
+	}
+	if !query.Since.Before(query.Until) {
+		return query, fmt.Errorf("开始时间必须早于结束时间")
+	}
+	if rest := strings.Trim(strings.TrimSpace(input), `'"`); rest != "" && query.Name == "" {
+		query.Name = rest
+	}
+	return query, nil
+}
+
+// normalizeKubeAuditResource 将 deploy、deployment、deployments.apps 等写法统一为审计日志中的复数资源名
+func normalizeKubeAuditResource(resource string) string {
+	resource = strings.ToLower(resource)
+	if i := strings.IndexByte(resource, '.'); i > 0 {
+		resource = resource[:i]
+	}
+	if full, ok := kubeAuditResources[resource]; ok {
+		return full
+	}
+	switch {
+	case strings.HasSuffix(resource, "y"):
+		resource = strings.TrimSuffix(resource, "y") + "ies"
+	case !strings.HasSuffix(resource, "s"):
+		resource += "s"
+	}
+	return resource
+}
+
+// formatKubeAuditEvents 每条记录一行："时间 集群 用户 动作 资源 命名空间/名称 摘要 (客户端)"
+func formatKubeAuditEvents(events []audit.KubeAuditEvent) string {
+	var b strings.Builder
+	for _, e := range events {
+		resource := e.Resource
+		if e.Subresource != "" {
+			resource += "/" + e.Subresource
+		}
+		object := e.Name
+		if e.Namespace != "" {
+			object = e.Namespace + "/" + e.Name
+		}
+		fmt.Fprintf(&b, "%s %s %s %s %s %s", e.Time.UTC().Format(time.RFC3339), e.Cluster, e.Username, e.Verb, resource, object)
+		if e.Detail != "" {
+			fmt.Fprintf(&b, " %s", e.Detail)
+		}
+		if agent := strings.Fields(e.UserAgent); len(agent) > 0 {
+			fmt.Fprintf(&b, " (%s)", agent[0])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseKubeAuditQuery(t *testing.T) {
+	now := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	q, err := parseKubeAuditQuery("--resource=deploy --namespace=edge --since=48h gateway", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Resource != "deployments" || q.Namespace != "edge" || q.Name != "gateway" || !q.Since.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("unexpected query: %+v", q)
+	}
+
+	for _, tt := range []struct{ in, want string }{
+		{"Deployment", "deployments"}, {"deployments.apps", "deployments"}, {"hpa", "horizontalpodautoscalers"},
+		{"networkpolicy", "networkpolicies"}, {"ingress", "ingresses"},
+	} {
+		if got := normalizeKubeAuditResource(tt.in); got != tt.want {
+			t.Errorf("normalizeKubeAuditResource(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseKubeAuditQuery("--since=abc", now); err == nil {
+		t.Error("expected error for invalid --since")
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// kubeconfigFlagRe 匹配命令中的 --kubeconfig 参数，LLM 自行添加的路径（例如 ./config）在容器中通常不存在
+var kubeconfigFlagRe = regexp.MustCompile(`(^|\s+)--kubeconfig(=|\s+)('[^']*'|"[^"]*"|\S+)`)
+
+// KubeconfigPath Kubectl 工具使用的 kubeconfig 文件，配置项 tools.kubectl.kubeconfig
+// （也可通过环境变量 OPSAGENT_TOOLS_KUBECTL_KUBECONFIG 设置），支持 ~ 开头的路径
+// 未配置时返回空，kubectl 按 KUBECONFIG 环境变量、~/.kube/config、集群内 ServiceAccount 的顺序查找
+func KubeconfigPath() string {
+	path := utils.GetConfig().GetString("tools.kubectl.kubeconfig")
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// withKubeconfigFlag 去掉命令中已有的 --kubeconfig 参数，为每个 kubectl 调用指定 kubeconfig 文件：
+// 目标集群登记了单独的 kubeconfig 时使用登记的文件，集群内访问的集群使用 ServiceAccount，
+// 否则使用 KubeconfigPath，均为空时不指定
+// kubeContext 为本次请求的集群，命令中显式指定了其他 --context 时按该 context 查找
+func withKubeconfigFlag(command, kubeContext string) string {
+	command = strings.TrimSpace(kubeconfigFlagRe.ReplaceAllString(command, ""))
+
+	registry := clusters.Default()
+	name := kubeContext
+	if m := kubeContextArgRe.FindStringSubmatch(command); m != nil {
+		if target, _ := registry.Target(kubeContext); kubeContext == "" || m[1] != target {
+			name = m[1]
+		}
+	}
+	kubeconfig := KubeconfigPath()
+	if name == clusters.InClusterContext || registry.InCluster(name) {
+		// 集群内访问的集群使用引用 ServiceAccount 令牌的 kubeconfig
+		path, err := kubernetes.InClusterKubeconfig()
+		if err != nil {
+			logger.Warn("生成集群内访问的 kubeconfig 失败", zap.String("cluster", name), zap.Error(err))
+		}
+		kubeconfig = path
+	} else if name != "" {
+		if _, path := registry.Target(name); path != "" {
+			kubeconfig = path
+		}
+	}
+	if kubeconfig == "" {
+		return command
+	}
+	return withKubectlFlag(command, "--kubeconfig "+shellQuote(kubeconfig))
+}
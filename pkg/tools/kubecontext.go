@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/clusters"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// 默认置信度阈值，低于该值需要用户确认集群
+const defaultClusterConfidenceThreshold = 0.8
+
+var (
+	// kubectlCommandRe 匹配命令中每个 kubectl 调用（包括管道和命令组合中的）
+	kubectlCommandRe = regexp.MustCompile(`(^|[|;&(]\s*)kubectl\s`)
+	// kubeContextFlagRe 匹配已显式指定的 --context 参数
+	kubeContextFlagRe = regexp.MustCompile(`(^|\s)--context[=\s]`)
+)
+
+// ClusterResolution 集群解析结果
+// NeedsConfirmation 为 true 时不应直接使用 Candidate，需由用户从候选项中确认
+type ClusterResolution struct {
+	kubernetes.ContextResolution
+	Threshold         float64 `json:"threshold"`
+	NeedsConfirmation bool    `json:"needs_confirmation"`
+}
+
+// WithKubeContext 在上下文中记录本次请求使用的 kubeconfig context
+// 请求级传递，避免修改全局的当前 context 影响其他用户的请求
+func WithKubeContext(ctx context.Context, kubeContext string) context.Context {
+	return context.WithValue(ctx, kubeContextKey, kubeContext)
+}
+
+// KubeContextFromContext 获取本次请求使用的 kubeconfig context
+func KubeContextFromContext(ctx context.Context) string {
+	kubeContext, _ := ctx.Value(kubeContextKey).(string)
+	return kubeContext
+}
+
+// ResolveCluster 将用户输入的集群名称解析为 kubeconfig context
+// 配置项：
+//   - clusters.aliases: 集群别名到 context 的映射
+//   - clusters.confidence_threshold: 低于该置信度时需要用户确认
+//   - clusters.max_alternatives: 返回的备选 context 数量
+func ResolveCluster(query string) (*ClusterResolution, error) {
+	registry := clusters.Default()
+	contexts, _, err := kubernetes.ListContexts()
+	if err != nil && len(registry.List()) == 0 {
+		return nil, fmt.Errorf("读取 kubeconfig 失败: %v", err)
+	}
+	// 登记的集群名称与 kubeconfig 中的 context 一起参与解析
+	for _, c := range registry.List() {
+		if !containsString(contexts, c.Name) {
+			contexts = append(contexts, c.Name)
+		}
+	}
+	if len(contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig 中没有可用的 context")
+	}
+
+	config := utils.GetConfig()
+	threshold := defaultClusterConfidenceThreshold
+	if config.IsSet("clusters.confidence_threshold") {
+		threshold = config.GetFloat64("clusters.confidence_threshold")
+	}
+	maxAlternatives := 3
+	if config.IsSet("clusters.max_alternatives") {
+		maxAlternatives = config.GetInt("clusters.max_alternatives")
+	}
+
+	aliases := registry.Aliases()
+	for alias, target := range config.GetStringMapString("clusters.aliases") {
+		aliases[alias] = target
+	}
+	resolution := &ClusterResolution{
+		ContextResolution: kubernetes.ResolveContext(query, contexts, aliases, maxAlternatives),
+		Threshold:         threshold,
+	}
+	resolution.NeedsConfirmation = resolution.Candidate == "" || resolution.Confidence < threshold
+	return resolution, nil
+}
+
+// withKubeContextFlag 为命令中的每个 kubectl 调用添加 --context 参数，登记的集群名称替换为对应的 context
+// 已显式指定 --context 的命令保持不变；kubeconfig 文件由 Kubectl 工具处理，见 withKubeconfigFlag
+func withKubeContextFlag(command, kubeContext string) string {
+	if kubeContext == "" || kubeContextFlagRe.MatchString(command) {
+		return command
+	}
+	target, _ := clusters.Default().Target(kubeContext)
+	return withKubectlFlag(command, "--context "+shellQuote(target))
+}
+
+// withKubectlFlag 在命令中每个 kubectl 调用之后插入 flag，命令不以 kubectl 开头时加在最前面
+func withKubectlFlag(command, flag string) string {
+	if !kubectlCommandRe.MatchString(command) {
+		// Kubectl 工具会自动补全 kubectl 前缀
+		return flag + " " + command
+	}
+	return kubectlCommandRe.ReplaceAllString(command, "${1}kubectl "+strings.ReplaceAll(flag, "$", "$$")+" ")
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestWithKubeContextFlag(t *testing.T) {
+	tests := map[string]string{
+		"get pods":                              "--context 'prod' get pods",
+		"kubectl get pods -A":                   "kubectl --context 'prod' get pods -A",
+		"kubectl get ns | grep kube":            "kubectl --context 'prod' get ns | grep kube",
+		"kubectl get ns && kubectl get nodes":   "kubectl --context 'prod' get ns && kubectl --context 'prod' get nodes",
+		"kubectl --context=staging get pods":    "kubectl --context=staging get pods",
+		"kubectl get pods -l app=kubectl-proxy": "kubectl --context 'prod' get pods -l app=kubectl-proxy",
+	}
+	for command, expected := range tests {
+		if got := withKubeContextFlag(command, "prod"); got != expected {
+			t.Errorf("%q: expected %q, got %q", command, expected, got)
+		}
+	}
+
+	if got := withKubeContextFlag("get pods", ""); got != "get pods" {
+		t.Errorf("expected command unchanged without context, got %q", got)
+	}
+}
+
+func TestWithKubeconfigFlag(t *testing.T) {
+	if got := withKubeconfigFlag("kubectl --kubeconfig=./config get pods", ""); got != "kubectl get pods" {
+		t.Errorf("expected invented kubeconfig to be stripped, got %q", got)
+	}
+
+	utils.GetConfig().Set("tools.kubectl.kubeconfig", "/etc/opsagent/kubeconfig")
+	defer utils.GetConfig().Set("tools.kubectl.kubeconfig", nil)
+	tests := map[string]string{
+		"kubectl get pods":                                  "kubectl --kubeconfig '/etc/opsagent/kubeconfig' get pods",
+		"kubectl --kubeconfig ./config get pods":            "kubectl --kubeconfig '/etc/opsagent/kubeconfig' get pods",
+		"kubectl get pods --kubeconfig='~/.kube/config' -A": "kubectl --kubeconfig '/etc/opsagent/kubeconfig' get pods -A",
+		"kubectl get ns | grep kube && kubectl get nodes":   "kubectl --kubeconfig '/etc/opsagent/kubeconfig' get ns | grep kube && kubectl --kubeconfig '/etc/opsagent/kubeconfig' get nodes",
+	}
+	for command, expected := range tests {
+		if got := withKubeconfigFlag(command, "prod"); got != expected {
+			t.Errorf("%q: expected %q, got %q", command, expected, got)
+		}
+	}
+}
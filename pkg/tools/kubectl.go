@@ -1,24 +1,26 @@
 package tools
 
 import (
+	"context"
 	"fmt"
 	"go.uber.org/zap"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
 	"github.com/myysophia/OpsAgent/pkg/utils"
 )
 
 // executeShellCommand 执行shell命令并返回输出
 // 参数：
+//   - ctx: 取消时终止命令及其子进程
 //   - command: 要执行的shell命令
 //
 // 返回：
 //   - string: 命令执行的输出
 //   - error: 执行过程中的错误
-func executeShellCommand(command string) (string, error) {
+func executeShellCommand(ctx context.Context, command string) (string, error) {
 	// 获取性能统计工具
 	perfStats := utils.GetPerfStats()
 	// 开始shell命令执行计时
@@ -29,7 +31,7 @@ func executeShellCommand(command string) (string, error) {
 	)
 
 	// 使用bash执行命令
-	cmd := exec.Command("bash", "-c", command)
+	cmd := commandContext(ctx, "bash", "-c", command)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		logger.Error("shell命令执行失败",
@@ -59,6 +61,11 @@ func executeShellCommand(command string) (string, error) {
 //   - string: 命令执行的输出
 //   - error: 执行过程中的错误
 func Kubectl(command string) (string, error) {
+	return KubectlContext(context.Background(), command)
+}
+
+// KubectlContext 执行kubectl命令，ctx 取消或超时时终止命令
+func KubectlContext(ctx context.Context, command string) (string, error) {
 	// 获取性能统计工具
 	perfStats := utils.GetPerfStats()
 	// 开始kubectl命令执行计时
@@ -76,8 +83,13 @@ func Kubectl(command string) (string, error) {
 		command = "kubectl " + command
 	}
 
+	// 指定本次请求的集群 context 和 kubeconfig 文件，忽略 LLM 自行添加的 --kubeconfig
+	// 经 Invoke 调用时 --context 已经注入，这里处理直接调用的情况
+	kubeContext := KubeContextFromContext(ctx)
+	command = withKubeconfigFlag(withKubeContextFlag(command, kubeContext), kubeContext)
+
 	// 执行命令
-	output, err := executeShellCommand(command)
+	output, err := runKubectl(ctx, command)
 
 	// 记录执行时间
 	duration := time.Since(startTime)
@@ -133,9 +145,43 @@ func Kubectl(command string) (string, error) {
 	// 过滤掉无关的错误信息
 	output = filterKubectlOutput(output)
 
+	// 输出过大时按结构截断，避免交给 LLM 时被按 token 数截掉表头
+	maxLines, maxBytes := kubectlOutputLimits()
+	if truncated, ok := truncateKubectlOutput(output, maxLines, maxBytes); ok {
+		logger.Info("kubectl 输出过大，已截断",
+			zap.String("command", command),
+			zap.Int("original_bytes", len(output)),
+			zap.Int("truncated_bytes", len(truncated)),
+		)
+		perfStats.IncrCounter("kubectl_output_truncated")
+		output = truncated
+	}
+
 	return output, nil
 }
 
+// NativeKubectlEnabled 是否使用 client-go 执行常用的只读 kubectl 命令，配置项 tools.kubectl.native，默认启用
+func NativeKubectlEnabled() bool {
+	config := utils.GetConfig()
+	return !config.IsSet("tools.kubectl.native") || config.GetBool("tools.kubectl.native")
+}
+
+// runKubectl 执行 kubectl 命令：get、describe、logs、top、events 由 client-go 直接执行，不经过 shell，
+// 参数原样发送给 API Server，不受单双引号、转义和 zsh 模式匹配的影响；
+// 包含管道、重定向等 shell 语法或原生执行不支持的子命令和参数时仍由 kubectl 执行
+func runKubectl(ctx context.Context, command string) (string, error) {
+	if NativeKubectlEnabled() {
+		cmd, err := kubernetes.ParseCommand(command)
+		if err == nil {
+			logger.Debug("使用 client-go 执行 kubectl 命令", zap.String("command", command))
+			utils.GetPerfStats().IncrCounter("kubectl_native")
+			return kubernetes.RunCommand(ctx, cmd)
+		}
+		logger.Debug("命令不支持原生执行，使用 kubectl", zap.String("command", command), zap.Error(err))
+	}
+	return executeShellCommand(ctx, command)
+}
+
 // filterKubectlOutput 过滤kubectl输出中的无关错误信息
 // 参数：
 //   - output: 原始输出内容
@@ -0,0 +1,314 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const (
+	defaultKubectlMaxLines = 200
+	// kubectl 输出以 ASCII 为主，按每个 token 约 3 字节估算，使截断后的输出能完整交给 LLM
+	kubectlBytesPerToken = 3
+	// 统计列中列出的取值数量
+	maxColumnValues = 10
+)
+
+// tableHeaderRe 匹配 kubectl 表格输出的表头，例如 "NAMESPACE   NAME   READY   STATUS"，列之间至少两个空格
+var tableHeaderRe = regexp.MustCompile(`^[A-Z][A-Z0-9()%/._-]*( +[A-Z][A-Z0-9()%/._-]*)+\s*$`)
+
+// abnormalMarkers 行中包含这些内容时优先保留，截断后仍能看到异常的资源
+var abnormalMarkers = []string{
+	"CrashLoopBackOff", "Error", "Pending", "Failed", "NotReady", "Evicted", "OOMKilled",
+	"ImagePullBackOff", "ErrImagePull", "Unknown", "Terminating", "ContainerCreating", "BackOff",
+	"Warning", "Lost", "SchedulingDisabled",
+}
+
+// kubectlOutputLimits kubectl 输出交给 LLM 前的上限
+// 配置项：
+//   - tools.kubectl.max_output_lines: 最多保留的行数，默认 200
+//   - tools.kubectl.max_output_bytes: 最多保留的字节数，默认按 llm.budget.observation_tokens 估算
+func kubectlOutputLimits() (maxLines, maxBytes int) {
+	config := utils.GetConfig()
+	maxLines = defaultKubectlMaxLines
+	if config.IsSet("tools.kubectl.max_output_lines") {
+		maxLines = config.GetInt("tools.kubectl.max_output_lines")
+	}
+	maxBytes = config.GetInt("tools.kubectl.max_output_bytes")
+	if maxBytes <= 0 {
+		maxBytes = llms.ObservationTokens() * kubectlBytesPerToken
+	}
+	return maxLines, maxBytes
+}
+
+// truncateKubectlOutput 按结构截断过大的 kubectl 输出，而不是交给 LLM 前按 token 数盲目截掉开头：
+//   - 表格：保留表头，优先保留状态异常的行，统计总行数和 STATUS/NAMESPACE 等列的取值分布
+//   - JSON 列表（-o json）：保留能放下的前几个 items，注明省略的数量
+//   - 其他文本（describe、logs、yaml）：保留开头和结尾，注明省略的行数
+//
+// maxLines、maxBytes 不大于 0 时不限制对应维度，未超出上限时原样返回
+func truncateKubectlOutput(output string, maxLines, maxBytes int) (string, bool) {
+	output = strings.TrimRight(output, "\n")
+	lines := strings.Split(output, "\n")
+	if (maxLines <= 0 || len(lines) <= maxLines) && (maxBytes <= 0 || len(output) <= maxBytes) {
+		return output, false
+	}
+	if maxLines <= 0 {
+		maxLines = len(lines)
+	}
+	if maxBytes <= 0 {
+		maxBytes = len(output)
+	}
+
+	if trimmed := strings.TrimSpace(output); strings.HasPrefix(trimmed, "{") {
+		if truncated, ok := truncateJSONList(trimmed, maxBytes); ok {
+			return truncated, true
+		}
+	}
+	if len(lines) > 1 && tableHeaderRe.MatchString(lines[0]) && strings.Contains(lines[0], "  ") {
+		return truncateTable(lines, maxLines, maxBytes), true
+	}
+	return truncateText(lines, maxLines, maxBytes), true
+}
+
+// truncateTable 保留表头和尽量多的行，状态异常的行优先，其余按原顺序保留开头的行
+func truncateTable(lines []string, maxLines, maxBytes int) string {
+	header, rows := lines[0], lines[1:]
+	summary := tableSummary(header, rows)
+	// 为表头、统计和省略说明预留空间
+	budget := maxBytes - len(header) - len(summary) - 200
+	rowBudget := maxLines - 1 - strings.Count(summary, "\n") - 2
+
+	keep := make([]bool, len(rows))
+	kept, used := 0, 0
+	add := func(i int) bool {
+		if kept >= rowBudget || used+len(rows[i])+1 > budget {
+			return false
+		}
+		keep[i] = true
+		kept++
+		used += len(rows[i]) + 1
+		return true
+	}
+	for i, row := range rows {
+		if isAbnormalRow(row) && !add(i) {
+			break
+		}
+	}
+	for i := range rows {
+		if !keep[i] && !add(i) {
+			break
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for i, row := range rows {
+		if keep[i] {
+			b.WriteString("\n")
+			b.WriteString(row)
+		}
+	}
+	fmt.Fprintf(&b, "\n... 输出过大已截断：共 %d 行，显示 %d 行（优先显示状态异常的行），省略 %d 行\n", len(rows), kept, len(rows)-kept)
+	b.WriteString(summary)
+	b.WriteString("请使用 -n、-l、--field-selector 或 | grep 缩小范围后查询被省略的行")
+	return b.String()
+}
+
+// tableSummary 统计表格中 STATUS、TYPE、REASON、NAMESPACE 列的取值分布
+func tableSummary(header string, rows []string) string {
+	var b strings.Builder
+	for _, column := range []string{"STATUS", "TYPE", "REASON", "NAMESPACE"} {
+		start, end, ok := columnRange(header, column)
+		if !ok {
+			continue
+		}
+		counts := map[string]int{}
+		for _, row := range rows {
+			if value := columnValue(row, start, end); value != "" {
+				counts[value]++
+			}
+		}
+		if len(counts) == 0 {
+			continue
+		}
+		values := make([]string, 0, len(counts))
+		for value := range counts {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		parts := make([]string, 0, maxColumnValues+1)
+		for i, value := range values {
+			if i == maxColumnValues {
+				parts = append(parts, fmt.Sprintf("其他 %d 种", len(values)-maxColumnValues))
+				break
+			}
+			parts = append(parts, fmt.Sprintf("%s=%d", value, counts[value]))
+		}
+		fmt.Fprintf(&b, "%s 统计: %s\n", column, strings.Join(parts, ", "))
+	}
+	return b.String()
+}
+
+// columnRange 返回列在表头中的起止位置，最后一列的结束位置为 -1
+func columnRange(header, column string) (int, int, bool) {
+	start := -1
+	for i := 0; i+len(column) <= len(header); i++ {
+		if header[i:i+len(column)] == column && (i == 0 || header[i-1] == ' ') &&
+			(i+len(column) == len(header) || header[i+len(column)] == ' ') {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+	end := strings.Index(header[start+len(column):], "  ")
+	if end < 0 {
+		return start, -1, true
+	}
+	end += start + len(column)
+	for end < len(header) && header[end] == ' ' {
+		end++
+	}
+	return start, end, true
+}
+
+// columnValue 按表头位置取出一行中某列的值，kubectl 按列对齐输出
+func columnValue(row string, start, end int) string {
+	if start >= len(row) {
+		return ""
+	}
+	if end < 0 || end > len(row) {
+		end = len(row)
+	}
+	fields := strings.Fields(row[start:end])
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+func isAbnormalRow(row string) bool {
+	for _, marker := range abnormalMarkers {
+		if strings.Contains(row, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateJSONList 保留 -o json 列表输出中能放下的前几个 items（压缩为单行 JSON 以节省 token），不是列表时返回 false
+func truncateJSONList(output string, maxBytes int) (string, bool) {
+	var list map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return "", false
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(list["items"], &items); err != nil || len(items) == 0 {
+		return "", false
+	}
+
+	list["items"] = json.RawMessage("[]")
+	empty, err := json.Marshal(list)
+	if err != nil {
+		return "", false
+	}
+	budget := maxBytes - len(empty) - 200
+	var kept []json.RawMessage
+	used := 0
+	for _, item := range items {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, item); err != nil {
+			return "", false
+		}
+		if used+compact.Len()+1 > budget {
+			break
+		}
+		kept = append(kept, compact.Bytes())
+		used += compact.Len() + 1
+	}
+	if len(kept) == 0 {
+		// 单个 item 也放不下时按文本截断
+		return "", false
+	}
+	list["items"], _ = json.Marshal(kept)
+	data, err := json.Marshal(list)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s\n... 输出过大已截断：共 %d 个 items，显示前 %d 个，省略 %d 个。"+
+		"请使用 -o jsonpath、-o custom-columns 只输出需要的字段，或用 -n、-l 缩小范围",
+		data, len(items), len(kept), len(items)-len(kept)), true
+}
+
+// truncateText 保留开头约 2/3 和结尾约 1/3 的行（日志、事件的最新内容通常在末尾）
+func truncateText(lines []string, maxLines, maxBytes int) string {
+	budget := maxBytes - 200
+	lineBudget := maxLines - 1
+	headBudget, tailBudget := budget*2/3, budget/3
+
+	var head, tail []string
+	used := 0
+	for _, line := range lines {
+		if len(head) >= lineBudget*2/3 || used+len(line)+1 > headBudget {
+			break
+		}
+		head = append(head, line)
+		used += len(line) + 1
+	}
+	used = 0
+	for i := len(lines) - 1; i >= len(head); i-- {
+		if len(head)+len(tail) >= lineBudget || used+len(lines[i])+1 > tailBudget {
+			break
+		}
+		tail = append(tail, lines[i])
+		used += len(lines[i]) + 1
+	}
+	for i, j := 0, len(tail)-1; i < j; i, j = i+1, j-1 {
+		tail[i], tail[j] = tail[j], tail[i]
+	}
+
+	omitted := len(lines) - len(head) - len(tail)
+	var b strings.Builder
+	if len(head) == 0 && len(tail) == 0 {
+		// 单行过长（例如不换行的输出），按字节截断
+		b.WriteString(truncateBytes(lines[0], budget))
+		b.WriteString("\n")
+	} else {
+		b.WriteString(strings.Join(head, "\n"))
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "... 输出过大已截断：共 %d 行，省略中间 %d 行 ...", len(lines), omitted)
+	if len(tail) > 0 {
+		b.WriteString("\n")
+		b.WriteString(strings.Join(tail, "\n"))
+	}
+	return b.String()
+}
+
+// truncateBytes 按字节截断，不切断多字节字符
+func truncateBytes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
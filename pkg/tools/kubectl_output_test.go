@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestTruncateKubectlTable(t *testing.T) {
+	lines := []string{"NAMESPACE   NAME                 READY   STATUS             RESTARTS   AGE"}
+	for i := 0; i < 500; i++ {
+		status := "Running"
+		if i == 420 {
+			status = "CrashLoopBackOff"
+		}
+		lines = append(lines, fmt.Sprintf("ns-%d        pod-%04d             1/1     %-16s   0          1d", i%3, i, status))
+	}
+	output := strings.Join(lines, "\n")
+
+	if got, ok := truncateKubectlOutput(output, 1000, 1<<20); ok || got != output {
+		t.Fatal("output within limits should be returned unchanged")
+	}
+
+	got, ok := truncateKubectlOutput(output, 50, 4000)
+	if !ok {
+		t.Fatal("expected output to be truncated")
+	}
+	if len(got) > 4000 || strings.Count(got, "\n")+1 > 50 {
+		t.Errorf("truncated output exceeds limits: %d bytes, %d lines", len(got), strings.Count(got, "\n")+1)
+	}
+	if !strings.HasPrefix(got, lines[0]) {
+		t.Error("expected header to be kept")
+	}
+	if !strings.Contains(got, "pod-0420") {
+		t.Error("expected abnormal row to be kept")
+	}
+	for _, want := range []string{"共 500 行", "STATUS 统计: Running=499, CrashLoopBackOff=1", "NAMESPACE 统计: ns-0=167, ns-1=167, ns-2=166"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestTruncateKubectlJSONList(t *testing.T) {
+	var items []map[string]interface{}
+	for i := 0; i < 100; i++ {
+		items = append(items, map[string]interface{}{"metadata": map[string]string{"name": fmt.Sprintf("pod-%d", i)}})
+	}
+	data, _ := json.MarshalIndent(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}, "", "    ")
+
+	got, ok := truncateKubectlOutput(string(data), 0, 1000)
+	if !ok {
+		t.Fatal("expected output to be truncated")
+	}
+	jsonPart, note, _ := strings.Cut(got, "\n")
+	var list struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(jsonPart), &list); err != nil {
+		t.Fatalf("truncated output is not valid JSON: %v", err)
+	}
+	if len(list.Items) == 0 || len(list.Items) >= 100 {
+		t.Fatalf("unexpected items kept: %d", len(list.Items))
+	}
+	if !strings.Contains(note, fmt.Sprintf("省略 %d 个", 100-len(list.Items))) {
+		t.Errorf("unexpected note: %s", note)
+	}
+}
+
+func TestTruncateKubectlText(t *testing.T) {
+	var lines []string
+	for i := 0; i < 1000; i++ {
+		lines = append(lines, fmt.Sprintf("2024-01-01T00:00:%02dZ log line %d", i%60, i))
+	}
+	got, ok := truncateKubectlOutput(strings.Join(lines, "\n"), 60, 1<<20)
+	if !ok {
+		t.Fatal("expected output to be truncated")
+	}
+	if !strings.HasPrefix(got, lines[0]) || !strings.HasSuffix(got, lines[999]) {
+		t.Error("expected first and last lines to be kept")
+	}
+	if !strings.Contains(got, "共 1000 行") || strings.Count(got, "\n")+1 > 60 {
+		t.Errorf("unexpected truncated text:\n%s", got)
+	}
+}
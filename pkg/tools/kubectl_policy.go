@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/users"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// PolicyViolationError 工具调用违反服务端策略，命令未执行
+type PolicyViolationError struct {
+	Tool    string `json:"tool"`
+	Verb    string `json:"verb"`
+	Command string `json:"command"`
+	Policy  string `json:"policy"`
+}
+
+func (e *PolicyViolationError) Error() string {
+	if e.Policy == "shell" {
+		return fmt.Sprintf("policy violation: %s is not allowed in kubectl tool input, the command was not executed: %s. "+
+			"Only kubectl itself may run, without command substitution, variables, redirection or other programs; "+
+			"use -l, --field-selector, -o jsonpath or -o custom-columns to filter output instead.",
+			e.Verb, e.Command)
+	}
+	return fmt.Sprintf("policy violation: %s %s is blocked by the %s policy, the command was not executed: %s. "+
+		"Do not retry this or any other state-changing command; answer with read-only findings and give the user the command to run themselves after review.",
+		e.Tool, e.Verb, e.Policy, e.Command)
+}
+
+// ApprovalRequiredError 启用审批时，违反只读策略的命令不直接拒绝，而是等待人工审批后执行
+type ApprovalRequiredError struct {
+	PolicyViolationError
+}
+
+func (e *ApprovalRequiredError) Error() string {
+	return fmt.Sprintf("approval required: %s %s must be approved by an operator before it runs: %s", e.Tool, e.Verb, e.Command)
+}
+
+type approvedCommand struct {
+	tool  string
+	input string
+}
+
+// WithApprovedCommand 在上下文中记录已审批通过的命令，只放行完全相同的工具输入
+func WithApprovedCommand(ctx context.Context, tool, input string) context.Context {
+	return context.WithValue(ctx, approvedCommandKey, approvedCommand{tool: tool, input: strings.TrimSpace(input)})
+}
+
+// CommandApproved 上下文中记录的已审批命令是否与本次工具输入完全相同
+func CommandApproved(ctx context.Context, tool, input string) bool {
+	approved, ok := ctx.Value(approvedCommandKey).(approvedCommand)
+	return ok && approved.tool == tool && approved.input == strings.TrimSpace(input)
+}
+
+// kubectlWriteAllowed 是否允许 kubectl 修改集群状态，配置项 tools.kubectl.allow_write，默认只读
+func kubectlWriteAllowed() bool {
+	return utils.GetConfig().GetBool("tools.kubectl.allow_write")
+}
+
+// approvalsEnabled 是否启用变更命令的人工审批，配置项 approvals.enabled
+func approvalsEnabled() bool {
+	return utils.GetConfig().GetBool("approvals.enabled")
+}
+
+// checkKubectlPolicy 检查 kubectl 输入（包括管道中的每个 kubectl 调用），默认拒绝：
+// 输入包含命令替换、变量展开、重定向或 kubectl 之外的可执行文件时总是拒绝（策略 shell），审批通过的命令除外；
+// 包含 kubectlReadVerbs、kubectlReadSubcommands 之外的子命令时返回 *PolicyViolationError，
+// 启用审批时返回 *ApprovalRequiredError，审批通过的命令（见 WithApprovedCommand）直接放行。
+// 上下文中记录了用户角色时，角色不允许的子命令总是拒绝，不受 allow_write 和审批影响
+func checkKubectlPolicy(ctx context.Context, input string) error {
+	approved := CommandApproved(ctx, "kubectl", input)
+	if !approved {
+		if reason := kubectlShellViolation(input); reason != "" {
+			return &PolicyViolationError{Tool: "kubectl", Verb: reason, Command: strings.TrimSpace(input), Policy: "shell"}
+		}
+	}
+	if role := RoleFromContext(ctx); role != "" {
+		for _, commands := range kubectlCommands(input) {
+			if verb := kubectlChangeVerb(commands); verb != "" && !users.KubectlVerbAllowed(users.Role(role), verb) {
+				return &PolicyViolationError{Tool: "kubectl", Verb: verb, Command: strings.TrimSpace(input), Policy: "role:" + role}
+			}
+		}
+	}
+	if kubectlWriteAllowed() || approved {
+		return nil
+	}
+	for _, commands := range kubectlCommands(input) {
+		if verb := kubectlChangeVerb(commands); verb != "" {
+			violation := PolicyViolationError{Tool: "kubectl", Verb: verb, Command: strings.TrimSpace(input), Policy: "read-only"}
+			if approvalsEnabled() {
+				return &ApprovalRequiredError{PolicyViolationError: violation}
+			}
+			return &violation
+		}
+	}
+	return nil
+}
+
+// kubectlChangeVerb 返回不在只读白名单中的子命令，例如 "delete"、"exec"、"rollout restart"，只读时返回空
+func kubectlChangeVerb(commands []string) string {
+	if kubectlCommandReadOnly(commands) {
+		return ""
+	}
+	if _, ok := kubectlReadSubcommands[commands[0]]; ok && len(commands) > 1 {
+		return commands[0] + " " + commands[1]
+	}
+	return commands[0]
+}
+
+// kubectlShellViolation 检查经由 bash 执行的输入的结构，返回违反策略的原因，合法时返回空：
+// 单引号之外的 $ 和反引号（命令替换、变量展开）、引号之外的 < 和 >（重定向），
+// 以及管道或命令列表中不是 kubectl（按文件名匹配）的可执行文件
+func kubectlShellViolation(input string) string {
+	var quote rune
+	for _, r := range input {
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			}
+		case r == '\'' && quote == 0, r == '"' && quote == 0:
+			quote = r
+		case r == '"' && quote == '"':
+			quote = 0
+		case r == '$' || r == '`':
+			return "command substitution"
+		case (r == '<' || r == '>') && quote == 0:
+			return "redirection"
+		}
+	}
+	for _, segment := range shellSeparatorRe.Split(kubectlInput(input), -1) {
+		fields := strings.Fields(segment)
+		if len(fields) == 0 {
+			continue
+		}
+		if path.Base(fields[0]) != "kubectl" {
+			return fields[0]
+		}
+	}
+	return ""
+}
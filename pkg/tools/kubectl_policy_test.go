@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestCheckKubectlPolicy(t *testing.T) {
+	tests := []struct {
+		input string
+		verb  string
+	}{
+		{"get pods -n shop", ""},
+		{"kubectl -n shop logs payment-api-7d9f --tail 100", ""},
+		{"kubectl rollout status deployment/payment-api", ""},
+		{"kubectl delete pod payment-api-7d9f", "delete"},
+		{"kubectl -n shop scale deployment/payment-api --replicas=0", "scale"},
+		{"kubectl --context prod-east drain node-1 --ignore-daemonsets", "drain"},
+		{"kubectl rollout restart deployment/payment-api", "rollout restart"},
+		{"kubectl get pods -o name | xargs kubectl delete", "xargs"},
+		// 默认拒绝：只读白名单之外的子命令和绕过 bash 解析的写法
+		{"kubectl get pods $(kubectl delete pod x)", "command substitution"},
+		{"kubectl get pods `kubectl delete pod x`", "command substitution"},
+		{"kubectl get pods -n $NAMESPACE", "command substitution"},
+		{"kubectl get pods > /tmp/pods", "redirection"},
+		{"kubectl get pods\nrm -rf /data", "rm"},
+		{"kubectl get pods | grep web", "grep"},
+		{"/usr/bin/kubectl delete pod x", "/usr/bin/kubectl"},
+		{"kubectl get pods; /usr/bin/kubectl delete pod x", "delete"},
+		{"kubectl -v 9 delete pod x", "delete"},
+		{"kubectl --unknown-flag 9 get pods", "9"},
+		{"kubectl exec web -- rm -rf /data", "exec"},
+		{"kubectl certificate approve csr-1", "certificate"},
+		{"kubectl auth reconcile -f rbac.yaml", "auth reconcile"},
+		{"kubectl get pods -o jsonpath='{$.items[*].metadata.name}'", ""},
+		{"kubectl -v=6 get pods -l 'app in (web)'", ""},
+	}
+	for _, tt := range tests {
+		err := checkKubectlPolicy(context.Background(), tt.input)
+		var policyErr *PolicyViolationError
+		if tt.verb == "" && err != nil {
+			t.Errorf("checkKubectlPolicy(%q) = %v, want allowed", tt.input, err)
+		}
+		if tt.verb != "" && (!errors.As(err, &policyErr) || policyErr.Verb != tt.verb) {
+			t.Errorf("checkKubectlPolicy(%q) = %v, want %s blocked", tt.input, err, tt.verb)
+		}
+	}
+
+	utils.GetConfig().Set("tools.kubectl.allow_write", true)
+	defer utils.GetConfig().Set("tools.kubectl.allow_write", nil)
+	if err := checkKubectlPolicy(context.Background(), "kubectl delete pod payment-api-7d9f"); err != nil {
+		t.Errorf("expected writes to be allowed with allow_write, got %v", err)
+	}
+}
+
+func TestInvokeBlocksKubectlWrite(t *testing.T) {
+	var calls int
+	original, _ := Registry.Get("kubectl")
+	Registry.Unregister("kubectl")
+	Registry.Register(ToolSpec{Name: "kubectl", Idempotency: IdempotencyPureRead, Run: WithoutContext(func(input string) (string, error) {
+		calls++
+		return "", nil
+	})})
+	defer func() {
+		Registry.Unregister("kubectl")
+		Registry.Register(original)
+	}()
+
+	var policyErr *PolicyViolationError
+	if _, err := Invoke(context.Background(), "kubectl", "kubectl apply -f payment-api.yaml"); !errors.As(err, &policyErr) || calls != 0 {
+		t.Fatalf("expected apply to be blocked before execution, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCheckKubectlPolicyApproval(t *testing.T) {
+	utils.GetConfig().Set("approvals.enabled", true)
+	defer utils.GetConfig().Set("approvals.enabled", nil)
+
+	const command = "kubectl rollout restart deployment/payment-api"
+	var approvalErr *ApprovalRequiredError
+	if err := checkKubectlPolicy(context.Background(), command); !errors.As(err, &approvalErr) || approvalErr.Verb != "rollout restart" {
+		t.Fatalf("expected approval to be required, got %v", err)
+	}
+
+	ctx := WithApprovedCommand(context.Background(), "kubectl", command)
+	if err := checkKubectlPolicy(ctx, command+" "); err != nil {
+		t.Errorf("expected approved command to run, got %v", err)
+	}
+	if err := checkKubectlPolicy(ctx, "kubectl delete deployment/payment-api"); !errors.As(err, &approvalErr) {
+		t.Errorf("expected approval to cover only the approved command, got %v", err)
+	}
+}
+
+func TestCheckKubectlPolicyRole(t *testing.T) {
+	utils.GetConfig().Set("tools.kubectl.allow_write", true)
+	defer utils.GetConfig().Set("tools.kubectl.allow_write", nil)
+
+	viewer := WithRole(context.Background(), "viewer")
+	var policyErr *PolicyViolationError
+	if err := checkKubectlPolicy(viewer, "kubectl scale deployment/payment-api --replicas=3"); !errors.As(err, &policyErr) || policyErr.Policy != "role:viewer" {
+		t.Errorf("expected viewer to be blocked by role policy, got %v", err)
+	}
+	if err := checkKubectlPolicy(viewer, "kubectl get pods -n shop"); err != nil {
+		t.Errorf("expected viewer reads to be allowed, got %v", err)
+	}
+
+	operator := WithRole(context.Background(), "operator")
+	if err := checkKubectlPolicy(operator, "kubectl rollout restart deployment/payment-api"); err != nil {
+		t.Errorf("expected operator restart to be allowed, got %v", err)
+	}
+	if err := checkKubectlPolicy(WithApprovedCommand(operator, "kubectl", "kubectl delete pod payment-api-7d9f"), "kubectl delete pod payment-api-7d9f"); !errors.As(err, &policyErr) {
+		t.Errorf("expected approval not to bypass the role policy, got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 日志后端类型
+const (
+	LogBackendLoki          = "loki"
+	LogBackendElasticsearch = "elasticsearch"
+)
+
+const (
+	defaultLogSince   = time.Hour
+	defaultLogLimit   = 100
+	maxLogLimit       = 1000
+	maxLogLineRunes   = 500
+	defaultLogESIndex = "logs-*"
+)
+
+// logFlagRe 匹配查询前的 --namespace、--pod 等参数
+var logFlagRe = regexp.MustCompile(`^--(namespace|pod|container|since|start|end|limit)[=\s]+(\S+)\s*`)
+
+var (
+	logSearchClient     *http.Client
+	logSearchClientOnce sync.Once
+)
+
+// LogQuery 日志查询条件
+// Pod 按前缀匹配，传入 Deployment 名称即可查到已重建、已崩溃的历史 Pod 的日志
+type LogQuery struct {
+	Namespace string
+	Pod       string
+	Container string
+	Contains  string // 日志内容包含的文本，为空时不过滤
+	Start     time.Time
+	End       time.Time
+	Limit     int
+}
+
+// LogEntry 一条日志
+type LogEntry struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace,omitempty"`
+	Pod       string    `json:"pod,omitempty"`
+	Container string    `json:"container,omitempty"`
+	Message   string    `json:"message"`
+}
+
+// LogSearchEnabled 是否配置了日志后端（tools.logs.url）
+func LogSearchEnabled() bool {
+	return utils.GetConfig().GetString("tools.logs.url") != ""
+}
+
+// LogSearch 从 Loki 或 Elasticsearch 查询历史日志
+// 输入：--namespace=<ns> --pod=<pod 名称前缀> [--container=<c>] [--since=1h | --start=<RFC3339> --end=<RFC3339>] [--limit=100] [包含的文本]
+// 输出：按时间排序的日志，每行 "时间 pod/container: 内容"
+func LogSearch(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return LogSearchContext(ctx, input)
+}
+
+// LogSearchContext 查询历史日志，ctx 取消时中止请求
+func LogSearchContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_logs")()
+
+	query, err := parseLogQuery(input, time.Now())
+	if err != nil {
+		return err.Error(), err
+	}
+	entries, err := SearchLogs(ctx, query)
+	if err != nil {
+		return err.Error(), err
+	}
+	if len(entries) == 0 {
+		return fmt.Sprintf("%s 至 %s 之间没有匹配的日志，请放宽时间范围或检查命名空间、Pod 名称",
+			query.Start.Format(time.RFC3339), query.End.Format(time.RFC3339)), nil
+	}
+	return FormatLogEntries(entries), nil
+}
+
+// SearchLogs 按条件查询日志，返回按时间升序排列的最近 Limit 条
+func SearchLogs(ctx context.Context, query LogQuery) ([]LogEntry, error) {
+	config := utils.GetConfig()
+	endpoint := strings.TrimSuffix(config.GetString("tools.logs.url"), "/")
+	if endpoint == "" {
+		return nil, fmt.Errorf("未配置日志后端（tools.logs.url），只能通过 kubectl logs 查看当前容器的日志")
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultLogLimit
+	}
+	if query.Limit > maxLogLimit {
+		query.Limit = maxLogLimit
+	}
+	if query.End.IsZero() {
+		query.End = time.Now()
+	}
+	if query.Start.IsZero() {
+		query.Start = query.End.Add(-defaultLogSince)
+	}
+
+	var (
+		entries []LogEntry
+		err     error
+	)
+	switch backend := strings.ToLower(config.GetString("tools.logs.backend")); backend {
+	case "", LogBackendLoki:
+		entries, err = searchLoki(ctx, endpoint, query)
+	case LogBackendElasticsearch:
+		entries, err = searchElasticsearch(ctx, endpoint, query)
+	default:
+		err = fmt.Errorf("不支持的日志后端 %q", backend)
+	}
+	if err != nil {
+		logger.Error("查询日志失败",
+			zap.String("namespace", query.Namespace),
+			zap.String("pod", query.Pod),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	if len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// FormatLogEntries 将日志格式化为文本，过长的行会被截断
+func FormatLogEntries(entries []LogEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		source := entry.Pod
+		if entry.Container != "" {
+			source += "/" + entry.Container
+		}
+		message := strings.TrimRight(entry.Message, "\n")
+		if utf8.RuneCountInString(message) > maxLogLineRunes {
+			message = string([]rune(message)[:maxLogLineRunes]) + "…"
+		}
+		fmt.Fprintf(&b, "%s %s: %s\n", entry.Time.UTC().Format(time.RFC3339), source, message)
+	}
+	return b.String()
+}
+
+// parseLogQuery 解析工具输入中的参数，剩余部分作为日志内容过滤
+func parseLogQuery(input string, now time.Time) (LogQuery, error) {
+	query := LogQuery{End: now, Limit: defaultLogLimit}
+	since := defaultLogSince
+	input = strings.TrimSpace(input)
+	for {
+		m := logFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		switch m[1] {
+		case "namespace":
+			query.Namespace = value
+		case "pod":
+			query.Pod = value
+		case "container":
+			query.Container = value
+		case "since":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return query, fmt.Errorf("--since 取值无效 %q，请使用 30m、6h 等时长", value)
+			}
+			since = d
+		case "start", "end":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("--%s 取值无效 %q，请使用 RFC3339 格式", m[1], value)
+			}
+			if m[1] == "start" {
+				query.Start = t
+			} else {
+				query.End = t
+			}
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return query, fmt.Errorf("--limit 取值无效 %q", value)
+			}
+			query.Limit = n
+		}
+	}
+	if query.Start.IsZero() {
+		query.Start = query.End.Add(-since)
+	}
+	if !query.Start.Before(query.End) {
+		return query, fmt.Errorf("开始时间必须早于结束时间")
+	}
+	if query.Namespace == "" && query.Pod == "" {
+		return query, fmt.Errorf("至少需要 --namespace 或 --pod，避免扫描全部日志")
+	}
+	query.Contains = strings.Trim(strings.TrimSpace(input), `'"`)
+	return query, nil
+}
+
+// logFields 日志字段名，配置项 tools.logs.fields 覆盖默认值
+// Loki 为流标签名（默认 namespace、pod、container），Elasticsearch 为文档字段路径（默认 Fluent Bit 的 kubernetes.* 字段）
+func logFields(backend string) map[string]string {
+	fields := map[string]string{"namespace": "namespace", "pod": "pod", "container": "container"}
+	if backend == LogBackendElasticsearch {
+		fields = map[string]string{
+			"namespace": "kubernetes.namespace_name",
+			"pod":       "kubernetes.pod_name",
+			"container": "kubernetes.container_name",
+			"message":   "log",
+			"timestamp": "@timestamp",
+		}
+	}
+	for key, value := range utils.GetConfig().GetStringMapString("tools.logs.fields") {
+		fields[key] = value
+	}
+	return fields
+}
+
+// searchLoki 通过 /loki/api/v1/query_range 查询日志
+func searchLoki(ctx context.Context, endpoint string, query LogQuery) ([]LogEntry, error) {
+	fields := logFields(LogBackendLoki)
+	var matchers []string
+	if query.Namespace != "" {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", fields["namespace"], query.Namespace))
+	}
+	if query.Pod != "" {
+		matchers = append(matchers, fmt.Sprintf("%s=~%q", fields["pod"], regexp.QuoteMeta(query.Pod)+".*"))
+	}
+	if query.Container != "" {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", fields["container"], query.Container))
+	}
+	logQL := "{" + strings.Join(matchers, ",") + "}"
+	if query.Contains != "" {
+		logQL += fmt.Sprintf(" |= %q", query.Contains)
+	}
+
+	params := url.Values{
+		"query":     {logQL},
+		"start":     {strconv.FormatInt(query.Start.UnixNano(), 10)},
+		"end":       {strconv.FormatInt(query.End.UnixNano(), 10)},
+		"limit":     {strconv.Itoa(query.Limit)},
+		"direction": {"backward"},
+	}
+	var resp struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := doLogRequest(ctx, http.MethodGet, endpoint+"/loki/api/v1/query_range?"+params.Encode(), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var entries []LogEntry
+	for _, stream := range resp.Data.Result {
+		for _, value := range stream.Values {
+			ns, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, LogEntry{
+				Time:      time.Unix(0, ns),
+				Namespace: stream.Stream[fields["namespace"]],
+				Pod:       stream.Stream[fields["pod"]],
+				Container: stream.Stream[fields["container"]],
+				Message:   value[1],
+			})
+		}
+	}
+	return entries, nil
+}
+
+// searchElasticsearch 通过 <index>/_search 查询日志，索引由 tools.logs.index 配置
+func searchElasticsearch(ctx context.Context, endpoint string, query LogQuery) ([]LogEntry, error) {
+	fields := logFields(LogBackendElasticsearch)
+	index := utils.GetConfig().GetString("tools.logs.index")
+	if index == "" {
+		index = defaultLogESIndex
+	}
+
+	filters := []interface{}{
+		map[string]interface{}{"range": map[string]interface{}{fields["timestamp"]: map[string]interface{}{
+			"gte": query.Start.UTC().Format(time.RFC3339Nano),
+			"lte": query.End.UTC().Format(time.RFC3339Nano),
+		}}},
+	}
+	if query.Namespace != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{fields["namespace"]: query.Namespace}})
+	}
+	if query.Pod != "" {
+		filters = append(filters, map[string]interface{}{"prefix": map[string]interface{}{fields["pod"]: query.Pod}})
+	}
+	if query.Container != "" {
+		filters = append(filters, map[string]interface{}{"term": map[string]interface{}{fields["container"]: query.Container}})
+	}
+	if query.Contains != "" {
+		filters = append(filters, map[string]interface{}{"match_phrase": map[string]interface{}{fields["message"]: query.Contains}})
+	}
+	body := map[string]interface{}{
+		"size":  query.Limit,
+		"sort":  []interface{}{map[string]interface{}{fields["timestamp"]: map[string]string{"order": "desc"}}},
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": filters}},
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				Source map[string]interface{} `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := doLogRequest(ctx, http.MethodPost, endpoint+"/"+url.PathEscape(index)+"/_search", body, &resp); err != nil {
+		return nil, err
+	}
+
+	entries := make([]LogEntry, 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		t, _ := time.Parse(time.RFC3339Nano, sourceField(hit.Source, fields["timestamp"]))
+		entries = append(entries, LogEntry{
+			Time:      t,
+			Namespace: sourceField(hit.Source, fields["namespace"]),
+			Pod:       sourceField(hit.Source, fields["pod"]),
+			Container: sourceField(hit.Source, fields["container"]),
+			Message:   sourceField(hit.Source, fields["message"]),
+		})
+	}
+	return entries, nil
+}
+
+// sourceField 读取文档中的字段，支持扁平的 "a.b" 键和嵌套对象两种写法
+func sourceField(source map[string]interface{}, path string) string {
+	if value, ok := source[path]; ok {
+		return fmt.Sprint(value)
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return ""
+	}
+	if nested, ok := source[head].(map[string]interface{}); ok {
+		return sourceField(nested, rest)
+	}
+	return ""
+}
+
+// doLogRequest 发送日志查询请求，附带 tools.logs.bearer_token 和 tools.logs.headers
+func doLogRequest(ctx context.Context, method, rawURL string, body, out interface{}) error {
+	logSearchClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("logs")
+		if err != nil {
+			logger.Warn("创建日志查询 HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = http.DefaultClient
+		}
+		logSearchClient = client
+	})
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	config := utils.GetConfig()
+	if token := config.GetString("tools.logs.bearer_token"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range config.GetStringMapString("tools.logs.headers") {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := logSearchClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("日志后端返回 HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
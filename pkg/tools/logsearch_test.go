@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestParseLogQuery(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	query, err := parseLogQuery(`--namespace=shop --pod payment-api --since=2h "OutOfMemoryError"`, now)
+	if err != nil {
+		t.Fatalf("parseLogQuery() error = %v", err)
+	}
+	if query.Namespace != "shop" || query.Pod != "payment-api" || query.Contains != "OutOfMemoryError" ||
+		!query.Start.Equal(now.Add(-2*time.Hour)) || !query.End.Equal(now) {
+		t.Errorf("parseLogQuery() = %+v", query)
+	}
+	for _, input := range []string{"error", "--namespace=shop --since=abc", "--pod=a --limit=0",
+		"--pod=a --start=2025-03-01T10:00:00Z --end=2025-03-01T09:00:00Z"} {
+		if _, err := parseLogQuery(input, now); err == nil {
+			t.Errorf("parseLogQuery(%q) expected error", input)
+		}
+	}
+}
+
+func TestSearchLogsLoki(t *testing.T) {
+	var logQL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logQL = r.URL.Query().Get("query")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"namespace":"shop","pod":"payment-api-7d9f","container":"app"},
+			 "values":[["1740823200000000000","panic: nil map"],["1740823100000000000","starting"]]}]}}`))
+	}))
+	defer server.Close()
+
+	config := utils.GetConfig()
+	config.Set("tools.logs.backend", "loki")
+	config.Set("tools.logs.url", server.URL)
+	defer config.Set("tools.logs.url", "")
+
+	entries, err := SearchLogs(context.Background(), LogQuery{Namespace: "shop", Pod: "payment-api", Contains: "panic"})
+	if err != nil {
+		t.Fatalf("SearchLogs() error = %v", err)
+	}
+	if want := `{namespace="shop",pod=~"payment-api.*"} |= "panic"`; logQL != want {
+		t.Errorf("LogQL = %q, want %q", logQL, want)
+	}
+	if len(entries) != 2 || entries[1].Message != "panic: nil map" || entries[1].Container != "app" {
+		t.Errorf("SearchLogs() = %+v", entries)
+	}
+	if out := FormatLogEntries(entries); !strings.Contains(out, "payment-api-7d9f/app: panic: nil map") {
+		t.Errorf("FormatLogEntries() = %q", out)
+	}
+}
+
+func TestSearchLogsElasticsearch(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs-*/_search" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"hits":{"hits":[{"_source":{"@timestamp":"2025-03-01T09:59:00Z","log":"connection refused",
+			"kubernetes":{"namespace_name":"shop","pod_name":"payment-api-7d9f","container_name":"app"}}}]}}`))
+	}))
+	defer server.Close()
+
+	config := utils.GetConfig()
+	config.Set("tools.logs.backend", "elasticsearch")
+	config.Set("tools.logs.url", server.URL)
+	defer config.Set("tools.logs.backend", "")
+	defer config.Set("tools.logs.url", "")
+
+	entries, err := SearchLogs(context.Background(), LogQuery{Namespace: "shop", Pod: "payment-api", Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchLogs() error = %v", err)
+	}
+	if body["size"] != float64(10) {
+		t.Errorf("request size = %v, want 10", body["size"])
+	}
+	if len(entries) != 1 || entries[0].Pod != "payment-api-7d9f" || entries[0].Message != "connection refused" {
+		t.Errorf("SearchLogs() = %+v", entries)
+	}
+}
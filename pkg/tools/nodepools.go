@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// NodePools 按节点池汇总节点标签、污点和容量，数据直接来自 API Server
+// 输入：可选的过滤词（节点池、命名空间或 Pod 名称的一部分），可带 --context 指定集群
+// 输出：每个节点池一行的表格，包含节点数、实例类型、CPU/内存/GPU 的已请求/可分配量
+func NodePools(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return NodePoolsContext(ctx, input)
+}
+
+// NodePoolsContext 按节点池汇总节点信息，ctx 取消或超时时中止对 API Server 的请求
+func NodePoolsContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_nodepools")()
+
+	var kubeContext string
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		kubeContext = m[1]
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	filter := strings.Trim(strings.TrimSpace(input), `'"`)
+
+	pools, err := kubernetes.ListNodePools(ctx, kubeContext, utils.GetConfig().GetStringSlice("nodepools.labels"))
+	if err != nil {
+		logger.Error("获取节点池失败",
+			zap.String("context", kubeContext),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+
+	var matched []kubernetes.NodePool
+	for _, pool := range pools {
+		if pool.Matches(filter) {
+			matched = append(matched, pool)
+		}
+	}
+	if len(matched) == 0 {
+		return fmt.Sprintf("没有匹配 %q 的节点池，共有 %d 个节点池", filter, len(pools)), nil
+	}
+	return formatNodePools(matched), nil
+}
+
+// formatNodePools 输出表格，空值使用 <none>，便于图表提取
+func formatNodePools(pools []kubernetes.NodePool) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "POOL\tNODES\tREADY\tINSTANCE-TYPES\tCPU(REQ/ALLOC)\tMEMORY(REQ/ALLOC)\tGPU(REQ/ALLOC)\tPODS\tTAINTS\tTOP-NAMESPACES")
+	for _, p := range pools {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%dm/%dm\t%s/%s\t%d/%d\t%d\t%s\t%s\n",
+			p.Name, p.Nodes, p.Ready, orNone(p.InstanceTypes),
+			p.CPURequested, p.CPUAllocatable,
+			kubernetes.FormatMemory(p.MemRequested), kubernetes.FormatMemory(p.MemAllocatable),
+			p.GPURequested, p.GPUAllocatable, p.Pods,
+			orNone(p.Taints), orNone(p.TopNamespaces(3)),
+		)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func orNone(values []string) string {
+	if len(values) == 0 {
+		return "<none>"
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPromQLStep      = time.Minute
+	defaultPromQLMaxSeries = 20
+	// promqlMaxPoints Prometheus 单次范围查询允许的最大点数
+	promqlMaxPoints = 11000
+)
+
+// promqlFlagRe 匹配查询前的 --range、--step、--time 参数
+var promqlFlagRe = regexp.MustCompile(`^--(range|step|time)[=\s]+(\S+)\s*`)
+
+var (
+	promqlClient     *http.Client
+	promqlClientOnce sync.Once
+)
+
+// promqlRequest 解析后的 promql 工具输入
+type promqlRequest struct {
+	Query string
+	Range time.Duration // 为 0 时执行即时查询
+	Step  time.Duration
+	Time  time.Time
+}
+
+// promqlResponse Prometheus HTTP API 响应
+type promqlResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+			Values [][]interface{}   `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// PromQL 查询 Prometheus（或兼容的 Thanos、VictoriaMetrics）指标
+// 输入：PromQL 表达式，可在前面加 --range=1h [--step=1m] 执行范围查询，或 --time=<RFC3339> 指定即时查询的时间点
+// 输出：即时查询每个序列一行当前值；范围查询每个序列一行 min/avg/max/last，避免把全部采样点交给 LLM
+func PromQL(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return PromQLContext(ctx, input)
+}
+
+// PromQLContext 查询 Prometheus 指标，使用请求上下文中的集群选择 tools.promql.endpoints 中对应的地址
+func PromQLContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_promql")()
+
+	endpoint := promqlEndpoint(KubeContextFromContext(ctx))
+	if endpoint == "" {
+		err := fmt.Errorf("未配置 Prometheus 地址（tools.promql.url），无法查询指标，请改用 kubectl top 等命令")
+		return err.Error(), err
+	}
+	req, err := parsePromQLInput(input, time.Now())
+	if err != nil {
+		return err.Error(), err
+	}
+
+	params := url.Values{"query": {req.Query}}
+	path := "/api/v1/query"
+	if req.Range > 0 {
+		path = "/api/v1/query_range"
+		params.Set("start", strconv.FormatInt(req.Time.Add(-req.Range).Unix(), 10))
+		params.Set("end", strconv.FormatInt(req.Time.Unix(), 10))
+		params.Set("step", strconv.FormatFloat(req.Step.Seconds(), 'f', -1, 64))
+	} else {
+		params.Set("time", strconv.FormatInt(req.Time.Unix(), 10))
+	}
+
+	resp, err := queryPrometheus(ctx, strings.TrimSuffix(endpoint, "/")+path+"?"+params.Encode())
+	if err != nil {
+		logger.Error("查询 Prometheus 失败",
+			zap.String("endpoint", endpoint),
+			zap.String("query", req.Query),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+	return formatPromQLResult(resp), nil
+}
+
+// promqlEndpoint 返回集群对应的 Prometheus 地址，tools.promql.endpoints.<context> 优先于 tools.promql.url
+func promqlEndpoint(kubeContext string) string {
+	config := utils.GetConfig()
+	if kubeContext != "" {
+		if endpoint, ok := config.GetStringMapString("tools.promql.endpoints")[strings.ToLower(kubeContext)]; ok {
+			return endpoint
+		}
+	}
+	return config.GetString("tools.promql.url")
+}
+
+// parsePromQLInput 解析工具输入中的参数和 PromQL 表达式
+func parsePromQLInput(input string, now time.Time) (promqlRequest, error) {
+	req := promqlRequest{Step: defaultPromQLStep, Time: now}
+	input = strings.TrimSpace(input)
+	for {
+		m := promqlFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		switch m[1] {
+		case "range", "step":
+			d, err := time.ParseDuration(m[2])
+			if err != nil || d <= 0 {
+				return req, fmt.Errorf("--%s 取值无效 %q，请使用 5m、1h 等时长", m[1], m[2])
+			}
+			if m[1] == "range" {
+				req.Range = d
+			} else {
+				req.Step = d
+			}
+		case "time":
+			t, err := time.Parse(time.RFC3339, m[2])
+			if err != nil {
+				return req, fmt.Errorf("--time 取值无效 %q，请使用 RFC3339 格式", m[2])
+			}
+			req.Time = t
+		}
+	}
+	req.Query = strings.Trim(strings.TrimSpace(input), `'"`)
+	if req.Query == "" {
+		return req, fmt.Errorf("缺少 PromQL 表达式")
+	}
+	// 步长过小时自动放大，避免超过 Prometheus 的点数上限
+	if req.Range > 0 && req.Range/req.Step > promqlMaxPoints {
+		req.Step = req.Range / promqlMaxPoints
+	}
+	return req, nil
+}
+
+// queryPrometheus 发送查询请求，附带 tools.promql.bearer_token 和 tools.promql.headers（如 Thanos/Cortex 的 X-Scope-OrgID）
+func queryPrometheus(ctx context.Context, rawURL string) (*promqlResponse, error) {
+	promqlClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("promql")
+		if err != nil {
+			logger.Warn("创建 Prometheus HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = http.DefaultClient
+		}
+		promqlClient = client
+	})
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	config := utils.GetConfig()
+	if token := config.GetString("tools.promql.bearer_token"); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	for key, value := range config.GetStringMapString("tools.promql.headers") {
+		httpReq.Header.Set(key, value)
+	}
+
+	httpResp, err := promqlClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, 8<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp promqlResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("Prometheus 返回 HTTP %d: %s", httpResp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("PromQL 查询失败（%s）: %s", resp.ErrorType, resp.Error)
+	}
+	return &resp, nil
+}
+
+// formatPromQLResult 将查询结果转换为紧凑的文本，序列超过 tools.promql.max_series 时截断
+func formatPromQLResult(resp *promqlResponse) string {
+	results := resp.Data.Result
+	if len(results) == 0 {
+		return "查询结果为空：没有匹配的序列，请检查指标名和标签（可先查询 count by (__name__) ({__name__=~\"...\"})）"
+	}
+	maxSeries := utils.GetConfig().GetInt("tools.promql.max_series")
+	if maxSeries <= 0 {
+		maxSeries = defaultPromQLMaxSeries
+	}
+
+	lines := make([]string, 0, len(results))
+	for _, result := range results {
+		labels := formatPromQLLabels(result.Metric)
+		switch resp.Data.ResultType {
+		case "matrix":
+			var values []float64
+			for _, point := range result.Values {
+				if v, ok := promqlSampleValue(point); ok {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			min, max, sum := values[0], values[0], 0.0
+			for _, v := range values {
+				min, max, sum = math.Min(min, v), math.Max(max, v), sum+v
+			}
+			lines = append(lines, fmt.Sprintf("%s min=%s avg=%s max=%s last=%s samples=%d", labels,
+				formatPromQLValue(min), formatPromQLValue(sum/float64(len(values))), formatPromQLValue(max),
+				formatPromQLValue(values[len(values)-1]), len(values)))
+		default:
+			if v, ok := promqlSampleValue(result.Value); ok {
+				lines = append(lines, fmt.Sprintf("%s %s", labels, formatPromQLValue(v)))
+			}
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) > maxSeries {
+		omitted := len(lines) - maxSeries
+		lines = append(lines[:maxSeries], fmt.Sprintf("……另有 %d 个序列未显示，请使用 topk() 或更具体的标签筛选", omitted))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// promqlSampleValue 解析 [时间戳, "值"] 格式的采样点
+func promqlSampleValue(sample []interface{}) (float64, bool) {
+	if len(sample) != 2 {
+		return 0, false
+	}
+	s, ok := sample[1].(string)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil && !math.IsNaN(v)
+}
+
+func formatPromQLLabels(metric map[string]string) string {
+	if len(metric) == 0 {
+		return "{}"
+	}
+	keys := make([]string, 0, len(metric))
+	for key := range metric {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%q", key, metric[key])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatPromQLValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
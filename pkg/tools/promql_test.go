@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestParsePromQLInput(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	req, err := parsePromQLInput(`--range=1h --step 30s 'sum(rate(http_requests_total[5m]))'`, now)
+	if err != nil || req.Range != time.Hour || req.Step != 30*time.Second || req.Query != "sum(rate(http_requests_total[5m]))" {
+		t.Errorf("parsePromQLInput() = %+v, %v", req, err)
+	}
+	if req, _ := parsePromQLInput("--range=30d up", now); req.Range/req.Step > promqlMaxPoints {
+		t.Errorf("expected step to be widened for long ranges, got %s", req.Step)
+	}
+	for _, input := range []string{"", "--range=abc up", "--range=1h"} {
+		if _, err := parsePromQLInput(input, now); err == nil {
+			t.Errorf("parsePromQLInput(%q) expected error", input)
+		}
+	}
+}
+
+func TestPromQLRangeQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" || r.Header.Get("X-Scope-OrgID") != "ops" {
+			http.Error(w, `{"status":"error","errorType":"bad_data","error":"unexpected request"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"pod":"payment-api-7d9f"},"values":[[1,"0.2"],[2,"0.6"],[3,"0.4"]]}]}}`))
+	}))
+	defer server.Close()
+
+	config := utils.GetConfig()
+	config.Set("tools.promql.endpoints", map[string]string{"prod-east": server.URL})
+	config.Set("tools.promql.headers", map[string]string{"X-Scope-OrgID": "ops"})
+	defer config.Set("tools.promql.endpoints", nil)
+	defer config.Set("tools.promql.headers", nil)
+
+	ctx := WithKubeContext(context.Background(), "prod-east")
+	out, err := PromQLContext(ctx, `--range=1h sum(rate(container_cpu_usage_seconds_total{pod=~"payment-api.*"}[5m])) by (pod)`)
+	if err != nil {
+		t.Fatalf("PromQLContext() error = %v", err)
+	}
+	if want := `{pod="payment-api-7d9f"} min=0.2 avg=0.4 max=0.6 last=0.4 samples=3`; !strings.Contains(out, want) {
+		t.Errorf("PromQLContext() = %q, want %q", out, want)
+	}
+
+	if _, err := PromQLContext(context.Background(), "up"); err == nil {
+		t.Error("expected error without a configured Prometheus endpoint")
+	}
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package tools
 
 import (
+	"context"
 	"fmt"
 	"github.com/fatih/color"
 	"os/exec"
@@ -23,13 +24,22 @@ import (
 
 // PythonREPL runs the given Python script and returns the output.
 func PythonREPL(script string) (string, error) {
+	return PythonREPLContext(context.Background(), script)
+}
+
+// PythonREPLContext runs the given Python script and kills it when ctx is cancelled.
+// When tools.python.sandbox.enabled is set the script runs in the sandbox instead, see PythonSandbox.
+func PythonREPLContext(ctx context.Context, script string) (string, error) {
 	logger.Debug("准备执行 Python 脚本",
 		zap.String("script", script),
 	)
+	if sandbox := PythonSandboxConfig(); sandbox != nil {
+		return sandbox.Run(ctx, script)
+	}
 
 	escapedScript := strings.ReplaceAll(script, "\"", "\\\"")
 	cmdStr := fmt.Sprintf("cd ~/k8s/python-cli && source k8s-env/bin/activate && python3 -c \"%s\"", escapedScript)
-	cmd := exec.Command("bash", "-c", cmdStr)
+	cmd := commandContext(ctx, "bash", "-c", cmdStr)
 	
 	logger.Debug("构建命令",
 		zap.String("command", cmdStr),
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSandboxRuntime  = "docker"
+	defaultSandboxImage    = "python:3.12-slim"
+	defaultSandboxCPUs     = 1.0
+	defaultSandboxMemoryMB = 256
+	defaultSandboxTimeout  = 30 * time.Second
+	// sandboxPidsLimit 容器内的最大进程数
+	sandboxPidsLimit = 64
+)
+
+// defaultSandboxModules 未配置 tools.python.sandbox.allowed_modules 时允许导入的模块，只包含计算和文本处理相关的标准库
+var defaultSandboxModules = []string{
+	"base64", "collections", "csv", "datetime", "decimal", "fractions", "functools", "hashlib", "heapq",
+	"ipaddress", "itertools", "json", "math", "operator", "random", "re", "statistics", "string", "textwrap", "time",
+}
+
+// sandboxPrelude 在沙箱中执行脚本的引导代码：设置内存和 CPU 时间上限，只允许脚本导入白名单中的模块，
+// 脚本从标准输入读取。导入限制只作用于脚本本身，白名单模块内部的导入不受影响；
+// 它只是减少误用的手段，隔离由容器或命名空间加 cgroup 保证
+const sandboxPrelude = `import builtins, resource, sys
+_allowed = frozenset(m for m in sys.argv[1].split(",") if m)
+_memory, _cpu = int(sys.argv[2]), int(sys.argv[3])
+if _memory > 0:
+    resource.setrlimit(resource.RLIMIT_AS, (_memory, _memory))
+if _cpu > 0:
+    resource.setrlimit(resource.RLIMIT_CPU, (_cpu, _cpu))
+_import = builtins.__import__
+def _guarded_import(name, globals=None, locals=None, fromlist=(), level=0):
+    if level == 0 and (globals is None or globals.get("__name__") == "__main__") and name.partition(".")[0] not in _allowed:
+        raise ImportError("module %r is not allowed in the sandbox" % name)
+    return _import(name, globals, locals, fromlist, level)
+_source = sys.stdin.read()
+sys.argv = ["<script>"]
+builtins.__import__ = _guarded_import
+exec(compile(_source, "<script>", "exec"), {"__name__": "__main__", "__builtins__": builtins})
+`
+
+// sandboxMountScript process 运行方式在新的挂载命名空间中执行的引导脚本：以 tmpfs 作为根目录，
+// 只读挂载解释器所需的 /usr、/lib 等系统目录，读写挂载本次执行的工作目录，切换根目录后卸载原来的根，
+// 脚本看不到 /etc、/home、/var/run/secrets 等目录，无法读取 kubeconfig 和服务账号令牌
+// 参数：新根目录的挂载点、工作目录，其后为要执行的命令
+const sandboxMountScript = `set -e
+root=$1 work=$2
+shift 2
+mount -t tmpfs -o size=16m,mode=755 tmpfs "$root"
+for d in /usr /bin /sbin /lib /lib32 /lib64 /libx32; do
+	if [ -L "$d" ]; then
+		ln -s "$(readlink "$d")" "$root$d"
+	elif [ -d "$d" ]; then
+		mkdir "$root$d"
+		mount --rbind "$d" "$root$d"
+		mount -o remount,bind,ro "$root$d"
+	fi
+done
+mkdir "$root/work" "$root/tmp" "$root/proc" "$root/.old"
+mount --bind "$work" "$root/work"
+cd "$root"
+pivot_root . .old
+cd /
+mount -t proc proc /proc
+umount -l /.old
+rmdir /.old
+cd /work
+exec "$@"
+`
+
+// PythonSandbox python 工具的沙箱配置，配置项 tools.python.sandbox
+type PythonSandbox struct {
+	Runtime        string // docker（默认）、podman 或 process（Linux 命名空间加 cgroup v2）
+	Image          string // 容器镜像，Runtime 为 docker、podman 时使用
+	Python         string // Runtime 为 process 时使用的解释器，必须位于 /usr 下
+	Cgroup         string // Runtime 为 process 时使用的 cgroup v2 目录，需要委派给服务的运行用户
+	CPUs           float64
+	MemoryMB       int
+	Timeout        time.Duration
+	AllowedModules []string
+}
+
+// PythonSandboxConfig 读取沙箱配置，未启用 tools.python.sandbox.enabled 时返回 nil
+func PythonSandboxConfig() *PythonSandbox {
+	config := utils.GetConfig()
+	if !config.GetBool("tools.python.sandbox.enabled") {
+		return nil
+	}
+	sandbox := &PythonSandbox{
+		Runtime:        config.GetString("tools.python.sandbox.runtime"),
+		Image:          config.GetString("tools.python.sandbox.image"),
+		Python:         config.GetString("tools.python.sandbox.python"),
+		Cgroup:         config.GetString("tools.python.sandbox.cgroup"),
+		CPUs:           config.GetFloat64("tools.python.sandbox.cpus"),
+		MemoryMB:       config.GetInt("tools.python.sandbox.memory_mb"),
+		Timeout:        config.GetDuration("tools.python.sandbox.timeout"),
+		AllowedModules: config.GetStringSlice("tools.python.sandbox.allowed_modules"),
+	}
+	if sandbox.Runtime == "" {
+		sandbox.Runtime = defaultSandboxRuntime
+	}
+	if sandbox.Image == "" {
+		sandbox.Image = defaultSandboxImage
+	}
+	if sandbox.Python == "" {
+		sandbox.Python = "python3"
+	}
+	if sandbox.CPUs <= 0 {
+		sandbox.CPUs = defaultSandboxCPUs
+	}
+	if sandbox.MemoryMB <= 0 {
+		sandbox.MemoryMB = defaultSandboxMemoryMB
+	}
+	if sandbox.Timeout <= 0 {
+		sandbox.Timeout = defaultSandboxTimeout
+	}
+	if len(sandbox.AllowedModules) == 0 {
+		sandbox.AllowedModules = defaultSandboxModules
+	}
+	return sandbox
+}
+
+// Run 在沙箱中执行脚本：没有网络，限制内存、CPU 时间和运行时长，只能导入白名单中的模块
+func (s *PythonSandbox) Run(ctx context.Context, script string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	var dir string
+	if s.Runtime == "process" {
+		// 每次执行使用新的工作目录，结束后删除
+		var err error
+		if dir, err = os.MkdirTemp("", "opsagent-python-"); err != nil {
+			return err.Error(), err
+		}
+		defer os.RemoveAll(dir)
+		for _, sub := range []string{"root", "work"} {
+			if err := os.Mkdir(filepath.Join(dir, sub), 0o700); err != nil {
+				return err.Error(), err
+			}
+		}
+	}
+	name, args, err := s.command(dir)
+	if err != nil {
+		return err.Error(), err
+	}
+	cmd := commandContext(ctx, name, args...)
+	cmd.Stdin = strings.NewReader(script)
+	if s.Runtime == "process" {
+		release, err := s.limitProcess(cmd)
+		if err != nil {
+			return err.Error(), err
+		}
+		defer release()
+	}
+	if container := containerName(args); container != "" {
+		// 结束 docker/podman 客户端不会停止容器，取消时同时结束容器
+		cancelProcess := cmd.Cancel
+		cmd.Cancel = func() error {
+			_ = exec.Command(name, "kill", container).Run()
+			if cancelProcess != nil {
+				return cancelProcess()
+			}
+			return cmd.Process.Kill()
+		}
+	}
+
+	output, err := cmd.CombinedOutput()
+	result := strings.TrimSpace(string(output))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("python script exceeded the sandbox time limit of %s", s.Timeout)
+		return strings.TrimSpace(result + "\n" + err.Error()), err
+	}
+	if err != nil {
+		logger.Error("沙箱中的 Python 脚本执行失败",
+			zap.String("runtime", s.Runtime),
+			zap.Error(err),
+			zap.String("output", result),
+		)
+		return result, err
+	}
+	return result, nil
+}
+
+// command 返回执行沙箱的命令和参数，dir 为 process 运行方式的临时目录，其中包含 root 和 work 两个子目录
+func (s *PythonSandbox) command(dir string) (string, []string, error) {
+	memory := int64(s.MemoryMB) << 20
+	// CPU 时间上限比运行时长略多，正常情况下由运行时长先触发，返回明确的超时错误
+	cpuSeconds := int64(math.Ceil(s.Timeout.Seconds()*s.CPUs)) + 1
+	python := []string{"-I", "-c", sandboxPrelude, strings.Join(s.AllowedModules, ","),
+		strconv.FormatInt(memory, 10), strconv.FormatInt(cpuSeconds, 10)}
+
+	switch s.Runtime {
+	case "process":
+		// 在新的用户、网络、挂载和 PID 命名空间中运行：只有回环接口，无法访问网络，
+		// 文件系统只有只读的系统目录和工作目录，资源上限由 limitProcess 的 cgroup 保证
+		path, err := exec.LookPath("unshare")
+		if err != nil {
+			return "", nil, fmt.Errorf("python sandbox runtime process needs unshare (util-linux) on Linux: %v", err)
+		}
+		args := []string{"--user", "--map-root-user", "--net", "--mount", "--pid", "--fork", "--",
+			"sh", "-c", sandboxMountScript, "sh", filepath.Join(dir, "root"), filepath.Join(dir, "work"), s.Python}
+		return path, append(args, python...), nil
+	case "docker", "podman":
+		args := []string{"run", "--rm", "-i",
+			"--name", "opsagent-python-" + randomSuffix(),
+			"--network", "none",
+			"--cpus", strconv.FormatFloat(s.CPUs, 'f', -1, 64),
+			"--memory", fmt.Sprintf("%dm", s.MemoryMB),
+			"--memory-swap", fmt.Sprintf("%dm", s.MemoryMB),
+			"--pids-limit", strconv.Itoa(sandboxPidsLimit),
+			"--read-only", "--tmpfs", "/tmp:rw,size=16m",
+			"--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+			"--user", "65534:65534",
+			s.Image, "python3"}
+		return s.Runtime, append(args, python...), nil
+	default:
+		return "", nil, fmt.Errorf("unsupported python sandbox runtime %q, supported: process, docker, podman", s.Runtime)
+	}
+}
+
+// containerName 返回 docker/podman 参数中的容器名称
+func containerName(args []string) string {
+	for i, arg := range args {
+		if arg == "--name" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+)
+
+// limitProcess 在 tools.python.sandbox.cgroup 下为本次执行创建子 cgroup，写入内存、CPU 和进程数上限，
+// 命令启动时直接进入该 cgroup，返回的函数结束 cgroup 中残留的进程并删除它
+func (s *PythonSandbox) limitProcess(cmd *exec.Cmd) (func(), error) {
+	if s.Cgroup == "" {
+		return nil, errors.New("python sandbox runtime process needs tools.python.sandbox.cgroup (a cgroup v2 directory delegated to this user) to enforce resource limits, or use runtime docker or podman")
+	}
+	dir := filepath.Join(s.Cgroup, "opsagent-python-"+randomSuffix())
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create python sandbox cgroup: %v", err)
+	}
+	remove := func() {
+		_ = os.WriteFile(filepath.Join(dir, "cgroup.kill"), []byte("1"), 0)
+		_ = os.Remove(dir)
+	}
+
+	limits := []struct{ file, value string }{
+		{"memory.max", strconv.Itoa(s.MemoryMB << 20)},
+		{"memory.swap.max", "0"},
+		{"pids.max", strconv.Itoa(sandboxPidsLimit)},
+		{"cpu.max", fmt.Sprintf("%d 100000", int(s.CPUs*100000))},
+	}
+	for _, limit := range limits {
+		err := os.WriteFile(filepath.Join(dir, limit.file), []byte(limit.value), 0)
+		if errors.Is(err, os.ErrNotExist) && limit.file == "memory.swap.max" {
+			// 未启用 swap 记账时没有该文件
+			continue
+		}
+		if err != nil {
+			remove()
+			return nil, fmt.Errorf("failed to set python sandbox cgroup limit %s (is the memory, cpu and pids controller enabled in %s/cgroup.subtree_control?): %v",
+				limit.file, s.Cgroup, err)
+		}
+	}
+
+	fd, err := os.Open(dir)
+	if err != nil {
+		remove()
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(fd.Fd())
+	return func() {
+		fd.Close()
+		remove()
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tools
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// limitProcess 当前平台不支持 process 运行方式
+func (s *PythonSandbox) limitProcess(cmd *exec.Cmd) (func(), error) {
+	return nil, errors.New("python sandbox runtime process is only supported on Linux, use runtime docker or podman")
+}
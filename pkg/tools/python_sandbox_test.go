@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPythonSandboxCommand(t *testing.T) {
+	sandbox := &PythonSandbox{Runtime: "docker", Image: "python:3.12-slim", CPUs: 0.5, MemoryMB: 128, Timeout: 10 * time.Second, AllowedModules: []string{"json"}}
+	name, args, err := sandbox.command("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"--network none", "--cpus 0.5", "--memory 128m", "--read-only", "--cap-drop ALL", "python:3.12-slim python3 -I -c"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("%s args missing %q: %s", name, want, joined)
+		}
+	}
+	if n := len(args); args[n-3] != "json" || args[n-2] != "134217728" || args[n-1] != "6" {
+		t.Errorf("prelude arguments = %v", args[n-3:])
+	}
+	if containerName(args) == "" {
+		t.Error("container should be named so it can be killed on cancel")
+	}
+
+	sandbox.Runtime = "chroot"
+	if _, _, err := sandbox.command(""); err == nil {
+		t.Error("expected error for unsupported runtime")
+	}
+}
+
+// requireNamespaces 跳过不支持 process 运行方式所需命名空间的环境
+func requireNamespaces(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	if err := exec.Command("unshare", "--user", "--map-root-user", "--net", "--mount", "--pid", "--fork", "true").Run(); err != nil {
+		t.Skipf("user namespaces not available: %v", err)
+	}
+}
+
+func TestPythonSandboxProcessMounts(t *testing.T) {
+	requireNamespaces(t)
+	home := t.TempDir()
+	secret := filepath.Join(home, "kubeconfig")
+	if err := os.WriteFile(secret, []byte("token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, sub := range []string{"root", "work"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sandbox := &PythonSandbox{Runtime: "process", Python: "python3", CPUs: 1, MemoryMB: 256, Timeout: 5 * time.Second,
+		AllowedModules: []string{"os"}}
+	name, args, err := sandbox.command(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 直接执行命令，不经过 limitProcess，验证挂载命名空间中只能看到系统目录和工作目录
+	script := fmt.Sprintf("import os\nprint(sorted(os.listdir('/')))\nopen('out', 'w').write('ok')\n"+
+		"for p in (%q, '/etc/passwd'):\n    try:\n        open(p)\n        print('readable', p)\n    except OSError:\n        pass", secret)
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(script)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("sandbox failed: %v: %s", err, output)
+	}
+	if strings.Contains(string(output), "readable") {
+		t.Errorf("files outside the sandbox are readable: %s", output)
+	}
+	for _, hidden := range []string{"'etc'", "'home'", "'root'", "'var'"} {
+		if strings.Contains(string(output), hidden) {
+			t.Errorf("root directory should not contain %s: %s", hidden, output)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "work", "out")); err != nil || string(data) != "ok" {
+		t.Errorf("work directory = %q, %v", data, err)
+	}
+}
+
+func TestPythonSandboxProcessCgroup(t *testing.T) {
+	if _, err := exec.LookPath("unshare"); err != nil {
+		t.Skip("unshare not installed")
+	}
+	sandbox := &PythonSandbox{Runtime: "process", Python: "python3", CPUs: 1, MemoryMB: 64, Timeout: 5 * time.Second}
+	if output, err := sandbox.Run(context.Background(), "print(1)"); err == nil || !strings.Contains(output, "tools.python.sandbox.cgroup") {
+		t.Errorf("Run without cgroup = %q, %v, want error", output, err)
+	}
+}
+
+func TestPythonSandboxProcess(t *testing.T) {
+	requireNamespaces(t)
+	cgroup := os.Getenv("OPSAGENT_TEST_CGROUP")
+	if cgroup == "" {
+		t.Skip("OPSAGENT_TEST_CGROUP not set to a delegated cgroup v2 directory")
+	}
+	sandbox := &PythonSandbox{Runtime: "process", Python: "python3", Cgroup: cgroup, CPUs: 1, MemoryMB: 256, Timeout: 5 * time.Second,
+		AllowedModules: append(slices.Clone(defaultSandboxModules), "socket")}
+	ctx := context.Background()
+
+	if output, err := sandbox.Run(ctx, `import json; print(json.dumps({"quote": "it's \"ok\""}))`); err != nil || output != `{"quote": "it's \"ok\""}` {
+		t.Errorf("Run = %q, %v", output, err)
+	}
+	if output, err := sandbox.Run(ctx, "import os\nprint(os.listdir('/'))"); err == nil || !strings.Contains(output, "not allowed in the sandbox") {
+		t.Errorf("import os = %q, %v, want ImportError", output, err)
+	}
+	script := "import socket\ns = socket.socket()\ns.settimeout(2)\ntry:\n    s.connect(('1.1.1.1', 53))\n    print('connected')\nexcept OSError as e:\n    print('blocked', e)"
+	if output, err := sandbox.Run(ctx, script); err != nil || !strings.HasPrefix(output, "blocked") {
+		t.Errorf("network access = %q, %v, want blocked", output, err)
+	}
+
+	sandbox.Timeout = time.Second
+	if output, err := sandbox.Run(ctx, "while True:\n    pass"); err == nil || !strings.Contains(output, "time limit") {
+		t.Errorf("endless loop = %q, %v, want time limit error", output, err)
+	}
+}
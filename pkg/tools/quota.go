@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// QuotaExceededError 工具调用超出每日配额
+type QuotaExceededError struct {
+	Tool    string
+	User    string
+	Used    int
+	Limit   int
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily quota for tool %s exceeded for user %s (%d/%d), resets at %s. Do not call this tool again; answer with the information already gathered or suggest asking an administrator to raise the quota.",
+		e.Tool, e.User, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+// QuotaUsage 用户某个工具当天的配额使用情况，Limit 为 -1 表示不限制
+type QuotaUsage struct {
+	Tool       string `json:"tool"`
+	Used       int    `json:"used"`
+	Limit      int    `json:"limit"`
+	Overridden bool   `json:"overridden"`
+}
+
+// quotaStore 每日调用次数和覆盖配额的存储，启用审计时保存在审计数据库，多个实例共享且重启后保留，否则只保存在内存中
+type quotaStore interface {
+	GetToolQuota(ctx context.Context, day, username, tool string) (*audit.ToolQuota, error)
+	IncrementToolQuota(ctx context.Context, day, username, tool string, limit int) (bool, error)
+	SetToolQuotaOverride(ctx context.Context, day, username, tool string, limit int) error
+	ListToolQuotas(ctx context.Context, day, username string) ([]audit.ToolQuota, error)
+	PruneToolQuotas(ctx context.Context, before string) (int64, error)
+}
+
+// QuotaManager 按用户统计昂贵工具的每日调用次数
+// 配额通过 tools.quotas.<tool> 配置，管理员可以为某个用户临时覆盖当天的配额
+type QuotaManager struct {
+	memory *memoryQuotaStore
+	now    func() time.Time
+
+	mu        sync.Mutex
+	prunedDay string // 最近一次清理前一天记录的日期，每天只清理一次
+}
+
+var (
+	quotaManager     *QuotaManager
+	quotaManagerOnce sync.Once
+)
+
+// GetQuotaManager 获取全局配额管理器
+func GetQuotaManager() *QuotaManager {
+	quotaManagerOnce.Do(func() {
+		quotaManager = NewQuotaManager()
+	})
+	return quotaManager
+}
+
+// NewQuotaManager 创建配额管理器
+func NewQuotaManager() *QuotaManager {
+	return &QuotaManager{
+		memory: newMemoryQuotaStore(),
+		now:    time.Now,
+	}
+}
+
+func (m *QuotaManager) store() quotaStore {
+	if s := audit.GetStore(); s != nil {
+		return s
+	}
+	return m.memory
+}
+
+// Consume 消耗一次工具调用配额，超出时返回 *QuotaExceededError
+// 未识别用户（如命令行调用）和未配置配额的工具不受限制；读写配额存储失败时放行，避免存储故障导致工具不可用
+func (m *QuotaManager) Consume(ctx context.Context, user, tool string) error {
+	if user == "" {
+		return nil
+	}
+	m.prune(ctx)
+
+	store, day := m.store(), m.day()
+	q, err := store.GetToolQuota(ctx, day, user, tool)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warn("读取工具配额失败，本次调用不计入配额", zap.String("tool", tool), zap.Error(err))
+		return nil
+	}
+	// 未配置配额且未被覆盖的工具不记录调用次数
+	if q == nil && !utils.GetConfig().IsSet("tools.quotas."+tool) {
+		return nil
+	}
+	limit, _ := m.limit(tool, q)
+	ok, err := store.IncrementToolQuota(ctx, day, user, tool, limit)
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warn("记录工具配额失败，本次调用不计入配额", zap.String("tool", tool), zap.Error(err))
+		return nil
+	}
+	if ok {
+		return nil
+	}
+	used := limit
+	if q, err := store.GetToolQuota(ctx, day, user, tool); err == nil && q != nil {
+		used = q.Used
+	}
+	return &QuotaExceededError{
+		Tool:    tool,
+		User:    user,
+		Used:    used,
+		Limit:   limit,
+		ResetAt: m.resetAt(),
+	}
+}
+
+// Override 为用户设置当天的工具配额，limit 为 -1 表示不限制
+func (m *QuotaManager) Override(ctx context.Context, user, tool string, limit int) error {
+	return m.store().SetToolQuotaOverride(ctx, m.day(), user, tool, limit)
+}
+
+// Usage 返回用户当天所有受限工具的配额使用情况
+func (m *QuotaManager) Usage(ctx context.Context, user string) ([]QuotaUsage, error) {
+	quotas, err := m.store().ListToolQuotas(ctx, m.day(), user)
+	if err != nil {
+		return nil, err
+	}
+	records := map[string]*audit.ToolQuota{}
+	for i := range quotas {
+		records[quotas[i].Tool] = &quotas[i]
+	}
+
+	names := map[string]bool{}
+	for tool := range utils.GetConfig().GetStringMap("tools.quotas") {
+		names[tool] = true
+	}
+	for tool, q := range records {
+		if q.Override != nil {
+			names[tool] = true
+		}
+	}
+
+	usage := make([]QuotaUsage, 0, len(names))
+	for tool := range names {
+		q := records[tool]
+		limit, overridden := m.limit(tool, q)
+		used := 0
+		if q != nil {
+			used = q.Used
+		}
+		usage = append(usage, QuotaUsage{Tool: tool, Used: used, Limit: limit, Overridden: overridden})
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Tool < usage[j].Tool })
+	return usage, nil
+}
+
+// limit 返回配额上限，-1 表示不限制；q 为当天的调用记录，其中的覆盖配额优先
+func (m *QuotaManager) limit(tool string, q *audit.ToolQuota) (int, bool) {
+	if q != nil && q.Override != nil {
+		return *q.Override, true
+	}
+	configKey := "tools.quotas." + tool
+	if !utils.GetConfig().IsSet(configKey) {
+		return -1, false
+	}
+	limit := utils.GetConfig().GetInt(configKey)
+	if limit <= 0 {
+		return -1, false
+	}
+	return limit, false
+}
+
+// prune 每天第一次调用时清理前一天及更早的记录
+func (m *QuotaManager) prune(ctx context.Context) {
+	day := m.day()
+	m.mu.Lock()
+	if m.prunedDay == day {
+		m.mu.Unlock()
+		return
+	}
+	m.prunedDay = day
+	m.mu.Unlock()
+
+	if _, err := m.store().PruneToolQuotas(ctx, day); err != nil {
+		utils.LoggerFromContext(ctx).Warn("清理过期的工具配额记录失败", zap.Error(err))
+	}
+}
+
+func (m *QuotaManager) day() string {
+	return m.now().Format("2006-01-02")
+}
+
+func (m *QuotaManager) resetAt() time.Time {
+	now := m.now()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
+
+type quotaKey struct {
+	day  string
+	user string
+	tool string
+}
+
+// memoryQuotaStore 未启用审计时使用的进程内配额存储
+type memoryQuotaStore struct {
+	mu     sync.Mutex
+	quotas map[quotaKey]*audit.ToolQuota
+}
+
+func newMemoryQuotaStore() *memoryQuotaStore {
+	return &memoryQuotaStore{quotas: map[quotaKey]*audit.ToolQuota{}}
+}
+
+func (s *memoryQuotaStore) GetToolQuota(ctx context.Context, day, username, tool string) (*audit.ToolQuota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q, ok := s.quotas[quotaKey{day: day, user: username, tool: tool}]
+	if !ok {
+		return nil, nil
+	}
+	copied := *q
+	return &copied, nil
+}
+
+func (s *memoryQuotaStore) IncrementToolQuota(ctx context.Context, day, username, tool string, limit int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := s.getLocked(day, username, tool)
+	if limit >= 0 && q.Used >= limit {
+		return false, nil
+	}
+	q.Used++
+	return true, nil
+}
+
+func (s *memoryQuotaStore) SetToolQuotaOverride(ctx context.Context, day, username, tool string, limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.getLocked(day, username, tool).Override = &limit
+	return nil
+}
+
+func (s *memoryQuotaStore) ListToolQuotas(ctx context.Context, day, username string) ([]audit.ToolQuota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var quotas []audit.ToolQuota
+	for key, q := range s.quotas {
+		if key.day == day && key.user == username {
+			quotas = append(quotas, *q)
+		}
+	}
+	return quotas, nil
+}
+
+func (s *memoryQuotaStore) PruneToolQuotas(ctx context.Context, before string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var pruned int64
+	for key := range s.quotas {
+		if key.day < before {
+			delete(s.quotas, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// getLocked 返回调用记录，不存在时创建，调用方需持有锁
+func (s *memoryQuotaStore) getLocked(day, username, tool string) *audit.ToolQuota {
+	key := quotaKey{day: day, user: username, tool: tool}
+	q, ok := s.quotas[key]
+	if !ok {
+		q = &audit.ToolQuota{Day: day, Username: username, Tool: tool}
+		s.quotas[key] = q
+	}
+	return q
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestQuotaManager(t *testing.T) {
+	utils.GetConfig().Set("tools.quotas", map[string]interface{}{"trivy": 2})
+
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	m := NewQuotaManager()
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := m.Consume(ctx, "alice", "trivy"); err != nil {
+			t.Fatalf("unexpected error on call %d: %v", i+1, err)
+		}
+	}
+
+	var quotaErr *QuotaExceededError
+	if err := m.Consume(ctx, "alice", "trivy"); !errors.As(err, &quotaErr) {
+		t.Fatalf("expected quota exceeded error, got %v", err)
+	}
+	if quotaErr.Used != 2 || quotaErr.Limit != 2 || !quotaErr.ResetAt.Equal(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected quota error: %+v", quotaErr)
+	}
+
+	// 其他用户、未配置配额的工具和匿名调用不受影响
+	if err := m.Consume(ctx, "bob", "trivy"); err != nil {
+		t.Errorf("unexpected error for another user: %v", err)
+	}
+	if err := m.Consume(ctx, "alice", "kubectl"); err != nil {
+		t.Errorf("unexpected error for unlimited tool: %v", err)
+	}
+	if err := m.Consume(ctx, "", "trivy"); err != nil {
+		t.Errorf("unexpected error for anonymous user: %v", err)
+	}
+
+	// 管理员覆盖当天配额
+	if err := m.Override(ctx, "alice", "trivy", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Consume(ctx, "alice", "trivy"); err != nil {
+		t.Errorf("unexpected error after override: %v", err)
+	}
+	if err := m.Consume(ctx, "alice", "trivy"); err == nil {
+		t.Error("expected quota exceeded after override limit reached")
+	}
+
+	if usage, err := m.Usage(ctx, "alice"); err != nil || len(usage) != 1 || usage[0].Used != 3 || usage[0].Limit != 3 || !usage[0].Overridden {
+		t.Errorf("unexpected usage after override: %+v, %v", usage, err)
+	}
+
+	// 第二天配额和覆盖均重置，第一次调用时清理前一天的记录
+	now = now.Add(24 * time.Hour)
+	usage, err := m.Usage(ctx, "alice")
+	if err != nil || len(usage) != 1 || usage[0].Used != 0 || usage[0].Limit != 2 || usage[0].Overridden {
+		t.Errorf("unexpected usage on next day: %+v, %v", usage, err)
+	}
+	if err := m.Consume(ctx, "alice", "trivy"); err != nil {
+		t.Errorf("unexpected error on next day: %v", err)
+	}
+	if len(m.memory.quotas) != 1 {
+		t.Errorf("expected the previous day's records to be pruned, got %d", len(m.memory.quotas))
+	}
+}
@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// maxQuotaRows 输出中列出的配额使用率条数
+const maxQuotaRows = 20
+
+// quotaCheckFlagRe 匹配输入中的 -n、--namespace、--threshold 参数
+var quotaCheckFlagRe = regexp.MustCompile(`^(-n|--namespace|--threshold)[=\s]+(\S+)\s*`)
+
+// QuotaCheck 对比命名空间的 ResourceQuota/LimitRange 与实际的 requests/limits，
+// 列出接近配额的命名空间、未设置 requests/limits 或超出 LimitRange 的工作负载
+// 输入：[--context=<集群>] [-n <命名空间>] [--threshold=0.8]，不指定命名空间时检查全部命名空间
+func QuotaCheck(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return QuotaCheckContext(ctx, input)
+}
+
+// QuotaCheckContext 检查配额合规情况，ctx 取消或超时时中止对 API Server 的请求
+func QuotaCheckContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_quotacheck")()
+
+	kubeContext := KubeContextFromContext(ctx)
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		kubeContext = m[1]
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	namespace, threshold, err := parseQuotaCheckInput(input)
+	if err != nil {
+		return err.Error(), err
+	}
+
+	report, err := CheckQuotaCompliance(ctx, kubeContext, namespace, threshold)
+	if err != nil {
+		logger.Error("检查配额合规失败",
+			zap.String("context", kubeContext),
+			zap.String("namespace", namespace),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+	return report.Format(maxQuotaRows), nil
+}
+
+// CheckQuotaCompliance 检查集群的配额合规情况，threshold 不大于 0 时使用配置项 compliance.quota_threshold（默认 0.8）
+func CheckQuotaCompliance(ctx context.Context, kubeContext, namespace string, threshold float64) (*kubernetes.ComplianceReport, error) {
+	if threshold <= 0 {
+		threshold = utils.GetConfig().GetFloat64("compliance.quota_threshold")
+	}
+	client, err := kubernetes.ClientsetForContext(kubeContext)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.CheckCompliance(ctx, client, namespace, threshold)
+}
+
+// parseQuotaCheckInput 解析命名空间和配额告警阈值，剩余的单个参数作为命名空间
+func parseQuotaCheckInput(input string) (namespace string, threshold float64, err error) {
+	input = strings.TrimSpace(input)
+	for {
+		m := quotaCheckFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		if m[1] == "--threshold" {
+			threshold, err = strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+			if err != nil || threshold <= 0 {
+				return "", 0, fmt.Errorf("--threshold 取值无效 %q，请使用 0.8 或 80%% 这样的比例", value)
+			}
+			if strings.HasSuffix(value, "%") || threshold > 1 {
+				threshold /= 100
+			}
+			continue
+		}
+		namespace = value
+	}
+	if rest := strings.Trim(strings.TrimSpace(input), `'"`); rest != "" && namespace == "" {
+		namespace = rest
+	}
+	return namespace, threshold, nil
+}
@@ -0,0 +1,24 @@
+package tools
+
+import "testing"
+
+func TestParseQuotaCheckInput(t *testing.T) {
+	for _, tt := range []struct {
+		in        string
+		namespace string
+		threshold float64
+	}{
+		{"", "", 0},
+		{"-n shop", "shop", 0},
+		{"--threshold=90% shop", "shop", 0.9},
+		{"--namespace=edge --threshold 0.75", "edge", 0.75},
+	} {
+		namespace, threshold, err := parseQuotaCheckInput(tt.in)
+		if err != nil || namespace != tt.namespace || threshold != tt.threshold {
+			t.Errorf("parseQuotaCheckInput(%q) = %q, %v, %v", tt.in, namespace, threshold, err)
+		}
+	}
+	if _, _, err := parseQuotaCheckInput("--threshold=abc"); err == nil {
+		t.Error("expected error for invalid --threshold")
+	}
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/receipts"
+)
+
+// ReceiptLog 单次交互内工具执行的签名回执，交互结束时随审计记录写入
+type ReceiptLog struct {
+	interactionID string
+
+	mu       sync.Mutex
+	seq      int // 已分配的最大序号
+	receipts []audit.ToolReceipt
+}
+
+// WithReceipts 为交互 interactionID 附加回执记录，未启用 receipts.enabled 或签名私钥不可用时不附加，返回 nil
+func WithReceipts(ctx context.Context, interactionID string) (context.Context, *ReceiptLog) {
+	if receipts.Default() == nil {
+		return ctx, nil
+	}
+	log := &ReceiptLog{interactionID: interactionID}
+	return context.WithValue(ctx, receiptLogKey, log), log
+}
+
+// ReceiptsFromContext 获取交互的回执记录，未附加时返回 nil
+func ReceiptsFromContext(ctx context.Context) *ReceiptLog {
+	log, _ := ctx.Value(receiptLogKey).(*ReceiptLog)
+	return log
+}
+
+// List 按序号返回回执，log 为 nil 时返回 nil
+func (l *ReceiptLog) List() []audit.ToolReceipt {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	list := append([]audit.ToolReceipt(nil), l.receipts...)
+	sort.Slice(list, func(i, j int) bool { return list[i].Seq < list[j].Seq })
+	return list
+}
+
+// nextSeq 在工具执行前分配回执序号，runner 执行时序号随请求发送，由 runner 一并签名
+func (l *ReceiptLog) nextSeq() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.seq++
+	return l.seq
+}
+
+func (l *ReceiptLog) add(r audit.ToolReceipt) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.receipts = append(l.receipts, r)
+}
+
+// receiptSlot 一次工具调用的回执序号，以及 runner 返回的回执，见 SetRemoteReceipt
+type receiptSlot struct {
+	interactionID string
+	seq           int
+	receipt       *audit.ToolReceipt
+}
+
+// ReceiptScope 返回本次工具调用的回执所属的交互和序号，转发到 runner 时随请求发送；未启用回执时返回空值
+func ReceiptScope(ctx context.Context) (string, int) {
+	if slot, ok := ctx.Value(receiptSlotKey).(*receiptSlot); ok {
+		return slot.interactionID, slot.seq
+	}
+	return "", 0
+}
+
+// SetRemoteReceipt 记录 runner 签发的回执，由 RemoteFunc 返回的函数在返回前调用；
+// Invoke 使用它代替中心服务签发的回执，回执中的执行方为实际执行命令的 runner
+func SetRemoteReceipt(ctx context.Context, r audit.ToolReceipt) {
+	if slot, ok := ctx.Value(receiptSlotKey).(*receiptSlot); ok {
+		slot.receipt = &r
+	}
+}
+
+// withReceiptSlot 为一次工具调用分配回执序号，并附加接收 runner 回执的位置
+func (l *ReceiptLog) withReceiptSlot(ctx context.Context) (context.Context, *receiptSlot) {
+	slot := &receiptSlot{interactionID: l.interactionID, seq: l.nextSeq()}
+	return context.WithValue(ctx, receiptSlotKey, slot), slot
+}
+
+// record 记录一次工具执行的回执：runner 已签发时使用 runner 的回执，否则由本服务签发
+// runner 的回执按分配的交互和序号保存，runner 签名时使用了其他值的回执校验不通过
+func (l *ReceiptLog) record(slot *receiptSlot, name, input, output string, executedAt time.Time) {
+	if slot.receipt != nil {
+		r := *slot.receipt
+		r.InteractionID, r.Seq = slot.interactionID, slot.seq
+		l.add(r)
+		return
+	}
+	if signer := receipts.Default(); signer != nil {
+		l.add(signer.Sign(slot.interactionID, slot.seq, name, input, output, executedAt))
+	}
+}
@@ -0,0 +1,167 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/chaos"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// ToolFunc 支持取消的工具实现，HTTP 请求中断或超过工具超时时 ctx 会被取消
+type ToolFunc func(ctx context.Context, input string) (string, error)
+
+// ToolSpec 工具的声明
+type ToolSpec struct {
+	Name        string                 // 工具名称，即 action.name
+	Description string                 // 在系统提示和工具定义中展示的说明
+	InputHint   string                 // input 参数的说明
+	Schema      map[string]interface{} // 参数的 JSON Schema，为空时使用只有 input 字符串参数的默认 Schema
+	Timeout     time.Duration          // 单次调用超时，可通过 tools.timeouts.<name> 覆盖，0 表示不限制
+	Idempotency Idempotency            // 幂等性分类，为空时按 side-effecting 处理
+	Run         ToolFunc
+}
+
+// InputSchema 返回工具参数的 JSON Schema，用于原生工具调用
+func (s ToolSpec) InputSchema() map[string]interface{} {
+	if s.Schema != nil {
+		return s.Schema
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"input": map[string]interface{}{"type": "string", "description": s.InputHint},
+		},
+		"required": []string{"input"},
+	}
+}
+
+// EffectiveTimeout 返回工具的超时时间，配置 tools.timeouts.<name> 优先于声明的超时
+func (s ToolSpec) EffectiveTimeout() time.Duration {
+	if config := utils.GetConfig(); config.IsSet("tools.timeouts." + s.Name) {
+		return config.GetDuration("tools.timeouts." + s.Name)
+	}
+	return s.Timeout
+}
+
+// ToolTimeoutError 工具调用超时
+type ToolTimeoutError struct {
+	Tool    string
+	Timeout time.Duration
+}
+
+func (e *ToolTimeoutError) Error() string {
+	return fmt.Sprintf("工具 %s 执行超过 %s 未完成，已终止。请缩小查询范围（例如指定命名空间、标签选择器或 --tail）后重试，或基于已获得的信息作答。", e.Tool, e.Timeout)
+}
+
+// run 在工具超时内执行一次调用，超时返回 ToolTimeoutError，请求被取消时返回取消原因
+func (s ToolSpec) run(ctx context.Context, input string) (string, error) {
+	// 注入的故障按目标不可达返回，经过与真实故障相同的自动重试和重试预算
+	if err := chaos.Inject(chaos.TargetTool); err != nil {
+		return "", fmt.Errorf("%w: connection refused", err)
+	}
+	callCtx := ctx
+	timeout := s.EffectiveTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	output, err := s.Run(callCtx, input)
+	if err == nil {
+		return output, nil
+	}
+	if ctx.Err() != nil {
+		return output, fmt.Errorf("tool %s cancelled: %w", s.Name, ctx.Err())
+	}
+	if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+		return output, &ToolTimeoutError{Tool: s.Name, Timeout: timeout}
+	}
+	return output, err
+}
+
+// ToolRegistry 工具注册表
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools map[string]ToolSpec
+}
+
+// NewToolRegistry 创建注册表并注册给定的工具，声明无效时 panic（仅用于初始化内置工具）
+func NewToolRegistry(specs ...ToolSpec) *ToolRegistry {
+	r := &ToolRegistry{tools: make(map[string]ToolSpec, len(specs))}
+	for _, spec := range specs {
+		if err := r.Register(spec); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register 注册工具，名称为空、没有实现或名称已存在时返回错误
+func (r *ToolRegistry) Register(spec ToolSpec) error {
+	if spec.Name == "" || spec.Run == nil {
+		return fmt.Errorf("invalid tool %q: name and run function are required", spec.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tools[spec.Name]; ok {
+		return fmt.Errorf("tool %s is already registered", spec.Name)
+	}
+	r.tools[spec.Name] = spec
+	return nil
+}
+
+// Unregister 移除工具
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+}
+
+// Get 获取工具声明
+func (r *ToolRegistry) Get(name string) (ToolSpec, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	spec, ok := r.tools[name]
+	return spec, ok
+}
+
+// List 返回所有工具，按名称排序
+func (r *ToolRegistry) List() []ToolSpec {
+	r.mu.RLock()
+	specs := make([]ToolSpec, 0, len(r.tools))
+	for _, spec := range r.tools {
+		specs = append(specs, spec)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// WithoutContext 将不支持取消的工具函数适配为 ToolFunc，超时后调用方不再等待，但函数本身会继续运行至结束
+func WithoutContext(tool Tool) ToolFunc {
+	return func(ctx context.Context, input string) (string, error) {
+		type result struct {
+			output string
+			err    error
+		}
+		done := make(chan result, 1)
+		go func() {
+			output, err := tool(input)
+			done <- result{output, err}
+		}()
+		select {
+		case r := <-done:
+			return r.output, r.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToolRegistry(t *testing.T) {
+	r := NewToolRegistry(ToolSpec{Name: "b", Run: WithoutContext(JQ)}, ToolSpec{Name: "a", Run: WithoutContext(JQ)})
+	if err := r.Register(ToolSpec{Name: "a", Run: WithoutContext(JQ)}); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+	if err := r.Register(ToolSpec{Name: "c"}); err == nil {
+		t.Error("expected registration without run function to fail")
+	}
+	if specs := r.List(); len(specs) != 2 || specs[0].Name != "a" || specs[1].Name != "b" {
+		t.Errorf("unexpected tools %+v", specs)
+	}
+}
+
+func TestToolSpecTimeoutAndCancel(t *testing.T) {
+	spec := ToolSpec{
+		Name:    "sleep",
+		Timeout: 100 * time.Millisecond,
+		Run: func(ctx context.Context, input string) (string, error) {
+			return executeShellCommand(ctx, "sleep 5 | cat")
+		},
+	}
+
+	start := time.Now()
+	_, err := spec.run(context.Background(), "")
+	var timeoutErr *ToolTimeoutError
+	if !errors.As(err, &timeoutErr) || timeoutErr.Tool != "sleep" {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the command to be killed on timeout, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	spec.Timeout = 0
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := spec.run(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+}
+
+func TestInvokeToolNotFound(t *testing.T) {
+	_, err := Invoke(context.Background(), "no-such-tool", "")
+	var notFound *ToolNotFoundError
+	if !errors.As(err, &notFound) || notFound.Tool != "no-such-tool" || !errors.Is(err, ErrToolNotFound) {
+		t.Fatalf("expected ToolNotFoundError, got %v", err)
+	}
+	if notFound.ErrorCode() != ErrCodeToolNotFound {
+		t.Errorf("error code = %q", notFound.ErrorCode())
+	}
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// RemoteFunc 返回在集群 cluster 内的 runner 上执行工具 name 的函数，没有对应的 runner 时返回 false，由本地执行
+type RemoteFunc func(cluster, name string) (ToolFunc, bool)
+
+// remoteFunc 访问集群的工具调用转发到 runner，见 SetRemoteFunc
+var remoteFunc RemoteFunc
+
+// SetRemoteFunc 使访问集群的工具调用（kubectl、rollout 等）优先转发到集群内的 runner 执行，
+// 中心服务不需要持有这些集群的 kubeconfig；需要在处理请求之前调用，nil 恢复为本地执行
+func SetRemoteFunc(fn RemoteFunc) {
+	remoteFunc = fn
+}
+
+// AvailabilityFunc 返回集群 cluster 上工具 name 是否可用，由 runner 声明的能力决定
+type AvailabilityFunc func(cluster, name string) bool
+
+// availabilityFunc 见 SetAvailabilityFunc
+var availabilityFunc AvailabilityFunc
+
+// SetAvailabilityFunc 设置访问集群的工具在各集群上是否可用，系统提示和工具定义中只列出可用的工具；
+// 需要在处理请求之前调用，nil 表示所有工具均可用
+func SetAvailabilityFunc(fn AvailabilityFunc) {
+	availabilityFunc = fn
+}
+
+// Available 工具在集群上是否可用：不访问集群的工具在中心服务执行，总是可用；
+// 访问集群的工具在集群由 runner 执行时只有 runner 声明支持才可用
+func Available(cluster, name string) bool {
+	fn := availabilityFunc
+	return fn == nil || !clusterTools[name] || fn(cluster, name)
+}
+
+// clusterTools 访问集群的工具，调用目标为集群 context
+var clusterTools = map[string]bool{
+	"kubectl": true, "nodepools": true, "rollout": true, "quotacheck": true, "restarts": true,
+}
+
+// remoteContextArgRe 匹配转发前需要去掉的 --context 参数（含前面的空白和引号）
+var remoteContextArgRe = regexp.MustCompile(`\s*--context[=\s]+['"]?[^'"\s]+['"]?`)
+
+// remoteSpec 目标集群配置了 runner 时返回转发到 runner 的工具声明和输入，超时等与本地执行相同
+// runner 在集群内使用自身的凭据，转发的输入中去掉 --context 参数
+func remoteSpec(ctx context.Context, spec ToolSpec, input string) (ToolSpec, string, bool) {
+	fn := remoteFunc
+	if fn == nil || !clusterTools[spec.Name] {
+		return spec, input, false
+	}
+	run, ok := fn(invocationTarget(ctx, spec.Name, input), spec.Name)
+	if !ok {
+		return spec, input, false
+	}
+	// 已审批的命令去掉 --context 后仍视为已审批，由 runner 放行
+	approved := CommandApproved(ctx, spec.Name, input)
+	spec.Run = func(ctx context.Context, input string) (string, error) {
+		if approved {
+			ctx = WithApprovedCommand(ctx, spec.Name, input)
+		}
+		return run(ctx, input)
+	}
+	return spec, strings.TrimSpace(remoteContextArgRe.ReplaceAllString(input, "")), true
+}
@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/receipts"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestInvokeRemote(t *testing.T) {
+	var cluster, forwarded string
+	var approved bool
+	SetRemoteFunc(func(target, name string) (ToolFunc, bool) {
+		if target != "prod-cn" {
+			return nil, false
+		}
+		return func(ctx context.Context, input string) (string, error) {
+			cluster, forwarded = target, input
+			approved = CommandApproved(ctx, name, input)
+			return "remote", nil
+		}, true
+	})
+	defer SetRemoteFunc(nil)
+
+	const command = "kubectl --context=prod-cn get pods -n shop"
+	ctx := WithApprovedCommand(context.Background(), "kubectl", command)
+	output, err := Invoke(ctx, "kubectl", command)
+	if err != nil || output != "remote" {
+		t.Fatalf("Invoke = %q, %v", output, err)
+	}
+	if cluster != "prod-cn" || forwarded != "kubectl get pods -n shop" {
+		t.Errorf("expected --context to be stripped before forwarding to prod-cn, got %q on %q", forwarded, cluster)
+	}
+	if !approved {
+		t.Error("expected the approval to carry over to the forwarded command")
+	}
+
+	if _, _, remote := remoteSpec(context.Background(), ToolSpec{Name: "kubectl"}, "kubectl --context=dev-eu get pods"); remote {
+		t.Error("expected clusters without a runner to run locally")
+	}
+	if _, _, remote := remoteSpec(context.Background(), ToolSpec{Name: "jq"}, "--context=prod-cn ."); remote {
+		t.Error("expected tools that do not access the cluster to run locally")
+	}
+}
+
+func TestInvokeRemoteReceipts(t *testing.T) {
+	_, key, _ := ed25519.GenerateKey(rand.Reader)
+	keyFile := filepath.Join(t.TempDir(), "receipts.key")
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config := utils.GetConfig()
+	config.Set("receipts.enabled", true)
+	config.Set("receipts.key_file", keyFile)
+	defer func() {
+		config.Set("receipts.enabled", nil)
+		config.Set("receipts.key_file", nil)
+	}()
+
+	_, runnerKey, _ := ed25519.GenerateKey(rand.Reader)
+	runner := receipts.NewSigner(runnerKey, "runner-prod-cn")
+	SetRemoteFunc(func(target, name string) (ToolFunc, bool) {
+		return func(ctx context.Context, input string) (string, error) {
+			// runner 按请求中的交互和序号签名，第二次调用模拟签发了其他序号的回执
+			interactionID, seq := ReceiptScope(ctx)
+			if input == "kubectl get svc" {
+				seq = 1
+			}
+			SetRemoteReceipt(ctx, runner.Sign(interactionID, seq, name, input, "remote", time.Now()))
+			return "remote", nil
+		}, true
+	})
+	defer SetRemoteFunc(nil)
+
+	ctx, log := WithReceipts(WithKubeContext(context.Background(), "prod-cn"), "i-1")
+	if log == nil {
+		t.Fatal("expected receipts to be enabled")
+	}
+	for _, command := range []string{"kubectl get pods", "kubectl get svc"} {
+		if _, err := Invoke(ctx, "kubectl", command); err != nil {
+			t.Fatalf("Invoke(%q) error = %v", command, err)
+		}
+	}
+
+	keys := receipts.TrustedKeys()
+	keys[receipts.KeyID(runner.PublicKey())] = runner.PublicKey()
+	list := log.List()
+	if len(list) != 2 || list[0].Seq != 1 || list[1].Seq != 2 || list[0].InteractionID != "i-1" {
+		t.Fatalf("receipts = %+v", list)
+	}
+	if err := receipts.Verify(list[0], keys); err != nil {
+		t.Errorf("Verify(runner receipt) error = %v", err)
+	}
+	if err := receipts.Verify(list[1], keys); err == nil {
+		t.Error("expected a runner receipt signed for another seq to fail verification")
+	}
+}
@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	// 内嵌时区数据库，精简镜像中没有 /usr/share/zoneinfo 时 --tz 也能使用
+	_ "time/tzdata"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxRestartRows 输出中列出的工作负载数
+	maxRestartRows = 30
+	// defaultRestartWindow 未指定 --window 且未配置 restarts.window 时的统计窗口
+	defaultRestartWindow = 24 * time.Hour
+)
+
+// restartsFlagRe 匹配输入中的 -n、--namespace、--window、--tz 参数
+var restartsFlagRe = regexp.MustCompile(`^(-n|--namespace|--window|--tz)[=\s]+(\S+)\s*`)
+
+// RestartsOptions 重启统计的参数
+type RestartsOptions struct {
+	Clusters  []string // kubeconfig context，为空时使用当前 context
+	Namespace string
+	Window    time.Duration
+	Location  *time.Location
+}
+
+// Restarts 按集群和工作负载汇总窗口内的容器重启次数和 OOMKill，时间统一换算到指定时区
+// 输入：[--context=<集群>[,<集群>...]] [-n <命名空间>] [--window=24h] [--tz=Asia/Shanghai]
+func Restarts(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return RestartsContext(ctx, input)
+}
+
+// RestartsContext 汇总重启情况，ctx 取消或超时时中止对 API Server 的请求
+func RestartsContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_restarts")()
+
+	opts, err := parseRestartsInput(input)
+	if err != nil {
+		return err.Error(), err
+	}
+	if len(opts.Clusters) == 0 {
+		opts.Clusters = []string{KubeContextFromContext(ctx)}
+	}
+
+	report, err := CollectRestarts(ctx, opts)
+	if err != nil {
+		logger.Error("汇总容器重启失败",
+			zap.Strings("clusters", opts.Clusters),
+			zap.String("namespace", opts.Namespace),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+	return report.Format(maxRestartRows), nil
+}
+
+// CollectRestarts 汇总一个或多个集群的重启情况，Window、Location 为空时使用
+// 配置项 restarts.window（默认 24h）和 restarts.timezone（默认 UTC）
+func CollectRestarts(ctx context.Context, opts RestartsOptions) (*kubernetes.RestartReport, error) {
+	config := utils.GetConfig()
+	if opts.Window <= 0 {
+		opts.Window = config.GetDuration("restarts.window")
+	}
+	if opts.Window <= 0 {
+		opts.Window = defaultRestartWindow
+	}
+	if opts.Location == nil {
+		loc, err := time.LoadLocation(config.GetString("restarts.timezone"))
+		if err != nil {
+			return nil, fmt.Errorf("restarts.timezone 无效：%v", err)
+		}
+		opts.Location = loc
+	}
+	if len(opts.Clusters) == 0 {
+		opts.Clusters = []string{""}
+	}
+
+	until := time.Now()
+	since := until.Add(-opts.Window)
+	var workloads []kubernetes.WorkloadRestarts
+	for _, cluster := range opts.Clusters {
+		client, err := kubernetes.ClientsetForContext(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("集群 %s: %w", cluster, err)
+		}
+		found, err := kubernetes.CollectRestarts(ctx, client, cluster, opts.Namespace, since)
+		if err != nil {
+			return nil, fmt.Errorf("集群 %s: %w", cluster, err)
+		}
+		workloads = append(workloads, found...)
+	}
+	return kubernetes.NewRestartReport(workloads, since, until, opts.Location), nil
+}
+
+// parseRestartsInput 解析集群、命名空间、统计窗口和时区，剩余的单个参数作为命名空间
+func parseRestartsInput(input string) (RestartsOptions, error) {
+	var opts RestartsOptions
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		for _, cluster := range strings.Split(m[1], ",") {
+			if cluster = strings.TrimSpace(cluster); cluster != "" {
+				opts.Clusters = append(opts.Clusters, cluster)
+			}
+		}
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	input = strings.TrimSpace(input)
+	for {
+		m := restartsFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		switch m[1] {
+		case "--window":
+			d, err := parseWindow(value)
+			if err != nil || d <= 0 {
+				return opts, fmt.Errorf("--window 取值无效 %q，请使用 6h、24h、7d 等时长", value)
+			}
+			opts.Window = d
+		case "--tz":
+			loc, err := time.LoadLocation(value)
+			if err != nil {
+				return opts, fmt.Errorf("--tz 取值无效 %q，请使用 Asia/Shanghai、Australia/Sydney、Europe/Berlin 这样的 IANA 时区名", value)
+			}
+			opts.Location = loc
+		default:
+			opts.Namespace = value
+		}
+	}
+	if rest := strings.Trim(strings.TrimSpace(input), `'"`); rest != "" && opts.Namespace == "" {
+		opts.Namespace = rest
+	}
+	return opts, nil
+}
+
+// parseWindow 解析时长，额外支持以天为单位的 7d
+func parseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		d, err := time.ParseDuration(days + "h")
+		return d * 24, err
+	}
+	return time.ParseDuration(value)
+}
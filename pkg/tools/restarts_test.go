@@ -0,0 +1,26 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRestartsInput(t *testing.T) {
+	opts, err := parseRestartsInput("--context=prod-east,prod-eu -n shop --window=7d --tz=Australia/Sydney")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Clusters) != 2 || opts.Clusters[1] != "prod-eu" || opts.Namespace != "shop" ||
+		opts.Window != 7*24*time.Hour || opts.Location.String() != "Australia/Sydney" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	if opts, err := parseRestartsInput("edge"); err != nil || opts.Namespace != "edge" || opts.Window != 0 || opts.Location != nil {
+		t.Errorf("parseRestartsInput(edge) = %+v, %v", opts, err)
+	}
+	for _, in := range []string{"--window=abc", "--tz=Mars/Base"} {
+		if _, err := parseRestartsInput(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// CachedResultNote 缓存命中时加在工具输出前的说明，会出现在观察结果和工具调用历史中
+const CachedResultNote = "[缓存] 本次交互中已执行过相同的调用，以下为当时的结果，无需重复查询。\n"
+
+// ResultCache 单次交互内只读工具调用的结果缓存
+// LLM 在后续迭代中再次请求相同数据（如重复的 kubectl get）时直接返回之前的结果，不再访问 API Server
+type ResultCache struct {
+	mu      sync.Mutex
+	entries map[string]string
+	hits    int
+}
+
+// NewResultCache 创建结果缓存
+func NewResultCache() *ResultCache {
+	return &ResultCache{entries: map[string]string{}}
+}
+
+// WithResultCache 为交互附加新的结果缓存，配置项 tools.result_cache.enabled 为 false 时不附加，返回 nil
+func WithResultCache(ctx context.Context) (context.Context, *ResultCache) {
+	config := utils.GetConfig()
+	if config.IsSet("tools.result_cache.enabled") && !config.GetBool("tools.result_cache.enabled") {
+		return ctx, nil
+	}
+	cache := NewResultCache()
+	return context.WithValue(ctx, resultCacheKey, cache), cache
+}
+
+// ResultCacheFromContext 获取交互的结果缓存，未附加时返回 nil
+func ResultCacheFromContext(ctx context.Context) *ResultCache {
+	cache, _ := ctx.Value(resultCacheKey).(*ResultCache)
+	return cache
+}
+
+// Get 查找缓存的输出
+func (c *ResultCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	output, ok := c.entries[key]
+	if ok {
+		c.hits++
+	}
+	return output, ok
+}
+
+// Put 缓存一次成功调用的输出
+func (c *ResultCache) Put(key, output string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = output
+}
+
+// Hits 缓存命中次数
+func (c *ResultCache) Hits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// IsCachedResult 判断观察结果是否来自缓存
+func IsCachedResult(observation string) bool {
+	return strings.HasPrefix(observation, CachedResultNote)
+}
+
+// cacheKeyFor 缓存键：工具名、集群 context 和去掉多余空白的输入
+func cacheKeyFor(ctx context.Context, name, input string) string {
+	return name + "\x00" + KubeContextFromContext(ctx) + "\x00" + strings.Join(strings.Fields(input), " ")
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInvokeResultCache(t *testing.T) {
+	calls := 0
+	spec := ToolSpec{
+		Name:        "cachetest",
+		Idempotency: IdempotencyCacheable,
+		Run: func(ctx context.Context, input string) (string, error) {
+			calls++
+			return "pods: 3", nil
+		},
+	}
+	if err := Registry.Register(spec); err != nil {
+		t.Fatal(err)
+	}
+	defer Registry.Unregister(spec.Name)
+
+	ctx, cache := WithResultCache(context.Background())
+	first, err := Invoke(ctx, "cachetest", "get pods -n shop")
+	if err != nil || IsCachedResult(first) {
+		t.Fatalf("first Invoke() = %q, %v", first, err)
+	}
+	second, err := Invoke(ctx, "cachetest", "get  pods -n shop ")
+	if err != nil || !IsCachedResult(second) || second != CachedResultNote+first {
+		t.Errorf("second Invoke() = %q, %v, want cached result", second, err)
+	}
+	if calls != 1 || cache.Hits() != 1 {
+		t.Errorf("calls = %d, hits = %d, want 1 and 1", calls, cache.Hits())
+	}
+
+	// 不同集群、不同交互不共享缓存
+	if out, _ := Invoke(WithKubeContext(ctx, "prod-east"), "cachetest", "get pods -n shop"); IsCachedResult(out) {
+		t.Error("expected a different kube context to miss the cache")
+	}
+	if out, _ := Invoke(context.Background(), "cachetest", "get pods -n shop"); IsCachedResult(out) {
+		t.Error("expected calls without a cache to run the tool")
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
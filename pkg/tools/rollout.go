@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const defaultRolloutRevisions = 5
+
+// rolloutFlagRe 匹配输入中的 -n、--namespace、--limit 参数
+var rolloutFlagRe = regexp.MustCompile(`^(-n|--namespace|--limit)[=\s]+(\S+)\s*`)
+
+// Rollout 查询 Deployment 的发布状态和修订历史，列出每个修订相对上一修订的镜像、环境变量名、资源和配置引用变化
+// 输入：[--context=<集群>] [-n <命名空间>] [--limit=5] <deployment 名称或 Pod 名称>，也可以写成 <命名空间>/<名称> 或 deployment/<名称>
+func Rollout(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return RolloutContext(ctx, input)
+}
+
+// RolloutContext 查询 Deployment 的发布历史，ctx 取消或超时时中止对 API Server 的请求
+func RolloutContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_rollout")()
+
+	kubeContext := KubeContextFromContext(ctx)
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		kubeContext = m[1]
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	namespace, name, limit, err := parseRolloutInput(input)
+	if err != nil {
+		return err.Error(), err
+	}
+
+	client, err := kubernetes.ClientsetForContext(kubeContext)
+	if err != nil {
+		return err.Error(), err
+	}
+	deployment, err := kubernetes.DeploymentForWorkload(ctx, client, namespace, name)
+	if err != nil {
+		logger.Error("查询发布历史失败",
+			zap.String("context", kubeContext),
+			zap.String("namespace", namespace),
+			zap.String("name", name),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+	if deployment == "" {
+		return fmt.Sprintf("命名空间 %s 中没有名为 %s 的 Deployment 或属于 Deployment 的 Pod，请确认名称和命名空间，"+
+			"StatefulSet、DaemonSet 的发布历史请使用 kubectl rollout history", namespace, name), nil
+	}
+
+	history, err := kubernetes.RevisionHistory(ctx, client, namespace, deployment)
+	if err != nil {
+		return err.Error(), err
+	}
+	return history.Format(limit), nil
+}
+
+// parseRolloutInput 解析命名空间、名称和修订数量，未指定命名空间时使用 default
+func parseRolloutInput(input string) (namespace, name string, limit int, err error) {
+	namespace, limit = "default", defaultRolloutRevisions
+	input = strings.TrimSpace(input)
+	var rest []string
+	for input != "" {
+		if m := rolloutFlagRe.FindStringSubmatch(input); m != nil {
+			input = input[len(m[0]):]
+			value := strings.Trim(m[2], `'"`)
+			if m[1] == "--limit" {
+				if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+					return "", "", 0, fmt.Errorf("--limit 取值无效 %q", value)
+				}
+			} else {
+				namespace = value
+			}
+			continue
+		}
+		fields := strings.SplitN(input, " ", 2)
+		rest = append(rest, strings.Trim(fields[0], `'"`))
+		input = ""
+		if len(fields) == 2 {
+			input = strings.TrimSpace(fields[1])
+		}
+	}
+
+	for _, arg := range rest {
+		switch {
+		case arg == "" || arg == "deployment" || arg == "deploy" || arg == "deployments":
+		case name != "":
+			return "", "", 0, fmt.Errorf("只能查询一个 Deployment，多余的参数 %q", arg)
+		default:
+			name = arg
+		}
+	}
+	if prefix, after, ok := strings.Cut(name, "/"); ok {
+		switch strings.ToLower(prefix) {
+		case "deployment", "deploy", "deployments", "deployment.apps", "deployments.apps":
+		default:
+			namespace = prefix
+		}
+		name = after
+	}
+	if name == "" {
+		return "", "", 0, fmt.Errorf("请提供 Deployment 名称，例如 -n shop payment-api")
+	}
+	return namespace, name, limit, nil
+}
@@ -0,0 +1,26 @@
+package tools
+
+import "testing"
+
+func TestParseRolloutInput(t *testing.T) {
+	for _, tt := range []struct {
+		in, namespace, name string
+		limit               int
+	}{
+		{"-n shop payment-api", "shop", "payment-api", defaultRolloutRevisions},
+		{"shop/payment-api --limit=3", "shop", "payment-api", 3},
+		{"--namespace=edge deployment/gateway", "edge", "gateway", defaultRolloutRevisions},
+		{"deployment gateway", "default", "gateway", defaultRolloutRevisions},
+	} {
+		namespace, name, limit, err := parseRolloutInput(tt.in)
+		if err != nil || namespace != tt.namespace || name != tt.name || limit != tt.limit {
+			t.Errorf("parseRolloutInput(%q) = %q, %q, %d, %v", tt.in, namespace, name, limit, err)
+		}
+	}
+
+	for _, in := range []string{"", "-n shop", "--limit=0 web", "web api"} {
+		if _, _, _, err := parseRolloutInput(in); err == nil {
+			t.Errorf("parseRolloutInput(%q) expected error", in)
+		}
+	}
+}
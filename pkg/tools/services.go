@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/myysophia/OpsAgent/pkg/knowledge"
+)
+
+// maxServiceMatches 查询服务知识库时最多输出的服务数
+const maxServiceMatches = 10
+
+// Services 在服务别名知识库中查找中文名、简称对应的 K8s 资源名称
+// 输入：服务的中文名、简称或名称的一部分，为空时列出全部服务
+// 输出：每行一个服务，包含资源名称、类型、命名空间、集群、别名和说明
+func Services(input string) (string, error) {
+	return ServicesContext(context.Background(), input)
+}
+
+// ServicesContext 查找服务，知识库在内存中，ctx 仅用于统一工具签名
+func ServicesContext(_ context.Context, input string) (string, error) {
+	term := strings.Trim(strings.TrimSpace(input), `'"`)
+	matches := knowledge.Lookup(knowledge.Services(), term)
+	if len(matches) == 0 {
+		return fmt.Sprintf("服务知识库中没有与 %q 匹配的服务，请使用 kubectl get deploy -A 按名称模糊查找", term), nil
+	}
+
+	var b strings.Builder
+	if exact := exactServiceMatches(matches, term); exact > 1 {
+		fmt.Fprintf(&b, "%q 对应 %d 个服务，不要把它们混在一个回答中；无法从问题中确定是哪一个时，先列出候选服务请用户确认\n", term, exact)
+	}
+	for i, s := range matches {
+		if i == maxServiceMatches {
+			fmt.Fprintf(&b, "... 还有 %d 个匹配的服务，请使用更具体的名称\n", len(matches)-maxServiceMatches)
+			break
+		}
+		kind := s.Kind
+		if kind == "" {
+			kind = "deployment"
+		}
+		fmt.Fprintf(&b, "- %s（%s", s.Name, kind)
+		if s.Namespace != "" {
+			fmt.Fprintf(&b, "，命名空间 %s", s.Namespace)
+		}
+		if s.Cluster != "" {
+			fmt.Fprintf(&b, "，集群 %s", s.Cluster)
+		}
+		b.WriteString("）")
+		if len(s.Aliases) > 0 {
+			fmt.Fprintf(&b, " 别名：%s", strings.Join(s.Aliases, ", "))
+		}
+		if s.Description != "" {
+			fmt.Fprintf(&b, " 说明：%s", s.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// exactServiceMatches 名称或别名与 term 完全相同的服务数量
+func exactServiceMatches(services []knowledge.Service, term string) int {
+	n := 0
+	for _, s := range services {
+		for _, name := range append([]string{s.Name}, s.Aliases...) {
+			if strings.EqualFold(name, term) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/snapshots"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// maxSnapshotRows 输出中列出的对象数
+const maxSnapshotRows = 100
+
+// snapshotFlagRe 匹配输入中的 --at、--kind、-n、--namespace 参数
+var snapshotFlagRe = regexp.MustCompile(`^(--at|--kind|-n|--namespace)[=\s]+(\S+)\s*`)
+
+// snapshotQuery 快照查询条件
+type snapshotQuery struct {
+	At        time.Time
+	Kind      string
+	Namespace string
+	Name      string // 名称包含的文本
+}
+
+// Snapshot 查询集群历史快照中当时存在的资源，回答"上周二有哪些 Deployment"这类问题
+// 输入：[--context=<集群>] --at=<RFC3339 时间、2006-01-02 或 72h/7d 表示多久之前> [--kind=deployments] [-n <命名空间>] [名称]
+func Snapshot(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return SnapshotContext(ctx, input)
+}
+
+// SnapshotContext 查询集群快照，ctx 取消时中止查询
+func SnapshotContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_snapshot")()
+
+	if !snapshots.Enabled() || audit.GetStore() == nil {
+		err := fmt.Errorf("snapshot is not available: 未启用集群快照（snapshots.enabled 和 audit.enabled）")
+		return err.Error(), err
+	}
+	kubeContext := KubeContextFromContext(ctx)
+	if m := kubeContextArgRe.FindStringSubmatch(input); m != nil {
+		kubeContext = m[1]
+		input = kubeContextArgRe.ReplaceAllString(input, "")
+	}
+	query, err := parseSnapshotQuery(input, time.Now())
+	if err != nil {
+		return err.Error(), err
+	}
+
+	snapshot, err := snapshots.At(ctx, kubeContext, query.At)
+	if errors.Is(err, audit.ErrSnapshotNotFound) {
+		return fmt.Sprintf("%s 之前没有该集群的快照，快照从启用 snapshots.enabled 之后开始保存，保留 snapshots.retention",
+			query.At.Format(time.RFC3339)), nil
+	}
+	if err != nil {
+		return err.Error(), err
+	}
+	return formatSnapshotObjects(snapshot, snapshot.Filter(query.Kind, query.Namespace, query.Name)), nil
+}
+
+// parseSnapshotQuery 解析工具输入中的参数，剩余部分作为名称过滤条件，未指定 --at 时查询最近的快照
+func parseSnapshotQuery(input string, now time.Time) (snapshotQuery, error) {
+	query := snapshotQuery{At: now}
+	input = strings.TrimSpace(input)
+	for {
+		m := snapshotFlagRe.FindStringSubmatch(input)
+		if m == nil {
+			break
+		}
+		input = input[len(m[0]):]
+		value := strings.Trim(m[2], `'"`)
+		switch m[1] {
+		case "--at":
+			at, err := snapshots.ParseTime(value, now)
+			if err != nil {
+				return query, fmt.Errorf("--at 取值无效 %q，请使用 RFC3339 时间、2006-01-02 日期或 72h、7d 这样的时长", value)
+			}
+			query.At = at
+		case "--kind":
+			query.Kind = normalizeKubeAuditResource(value)
+		default:
+			query.Namespace = value
+		}
+	}
+	query.Name = strings.Trim(strings.TrimSpace(input), `'"`)
+	return query, nil
+}
+
+func formatSnapshotObjects(snapshot *kubernetes.Snapshot, objects []kubernetes.SnapshotObject) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "snapshot of %s taken at %s: %d matching objects of %d\n",
+		snapshot.Cluster, snapshot.TakenAt.UTC().Format(time.RFC3339), len(objects), len(snapshot.Objects))
+	for i, o := range objects {
+		if i == maxSnapshotRows {
+			fmt.Fprintf(&b, "... %d more objects, use --kind, -n or a name to narrow down\n", len(objects)-maxSnapshotRows)
+			break
+		}
+		name := o.Name
+		if o.Namespace != "" {
+			name = o.Namespace + "/" + o.Name
+		}
+		fmt.Fprintf(&b, "%s %s created %s", o.Kind, name, o.CreatedAt.UTC().Format(time.RFC3339))
+		if o.Owner != "" {
+			fmt.Fprintf(&b, " owner %s", o.Owner)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSnapshotQuery(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	query, err := parseSnapshotQuery("--at=2026-10-13 --kind=deploy -n shop payment", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 10, 13, 23, 59, 59, 0, time.UTC); !query.At.Equal(want) || query.Kind != "deployments" ||
+		query.Namespace != "shop" || query.Name != "payment" {
+		t.Errorf("unexpected query: %+v", query)
+	}
+
+	if query, err := parseSnapshotQuery("--at=3d", now); err != nil || !query.At.Equal(now.Add(-72*time.Hour)) {
+		t.Errorf("parseSnapshotQuery(--at=3d) = %+v, %v", query, err)
+	}
+	if _, err := parseSnapshotQuery("--at=last-tuesday", now); err == nil {
+		t.Error("expected error for invalid --at")
+	}
+}
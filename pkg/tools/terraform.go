@@ -0,0 +1,380 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTerraformCacheTTL = 5 * time.Minute
+	// terraformMaxResources 按过滤词查询时最多输出的资源数
+	terraformMaxResources = 20
+	// terraformMaxAttributes 单个资源属性 JSON 的最大长度
+	terraformMaxAttributes = 2000
+)
+
+// terraformStateFlagRe 匹配输入开头的 --state 参数
+var terraformStateFlagRe = regexp.MustCompile(`^--state[=\s]+(\S+)\s*`)
+
+// terraformSensitiveRe 属性名包含这些词时不输出取值
+var terraformSensitiveRe = regexp.MustCompile(`(?i)password|secret|token|private_key|access_key|kube_?config|client_key|certificate`)
+
+// terraformFact 集群相关基础设施资源需要输出的属性
+type terraformFact struct {
+	Category   string
+	Type       string
+	Attributes []string // 属性路径，嵌套对象用 . 分隔
+}
+
+// terraformFacts 不带过滤词查询时汇总的资源：集群、节点组和负载均衡
+var terraformFacts = []terraformFact{
+	{"集群", "aws_eks_cluster", []string{"version", "vpc_config.endpoint_public_access"}},
+	{"集群", "alicloud_cs_managed_kubernetes", []string{"version", "cluster_spec"}},
+	{"集群", "google_container_cluster", []string{"min_master_version", "location"}},
+	{"集群", "azurerm_kubernetes_cluster", []string{"kubernetes_version", "sku_tier"}},
+	{"节点组", "aws_eks_node_group", []string{"scaling_config", "instance_types", "capacity_type", "disk_size"}},
+	{"节点组", "alicloud_cs_kubernetes_node_pool", []string{"desired_size", "scaling_config", "instance_types", "system_disk_size"}},
+	{"节点组", "google_container_node_pool", []string{"node_count", "autoscaling", "node_config.machine_type"}},
+	{"节点组", "azurerm_kubernetes_cluster_node_pool", []string{"node_count", "min_count", "max_count", "vm_size"}},
+	{"负载均衡", "aws_lb", []string{"load_balancer_type", "internal", "dns_name", "idle_timeout"}},
+	{"负载均衡", "aws_lb_listener", []string{"port", "protocol", "ssl_policy"}},
+	{"负载均衡", "aws_lb_target_group", []string{"port", "protocol", "target_type", "health_check"}},
+	{"负载均衡", "alicloud_slb_load_balancer", []string{"load_balancer_spec", "address_type", "address", "internet_charge_type"}},
+	{"负载均衡", "alicloud_alb_load_balancer", []string{"load_balancer_edition", "address_type", "dns_name"}},
+	{"负载均衡", "google_compute_forwarding_rule", []string{"load_balancing_scheme", "ip_address", "port_range"}},
+}
+
+// terraformState Terraform state（格式版本 4）中用到的字段
+type terraformState struct {
+	Version          int    `json:"version"`
+	TerraformVersion string `json:"terraform_version"`
+	Serial           int    `json:"serial"`
+	Resources        []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Instances []struct {
+			IndexKey   interface{}            `json:"index_key"`
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// terraformResource 展开后的单个资源实例
+type terraformResource struct {
+	Address    string
+	Type       string
+	Attributes map[string]interface{}
+}
+
+type cachedTerraformState struct {
+	state     *terraformState
+	fetchedAt time.Time
+}
+
+var (
+	terraformClient     *http.Client
+	terraformClientOnce sync.Once
+	terraformCache      sync.Map // state 对象键 -> cachedTerraformState
+)
+
+// Terraform 只读查询存放在 S3 兼容存储（AWS S3、MinIO、阿里云 OSS）中的 Terraform state，
+// 回答节点组规模、负载均衡配置等 kubectl 无法查询的基础设施问题
+// 输入：可选的 --state=<名称>（tools.terraform.states 中配置的名称或对象键），以及可选的过滤词（资源类型、名称或地址的一部分）
+// 输出：不带过滤词时汇总集群、节点组、负载均衡和资源数量；带过滤词时输出匹配资源的属性（敏感属性已隐藏）
+func Terraform(input string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return TerraformContext(ctx, input)
+}
+
+// TerraformContext 查询 Terraform state，未指定 --state 时使用 tools.terraform.states 中与请求集群对应的 state
+func TerraformContext(ctx context.Context, input string) (string, error) {
+	perfStats := utils.GetPerfStats()
+	defer perfStats.TraceFunc("tool_terraform")()
+
+	input = strings.TrimSpace(input)
+	stateName := ""
+	if m := terraformStateFlagRe.FindStringSubmatch(input); m != nil {
+		stateName = strings.Trim(m[1], `'"`)
+		input = input[len(m[0]):]
+	}
+	filter := strings.ToLower(strings.Trim(strings.TrimSpace(input), `'"`))
+
+	key, err := terraformStateKey(stateName, KubeContextFromContext(ctx))
+	if err != nil {
+		logger.Warn("拒绝查询未配置的 Terraform state", zap.String("state", stateName))
+		return err.Error(), err
+	}
+	if key == "" {
+		err := fmt.Errorf("未配置 Terraform state（tools.terraform.states 或 tools.terraform.key），无法查询基础设施信息")
+		return err.Error(), err
+	}
+	state, err := loadTerraformState(ctx, key)
+	if err != nil {
+		logger.Error("读取 Terraform state 失败",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return err.Error(), err
+	}
+
+	resources := terraformResources(state)
+	if filter == "" {
+		return summarizeTerraformState(key, state, resources), nil
+	}
+	return formatTerraformResources(resources, filter), nil
+}
+
+// terraformStateKey 返回 state 的对象键：--state 只能是 tools.terraform.states 中的名称或已配置的对象键，
+// 避免助手读取存储桶中的任意对象；未指定时按请求的集群 context 查找，最后使用 tools.terraform.key
+func terraformStateKey(name, kubeContext string) (string, error) {
+	config := utils.GetConfig()
+	states := config.GetStringMapString("tools.terraform.states")
+	defaultKey := config.GetString("tools.terraform.key")
+	if name != "" {
+		if key, ok := states[strings.ToLower(name)]; ok {
+			return key, nil
+		}
+		if name == defaultKey {
+			return name, nil
+		}
+		for _, key := range states {
+			if name == key {
+				return name, nil
+			}
+		}
+		return "", fmt.Errorf("Terraform state %q 未在 tools.terraform.states 中配置", name)
+	}
+	if key, ok := states[strings.ToLower(kubeContext)]; ok && kubeContext != "" {
+		return key, nil
+	}
+	return defaultKey, nil
+}
+
+// loadTerraformState 下载并解析 state，结果缓存 tools.terraform.cache_ttl（默认 5m）
+func loadTerraformState(ctx context.Context, key string) (*terraformState, error) {
+	config := utils.GetConfig()
+	ttl := defaultTerraformCacheTTL
+	if config.IsSet("tools.terraform.cache_ttl") {
+		ttl = config.GetDuration("tools.terraform.cache_ttl")
+	}
+	if cached, ok := terraformCache.Load(key); ok {
+		if entry := cached.(cachedTerraformState); time.Since(entry.fetchedAt) < ttl {
+			return entry.state, nil
+		}
+	}
+
+	endpoint := strings.TrimSuffix(config.GetString("tools.terraform.endpoint"), "/")
+	bucket := config.GetString("tools.terraform.bucket")
+	if endpoint == "" || bucket == "" {
+		return nil, fmt.Errorf("未配置 Terraform state 存储（tools.terraform.endpoint、tools.terraform.bucket）")
+	}
+	terraformClientOnce.Do(func() {
+		client, err := utils.NewHTTPClient("terraform")
+		if err != nil {
+			logger.Warn("创建 Terraform state HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = http.DefaultClient
+		}
+		terraformClient = client
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/"+path.Join(bucket, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	accessKeyID, secretAccessKey := config.GetString("tools.terraform.access_key_id"), config.GetString("tools.terraform.secret_access_key")
+	if accessKeyID == "" {
+		accessKeyID, secretAccessKey = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	region := config.GetString("tools.terraform.region")
+	if region == "" {
+		region = "us-east-1"
+	}
+	utils.SignS3Request(req, accessKeyID, secretAccessKey, region, time.Now())
+
+	resp, err := terraformClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("读取 Terraform state %s 返回 HTTP %d: %s", key, resp.StatusCode, firstLine(string(body)))
+	}
+
+	var state terraformState
+	if err := json.Unmarshal(body, &state); err != nil {
+		return nil, fmt.Errorf("解析 Terraform state %s 失败: %v", key, err)
+	}
+	if state.Version != 4 {
+		return nil, fmt.Errorf("不支持的 Terraform state 格式版本 %d", state.Version)
+	}
+	terraformCache.Store(key, cachedTerraformState{state: &state, fetchedAt: time.Now()})
+	return &state, nil
+}
+
+// terraformResources 展开 state 中的托管资源实例，数据源（data）不输出
+func terraformResources(state *terraformState) []terraformResource {
+	var resources []terraformResource
+	for _, r := range state.Resources {
+		if r.Mode != "managed" {
+			continue
+		}
+		address := r.Type + "." + r.Name
+		if r.Module != "" {
+			address = r.Module + "." + address
+		}
+		for _, instance := range r.Instances {
+			instanceAddress := address
+			switch key := instance.IndexKey.(type) {
+			case string:
+				instanceAddress += fmt.Sprintf("[%q]", key)
+			case float64:
+				instanceAddress += fmt.Sprintf("[%d]", int(key))
+			}
+			resources = append(resources, terraformResource{Address: instanceAddress, Type: r.Type, Attributes: instance.Attributes})
+		}
+	}
+	return resources
+}
+
+// summarizeTerraformState 汇总集群、节点组、负载均衡的关键属性和各类型资源数量
+func summarizeTerraformState(key string, state *terraformState, resources []terraformResource) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Terraform state %s（terraform %s，serial %d，%d 个资源）\n", key, state.TerraformVersion, state.Serial, len(resources))
+
+	counts := map[string]int{}
+	for _, r := range resources {
+		counts[r.Type]++
+	}
+	for _, category := range []string{"集群", "节点组", "负载均衡"} {
+		var lines []string
+		for _, fact := range terraformFacts {
+			if fact.Category != category {
+				continue
+			}
+			for _, r := range resources {
+				if r.Type != fact.Type {
+					continue
+				}
+				var values []string
+				for _, attr := range fact.Attributes {
+					if value, ok := terraformAttribute(r.Attributes, attr); ok {
+						values = append(values, attr+"="+value)
+					}
+				}
+				lines = append(lines, "- "+r.Address+" "+strings.Join(values, " "))
+			}
+		}
+		if len(lines) > 0 {
+			fmt.Fprintf(&b, "\n%s:\n%s\n", category, strings.Join(lines, "\n"))
+		}
+	}
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	b.WriteString("\n资源数量:\n")
+	for _, t := range types {
+		fmt.Fprintf(&b, "- %s: %d\n", t, counts[t])
+	}
+	b.WriteString("\n可带过滤词（资源类型或名称）查看资源的完整属性")
+	return b.String()
+}
+
+// formatTerraformResources 输出类型或地址包含过滤词的资源属性
+func formatTerraformResources(resources []terraformResource, filter string) string {
+	var b strings.Builder
+	matched := 0
+	for _, r := range resources {
+		if !strings.Contains(strings.ToLower(r.Address), filter) {
+			continue
+		}
+		matched++
+		if matched > terraformMaxResources {
+			continue
+		}
+		data, _ := json.Marshal(redactTerraformAttributes(r.Attributes))
+		attributes := string(data)
+		if len(attributes) > terraformMaxAttributes {
+			attributes = attributes[:terraformMaxAttributes] + "…"
+		}
+		fmt.Fprintf(&b, "%s\n%s\n\n", r.Address, attributes)
+	}
+	if matched == 0 {
+		return fmt.Sprintf("state 中没有匹配 %q 的资源，可不带过滤词查看资源类型列表", filter)
+	}
+	if matched > terraformMaxResources {
+		fmt.Fprintf(&b, "……另有 %d 个资源未显示，请使用更具体的过滤词", matched-terraformMaxResources)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// terraformAttribute 读取属性路径的值，单元素列表（如 AWS 的 scaling_config 块）会自动展开
+func terraformAttribute(attributes map[string]interface{}, attrPath string) (string, bool) {
+	var value interface{} = attributes
+	for _, part := range strings.Split(attrPath, ".") {
+		if list, ok := value.([]interface{}); ok && len(list) == 1 {
+			value = list[0]
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		if value, ok = m[part]; !ok || value == nil {
+			return "", false
+		}
+	}
+	if list, ok := value.([]interface{}); ok && len(list) == 1 {
+		value = list[0]
+	}
+	value = redactTerraformAttributes(value)
+	if s, ok := value.(string); ok {
+		return s, s != ""
+	}
+	data, err := json.Marshal(value)
+	return string(data), err == nil
+}
+
+// redactTerraformAttributes 隐藏密码、密钥、证书等敏感属性的取值
+func redactTerraformAttributes(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if terraformSensitiveRe.MatchString(key) && item != nil && item != "" {
+				redacted[key] = "<redacted>"
+				continue
+			}
+			redacted[key] = redactTerraformAttributes(item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactTerraformAttributes(item)
+		}
+		return redacted
+	}
+	return value
+}
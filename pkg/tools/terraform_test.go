@@ -0,0 +1,96 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const testTerraformState = `{
+  "version": 4, "terraform_version": "1.7.5", "serial": 42,
+  "resources": [
+    {"module": "module.eks", "mode": "managed", "type": "aws_eks_node_group", "name": "general",
+     "instances": [{"attributes": {"instance_types": ["m6i.xlarge"], "capacity_type": "ON_DEMAND",
+       "scaling_config": [{"desired_size": 3, "min_size": 2, "max_size": 6}]}}]},
+    {"mode": "managed", "type": "aws_lb", "name": "ingress",
+     "instances": [{"attributes": {"load_balancer_type": "network", "internal": false, "dns_name": "ingress.elb.amazonaws.com"}}]},
+    {"mode": "managed", "type": "aws_db_instance", "name": "orders",
+     "instances": [{"attributes": {"instance_class": "db.r6g.large", "password": "hunter2"}}]},
+    {"mode": "data", "type": "aws_caller_identity", "name": "current", "instances": [{"attributes": {}}]}
+  ]
+}`
+
+func TestTerraformContext(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/infra/prod-east/terraform.tfstate" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(testTerraformState))
+	}))
+	defer server.Close()
+
+	config := utils.GetConfig()
+	config.Set("tools.terraform.endpoint", server.URL)
+	config.Set("tools.terraform.bucket", "infra")
+	config.Set("tools.terraform.states", map[string]string{"prod-east": "prod-east/terraform.tfstate"})
+	config.Set("tools.terraform.access_key_id", "AKIDEXAMPLE")
+	config.Set("tools.terraform.secret_access_key", "secret")
+	defer func() {
+		for _, key := range []string{"endpoint", "bucket", "states", "access_key_id", "secret_access_key"} {
+			config.Set("tools.terraform."+key, nil)
+		}
+	}()
+
+	ctx := WithKubeContext(context.Background(), "prod-east")
+	summary, err := TerraformContext(ctx, "")
+	if err != nil {
+		t.Fatalf("TerraformContext() error = %v", err)
+	}
+	for _, want := range []string{
+		`module.eks.aws_eks_node_group.general scaling_config={"desired_size":3,"max_size":6,"min_size":2} instance_types=m6i.xlarge capacity_type=ON_DEMAND`,
+		"aws_lb.ingress load_balancer_type=network internal=false",
+		"- aws_db_instance: 1",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "aws_caller_identity") {
+		t.Error("data sources should not be listed")
+	}
+
+	details, err := TerraformContext(ctx, "aws_db_instance")
+	if err != nil || !strings.Contains(details, `"password":"\u003credacted\u003e"`) || strings.Contains(details, "hunter2") {
+		t.Errorf("TerraformContext(filter) = %q, %v", details, err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the state to be cached, fetched %d times", requests)
+	}
+
+	if _, err := TerraformContext(context.Background(), ""); err == nil {
+		t.Error("expected error without a configured state for the cluster")
+	}
+
+	// --state 只接受已配置的名称或对象键，不能读取存储桶中的其他对象
+	if _, err := TerraformContext(context.Background(), "--state=prod-east aws_lb"); err != nil {
+		t.Errorf("TerraformContext(--state=prod-east) error = %v", err)
+	}
+	if _, err := TerraformContext(context.Background(), "--state=prod-east/terraform.tfstate"); err != nil {
+		t.Errorf("TerraformContext(--state=<configured key>) error = %v", err)
+	}
+	for _, state := range []string{"../secrets/creds.json", "/other-bucket/terraform.tfstate", "staging/terraform.tfstate"} {
+		if _, err := TerraformContext(context.Background(), "--state="+state); err == nil {
+			t.Errorf("expected --state=%s to be rejected", state)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected only the configured state to be fetched, fetched %d times", requests)
+	}
+}
@@ -1,6 +1,8 @@
 package tools
 
 import (
+	"time"
+
 	"go.uber.org/zap"
 
 	"github.com/myysophia/OpsAgent/pkg/utils"
@@ -16,14 +18,161 @@ func init() {
 // Tool 是一个接受输入并返回输出的函数类型
 type Tool func(input string) (string, error)
 
-// function call ，可以理解这里是hook点，可以在这里添加自己的工具
-var CopilotTools = map[string]Tool{
-	"search":  GoogleSearch,
-	"python":  PythonREPL,
-	"trivy":   Trivy,
-	"kubectl": Kubectl,
-	"jq":      JQ,
-}
+// Registry 全局工具注册表，可以理解这里是 hook 点，可以在这里添加自己的工具
+// 未登记幂等性的工具按 side-effecting 处理；kubectl 按子命令进一步区分，见 Classify
+var Registry = NewToolRegistry(
+	ToolSpec{
+		Name:        "search",
+		Description: "用于搜索互联网信息。输入：搜索关键词，输出：搜索结果。",
+		InputHint:   "搜索关键词",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyCacheable,
+		Run:         GoogleSearchContext,
+	},
+	ToolSpec{
+		Name:        "python",
+		Description: "用于复杂逻辑或调用 Kubernetes Python SDK。输入：Python 脚本，输出：通过 print(...) 返回。",
+		InputHint:   "Python 脚本，结果通过 print(...) 输出",
+		Timeout:     2 * time.Minute,
+		Idempotency: IdempotencySideEffecting,
+		Run:         PythonREPLContext,
+	},
+	ToolSpec{
+		Name:        "trivy",
+		Description: "用于扫描镜像漏洞。输入：镜像名称，输出：漏洞报告。",
+		InputHint:   "镜像名称，例如 nginx:1.25",
+		Timeout:     10 * time.Minute,
+		Idempotency: IdempotencyCacheable,
+		Run:         TrivyContext,
+	},
+	ToolSpec{
+		Name:        "kubectl",
+		Description: "用于执行 Kubernetes 命令。必须使用正确语法（例如 'kubectl get pods' 而非 'kubectl get pod'），避免使用 -o json/yaml 全量输出。",
+		InputHint:   "kubectl 命令，例如 kubectl get pods -n shop",
+		Timeout:     time.Minute,
+		Idempotency: IdempotencyPureRead,
+		Run:         KubectlContext,
+	},
+	ToolSpec{
+		Name:        "jq",
+		Description: "用于处理 JSON 数据。输入：JSON 对象 {\"expression\": \"jq 表达式\", \"json\": \"要处理的 JSON 文本\", \"raw_output\": false}，表达式和数据分开传递，不需要 shell 引号；始终使用 'test()' 进行名称匹配。",
+		InputHint:   `{"expression": ".items[].metadata.name", "json": "{\"items\": []}"}`,
+		Schema:      jqSchema,
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyCacheable,
+		Run:         JQContext,
+	},
+	ToolSpec{
+		Name:        "calc",
+		Description: "用于精确计算资源数量，禁止自行对 Mi/Gi、m 等单位做算术。输入：运算和数量，例如 'sum 512Mi 1Gi 250Mi'、'percent 1536Mi 4Gi'、'convert 1536Mi Gi'、'avg 100m 250m 1'，运算支持 sum/avg/min/max/percent/convert，数量可直接粘贴 kubectl 输出。",
+		InputHint:   "运算和数量，例如 sum 512Mi 1Gi 250Mi",
+		Idempotency: IdempotencyPureRead,
+		Run:         CalcContext,
+	},
+	ToolSpec{
+		Name:        "promql",
+		Description: "用于查询 Prometheus 监控指标（CPU/内存使用率、请求量、延迟等），比 kubectl top 更适合回答一段时间内的趋势。输入：PromQL 表达式，查询一段时间时在前面加 --range=1h（可选 --step=1m），例如 '--range=1h sum(rate(container_cpu_usage_seconds_total{namespace=\"shop\",pod=~\"payment-api.*\"}[5m]))'。输出：即时值，或范围内的 min/avg/max/last。",
+		InputHint:   "PromQL 表达式，可加 --range=1h --step=1m 执行范围查询",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         PromQLContext,
+	},
+	ToolSpec{
+		Name:        "terraform",
+		Description: "用于查询 Terraform state 中的基础设施信息（节点组规模和机型、负载均衡配置、集群版本等 kubectl 查不到的信息），只读。输入：可选的过滤词（资源类型或名称的一部分，如 aws_eks_node_group、slb、default），不带过滤词时返回集群、节点组、负载均衡汇总和资源列表；可加 --state=<名称> 查询其他 state。",
+		InputHint:   "可选的资源类型或名称，例如 aws_eks_node_group",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyCacheable,
+		Run:         TerraformContext,
+	},
+	ToolSpec{
+		Name:        "logs",
+		Description: "用于从日志系统查询历史日志，包括已崩溃、已重建的 Pod（kubectl logs 只能看到当前容器）。输入：--namespace=<命名空间> --pod=<Pod 名称前缀，可用 Deployment 名称> [--container=<容器>] [--since=1h 或 --start=<RFC3339> --end=<RFC3339>] [--limit=100] [日志中包含的文本，如 Exception]。",
+		InputHint:   "--namespace=shop --pod=payment-api --since=2h Exception",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         LogSearchContext,
+	},
+	ToolSpec{
+		Name:        "services",
+		Description: "用于查询服务别名知识库，将用户提到的中文名或简称（如 支付、网关）转换为 K8s 资源名称、命名空间和集群，查询资源前不确定名称时先使用。输入：服务的中文名、简称或名称的一部分，为空时列出全部服务。",
+		InputHint:   "服务的中文名或简称，例如 支付",
+		Idempotency: IdempotencyPureRead,
+		Run:         ServicesContext,
+	},
+	ToolSpec{
+		Name:        "kubeaudit",
+		Description: "用于查询集群审计日志中的变更记录，回答谁在什么时候扩缩容、修改或删除了某个资源（kubectl 只能看到当前状态）。输入：[--namespace=<命名空间>] [--resource=deployments] [--name=<资源名称前缀>] [--verb=patch|update|create|delete] [--user=<用户>] [--since=24h 或 --start=<RFC3339> --end=<RFC3339>] [--limit=20]，默认查询目标集群最近 7 天。输出：每行一条记录，包含时间、用户、动作、资源（scale 子资源附带副本数）和客户端。",
+		InputHint:   "--resource=deployments --name=gateway --since=48h",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         KubeAuditContext,
+	},
+	ToolSpec{
+		Name:        "rollout",
+		Description: "用于查询 Deployment 的发布状态和修订历史，回答最近发布改了什么：每个修订的创建时间、镜像、change-cause，以及相对上一修订的镜像、环境变量名、资源、启动参数和 ConfigMap/Secret 引用变化。输入：[-n <命名空间>] [--limit=5] <Deployment 名称或 Pod 名称>，也可以写成 <命名空间>/<名称>。",
+		InputHint:   "-n shop payment-api",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         RolloutContext,
+	},
+	ToolSpec{
+		Name:        "quotacheck",
+		Description: "用于检查 ResourceQuota/LimitRange 合规情况：列出配额使用率超过阈值的命名空间、未设置 CPU/内存 requests 或 limits 的工作负载，以及超出 LimitRange 范围的容器。输入：[-n <命名空间>] [--threshold=0.8]，不指定命名空间时检查全部命名空间。",
+		InputHint:   "-n shop --threshold=0.8",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         QuotaCheckContext,
+	},
+	ToolSpec{
+		Name:        "restarts",
+		Description: "用于统计一段时间内容器重启次数和 OOMKill，按集群和工作负载（服务）汇总，并把时间统一换算到指定时区，便于不同时区的值班同事对照。输入：[--context=<集群>[,<集群>...]] [-n <命名空间>] [--window=24h] [--tz=Asia/Shanghai]，不指定命名空间时统计全部命名空间。",
+		InputHint:   "--context=prod-east,prod-eu -n shop --window=7d --tz=Australia/Sydney",
+		Timeout:     time.Minute,
+		Idempotency: IdempotencyPureRead,
+		Run:         RestartsContext,
+	},
+	ToolSpec{
+		Name:        "snapshot",
+		Description: "用于查询集群历史快照中某一时刻存在的资源（Namespace、Node、Deployment、Service、ConfigMap 等），回答\"上周二有哪些资源\"、事后复盘时资源是否存在等问题。输入：[--context=<集群>] --at=<RFC3339 时间、2006-01-02 日期或 72h/7d 表示多久之前> [--kind=deployments] [-n <命名空间>] [名称]。",
+		InputHint:   "--at=2026-10-13 --kind=deployments -n shop payment",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         SnapshotContext,
+	},
+	ToolSpec{
+		Name:        "compare",
+		Description: "用于比较集群历史快照与现在（或另一个时刻）之间的变化：新增和删除的资源，以及镜像、副本数、资源配置、ConfigMap 内容、标签的变化，回答\"昨天以来有什么变化\"、故障前后改了什么等问题。输入：[--context=<集群>] [--since=<RFC3339 时间、2006-01-02 日期或 24h/7d 表示多久之前，默认 24h>] [--to=<同 --since，默认现在>] [--kind=deployments] [-n <命名空间>[,<命名空间>...]] [名称]。",
+		InputHint:   "--since=24h -n shop",
+		Timeout:     time.Minute,
+		Idempotency: IdempotencyPureRead,
+		Run:         CompareContext,
+	},
+	ToolSpec{
+		Name:        "inventory",
+		Description: "用于在全部集群中按名称搜索资源（Namespace、Node、Deployment、Service、ConfigMap 等），回答某个服务部署在哪些集群、哪个命名空间等问题，结果来自定期刷新的资源清单缓存。输入：[--context=<集群>[,<集群>...]] [--kind=deployments] [-n <命名空间>] <名称的一部分>，不指定 --context 时搜索全部集群。",
+		InputHint:   "--kind=deployments payment",
+		Timeout:     time.Minute,
+		Idempotency: IdempotencyPureRead,
+		Run:         InventoryContext,
+	},
+	ToolSpec{
+		Name:        "dryrun",
+		Description: "用于在应用前校验 YAML 清单：在目标集群执行服务端 dry-run（kubectl apply --dry-run=server），返回 API Server 的校验错误，以及每个对象相对集群中现有对象的 diff（kubectl diff），不会修改集群。输入：YAML 清单，多个对象用 --- 分隔。",
+		InputHint:   "apiVersion: apps/v1\nkind: Deployment\n...",
+		Timeout:     time.Minute,
+		Idempotency: IdempotencyPureRead,
+		Run:         DryRunContext,
+	},
+	ToolSpec{
+		Name:        "nodepools",
+		Description: "用于回答节点池、GPU 和调度容量相关的问题，按节点池汇总节点标签、污点以及 CPU/内存/GPU 的已请求和可分配量。输入：可选的过滤词（节点池、命名空间或工作负载名称的一部分），为空时列出全部节点池。",
+		InputHint:   "可选的过滤词，为空时列出全部节点池",
+		Timeout:     30 * time.Second,
+		Idempotency: IdempotencyPureRead,
+		Run:         NodePoolsContext,
+	},
+)
 
 // ToolPrompt 定义了与 LLM 交互的 JSON 格式
 type ToolPrompt struct {
@@ -14,13 +14,18 @@ limitations under the License.
 package tools
 
 import (
-	"os/exec"
+	"context"
 	"strings"
 	"go.uber.org/zap"
 )
 
 // Trivy runs trivy against the image and returns the output
 func Trivy(image string) (string, error) {
+	return TrivyContext(context.Background(), image)
+}
+
+// TrivyContext runs trivy against the image and kills the scan when ctx is cancelled.
+func TrivyContext(ctx context.Context, image string) (string, error) {
 	logger.Debug("准备执行 Trivy 扫描",
 		zap.String("raw_image", image),
 	)
@@ -34,7 +39,7 @@ func Trivy(image string) (string, error) {
 		zap.String("image", image),
 	)
 
-	cmd := exec.Command("trivy", "image", image, "--scanners", "vuln")
+	cmd := commandContext(ctx, "trivy", "image", image, "--scanners", "vuln")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		logger.Error("Trivy 扫描失败",
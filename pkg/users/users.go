@@ -0,0 +1,194 @@
+package users
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sync"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Role 用户角色，决定可以访问的接口和可以触发的 kubectl 变更命令
+type Role string
+
+const (
+	// RoleViewer 只读：可以提问和诊断，不能触发任何变更命令
+	RoleViewer Role = "viewer"
+	// RoleOperator 运维：可以提交 auth.roles.operator.kubectl_verbs 中的变更命令（仍受只读策略和审批约束）
+	RoleOperator Role = "operator"
+	// RoleAdmin 管理员：可以访问管理接口、审批变更
+	RoleAdmin Role = "admin"
+)
+
+// 未配置 auth.users 时使用的内置账号，仅用于本地体验
+const (
+	DefaultUsername = "admin"
+	DefaultPassword = "novastar"
+)
+
+// roleRank 角色等级，高等级拥有低等级的全部权限
+var roleRank = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// defaultKubectlVerbs 各角色默认可以触发的 kubectl 变更子命令，"*" 表示全部
+var defaultKubectlVerbs = map[Role][]string{
+	RoleViewer:   {},
+	RoleOperator: {"scale", "rollout restart", "rollout undo", "rollout pause", "rollout resume", "cordon", "uncordon", "label", "annotate"},
+	RoleAdmin:    {"*"},
+}
+
+// Valid 是否为合法角色
+func (r Role) Valid() bool {
+	return roleRank[r] > 0
+}
+
+// AtLeast 角色是否不低于 min，未知角色没有任何权限
+func (r Role) AtLeast(min Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[min]
+}
+
+// User 登录用户，通过 auth.users 配置，密码保存为 bcrypt 哈希
+type User struct {
+	Username     string `mapstructure:"username" json:"username"`
+	PasswordHash string `mapstructure:"password_hash" json:"-"`
+	Role         Role   `mapstructure:"role" json:"role"`
+}
+
+// Store 用户存储
+type Store struct {
+	users map[string]*User
+	// builtin 未配置用户时启用内置的 admin 账号
+	builtin bool
+}
+
+var (
+	defaultStore *Store
+	storeOnce    sync.Once
+
+	dummyHash []byte
+	dummyOnce sync.Once
+)
+
+// Default 获取根据 auth.users 配置创建的全局用户存储
+func Default() *Store {
+	storeOnce.Do(func() {
+		var list []User
+		if err := utils.GetConfig().UnmarshalKey("auth.users", &list); err != nil {
+			utils.Error("解析用户配置失败", zap.Error(err))
+		}
+		store, err := NewStore(list)
+		if err != nil {
+			utils.Error("加载用户配置失败", zap.Error(err))
+			store = &Store{users: map[string]*User{}}
+		}
+		if store.builtin {
+			utils.Warn("未配置 auth.users，使用内置管理员账号，生产环境请配置用户并修改密码",
+				zap.String("username", DefaultUsername))
+		}
+		defaultStore = store
+	})
+	return defaultStore
+}
+
+// NewStore 创建用户存储，list 为空时启用内置的 admin 账号
+func NewStore(list []User) (*Store, error) {
+	s := &Store{users: map[string]*User{}}
+	for i := range list {
+		u := list[i]
+		if u.Username == "" || u.PasswordHash == "" {
+			return nil, fmt.Errorf("用户 %d 缺少 username 或 password_hash", i+1)
+		}
+		if u.Role == "" {
+			u.Role = RoleViewer
+		}
+		if !u.Role.Valid() {
+			return nil, fmt.Errorf("用户 %s 的角色 %q 无效，可选值: viewer, operator, admin", u.Username, u.Role)
+		}
+		s.users[u.Username] = &u
+	}
+	s.builtin = len(s.users) == 0
+	return s, nil
+}
+
+// Builtin 是否在使用内置的 admin 账号
+func (s *Store) Builtin() bool {
+	return s.builtin
+}
+
+// Authenticate 校验用户名和密码，成功时返回用户
+func (s *Store) Authenticate(username, password string) (*User, bool) {
+	if s.builtin {
+		ok := subtle.ConstantTimeCompare([]byte(username), []byte(DefaultUsername)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(DefaultPassword)) == 1
+		if !ok {
+			return nil, false
+		}
+		return &User{Username: DefaultUsername, Role: RoleAdmin}, true
+	}
+
+	u, ok := s.users[username]
+	if !ok {
+		// 用户不存在时同样计算一次哈希，避免通过响应时间枚举用户名
+		dummyOnce.Do(func() { dummyHash, _ = bcrypt.GenerateFromPassword([]byte(DefaultPassword), bcrypt.DefaultCost) })
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return nil, false
+	}
+	copied := *u
+	return &copied, true
+}
+
+// Role 返回用户的角色，未知用户返回空
+func (s *Store) Role(username string) Role {
+	if s.builtin && username == DefaultUsername {
+		return RoleAdmin
+	}
+	if u, ok := s.users[username]; ok {
+		return u.Role
+	}
+	return ""
+}
+
+// RoleOf 返回用户的角色；auth.admins 中的用户视为管理员，兼容只配置管理员列表的部署
+func RoleOf(username string) Role {
+	if username == "" {
+		return ""
+	}
+	for _, admin := range utils.GetConfig().GetStringSlice("auth.admins") {
+		if admin == username {
+			return RoleAdmin
+		}
+	}
+	return Default().Role(username)
+}
+
+// HashPassword 生成 auth.users 中使用的 bcrypt 密码哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// KubectlVerbAllowed 角色是否可以触发 kubectl 变更子命令（如 "scale"、"rollout restart"），
+// 配置项 auth.roles.<role>.kubectl_verbs 覆盖默认值，"*" 表示全部
+func KubectlVerbAllowed(role Role, verb string) bool {
+	verbs, ok := defaultKubectlVerbs[role]
+	if !ok {
+		return false
+	}
+	key := fmt.Sprintf("auth.roles.%s.kubectl_verbs", role)
+	if config := utils.GetConfig(); config.IsSet(key) {
+		verbs = config.GetStringSlice(key)
+	}
+	for _, v := range verbs {
+		if v == "*" || v == verb {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+func TestStoreAuthenticate(t *testing.T) {
+	hash, err := HashPassword("s3cret")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	store, err := NewStore([]User{
+		{Username: "alice", PasswordHash: hash, Role: RoleOperator},
+		{Username: "bob", PasswordHash: hash},
+	})
+	if err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	if store.Builtin() {
+		t.Error("builtin account should be disabled once users are configured")
+	}
+	if user, ok := store.Authenticate("alice", "s3cret"); !ok || user.Role != RoleOperator {
+		t.Errorf("Authenticate(alice) = %+v, %v", user, ok)
+	}
+	for _, creds := range [][2]string{{"alice", "wrong"}, {"mallory", "s3cret"}, {DefaultUsername, DefaultPassword}} {
+		if _, ok := store.Authenticate(creds[0], creds[1]); ok {
+			t.Errorf("Authenticate(%s, %s) should fail", creds[0], creds[1])
+		}
+	}
+	if role := store.Role("bob"); role != RoleViewer {
+		t.Errorf("Role(bob) = %q, want viewer by default", role)
+	}
+
+	if _, err := NewStore([]User{{Username: "carol", PasswordHash: hash, Role: "root"}}); err == nil {
+		t.Error("expected invalid role to be rejected")
+	}
+
+	builtin, _ := NewStore(nil)
+	if user, ok := builtin.Authenticate(DefaultUsername, DefaultPassword); !ok || user.Role != RoleAdmin {
+		t.Errorf("builtin Authenticate() = %+v, %v", user, ok)
+	}
+}
+
+func TestKubectlVerbAllowed(t *testing.T) {
+	tests := []struct {
+		role Role
+		verb string
+		want bool
+	}{
+		{RoleViewer, "scale", false},
+		{RoleOperator, "rollout restart", true},
+		{RoleOperator, "delete", false},
+		{RoleAdmin, "drain", true},
+		{"", "scale", false},
+	}
+	for _, tt := range tests {
+		if got := KubectlVerbAllowed(tt.role, tt.verb); got != tt.want {
+			t.Errorf("KubectlVerbAllowed(%q, %q) = %v, want %v", tt.role, tt.verb, got, tt.want)
+		}
+	}
+
+	utils.GetConfig().Set("auth.roles.operator.kubectl_verbs", []string{"delete"})
+	defer utils.GetConfig().Set("auth.roles.operator.kubectl_verbs", nil)
+	if !KubectlVerbAllowed(RoleOperator, "delete") || KubectlVerbAllowed(RoleOperator, "scale") {
+		t.Error("auth.roles.operator.kubectl_verbs should replace the default verbs")
+	}
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	if !RoleAdmin.AtLeast(RoleOperator) || RoleViewer.AtLeast(RoleOperator) || Role("").AtLeast(RoleViewer) {
+		t.Error("unexpected role ordering")
+	}
+}
@@ -1,48 +1,31 @@
 package utils
 
 import (
+	"strings"
+
 	"github.com/spf13/viper"
 )
 
+// EnvPrefix 环境变量前缀，例如 OPSAGENT_SERVER_PORT 覆盖 server.port
+const EnvPrefix = "OPSAGENT"
+
 var config *viper.Viper
 
 // GetConfig 获取配置实例
 func GetConfig() *viper.Viper {
 	if config == nil {
-		config = viper.New()
-		config.SetConfigName("config")
-		config.SetConfigType("yaml")
-
-		// 设置配置文件路径
-		config.AddConfigPath("configs")
-		config.AddConfigPath(".")
-
-		// 读取配置文件
-		if err := config.ReadInConfig(); err != nil {
-			// 如果配置文件不存在，使用默认配置
-			config.SetDefault("jwt.key", "your-secret-key-please-change-in-production")
-			config.SetDefault("jwt.expire", "24h")
-			config.SetDefault("server.port", 8080)
-			config.SetDefault("server.host", "0.0.0.0")
-			config.SetDefault("log.level", "info")
-			config.SetDefault("log.format", "json")
-			config.SetDefault("log.output", "stdout")
-			config.SetDefault("perf.enabled", true)
-			config.SetDefault("perf.reset_interval", "24h")
-		}
+		config = newConfig()
+
+		// 读取配置文件，如果配置文件不存在，使用默认配置
+		_ = config.ReadInConfig()
 	}
 	return config
 }
 
 // InitConfig 初始化配置
+// 优先级：命令行参数 > 环境变量 > 配置文件 > 默认值
 func InitConfig() error {
-	config = viper.New()
-	config.SetConfigName("config")
-	config.SetConfigType("yaml")
-
-	// 设置配置文件路径
-	config.AddConfigPath("configs")
-	config.AddConfigPath(".")
+	config = newConfig()
 
 	// 读取配置文件
 	if err := config.ReadInConfig(); err != nil {
@@ -51,3 +34,45 @@ func InitConfig() error {
 
 	return nil
 }
+
+// newConfig 创建带默认值和环境变量绑定的配置实例
+func newConfig() *viper.Viper {
+	v := viper.New()
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+
+	// 设置配置文件路径
+	v.AddConfigPath("configs")
+	v.AddConfigPath(".")
+
+	// 环境变量覆盖配置文件
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	v.SetDefault("jwt.key", "your-secret-key-please-change-in-production")
+	v.SetDefault("jwt.expire", "24h")
+	v.SetDefault("server.port", 8080)
+	v.SetDefault("server.host", "0.0.0.0")
+	v.SetDefault("log.level", "info")
+	v.SetDefault("log.format", "json")
+	v.SetDefault("log.output", "stdout")
+	v.SetDefault("perf.enabled", true)
+	v.SetDefault("perf.reset_interval", "24h")
+	return v
+}
+
+// IsProductionEnvironment 部署环境（server.environment）是否为生产环境
+// 未设置时按生产环境处理，故障注入等测试功能只有明确声明为非生产环境才能启用
+func IsProductionEnvironment(environment string) bool {
+	switch strings.ToLower(strings.TrimSpace(environment)) {
+	case "", "prod", "production":
+		return true
+	}
+	return false
+}
+
+// ConfigEnvName 返回配置项对应的环境变量名
+func ConfigEnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
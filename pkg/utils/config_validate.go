@@ -0,0 +1,662 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// 配置问题级别
+const (
+	ConfigIssueError   = "error"
+	ConfigIssueWarning = "warning"
+)
+
+// ConfigIssue 配置校验发现的问题
+type ConfigIssue struct {
+	Level   string `json:"level"`
+	Key     string `json:"key"`
+	Message string `json:"message"`
+}
+
+func (i ConfigIssue) String() string {
+	return fmt.Sprintf("[%s] %s: %s", i.Level, i.Key, i.Message)
+}
+
+// 配置项类型
+const (
+	kindString   = "string"
+	kindInt      = "int"
+	kindFloat    = "float"
+	kindBool     = "bool"
+	kindDuration = "duration"
+	kindList     = "list"
+	kindMap      = "map"
+)
+
+// knownConfigKeys 所有支持的配置项及其类型，新增配置项时需同步更新
+// kindMap 类型的配置项允许任意子键
+var knownConfigKeys = map[string]string{
+	"jwt.key":                   kindString,
+	"jwt.expire":                kindDuration,
+	"auth.admins":               kindList,
+	"auth.users":                kindList,
+	"auth.roles":                kindMap,
+	"server.port":               kindInt,
+	"server.host":               kindString,
+	"server.shutdown_timeout":   kindDuration,
+	"server.environment":        kindString,
+	"api.legacy.sunset":         kindString,
+	"openapi.swagger_ui_assets": kindString,
+	"log.level":                 kindString,
+	"log.format":                kindString,
+	"log.output":                kindString,
+	"perf.enabled":              kindBool,
+	"perf.reset_interval":       kindDuration,
+	"apikeys.enabled":           kindBool,
+	"apikeys.file":              kindString,
+	"generate.apply.require_distinct_reviewer": kindBool,
+	"approvals.enabled":                        kindBool,
+	"approvals.ttl":                            kindDuration,
+	"approvals.require_distinct_reviewer":      kindBool,
+	"approvals.im.platform":                    kindString,
+	"approvals.im.channel":                     kindString,
+	"approvals.im.base_url":                    kindString,
+	"approvals.im.secret":                      kindString,
+	"approvals.im.client_id":                   kindString,
+	"approvals.im.client_secret":               kindString,
+	"approvals.im.agent_id":                    kindString,
+	"approvals.im.users":                       kindMap,
+	"audit.enabled":                            kindBool,
+	"audit.driver":                             kindString,
+	"audit.dsn":                                kindString,
+	"audit.batch_size":                         kindInt,
+	"audit.alert.lag_threshold":                kindDuration,
+	"audit.alert.queue_depth":                  kindInt,
+	"audit.alert.interval":                     kindDuration,
+	"audit.alert.cooldown":                     kindDuration,
+	"audit.backfill.enabled":                   kindBool,
+	"audit.backfill.batch_size":                kindInt,
+	"kube_audit.enabled":                       kindBool,
+	"kube_audit.verbs":                         kindList,
+	"kube_audit.exclude_users":                 kindList,
+	"kube_audit.webhook_token":                 kindString,
+	"kube_audit.interval":                      kindDuration,
+	"kube_audit.sources":                       kindList,
+	"notify.channels":                          kindList,
+	"handoff.enabled":                          kindBool,
+	"handoff.confidence_threshold":             kindFloat,
+	"handoff.channel":                          kindString,
+	"handoff.base_url":                         kindString,
+	"handoff.snapshots":                        kindList,
+	"ownership.teams":                          kindMap,
+	"ownership.services":                       kindList,
+	"changes.enabled":                          kindBool,
+	"changes.clusters":                         kindList,
+	"changes.window":                           kindDuration,
+	"changes.max_events":                       kindInt,
+	"nodepools.labels":                         kindList,
+	"compliance.quota_threshold":               kindFloat,
+	"restarts.window":                          kindDuration,
+	"restarts.timezone":                        kindString,
+	"snapshots.enabled":                        kindBool,
+	"snapshots.clusters":                       kindList,
+	"snapshots.interval":                       kindDuration,
+	"snapshots.retention":                      kindDuration,
+	"snapshots.kinds":                          kindList,
+	"inventory.clusters":                       kindList,
+	"inventory.ttl":                            kindDuration,
+	"inventory.kinds":                          kindList,
+	"reports.schedules":                        kindList,
+	"runners.tls.cert_file":                    kindString,
+	"runners.tls.key_file":                     kindString,
+	"runners.tls.ca_file":                      kindString,
+	"runners.endpoints":                        kindList,
+	"runners.capabilities_ttl":                 kindDuration,
+	"runner.listen":                            kindString,
+	"runner.tls.cert_file":                     kindString,
+	"runner.tls.key_file":                      kindString,
+	"runner.tls.ca_file":                       kindString,
+	"runner.tools":                             kindList,
+	"runner.receipts.key_file":                 kindString,
+	"runner.receipts.identity":                 kindString,
+	"receipts.enabled":                         kindBool,
+	"receipts.key_file":                        kindString,
+	"receipts.identity":                        kindString,
+	"receipts.trusted_keys":                    kindList,
+	"llm.api_key":                              kindString,
+	"llm.routing.long_context_model":           kindString,
+	"llm.routing.threshold":                    kindInt,
+	"llm.token_limits":                         kindMap,
+	"llm.hooks":                                kindList,
+	"llm.deidentify.internal_domains":          kindList,
+	"llm.deidentify.ip_ranges":                 kindList,
+	"llm.deidentify.patterns":                  kindMap,
+	"llm.deidentify.secret":                    kindString,
+	"llm.embedding_model":                      kindString,
+	"llm.allowed_base_urls":                    kindList,
+	"llm.warmup.enabled":                       kindBool,
+	"llm.warmup.endpoints":                     kindList,
+	"llm.warmup.ping_model":                    kindString,
+	"llm.warmup.timeout":                       kindDuration,
+	"llm.cassette.mode":                        kindString,
+	"llm.cassette.dir":                         kindString,
+	"llm.cassette.ignore_system":               kindBool,
+	"llm.providers":                            kindMap,
+	"llm.tool_calling":                         kindString,
+	"llm.failover.cooldown":                    kindDuration,
+	"llm.failover.health_check_interval":       kindDuration,
+	"memory.recent_turns":                      kindInt,
+	"memory.top_k":                             kindInt,
+	"memory.max_turns":                         kindInt,
+	"memory.max_conversations":                 kindInt,
+	"memory.ttl":                               kindDuration,
+	"models.default.models":                    kindList,
+	"models.default.providers":                 kindList,
+	"models.tenants":                           kindMap,
+	"models.roles":                             kindMap,
+	"tools.quotas":                             kindMap,
+	"tools.retry_budget.per_target":            kindInt,
+	"tools.result_cache.enabled":               kindBool,
+	"tools.auto_retry.attempts":                kindInt,
+	"tools.auto_retry.backoff":                 kindDuration,
+	"tools.timeouts":                           kindMap,
+	"tools.kubectl.allow_write":                kindBool,
+	"tools.kubectl.kubeconfig":                 kindString,
+	"tools.kubectl.native":                     kindBool,
+	"tools.kubectl.max_output_lines":           kindInt,
+	"tools.kubectl.max_output_bytes":           kindInt,
+	"tools.jq.max_input_bytes":                 kindInt,
+	"tools.jq.max_output_bytes":                kindInt,
+	"tools.python.sandbox.enabled":             kindBool,
+	"tools.python.sandbox.runtime":             kindString,
+	"tools.python.sandbox.image":               kindString,
+	"tools.python.sandbox.python":              kindString,
+	"tools.python.sandbox.cgroup":              kindString,
+	"tools.python.sandbox.cpus":                kindFloat,
+	"tools.python.sandbox.memory_mb":           kindInt,
+	"tools.python.sandbox.timeout":             kindDuration,
+	"tools.python.sandbox.allowed_modules":     kindList,
+	"tools.promql.url":                         kindString,
+	"tools.promql.endpoints":                   kindMap,
+	"tools.promql.bearer_token":                kindString,
+	"tools.promql.headers":                     kindMap,
+	"tools.promql.max_series":                  kindInt,
+	"tools.logs.backend":                       kindString,
+	"tools.logs.url":                           kindString,
+	"tools.logs.index":                         kindString,
+	"tools.logs.fields":                        kindMap,
+	"tools.logs.bearer_token":                  kindString,
+	"tools.logs.headers":                       kindMap,
+	"tools.terraform.endpoint":                 kindString,
+	"tools.terraform.region":                   kindString,
+	"tools.terraform.bucket":                   kindString,
+	"tools.terraform.key":                      kindString,
+	"tools.terraform.states":                   kindMap,
+	"tools.terraform.access_key_id":            kindString,
+	"tools.terraform.secret_access_key":        kindString,
+	"tools.terraform.cache_ttl":                kindDuration,
+	"log.max_size_mb":                          kindInt,
+	"log.max_backups":                          kindInt,
+	"log.max_age_days":                         kindInt,
+	"log.max_total_size_mb":                    kindInt,
+	"log.compress":                             kindBool,
+	"log.shipper.type":                         kindString,
+	"log.shipper.url":                          kindString,
+	"log.shipper.labels":                       kindMap,
+	"log.shipper.batch_size":                   kindInt,
+	"log.shipper.flush_interval":               kindDuration,
+	"log.shipper.s3.bucket":                    kindString,
+	"log.shipper.s3.region":                    kindString,
+	"log.shipper.s3.prefix":                    kindString,
+	"log.shipper.s3.access_key_id":             kindString,
+	"log.shipper.s3.secret_access_key":         kindString,
+	"http_client.timeout":                      kindDuration,
+	"http_client.dial_timeout":                 kindDuration,
+	"http_client.tls_handshake_timeout":        kindDuration,
+	"http_client.keep_alive":                   kindDuration,
+	"http_client.idle_conn_timeout":            kindDuration,
+	"http_client.max_idle_conns":               kindInt,
+	"http_client.max_idle_conns_per_host":      kindInt,
+	"http_client.http2":                        kindBool,
+	"http_client.proxy":                        kindString,
+	"http_client.ca_file":                      kindString,
+	"http_client.insecure_skip_verify":         kindBool,
+	"http_client.clients":                      kindMap,
+	"clusters.confidence_threshold":            kindFloat,
+	"clusters.max_alternatives":                kindInt,
+	"clusters.aliases":                         kindMap,
+	"clusters.registry":                        kindList,
+	"clusters.file":                            kindString,
+	"clusters.credentials.enabled":             kindBool,
+	"clusters.credentials.check_interval":      kindDuration,
+	"clusters.credentials.refresh_before":      kindDuration,
+	"clusters.credentials.warn_before":         kindDuration,
+	"clusters.credentials.rotations":           kindList,
+	"redaction.enabled":                        kindBool,
+	"redaction.fields":                         kindList,
+	"redaction.rules":                          kindList,
+	"tracing.enabled":                          kindBool,
+	"tracing.endpoint":                         kindString,
+	"tracing.insecure":                         kindBool,
+	"tracing.headers":                          kindMap,
+	"tracing.service_name":                     kindString,
+	"tracing.sample_ratio":                     kindFloat,
+	"config_repo.enabled":                      kindBool,
+	"config_repo.url":                          kindString,
+	"config_repo.ref":                          kindString,
+	"config_repo.dir":                          kindString,
+	"config_repo.interval":                     kindDuration,
+	"config_repo.webhook_secret":               kindString,
+	"config_repo.files.clusters":               kindString,
+	"config_repo.files.services":               kindString,
+	"sessions.idle_timeout":                    kindDuration,
+	"sessions.max_turns":                       kindInt,
+	"sessions.max_sessions":                    kindInt,
+	"sessions.history_turns":                   kindInt,
+	"sessions.progress_interval":               kindDuration,
+	"answer_cache.enabled":                     kindBool,
+	"answer_cache.ttl":                         kindDuration,
+	"answer_cache.max_entries":                 kindInt,
+	"answer_cache.redis.addr":                  kindString,
+	"answer_cache.redis.password":              kindString,
+	"answer_cache.redis.db":                    kindInt,
+	"perf.resources.cpu_warn":                  kindDuration,
+	"perf.resources.heap_warn_bytes":           kindInt,
+	"perf.resources.goroutine_warn":            kindInt,
+	"answer.language":                          kindString,
+	"llm.budget.per_request":                   kindInt,
+	"llm.budget.per_user_daily":                kindInt,
+	"llm.budget.observation_tokens":            kindInt,
+	"answer.review.enabled":                    kindBool,
+	"answer.review.provider":                   kindString,
+	"answer.review.model":                      kindString,
+	"answer.footer.enabled":                    kindBool,
+	"answer.footer.timezone":                   kindString,
+	"answer.footer.template":                   kindString,
+	"prompts.ttl":                              kindDuration,
+	"prompts.refresh_before":                   kindDuration,
+	"prompts.sources":                          kindMap,
+	"prompts.services":                         kindList,
+	"prompts.git_dir":                          kindString,
+	"prompts.prewarm.enabled":                  kindBool,
+	"prompts.prewarm.timeout":                  kindDuration,
+	"prompts.prewarm.interval":                 kindDuration,
+	"prompts.context_aware":                    kindBool,
+	"prompts.aliases_file":                     kindString,
+	"prompts.alias_min_occurrences":            kindInt,
+	"prompts.service_confidence_threshold":     kindFloat,
+	"chaos.enabled":                            kindBool,
+	"chaos.seed":                               kindInt,
+	"chaos.llm_error_rate":                     kindFloat,
+	"chaos.tool_error_rate":                    kindFloat,
+	"chaos.db_error_rate":                      kindFloat,
+	"events.notify.types":                      kindList,
+	"events.notify.channel":                    kindString,
+	"events.notify.cooldown":                   kindDuration,
+}
+
+const defaultJWTKey = "your-secret-key-please-change-in-production"
+
+// IsDefaultJWTKey 判断 JWT 密钥是否未设置或仍为配置文件中公开的默认值
+func IsDefaultJWTKey(key string) bool {
+	return key == "" || key == defaultJWTKey
+}
+
+// ValidateConfig 校验配置，返回未知配置项、缺失的必填项、类型错误以及参数冲突
+// overrides 为命令行显式设置的参数，键为对应的配置项
+func ValidateConfig(v *viper.Viper, overrides map[string]string) []ConfigIssue {
+	var issues []ConfigIssue
+	add := func(level, key, format string, args ...interface{}) {
+		issues = append(issues, ConfigIssue{Level: level, Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if v.ConfigFileUsed() == "" {
+		add(ConfigIssueWarning, "config", "未找到配置文件 configs/config.yaml，使用默认配置")
+	}
+
+	// 未知配置项
+	for _, key := range v.AllKeys() {
+		if _, ok := knownConfigKeys[key]; ok || isMapChild(key) {
+			continue
+		}
+		if suggestion := suggestConfigKey(key); suggestion != "" {
+			add(ConfigIssueWarning, key, "未知的配置项，是否想使用 %s？", suggestion)
+		} else {
+			add(ConfigIssueWarning, key, "未知的配置项，将被忽略")
+		}
+	}
+
+	// 类型校验
+	for key, kind := range knownConfigKeys {
+		if !v.IsSet(key) {
+			continue
+		}
+		if err := checkConfigKind(v.Get(key), kind); err != nil {
+			add(ConfigIssueError, key, "取值无效（%v），请设置为 %s 类型", err, kind)
+		}
+	}
+
+	// 取值校验
+	switch level := v.GetString("log.level"); level {
+	case "debug", "info", "warn", "error":
+	default:
+		add(ConfigIssueError, "log.level", "不支持的日志级别 %q，可选值: debug, info, warn, error", level)
+	}
+	switch shipper := strings.ToLower(v.GetString("log.shipper.type")); shipper {
+	case "", LogShipperLoki, LogShipperS3:
+	default:
+		add(ConfigIssueError, "log.shipper.type", "不支持的日志外发类型 %q，可选值: loki, s3", shipper)
+	}
+	switch backend := strings.ToLower(v.GetString("tools.logs.backend")); backend {
+	case "", "loki", "elasticsearch":
+	default:
+		add(ConfigIssueError, "tools.logs.backend", "不支持的日志后端 %q，可选值: loki, elasticsearch", backend)
+	}
+	switch platform := strings.ToLower(v.GetString("approvals.im.platform")); platform {
+	case "", "dingtalk", "wecom":
+	default:
+		add(ConfigIssueError, "approvals.im.platform", "不支持的审批 IM 平台 %q，可选值: dingtalk, wecom", platform)
+	}
+	switch mode := v.GetString("llm.cassette.mode"); mode {
+	case "", "off", "record", "replay", "auto":
+	default:
+		add(ConfigIssueError, "llm.cassette.mode", "不支持的录制模式 %q，可选值: off, record, replay, auto", mode)
+	}
+	switch mode := strings.ToLower(v.GetString("llm.tool_calling")); mode {
+	case "", "prompt", "native":
+	default:
+		add(ConfigIssueError, "llm.tool_calling", "不支持的工具调用模式 %q，可选值: prompt, native", mode)
+	}
+	if lang := strings.ToLower(v.GetString("answer.language")); lang != "" && lang != "cn" &&
+		!strings.HasPrefix(lang, "zh") && !strings.HasPrefix(lang, "en") {
+		add(ConfigIssueError, "answer.language", "不支持的回答语言 %q，可选值: zh, en", lang)
+	}
+	if _, err := time.LoadLocation(v.GetString("answer.footer.timezone")); err != nil {
+		add(ConfigIssueError, "answer.footer.timezone", "时区无效：%v", err)
+	}
+	if text := v.GetString("answer.footer.template"); text != "" {
+		if _, err := template.New("footer").Parse(text); err != nil {
+			add(ConfigIssueError, "answer.footer.template", "来源说明模板无效：%v", err)
+		}
+	}
+	for _, cidr := range v.GetStringSlice("llm.deidentify.ip_ranges") {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+			add(ConfigIssueError, "llm.deidentify.ip_ranges", "网段格式无效 %q，请使用 CIDR 格式，例如 10.0.0.0/8", cidr)
+		}
+	}
+	for name, pattern := range v.GetStringMapString("llm.deidentify.patterns") {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add(ConfigIssueError, "llm.deidentify.patterns."+name, "正则表达式无效: %v", err)
+		}
+	}
+	if threshold := v.GetFloat64("clusters.confidence_threshold"); threshold < 0 || threshold > 1 {
+		add(ConfigIssueError, "clusters.confidence_threshold", "置信度阈值 %v 超出范围 0-1", threshold)
+	}
+	var rotations []struct {
+		Context string   `mapstructure:"context"`
+		Command string   `mapstructure:"command"`
+		Plugin  []string `mapstructure:"plugin"`
+	}
+	if err := v.UnmarshalKey("clusters.credentials.rotations", &rotations); err != nil {
+		add(ConfigIssueError, "clusters.credentials.rotations", "格式无效: %v", err)
+	}
+	for i, rotation := range rotations {
+		key := fmt.Sprintf("clusters.credentials.rotations[%d]", i)
+		if rotation.Context == "" {
+			add(ConfigIssueError, key, "缺少 context")
+		}
+		if (rotation.Command == "") == (len(rotation.Plugin) == 0) {
+			add(ConfigIssueError, key, "command 和 plugin 需要且只能配置一个")
+		}
+	}
+	var redactionRules []struct {
+		Name    string `mapstructure:"name"`
+		Pattern string `mapstructure:"pattern"`
+	}
+	if err := v.UnmarshalKey("redaction.rules", &redactionRules); err != nil {
+		add(ConfigIssueError, "redaction.rules", "格式无效: %v", err)
+	}
+	for i, rule := range redactionRules {
+		key := fmt.Sprintf("redaction.rules[%d]", i)
+		if rule.Pattern == "" {
+			add(ConfigIssueError, key, "缺少 pattern")
+		} else if _, err := regexp.Compile(rule.Pattern); err != nil {
+			add(ConfigIssueError, key, "正则表达式无效: %v", err)
+		}
+	}
+	if v.IsSet("redaction.enabled") && !v.GetBool("redaction.enabled") {
+		add(ConfigIssueWarning, "redaction.enabled", "已关闭脱敏，工具输出中的 Secret 数据和令牌会原样发送给 LLM 并写入审计")
+	}
+	if ratio := v.GetFloat64("tracing.sample_ratio"); ratio < 0 || ratio > 1 {
+		add(ConfigIssueError, "tracing.sample_ratio", "采样比例 %v 超出范围 0-1", ratio)
+	}
+	if v.GetBool("config_repo.enabled") && v.GetString("config_repo.url") == "" {
+		add(ConfigIssueError, "config_repo.url", "启用配置仓库时必须设置仓库地址")
+	}
+	for _, key := range []string{"config_repo.files.clusters", "config_repo.files.services"} {
+		if file := v.GetString(key); filepath.IsAbs(file) || strings.HasPrefix(filepath.Clean(file), "..") {
+			add(ConfigIssueError, key, "文件路径 %q 必须是仓库内的相对路径", file)
+		}
+	}
+	if threshold := v.GetFloat64("handoff.confidence_threshold"); threshold < 0 || threshold > 1 {
+		add(ConfigIssueError, "handoff.confidence_threshold", "置信度阈值 %v 超出范围 0-1", threshold)
+	}
+	if sunset := v.GetString("api.legacy.sunset"); sunset != "" {
+		if _, err := time.Parse("2006-01-02", sunset); err != nil {
+			add(ConfigIssueError, "api.legacy.sunset", "日期格式无效 %q，请使用 YYYY-MM-DD", sunset)
+		}
+	}
+	if port := v.GetInt("server.port"); port <= 0 || port > 65535 {
+		add(ConfigIssueError, "server.port", "端口 %d 超出范围 1-65535", port)
+	}
+	if v.GetBool("chaos.enabled") && IsProductionEnvironment(v.GetString("server.environment")) {
+		add(ConfigIssueError, "chaos.enabled", "故障注入只能在非生产环境启用，请将 server.environment 设置为 staging 等非生产环境")
+	}
+	for _, key := range []string{"chaos.llm_error_rate", "chaos.tool_error_rate", "chaos.db_error_rate"} {
+		if rate := v.GetFloat64(key); rate < 0 || rate > 1 {
+			add(ConfigIssueError, key, "失败比例 %v 超出范围 0-1", rate)
+		}
+	}
+	for _, eventType := range v.GetStringSlice("events.notify.types") {
+		switch eventType {
+		case "iteration_started", "tool_selected", "tool_completed", "parse_failed", "final_answer":
+		default:
+			add(ConfigIssueError, "events.notify.types", "不支持的事件类型 %q，可选值: iteration_started, tool_selected, tool_completed, parse_failed, final_answer", eventType)
+		}
+	}
+
+	// 必填项
+	// 默认密钥随配置文件公开，任何人都可以用它签发管理员 token
+	jwtKey := overrides["jwt.key"]
+	if jwtKey == "" {
+		jwtKey = v.GetString("jwt.key")
+	}
+	if IsDefaultJWTKey(jwtKey) {
+		add(ConfigIssueError, "jwt.key", "未设置 JWT 密钥或仍在使用默认密钥，请通过 --jwt-key 或 %s 设置", ConfigEnvName("jwt.key"))
+	}
+	switch driver := strings.ToLower(v.GetString("audit.driver")); driver {
+	case "", "postgres", "postgresql", "pgx", "sqlite", "sqlite3", "mysql":
+	default:
+		add(ConfigIssueError, "audit.driver", "不支持的审计数据库驱动 %q，可选值: postgres, sqlite, mysql", driver)
+	}
+	if v.GetBool("audit.enabled") && v.GetString("audit.dsn") == "" {
+		add(ConfigIssueError, "audit.dsn", "启用审计时必须设置数据库连接串，或设置 audit.enabled=false")
+	}
+	if v.GetBool("snapshots.enabled") && !v.GetBool("audit.enabled") {
+		add(ConfigIssueError, "snapshots.enabled", "集群快照保存在审计数据库中，需要同时启用 audit.enabled")
+	}
+	if endpoints, ok := v.Get("runners.endpoints").([]interface{}); ok && len(endpoints) > 0 {
+		for _, key := range []string{"runners.tls.cert_file", "runners.tls.key_file", "runners.tls.ca_file"} {
+			if v.GetString(key) == "" {
+				add(ConfigIssueError, key, "配置了 runners.endpoints 时必须设置 mTLS 证书")
+			}
+		}
+	}
+	if v.GetBool("receipts.enabled") {
+		if v.GetString("receipts.key_file") == "" {
+			add(ConfigIssueError, "receipts.key_file", "启用执行回执时必须设置签名私钥")
+		}
+		if !v.GetBool("audit.enabled") {
+			add(ConfigIssueError, "receipts.enabled", "执行回执随审计记录保存，需要同时启用 audit.enabled")
+		}
+	}
+	switch runtime := v.GetString("tools.python.sandbox.runtime"); runtime {
+	case "", "docker", "podman":
+	case "process":
+		if v.GetBool("tools.python.sandbox.enabled") && v.GetString("tools.python.sandbox.cgroup") == "" {
+			add(ConfigIssueError, "tools.python.sandbox.cgroup", "Python 沙箱运行方式为 process 时必须设置 cgroup v2 目录用于限制资源，或改用 docker、podman")
+		}
+	default:
+		add(ConfigIssueError, "tools.python.sandbox.runtime", "不支持的 Python 沙箱运行方式 %q，可选值: process, docker, podman", runtime)
+	}
+	if v.GetBool("kube_audit.enabled") && !v.GetBool("audit.enabled") {
+		add(ConfigIssueError, "kube_audit.enabled", "集群审计日志保存在审计数据库中，需要同时启用 audit.enabled")
+	}
+	if threshold := v.GetFloat64("compliance.quota_threshold"); threshold < 0 || threshold > 1 {
+		add(ConfigIssueError, "compliance.quota_threshold", "配额告警阈值 %v 超出范围 0-1", threshold)
+	}
+	if _, err := time.LoadLocation(v.GetString("restarts.timezone")); err != nil {
+		add(ConfigIssueError, "restarts.timezone", "时区无效：%v", err)
+	}
+	if v.GetDuration("answer_cache.ttl") < 0 {
+		add(ConfigIssueError, "answer_cache.ttl", "回答缓存有效期不能为负数")
+	}
+	if v.GetBool("apikeys.enabled") && v.GetString("llm.api_key") == "" && os.Getenv("OPENAI_API_KEY") == "" && v.GetString("llm.cassette.mode") != "replay" {
+		add(ConfigIssueError, "llm.api_key", "启用托管 API Key 时必须设置 llm.api_key 或环境变量 OPENAI_API_KEY")
+	}
+
+	// HTTP 客户端：代理地址、CA 证书等需要能成功创建客户端
+	httpClients := []string{""}
+	for name := range v.GetStringMap("http_client.clients") {
+		httpClients = append(httpClients, name)
+	}
+	sort.Strings(httpClients)
+	for _, name := range httpClients {
+		key := "http_client"
+		if name != "" {
+			key = "http_client.clients." + name
+		}
+		cfg, err := LoadHTTPClientConfig(name)
+		if err == nil {
+			_, err = cfg.NewClient()
+		}
+		if err != nil {
+			add(ConfigIssueError, key, "无法创建 HTTP 客户端: %v", err)
+		}
+	}
+
+	// 参数冲突：命令行参数优先于环境变量和配置文件
+	keys := make([]string, 0, len(overrides))
+	for key := range overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := overrides[key]
+		env := ConfigEnvName(key)
+		if envValue, ok := os.LookupEnv(env); ok && envValue != value {
+			add(ConfigIssueWarning, key, "命令行参数与环境变量 %s 取值不同，将使用命令行参数", env)
+		} else if v.InConfig(key) && cast.ToString(v.Get(key)) != value {
+			add(ConfigIssueWarning, key, "命令行参数与配置文件取值不同，将使用命令行参数")
+		}
+	}
+	if key := v.GetString("llm.api_key"); key != "" && os.Getenv("OPENAI_API_KEY") != "" && os.Getenv("OPENAI_API_KEY") != key {
+		add(ConfigIssueWarning, "llm.api_key", "与环境变量 OPENAI_API_KEY 取值不同，将使用 llm.api_key")
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Level != issues[j].Level {
+			return issues[i].Level == ConfigIssueError
+		}
+		return issues[i].Key < issues[j].Key
+	})
+	return issues
+}
+
+// HasConfigErrors 是否存在错误级别的问题
+func HasConfigErrors(issues []ConfigIssue) bool {
+	for _, issue := range issues {
+		if issue.Level == ConfigIssueError {
+			return true
+		}
+	}
+	return false
+}
+
+// isMapChild 判断配置项是否为 map 类型配置项的子键
+func isMapChild(key string) bool {
+	for known, kind := range knownConfigKeys {
+		if kind == kindMap && strings.HasPrefix(key, known+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConfigKind 校验配置取值类型
+func checkConfigKind(value interface{}, kind string) error {
+	var err error
+	switch kind {
+	case kindString:
+		_, err = cast.ToStringE(value)
+	case kindInt:
+		_, err = cast.ToIntE(value)
+	case kindFloat:
+		_, err = cast.ToFloat64E(value)
+	case kindBool:
+		_, err = cast.ToBoolE(value)
+	case kindDuration:
+		if s, ok := value.(string); ok {
+			_, err = time.ParseDuration(s)
+		} else {
+			_, err = cast.ToDurationE(value)
+		}
+	case kindList:
+		_, err = cast.ToStringSliceE(value)
+	case kindMap:
+		_, err = cast.ToStringMapE(value)
+	}
+	return err
+}
+
+// suggestConfigKey 为拼写错误的配置项推荐最相近的已知配置项
+func suggestConfigKey(key string) string {
+	best, bestDistance := "", 3
+	for known := range knownConfigKeys {
+		if d := Levenshtein(key, known); d < bestDistance || (d == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// Levenshtein 计算两个字符串的编辑距离
+func Levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidateConfigJWTKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		overrides map[string]string
+		wantError bool
+	}{
+		{name: "empty", key: "", wantError: true},
+		{name: "default", key: defaultJWTKey, wantError: true},
+		{name: "default overridden by flag", key: defaultJWTKey, overrides: map[string]string{"jwt.key": "s3cret"}},
+		{name: "default passed by flag", key: "s3cret", overrides: map[string]string{"jwt.key": defaultJWTKey}, wantError: true},
+		{name: "configured", key: "s3cret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("jwt.key", tt.key)
+			var gotError bool
+			for _, issue := range ValidateConfig(v, tt.overrides) {
+				if issue.Key == "jwt.key" && issue.Level == ConfigIssueError {
+					gotError = true
+				}
+			}
+			if gotError != tt.wantError {
+				t.Errorf("jwt.key error = %v, want %v", gotError, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// gitLocks 每个工作目录一把锁，避免并发的 clone/fetch 互相破坏工作目录
+var gitLocks sync.Map
+
+// SyncGitRepo 将仓库 ref（分支、标签或提交，为空时使用默认分支）的最新提交同步到 dir，返回提交哈希
+// 首次浅克隆，之后拉取并强制重置到最新提交，工作目录中的本地修改会被丢弃
+func SyncGitRepo(ctx context.Context, repo, ref, dir string) (string, error) {
+	lock, _ := gitLocks.LoadOrStore(filepath.Clean(dir), &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		args := []string{"clone", "--depth", "1"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		os.RemoveAll(dir)
+		if err := runGit(ctx, "", append(args, repo, dir)...); err != nil {
+			return "", err
+		}
+	} else {
+		if ref == "" {
+			ref = "HEAD"
+		}
+		if err := runGit(ctx, dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+			return "", err
+		}
+		if err := runGit(ctx, dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("读取 Git 提交失败: %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// runGit 执行 git 命令，禁止交互式输入凭据，失败时返回命令输出
+func runGit(ctx context.Context, dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s 失败: %v: %s", args[len(args)-1], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
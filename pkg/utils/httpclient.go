@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// HTTPClientConfig HTTP 客户端传输层配置
+type HTTPClientConfig struct {
+	Timeout             time.Duration `mapstructure:"timeout"`               // 整个请求的超时时间
+	DialTimeout         time.Duration `mapstructure:"dial_timeout"`          // 建立 TCP 连接的超时时间
+	TLSHandshakeTimeout time.Duration `mapstructure:"tls_handshake_timeout"` // TLS 握手超时时间
+	KeepAlive           time.Duration `mapstructure:"keep_alive"`            // TCP keep-alive 间隔
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`     // 空闲连接保留时间
+	MaxIdleConns        int           `mapstructure:"max_idle_conns"`        // 最大空闲连接数
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	HTTP2               bool          `mapstructure:"http2"`                // 是否尝试 HTTP/2
+	Proxy               string        `mapstructure:"proxy"`                // 代理地址，为空时使用 HTTPS_PROXY 等环境变量
+	CAFile              string        `mapstructure:"ca_file"`              // 额外信任的 CA 证书（企业内网代理等）
+	InsecureSkipVerify  bool          `mapstructure:"insecure_skip_verify"` // 跳过证书校验，仅用于测试环境
+}
+
+// DefaultHTTPClientConfig 返回默认的 HTTP 客户端配置
+func DefaultHTTPClientConfig() HTTPClientConfig {
+	return HTTPClientConfig{
+		Timeout:             120 * time.Second,
+		DialTimeout:         10 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		KeepAlive:           30 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		HTTP2:               true,
+	}
+}
+
+// LoadHTTPClientConfig 加载指定客户端的配置
+// 先读取 http_client 中的公共配置，再用 http_client.clients.<name> 覆盖
+func LoadHTTPClientConfig(name string) (HTTPClientConfig, error) {
+	cfg := DefaultHTTPClientConfig()
+	config := GetConfig()
+
+	for _, key := range []string{"http_client", "http_client.clients." + name} {
+		if !config.IsSet(key) {
+			continue
+		}
+		if err := config.UnmarshalKey(key, &cfg); err != nil {
+			return cfg, fmt.Errorf("解析 %s 失败: %v", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// NewHTTPClient 根据配置创建指定用途的 HTTP 客户端，例如 llm、prompt_cache
+func NewHTTPClient(name string) (*http.Client, error) {
+	cfg, err := LoadHTTPClientConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.NewClient()
+}
+
+// NewClient 根据配置创建 HTTP 客户端
+func (c HTTPClientConfig) NewClient() (*http.Client, error) {
+	proxy := http.ProxyFromEnvironment
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("无效的代理地址 %s: %v", c.Proxy, err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 证书 %s 中没有有效的证书", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	dialer := &net.Dialer{Timeout: c.DialTimeout, KeepAlive: c.KeepAlive}
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   c.TLSHandshakeTimeout,
+		IdleConnTimeout:       c.IdleConnTimeout,
+		MaxIdleConns:          c.MaxIdleConns,
+		MaxIdleConnsPerHost:   c.MaxIdleConnsPerHost,
+		ForceAttemptHTTP2:     c.HTTP2,
+		ExpectContinueTimeout: time.Second,
+	}
+	if !c.HTTP2 {
+		// 非 nil 的空映射会禁用 HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{Transport: transport, Timeout: c.Timeout}, nil
+}
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"sort"
+	"sync"
+)
+
+// LLM 响应的 JSON 解析路径，依次尝试，越靠后说明模型输出越偏离约定格式
+const (
+	ParsePathStandard     = "standard"      // 标准 JSON 解析
+	ParsePathExtractField = "extract_field" // ExtractField 按字段提取
+	ParsePathCleanJSON    = "clean_json"    // CleanJSON 清理后解析
+	ParsePathGenericMap   = "generic_map"   // 解析为通用 map 后提取
+	ParsePathRaw          = "raw"           // 所有方法均失败，返回原始响应
+)
+
+// ParsePathCount 某个模型走某条解析路径的次数
+type ParsePathCount struct {
+	Model string `json:"model"`
+	Path  string `json:"path"`
+	Count int64  `json:"count"`
+}
+
+// ParsePathSummary 某个模型的解析路径分布和回退比例（非 standard 路径的占比）
+type ParsePathSummary struct {
+	Model        string           `json:"model"`
+	Total        int64            `json:"total"`
+	Paths        map[string]int64 `json:"paths"`
+	FallbackRate float64          `json:"fallback_rate"`
+}
+
+type parsePathKey struct {
+	model string
+	path  string
+}
+
+// parsePathStats 解析路径计数，作为 Prometheus 计数器导出，不随性能统计重置
+var parsePathStats = struct {
+	mu     sync.Mutex
+	counts map[parsePathKey]int64
+}{counts: map[parsePathKey]int64{}}
+
+// RecordParsePath 记录一次响应解析使用的路径
+func RecordParsePath(model, path string) {
+	if model == "" {
+		model = "unknown"
+	}
+	parsePathStats.mu.Lock()
+	parsePathStats.counts[parsePathKey{model: model, path: path}]++
+	parsePathStats.mu.Unlock()
+}
+
+// ParsePathCounts 返回各模型各解析路径的次数，按模型和路径排序
+func ParsePathCounts() []ParsePathCount {
+	parsePathStats.mu.Lock()
+	counts := make([]ParsePathCount, 0, len(parsePathStats.counts))
+	for key, count := range parsePathStats.counts {
+		counts = append(counts, ParsePathCount{Model: key.model, Path: key.path, Count: count})
+	}
+	parsePathStats.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Model != counts[j].Model {
+			return counts[i].Model < counts[j].Model
+		}
+		return counts[i].Path < counts[j].Path
+	})
+	return counts
+}
+
+// ParsePathSummaries 按模型汇总解析路径分布和回退比例
+func ParsePathSummaries() []ParsePathSummary {
+	var summaries []ParsePathSummary
+	for _, count := range ParsePathCounts() {
+		if n := len(summaries); n == 0 || summaries[n-1].Model != count.Model {
+			summaries = append(summaries, ParsePathSummary{Model: count.Model, Paths: map[string]int64{}})
+		}
+		summary := &summaries[len(summaries)-1]
+		summary.Paths[count.Path] = count.Count
+		summary.Total += count.Count
+	}
+	for i := range summaries {
+		if summaries[i].Total > 0 {
+			summaries[i].FallbackRate = float64(summaries[i].Total-summaries[i].Paths[ParsePathStandard]) / float64(summaries[i].Total)
+		}
+	}
+	return summaries
+}
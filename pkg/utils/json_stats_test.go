@@ -0,0 +1,21 @@
+package utils
+
+import "testing"
+
+func TestParsePathSummaries(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		RecordParsePath("parse-test-model", ParsePathStandard)
+	}
+	RecordParsePath("parse-test-model", ParsePathCleanJSON)
+
+	for _, summary := range ParsePathSummaries() {
+		if summary.Model != "parse-test-model" {
+			continue
+		}
+		if summary.Total != 4*summary.Paths[ParsePathCleanJSON] || summary.FallbackRate != 0.25 {
+			t.Errorf("unexpected summary %+v", summary)
+		}
+		return
+	}
+	t.Fatal("summary for parse-test-model not found")
+}
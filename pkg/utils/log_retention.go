@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logRetentionInterval = time.Hour
+	// shippedIndexFile 记录已上传到对象存储的归档文件，避免重复上传
+	shippedIndexFile = ".shipped"
+)
+
+var (
+	retentionOnce sync.Once
+	retentionMu   sync.Mutex
+)
+
+// startLogRetention 立即执行一次日志清理，并每小时按当前日志配置重复执行
+// lumberjack 只管理当天的日志文件，按天拆分后的历史文件由这里压缩、外发和清理
+func startLogRetention(config *LogConfig, current string) {
+	go enforceLogRetention(config, current)
+	retentionOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(logRetentionInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				rotateMutex.Lock()
+				config, current := activeLogConfig, currentLogFile
+				rotateMutex.Unlock()
+				if config != nil {
+					enforceLogRetention(config, current)
+				}
+			}
+		}()
+	})
+}
+
+// enforceLogRetention 压缩历史日志、上传归档，并按保留天数和总大小上限删除最旧的文件
+// current 为当天的日志文件名，当天文件及其 lumberjack 备份由 lumberjack 自行处理
+func enforceLogRetention(config *LogConfig, current string) {
+	retentionMu.Lock()
+	defer retentionMu.Unlock()
+
+	currentBase := strings.TrimSuffix(current, filepath.Ext(current))
+	if config.Compress {
+		for _, file := range listLogFiles(config.LogDir) {
+			name := filepath.Base(file.path)
+			if strings.HasSuffix(name, ".log") && !strings.HasPrefix(name, currentBase) {
+				if err := gzipFile(file.path); err != nil {
+					shipperError("compress %s failed: %v", file.path, err)
+				}
+			}
+		}
+	}
+
+	if config.Shipper.Type == LogShipperS3 {
+		shipArchives(config)
+	}
+
+	files := listLogFiles(config.LogDir)
+	if config.MaxAge > 0 {
+		cutoff := time.Now().AddDate(0, 0, -config.MaxAge)
+		kept := files[:0]
+		for _, file := range files {
+			if file.modTime.Before(cutoff) && filepath.Base(file.path) != current {
+				os.Remove(file.path)
+				continue
+			}
+			kept = append(kept, file)
+		}
+		files = kept
+	}
+	if config.MaxTotalSize > 0 {
+		limit := int64(config.MaxTotalSize) * 1024 * 1024
+		var total int64
+		for _, file := range files {
+			total += file.size
+		}
+		// 从最旧的文件开始删除，当天正在写入的文件不删除
+		for _, file := range files {
+			if total <= limit {
+				break
+			}
+			if filepath.Base(file.path) == current {
+				continue
+			}
+			if err := os.Remove(file.path); err == nil {
+				total -= file.size
+			}
+		}
+		if total > limit {
+			shipperError("log directory %s uses %d bytes, above the %d MB cap", config.LogDir, total, config.MaxTotalSize)
+		}
+	}
+}
+
+type logFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// listLogFiles 返回日志目录下的日志和归档文件，按修改时间从旧到新排序
+func listLogFiles(dir string) []logFile {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var files []logFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz")) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logFile{path: filepath.Join(dir, name), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	return files
+}
+
+// gzipFile 将文件压缩为 .gz 并删除原文件，保留原文件的修改时间用于按天数清理
+func gzipFile(filename string) error {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	target := filename + ".gz"
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err == nil {
+		err = gz.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(target)
+		return err
+	}
+	os.Chtimes(target, info.ModTime(), info.ModTime())
+	src.Close()
+	return os.Remove(filename)
+}
+
+// shipArchives 上传尚未上传的归档文件，成功的文件名记录在日志目录的 .shipped 中
+func shipArchives(config *LogConfig) {
+	uploader, err := newS3Uploader(config.Shipper)
+	if err != nil {
+		shipperError("%v", err)
+		return
+	}
+
+	indexPath := filepath.Join(config.LogDir, shippedIndexFile)
+	shipped := map[string]bool{}
+	if f, err := os.Open(indexPath); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			shipped[scanner.Text()] = true
+		}
+		f.Close()
+	}
+
+	index, err := os.OpenFile(indexPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		shipperError("open %s failed: %v", indexPath, err)
+		return
+	}
+	defer index.Close()
+
+	for _, file := range listLogFiles(config.LogDir) {
+		name := filepath.Base(file.path)
+		if !strings.HasSuffix(name, ".gz") || shipped[name] {
+			continue
+		}
+		if err := uploader.Upload(file.path); err != nil {
+			shipperError("upload %s failed: %v", file.path, err)
+			continue
+		}
+		index.WriteString(name + "\n")
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnforceLogRetention(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int, age time.Duration) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(-age)
+		os.Chtimes(path, modTime, modTime)
+	}
+	write("kube-copilot-20261001.log", 1024, 15*24*time.Hour)
+	write("kube-copilot-20261014.log.gz", 600*1024, 48*time.Hour)
+	write("kube-copilot-20261015.log", 4096, 24*time.Hour)
+	write("kube-copilot-20261016.log", 700*1024, 0)
+	write("kube-copilot-20261016-2026-10-16T08-00-00.000.log", 1024, time.Hour)
+
+	config := &LogConfig{LogDir: dir, MaxAge: 7, MaxTotalSize: 1, Compress: true}
+	enforceLogRetention(config, "kube-copilot-20261016.log")
+
+	var names []string
+	for _, file := range listLogFiles(dir) {
+		names = append(names, filepath.Base(file.path))
+	}
+	// 15 天前的文件超过保留天数；总大小超过 1MB 时删除最旧的归档；
+	// 昨天的文件被压缩，当天文件及其 lumberjack 备份不处理
+	want := []string{"kube-copilot-20261015.log.gz", "kube-copilot-20261016-2026-10-16T08-00-00.000.log", "kube-copilot-20261016.log"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("files after retention = %v, want %v", names, want)
+	}
+}
+
+func TestS3UploaderSignsRequest(t *testing.T) {
+	var path, auth, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer srv.Close()
+
+	archive := filepath.Join(t.TempDir(), "kube-copilot-20261015.log.gz")
+	os.WriteFile(archive, []byte("archive"), 0644)
+
+	uploader, err := newS3Uploader(LogShipperConfig{Type: LogShipperS3, URL: srv.URL, S3: S3ShipperConfig{
+		Bucket: "ops-logs", Region: "ap-east-1", Prefix: "opsagent/jump-1", AccessKeyID: "AKID", SecretAccessKey: "secret",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader.now = func() time.Time { return time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC) }
+	if err := uploader.Upload(archive); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/ops-logs/opsagent/jump-1/kube-copilot-20261015.log.gz" || body != "archive" {
+		t.Errorf("uploaded %q to %s", body, path)
+	}
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/20261016/ap-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
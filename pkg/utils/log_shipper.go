@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// 日志外发类型
+const (
+	LogShipperLoki = "loki" // 实时推送日志行到 Loki（/loki/api/v1/push）
+	LogShipperS3   = "s3"   // 压缩归档后的日志文件上传到 S3 兼容的对象存储
+)
+
+const (
+	defaultLokiBatchSize     = 500
+	defaultLokiFlushInterval = 5 * time.Second
+	lokiBufferSize           = 10000
+)
+
+// LogShipperConfig 日志外发配置，Type 为空时不外发
+type LogShipperConfig struct {
+	Type          string            `mapstructure:"type"`           // loki 或 s3
+	URL           string            `mapstructure:"url"`            // Loki 地址或 S3 endpoint
+	Labels        map[string]string `mapstructure:"labels"`         // Loki 日志流标签
+	BatchSize     int               `mapstructure:"batch_size"`     // Loki 单次推送的最大行数
+	FlushInterval time.Duration     `mapstructure:"flush_interval"` // Loki 推送间隔
+	S3            S3ShipperConfig   `mapstructure:"s3"`
+}
+
+// S3ShipperConfig S3 归档配置，访问密钥为空时读取 AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY
+type S3ShipperConfig struct {
+	Bucket          string `mapstructure:"bucket"`
+	Region          string `mapstructure:"region"`
+	Prefix          string `mapstructure:"prefix"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key"`
+}
+
+// LogShipperConfigFrom 从配置项 log.shipper 读取日志外发配置
+func LogShipperConfigFrom(v *viper.Viper) (LogShipperConfig, error) {
+	var cfg LogShipperConfig
+	if !v.IsSet("log.shipper") {
+		return cfg, nil
+	}
+	if err := v.UnmarshalKey("log.shipper", &cfg); err != nil {
+		return cfg, fmt.Errorf("解析 log.shipper 失败: %v", err)
+	}
+	cfg.Type = strings.ToLower(cfg.Type)
+	return cfg, nil
+}
+
+// shipperError 外发失败时输出到标准错误，不能写入日志系统（否则会再次触发外发）
+func shipperError(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "log shipper: "+format+"\n", args...)
+}
+
+// lokiWriter 将日志行批量推送到 Loki
+// 缓冲区满时丢弃日志行，外发故障不会阻塞业务日志
+type lokiWriter struct {
+	url           string
+	labels        map[string]string
+	batchSize     int
+	flushInterval time.Duration
+	client        *http.Client
+	lines         chan [2]string
+	dropped       atomic.Int64
+}
+
+var (
+	lokiWriterOnce sync.Once
+	lokiWriterInst *lokiWriter
+	lokiWriterErr  error
+)
+
+// getLokiWriter 返回全局 Loki 写入器，按天轮转重新初始化日志时复用，避免重复启动推送协程
+func getLokiWriter(cfg LogShipperConfig) (*lokiWriter, error) {
+	lokiWriterOnce.Do(func() {
+		lokiWriterInst, lokiWriterErr = newLokiWriter(cfg)
+	})
+	return lokiWriterInst, lokiWriterErr
+}
+
+// newLokiWriter 创建 Loki 写入器并启动后台推送
+func newLokiWriter(cfg LogShipperConfig) (*lokiWriter, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("log.shipper.url is required for loki")
+	}
+	client, err := NewHTTPClient("log_shipper")
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{"app": "opsagent"}
+	if hostname, err := os.Hostname(); err == nil {
+		labels["host"] = hostname
+	}
+	for k, v := range cfg.Labels {
+		labels[k] = v
+	}
+	w := &lokiWriter{
+		url:           strings.TrimSuffix(cfg.URL, "/") + "/loki/api/v1/push",
+		labels:        labels,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		client:        client,
+		lines:         make(chan [2]string, lokiBufferSize),
+	}
+	if w.batchSize <= 0 {
+		w.batchSize = defaultLokiBatchSize
+	}
+	if w.flushInterval <= 0 {
+		w.flushInterval = defaultLokiFlushInterval
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write 实现 zapcore.WriteSyncer，每次调用对应一条日志
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	line := [2]string{strconv.FormatInt(time.Now().UnixNano(), 10), strings.TrimRight(string(p), "\n")}
+	select {
+	case w.lines <- line:
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Sync 推送在后台异步进行，这里不等待
+func (w *lokiWriter) Sync() error {
+	return nil
+}
+
+func (w *lokiWriter) run() {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([][2]string, 0, w.batchSize)
+	for {
+		select {
+		case line := <-w.lines:
+			batch = append(batch, line)
+			if len(batch) < w.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if err := w.push(batch); err != nil {
+			shipperError("push %d lines to loki failed: %v", len(batch), err)
+		}
+		batch = batch[:0]
+		if dropped := w.dropped.Swap(0); dropped > 0 {
+			shipperError("dropped %d log lines because the loki buffer was full", dropped)
+		}
+	}
+}
+
+func (w *lokiWriter) push(batch [][2]string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"streams": []map[string]interface{}{{"stream": w.labels, "values": batch}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// s3Uploader 将归档的日志文件上传到 S3 兼容的对象存储（AWS Signature V4，path-style 地址）
+type s3Uploader struct {
+	endpoint string
+	cfg      S3ShipperConfig
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newS3Uploader(cfg LogShipperConfig) (*s3Uploader, error) {
+	if cfg.URL == "" || cfg.S3.Bucket == "" {
+		return nil, fmt.Errorf("log.shipper.url and log.shipper.s3.bucket are required for s3")
+	}
+	if cfg.S3.Region == "" {
+		cfg.S3.Region = "us-east-1"
+	}
+	if cfg.S3.AccessKeyID == "" {
+		cfg.S3.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if cfg.S3.SecretAccessKey == "" {
+		cfg.S3.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if cfg.S3.Prefix == "" {
+		if hostname, err := os.Hostname(); err == nil {
+			cfg.S3.Prefix = "opsagent/" + hostname
+		}
+	}
+	client, err := NewHTTPClient("log_shipper")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Uploader{endpoint: strings.TrimSuffix(cfg.URL, "/"), cfg: cfg.S3, client: client, now: time.Now}, nil
+}
+
+// Upload 上传文件，对象名为 <prefix>/<文件名>
+func (u *s3Uploader) Upload(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	key := path.Join(u.cfg.Prefix, path.Base(filename))
+	req, err := http.NewRequest(http.MethodPut, u.endpoint+"/"+path.Join(u.cfg.Bucket, key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	u.sign(req)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("upload %s returned status %d", key, resp.StatusCode)
+	}
+	return nil
+}
+
+// sign 按 AWS Signature V4 签名请求
+func (u *s3Uploader) sign(req *http.Request) {
+	SignS3Request(req, u.cfg.AccessKeyID, u.cfg.SecretAccessKey, u.cfg.Region, u.now())
+}
+
+// SignS3Request 按 AWS Signature V4 签名 S3 兼容存储（AWS S3、MinIO、OSS 的 S3 兼容接口）的请求，
+// 请求体不参与签名（UNSIGNED-PAYLOAD），accessKeyID 为空时不签名（匿名访问）
+func SignS3Request(req *http.Request, accessKeyID, secretAccessKey, region string, now time.Time) {
+	if accessKeyID == "" {
+		return
+	}
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:UNSIGNED-PAYLOAD\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, part := range []string{date, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
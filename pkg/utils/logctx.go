@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// 请求级日志字段名，统一各处的命名
+const (
+	LogFieldUser        = "user"
+	LogFieldSession     = "session"
+	LogFieldInteraction = "interaction_id"
+	LogFieldCluster     = "cluster"
+	LogFieldModel       = "model"
+	LogFieldRequest     = "request_id"
+)
+
+type loggerContextKey struct{}
+
+// WithLogger 将日志记录器放入上下文
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// LoggerFromContext 获取上下文中的请求级日志记录器，不存在时返回全局日志记录器
+func LoggerFromContext(ctx context.Context) *zap.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return GetLogger()
+}
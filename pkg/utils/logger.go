@@ -25,6 +25,8 @@ var (
 	lastRotateDate time.Time
 	// 日志轮转锁
 	rotateMutex sync.Mutex
+	// 当前生效的日志配置，按天轮转时沿用
+	activeLogConfig *LogConfig
 )
 
 // LogConfig 日志配置
@@ -41,8 +43,12 @@ type LogConfig struct {
 	MaxBackups int
 	// 保留的日志文件最大天数
 	MaxAge int
-	// 是否压缩旧日志文件
+	// 是否压缩旧日志文件（包括按天拆分的历史文件）
 	Compress bool
+	// 日志目录占用的磁盘空间上限，单位MB，超出时删除最旧的文件，0 表示不限制
+	MaxTotalSize int
+	// 日志外发（Loki / S3），Type 为空时不外发
+	Shipper LogShipperConfig
 	// 是否在控制台输出
 	ConsoleOutput bool
 	// 是否使用彩色日志
@@ -61,6 +67,7 @@ func DefaultLogConfig() *LogConfig {
 		MaxBackups:    10,
 		MaxAge:        7, // 7天
 		Compress:      true,
+		MaxTotalSize:  1024, // 1GB
 		ConsoleOutput: true,
 		ColoredOutput: true,
 	}
@@ -114,6 +121,10 @@ func InitLogger(config *LogConfig) (*zap.Logger, error) {
 		// 更新当前日志文件名和轮转时间
 		currentLogFile = filename
 		lastRotateDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		activeLogConfig = config
+
+		// 压缩、外发和清理历史日志，限制日志目录的磁盘占用
+		startLogRetention(config, filename)
 
 		// 创建 lumberjack 日志切割器
 		lumberjackLogger := &lumberjack.Logger{
@@ -166,6 +177,21 @@ func InitLogger(config *LogConfig) (*zap.Logger, error) {
 			cores = append(cores, consoleCore)
 		}
 
+		// 实时外发到 Loki，推送失败或缓冲区满时丢弃，不影响本地日志
+		if config.Shipper.Type == LogShipperLoki {
+			if writer, shipErr := getLokiWriter(config.Shipper); shipErr != nil {
+				shipperError("%v", shipErr)
+			} else {
+				lokiEncoderConfig := encoderConfig
+				lokiEncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+				cores = append(cores, zapcore.NewCore(
+					zapcore.NewJSONEncoder(lokiEncoderConfig),
+					writer,
+					config.Level,
+				))
+			}
+		}
+
 		// 合并所有核心
 		core := zapcore.NewTee(cores...)
 
@@ -178,12 +204,16 @@ func InitLogger(config *LogConfig) (*zap.Logger, error) {
 
 // GetLogger 获取全局日志记录器
 func GetLogger() *zap.Logger {
-	// 检查是否需要轮转日志文件
-	checkRotateLogger(DefaultLogConfig())
+	// 检查是否需要轮转日志文件，沿用初始化时的配置
+	config := activeLogConfig
+	if config == nil {
+		config = DefaultLogConfig()
+	}
+	checkRotateLogger(config)
 	
 	if globalLogger == nil {
 		// 如果尚未初始化，使用默认配置初始化
-		logger, err := InitLogger(DefaultLogConfig())
+		logger, err := InitLogger(config)
 		if err != nil {
 			// 如果初始化失败，使用标准输出的开发配置
 			config := zap.NewDevelopmentConfig()
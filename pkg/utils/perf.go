@@ -9,7 +9,7 @@ import (
 )
 
 // PerfStats 性能统计结构体
-// 用于收集和分析系统各个部分的性能数据
+// 用于收集和分析系统各个部分的性能数据，计时和计数同时导出为 Prometheus 指标（见 WritePrometheusMetrics）
 type PerfStats struct {
 	mu            sync.RWMutex
 	metrics       map[string][]time.Duration // 存储每个操作的耗时记录
@@ -19,6 +19,7 @@ type PerfStats struct {
 	timers        map[string]time.Duration
 	callCounts    map[string]int64
 	lastResetTime time.Time
+	resources     []ResourceUsage // 最近交互的资源消耗
 }
 
 // 全局性能统计实例
@@ -101,6 +102,7 @@ func (p *PerfStats) StopTimer(operation string) time.Duration {
 		p.metrics[operation] = []time.Duration{}
 	}
 	p.metrics[operation] = append(p.metrics[operation], elapsed)
+	recordOperation(operation, elapsed)
 	
 	if _, exists := p.timers[operation]; !exists {
 		p.timers[operation] = 0
@@ -129,6 +131,7 @@ func (p *PerfStats) RecordMetric(operation string, duration time.Duration) {
 		p.metrics[operation] = []time.Duration{}
 	}
 	p.metrics[operation] = append(p.metrics[operation], duration)
+	recordOperation(operation, duration)
 	
 	if p.enableLogging && p.logger != nil {
 		p.logger.Debug("记录性能指标",
@@ -138,6 +141,17 @@ func (p *PerfStats) RecordMetric(operation string, duration time.Duration) {
 	}
 }
 
+// IncrCounter 增加特定事件的调用次数
+// 参数：
+//   - name: 事件名称
+func (p *PerfStats) IncrCounter(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.callCounts[name]++
+	recordEvent(name)
+}
+
 // GetMetrics 获取所有性能指标
 // 返回：
 //   - map[string][]time.Duration: 所有操作的耗时记录
@@ -316,6 +330,9 @@ func (p *PerfStats) GetStats() map[string]interface{} {
 	// 添加最后重置时间
 	stats["lastResetTime"] = p.lastResetTime
 
+	// 添加交互资源记录数量，明细通过 TopResourceUsage 查询
+	stats["resourceRecords"] = len(p.resources)
+
 	return stats
 }
 
@@ -330,6 +347,9 @@ func (p *PerfStats) Reset() {
 	// 清空调用次数
 	p.callCounts = make(map[string]int64)
 	
+	// 清空交互资源记录
+	p.resources = nil
+
 	// 更新最后重置时间
 	p.lastResetTime = time.Now()
 } 
\ No newline at end of file
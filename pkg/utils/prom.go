@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets 耗时直方图的桶（秒），覆盖毫秒级的工具调用到分钟级的 LLM 多轮对话
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// histogram 以 Prometheus 直方图格式累计的耗时分布
+type histogram struct {
+	buckets []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(durationBuckets))
+	}
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// metricFamily 一个指标名下按标签区分的计数器或直方图
+type metricFamily struct {
+	name       string
+	help       string
+	kind       string // counter 或 histogram
+	labels     []string
+	counters   map[string]float64
+	histograms map[string]*histogram
+}
+
+// promRegistry 服务端指标，作为 Prometheus 指标导出，不随性能统计重置
+type promRegistry struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+var promMetrics = &promRegistry{families: map[string]*metricFamily{}}
+
+func (r *promRegistry) family(name, help, kind string, labels ...string) *metricFamily {
+	f, ok := r.families[name]
+	if !ok {
+		f = &metricFamily{name: name, help: help, kind: kind, labels: labels,
+			counters: map[string]float64{}, histograms: map[string]*histogram{}}
+		r.families[name] = f
+	}
+	return f
+}
+
+// labelValueEscaper 按 Prometheus 文本格式转义标签值，只转义反斜杠、双引号和换行
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelKey 将标签值编码为 Prometheus 标签字符串，同时作为 map 的键
+func (f *metricFamily) labelKey(values ...string) string {
+	pairs := make([]string, len(f.labels))
+	for i, label := range f.labels {
+		pairs[i] = label + `="` + labelValueEscaper.Replace(values[i]) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+// defaultMetricModels 未配置 perf.metric_models 时作为指标标签的模型
+var defaultMetricModels = []string{
+	"gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini", "gpt-3.5-turbo", "o1", "o1-mini", "o3-mini",
+	"claude-3-5-sonnet-latest", "claude-3-5-haiku-latest", "gemini-1.5-pro", "gemini-1.5-flash",
+	"qwen-max", "qwen-plus", "qwen-turbo",
+}
+
+// metricModel 返回模型的指标标签值：模型名由调用方指定，不在 perf.metric_models 中的模型记为 other，避免标签基数无限增长
+func metricModel(model string) string {
+	models := defaultMetricModels
+	if config := GetConfig(); config.IsSet("perf.metric_models") {
+		models = config.GetStringSlice("perf.metric_models")
+	}
+	for _, m := range models {
+		if strings.EqualFold(m, model) {
+			return m
+		}
+	}
+	return "other"
+}
+
+func (r *promRegistry) add(name, help string, value float64, labels []string, values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.family(name, help, "counter", labels...)
+	f.counters[f.labelKey(values...)] += value
+}
+
+func (r *promRegistry) observe(name, help string, d time.Duration, labels []string, values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.family(name, help, "histogram", labels...)
+	key := f.labelKey(values...)
+	h, ok := f.histograms[key]
+	if !ok {
+		h = &histogram{}
+		f.histograms[key] = h
+	}
+	h.observe(d.Seconds())
+}
+
+// RecordHTTPRequest 记录一次 HTTP 请求，route 为路由模板（如 /api/approvals/:id），避免路径参数导致指标过多
+func RecordHTTPRequest(method, route string, status int, d time.Duration) {
+	promMetrics.add("opsagent_http_requests_total", "HTTP requests handled, by method, route and status code.", 1,
+		[]string{"method", "route", "status"}, method, route, fmt.Sprint(status))
+	promMetrics.observe("opsagent_http_request_duration_seconds", "HTTP request latency, by method and route.", d,
+		[]string{"method", "route"}, method, route)
+}
+
+// RecordLLMUsage 记录一次 LLM 调用消耗的 token 数
+func RecordLLMUsage(provider, model string, promptTokens, completionTokens int) {
+	model = metricModel(model)
+	labels := []string{"provider", "model", "type"}
+	help := "LLM tokens consumed, by provider, model and token type (prompt, completion)."
+	promMetrics.add("opsagent_llm_tokens_total", help, float64(promptTokens), labels, provider, model, "prompt")
+	promMetrics.add("opsagent_llm_tokens_total", help, float64(completionTokens), labels, provider, model, "completion")
+	promMetrics.add("opsagent_llm_requests_total", "Successful LLM chat completions, by provider and model.", 1,
+		[]string{"provider", "model"}, provider, model)
+}
+
+// RecordToolCall 记录一次工具调用的耗时和结果
+func RecordToolCall(tool string, d time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	promMetrics.add("opsagent_tool_calls_total", "Tool invocations, by tool and result.", 1,
+		[]string{"tool", "status"}, tool, status)
+	promMetrics.observe("opsagent_tool_call_duration_seconds", "Tool invocation latency, by tool.", d,
+		[]string{"tool"}, tool)
+}
+
+// RecordAssistantEvent 记录一次 Assistant 循环事件，tool 仅在工具事件中不为空
+func RecordAssistantEvent(eventType, tool string) {
+	promMetrics.add("opsagent_assistant_events_total", "Assistant loop events, by type (iteration_started, tool_selected, tool_completed, parse_failed, final_answer) and tool.", 1,
+		[]string{"type", "tool"}, eventType, tool)
+}
+
+// RecordSystemPrompt 记录一次系统提示的 token 数，mode 为 full（完整提示）或 trimmed（按问题裁剪）
+// 两种模式的 tokens_total / prompts_total 之比即为平均每次调用的提示 token 数
+func RecordSystemPrompt(prompt, mode string, tokens int) {
+	labels := []string{"prompt", "mode"}
+	promMetrics.add("opsagent_system_prompt_tokens_total", "Estimated system prompt tokens sent to the LLM, by prompt and mode (full, trimmed).", float64(tokens), labels, prompt, mode)
+	promMetrics.add("opsagent_system_prompts_total", "System prompts rendered, by prompt and mode (full, trimmed).", 1, labels, prompt, mode)
+}
+
+// recordOperation 性能统计中的计时同时导出为直方图
+func recordOperation(operation string, d time.Duration) {
+	promMetrics.observe("opsagent_operation_duration_seconds", "Duration of internal operations tracked by the performance statistics.", d,
+		[]string{"operation"}, operation)
+}
+
+// recordEvent 性能统计中的计数同时导出为计数器
+func recordEvent(name string) {
+	promMetrics.add("opsagent_events_total", "Internal events counted by the performance statistics.", 1,
+		[]string{"name"}, name)
+}
+
+// WritePrometheusMetrics 以 Prometheus 文本格式写出服务端指标，按指标名和标签排序
+func WritePrometheusMetrics(w io.Writer) {
+	promMetrics.mu.Lock()
+	defer promMetrics.mu.Unlock()
+
+	names := make([]string, 0, len(promMetrics.families))
+	for name := range promMetrics.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := promMetrics.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+		if f.kind == "counter" {
+			for _, key := range sortedKeys(f.counters) {
+				fmt.Fprintf(w, "%s{%s} %v\n", f.name, key, f.counters[key])
+			}
+			continue
+		}
+		for _, key := range sortedKeys(f.histograms) {
+			h := f.histograms[key]
+			for i, bound := range durationBuckets {
+				fmt.Fprintf(w, "%s_bucket{%s,le=\"%v\"} %d\n", f.name, key, bound, h.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", f.name, key, h.count)
+			fmt.Fprintf(w, "%s_sum{%s} %v\n", f.name, key, h.sum)
+			fmt.Fprintf(w, "%s_count{%s} %d\n", f.name, key, h.count)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheusMetrics(t *testing.T) {
+	RecordHTTPRequest("POST", "/api/execute", 200, 300*time.Millisecond)
+	RecordLLMUsage("openai", "gpt-4o", 1200, 80)
+	RecordLLMUsage("openai", "my-finetune-\"x\"", 10, 1)
+	RecordToolCall("c:\\tools\n\"x\"", time.Millisecond, nil)
+	RecordToolCall("kubectl", 40*time.Millisecond, nil)
+	RecordToolCall("kubectl", 2*time.Second, errors.New("timeout"))
+	RecordAssistantEvent("tool_completed", "kubectl")
+	GetPerfStats().IncrCounter("llm_failover_openai")
+
+	var b strings.Builder
+	WritePrometheusMetrics(&b)
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE opsagent_http_requests_total counter",
+		`opsagent_http_requests_total{method="POST",route="/api/execute",status="200"} 1`,
+		`opsagent_http_request_duration_seconds_bucket{method="POST",route="/api/execute",le="0.25"} 0`,
+		`opsagent_http_request_duration_seconds_bucket{method="POST",route="/api/execute",le="0.5"} 1`,
+		`opsagent_llm_tokens_total{provider="openai",model="gpt-4o",type="prompt"} 1200`,
+		`opsagent_tool_calls_total{tool="kubectl",status="error"} 1`,
+		// 标签值只转义反斜杠、双引号和换行，未配置的模型记为 other
+		`opsagent_tool_calls_total{tool="c:\\tools\n\"x\"",status="success"} 1`,
+		`opsagent_llm_requests_total{provider="openai",model="other"} 1`,
+		`opsagent_tool_call_duration_seconds_count{tool="kubectl"} 2`,
+		`opsagent_assistant_events_total{type="tool_completed",tool="kubectl"} 1`,
+		`opsagent_events_total{name="llm_failover_openai"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,594 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 提示缓存默认配置
+const (
+	defaultPromptTTL           = 10 * time.Minute
+	defaultPromptRefreshBefore = time.Minute
+	// defaultPromptFileTTL 本地文件提示的检查间隔，文件修改后最迟在该间隔后生效
+	defaultPromptFileTTL = 5 * time.Second
+	// defaultPromptPrewarmTimeout 启动时预热提示的最长等待时间
+	defaultPromptPrewarmTimeout = 30 * time.Second
+)
+
+// PromptSource 提示的来源配置，url（HTTP/OSS 地址）、path（本地文件）、git（Git 仓库）三选一
+type PromptSource struct {
+	Name     string        `mapstructure:"name"` // 来源名称，例如 oss、git、local，记录在提示版本中
+	URL      string        `mapstructure:"url"`
+	Path     string        `mapstructure:"path"`
+	Git      string        `mapstructure:"git"`      // Git 仓库地址，需要安装 git 命令
+	Ref      string        `mapstructure:"ref"`      // Git 分支或标签，为空时使用默认分支
+	File     string        `mapstructure:"file"`     // 提示在 Git 仓库中的路径
+	Priority int           `mapstructure:"priority"` // 配置多个来源时按 priority 从小到大尝试
+	TTL      time.Duration `mapstructure:"ttl"`      // 为 0 时远程提示使用 prompts.ttl，本地文件每 5s 检查一次修改时间
+}
+
+// label 日志和状态中显示的来源名称
+func (s PromptSource) label() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case s.URL != "":
+		return s.URL
+	case s.Path != "":
+		return s.Path
+	}
+	return s.Git + "#" + s.Ref + ":" + s.File
+}
+
+func (s PromptSource) valid() bool {
+	return s.URL != "" || s.Path != "" || (s.Git != "" && s.File != "")
+}
+
+// PromptStatus 已缓存提示的状态
+type PromptStatus struct {
+	Name         string    `json:"name"`
+	Source       string    `json:"source,omitempty"` // 当前内容来自的来源
+	URL          string    `json:"url,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	Git          string    `json:"git,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Size         int       `json:"size"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+type promptEntry struct {
+	source       PromptSource // 当前内容来自的来源
+	content      string
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+	expiresAt    time.Time
+	lastError    string
+	refreshing   bool
+}
+
+// PromptCache 远程系统提示缓存
+// 特性：
+// 1. 按名称缓存多个提示，来源由 prompts.sources.<name>.url、path 或 git 配置，本地文件按修改时间热加载
+// 2. prompts.sources.<name>.sources 可以配置多个来源，按 priority 依次尝试，高优先级来源不可用时使用下一个
+// 3. 使用 ETag/If-Modified-Since 重新校验，未修改时只延长有效期；Git 来源以提交哈希作为 ETag
+// 4. 临近过期（prompts.refresh_before）时在后台刷新，请求不必等待下载
+// 5. 刷新失败时继续使用旧内容，避免远程服务故障影响请求
+type PromptCache struct {
+	mu      sync.Mutex
+	entries map[string]*promptEntry
+	client  *http.Client
+	now     func() time.Time
+	// fetchMu 保证同一时刻每个提示只有一个下载请求
+	fetchMu sync.Map
+}
+
+var (
+	promptCache     *PromptCache
+	promptCacheOnce sync.Once
+)
+
+// GetPromptCache 获取全局提示缓存
+func GetPromptCache() *PromptCache {
+	promptCacheOnce.Do(func() {
+		client, err := NewHTTPClient("prompt_cache")
+		if err != nil {
+			GetLogger().Warn("创建提示缓存 HTTP 客户端失败，使用默认客户端", zap.Error(err))
+			client = &http.Client{Timeout: 30 * time.Second}
+		}
+		promptCache = NewPromptCache(client)
+	})
+	return promptCache
+}
+
+// NewPromptCache 创建提示缓存
+func NewPromptCache(client *http.Client) *PromptCache {
+	return &PromptCache{
+		entries: map[string]*promptEntry{},
+		client:  client,
+		now:     time.Now,
+	}
+}
+
+// Lookup 获取指定名称的提示，未配置来源或从未成功下载时返回 false
+func (p *PromptCache) Lookup(ctx context.Context, name string) (string, bool) {
+	content, err := p.Get(ctx, name)
+	if err != nil {
+		return "", false
+	}
+	return content, true
+}
+
+// Get 获取指定名称的提示
+// 缓存有效时直接返回；临近过期时返回缓存并在后台刷新；已过期时同步刷新
+func (p *PromptCache) Get(ctx context.Context, name string) (string, error) {
+	sources, ok := promptSources(name)
+	if !ok {
+		return "", fmt.Errorf("未配置提示 %s 的来源", name)
+	}
+
+	now := p.now()
+	p.mu.Lock()
+	entry, cached := p.entries[name]
+	if cached && entry.fetchedAt.IsZero() {
+		cached = false
+	}
+	if cached && now.Before(entry.expiresAt) {
+		content := entry.content
+		if !entry.refreshing && !now.Before(entry.expiresAt.Add(-promptRefreshBefore())) {
+			entry.refreshing = true
+			go func() {
+				if err := p.refresh(context.Background(), name, sources); err != nil {
+					GetLogger().Warn("后台刷新提示失败", zap.String("name", name), zap.Error(err))
+				}
+			}()
+		}
+		p.mu.Unlock()
+		return content, nil
+	}
+	p.mu.Unlock()
+
+	if err := p.refresh(ctx, name, sources); err != nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if entry, ok := p.entries[name]; ok && !entry.fetchedAt.IsZero() {
+			GetLogger().Warn("刷新提示失败，继续使用过期的缓存",
+				zap.String("name", name),
+				zap.Error(err),
+			)
+			return entry.content, nil
+		}
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.entries[name].content, nil
+}
+
+// ETag 返回已缓存提示的 ETag，未缓存时返回空字符串
+func (p *PromptCache) ETag(name string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[name]; ok {
+		return entry.etag
+	}
+	return ""
+}
+
+// SourceName 返回提示当前内容来自的来源名称，来源未配置 name 或尚未加载时返回空字符串
+func (p *PromptCache) SourceName(name string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if entry, ok := p.entries[name]; ok {
+		return entry.source.Name
+	}
+	return ""
+}
+
+// Invalidate 使指定提示失效，下次获取时重新下载；name 为空时使全部提示失效
+// 返回失效的提示名称
+func (p *PromptCache) Invalidate(name string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var invalidated []string
+	for key := range p.entries {
+		if name == "" || key == name {
+			delete(p.entries, key)
+			invalidated = append(invalidated, key)
+		}
+	}
+	sort.Strings(invalidated)
+	return invalidated
+}
+
+// Status 返回所有已缓存提示的状态
+func (p *PromptCache) Status() []PromptStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]PromptStatus, 0, len(p.entries))
+	for name, entry := range p.entries {
+		source := entry.source
+		if !source.valid() {
+			if sources, ok := promptSources(name); ok {
+				source = sources[0]
+			}
+		}
+		statuses = append(statuses, PromptStatus{
+			Name:         name,
+			Source:       source.label(),
+			URL:          source.URL,
+			Path:         source.Path,
+			Git:          source.Git,
+			ETag:         entry.etag,
+			LastModified: entry.lastModified,
+			FetchedAt:    entry.fetchedAt,
+			ExpiresAt:    entry.expiresAt,
+			Size:         len(entry.content),
+			LastError:    entry.lastError,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// promptFetch 一次加载的结果，notModified 时只延长有效期
+type promptFetch struct {
+	content      string
+	etag         string
+	lastModified string
+	notModified  bool
+}
+
+// refresh 按优先级依次从来源加载提示，第一个成功的来源生效
+// 上次的内容来自同一来源时携带 ETag/If-Modified-Since 进行条件请求
+func (p *PromptCache) refresh(ctx context.Context, name string, sources []PromptSource) error {
+	lock, _ := p.fetchMu.LoadOrStore(name, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	p.mu.Lock()
+	entry, ok := p.entries[name]
+	if !ok {
+		entry = &promptEntry{}
+		p.entries[name] = entry
+	} else if !entry.fetchedAt.IsZero() && !entry.refreshing && p.now().Before(entry.expiresAt) {
+		// 等待锁期间其他请求已完成刷新
+		p.mu.Unlock()
+		return nil
+	}
+	current, etag, lastModified := entry.source, entry.etag, entry.lastModified
+	p.mu.Unlock()
+
+	fetch := func(source PromptSource, etag, lastModified string) (promptFetch, error) {
+		switch {
+		case source.Path != "":
+			return fetchPromptFile(source, lastModified)
+		case source.Git != "":
+			return p.fetchPromptGit(ctx, source, etag)
+		}
+		return p.fetchPromptURL(ctx, source, etag, lastModified)
+	}
+
+	var errs []string
+	for i, source := range sources {
+		var result promptFetch
+		var err error
+		if source == current {
+			result, err = fetch(source, etag, lastModified)
+		} else {
+			result, err = fetch(source, "", "")
+		}
+		if err == nil && !p.store(name, source, result) {
+			// 加载期间被设为失效，缓存中已没有旧内容，不带条件重新加载
+			if result, err = fetch(source, "", ""); err == nil {
+				p.store(name, source, result)
+			}
+		}
+		if err != nil {
+			if len(sources) == 1 {
+				return p.fail(name, err)
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", source.label(), err))
+			if i < len(sources)-1 {
+				GetLogger().Warn("提示来源不可用，尝试下一个来源",
+					zap.String("name", name),
+					zap.String("source", source.label()),
+					zap.Error(err),
+				)
+			}
+			continue
+		}
+		return nil
+	}
+	return p.fail(name, errors.New(strings.Join(errs, "; ")))
+}
+
+// store 保存加载结果，未修改但缓存中已没有旧内容时返回 false
+func (p *PromptCache) store(name string, source PromptSource, result promptFetch) bool {
+	ttl := source.TTL
+	if ttl <= 0 {
+		ttl = promptTTL()
+		if source.Path != "" {
+			ttl = defaultPromptFileTTL
+		}
+	}
+
+	p.mu.Lock()
+	entry, ok := p.entries[name]
+	if result.notModified && (!ok || entry.source != source) {
+		p.mu.Unlock()
+		return false
+	}
+	if !ok {
+		// 加载期间被设为失效，重新创建
+		entry = &promptEntry{}
+		p.entries[name] = entry
+	}
+	if !result.notModified {
+		entry.content = result.content
+		entry.etag = result.etag
+		entry.lastModified = result.lastModified
+	}
+	entry.source = source
+	entry.fetchedAt = p.now()
+	entry.expiresAt = entry.fetchedAt.Add(ttl)
+	entry.lastError = ""
+	entry.refreshing = false
+	p.mu.Unlock()
+
+	if result.notModified {
+		GetLogger().Debug("提示未修改", zap.String("name", name), zap.String("source", source.label()))
+		return true
+	}
+	GetLogger().Info("提示已更新",
+		zap.String("name", name),
+		zap.String("source", source.label()),
+		zap.String("etag", result.etag),
+		zap.Int("size", len(result.content)),
+	)
+	return true
+}
+
+// fetchPromptURL 下载 HTTP/OSS 提示
+func (p *PromptCache) fetchPromptURL(ctx context.Context, source PromptSource, etag, lastModified string) (promptFetch, error) {
+	perfStats := GetPerfStats()
+	defer perfStats.TraceFunc("prompt_cache_fetch")()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return promptFetch{}, err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return promptFetch{}, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return promptFetch{notModified: true}, nil
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return promptFetch{}, err
+		}
+		return promptFetch{
+			content:      string(body),
+			etag:         resp.Header.Get("ETag"),
+			lastModified: resp.Header.Get("Last-Modified"),
+		}, nil
+	default:
+		return promptFetch{}, fmt.Errorf("下载提示 %s 失败: HTTP %d", source.URL, resp.StatusCode)
+	}
+}
+
+// fetchPromptFile 读取本地文件提示，修改时间未变化时不重新读取
+func fetchPromptFile(source PromptSource, lastModified string) (promptFetch, error) {
+	info, err := os.Stat(source.Path)
+	if err != nil {
+		return promptFetch{}, err
+	}
+	modified := info.ModTime().UTC().Format(time.RFC3339Nano)
+	if modified == lastModified {
+		return promptFetch{notModified: true}, nil
+	}
+	body, err := os.ReadFile(source.Path)
+	if err != nil {
+		return promptFetch{}, err
+	}
+	return promptFetch{content: string(body), lastModified: modified}, nil
+}
+
+// fetchPromptGit 从 Git 仓库读取提示：首次浅克隆到 prompts.git_dir，之后拉取最新提交
+// 同一仓库和分支的多个提示共用一个工作目录，提交未变化时不重新读取
+func (p *PromptCache) fetchPromptGit(ctx context.Context, source PromptSource, etag string) (promptFetch, error) {
+	perfStats := GetPerfStats()
+	defer perfStats.TraceFunc("prompt_cache_git")()
+
+	sum := sha256.Sum256([]byte(source.Git + "#" + source.Ref))
+	dir := filepath.Join(promptGitDir(), hex.EncodeToString(sum[:8]))
+	commit, err := SyncGitRepo(ctx, source.Git, source.Ref, dir)
+	if err != nil {
+		return promptFetch{}, err
+	}
+	if commit == etag {
+		return promptFetch{notModified: true}, nil
+	}
+	body, err := os.ReadFile(filepath.Join(dir, filepath.Clean("/"+source.File)))
+	if err != nil {
+		return promptFetch{}, err
+	}
+	return promptFetch{content: string(body), etag: commit}, nil
+}
+
+// fail 记录刷新失败，保留旧内容以便继续使用
+func (p *PromptCache) fail(name string, err error) error {
+	p.mu.Lock()
+	if entry, ok := p.entries[name]; ok {
+		entry.lastError = err.Error()
+		entry.refreshing = false
+	}
+	p.mu.Unlock()
+	return err
+}
+
+// Prewarm 并发加载所有配置了来源的提示，返回加载失败的提示及原因
+func (p *PromptCache) Prewarm(ctx context.Context) map[string]error {
+	names := make([]string, 0)
+	for name := range GetConfig().GetStringMap("prompts.sources") {
+		names = append(names, name)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed = map[string]error{}
+	)
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := p.Get(ctx, name); err != nil {
+				mu.Lock()
+				failed[name] = err
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+	return failed
+}
+
+// StartPromptPrewarm 启动时预热所有提示，部署后的首个请求不必等待远程下载
+// 之后按 prompts.prewarm.interval 定期检查，临近过期的提示在后台刷新
+// 配置项：
+//   - prompts.prewarm.enabled: 是否预热，默认 true
+//   - prompts.prewarm.timeout: 启动时最多等待多久，默认 30s，超时后未加载的提示在首次请求时加载
+//   - prompts.prewarm.interval: 定期检查间隔，默认与 prompts.refresh_before 相同，小于 0 时只在启动时预热
+func StartPromptPrewarm(ctx context.Context) {
+	config := GetConfig()
+	if config.IsSet("prompts.prewarm.enabled") && !config.GetBool("prompts.prewarm.enabled") {
+		return
+	}
+	if len(config.GetStringMap("prompts.sources")) == 0 {
+		return
+	}
+
+	timeout := config.GetDuration("prompts.prewarm.timeout")
+	if timeout <= 0 {
+		timeout = defaultPromptPrewarmTimeout
+	}
+	cache := GetPromptCache()
+	prewarmCtx, cancel := context.WithTimeout(ctx, timeout)
+	start := time.Now()
+	failed := cache.Prewarm(prewarmCtx)
+	cancel()
+	for name, err := range failed {
+		GetLogger().Warn("预热提示失败，将在首次请求时重试", zap.String("name", name), zap.Error(err))
+	}
+	GetLogger().Info("提示预热完成",
+		zap.Int("prompts", len(config.GetStringMap("prompts.sources"))),
+		zap.Int("failed", len(failed)),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	interval := config.GetDuration("prompts.prewarm.interval")
+	if interval == 0 {
+		interval = promptRefreshBefore()
+	}
+	if interval < 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Get 在临近过期时触发后台刷新，已过期时同步刷新
+				cache.Prewarm(ctx)
+			}
+		}
+	}()
+}
+
+// promptSources 读取提示来源配置，按 priority 排序
+// prompts.sources.<name> 可以直接配置一个来源，也可以在 sources 中配置多个来源
+func promptSources(name string) ([]PromptSource, bool) {
+	key := "prompts.sources." + name
+	if !GetConfig().IsSet(key) {
+		return nil, false
+	}
+	var config struct {
+		PromptSource `mapstructure:",squash"`
+		Sources      []PromptSource `mapstructure:"sources"`
+	}
+	if err := GetConfig().UnmarshalKey(key, &config); err != nil {
+		return nil, false
+	}
+
+	candidates := config.Sources
+	if len(candidates) == 0 {
+		candidates = []PromptSource{config.PromptSource}
+	}
+	var sources []PromptSource
+	for _, source := range candidates {
+		if source.valid() {
+			sources = append(sources, source)
+		}
+	}
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].Priority < sources[j].Priority })
+	return sources, len(sources) > 0
+}
+
+// promptGitDir Git 来源的本地工作目录，配置项 prompts.git_dir
+func promptGitDir() string {
+	if dir := GetConfig().GetString("prompts.git_dir"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "opsagent-prompts")
+}
+
+func promptTTL() time.Duration {
+	if ttl := GetConfig().GetDuration("prompts.ttl"); ttl > 0 {
+		return ttl
+	}
+	return defaultPromptTTL
+}
+
+func promptRefreshBefore() time.Duration {
+	if d := GetConfig().GetDuration("prompts.refresh_before"); d > 0 {
+		return d
+	}
+	return defaultPromptRefreshBefore
+}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPromptCacheRevalidation(t *testing.T) {
+	var requests, notModified int32
+	content := "v1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		etag := `"` + content + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	GetConfig().Set("prompts.sources", map[string]interface{}{
+		"execute": map[string]interface{}{"url": server.URL, "ttl": "10m"},
+	})
+	GetConfig().Set("prompts.refresh_before", "1m")
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewPromptCache(server.Client())
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if got, err := cache.Get(ctx, "execute"); err != nil || got != "v1" {
+			t.Fatalf("expected v1, got %q (%v)", got, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("expected 1 request while cached, got %d", n)
+	}
+
+	// 过期后条件请求，未修改时沿用缓存
+	now = now.Add(11 * time.Minute)
+	if got, err := cache.Get(ctx, "execute"); err != nil || got != "v1" {
+		t.Fatalf("expected v1 after revalidation, got %q (%v)", got, err)
+	}
+	if n := atomic.LoadInt32(&notModified); n != 1 {
+		t.Fatalf("expected a 304 revalidation, got %d", n)
+	}
+
+	// 失效后重新下载新内容
+	content = "v2"
+	if invalidated := cache.Invalidate("execute"); len(invalidated) != 1 {
+		t.Fatalf("expected execute to be invalidated, got %v", invalidated)
+	}
+	if got, err := cache.Get(ctx, "execute"); err != nil || got != "v2" {
+		t.Fatalf("expected v2 after invalidation, got %q (%v)", got, err)
+	}
+
+	if _, ok := cache.Lookup(ctx, "unknown"); ok {
+		t.Fatal("expected lookup of an unconfigured prompt to fail")
+	}
+}
+
+func TestPromptCacheFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "execute.tmpl")
+	if err := os.WriteFile(path, []byte("v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	GetConfig().Set("prompts.sources", map[string]interface{}{
+		"execute": map[string]interface{}{"path": path, "ttl": "10s"},
+	})
+	// 不在后台刷新，避免测试结束后遗留协程
+	GetConfig().Set("prompts.refresh_before", "1ns")
+	defer GetConfig().Set("prompts.sources", map[string]interface{}{})
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewPromptCache(http.DefaultClient)
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if got, err := cache.Get(ctx, "execute"); err != nil || got != "v1" {
+		t.Fatalf("expected v1, got %q (%v)", got, err)
+	}
+
+	// 修改文件，检查间隔内沿用缓存，之后重新加载
+	if err := os.WriteFile(path, []byte("v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := cache.Get(ctx, "execute"); got != "v1" {
+		t.Fatalf("expected cached v1 before the check interval, got %q", got)
+	}
+	now = now.Add(11 * time.Second)
+	if got, err := cache.Get(ctx, "execute"); err != nil || got != "v2" {
+		t.Fatalf("expected v2 after reload, got %q (%v)", got, err)
+	}
+
+	// 文件不可读时继续使用旧内容
+	os.Remove(path)
+	now = now.Add(11 * time.Second)
+	if got, err := cache.Get(ctx, "execute"); err != nil || got != "v2" {
+		t.Fatalf("expected stale v2 when the file is missing, got %q (%v)", got, err)
+	}
+}
+
+func TestPromptCacheMultiSource(t *testing.T) {
+	var down int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("from oss"))
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "diagnose.tmpl")
+	if err := os.WriteFile(path, []byte("from local"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	GetConfig().Set("prompts.sources", map[string]interface{}{
+		"diagnose": map[string]interface{}{"sources": []interface{}{
+			map[string]interface{}{"name": "local", "path": path, "priority": 2, "ttl": "1m"},
+			map[string]interface{}{"name": "oss", "url": server.URL, "priority": 1, "ttl": "1m"},
+		}},
+	})
+	GetConfig().Set("prompts.refresh_before", "1ns")
+	defer GetConfig().Set("prompts.sources", map[string]interface{}{})
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewPromptCache(server.Client())
+	cache.now = func() time.Time { return now }
+
+	// 高优先级来源不可用时使用下一个来源
+	if failed := cache.Prewarm(context.Background()); len(failed) != 0 {
+		t.Fatalf("Prewarm() failed = %v", failed)
+	}
+	if got, _ := cache.Get(context.Background(), "diagnose"); got != "from local" || cache.SourceName("diagnose") != "local" {
+		t.Fatalf("expected the local fallback, got %q from %q", got, cache.SourceName("diagnose"))
+	}
+
+	// 恢复后重新使用高优先级来源
+	atomic.StoreInt32(&down, 0)
+	now = now.Add(2 * time.Minute)
+	if got, _ := cache.Get(context.Background(), "diagnose"); got != "from oss" || cache.SourceName("diagnose") != "oss" {
+		t.Fatalf("expected oss after recovery, got %q from %q", got, cache.SourceName("diagnose"))
+	}
+}
+
+func TestPromptCacheGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(repo, "execute.tmpl"), []byte("v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+
+	GetConfig().Set("prompts.git_dir", t.TempDir())
+	GetConfig().Set("prompts.sources", map[string]interface{}{
+		"execute": map[string]interface{}{"git": repo, "ref": "main", "file": "execute.tmpl", "ttl": "1m"},
+	})
+	GetConfig().Set("prompts.refresh_before", "1ns")
+	defer GetConfig().Set("prompts.git_dir", "")
+	defer GetConfig().Set("prompts.sources", map[string]interface{}{})
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cache := NewPromptCache(http.DefaultClient)
+	cache.now = func() time.Time { return now }
+	if got, err := cache.Get(context.Background(), "execute"); err != nil || got != "v1" || len(cache.ETag("execute")) != 40 {
+		t.Fatalf("expected v1 at a commit, got %q (%v), etag %q", got, err, cache.ETag("execute"))
+	}
+
+	if err := os.WriteFile(filepath.Join(repo, "execute.tmpl"), []byte("v2"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	git("commit", "-q", "-am", "v2")
+	now = now.Add(2 * time.Minute)
+	if got, err := cache.Get(context.Background(), "execute"); err != nil || got != "v2" {
+		t.Fatalf("expected v2 after the new commit, got %q (%v)", got, err)
+	}
+}
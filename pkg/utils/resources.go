@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"runtime/metrics"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// resourceSampleInterval 交互期间采样堆内存和 goroutine 数量的间隔
+	resourceSampleInterval = 100 * time.Millisecond
+	// maxResourceRecords 性能统计中保留的最近交互资源记录数
+	maxResourceRecords = 500
+	// maxResourceQuestionRunes 资源记录中保留的问题长度
+	maxResourceQuestionRunes = 200
+)
+
+// runtime/metrics 中采样的指标
+const (
+	metricHeapObjects = "/memory/classes/heap/objects:bytes"
+	metricGoroutines  = "/sched/goroutines:goroutines"
+	metricHeapAllocs  = "/gc/heap/allocs:bytes"
+)
+
+// inflightInteractions 正在采样的交互数量
+var inflightInteractions atomic.Int64
+
+// ResourceUsage 一次交互中 OpsAgent 自身的资源消耗
+// CPU 时间、堆内存和分配量均为进程级指标的差值或峰值，Concurrent 大于 1 时包含同时进行的其他交互的消耗
+type ResourceUsage struct {
+	InteractionID   string        `json:"interaction_id"`
+	Operation       string        `json:"operation"`
+	Question        string        `json:"question"`
+	StartedAt       time.Time     `json:"started_at"`
+	Duration        time.Duration `json:"duration"`
+	CPUTime         time.Duration `json:"cpu_time"`
+	PeakHeapBytes   uint64        `json:"peak_heap_bytes"`
+	AllocBytes      uint64        `json:"alloc_bytes"`
+	StartGoroutines int           `json:"start_goroutines"`
+	PeakGoroutines  int           `json:"peak_goroutines"`
+	Concurrent      int           `json:"concurrent"`
+}
+
+// ResourceSampler 在交互期间周期采样运行时指标
+type ResourceSampler struct {
+	operation  string
+	start      time.Time
+	startCPU   time.Duration
+	startAlloc uint64
+
+	mu             sync.Mutex
+	startGoroutine int
+	peakHeap       uint64
+	peakGoroutines int
+	concurrent     int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// StartResourceSampler 开始采样一次交互的资源消耗，交互结束时调用 Stop
+func StartResourceSampler(operation string) *ResourceSampler {
+	s := &ResourceSampler{
+		operation: operation,
+		start:     time.Now(),
+		startCPU:  processCPUTime(),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	heap, goroutines, allocs := readRuntimeMetrics()
+	s.startAlloc = allocs
+	s.startGoroutine = goroutines
+	s.concurrent = int(inflightInteractions.Add(1))
+	s.observe(heap, goroutines)
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(resourceSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				heap, goroutines, _ := readRuntimeMetrics()
+				s.observe(heap, goroutines)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *ResourceSampler) observe(heap uint64, goroutines int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if heap > s.peakHeap {
+		s.peakHeap = heap
+	}
+	if goroutines > s.peakGoroutines {
+		s.peakGoroutines = goroutines
+	}
+	if n := int(inflightInteractions.Load()); n > s.concurrent {
+		s.concurrent = n
+	}
+}
+
+// Stop 结束采样并将结果记录到性能统计，超过 perf.resources 阈值时输出告警日志
+func (s *ResourceSampler) Stop(interactionID, question string) ResourceUsage {
+	close(s.stop)
+	<-s.done
+	inflightInteractions.Add(-1)
+
+	heap, goroutines, allocs := readRuntimeMetrics()
+	s.observe(heap, goroutines)
+
+	s.mu.Lock()
+	usage := ResourceUsage{
+		InteractionID:   interactionID,
+		Operation:       s.operation,
+		Question:        truncateRunes(question, maxResourceQuestionRunes),
+		StartedAt:       s.start,
+		Duration:        time.Since(s.start),
+		CPUTime:         processCPUTime() - s.startCPU,
+		PeakHeapBytes:   s.peakHeap,
+		StartGoroutines: s.startGoroutine,
+		PeakGoroutines:  s.peakGoroutines,
+		Concurrent:      s.concurrent,
+	}
+	s.mu.Unlock()
+	if allocs > s.startAlloc {
+		usage.AllocBytes = allocs - s.startAlloc
+	}
+
+	GetPerfStats().RecordResourceUsage(usage)
+
+	config := GetConfig()
+	cpuWarn := config.GetDuration("perf.resources.cpu_warn")
+	heapWarn := uint64(config.GetInt64("perf.resources.heap_warn_bytes"))
+	goroutineWarn := config.GetInt("perf.resources.goroutine_warn")
+	if (cpuWarn > 0 && usage.CPUTime > cpuWarn) ||
+		(heapWarn > 0 && usage.PeakHeapBytes > heapWarn) ||
+		(goroutineWarn > 0 && usage.PeakGoroutines > goroutineWarn) {
+		Warn("交互资源消耗过高",
+			zap.String(LogFieldInteraction, usage.InteractionID),
+			zap.String("operation", usage.Operation),
+			zap.String("question", usage.Question),
+			zap.Duration("cpu_time", usage.CPUTime),
+			zap.Uint64("peak_heap_bytes", usage.PeakHeapBytes),
+			zap.Uint64("alloc_bytes", usage.AllocBytes),
+			zap.Int("peak_goroutines", usage.PeakGoroutines),
+			zap.Int("concurrent", usage.Concurrent),
+		)
+	}
+	return usage
+}
+
+// readRuntimeMetrics 读取当前堆对象大小、goroutine 数量和累计分配量
+func readRuntimeMetrics() (heap uint64, goroutines int, allocs uint64) {
+	samples := []metrics.Sample{{Name: metricHeapObjects}, {Name: metricGoroutines}, {Name: metricHeapAllocs}}
+	metrics.Read(samples)
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		heap = samples[0].Value.Uint64()
+	}
+	if samples[1].Value.Kind() == metrics.KindUint64 {
+		goroutines = int(samples[1].Value.Uint64())
+	}
+	if samples[2].Value.Kind() == metrics.KindUint64 {
+		allocs = samples[2].Value.Uint64()
+	}
+	return heap, goroutines, allocs
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
+
+// RecordResourceUsage 记录一次交互的资源消耗，只保留最近 maxResourceRecords 条
+func (p *PerfStats) RecordResourceUsage(usage ResourceUsage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resources = append(p.resources, usage)
+	if excess := len(p.resources) - maxResourceRecords; excess > 0 {
+		p.resources = append([]ResourceUsage(nil), p.resources[excess:]...)
+	}
+}
+
+// TopResourceUsage 返回最近记录中按指定维度（cpu、heap、goroutines、alloc）消耗最高的 n 次交互
+func (p *PerfStats) TopResourceUsage(by string, n int) []ResourceUsage {
+	p.mu.RLock()
+	usages := append([]ResourceUsage(nil), p.resources...)
+	p.mu.RUnlock()
+
+	less := func(a, b ResourceUsage) bool { return a.CPUTime > b.CPUTime }
+	switch by {
+	case "heap":
+		less = func(a, b ResourceUsage) bool { return a.PeakHeapBytes > b.PeakHeapBytes }
+	case "goroutines":
+		less = func(a, b ResourceUsage) bool { return a.PeakGoroutines > b.PeakGoroutines }
+	case "alloc":
+		less = func(a, b ResourceUsage) bool { return a.AllocBytes > b.AllocBytes }
+	}
+	sort.SliceStable(usages, func(i, j int) bool { return less(usages[i], usages[j]) })
+	if n > 0 && len(usages) > n {
+		usages = usages[:n]
+	}
+	return usages
+}
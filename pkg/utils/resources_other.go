@@ -0,0 +1,10 @@
+//go:build !unix
+
+package utils
+
+import "time"
+
+// processCPUTime 当前平台不支持读取进程 CPU 时间
+func processCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResourceSampler(t *testing.T) {
+	sampler := StartResourceSampler("test")
+
+	// 制造 goroutine 和内存分配，采样结果应反映峰值
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-release
+		}()
+	}
+	buf := make([][]byte, 0, 64)
+	for i := 0; i < 64; i++ {
+		buf = append(buf, make([]byte, 64<<10))
+	}
+	// 等待采样协程观察到峰值，负载较高时采样可能延迟
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(resourceSampleInterval) {
+		sampler.mu.Lock()
+		observed := sampler.peakGoroutines >= sampler.startGoroutine+50
+		sampler.mu.Unlock()
+		if observed {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	usage := sampler.Stop("interaction-1", "为什么 payment-api 一直重启")
+	if usage.PeakGoroutines < usage.StartGoroutines+50 {
+		t.Errorf("expected peak goroutines to include spawned goroutines, got start=%d peak=%d", usage.StartGoroutines, usage.PeakGoroutines)
+	}
+	if usage.AllocBytes < 64*64<<10 {
+		t.Errorf("expected at least %d allocated bytes, got %d", 64*64<<10, usage.AllocBytes)
+	}
+	if usage.PeakHeapBytes == 0 || usage.Concurrent < 1 || usage.Duration <= 0 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+
+	found := false
+	for _, recorded := range GetPerfStats().TopResourceUsage("alloc", 0) {
+		found = found || recorded.InteractionID == "interaction-1"
+	}
+	if !found {
+		t.Error("expected usage to be recorded in perf stats")
+	}
+}
+
+func TestTopResourceUsage(t *testing.T) {
+	p := &PerfStats{}
+	p.RecordResourceUsage(ResourceUsage{InteractionID: "a", CPUTime: time.Second, PeakHeapBytes: 300})
+	p.RecordResourceUsage(ResourceUsage{InteractionID: "b", CPUTime: 3 * time.Second, PeakHeapBytes: 100})
+	p.RecordResourceUsage(ResourceUsage{InteractionID: "c", CPUTime: 2 * time.Second, PeakHeapBytes: 200})
+
+	if top := p.TopResourceUsage("cpu", 2); len(top) != 2 || top[0].InteractionID != "b" || top[1].InteractionID != "c" {
+		t.Errorf("unexpected top by cpu: %+v", top)
+	}
+	if top := p.TopResourceUsage("heap", 1); len(top) != 1 || top[0].InteractionID != "a" {
+		t.Errorf("unexpected top by heap: %+v", top)
+	}
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracerName 本服务创建 span 时使用的 instrumentation 名称
+const tracerName = "github.com/myysophia/OpsAgent"
+
+// LogFieldTrace 启用链路追踪时请求级日志中的 trace ID 字段
+const LogFieldTrace = "trace_id"
+
+// tracerProvider 启用链路追踪时创建的 TracerProvider，退出时用于导出剩余的 span
+var tracerProvider *sdktrace.TracerProvider
+
+// InitTracing 根据 tracing 配置初始化 OpenTelemetry 并通过 OTLP/HTTP 导出 span
+// tracing.enabled 为 false 时不做任何事情，StartSpan 使用 no-op 实现，几乎没有开销
+// tracing.endpoint 未配置时使用 OTEL_EXPORTER_OTLP_* 环境变量，默认 localhost:4318
+func InitTracing(ctx context.Context) error {
+	config := GetConfig()
+	if !config.GetBool("tracing.enabled") {
+		return nil
+	}
+
+	var options []otlptracehttp.Option
+	if endpoint := config.GetString("tracing.endpoint"); strings.Contains(endpoint, "://") {
+		options = append(options, otlptracehttp.WithEndpointURL(endpoint))
+	} else if endpoint != "" {
+		options = append(options, otlptracehttp.WithEndpoint(endpoint))
+	}
+	if config.GetBool("tracing.insecure") {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if headers := config.GetStringMapString("tracing.headers"); len(headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(headers))
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return fmt.Errorf("创建 OTLP 导出器失败: %w", err)
+	}
+
+	serviceName := config.GetString("tracing.service_name")
+	if serviceName == "" {
+		serviceName = "opsagent"
+	}
+	ratio := 1.0
+	if config.IsSet("tracing.sample_ratio") {
+		ratio = config.GetFloat64("tracing.sample_ratio")
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+		// 上游已决定采样的请求（traceparent 头）沿用上游的决定
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+	tracerProvider = provider
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		Warn("导出链路追踪数据失败", zap.Error(err))
+	}))
+
+	Info("已启用链路追踪",
+		zap.String("service_name", serviceName),
+		zap.Float64("sample_ratio", ratio),
+	)
+	return nil
+}
+
+// ShutdownTracing 导出缓冲中剩余的 span 并关闭导出器，未启用链路追踪时不做任何事情
+func ShutdownTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	return tracerProvider.Shutdown(ctx)
+}
+
+type spanTimingsKey struct{}
+
+// spanTimings 记录一个 span 内各 PerfStats 计时的累计耗时和次数
+// 同一操作在一个 span 内可能执行多次（如每轮迭代的对话），属性值为累计值
+type spanTimings struct {
+	mu     sync.Mutex
+	totals map[string]time.Duration
+	counts map[string]int
+}
+
+// StartSpan 创建子 span，返回的上下文需要传给后续调用，使下游的 span 和计时归属于该 span
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+	if span.IsRecording() {
+		ctx = context.WithValue(ctx, spanTimingsKey{}, &spanTimings{
+			totals: make(map[string]time.Duration),
+			counts: make(map[string]int),
+		})
+	}
+	return ctx, span
+}
+
+// EndSpan 结束 span，err 不为空时记录错误并将状态置为 Error
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceIDFromContext 获取上下文中 span 的 trace ID，未启用链路追踪或未采样时返回空
+func TraceIDFromContext(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// StopSpanTimer 停止计时并将耗时写入上下文中 span 的属性：perf.<operation>.ms 为累计毫秒数，perf.<operation>.count 为次数
+func (p *PerfStats) StopSpanTimer(ctx context.Context, operation string) time.Duration {
+	elapsed := p.StopTimer(operation)
+	timings, ok := ctx.Value(spanTimingsKey{}).(*spanTimings)
+	if !ok {
+		return elapsed
+	}
+	timings.mu.Lock()
+	timings.totals[operation] += elapsed
+	timings.counts[operation]++
+	total, count := timings.totals[operation], timings.counts[operation]
+	timings.mu.Unlock()
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Float64("perf."+operation+".ms", float64(total.Microseconds())/1000),
+		attribute.Int("perf."+operation+".count", count),
+	)
+	return elapsed
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStopSpanTimer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	perfStats := GetPerfStats()
+	ctx, parent := StartSpan(context.Background(), "request")
+	if TraceIDFromContext(ctx) == "" {
+		t.Fatal("TraceIDFromContext returned empty trace ID")
+	}
+	for i := 0; i < 2; i++ {
+		perfStats.StartTimer("test_span_chat")
+		perfStats.StopSpanTimer(ctx, "test_span_chat")
+	}
+	childCtx, child := StartSpan(ctx, "tool")
+	perfStats.StartTimer("test_span_tool")
+	perfStats.StopSpanTimer(childCtx, "test_span_tool")
+	EndSpan(child, errors.New("boom"))
+	EndSpan(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(spans))
+	}
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := make(map[attribute.Key]attribute.Value)
+		for _, kv := range span.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	toolSpan, requestSpan := spans[0], spans[1]
+	if requestSpan.Name() != "request" || toolSpan.Parent().SpanID() != requestSpan.SpanContext().SpanID() {
+		t.Fatalf("unexpected span tree: %s -> %s", requestSpan.Name(), toolSpan.Name())
+	}
+	if got := attrs(requestSpan)["perf.test_span_chat.count"].AsInt64(); got != 2 {
+		t.Errorf("perf.test_span_chat.count = %d, want 2", got)
+	}
+	if _, ok := attrs(requestSpan)["perf.test_span_tool.ms"]; ok {
+		t.Error("child timer recorded on parent span")
+	}
+	if _, ok := attrs(toolSpan)["perf.test_span_tool.ms"]; !ok {
+		t.Error("perf.test_span_tool.ms missing on child span")
+	}
+	if toolSpan.Status().Code != codes.Error {
+		t.Errorf("child status = %v, want Error", toolSpan.Status().Code)
+	}
+}
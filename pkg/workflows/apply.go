@@ -0,0 +1,414 @@
+package workflows
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myysophia/OpsAgent/pkg/audit"
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"go.uber.org/zap"
+)
+
+// 应用请求的状态
+const (
+	ApplyStatusPending  = "pending"
+	ApplyStatusRejected = "rejected"
+	ApplyStatusApplying = "applying" // 审批通过，正在应用
+	ApplyStatusApplied  = "applied"
+	ApplyStatusFailed   = "failed"
+)
+
+// maxDriftRequests 漂移检测覆盖的最近已应用请求数
+const maxDriftRequests = 1000
+
+// ErrApplyNotFound 应用请求不存在
+var ErrApplyNotFound = audit.ErrApplyRequestNotFound
+
+// ApplyRequest 生成清单的应用请求
+// 流程：校验 → 服务端 dry-run 和 diff → 人工审批 → 按记录的清单哈希应用
+type ApplyRequest struct {
+	ID       string   `json:"id"`
+	Manifest string   `json:"manifest"`
+	Hash     string   `json:"hash"`
+	Objects  []string `json:"objects"`
+	// 提交时每个对象相对集群中现有对象的变化，审批人据此确认将要应用的内容
+	Diff        []kubernetes.ObjectDiff `json:"diff,omitempty"`
+	Status      string                  `json:"status"`
+	RequestedBy string                  `json:"requested_by"`
+	ReviewedBy  string                  `json:"reviewed_by,omitempty"`
+	Error       string                  `json:"error,omitempty"`
+	CreatedAt   time.Time               `json:"created_at"`
+	ReviewedAt  *time.Time              `json:"reviewed_at,omitempty"`
+}
+
+// applyStore 应用请求存储，启用审计时保存在审计数据库，否则只保存在内存中
+type applyStore interface {
+	SaveApplyRequest(ctx context.Context, r *audit.ApplyRequest) error
+	TransitionApplyRequest(ctx context.Context, r *audit.ApplyRequest, from string) error
+	GetApplyRequest(ctx context.Context, id string) (*audit.ApplyRequest, error)
+	ListApplyRequests(ctx context.Context, status string, limit int) ([]*audit.ApplyRequest, error)
+}
+
+var applyMemory = newMemoryApplyStore()
+
+func getApplyStore() applyStore {
+	if s := audit.GetStore(); s != nil {
+		return s
+	}
+	return applyMemory
+}
+
+// 访问集群的函数，见 SetManifestFuncs
+var (
+	diffManifest  = defaultDiffManifest
+	applyManifest = kubernetes.ApplyTrackedYaml
+)
+
+func defaultDiffManifest(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+	return kubernetes.DiffYaml(ctx, "", manifest)
+}
+
+// SetManifestFuncs 使应用流程通过 diff 执行服务端 dry-run、通过 apply 应用清单，而不是连接 kubeconfig 中的集群，
+// 例如测试中的模拟集群；需要在处理请求之前调用，nil 恢复默认行为
+func SetManifestFuncs(diff func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error),
+	apply func(manifest, id, hash string) ([]string, error)) {
+	diffManifest, applyManifest = defaultDiffManifest, kubernetes.ApplyTrackedYaml
+	if diff != nil {
+		diffManifest = diff
+	}
+	if apply != nil {
+		applyManifest = apply
+	}
+}
+
+// ManifestHash 计算清单的 SHA256 哈希
+func ManifestHash(manifest string) string {
+	sum := sha256.Sum256([]byte(manifest))
+	return hex.EncodeToString(sum[:])
+}
+
+// ManifestValidationError 清单未通过服务端 dry-run，Errors 为每个被 API Server 拒绝的对象的错误
+type ManifestValidationError struct {
+	Errors []string
+	Diff   *kubernetes.ManifestDiff
+}
+
+func (e *ManifestValidationError) Error() string {
+	return "服务端 dry-run 校验失败: " + strings.Join(e.Errors, "; ")
+}
+
+// PreviewApply 校验清单并执行服务端 dry-run，返回每个对象相对集群中现有对象的 diff，不会修改集群
+// 相当于 kubectl apply --dry-run=server 加 kubectl diff；清单无法解析时返回错误，对象被拒绝时在结果中标记为 invalid
+func PreviewApply(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+	if _, err := kubernetes.ParseYaml(manifest); err != nil {
+		return nil, fmt.Errorf("清单校验失败: %v", err)
+	}
+	diff, err := diffManifest(ctx, manifest)
+	if err != nil {
+		return nil, fmt.Errorf("服务端 dry-run 失败: %v", err)
+	}
+	return diff, nil
+}
+
+// SubmitApply 校验清单并执行服务端 dry-run 和 diff，全部对象通过后创建待审批的应用请求
+// 有对象被 API Server 拒绝时返回 *ManifestValidationError，包含全部错误和 diff
+func SubmitApply(ctx context.Context, manifest string, username string) (*ApplyRequest, error) {
+	diff, err := PreviewApply(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if !diff.Valid {
+		return nil, &ManifestValidationError{Errors: diff.Errors, Diff: diff}
+	}
+
+	objects := make([]string, 0, len(diff.Objects))
+	for _, obj := range diff.Objects {
+		objects = append(objects, obj.Object)
+	}
+	req := &ApplyRequest{
+		ID:          audit.NewInteractionID(),
+		Manifest:    manifest,
+		Hash:        ManifestHash(manifest),
+		Objects:     objects,
+		Diff:        diff.Objects,
+		Status:      ApplyStatusPending,
+		RequestedBy: username,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := saveApply(ctx, req); err != nil {
+		return nil, fmt.Errorf("保存应用请求失败: %v", err)
+	}
+
+	logger.Info("创建清单应用请求",
+		zap.String("id", req.ID),
+		zap.String("hash", req.Hash),
+		zap.Strings("objects", objects),
+		zap.String("requested_by", username),
+	)
+	return req, nil
+}
+
+// GetApply 获取应用请求，不存在时返回 ErrApplyNotFound
+func GetApply(ctx context.Context, id string) (*ApplyRequest, error) {
+	r, err := getApplyStore().GetApplyRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return fromAuditApply(r), nil
+}
+
+// distinctReviewerRequired 审批人是否必须与申请人不同，配置项 generate.apply.require_distinct_reviewer，默认 true
+func distinctReviewerRequired() bool {
+	config := utils.GetConfig()
+	return !config.IsSet("generate.apply.require_distinct_reviewer") || config.GetBool("generate.apply.require_distinct_reviewer")
+}
+
+// ReviewApply 审批应用请求
+// 审批通过时必须提供与 dry-run 时一致的清单哈希，确保应用的正是审阅过的内容；
+// 校验全部通过后才修改请求，多个审批同时到达时只有一个成功，应用期间状态为 applying
+func ReviewApply(ctx context.Context, id string, reviewer string, approve bool, hash string) (*ApplyRequest, error) {
+	req, err := GetApply(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != ApplyStatusPending {
+		return nil, fmt.Errorf("应用请求 %s 当前状态为 %s，无法审批", id, req.Status)
+	}
+	if distinctReviewerRequired() && reviewer == req.RequestedBy {
+		return nil, fmt.Errorf("审批人不能与申请人相同")
+	}
+	if approve && hash != req.Hash {
+		return nil, fmt.Errorf("清单哈希不匹配，请重新审阅")
+	}
+
+	now := time.Now()
+	req.ReviewedBy = reviewer
+	req.ReviewedAt = &now
+	req.Status = ApplyStatusRejected
+	if approve {
+		req.Status = ApplyStatusApplying
+	}
+	store := getApplyStore()
+	if err := store.TransitionApplyRequest(ctx, toAuditApply(req), ApplyStatusPending); err != nil {
+		if errors.Is(err, audit.ErrApplyRequestConflict) {
+			return nil, fmt.Errorf("应用请求 %s 已被其他人审批", id)
+		}
+		return nil, err
+	}
+
+	if !approve {
+		logger.Info("拒绝清单应用请求",
+			zap.String("id", id),
+			zap.String("reviewer", reviewer),
+		)
+		return req, nil
+	}
+
+	if _, err := applyManifest(req.Manifest, req.ID, req.Hash); err != nil {
+		req.Status = ApplyStatusFailed
+		req.Error = err.Error()
+		logger.Error("应用清单失败",
+			zap.String("id", id),
+			zap.String("hash", req.Hash),
+			zap.Error(err),
+		)
+	} else {
+		req.Status = ApplyStatusApplied
+		logger.Info("应用清单成功",
+			zap.String("id", id),
+			zap.String("hash", req.Hash),
+			zap.Strings("objects", req.Objects),
+			zap.String("requested_by", req.RequestedBy),
+			zap.String("reviewer", reviewer),
+		)
+	}
+	// 请求已经开始应用，即使调用方已经断开也要记录结果
+	if err := store.SaveApplyRequest(context.WithoutCancel(ctx), toAuditApply(req)); err != nil {
+		logger.Error("保存应用结果失败",
+			zap.String("id", id),
+			zap.String("status", req.Status),
+			zap.Error(err),
+		)
+	}
+	return req, nil
+}
+
+func saveApply(ctx context.Context, req *ApplyRequest) error {
+	return getApplyStore().SaveApplyRequest(ctx, toAuditApply(req))
+}
+
+// toAuditApply 转换为审计数据库中的记录，对象列表和 diff 保存为 JSON
+func toAuditApply(req *ApplyRequest) *audit.ApplyRequest {
+	objects, _ := json.Marshal(req.Objects)
+	diff, _ := json.Marshal(req.Diff)
+	return &audit.ApplyRequest{
+		ID:          req.ID,
+		Manifest:    req.Manifest,
+		Hash:        req.Hash,
+		Objects:     string(objects),
+		Diff:        string(diff),
+		Status:      req.Status,
+		RequestedBy: req.RequestedBy,
+		ReviewedBy:  req.ReviewedBy,
+		Error:       req.Error,
+		CreatedAt:   req.CreatedAt,
+		ReviewedAt:  req.ReviewedAt,
+	}
+}
+
+func fromAuditApply(r *audit.ApplyRequest) *ApplyRequest {
+	req := &ApplyRequest{
+		ID:          r.ID,
+		Manifest:    r.Manifest,
+		Hash:        r.Hash,
+		Status:      r.Status,
+		RequestedBy: r.RequestedBy,
+		ReviewedBy:  r.ReviewedBy,
+		Error:       r.Error,
+		CreatedAt:   r.CreatedAt,
+		ReviewedAt:  r.ReviewedAt,
+	}
+	if err := json.Unmarshal([]byte(r.Objects), &req.Objects); err != nil {
+		logger.Warn("解析应用请求的对象列表失败", zap.String("id", r.ID), zap.Error(err))
+	}
+	if err := json.Unmarshal([]byte(r.Diff), &req.Diff); err != nil {
+		logger.Warn("解析应用请求的 diff 失败", zap.String("id", r.ID), zap.Error(err))
+	}
+	return req
+}
+
+// DriftReport 已应用清单的漂移检测结果
+type DriftReport struct {
+	ID        string                   `json:"id"`
+	Hash      string                   `json:"hash"`
+	Drifted   bool                     `json:"drifted"`
+	Objects   []kubernetes.ObjectDrift `json:"objects"`
+	CheckedAt time.Time                `json:"checked_at"`
+	// 批量检测时单个请求检测失败的原因，其余请求照常检测
+	Error string `json:"error,omitempty"`
+}
+
+// CheckDrift 对比集群中的实际对象与已应用的清单，报告外部修改
+func CheckDrift(ctx context.Context, id string) (*DriftReport, error) {
+	req, err := GetApply(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != ApplyStatusApplied {
+		return nil, fmt.Errorf("应用请求 %s 当前状态为 %s，尚未应用", id, req.Status)
+	}
+
+	drifts, err := kubernetes.DetectDrift(req.Manifest, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{
+		ID:        req.ID,
+		Hash:      req.Hash,
+		Drifted:   len(drifts) > 0,
+		Objects:   drifts,
+		CheckedAt: time.Now(),
+	}
+	if report.Drifted {
+		logger.Warn("检测到清单漂移",
+			zap.String("id", req.ID),
+			zap.String("hash", req.Hash),
+			zap.Any("objects", drifts),
+		)
+	}
+	return report, nil
+}
+
+// CheckAllDrift 对最近已应用的清单执行漂移检测，单个请求检测失败时在其报告中记录错误
+func CheckAllDrift(ctx context.Context) ([]*DriftReport, error) {
+	applied, err := getApplyStore().ListApplyRequests(ctx, ApplyStatusApplied, maxDriftRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]*DriftReport, 0, len(applied))
+	for _, r := range applied {
+		report, err := CheckDrift(ctx, r.ID)
+		if err != nil {
+			logger.Warn("漂移检测失败",
+				zap.String("id", r.ID),
+				zap.Error(err),
+			)
+			report = &DriftReport{ID: r.ID, Hash: r.Hash, Objects: []kubernetes.ObjectDrift{}, CheckedAt: time.Now(), Error: err.Error()}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// memoryApplyStore 未启用审计数据库时的应用请求存储，服务重启后丢失
+type memoryApplyStore struct {
+	mu       sync.Mutex
+	requests map[string]*audit.ApplyRequest
+}
+
+func newMemoryApplyStore() *memoryApplyStore {
+	return &memoryApplyStore{requests: map[string]*audit.ApplyRequest{}}
+}
+
+func (s *memoryApplyStore) SaveApplyRequest(ctx context.Context, r *audit.ApplyRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *r
+	s.requests[r.ID] = &copied
+	return nil
+}
+
+func (s *memoryApplyStore) TransitionApplyRequest(ctx context.Context, r *audit.ApplyRequest, from string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.requests[r.ID]
+	if !ok {
+		return audit.ErrApplyRequestNotFound
+	}
+	if current.Status != from {
+		return audit.ErrApplyRequestConflict
+	}
+	copied := *r
+	s.requests[r.ID] = &copied
+	return nil
+}
+
+func (s *memoryApplyStore) GetApplyRequest(ctx context.Context, id string) (*audit.ApplyRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.requests[id]
+	if !ok {
+		return nil, audit.ErrApplyRequestNotFound
+	}
+	copied := *r
+	return &copied, nil
+}
+
+func (s *memoryApplyStore) ListApplyRequests(ctx context.Context, status string, limit int) ([]*audit.ApplyRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []*audit.ApplyRequest
+	for _, r := range s.requests {
+		if status == "" || r.Status == status {
+			copied := *r
+			requests = append(requests, &copied)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.After(requests[j].CreatedAt) })
+	if len(requests) > limit {
+		requests = requests[:limit]
+	}
+	return requests, nil
+}
@@ -0,0 +1,135 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/kubernetes"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+const testManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+  namespace: default
+data:
+  key: value
+`
+
+func TestReviewApply(t *testing.T) {
+	ctx := context.Background()
+	applyMemory = newMemoryApplyStore()
+	defer func() { applyMemory = newMemoryApplyStore() }()
+
+	var applyErr error
+	var statusDuringApply string
+	SetManifestFuncs(
+		func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+			return &kubernetes.ManifestDiff{Valid: true, Objects: []kubernetes.ObjectDiff{{Object: "ConfigMap/default/demo", Action: "create"}}}, nil
+		},
+		func(manifest, id, hash string) ([]string, error) {
+			// 应用期间不持有任何锁，其他请求可以读取到 applying 状态
+			if req, err := GetApply(ctx, id); err == nil {
+				statusDuringApply = req.Status
+			}
+			return []string{"ConfigMap/default/demo"}, applyErr
+		},
+	)
+	defer SetManifestFuncs(nil, nil)
+
+	// 未配置时默认要求审批人与申请人不同
+	utils.GetConfig().Set("generate.apply.require_distinct_reviewer", nil)
+
+	submit := func() *ApplyRequest {
+		req, err := SubmitApply(ctx, testManifest, "alice")
+		if err != nil {
+			t.Fatalf("SubmitApply() error = %v", err)
+		}
+		return req
+	}
+
+	req := submit()
+	if got, err := GetApply(ctx, req.ID); err != nil || got.Status != ApplyStatusPending || len(got.Diff) != 1 || got.Objects[0] != "ConfigMap/default/demo" {
+		t.Fatalf("GetApply() = %+v, %v", got, err)
+	}
+	if _, err := GetApply(ctx, "missing"); !errors.Is(err, ErrApplyNotFound) {
+		t.Errorf("GetApply(missing) error = %v, want ErrApplyNotFound", err)
+	}
+
+	if _, err := ReviewApply(ctx, req.ID, "alice", true, req.Hash); err == nil {
+		t.Error("expected requester to be rejected as reviewer")
+	}
+	if _, err := ReviewApply(ctx, req.ID, "bob", true, "stale"); err == nil {
+		t.Error("expected hash mismatch to fail")
+	}
+	// 校验失败不能修改请求
+	if got, _ := GetApply(ctx, req.ID); got.Status != ApplyStatusPending || got.ReviewedBy != "" || got.ReviewedAt != nil {
+		t.Fatalf("request modified by failed reviews: %+v", got)
+	}
+
+	applied, err := ReviewApply(ctx, req.ID, "bob", true, req.Hash)
+	if err != nil || applied.Status != ApplyStatusApplied || applied.ReviewedBy != "bob" {
+		t.Fatalf("ReviewApply() = %+v, %v, want applied by bob", applied, err)
+	}
+	if statusDuringApply != ApplyStatusApplying {
+		t.Errorf("status during apply = %q, want %q", statusDuringApply, ApplyStatusApplying)
+	}
+	if got, _ := GetApply(ctx, req.ID); got.Status != ApplyStatusApplied {
+		t.Errorf("stored status = %q, want applied", got.Status)
+	}
+	if _, err := ReviewApply(ctx, req.ID, "carol", false, ""); err == nil {
+		t.Error("expected second review to fail")
+	}
+
+	rejected := submit()
+	if got, err := ReviewApply(ctx, rejected.ID, "bob", false, ""); err != nil || got.Status != ApplyStatusRejected {
+		t.Errorf("reject = %+v, %v", got, err)
+	}
+
+	applyErr = errors.New("forbidden")
+	failed := submit()
+	if got, err := ReviewApply(ctx, failed.ID, "bob", true, failed.Hash); err != nil || got.Status != ApplyStatusFailed || got.Error != "forbidden" {
+		t.Errorf("failed apply = %+v, %v", got, err)
+	}
+	if got, _ := GetApply(ctx, failed.ID); got.Status != ApplyStatusFailed {
+		t.Errorf("stored status = %q, want failed", got.Status)
+	}
+
+	applyErr = nil
+	second := submit()
+	if _, err := ReviewApply(ctx, second.ID, "bob", true, second.Hash); err != nil {
+		t.Fatalf("ReviewApply() error = %v", err)
+	}
+
+	// 测试环境没有集群，漂移检测逐个失败，每个已应用请求仍有各自的报告
+	t.Setenv("KUBECONFIG", t.TempDir()+"/missing")
+	reports, err := CheckAllDrift(ctx)
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("CheckAllDrift() = %d reports, %v, want 2", len(reports), err)
+	}
+	for _, report := range reports {
+		if report.Error == "" || report.Drifted || (report.ID != req.ID && report.ID != second.ID) {
+			t.Errorf("drift report = %+v, want per-request error", report)
+		}
+	}
+}
+
+func TestSubmitApplyInvalid(t *testing.T) {
+	applyMemory = newMemoryApplyStore()
+	defer func() { applyMemory = newMemoryApplyStore() }()
+	SetManifestFuncs(func(ctx context.Context, manifest string) (*kubernetes.ManifestDiff, error) {
+		return &kubernetes.ManifestDiff{Errors: []string{"ConfigMap/default/demo: forbidden"}}, nil
+	}, nil)
+	defer SetManifestFuncs(nil, nil)
+
+	_, err := SubmitApply(context.Background(), testManifest, "alice")
+	var validation *ManifestValidationError
+	if !errors.As(err, &validation) || len(validation.Errors) != 1 {
+		t.Fatalf("SubmitApply() error = %v, want ManifestValidationError", err)
+	}
+	if list, _ := applyMemory.ListApplyRequests(context.Background(), "", 10); len(list) != 0 {
+		t.Errorf("invalid manifest stored: %v", list)
+	}
+}
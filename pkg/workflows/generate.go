@@ -19,8 +19,12 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/feiskyer/swarm-go"
+	"github.com/myysophia/OpsAgent/pkg/llms"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+	"github.com/sashabaranov/go-openai"
 )
 
 const generatePrompt = `As a skilled technical specialist in Kubernetes and cloud-native technologies, your task is to create Kubernetes YAML manifests by following these detailed steps:
@@ -52,6 +56,9 @@ const generatePrompt = `As a skilled technical specialist in Kubernetes and clou
 
 Your expertise ensures these manifests are not only functional but also compliant with the highest standards in Kubernetes and cloud-native technologies.`
 
+// generateSystemPrompt is the system prompt shared by GeneratorFlow and GenerateManifest.
+const generateSystemPrompt = "You are an expert on Kubernetes helping user to generate Kubernetes YAML manifests."
+
 // GeneratorFlow runs a workflow to generate Kubernetes YAML manifests based on the provided instructions.
 func GeneratorFlow(model string, instructions string, verbose bool) (string, error) {
 	generatorWorkflow := &swarm.SimpleFlow{
@@ -59,7 +66,7 @@ func GeneratorFlow(model string, instructions string, verbose bool) (string, err
 		Model:    model,
 		MaxTurns: 30,
 		Verbose:  verbose,
-		System:   "You are an expert on Kubernetes helping user to generate Kubernetes YAML manifests.",
+		System:   generateSystemPrompt,
 		Steps: []swarm.SimpleFlowStep{
 			{
 				Name:         "generator",
@@ -87,3 +94,24 @@ func GeneratorFlow(model string, instructions string, verbose bool) (string, err
 
 	return result, nil
 }
+
+// GenerateManifest 使用指定的服务商按说明生成 Kubernetes YAML 清单，供 API 调用
+// 与 GeneratorFlow 使用相同的提示，但不依赖环境变量中的 OpenAI 配置；模型输出带有代码块时只保留其中的 YAML
+func GenerateManifest(client llms.Provider, model, instructions string) (string, error) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: generateSystemPrompt + "\n\n" + generatePrompt},
+		{Role: openai.ChatMessageRoleUser, Content: instructions},
+	}
+	result, err := client.Chat(model, 0, messages)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(result, "```") {
+		result = utils.ExtractYaml(result)
+	}
+	result = strings.TrimSpace(result)
+	if result == "" {
+		return "", fmt.Errorf("模型没有生成清单")
+	}
+	return result, nil
+}
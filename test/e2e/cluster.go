@@ -0,0 +1,91 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 默认的 kind 集群名称
+const defaultClusterName = "opsagent-e2e"
+
+// Cluster 端到端测试使用的集群
+// 设置 E2E_KUBECONFIG 时复用已有集群（例如 envtest 或预先创建的 kind 集群），否则创建临时 kind 集群
+type Cluster struct {
+	Name       string
+	Kubeconfig string
+	// created 为 true 表示集群由测试创建，测试结束后删除
+	created bool
+}
+
+// StartCluster 准备测试集群
+func StartCluster(workDir string) (*Cluster, error) {
+	if kubeconfig := os.Getenv("E2E_KUBECONFIG"); kubeconfig != "" {
+		return &Cluster{Name: "external", Kubeconfig: kubeconfig}, nil
+	}
+
+	if _, err := exec.LookPath("kind"); err != nil {
+		return nil, fmt.Errorf("未找到 kind，请安装 kind 或通过 E2E_KUBECONFIG 指定已有集群")
+	}
+
+	name := os.Getenv("E2E_CLUSTER_NAME")
+	if name == "" {
+		name = defaultClusterName
+	}
+	cluster := &Cluster{
+		Name:       name,
+		Kubeconfig: filepath.Join(workDir, "kubeconfig"),
+		created:    true,
+	}
+	args := []string{"create", "cluster", "--name", name, "--kubeconfig", cluster.Kubeconfig, "--wait", "120s"}
+	if image := os.Getenv("E2E_NODE_IMAGE"); image != "" {
+		args = append(args, "--image", image)
+	}
+	if _, err := run("kind", args...); err != nil {
+		return nil, fmt.Errorf("创建 kind 集群失败: %v", err)
+	}
+	return cluster, nil
+}
+
+// Stop 删除测试创建的集群，设置 E2E_KEEP_CLUSTER 时保留以便排查
+func (c *Cluster) Stop() error {
+	if !c.created || os.Getenv("E2E_KEEP_CLUSTER") != "" {
+		return nil
+	}
+	_, err := run("kind", "delete", "cluster", "--name", c.Name, "--kubeconfig", c.Kubeconfig)
+	return err
+}
+
+// Seed 部署测试工作负载并等待 Deployment 就绪，带 opsagent-e2e/skip-wait 标签的除外
+func (c *Cluster) Seed(manifest string, timeout time.Duration) error {
+	if _, err := c.Kubectl("apply", "-f", manifest); err != nil {
+		return fmt.Errorf("部署测试工作负载失败: %v", err)
+	}
+	if _, err := c.Kubectl("wait", "--for=condition=Available", "deployment", "-l", "opsagent-e2e/skip-wait!=true",
+		"-n", Namespace, "--timeout", timeout.String()); err != nil {
+		return fmt.Errorf("等待测试工作负载就绪失败: %v", err)
+	}
+	return nil
+}
+
+// Kubectl 使用测试集群的 kubeconfig 执行 kubectl
+func (c *Cluster) Kubectl(args ...string) (string, error) {
+	return run("kubectl", append([]string{"--kubeconfig", c.Kubeconfig}, args...)...)
+}
+
+func run(name string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
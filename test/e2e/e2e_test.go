@@ -0,0 +1,122 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/myysophia/OpsAgent/pkg/handlers"
+)
+
+// 运行方式：go test -tags e2e ./test/e2e/ -v
+// 需要 kind 和 kubectl；设置 E2E_KUBECONFIG 时复用已有集群
+var harness *Harness
+
+func TestMain(m *testing.M) {
+	workDir, err := os.MkdirTemp("", "opsagent-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	harness, err = NewHarness(workDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "准备端到端测试环境失败: %v\n", err)
+		os.RemoveAll(workDir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	harness.Close()
+	os.RemoveAll(workDir)
+	os.Exit(code)
+}
+
+func TestExecuteListPods(t *testing.T) {
+	result := harness.Execute(t, "list_pods", handlers.ExecuteRequest{
+		Instructions: "opsagent-e2e 命名空间中 web 有几个 Pod 在运行？",
+		Args:         "web",
+		Cluster:      harness.Cluster.Name,
+	})
+
+	if result.HTTPStatus != http.StatusOK || result.Status != "success" {
+		t.Fatalf("unexpected response: %d %+v", result.HTTPStatus, result)
+	}
+	if got := result.Tools(); !reflect.DeepEqual(got, []string{"kubectl"}) {
+		t.Errorf("tools = %v, want [kubectl]", got)
+	}
+	if obs := result.ToolsHistory[0].Observation; strings.Count(obs, "Running") != 2 {
+		t.Errorf("expected 2 running pods in observation, got %q", obs)
+	}
+	if !strings.Contains(result.Message, "2 个 Pod") {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+	if result.InteractionID == "" {
+		t.Error("expected interaction_id")
+	}
+}
+
+func TestExecuteCrashLoop(t *testing.T) {
+	result := harness.Execute(t, "crashloop", handlers.ExecuteRequest{
+		Instructions: "为什么 crashy 一直重启？",
+		Args:         "crashy",
+		Cluster:      harness.Cluster.Name,
+	})
+
+	if result.HTTPStatus != http.StatusOK || result.Status != "success" {
+		t.Fatalf("unexpected response: %d %+v", result.HTTPStatus, result)
+	}
+	if got := result.Tools(); !reflect.DeepEqual(got, []string{"kubectl", "kubectl"}) {
+		t.Fatalf("tools = %v, want [kubectl kubectl]", got)
+	}
+	if input := result.ToolsHistory[1].Input; !strings.Contains(input, "logs") {
+		t.Errorf("expected a kubectl logs call, got %q", input)
+	}
+	if !strings.Contains(result.Message, "DATABASE_URL") {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+}
+
+func TestExecuteUnknownClusterNeedsConfirmation(t *testing.T) {
+	harness.LLM.Load(&Plan{Name: "no_llm_calls"})
+
+	var result ExecuteResult
+	status := harness.Post(t, "/api/execute", handlers.ExecuteRequest{
+		Instructions: "列出所有 Pod",
+		Args:         "pods",
+		Cluster:      "billing-staging",
+		BaseUrl:      harness.LLM.BaseURL(),
+	}, &result)
+
+	if status != http.StatusOK || result.Status != "needs_confirmation" {
+		t.Fatalf("unexpected response: %d %+v", status, result)
+	}
+	// 集群未确认前不应调用 LLM
+	harness.LLM.Verify(t)
+	if n := len(harness.LLM.Requests()); n != 0 {
+		t.Errorf("expected no LLM requests, got %d", n)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	var result struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	status := harness.Post(t, "/api/diagnose", handlers.DiagnoseRequest{
+		Name:      "crashy",
+		Namespace: Namespace,
+	}, &result)
+
+	if status != http.StatusOK || result.Status != "success" {
+		t.Fatalf("unexpected response: %d %+v", status, result)
+	}
+	if !strings.Contains(result.Message, "crashy") || !strings.Contains(result.Message, Namespace) {
+		t.Errorf("unexpected message %q", result.Message)
+	}
+}
@@ -0,0 +1,157 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/myysophia/OpsAgent/pkg/api"
+	"github.com/myysophia/OpsAgent/pkg/handlers"
+	"github.com/myysophia/OpsAgent/pkg/users"
+	"github.com/myysophia/OpsAgent/pkg/utils"
+)
+
+// Namespace 测试工作负载所在的命名空间，与 testdata/workloads.yaml 保持一致
+const Namespace = "opsagent-e2e"
+
+// Harness 端到端测试环境：测试集群、模拟 LLM 以及完整的 API 路由
+type Harness struct {
+	Cluster *Cluster
+	LLM     *FakeLLM
+
+	router *gin.Engine
+	token  string
+}
+
+// NewHarness 准备测试集群、部署工作负载并登录 API
+func NewHarness(workDir string) (*Harness, error) {
+	cluster, err := StartCluster(workDir)
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{Cluster: cluster, LLM: NewFakeLLM()}
+	if err := h.setup(); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+func (h *Harness) setup() error {
+	// kubectl 工具通过 KUBECONFIG 访问测试集群
+	if err := os.Setenv("KUBECONFIG", h.Cluster.Kubeconfig); err != nil {
+		return err
+	}
+	if err := h.Cluster.Seed(filepath.Join("testdata", "workloads.yaml"), 3*time.Minute); err != nil {
+		return err
+	}
+
+	utils.SetGlobalVar("jwtKey", []byte("opsagent-e2e"))
+	utils.SetGlobalVar("showThought", true)
+	gin.SetMode(gin.TestMode)
+	h.router = api.Router()
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	status, err := h.do(http.MethodPost, "/login", handlers.LoginRequest{
+		Username: users.DefaultUsername,
+		Password: users.DefaultPassword,
+	}, &resp)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK || resp.Token == "" {
+		return fmt.Errorf("登录失败: HTTP %d", status)
+	}
+	h.token = resp.Token
+	return nil
+}
+
+// Close 清理测试环境
+func (h *Harness) Close() {
+	h.LLM.Close()
+	if err := h.Cluster.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "删除测试集群失败: %v\n", err)
+	}
+}
+
+// Execute 加载回放计划后调用 /api/execute
+func (h *Harness) Execute(t *testing.T, plan string, req handlers.ExecuteRequest) ExecuteResult {
+	t.Helper()
+	p, err := LoadPlan(filepath.Join("testdata", "plans", plan+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.LLM.Load(p)
+	req.BaseUrl = h.LLM.BaseURL()
+
+	var result ExecuteResult
+	status, err := h.do(http.MethodPost, "/api/execute", req, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.HTTPStatus = status
+	h.LLM.Verify(t)
+	return result
+}
+
+// Post 调用不依赖 LLM 的接口
+func (h *Harness) Post(t *testing.T, path string, body, out interface{}) int {
+	t.Helper()
+	status, err := h.do(http.MethodPost, path, body, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func (h *Harness) do(method, path string, body, out interface{}) (int, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "e2e-key")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	w := httptest.NewRecorder()
+	h.router.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			return w.Code, fmt.Errorf("解析 %s 响应失败: %v: %s", path, err, w.Body.String())
+		}
+	}
+	return w.Code, nil
+}
+
+// ExecuteResult /api/execute 的响应
+type ExecuteResult struct {
+	HTTPStatus    int                    `json:"-"`
+	Status        string                 `json:"status"`
+	Message       string                 `json:"message"`
+	Error         string                 `json:"error"`
+	InteractionID string                 `json:"interaction_id"`
+	ToolsHistory  []handlers.ToolHistory `json:"tools_history"`
+}
+
+// Tools 返回调用的工具名称
+func (r ExecuteResult) Tools() []string {
+	names := make([]string, 0, len(r.ToolsHistory))
+	for _, call := range r.ToolsHistory {
+		names = append(names, call.Name)
+	}
+	return names
+}
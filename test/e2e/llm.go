@@ -0,0 +1,149 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// PlanStep 回放计划中的一轮对话
+type PlanStep struct {
+	// Expect 本轮请求最后一条消息应包含的内容，用于确认工具结果已回传给 LLM
+	Expect []string `json:"expect,omitempty"`
+	// Response LLM 的回复，按 tools.ToolPrompt 的格式编写
+	Response json.RawMessage `json:"response"`
+}
+
+// Plan 预先录制的工具调用计划
+type Plan struct {
+	Name  string     `json:"name"`
+	Steps []PlanStep `json:"steps"`
+}
+
+// LoadPlan 读取 testdata/plans 下的回放计划
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("解析回放计划 %s 失败: %v", path, err)
+	}
+	return &plan, nil
+}
+
+// FakeLLM 兼容 OpenAI Chat Completions 接口的模拟服务，按顺序回放计划中的回复
+type FakeLLM struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	plan     *Plan
+	next     int
+	requests []openai.ChatCompletionRequest
+	errors   []string
+}
+
+// NewFakeLLM 启动模拟 LLM 服务
+func NewFakeLLM() *FakeLLM {
+	f := &FakeLLM{}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	return f
+}
+
+// BaseURL 返回传给 execute 请求的 baseUrl
+func (f *FakeLLM) BaseURL() string {
+	return f.server.URL + "/v1"
+}
+
+// Close 关闭模拟服务
+func (f *FakeLLM) Close() {
+	f.server.Close()
+}
+
+// Load 切换到新的回放计划并清空请求记录
+func (f *FakeLLM) Load(plan *Plan) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.plan, f.next, f.requests, f.errors = plan, 0, nil, nil
+}
+
+// Requests 返回收到的请求
+func (f *FakeLLM) Requests() []openai.ChatCompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), f.requests...)
+}
+
+// Verify 检查计划是否完整回放且每轮请求都符合预期
+func (f *FakeLLM) Verify(t *testing.T) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range f.errors {
+		t.Error(e)
+	}
+	if f.plan != nil && f.next != len(f.plan.Steps) {
+		t.Errorf("回放计划 %s 只使用了 %d/%d 轮", f.plan.Name, f.next, len(f.plan.Steps))
+	}
+}
+
+func (f *FakeLLM) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasSuffix(r.URL.Path, "/chat/completions") {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	if f.plan == nil || f.next >= len(f.plan.Steps) {
+		f.errors = append(f.errors, fmt.Sprintf("第 %d 轮请求超出回放计划", len(f.requests)))
+		// 使用 400 避免客户端按 5xx 重试
+		http.Error(w, "plan exhausted", http.StatusBadRequest)
+		return
+	}
+	step := f.plan.Steps[f.next]
+	f.next++
+
+	var last string
+	if len(req.Messages) > 0 {
+		last = req.Messages[len(req.Messages)-1].Content
+	}
+	for _, expect := range step.Expect {
+		if !strings.Contains(last, expect) {
+			f.errors = append(f.errors, fmt.Sprintf("计划 %s 第 %d 轮：最后一条消息不包含 %q:\n%s", f.plan.Name, f.next, expect, last))
+		}
+	}
+
+	resp := openai.ChatCompletionResponse{
+		ID:      fmt.Sprintf("e2e-%d", len(f.requests)),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: string(step.Response),
+			},
+			FinishReason: openai.FinishReasonStop,
+		}},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}